@@ -24,7 +24,12 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/kcp-dev/kcp/pkg/cmd/admin"
+	"github.com/kcp-dev/kcp/pkg/cmd/backup"
+	"github.com/kcp-dev/kcp/pkg/cmd/doctor"
 	"github.com/kcp-dev/kcp/pkg/cmd/help"
+	"github.com/kcp-dev/kcp/pkg/cmd/replay"
+	"github.com/kcp-dev/kcp/pkg/cmd/workload"
 	"github.com/kcp-dev/kcp/pkg/server"
 )
 
@@ -71,7 +76,16 @@ func main() {
 	}
 	cfg = server.BindOptions(server.DefaultConfig(), startCmd.Flags())
 	cmd.AddCommand(startCmd)
-	if err := cmd.Execute(); err != nil {
+	cmd.AddCommand(backup.NewCmdBackup(os.Stderr))
+	cmd.AddCommand(backup.NewCmdRestore(os.Stderr))
+	cmd.AddCommand(workload.NewCmdWorkload())
+	cmd.AddCommand(admin.NewCmdAdmin(os.Stdout))
+	cmd.AddCommand(doctor.NewCmdDoctor(os.Stdout))
+	cmd.AddCommand(replay.NewCmdReplay(os.Stdout))
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	if err := cmd.ExecuteContext(ctx); err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
 	}
 }
@@ -0,0 +1,55 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kcp-dev/kcp/pkg/cmd/help"
+	"github.com/kcp-dev/kcp/pkg/cmd/workspace"
+)
+
+func main() {
+	help.FitTerminal()
+	cmd := &cobra.Command{
+		Use:   "kcp",
+		Short: "kubectl plugin for KCP",
+		Long: help.Doc(`
+			KCP is a multi-tenant Kubernetes control plane for workloads on many
+			clusters.
+
+			This plugin of kubectl manages the resources specific to KCP, like
+			workspaces. Install it as 'kubectl-kcp' on your PATH and run it as
+			'kubectl kcp'.
+		`),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	cmd.AddCommand(workspace.NewCmdWorkspace(os.Stdout))
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	if err := cmd.ExecuteContext(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
@@ -28,16 +29,27 @@ import (
 	"k8s.io/klog/v2"
 
 	"github.com/kcp-dev/kcp/pkg/syncer"
+	"github.com/kcp-dev/kcp/pkg/tunnel"
 )
 
 const numThreads = 2
 
 var (
-	fromKubeconfig = flag.String("from_kubeconfig", "", "Kubeconfig file for -from cluster.")
-	fromCluster    = flag.String("from_cluster", "", "Name of the -from logical cluster.")
-	toKubeconfig   = flag.String("to_kubeconfig", "", "Kubeconfig file for -to cluster. If not set, the InCluster configuration will be used.")
-	toContext      = flag.String("to_context", "", "Context to use in the Kubeconfig file for -to cluster, instead of the current context.")
-	clusterID      = flag.String("cluster", "", "ID of the -to cluster. Resources with this ID set in the 'kcp.dev/cluster' label will be synced.")
+	fromKubeconfig    = flag.String("from_kubeconfig", "", "Kubeconfig file for -from cluster.")
+	fromCluster       = flag.String("from_cluster", "", "Name of the -from logical cluster.")
+	toKubeconfig      = flag.String("to_kubeconfig", "", "Kubeconfig file for -to cluster. If not set, the InCluster configuration will be used.")
+	toContext         = flag.String("to_context", "", "Context to use in the Kubeconfig file for -to cluster, instead of the current context.")
+	clusterID         = flag.String("cluster", "", "ID of the -to cluster. Resources with this ID set in the 'kcp.dev/cluster' label will be synced.")
+	conflictPolicy    = flag.String("conflict_policy", string(syncer.DefaultOptions().ConflictPolicy), "Which side wins when synced objects are changed in the -to cluster: UpstreamWins overwrites the changes, DownstreamWins keeps them until the object changes in the -from cluster.")
+	namespaceStrategy = flag.String("namespace_strategy", string(syncer.DefaultOptions().NamespaceStrategy), "Which namespaces of the -to cluster objects are synced to: Identity uses the namespace of the same name, WorkspacePrefix prefixes it with a hash of the -from logical cluster. Namespaces mapped by SyncTransforms are not affected.")
+	upsyncSelector    = flag.String("upsync_selector", "", "Label selector of pre-existing objects in the -to cluster to import into the -from logical cluster. Empty disables importing.")
+	openTunnel        = flag.Bool("tunnel", false, "Open a tunnel to the -from server, through which kcp reaches the API server of the -to cluster. For clusters which don't accept inbound connections, registered with the Tunnel connection mode.")
+	qps               = flag.Float64("qps", float64(syncer.DefaultOptions().QPS), "Sustained number of requests per second made to the -to cluster. Zero leaves the client defaults.")
+	burst             = flag.Int("burst", syncer.DefaultOptions().Burst, "Number of requests which can be made at once to the -to cluster above --qps.")
+	batchInterval     = flag.Duration("batch_interval", syncer.DefaultOptions().BatchInterval, "How long the changes of an object are batched before it is synced to the -to cluster. Zero syncs every change right away.")
+	heartbeatPeriod   = flag.Duration("heartbeat_period", syncer.DefaultOptions().HeartbeatPeriod, "How often the syncer records that it is alive in the status of its Cluster in the -from logical cluster. Zero disables heartbeats.")
+	mirrorEvents      = flag.Bool("mirror_events", false, "Mirror the Events of the -to cluster about synced objects and their dependents, like the Pods of a Deployment, into the -from logical cluster.")
+	resyncPeriod      = flag.Duration("resync_period", syncer.DefaultOptions().ResyncPeriod, "How often all objects are synced again to detect changes made in the -to cluster. Zero disables periodic resyncs.")
 )
 
 func main() {
@@ -84,7 +96,38 @@ func main() {
 		klog.Fatal(err)
 	}
 
-	syncer, err := syncer.StartSyncer(fromConfig, toConfig, sets.NewString(syncedResourceTypes...), *clusterID, *fromCluster, numThreads)
+	policy, err := syncer.ParseConflictPolicy(*conflictPolicy)
+	if err != nil {
+		klog.Fatal(err)
+	}
+	strategy, err := syncer.ParseNamespaceStrategy(*namespaceStrategy)
+	if err != nil {
+		klog.Fatal(err)
+	}
+	options := syncer.Options{
+		ConflictPolicy:    policy,
+		ResyncPeriod:      *resyncPeriod,
+		UpsyncSelector:    *upsyncSelector,
+		NamespaceStrategy: strategy,
+		QPS:               float32(*qps),
+		Burst:             *burst,
+		BatchInterval:     *batchInterval,
+		HeartbeatPeriod:   *heartbeatPeriod,
+		MirrorEvents:      *mirrorEvents,
+	}
+
+	if *openTunnel {
+		if *clusterID == "" {
+			klog.Fatal("--cluster is required to open a tunnel")
+		}
+		proxy, err := tunnel.NewProxy(toConfig)
+		if err != nil {
+			klog.Fatal(err)
+		}
+		go tunnel.Run(context.Background(), fromConfig, *fromCluster, *clusterID, proxy)
+	}
+
+	syncer, err := syncer.StartSyncer(fromConfig, toConfig, sets.NewString(syncedResourceTypes...), *clusterID, *fromCluster, numThreads, options)
 	if err != nil {
 		klog.Fatal(err)
 	}
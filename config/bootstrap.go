@@ -70,8 +70,9 @@ func BootstrapCustomResourceDefinition(ctx context.Context, client apiextensions
 	return BootstrapCustomResourceDefinitionFromFS(ctx, client, gk, rawCustomResourceDefinitions)
 }
 
-// BootstrapCustomResourceDefinitionFromFS creates the CRD using the target client from the
-// provided filesystem handle and waits for it to become established. This call is blocking.
+// BootstrapCustomResourceDefinitionFromFS creates or updates the CRD using the target client
+// from the provided filesystem handle and waits for it to become established. This call is
+// blocking.
 func BootstrapCustomResourceDefinitionFromFS(ctx context.Context, client apiextensionsv1client.CustomResourceDefinitionInterface, gk metav1.GroupKind, fs embed.FS) error {
 	start := time.Now()
 	klog.Infof("bootstrapping %v", gk.String())
@@ -96,7 +97,21 @@ func BootstrapCustomResourceDefinitionFromFS(ctx context.Context, client apiexte
 	}
 
 	crd, err := client.Create(ctx, rawCrd, metav1.CreateOptions{})
-	if err != nil && !apierrors.IsAlreadyExists(err) {
+	if apierrors.IsAlreadyExists(err) {
+		// the CRD of a previous version is updated, e.g. with new printer columns
+		crd, err = client.Get(ctx, rawCrd.Name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("could not get CRD %s: %w", gk.String(), err)
+		}
+		crd.Spec = rawCrd.Spec
+		crd, err = client.Update(ctx, crd, metav1.UpdateOptions{})
+		if err != nil {
+			return fmt.Errorf("could not update CRD %s: %w", gk.String(), err)
+		}
+		if crdhelpers.IsCRDConditionTrue(crd, apiextensionsv1.Established) {
+			return nil
+		}
+	} else if err != nil {
 		return fmt.Errorf("could not create CRD %s: %w", gk.String(), err)
 	}
 
@@ -0,0 +1,356 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	crdhelpers "k8s.io/apiextensions-apiserver/pkg/apihelpers"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// DefaultFieldManager is the field manager of the objects applied by a Bootstrapper.
+	DefaultFieldManager = "kcp-bootstrapper"
+
+	// maxManifestSize bounds the size of the manifests read from URLs.
+	maxManifestSize = 10 << 20
+)
+
+var crdGroupKind = schema.GroupKind{Group: apiextensionsv1.GroupName, Kind: "CustomResourceDefinition"}
+
+// Source provides the manifests of the objects to bootstrap.
+type Source interface {
+	// Manifests returns the objects of the source, in the order they are found.
+	Manifests(ctx context.Context) ([]*unstructured.Unstructured, error)
+	// String describes the source.
+	String() string
+}
+
+// FromFS returns the manifests of the YAML and JSON files of fsys, in lexical order of
+// their paths.
+func FromFS(fsys fs.FS) Source {
+	return &fsSource{fsys: fsys, name: "filesystem"}
+}
+
+// FromDirectory returns the manifests of the YAML and JSON files of the local directory,
+// in lexical order of their paths.
+func FromDirectory(dir string) Source {
+	return &fsSource{fsys: os.DirFS(dir), name: dir}
+}
+
+type fsSource struct {
+	fsys fs.FS
+	name string
+}
+
+func (s *fsSource) String() string {
+	return s.name
+}
+
+func (s *fsSource) Manifests(ctx context.Context) ([]*unstructured.Unstructured, error) {
+	var files []string
+	if err := fs.WalkDir(s.fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		switch path.Ext(p) {
+		case ".yaml", ".yml", ".json":
+			if !d.IsDir() {
+				files = append(files, p)
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("could not walk %s: %w", s.name, err)
+	}
+	sort.Strings(files)
+
+	var objs []*unstructured.Unstructured
+	for _, file := range files {
+		raw, err := fs.ReadFile(s.fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("could not read %s: %w", file, err)
+		}
+		fileObjs, err := decodeManifests(raw)
+		if err != nil {
+			return nil, fmt.Errorf("could not decode %s: %w", file, err)
+		}
+		objs = append(objs, fileObjs...)
+	}
+	return objs, nil
+}
+
+// FromURL returns the manifests served at the HTTPS URL, fetched with the given client,
+// or http.DefaultClient if nil.
+func FromURL(u string, client *http.Client) Source {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &urlSource{url: u, client: client}
+}
+
+type urlSource struct {
+	url    string
+	client *http.Client
+}
+
+func (s *urlSource) String() string {
+	return s.url
+}
+
+func (s *urlSource) Manifests(ctx context.Context) ([]*unstructured.Unstructured, error) {
+	u, err := url.Parse(s.url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %q: %w", s.url, err)
+	}
+	if u.Scheme != "https" {
+		return nil, fmt.Errorf("manifests can only be fetched over HTTPS, got %q", s.url)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch %s: %w", s.url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not fetch %s: %s", s.url, resp.Status)
+	}
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", s.url, err)
+	}
+	if len(raw) > maxManifestSize {
+		return nil, fmt.Errorf("manifests at %s are larger than %d bytes", s.url, maxManifestSize)
+	}
+	objs, err := decodeManifests(raw)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode %s: %w", s.url, err)
+	}
+	return objs, nil
+}
+
+// decodeManifests decodes the objects of a YAML stream or JSON document, skipping empty
+// documents and unrolling lists.
+func decodeManifests(raw []byte) ([]*unstructured.Unstructured, error) {
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(raw), 4096)
+	var objs []*unstructured.Unstructured
+	for {
+		obj := &unstructured.Unstructured{}
+		if err := decoder.Decode(&obj.Object); err != nil {
+			if errors.Is(err, io.EOF) {
+				return objs, nil
+			}
+			return nil, err
+		}
+		if len(obj.Object) == 0 {
+			continue
+		}
+		if obj.IsList() {
+			if err := obj.EachListItem(func(item runtime.Object) error {
+				objs = append(objs, item.(*unstructured.Unstructured))
+				return nil
+			}); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if obj.GetKind() == "" || obj.GetAPIVersion() == "" {
+			return nil, fmt.Errorf("object %q has no apiVersion or kind", obj.GetName())
+		}
+		objs = append(objs, obj)
+	}
+}
+
+// Result is the outcome of bootstrapping an object.
+type Result struct {
+	GroupVersionKind schema.GroupVersionKind
+	Namespace        string
+	Name             string
+	// Err is the reason the object couldn't be bootstrapped, if any.
+	Err error
+}
+
+func (r Result) String() string {
+	name := r.Name
+	if r.Namespace != "" {
+		name = r.Namespace + "/" + name
+	}
+	if r.Err != nil {
+		return fmt.Sprintf("%s %s failed: %v", r.GroupVersionKind.GroupKind(), name, r.Err)
+	}
+	return fmt.Sprintf("%s %s applied", r.GroupVersionKind.GroupKind(), name)
+}
+
+// Bootstrapper applies manifests to a logical cluster with server-side apply. The
+// CustomResourceDefinitions are applied first, and waited for to be established, so that
+// the other objects can be of the kinds they define.
+type Bootstrapper struct {
+	dynamicClient dynamic.Interface
+	mapper        *restmapper.DeferredDiscoveryRESTMapper
+
+	// FieldManager is the field manager of the applied objects.
+	FieldManager string
+	// Force takes over the fields of the objects managed by other field managers.
+	Force bool
+	// EstablishedTimeout bounds the wait for each CustomResourceDefinition to be
+	// established.
+	EstablishedTimeout time.Duration
+}
+
+// NewBootstrapper returns a Bootstrapper applying manifests with the clients of a
+// logical cluster.
+func NewBootstrapper(dynamicClient dynamic.Interface, discoveryClient discovery.DiscoveryInterface) *Bootstrapper {
+	return &Bootstrapper{
+		dynamicClient:      dynamicClient,
+		mapper:             restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient)),
+		FieldManager:       DefaultFieldManager,
+		Force:              true,
+		EstablishedTimeout: time.Minute,
+	}
+}
+
+// Bootstrap applies the objects of the sources, and returns the result for each of them.
+// The error aggregates the failures to read the sources and to apply the objects.
+func (b *Bootstrapper) Bootstrap(ctx context.Context, sources ...Source) ([]Result, error) {
+	var crds, others []*unstructured.Unstructured
+	var errs []error
+	for _, source := range sources {
+		objs, err := source.Manifests(ctx)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, obj := range objs {
+			if obj.GroupVersionKind().GroupKind() == crdGroupKind {
+				crds = append(crds, obj)
+			} else {
+				others = append(others, obj)
+			}
+		}
+	}
+
+	var results []Result
+	for i, objs := range [][]*unstructured.Unstructured{crds, others} {
+		if i == 1 && len(crds) > 0 {
+			// the kinds of the new CRDs are now served
+			b.mapper.Reset()
+		}
+		for _, obj := range objs {
+			err := b.apply(ctx, obj)
+			if err == nil && obj.GroupVersionKind().GroupKind() == crdGroupKind {
+				err = b.waitForEstablished(ctx, obj.GetName())
+			}
+			results = append(results, Result{
+				GroupVersionKind: obj.GroupVersionKind(),
+				Namespace:        obj.GetNamespace(),
+				Name:             obj.GetName(),
+				Err:              err,
+			})
+			if err != nil {
+				errs = append(errs, fmt.Errorf("could not bootstrap %s %s: %w", obj.GroupVersionKind().GroupKind(), obj.GetName(), err))
+			}
+		}
+	}
+	return results, kerrors.NewAggregate(errs)
+}
+
+// apply applies the object with server-side apply.
+func (b *Bootstrapper) apply(ctx context.Context, obj *unstructured.Unstructured) error {
+	gvk := obj.GroupVersionKind()
+	waitCtx, cancel := context.WithTimeout(ctx, b.EstablishedTimeout)
+	defer cancel()
+	var mapping *meta.RESTMapping
+	// the kinds of CRDs just established may take a moment to be discovered
+	if err := wait.PollImmediateUntil(time.Second, func() (bool, error) {
+		var err error
+		mapping, err = b.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if meta.IsNoMatchError(err) {
+			klog.V(4).Infof("waiting for %s to be served", gvk)
+			b.mapper.Reset()
+			return false, nil
+		}
+		return err == nil, err
+	}, waitCtx.Done()); err != nil {
+		return fmt.Errorf("could not find the resource of %s: %w", gvk, err)
+	}
+
+	data, err := obj.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	var client dynamic.ResourceInterface = b.dynamicClient.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		namespace := obj.GetNamespace()
+		if namespace == "" {
+			namespace = metav1.NamespaceDefault
+		}
+		client = b.dynamicClient.Resource(mapping.Resource).Namespace(namespace)
+	}
+	force := b.Force
+	_, err = client.Patch(ctx, obj.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{
+		FieldManager: b.FieldManager,
+		Force:        &force,
+	})
+	return err
+}
+
+// waitForEstablished waits for the CustomResourceDefinition to be established.
+func (b *Bootstrapper) waitForEstablished(ctx context.Context, name string) error {
+	client := b.dynamicClient.Resource(apiextensionsv1.SchemeGroupVersion.WithResource("customresourcedefinitions"))
+	waitCtx, cancel := context.WithTimeout(ctx, b.EstablishedTimeout)
+	defer cancel()
+	return wait.PollImmediateUntil(100*time.Millisecond, func() (bool, error) {
+		u, err := client.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		crd := &apiextensionsv1.CustomResourceDefinition{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, crd); err != nil {
+			return false, err
+		}
+		return crdhelpers.IsCRDConditionTrue(crd, apiextensionsv1.Established), nil
+	}, waitCtx.Done())
+}
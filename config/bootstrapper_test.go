@@ -0,0 +1,89 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const manifests = `
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: shop
+---
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: a
+    namespace: shop
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: b
+    namespace: shop
+`
+
+func TestManifests(t *testing.T) {
+	names := func(objs []*unstructured.Unstructured, err error) []string {
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, obj := range objs {
+			names = append(names, obj.GetKind()+" "+obj.GetName())
+		}
+		return names
+	}
+
+	fsys := fstest.MapFS{
+		"b/manifests.yaml": {Data: []byte(manifests)},
+		"a/crd.json":       {Data: []byte(`{"apiVersion": "apiextensions.k8s.io/v1", "kind": "CustomResourceDefinition", "metadata": {"name": "widgets.example.dev"}}`)},
+		"README.md":        {Data: []byte("# not a manifest")},
+	}
+	want := []string{"CustomResourceDefinition widgets.example.dev", "Namespace shop", "ConfigMap a", "ConfigMap b"}
+	if diff := cmp.Diff(want, names(FromFS(fsys).Manifests(context.Background()))); diff != "" {
+		t.Errorf("unexpected objects from the filesystem (-want +got):\n%s", diff)
+	}
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(manifests))
+	}))
+	defer server.Close()
+	if diff := cmp.Diff(want[1:], names(FromURL(server.URL, server.Client()).Manifests(context.Background()))); diff != "" {
+		t.Errorf("unexpected objects from the URL (-want +got):\n%s", diff)
+	}
+	if _, err := FromURL("http"+server.URL[len("https"):], server.Client()).Manifests(context.Background()); err == nil {
+		t.Errorf("expected manifests not to be fetched over plain HTTP")
+	}
+
+	if _, err := FromFS(fstest.MapFS{"bad.yaml": {Data: []byte("metadata:\n  name: foo\n")}}).Manifests(context.Background()); err == nil {
+		t.Errorf("expected objects without kind to be rejected")
+	}
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command applyconfiguration-gen runs the applyconfiguration-gen of k8s.io/code-generator
+// with the apply configurations of client-go for the Kubernetes types referenced by the kcp
+// APIs. Its --external-applyconfigurations flag can't be used for them, since it rejects
+// packages with dots in their path.
+package main
+
+import (
+	"flag"
+
+	"github.com/spf13/pflag"
+	"k8s.io/gengo/types"
+	"k8s.io/klog/v2"
+
+	generatorargs "k8s.io/code-generator/cmd/applyconfiguration-gen/args"
+	"k8s.io/code-generator/cmd/applyconfiguration-gen/generators"
+)
+
+// externalApplyConfigurations are the apply configuration packages of the types
+// referenced by the kcp APIs, in addition to TypeMeta and ObjectMeta.
+var externalApplyConfigurations = map[types.Name]string{
+	{Package: "k8s.io/apimachinery/pkg/apis/meta/v1", Name: "OwnerReference"}:     "k8s.io/client-go/applyconfigurations/meta/v1",
+	{Package: "k8s.io/apimachinery/pkg/apis/meta/v1", Name: "ManagedFieldsEntry"}: "k8s.io/client-go/applyconfigurations/meta/v1",
+	{Package: "k8s.io/api/rbac/v1", Name: "PolicyRule"}:                           "k8s.io/client-go/applyconfigurations/rbac/v1",
+	{Package: "k8s.io/api/rbac/v1", Name: "Subject"}:                              "k8s.io/client-go/applyconfigurations/rbac/v1",
+}
+
+func main() {
+	klog.InitFlags(nil)
+	genericArgs, customArgs := generatorargs.NewDefaults()
+	for name, pkg := range externalApplyConfigurations {
+		customArgs.ExternalApplyConfigurations[name] = pkg
+	}
+	genericArgs.AddFlags(pflag.CommandLine)
+	if err := flag.Set("logtostderr", "true"); err != nil {
+		klog.Fatalf("Error: %v", err)
+	}
+	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
+	pflag.Parse()
+
+	if err := generatorargs.Validate(genericArgs); err != nil {
+		klog.Fatalf("Error: %v", err)
+	}
+	if err := genericArgs.Execute(
+		generators.NameSystems(),
+		generators.DefaultNameSystem(),
+		generators.Packages,
+	); err != nil {
+		klog.Fatalf("Error: %v", err)
+	}
+}
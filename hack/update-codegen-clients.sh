@@ -24,9 +24,28 @@ export GOPATH=$(go env GOPATH)
 SCRIPT_ROOT=$(dirname "${BASH_SOURCE[0]}")/..
 CODEGEN_PKG=${CODEGEN_PKG:-$(cd "${SCRIPT_ROOT}"; go list -f '{{.Dir}}' -m k8s.io/code-generator)}
 
-bash "${CODEGEN_PKG}"/generate-groups.sh "deepcopy,client,informer,lister" \
+bash "${CODEGEN_PKG}"/generate-groups.sh "deepcopy,informer,lister" \
   github.com/kcp-dev/kcp/pkg/client github.com/kcp-dev/kcp/pkg/apis \
-  "cluster:v1alpha1 apiresource:v1alpha1 tenancy:v1alpha1" \
+  "cluster:v1alpha1 apiresource:v1alpha1 tenancy:v1alpha1 apis:v1alpha1" \
+  --go-header-file "${SCRIPT_ROOT}"/hack/boilerplate/boilerplate.go.txt --output-base ${GOPATH}/src
+
+# generate-groups.sh installed client-gen
+GOBIN="$(go env GOBIN)"
+gobin="${GOBIN:-$(go env GOPATH)/bin}"
+(cd "${SCRIPT_ROOT}"; go install ./hack/applyconfiguration-gen)
+
+# the apiresource types embed metav1.TableColumnDefinition, which has no apply
+# configuration, so they are not applied
+"${gobin}/applyconfiguration-gen" \
+  --input-dirs github.com/kcp-dev/kcp/pkg/apis/cluster/v1alpha1,github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1,github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1 \
+  --output-package github.com/kcp-dev/kcp/pkg/client/applyconfiguration \
+  --go-header-file "${SCRIPT_ROOT}"/hack/boilerplate/boilerplate.go.txt --output-base ${GOPATH}/src
+
+"${gobin}/client-gen" \
+  --clientset-name versioned --input-base "" \
+  --input github.com/kcp-dev/kcp/pkg/apis/cluster/v1alpha1,github.com/kcp-dev/kcp/pkg/apis/apiresource/v1alpha1,github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1,github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1 \
+  --output-package github.com/kcp-dev/kcp/pkg/client/clientset \
+  --apply-configuration-package github.com/kcp-dev/kcp/pkg/client/applyconfiguration \
   --go-header-file "${SCRIPT_ROOT}"/hack/boilerplate/boilerplate.go.txt --output-base ${GOPATH}/src
 
 bash "${CODEGEN_PKG}"/generate-groups.sh "deepcopy,client" \
@@ -0,0 +1,127 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package apibinder records the user binding an APIExport with an APIBinding, so that the
+// binding is only established if that user may bind the export and grant its permission
+// claims.
+package apibinder
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/apiserver/pkg/authentication/user"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+)
+
+const (
+	// PluginName is the name of this admission plugin.
+	PluginName = "apis.kcp.dev/APIBinder"
+
+	// BinderAnnotation is set on every APIBinding to the user who set its spec, encoded as
+	// an authentication.k8s.io/v1 UserInfo.
+	BinderAnnotation = "apis.kcp.dev/binder"
+)
+
+// Register registers the plugin.
+func Register(plugins *admission.Plugins) {
+	plugins.Register(PluginName, func(_ io.Reader) (admission.Interface, error) {
+		return &apiBinder{
+			Handler: admission.NewHandler(admission.Create, admission.Update),
+		}, nil
+	})
+}
+
+type apiBinder struct {
+	*admission.Handler
+}
+
+var _ admission.MutationInterface = &apiBinder{}
+
+// Admit sets the binder annotation of APIBindings to the requesting user when they are
+// created or their spec changes, and keeps it from being changed otherwise.
+func (b *apiBinder) Admit(_ context.Context, a admission.Attributes, _ admission.ObjectInterfaces) error {
+	if a.GetResource().GroupResource() != apisv1alpha1.Resource("apibindings") || a.GetSubresource() != "" {
+		return nil
+	}
+	binding, ok := a.GetObject().(*apisv1alpha1.APIBinding)
+	if !ok {
+		return fmt.Errorf("unexpected apibinding object %T", a.GetObject())
+	}
+
+	var binder string
+	if a.GetOperation() == admission.Update {
+		old, ok := a.GetOldObject().(*apisv1alpha1.APIBinding)
+		if !ok {
+			return fmt.Errorf("unexpected apibinding object %T", a.GetOldObject())
+		}
+		if equality.Semantic.DeepEqual(old.Spec, binding.Spec) {
+			binder = old.Annotations[BinderAnnotation]
+		}
+	}
+	if binder == "" && a.GetUserInfo() != nil {
+		encoded, err := json.Marshal(userInfo(a.GetUserInfo()))
+		if err != nil {
+			return err
+		}
+		binder = string(encoded)
+	}
+
+	if binder == "" {
+		delete(binding.Annotations, BinderAnnotation)
+		return nil
+	}
+	if binding.Annotations == nil {
+		binding.Annotations = map[string]string{}
+	}
+	binding.Annotations[BinderAnnotation] = binder
+	return nil
+}
+
+// userInfo returns the UserInfo recorded for the given user.
+func userInfo(u user.Info) authenticationv1.UserInfo {
+	info := authenticationv1.UserInfo{
+		Username: u.GetName(),
+		UID:      u.GetUID(),
+		Groups:   u.GetGroups(),
+	}
+	if extra := u.GetExtra(); len(extra) > 0 {
+		info.Extra = map[string]authenticationv1.ExtraValue{}
+		for key, value := range extra {
+			info.Extra[key] = value
+		}
+	}
+	return info
+}
+
+// Binder returns the user recorded as the binder of the APIBinding, if any.
+func Binder(binding *apisv1alpha1.APIBinding) (*authenticationv1.UserInfo, error) {
+	value, found := binding.Annotations[BinderAnnotation]
+	if !found {
+		return nil, nil
+	}
+	var info authenticationv1.UserInfo
+	if err := json.Unmarshal([]byte(value), &info); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", BinderAnnotation, err)
+	}
+	return &info, nil
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apibinder
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/apiserver/pkg/authentication/user"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+)
+
+func TestAdmit(t *testing.T) {
+	alice := &user.DefaultInfo{Name: "alice", Groups: []string{"developers"}}
+	bob := &user.DefaultInfo{Name: "bob"}
+	binding := func(binder, exportName string) *apisv1alpha1.APIBinding {
+		binding := &apisv1alpha1.APIBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "widgets"},
+			Spec:       apisv1alpha1.APIBindingSpec{Reference: apisv1alpha1.ExportReference{Workspace: "provider", ExportName: exportName}},
+		}
+		if binder != "" {
+			binding.Annotations = map[string]string{BinderAnnotation: binder}
+		}
+		return binding
+	}
+
+	for _, tc := range []struct {
+		name           string
+		operation      admission.Operation
+		obj, old       *apisv1alpha1.APIBinding
+		user           user.Info
+		expectedBinder string
+	}{
+		{
+			name:           "create",
+			operation:      admission.Create,
+			obj:            binding("", "widgets"),
+			user:           alice,
+			expectedBinder: "alice",
+		},
+		{
+			name:           "create with a spoofed binder",
+			operation:      admission.Create,
+			obj:            binding(`{"username":"admin","groups":["system:masters"]}`, "widgets"),
+			user:           bob,
+			expectedBinder: "bob",
+		},
+		{
+			name:           "update of the metadata",
+			operation:      admission.Update,
+			obj:            binding(`{"username":"bob"}`, "widgets"),
+			old:            binding(`{"username":"alice","groups":["developers"]}`, "widgets"),
+			user:           bob,
+			expectedBinder: "alice",
+		},
+		{
+			name:           "rebind",
+			operation:      admission.Update,
+			obj:            binding(`{"username":"alice","groups":["developers"]}`, "gadgets"),
+			old:            binding(`{"username":"alice","groups":["developers"]}`, "widgets"),
+			user:           bob,
+			expectedBinder: "bob",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var old runtime.Object
+			if tc.old != nil {
+				old = tc.old
+			}
+			attr := admission.NewAttributesRecord(tc.obj, old, apisv1alpha1.Kind("APIBinding").WithVersion("v1alpha1"), "", tc.obj.Name, apisv1alpha1.SchemeGroupVersion.WithResource("apibindings"), "", tc.operation, nil, false, tc.user)
+			if err := (&apiBinder{}).Admit(context.Background(), attr, nil); err != nil {
+				t.Fatal(err)
+			}
+			binder, err := Binder(tc.obj)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if binder == nil || binder.Username != tc.expectedBinder {
+				t.Errorf("expected binder %q, got %+v", tc.expectedBinder, binder)
+			}
+		})
+	}
+}
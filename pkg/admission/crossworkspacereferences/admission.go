@@ -0,0 +1,88 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package crossworkspacereferences rejects objects referencing objects of other workspaces
+// which did not grant these references.
+package crossworkspacereferences
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/admission"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+
+	"github.com/kcp-dev/kcp/pkg/crossworkspace"
+)
+
+// PluginName is the name of this admission plugin.
+const PluginName = "apis.kcp.dev/CrossWorkspaceReferences"
+
+// Register registers the plugin, validating references with the given resolver.
+func Register(plugins *admission.Plugins, resolver *crossworkspace.Resolver) {
+	plugins.Register(PluginName, func(_ io.Reader) (admission.Interface, error) {
+		return &crossWorkspaceReferences{
+			Handler:  admission.NewHandler(admission.Create, admission.Update),
+			resolver: resolver,
+		}, nil
+	})
+}
+
+type crossWorkspaceReferences struct {
+	*admission.Handler
+	resolver *crossworkspace.Resolver
+}
+
+var _ admission.ValidationInterface = &crossWorkspaceReferences{}
+
+// Validate rejects objects whose crossworkspace.ReferencesAnnotation holds references which
+// are not granted.
+func (p *crossWorkspaceReferences) Validate(ctx context.Context, a admission.Attributes, _ admission.ObjectInterfaces) error {
+	if a.GetSubresource() != "" || a.GetObject() == nil {
+		return nil
+	}
+	obj, err := meta.Accessor(a.GetObject())
+	if err != nil {
+		// not an object with metadata
+		return nil
+	}
+	refs, err := crossworkspace.ReferencesFrom(obj)
+	if err != nil {
+		return admission.NewForbidden(a, err)
+	}
+	if len(refs) == 0 {
+		return nil
+	}
+
+	clusterName, err := genericapirequest.ClusterNameFrom(ctx)
+	if err != nil {
+		return admission.NewForbidden(a, err)
+	}
+	for _, ref := range refs {
+		allowed, err := p.resolver.Allowed(clusterName, ref)
+		if err != nil {
+			return admission.NewForbidden(a, err)
+		}
+		if !allowed {
+			gr := schema.GroupResource{Group: ref.Group, Resource: ref.Resource}
+			return admission.NewForbidden(a, fmt.Errorf("workspace %q did not grant references to %s %s/%s from workspace %q", ref.Workspace, gr, ref.Namespace, ref.Name, clusterName))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package externalpolicy lets one external policy engine, like OPA, validate the requests
+// to all logical clusters. Unlike the ValidatingWebhookConfigurations of a workspace, the
+// endpoint is configured once for the server, and is told the logical cluster and the
+// workspace of each request, so that organizations can enforce policies across workspaces.
+package externalpolicy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apiserver/pkg/admission"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/apiserver/pkg/warning"
+	"k8s.io/klog/v2"
+)
+
+// PluginName is the name of this admission plugin.
+const PluginName = "tenancy.kcp.dev/ExternalPolicy"
+
+// Register registers the plugin, validating requests with the given webhook. The plugin
+// doesn't handle any request when the webhook is disabled.
+func Register(plugins *admission.Plugins, webhook *Webhook) {
+	plugins.Register(PluginName, func(_ io.Reader) (admission.Interface, error) {
+		handler := admission.NewHandler()
+		if webhook.Enabled() {
+			handler = admission.NewHandler(admission.Create, admission.Update, admission.Delete, admission.Connect)
+		}
+		return &externalPolicy{
+			Handler: handler,
+			webhook: webhook,
+		}, nil
+	})
+}
+
+type externalPolicy struct {
+	*admission.Handler
+	webhook *Webhook
+}
+
+var _ admission.ValidationInterface = &externalPolicy{}
+
+// Validate rejects the requests denied by the external policy endpoint, and those which it
+// couldn't decide on when the failure policy is Fail.
+func (p *externalPolicy) Validate(ctx context.Context, a admission.Attributes, o admission.ObjectInterfaces) error {
+	clusterName, err := genericapirequest.ClusterNameFrom(ctx)
+	if err != nil {
+		return nil
+	}
+
+	response, err := p.webhook.review(ctx, clusterName, a, o)
+	if err != nil {
+		if p.webhook.failurePolicy == Ignore {
+			klog.Warningf("Ignoring failed external policy check of %s %s/%s in logical cluster %q: %v", a.GetResource().GroupResource(), a.GetNamespace(), a.GetName(), clusterName, err)
+			return nil
+		}
+		return apierrors.NewInternalError(fmt.Errorf("failed to check the external policy: %w", err))
+	}
+
+	for _, w := range response.Warnings {
+		warning.AddWarning(ctx, "", w)
+	}
+	if response.Allowed {
+		return nil
+	}
+	message := "denied by the external policy"
+	if response.Result != nil && response.Result.Message != "" {
+		message = fmt.Sprintf("%s: %s", message, response.Result.Message)
+	}
+	return admission.NewForbidden(a, errors.New(message))
+}
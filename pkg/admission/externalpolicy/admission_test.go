@@ -0,0 +1,121 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externalpolicy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/apiserver/pkg/authentication/user"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/tools/cache"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/indexers"
+)
+
+func TestValidate(t *testing.T) {
+	// the policy denies ConfigMaps named "denied" in workspaces of the "Production" type
+	var reviews []Review
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var review Review
+		if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		reviews = append(reviews, review)
+		if review.Request.Name == "broken" {
+			http.Error(w, "broken", http.StatusInternalServerError)
+			return
+		}
+		allowed := review.Request.Name != "denied" || review.Cluster.Workspace == nil || review.Cluster.Workspace.Type != "Production"
+		review.Response = &admissionv1.AdmissionResponse{UID: review.Request.UID, Allowed: allowed}
+		if !allowed {
+			review.Response.Result = &metav1.Status{Message: "no denied ConfigMaps in production"}
+		}
+		review.Request = nil
+		_ = json.NewEncoder(w).Encode(review.AdmissionReview)
+	}))
+	defer server.Close()
+
+	workspaceIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{indexers.WorkspaceCluster: indexers.IndexWorkspaceByCluster})
+	if err := workspaceIndexer.Add(&tenancyv1alpha1.Workspace{
+		ObjectMeta: metav1.ObjectMeta{Name: "prod", ClusterName: "root", Labels: map[string]string{"team": "a"}},
+		Spec:       tenancyv1alpha1.WorkspaceSpec{Type: "Production"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		cluster       string
+		configMap     string
+		failurePolicy FailurePolicy
+		wantErr       func(error) bool
+	}{
+		{name: "allowed", cluster: "prod", configMap: "allowed"},
+		{name: "denied", cluster: "prod", configMap: "denied", wantErr: apierrors.IsForbidden},
+		{name: "not a workspace", cluster: "other", configMap: "denied"},
+		{name: "failure", cluster: "prod", configMap: "broken", failurePolicy: Fail, wantErr: apierrors.IsInternalError},
+		{name: "ignored failure", cluster: "prod", configMap: "broken", failurePolicy: Ignore},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reviews = nil
+			p := &externalPolicy{webhook: &Webhook{
+				client:           server.Client(),
+				url:              server.URL,
+				failurePolicy:    tt.failurePolicy,
+				workspaceIndexer: workspaceIndexer,
+				hasSynced:        func() bool { return true },
+			}}
+			configMap := &corev1.ConfigMap{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+				ObjectMeta: metav1.ObjectMeta{Name: tt.configMap, Namespace: "default"},
+			}
+			attr := admission.NewAttributesRecord(configMap, nil, corev1.SchemeGroupVersion.WithKind("ConfigMap"), "default", tt.configMap, corev1.SchemeGroupVersion.WithResource("configmaps"), "", admission.Create, &metav1.CreateOptions{}, false, &user.DefaultInfo{Name: "alice"})
+			ctx := genericapirequest.WithCluster(context.Background(), genericapirequest.Cluster{Name: tt.cluster})
+
+			err := p.Validate(ctx, attr, admission.NewObjectInterfacesFromScheme(runtime.NewScheme()))
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != nil && !tt.wantErr(err) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(reviews) != 1 {
+				t.Fatalf("expected one review, got %d", len(reviews))
+			}
+			if got := reviews[0].Cluster.Name; got != tt.cluster {
+				t.Errorf("expected cluster %q, got %q", tt.cluster, got)
+			}
+			if workspace := reviews[0].Cluster.Workspace; tt.cluster == "prod" && (workspace == nil || workspace.Parent != "root" || workspace.Labels["team"] != "a") {
+				t.Errorf("unexpected workspace context: %#v", workspace)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externalpolicy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/apiserver/pkg/admission/plugin/webhook/generic"
+	"k8s.io/apiserver/pkg/admission/plugin/webhook/request"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	tenancyinformer "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/indexers"
+)
+
+// FailurePolicy defines what happens to requests when the policy endpoint can't be reached
+// or answers with an error.
+type FailurePolicy string
+
+const (
+	// Fail rejects the requests.
+	Fail FailurePolicy = "Fail"
+	// Ignore admits the requests.
+	Ignore FailurePolicy = "Ignore"
+)
+
+// ParseFailurePolicy returns the FailurePolicy of the given name.
+func ParseFailurePolicy(s string) (FailurePolicy, error) {
+	switch policy := FailurePolicy(s); policy {
+	case Fail, Ignore:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown failure policy %q, must be %s or %s", s, Fail, Ignore)
+	}
+}
+
+// Review is the body posted to the policy endpoint: an admission.k8s.io/v1 AdmissionReview,
+// with the kcp context of the request in the additional cluster field.
+type Review struct {
+	admissionv1.AdmissionReview `json:",inline"`
+
+	Cluster ClusterContext `json:"cluster"`
+}
+
+// ClusterContext describes the logical cluster a request is made to.
+type ClusterContext struct {
+	// Name is the name of the logical cluster.
+	Name string `json:"name"`
+	// Workspace is the workspace of the logical cluster, if it is one.
+	Workspace *WorkspaceContext `json:"workspace,omitempty"`
+}
+
+// WorkspaceContext holds the metadata of a workspace relevant to policies.
+type WorkspaceContext struct {
+	Name string `json:"name"`
+	// Parent is the logical cluster the Workspace object lives in.
+	Parent      string            `json:"parent"`
+	Type        string            `json:"type,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Webhook posts the admission requests of all logical clusters to one policy endpoint. The
+// workspace metadata is only sent once the Webhook is initialized with the Workspace
+// informer.
+type Webhook struct {
+	client        *http.Client
+	url           string
+	failurePolicy FailurePolicy
+
+	lock             sync.RWMutex
+	workspaceIndexer cache.Indexer
+	hasSynced        func() bool
+}
+
+// NewWebhook returns a Webhook for the endpoint configured as the server of the current
+// context of the given kubeconfig file, along with its credentials. Without a file, the
+// returned Webhook is disabled.
+func NewWebhook(kubeconfigFile string, timeout time.Duration, failurePolicy FailurePolicy) (*Webhook, error) {
+	if kubeconfigFile == "" {
+		return &Webhook{}, nil
+	}
+	config, err := clientcmd.BuildConfigFromFlags("", kubeconfigFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the external policy kubeconfig: %w", err)
+	}
+	transport, err := rest.TransportFor(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Webhook{
+		client:        &http.Client{Transport: transport, Timeout: timeout},
+		url:           config.Host,
+		failurePolicy: failurePolicy,
+	}, nil
+}
+
+// Enabled returns whether the Webhook has an endpoint to post requests to.
+func (w *Webhook) Enabled() bool {
+	return w.client != nil
+}
+
+// Initialize makes the Webhook send the metadata of workspaces from the given informer.
+func (w *Webhook) Initialize(workspaceInformer tenancyinformer.WorkspaceInformer) error {
+	if err := indexers.AddIfNotPresent(workspaceInformer.Informer().GetIndexer(), cache.Indexers{
+		indexers.WorkspaceCluster: indexers.IndexWorkspaceByCluster,
+	}); err != nil {
+		return fmt.Errorf("failed to add indexer for Workspace: %w", err)
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.workspaceIndexer = workspaceInformer.Informer().GetIndexer()
+	w.hasSynced = workspaceInformer.Informer().HasSynced
+	return nil
+}
+
+// clusterContext returns the context of the given logical cluster.
+func (w *Webhook) clusterContext(clusterName string) (ClusterContext, error) {
+	w.lock.RLock()
+	workspaceIndexer, hasSynced := w.workspaceIndexer, w.hasSynced
+	w.lock.RUnlock()
+
+	cluster := ClusterContext{Name: clusterName}
+	if hasSynced == nil {
+		return cluster, nil
+	}
+	if !hasSynced() {
+		return cluster, fmt.Errorf("workspaces are not synced yet")
+	}
+	workspaces, err := workspaceIndexer.ByIndex(indexers.WorkspaceCluster, clusterName)
+	if err != nil || len(workspaces) == 0 {
+		return cluster, err
+	}
+	workspace := workspaces[0].(*tenancyv1alpha1.Workspace)
+	cluster.Workspace = &WorkspaceContext{
+		Name:        workspace.Name,
+		Parent:      workspace.ClusterName,
+		Type:        workspace.Spec.Type,
+		Labels:      workspace.Labels,
+		Annotations: workspace.Annotations,
+	}
+	return cluster, nil
+}
+
+// review posts the request of the given attributes in the given logical cluster, and
+// returns the response of the endpoint.
+func (w *Webhook) review(ctx context.Context, clusterName string, a admission.Attributes, o admission.ObjectInterfaces) (*admissionv1.AdmissionResponse, error) {
+	cluster, err := w.clusterContext(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	versionedAttr, err := generic.NewVersionedAttributes(a, a.GetKind(), o)
+	if err != nil {
+		return nil, err
+	}
+	uid := uuid.NewUUID()
+	review := Review{
+		AdmissionReview: *request.CreateV1AdmissionReview(uid, versionedAttr, &generic.WebhookInvocation{
+			Resource:    a.GetResource(),
+			Subresource: a.GetSubresource(),
+			Kind:        a.GetKind(),
+		}),
+		Cluster: cluster,
+	}
+	review.APIVersion = admissionv1.SchemeGroupVersion.String()
+	review.Kind = "AdmissionReview"
+
+	body, err := json.Marshal(review)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("external policy endpoint answered with status %d", resp.StatusCode)
+	}
+
+	var result admissionv1.AdmissionReview
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode the answer of the external policy endpoint: %w", err)
+	}
+	if result.Response == nil {
+		return nil, fmt.Errorf("external policy endpoint answered without a response")
+	}
+	if result.Response.UID != uid {
+		return nil, fmt.Errorf("external policy endpoint answered to request %q instead of %q", result.Response.UID, uid)
+	}
+	return result.Response, nil
+}
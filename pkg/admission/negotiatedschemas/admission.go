@@ -0,0 +1,113 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package negotiatedschemas checks the objects of the APIs imported from physical clusters
+// against the schemas of the Clusters they can be synced to, so that users learn about the
+// fields some Clusters don't support when they create objects rather than when they are
+// synced.
+package negotiatedschemas
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apiserver/pkg/admission"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/apiserver/pkg/warning"
+)
+
+// PluginName is the name of this admission plugin.
+const PluginName = "apiresource.kcp.dev/NegotiatedSchemas"
+
+// Mode decides what happens to the objects using fields which are not supported by all
+// the Clusters they can be synced to.
+type Mode string
+
+const (
+	// ModeWarn admits the objects with a warning for each unsupported field.
+	ModeWarn Mode = "Warn"
+	// ModeReject rejects the objects.
+	ModeReject Mode = "Reject"
+)
+
+// ParseMode returns the mode of the given name.
+func ParseMode(s string) (Mode, error) {
+	switch mode := Mode(s); mode {
+	case ModeWarn, ModeReject:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown negotiated schema admission mode %q, must be %s or %s", s, ModeWarn, ModeReject)
+	}
+}
+
+// Register registers the plugin, checking objects against the given schemas.
+func Register(plugins *admission.Plugins, schemas *Schemas, mode Mode) {
+	plugins.Register(PluginName, func(_ io.Reader) (admission.Interface, error) {
+		return &negotiatedSchemas{
+			Handler: admission.NewHandler(admission.Create, admission.Update),
+			schemas: schemas,
+			mode:    mode,
+		}, nil
+	})
+}
+
+type negotiatedSchemas struct {
+	*admission.Handler
+	schemas *Schemas
+	mode    Mode
+}
+
+var _ admission.ValidationInterface = &negotiatedSchemas{}
+
+// Validate warns about, or rejects, the objects of imported APIs using fields the schema
+// imported from one of their Clusters doesn't allow. Objects labeled for a Cluster are
+// only checked against the schema of that Cluster.
+func (p *negotiatedSchemas) Validate(ctx context.Context, a admission.Attributes, _ admission.ObjectInterfaces) error {
+	if a.GetSubresource() != "" {
+		return nil
+	}
+	obj, ok := a.GetObject().(*unstructured.Unstructured)
+	if !ok {
+		// only the objects of CRDs can be of imported APIs
+		return nil
+	}
+	clusterName, err := genericapirequest.ClusterNameFrom(ctx)
+	if err != nil {
+		return nil
+	}
+
+	errs, err := p.schemas.Check(clusterName, a.GetResource(), obj)
+	if err != nil {
+		if p.mode == ModeReject {
+			return admission.NewForbidden(a, err)
+		}
+		warning.AddWarning(ctx, "", fmt.Sprintf("could not check the object against the schemas of its Clusters: %v", err))
+		return nil
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	if p.mode == ModeReject {
+		return apierrors.NewInvalid(a.GetKind().GroupKind(), a.GetName(), errs)
+	}
+	for _, err := range errs {
+		warning.AddWarning(ctx, "", err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package negotiatedschemas
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	structuralschema "k8s.io/apiextensions-apiserver/pkg/apiserver/schema"
+	apiservervalidation "k8s.io/apiextensions-apiserver/pkg/apiserver/validation"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/kube-openapi/pkg/validation/validate"
+
+	apiresourcev1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apiresource/v1alpha1"
+	clusterv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/cluster/v1alpha1"
+	apiresourceinformer "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/apiresource/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/indexers"
+)
+
+const importIndex = "negotiatedschemas-clusterNameAndGVR"
+
+func importKey(clusterName string, gvr metav1.GroupVersionResource) string {
+	return fmt.Sprintf("%s|%s/%s/%s", clusterName, gvr.Group, gvr.Version, gvr.Resource)
+}
+
+// Schemas holds the schemas of the API resources imported from the Clusters of every
+// logical cluster.
+type Schemas struct {
+	lock          sync.RWMutex
+	importIndexer cache.Indexer
+	hasSynced     cache.InformerSynced
+
+	// compiled caches the compiled schemas of the imports, by UID and resource version.
+	compiled     map[types.UID]*compiledSchema
+	compiledLock sync.Mutex
+}
+
+type compiledSchema struct {
+	resourceVersion string
+	structural      *structuralschema.Structural
+	validator       *validate.SchemaValidator
+}
+
+// NewSchemas returns Schemas which have to be initialized before use. Objects are not
+// checked until then.
+func NewSchemas() *Schemas {
+	return &Schemas{
+		compiled: map[types.UID]*compiledSchema{},
+	}
+}
+
+// Initialize makes the Schemas use the given cross-cluster APIResourceImport informer.
+func (s *Schemas) Initialize(importInformer apiresourceinformer.APIResourceImportInformer) error {
+	if err := indexers.AddIfNotPresent(importInformer.Informer().GetIndexer(), cache.Indexers{
+		importIndex: func(obj interface{}) ([]string, error) {
+			if apiResourceImport, ok := obj.(*apiresourcev1alpha1.APIResourceImport); ok {
+				return []string{importKey(apiResourceImport.ClusterName, apiResourceImport.GVR())}, nil
+			}
+			return []string{}, nil
+		},
+	}); err != nil {
+		return fmt.Errorf("failed to add indexer for APIResourceImport: %w", err)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.importIndexer = importInformer.Informer().GetIndexer()
+	s.hasSynced = importInformer.Informer().HasSynced
+	return nil
+}
+
+// Check returns the fields of the object which are not allowed by the schema of the
+// resource imported from any of the Clusters it can be synced to: the Cluster it is
+// labeled for, or all the Clusters the resource was imported from.
+func (s *Schemas) Check(clusterName string, gvr schema.GroupVersionResource, obj *unstructured.Unstructured) (field.ErrorList, error) {
+	s.lock.RLock()
+	importIndexer, hasSynced := s.importIndexer, s.hasSynced
+	s.lock.RUnlock()
+	if importIndexer == nil {
+		return nil, nil
+	}
+	if !hasSynced() {
+		return nil, fmt.Errorf("API resource imports are not synced yet")
+	}
+
+	imports, err := importIndexer.ByIndex(importIndex, importKey(clusterName, metav1.GroupVersionResource{Group: gvr.Group, Version: gvr.Version, Resource: gvr.Resource}))
+	if err != nil {
+		return nil, err
+	}
+	placedOn := obj.GetLabels()[clusterv1alpha1.ClusterLabel]
+	sort.Slice(imports, func(i, j int) bool {
+		return imports[i].(*apiresourcev1alpha1.APIResourceImport).Spec.Location < imports[j].(*apiresourcev1alpha1.APIResourceImport).Spec.Location
+	})
+
+	var errs field.ErrorList
+	content := obj.UnstructuredContent()
+	for _, importObj := range imports {
+		apiResourceImport := importObj.(*apiresourcev1alpha1.APIResourceImport)
+		location := apiResourceImport.Spec.Location
+		if placedOn != "" && location != placedOn {
+			continue
+		}
+		compiled, err := s.compile(apiResourceImport)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schema imported from Cluster %q: %w", location, err)
+		}
+		for _, err := range append(unknownFields(nil, content, compiled.structural, true), apiservervalidation.ValidateCustomResource(nil, content, compiled.validator)...) {
+			err.Detail = fmt.Sprintf("not supported by Cluster %q", location) + detailSuffix(err.Detail)
+			errs = append(errs, err)
+		}
+	}
+	return errs, nil
+}
+
+func detailSuffix(detail string) string {
+	if detail == "" {
+		return ""
+	}
+	return ": " + detail
+}
+
+// compile returns the structural schema and the validator of the schema of the import.
+func (s *Schemas) compile(apiResourceImport *apiresourcev1alpha1.APIResourceImport) (*compiledSchema, error) {
+	s.compiledLock.Lock()
+	defer s.compiledLock.Unlock()
+	if compiled, found := s.compiled[apiResourceImport.UID]; found && compiled.resourceVersion == apiResourceImport.ResourceVersion {
+		return compiled, nil
+	}
+
+	v1Schema, err := apiResourceImport.Spec.GetSchema()
+	if err != nil {
+		return nil, err
+	}
+	internalSchema := &apiextensions.JSONSchemaProps{}
+	if err := apiextensionsv1.Convert_v1_JSONSchemaProps_To_apiextensions_JSONSchemaProps(v1Schema, internalSchema, nil); err != nil {
+		return nil, err
+	}
+	structural, err := structuralschema.NewStructural(internalSchema)
+	if err != nil {
+		return nil, err
+	}
+	validator, _, err := apiservervalidation.NewSchemaValidator(&apiextensions.CustomResourceValidation{OpenAPIV3Schema: internalSchema})
+	if err != nil {
+		return nil, err
+	}
+	compiled := &compiledSchema{
+		resourceVersion: apiResourceImport.ResourceVersion,
+		structural:      structural,
+		validator:       validator,
+	}
+	s.compiled[apiResourceImport.UID] = compiled
+	return compiled, nil
+}
+
+// unknownFields returns the fields of the value which are not specified by the schema,
+// and would be pruned, like the API server does for the objects of CRDs.
+func unknownFields(fldPath *field.Path, x interface{}, s *structuralschema.Structural, isResourceRoot bool) field.ErrorList {
+	if s == nil {
+		return nil
+	}
+	var errs field.ErrorList
+	switch x := x.(type) {
+	case map[string]interface{}:
+		for k, v := range x {
+			if (isResourceRoot || s.XEmbeddedResource) && (k == "apiVersion" || k == "kind" || k == "metadata") {
+				continue
+			}
+			if prop, found := s.Properties[k]; found {
+				errs = append(errs, unknownFields(fldPath.Child(k), v, &prop, false)...)
+				continue
+			}
+			if s.AdditionalProperties != nil {
+				if s.AdditionalProperties.Structural != nil {
+					errs = append(errs, unknownFields(fldPath.Key(k), v, s.AdditionalProperties.Structural, false)...)
+				}
+				continue
+			}
+			if s.XPreserveUnknownFields {
+				continue
+			}
+			errs = append(errs, field.Forbidden(fldPath.Child(k), ""))
+		}
+	case []interface{}:
+		for i, v := range x {
+			errs = append(errs, unknownFields(fldPath.Index(i), v, s.Items, false)...)
+		}
+	}
+	return errs
+}
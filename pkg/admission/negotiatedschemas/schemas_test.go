@@ -0,0 +1,133 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package negotiatedschemas
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+
+	apiresourcev1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apiresource/v1alpha1"
+	clusterv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/cluster/v1alpha1"
+)
+
+func TestCheck(t *testing.T) {
+	maxReplicas := float64(10)
+	newImport := func(location string, spec map[string]apiextensionsv1.JSONSchemaProps) *apiresourcev1alpha1.APIResourceImport {
+		apiResourceImport := &apiresourcev1alpha1.APIResourceImport{
+			ObjectMeta: metav1.ObjectMeta{ClusterName: "tenant", Name: "widgets." + location, UID: types.UID(location), ResourceVersion: "1"},
+			Spec: apiresourcev1alpha1.APIResourceImportSpec{
+				CommonAPIResourceSpec: apiresourcev1alpha1.CommonAPIResourceSpec{
+					GroupVersion:                  apiresourcev1alpha1.GroupVersion{Group: "example.com", Version: "v1"},
+					CustomResourceDefinitionNames: apiextensionsv1.CustomResourceDefinitionNames{Plural: "widgets"},
+				},
+				Location: location,
+			},
+		}
+		if err := apiResourceImport.Spec.SetSchema(&apiextensionsv1.JSONSchemaProps{
+			Type: "object",
+			Properties: map[string]apiextensionsv1.JSONSchemaProps{
+				"spec":  {Type: "object", Properties: spec},
+				"extra": {Type: "object", XPreserveUnknownFields: boolPtr(true)},
+			},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return apiResourceImport
+	}
+
+	schemas := NewSchemas()
+	schemas.importIndexer = cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		importIndex: func(obj interface{}) ([]string, error) {
+			apiResourceImport := obj.(*apiresourcev1alpha1.APIResourceImport)
+			return []string{importKey(apiResourceImport.ClusterName, apiResourceImport.GVR())}, nil
+		},
+	})
+	schemas.hasSynced = func() bool { return true }
+	for _, apiResourceImport := range []*apiresourcev1alpha1.APIResourceImport{
+		newImport("east", map[string]apiextensionsv1.JSONSchemaProps{
+			"replicas": {Type: "integer", Maximum: &maxReplicas},
+			"paused":   {Type: "boolean"},
+		}),
+		newImport("west", map[string]apiextensionsv1.JSONSchemaProps{
+			"replicas": {Type: "integer"},
+		}),
+	} {
+		if err := schemas.importIndexer.Add(apiResourceImport); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gvr := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+	newWidget := func(placedOn string) *unstructured.Unstructured {
+		widget := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "example.com/v1",
+			"kind":       "Widget",
+			"metadata":   map[string]interface{}{"name": "foo", "namespace": "default"},
+			"spec":       map[string]interface{}{"replicas": int64(20), "paused": true},
+			"extra":      map[string]interface{}{"anything": "goes"},
+		}}
+		if placedOn != "" {
+			widget.SetLabels(map[string]string{clusterv1alpha1.ClusterLabel: placedOn})
+		}
+		return widget
+	}
+
+	tests := []struct {
+		name     string
+		cluster  string
+		placedOn string
+		want     []string
+	}{
+		{name: "any Cluster", cluster: "tenant", want: []string{
+			`spec.paused: Forbidden: not supported by Cluster "west"`,
+			`spec.replicas: Invalid value: 20: not supported by Cluster "east": spec.replicas in body should be less than or equal to 10`,
+		}},
+		{name: "placed", cluster: "tenant", placedOn: "west", want: []string{
+			`spec.paused: Forbidden: not supported by Cluster "west"`,
+		}},
+		{name: "not imported", cluster: "other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs, err := schemas.Check(tt.cluster, gvr, newWidget(tt.placedOn))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, err := range errs {
+				got = append(got, err.Error())
+			}
+			sort.Strings(got)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("unexpected errors (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package objectlimits rejects objects larger than the limits of their workspace, so that
+// a single tenant can't fill the storage shared by all workspaces with giant objects.
+package objectlimits
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/apiserver/pkg/admission"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+
+	"github.com/kcp-dev/kcp/pkg/reconciler/workspacelimits"
+)
+
+// PluginName is the name of this admission plugin.
+const PluginName = "tenancy.kcp.dev/ObjectLimits"
+
+// Register registers the plugin, enforcing the limits of the given registry.
+func Register(plugins *admission.Plugins, registry *workspacelimits.Registry) {
+	plugins.Register(PluginName, func(_ io.Reader) (admission.Interface, error) {
+		return &objectLimits{
+			Handler:  admission.NewHandler(admission.Create, admission.Update),
+			registry: registry,
+		}, nil
+	})
+}
+
+type objectLimits struct {
+	*admission.Handler
+	registry *workspacelimits.Registry
+}
+
+var _ admission.ValidationInterface = &objectLimits{}
+
+// Validate rejects the objects whose size, managedFields or annotations exceed the limits
+// of their logical cluster.
+func (p *objectLimits) Validate(ctx context.Context, a admission.Attributes, _ admission.ObjectInterfaces) error {
+	if a.GetObject() == nil {
+		return nil
+	}
+	obj, err := meta.Accessor(a.GetObject())
+	if err != nil {
+		// not an object with metadata
+		return nil
+	}
+	clusterName, err := genericapirequest.ClusterNameFrom(ctx)
+	if err != nil {
+		return nil
+	}
+	limits := p.registry.Limits(clusterName)
+
+	if limits.MaxObjectSize > 0 {
+		data, err := json.Marshal(a.GetObject())
+		if err != nil {
+			return admission.NewForbidden(a, err)
+		}
+		if size := int64(len(data)); size > limits.MaxObjectSize {
+			return apierrors.NewRequestEntityTooLargeError(fmt.Sprintf("%s %q is %d bytes, more than the %d bytes allowed in workspace %q", a.GetResource().GroupResource(), a.GetName(), size, limits.MaxObjectSize, clusterName))
+		}
+	}
+
+	var errs field.ErrorList
+	if limits.MaxManagedFieldsSize > 0 {
+		data, err := json.Marshal(obj.GetManagedFields())
+		if err != nil {
+			return admission.NewForbidden(a, err)
+		}
+		if size := int64(len(data)); size > limits.MaxManagedFieldsSize {
+			errs = append(errs, field.TooLong(field.NewPath("metadata", "managedFields"), "", int(limits.MaxManagedFieldsSize)))
+		}
+	}
+	if limits.MaxAnnotationsSize > 0 {
+		var size int64
+		for k, v := range obj.GetAnnotations() {
+			size += int64(len(k)) + int64(len(v))
+		}
+		if size > limits.MaxAnnotationsSize {
+			errs = append(errs, field.TooLong(field.NewPath("metadata", "annotations"), "", int(limits.MaxAnnotationsSize)))
+		}
+	}
+	if len(errs) > 0 {
+		return apierrors.NewInvalid(a.GetKind().GroupKind(), a.GetName(), errs)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package objectlimits
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/admission"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+
+	"github.com/kcp-dev/kcp/pkg/reconciler/workspacelimits"
+)
+
+func TestValidate(t *testing.T) {
+	p := &objectLimits{registry: workspacelimits.NewRegistry(workspacelimits.Limits{
+		MaxObjectSize:        1024,
+		MaxManagedFieldsSize: 128,
+		MaxAnnotationsSize:   64,
+	})}
+
+	tests := []struct {
+		name      string
+		configMap *corev1.ConfigMap
+		wantErr   func(error) bool
+	}{
+		{
+			name:      "small",
+			configMap: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "small", Annotations: map[string]string{"a": "b"}}, Data: map[string]string{"key": "value"}},
+		},
+		{
+			name:      "large object",
+			configMap: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "large"}, Data: map[string]string{"key": strings.Repeat("x", 2048)}},
+			wantErr:   apierrors.IsRequestEntityTooLargeError,
+		},
+		{
+			name:      "large annotations",
+			configMap: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "annotated", Annotations: map[string]string{"a": strings.Repeat("x", 64)}}},
+			wantErr:   apierrors.IsInvalid,
+		},
+		{
+			name: "large managedFields",
+			configMap: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "managed", ManagedFields: []metav1.ManagedFieldsEntry{
+				{Manager: strings.Repeat("m", 64), Operation: metav1.ManagedFieldsOperationApply},
+				{Manager: strings.Repeat("n", 64), Operation: metav1.ManagedFieldsOperationApply},
+			}}},
+			wantErr: apierrors.IsInvalid,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := genericapirequest.WithCluster(context.Background(), genericapirequest.Cluster{Name: "tenant"})
+			attr := admission.NewAttributesRecord(tt.configMap, nil, corev1.SchemeGroupVersion.WithKind("ConfigMap"), "default", tt.configMap.Name, corev1.SchemeGroupVersion.WithResource("configmaps"), "", admission.Create, nil, false, nil)
+			err := p.Validate(ctx, attr, nil)
+			switch {
+			case tt.wantErr == nil && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantErr != nil && !tt.wantErr(err):
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package placementdefaults labels created objects for the Cluster of their namespace or
+// of their Placement, so that users don't have to label every object they create.
+package placementdefaults
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apiserver/pkg/admission"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/klog/v2"
+
+	"github.com/kcp-dev/kcp/pkg/reconciler/placement"
+)
+
+// PluginName is the name of this admission plugin.
+const PluginName = "cluster.kcp.dev/PlacementDefaults"
+
+// Register registers the plugin, choosing the Clusters with the given defaulter.
+func Register(plugins *admission.Plugins, defaulter *placement.Defaulter) {
+	plugins.Register(PluginName, func(_ io.Reader) (admission.Interface, error) {
+		return &placementDefaults{
+			Handler:   admission.NewHandler(admission.Create),
+			defaulter: defaulter,
+		}, nil
+	})
+}
+
+type placementDefaults struct {
+	*admission.Handler
+	defaulter *placement.Defaulter
+}
+
+var _ admission.MutationInterface = &placementDefaults{}
+
+// Admit sets the cluster label of the created objects which don't have one, when their
+// Cluster is known already. Objects are admitted unlabeled if it isn't, and are left to
+// the Placement controllers.
+func (p *placementDefaults) Admit(ctx context.Context, a admission.Attributes, _ admission.ObjectInterfaces) error {
+	if a.GetSubresource() != "" || a.GetObject() == nil {
+		return nil
+	}
+	obj, err := meta.Accessor(a.GetObject())
+	if err != nil {
+		// not an object with metadata
+		return nil
+	}
+	clusterName, err := genericapirequest.ClusterNameFrom(ctx)
+	if err != nil {
+		return nil
+	}
+
+	if _, err := p.defaulter.Default(clusterName, a.GetResource().GroupResource(), obj); err != nil {
+		if !errors.Is(err, placement.ErrDefaulterNotInitialized) {
+			klog.V(2).Infof("Not defaulting the Cluster of %s %s/%s: %v", a.GetResource().GroupResource(), a.GetNamespace(), a.GetName(), err)
+		}
+	}
+	return nil
+}
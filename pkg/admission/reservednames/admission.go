@@ -0,0 +1,157 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package reservednames keeps users from creating workspaces and logical clusters with the
+// names reserved by kcp, and from changing the system-managed objects of the bootstrap
+// workspaces.
+package reservednames
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/apiserver/pkg/authentication/user"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/kubernetes/pkg/genericcontrolplane"
+
+	"github.com/kcp-dev/kcp/config"
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/reconciler/bootstrap"
+)
+
+// PluginName is the name of this admission plugin.
+const PluginName = "tenancy.kcp.dev/ReservedNames"
+
+// shardSeparator separates the identifier of a shard from the name of its logical clusters,
+// like in the name of the root logical cluster. Workspaces can't be named like them.
+const shardSeparator = "---"
+
+var (
+	// ReservedNames are the names of the logical clusters of kcp itself.
+	ReservedNames = []string{genericcontrolplane.RootClusterName, "root", "system"}
+
+	// DefaultProtectedPrefixes are the prefixes of the names of system workspaces.
+	DefaultProtectedPrefixes = []string{"system-", "kcp-"}
+
+	// systemFieldManagers apply the system-managed objects of the bootstrap workspaces.
+	systemFieldManagers = []string{config.DefaultFieldManager, bootstrap.FieldManager}
+)
+
+// LogicalClusters tells the names of the existing logical clusters, which workspaces can't
+// be named like, not to shadow them in the paths of requests.
+type LogicalClusters interface {
+	IsLogicalCluster(name string) (bool, error)
+}
+
+// Register registers the plugin, reserving the names starting with one of the given
+// prefixes and the names of the given logical clusters in addition to the ReservedNames.
+func Register(plugins *admission.Plugins, protectedPrefixes []string, clusters LogicalClusters) {
+	plugins.Register(PluginName, func(_ io.Reader) (admission.Interface, error) {
+		return &reservedNames{
+			Handler:           admission.NewHandler(admission.Create, admission.Update, admission.Delete),
+			protectedPrefixes: protectedPrefixes,
+			clusters:          clusters,
+		}, nil
+	})
+}
+
+type reservedNames struct {
+	*admission.Handler
+	protectedPrefixes []string
+	clusters          LogicalClusters
+}
+
+var _ admission.ValidationInterface = &reservedNames{}
+
+// Validate rejects, unless the user is privileged:
+//
+//   - Workspaces with a reserved name or the name of an existing logical cluster,
+//   - objects created in logical clusters with a reserved name, which would create them,
+//   - changes to the objects applied by the bootstrappers of kcp.
+func (p *reservedNames) Validate(ctx context.Context, a admission.Attributes, _ admission.ObjectInterfaces) error {
+	if isPrivileged(a.GetUserInfo()) {
+		return nil
+	}
+
+	switch a.GetOperation() {
+	case admission.Create:
+		if a.GetResource().GroupResource() == tenancyv1alpha1.Resource("workspaces") && a.GetSubresource() == "" && (strings.Contains(a.GetName(), shardSeparator) || p.isReserved(a.GetName())) {
+			return admission.NewForbidden(a, fmt.Errorf("workspace name %q is reserved", a.GetName()))
+		}
+		if a.GetResource().GroupResource() == tenancyv1alpha1.Resource("workspaces") && a.GetSubresource() == "" && p.clusters != nil {
+			exists, err := p.clusters.IsLogicalCluster(a.GetName())
+			if err != nil {
+				return admission.NewForbidden(a, err)
+			}
+			if exists {
+				return admission.NewForbidden(a, fmt.Errorf("workspace name %q is the name of a logical cluster", a.GetName()))
+			}
+		}
+		if cluster := genericapirequest.ClusterFrom(ctx); cluster != nil && !cluster.Wildcard && p.isReserved(cluster.Name) {
+			return admission.NewForbidden(a, fmt.Errorf("logical cluster %q is reserved", cluster.Name))
+		}
+	case admission.Update, admission.Delete:
+		if a.GetOldObject() == nil {
+			return nil
+		}
+		old, err := meta.Accessor(a.GetOldObject())
+		if err != nil {
+			// not an object with metadata
+			return nil
+		}
+		for _, managedFields := range old.GetManagedFields() {
+			for _, manager := range systemFieldManagers {
+				if managedFields.Manager == manager {
+					return admission.NewForbidden(a, fmt.Errorf("%s %q is managed by the system", a.GetResource().Resource, a.GetName()))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// isReserved returns whether the name of a workspace or logical cluster is reserved. The
+// reserved names are also reserved as sanitized for any shard, like "<id>---admin" for the
+// root logical cluster.
+func (p *reservedNames) isReserved(name string) bool {
+	for _, reserved := range ReservedNames {
+		if name == reserved || strings.HasSuffix(name, shardSeparator+reserved) {
+			return true
+		}
+	}
+	for _, prefix := range p.protectedPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func isPrivileged(u user.Info) bool {
+	if u == nil {
+		return false
+	}
+	for _, group := range u.GetGroups() {
+		if group == user.SystemPrivilegedGroup {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reservednames
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/apiserver/pkg/authentication/user"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/kubernetes/pkg/genericcontrolplane"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/reconciler/bootstrap"
+)
+
+type logicalClusters []string
+
+func (c logicalClusters) IsLogicalCluster(name string) (bool, error) {
+	for _, cluster := range c {
+		if cluster == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestValidate(t *testing.T) {
+	p := &reservedNames{protectedPrefixes: DefaultProtectedPrefixes, clusters: logicalClusters{"k7c2q9x4"}}
+	alice := &user.DefaultInfo{Name: "alice"}
+	admin := &user.DefaultInfo{Name: "admin", Groups: []string{user.SystemPrivilegedGroup}}
+	workspace := func(name string) runtime.Object {
+		return &tenancyv1alpha1.Workspace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	}
+	systemConfigMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Name:          "settings",
+		ManagedFields: []metav1.ManagedFieldsEntry{{Manager: bootstrap.FieldManager}},
+	}}
+
+	for _, tc := range []struct {
+		name      string
+		cluster   string
+		attr      admission.Attributes
+		forbidden bool
+	}{
+		{
+			name:    "workspace",
+			cluster: "org",
+			attr:    admission.NewAttributesRecord(workspace("team"), nil, tenancyv1alpha1.Kind("Workspace").WithVersion("v1alpha1"), "", "team", tenancyv1alpha1.SchemeGroupVersion.WithResource("workspaces"), "", admission.Create, nil, false, alice),
+		},
+		{
+			name:      "reserved workspace",
+			cluster:   "org",
+			attr:      admission.NewAttributesRecord(workspace("admin"), nil, tenancyv1alpha1.Kind("Workspace").WithVersion("v1alpha1"), "", "admin", tenancyv1alpha1.SchemeGroupVersion.WithResource("workspaces"), "", admission.Create, nil, false, alice),
+			forbidden: true,
+		},
+		{
+			name:      "protected workspace",
+			cluster:   "org",
+			attr:      admission.NewAttributesRecord(workspace("system-billing"), nil, tenancyv1alpha1.Kind("Workspace").WithVersion("v1alpha1"), "", "system-billing", tenancyv1alpha1.SchemeGroupVersion.WithResource("workspaces"), "", admission.Create, nil, false, alice),
+			forbidden: true,
+		},
+		{
+			name:      "workspace named after a logical cluster",
+			cluster:   "org",
+			attr:      admission.NewAttributesRecord(workspace("k7c2q9x4"), nil, tenancyv1alpha1.Kind("Workspace").WithVersion("v1alpha1"), "", "k7c2q9x4", tenancyv1alpha1.SchemeGroupVersion.WithResource("workspaces"), "", admission.Create, nil, false, alice),
+			forbidden: true,
+		},
+		{
+			name:    "privileged",
+			cluster: "org",
+			attr:    admission.NewAttributesRecord(workspace("system-billing"), nil, tenancyv1alpha1.Kind("Workspace").WithVersion("v1alpha1"), "", "system-billing", tenancyv1alpha1.SchemeGroupVersion.WithResource("workspaces"), "", admission.Create, nil, false, admin),
+		},
+		{
+			name:      "protected logical cluster",
+			cluster:   "kcp-shards",
+			attr:      admission.NewAttributesRecord(&corev1.ConfigMap{}, nil, corev1.SchemeGroupVersion.WithKind("ConfigMap"), "default", "cm", corev1.SchemeGroupVersion.WithResource("configmaps"), "", admission.Create, nil, false, alice),
+			forbidden: true,
+		},
+		{
+			name:      "root logical cluster",
+			cluster:   genericcontrolplane.SanitizedClusterName("https://10.0.0.1:6443", genericcontrolplane.RootClusterName),
+			attr:      admission.NewAttributesRecord(&corev1.ConfigMap{}, nil, corev1.SchemeGroupVersion.WithKind("ConfigMap"), "default", "cm", corev1.SchemeGroupVersion.WithResource("configmaps"), "", admission.Create, nil, false, alice),
+			forbidden: true,
+		},
+		{
+			name:    "privileged in the root logical cluster",
+			cluster: genericcontrolplane.SanitizedClusterName("https://10.0.0.1:6443", genericcontrolplane.RootClusterName),
+			attr:    admission.NewAttributesRecord(&corev1.ConfigMap{}, nil, corev1.SchemeGroupVersion.WithKind("ConfigMap"), "default", "cm", corev1.SchemeGroupVersion.WithResource("configmaps"), "", admission.Create, nil, false, admin),
+		},
+		{
+			name:      "system-managed",
+			cluster:   "org",
+			attr:      admission.NewAttributesRecord(nil, systemConfigMap, corev1.SchemeGroupVersion.WithKind("ConfigMap"), "default", "settings", corev1.SchemeGroupVersion.WithResource("configmaps"), "", admission.Delete, nil, false, alice),
+			forbidden: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := genericapirequest.WithCluster(context.Background(), genericapirequest.Cluster{Name: tc.cluster})
+			err := p.Validate(ctx, tc.attr, nil)
+			if forbidden := err != nil; forbidden != tc.forbidden {
+				t.Errorf("expected forbidden=%v, got %v", tc.forbidden, err)
+			}
+		})
+	}
+}
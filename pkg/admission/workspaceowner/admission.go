@@ -0,0 +1,137 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package workspaceowner records the user creating a Workspace, so that the workspace can be
+// initialized with RBAC objects granting that user access to it.
+package workspaceowner
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/apiserver/pkg/authentication/user"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+)
+
+const (
+	// PluginName is the name of this admission plugin.
+	PluginName = "tenancy.kcp.dev/WorkspaceOwner"
+
+	// OwnerAnnotation is set on every Workspace to the name of the user who created it.
+	OwnerAnnotation = "tenancy.kcp.dev/owner"
+)
+
+// Register registers the plugin.
+func Register(plugins *admission.Plugins) {
+	plugins.Register(PluginName, func(_ io.Reader) (admission.Interface, error) {
+		return &workspaceOwner{
+			Handler: admission.NewHandler(admission.Create, admission.Update),
+		}, nil
+	})
+}
+
+type workspaceOwner struct {
+	*admission.Handler
+}
+
+var _ admission.MutationInterface = &workspaceOwner{}
+
+// reservedAnnotations are set by the rename of workspaces and the creation of home
+// workspaces only.
+var reservedAnnotations = []string{tenancyv1alpha1.WorkspaceRenamedFromAnnotation, tenancyv1alpha1.WorkspaceRenamedToAnnotation, tenancyv1alpha1.WorkspaceHomeOfAnnotation}
+
+// Admit sets the owner annotation of created Workspaces to the requesting user, and keeps
+// it from being changed afterwards. Workspaces created by a rename keep the owner of the
+// renamed workspace, and home workspaces are owned by their user; the annotations of renames
+// and home workspaces can only be set by privileged users.
+func (o *workspaceOwner) Admit(_ context.Context, a admission.Attributes, _ admission.ObjectInterfaces) error {
+	if a.GetResource().GroupResource() != tenancyv1alpha1.Resource("workspaces") || a.GetSubresource() != "" {
+		return nil
+	}
+
+	obj, err := meta.Accessor(a.GetObject())
+	if err != nil {
+		return fmt.Errorf("unexpected workspace object %T: %w", a.GetObject(), err)
+	}
+	annotations := obj.GetAnnotations()
+	privileged := isPrivileged(a.GetUserInfo())
+	var owner string
+	switch a.GetOperation() {
+	case admission.Create:
+		for _, key := range reservedAnnotations {
+			if _, found := annotations[key]; found && !privileged {
+				return admission.NewForbidden(a, fmt.Errorf("the %s annotation is set by kcp only", key))
+			}
+		}
+		if _, renamed := annotations[tenancyv1alpha1.WorkspaceRenamedFromAnnotation]; renamed && annotations[OwnerAnnotation] != "" {
+			owner = annotations[OwnerAnnotation]
+		} else if home := annotations[tenancyv1alpha1.WorkspaceHomeOfAnnotation]; home != "" {
+			owner = home
+		} else if a.GetUserInfo() != nil {
+			owner = a.GetUserInfo().GetName()
+		}
+	case admission.Update:
+		old, err := meta.Accessor(a.GetOldObject())
+		if err != nil {
+			return fmt.Errorf("unexpected workspace object %T: %w", a.GetOldObject(), err)
+		}
+		owner = old.GetAnnotations()[OwnerAnnotation]
+		for _, key := range reservedAnnotations {
+			if value, found := annotations[key]; !privileged && (found != hasAnnotation(old, key) || value != old.GetAnnotations()[key]) {
+				return admission.NewForbidden(a, fmt.Errorf("the %s annotation is set by kcp only", key))
+			}
+		}
+	}
+
+	if owner == "" {
+		if _, found := annotations[OwnerAnnotation]; found {
+			delete(annotations, OwnerAnnotation)
+			obj.SetAnnotations(annotations)
+		}
+		return nil
+	}
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[OwnerAnnotation] = owner
+	obj.SetAnnotations(annotations)
+	return nil
+}
+
+func hasAnnotation(obj metav1.Object, key string) bool {
+	_, found := obj.GetAnnotations()[key]
+	return found
+}
+
+// isPrivileged returns whether the user may rename workspaces and create home workspaces on
+// behalf of others: the server does both through its loopback client, whose user is in
+// system:masters.
+func isPrivileged(info user.Info) bool {
+	if info == nil {
+		return false
+	}
+	for _, group := range info.GetGroups() {
+		if group == user.SystemPrivilegedGroup {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workspaceowner
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/apiserver/pkg/authentication/user"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+)
+
+func TestAdmit(t *testing.T) {
+	alice := &user.DefaultInfo{Name: "alice"}
+	bob := &user.DefaultInfo{Name: "bob"}
+	loopback := &user.DefaultInfo{Name: "system:apiserver", Groups: []string{user.SystemPrivilegedGroup}}
+	workspace := func(annotations map[string]string) *tenancyv1alpha1.Workspace {
+		return &tenancyv1alpha1.Workspace{ObjectMeta: metav1.ObjectMeta{Name: "team", Annotations: annotations}}
+	}
+
+	for _, tc := range []struct {
+		name          string
+		operation     admission.Operation
+		obj, old      *tenancyv1alpha1.Workspace
+		user          user.Info
+		expectedOwner string
+		expectedError bool
+	}{
+		{
+			name:          "create",
+			operation:     admission.Create,
+			obj:           workspace(nil),
+			user:          alice,
+			expectedOwner: "alice",
+		},
+		{
+			name:          "create with a spoofed owner",
+			operation:     admission.Create,
+			obj:           workspace(map[string]string{OwnerAnnotation: "alice"}),
+			user:          bob,
+			expectedOwner: "bob",
+		},
+		{
+			name:          "update of the owner",
+			operation:     admission.Update,
+			obj:           workspace(map[string]string{OwnerAnnotation: "bob"}),
+			old:           workspace(map[string]string{OwnerAnnotation: "alice"}),
+			user:          bob,
+			expectedOwner: "alice",
+		},
+		{
+			name:          "update removing the owner",
+			operation:     admission.Update,
+			obj:           workspace(nil),
+			old:           workspace(map[string]string{OwnerAnnotation: "alice"}),
+			user:          bob,
+			expectedOwner: "alice",
+		},
+		{
+			name:          "create of a home workspace",
+			operation:     admission.Create,
+			obj:           workspace(map[string]string{tenancyv1alpha1.WorkspaceHomeOfAnnotation: "alice"}),
+			user:          loopback,
+			expectedOwner: "alice",
+		},
+		{
+			name:          "create of a home workspace by an unprivileged user",
+			operation:     admission.Create,
+			obj:           workspace(map[string]string{tenancyv1alpha1.WorkspaceHomeOfAnnotation: "alice"}),
+			user:          bob,
+			expectedError: true,
+		},
+		{
+			name:          "create of a renamed workspace by an unprivileged user",
+			operation:     admission.Create,
+			obj:           workspace(map[string]string{tenancyv1alpha1.WorkspaceRenamedFromAnnotation: "squad", OwnerAnnotation: "alice"}),
+			user:          bob,
+			expectedError: true,
+		},
+		{
+			name:          "update of a reserved annotation by an unprivileged user",
+			operation:     admission.Update,
+			obj:           workspace(map[string]string{OwnerAnnotation: "alice", tenancyv1alpha1.WorkspaceRenamedToAnnotation: "squad"}),
+			old:           workspace(map[string]string{OwnerAnnotation: "alice"}),
+			user:          alice,
+			expectedError: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var old runtime.Object
+			if tc.old != nil {
+				old = tc.old
+			}
+			attr := admission.NewAttributesRecord(tc.obj, old, tenancyv1alpha1.Kind("Workspace").WithVersion("v1alpha1"), "", tc.obj.Name, tenancyv1alpha1.SchemeGroupVersion.WithResource("workspaces"), "", tc.operation, nil, false, tc.user)
+			err := (&workspaceOwner{}).Admit(context.Background(), attr, nil)
+			if tc.expectedError {
+				if err == nil {
+					t.Fatal("expected the request to be forbidden")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if owner := tc.obj.Annotations[OwnerAnnotation]; owner != tc.expectedOwner {
+				t.Errorf("expected owner %q, got %q", tc.expectedOwner, owner)
+			}
+		})
+	}
+}
@@ -26,6 +26,7 @@ import (
 // +crd
 // +genclient
 // +genclient:nonNamespaced
+// +genclient:skipVerbs=apply,applyStatus
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:scope=Cluster,categories=kcp
@@ -140,6 +141,17 @@ type APIResourceImportCondition struct {
 // APIResourceImportStatus communicates the observed state of the APIResourceImport (from the controller).
 type APIResourceImportStatus struct {
 	Conditions []APIResourceImportCondition `json:"conditions,omitempty"`
+
+	// DroppedFields are the paths of the fields of the imported schema which are not in the
+	// negotiated schema, because other locations don't support them. They are pruned in kcp,
+	// and so never synced to this location.
+	// +optional
+	DroppedFields []string `json:"droppedFields,omitempty"`
+
+	// NarrowedFields describe the fields of the imported schema whose values are restricted
+	// further by the negotiated schema, like enums missing values supported by this location.
+	// +optional
+	NarrowedFields []string `json:"narrowedFields,omitempty"`
 }
 
 // APIResourceImportList is a list of APIResourceImport resources
@@ -95,6 +95,36 @@ func IsNegotiatedAPIResourceConditionEquivalent(lhs, rhs *NegotiatedAPIResourceC
 	return lhs.Message == rhs.Message && lhs.Reason == rhs.Reason && lhs.Status == rhs.Status && lhs.Type == rhs.Type
 }
 
+// SetIncompatibility records that the API resource imported from the location is not
+// compatible, or removes the location from the incompatibilities when incompatibility is
+// nil. It returns whether the status changed.
+func (negotiatedApiResource *NegotiatedAPIResource) SetIncompatibility(location string, incompatibility *Incompatibility) bool {
+	var incompatibilities []Incompatibility
+	changed := false
+	found := false
+	for _, existing := range negotiatedApiResource.Status.Incompatibilities {
+		if existing.Location != location {
+			incompatibilities = append(incompatibilities, existing)
+			continue
+		}
+		found = true
+		if incompatibility == nil {
+			changed = true
+			continue
+		}
+		if existing != *incompatibility {
+			changed = true
+		}
+		incompatibilities = append(incompatibilities, *incompatibility)
+	}
+	if !found && incompatibility != nil {
+		incompatibilities = append(incompatibilities, *incompatibility)
+		changed = true
+	}
+	negotiatedApiResource.Status.Incompatibilities = incompatibilities
+	return changed
+}
+
 // GVR returns the GVR that this NegotiatedAPIResource represents.
 func (negotiatedApiResource *NegotiatedAPIResource) GVR() metav1.GroupVersionResource {
 	return metav1.GroupVersionResource{
@@ -27,6 +27,7 @@ import (
 // +crd
 // +genclient
 // +genclient:nonNamespaced
+// +genclient:skipVerbs=apply,applyStatus
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:scope=Cluster,categories=kcp
@@ -35,6 +36,7 @@ import (
 // +kubebuilder:printcolumn:name="API Resource",type="string",JSONPath=`.spec.plural`,priority=4
 // +kubebuilder:printcolumn:name="Published",type="string",JSONPath=`.status.conditions[?(@.type=="Published")].status`,priority=5
 // +kubebuilder:printcolumn:name="Enforced",type="string",JSONPath=`.status.conditions[?(@.type=="Enforced")].status`,priority=6
+// +kubebuilder:printcolumn:name="Incompatible Locations",type="string",JSONPath=`.status.incompatibilities[*].location`,priority=7
 type NegotiatedAPIResource struct {
 	metav1.TypeMeta `json:",inline"`
 	// +optional
@@ -101,6 +103,25 @@ type NegotiatedAPIResourceCondition struct {
 // NegotiatedAPIResourceStatus communicates the observed state of the NegotiatedAPIResource (from the controller).
 type NegotiatedAPIResourceStatus struct {
 	Conditions []NegotiatedAPIResourceCondition `json:"conditions,omitempty"`
+
+	// Incompatibilities lists the locations whose imported API resource is not compatible
+	// with the negotiated one, and is therefore not synced to them.
+	// +optional
+	Incompatibilities []Incompatibility `json:"incompatibilities,omitempty"`
+}
+
+// Incompatibility describes why the API resource imported from a location is not
+// compatible with the negotiated one.
+type Incompatibility struct {
+	// Location is the location the incompatible API resource was imported from.
+	Location string `json:"location"`
+
+	// Reason is a unique, one-word, CamelCase reason for the incompatibility.
+	Reason string `json:"reason"`
+
+	// Message is a human-readable description of the incompatibility.
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 // NegotiatedAPIResourceList is a list of NegotiatedAPIResource resources
@@ -130,6 +130,16 @@ func (in *APIResourceImportStatus) DeepCopyInto(out *APIResourceImportStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.DroppedFields != nil {
+		in, out := &in.DroppedFields, &out.DroppedFields
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NarrowedFields != nil {
+		in, out := &in.NarrowedFields, &out.NarrowedFields
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -234,6 +244,22 @@ func (in *GroupVersion) DeepCopy() *GroupVersion {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Incompatibility) DeepCopyInto(out *Incompatibility) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Incompatibility.
+func (in *Incompatibility) DeepCopy() *Incompatibility {
+	if in == nil {
+		return nil
+	}
+	out := new(Incompatibility)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NegotiatedAPIResource) DeepCopyInto(out *NegotiatedAPIResource) {
 	*out = *in
@@ -339,6 +365,11 @@ func (in *NegotiatedAPIResourceStatus) DeepCopyInto(out *NegotiatedAPIResourceSt
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Incompatibilities != nil {
+		in, out := &in.Incompatibilities, &out.Incompatibilities
+		*out = make([]Incompatibility, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
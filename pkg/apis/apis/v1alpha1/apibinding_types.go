@@ -0,0 +1,120 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// APIBinding consumes an APIExport of another workspace: the exported API resources are
+// served in the workspace of the APIBinding.
+//
+// +crd
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,categories=kcp
+// +kubebuilder:printcolumn:name="Workspace",type="string",JSONPath=`.spec.reference.workspace`
+// +kubebuilder:printcolumn:name="Export",type="string",JSONPath=`.spec.reference.exportName`
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=`.status.phase`
+type APIBinding struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// +optional
+	Spec APIBindingSpec `json:"spec,omitempty"`
+
+	// +optional
+	Status APIBindingStatus `json:"status,omitempty"`
+}
+
+// APIBindingSpec holds the desired state of the APIBinding.
+type APIBindingSpec struct {
+	// Reference points to the APIExport to bind.
+	Reference ExportReference `json:"reference"`
+
+	// AcceptedPermissionClaims are the permission claims of the APIExport the owner of this
+	// workspace agrees to. The binding is not established before all of them are accepted.
+	//
+	// +optional
+	AcceptedPermissionClaims []PermissionClaim `json:"acceptedPermissionClaims,omitempty"`
+}
+
+// ExportReference identifies an APIExport.
+type ExportReference struct {
+	// Workspace is the path of the workspace the APIExport lives in, like org:team.
+	//
+	// +kubebuilder:validation:MinLength=1
+	Workspace string `json:"workspace"`
+
+	// ExportName is the name of the APIExport.
+	//
+	// +kubebuilder:validation:MinLength=1
+	ExportName string `json:"exportName"`
+}
+
+// APIBindingPhaseType is the type of the current phase of the APIBinding
+type APIBindingPhaseType string
+
+const (
+	APIBindingPhaseBinding APIBindingPhaseType = "Binding"
+	APIBindingPhaseBound   APIBindingPhaseType = "Bound"
+)
+
+// These are valid conditions of APIBinding.
+const (
+	// APIExportValid is true when the referenced APIExport exists and all of its resources
+	// are defined in the workspace of the export.
+	APIExportValid = "APIExportValid"
+	// BinderAuthorized is true when the user who set the spec of the APIBinding may bind
+	// the APIExport.
+	BinderAuthorized = "BinderAuthorized"
+	// PermissionClaimsAccepted is true when all permission claims of the APIExport are
+	// accepted by the APIBinding.
+	PermissionClaimsAccepted = "PermissionClaimsAccepted"
+	// BindingReady is true when all exported resources are served in the workspace of the
+	// APIBinding.
+	BindingReady = "Ready"
+)
+
+// APIBindingStatus communicates the observed state of the APIBinding.
+type APIBindingStatus struct {
+	// Phase of the binding (Binding / Bound)
+	//
+	// +optional
+	Phase APIBindingPhaseType `json:"phase,omitempty"`
+
+	// BoundResources are the API resources served in this workspace on behalf of the
+	// APIExport.
+	//
+	// +optional
+	BoundResources []GroupResource `json:"boundResources,omitempty"`
+
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// APIBindingList is a list of APIBinding resources
+//
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type APIBindingList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []APIBinding `json:"items"`
+}
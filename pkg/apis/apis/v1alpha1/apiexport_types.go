@@ -0,0 +1,93 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// APIExport publishes a set of API resources, defined by CustomResourceDefinitions of the
+// workspace it lives in, so that other workspaces can consume them through an APIBinding.
+//
+// +crd
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,categories=kcp
+type APIExport struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// +optional
+	Spec APIExportSpec `json:"spec,omitempty"`
+
+	// +optional
+	Status APIExportStatus `json:"status,omitempty"`
+}
+
+// APIExportSpec holds the desired state of the APIExport.
+type APIExportSpec struct {
+	// Resources are the exported API resources. Each of them must be defined by a
+	// CustomResourceDefinition in the workspace of the APIExport.
+	//
+	// +optional
+	Resources []GroupResource `json:"resources,omitempty"`
+
+	// PermissionClaims are the resources of the consuming workspaces the provider of this
+	// export needs access to. They have to be accepted by each APIBinding. Accepted claims
+	// are granted to the group system:kcp:apiexport:<logical cluster>:<name> of this export
+	// in the workspace of the APIBinding.
+	//
+	// +optional
+	PermissionClaims []PermissionClaim `json:"permissionClaims,omitempty"`
+}
+
+// GroupResource identifies an API resource.
+type GroupResource struct {
+	// Group is the API group of the resource. It is empty for the core group.
+	//
+	// +optional
+	Group string `json:"group,omitempty"`
+
+	// Resource is the plural name of the resource.
+	//
+	// +kubebuilder:validation:MinLength=1
+	Resource string `json:"resource"`
+}
+
+// PermissionClaim is a claim of the provider of an APIExport on a resource of the
+// consuming workspaces.
+type PermissionClaim struct {
+	GroupResource `json:",inline"`
+}
+
+// APIExportStatus communicates the observed state of the APIExport.
+type APIExportStatus struct {
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// APIExportList is a list of APIExport resources
+//
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type APIExportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []APIExport `json:"items"`
+}
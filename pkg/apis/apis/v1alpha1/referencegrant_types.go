@@ -0,0 +1,102 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ReferenceGrant grants other workspaces the claim to reference objects of the workspace
+// it lives in, e.g. a Secret of a provider workspace consumed from a consumer workspace.
+// Without a grant, objects can only reference objects of their own workspace.
+//
+// +crd
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:scope=Cluster,categories=kcp
+type ReferenceGrant struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// +optional
+	Spec ReferenceGrantSpec `json:"spec,omitempty"`
+}
+
+// ReferenceGrantSpec holds the desired state of the ReferenceGrant.
+type ReferenceGrantSpec struct {
+	// Workspaces are the names of the logical clusters allowed to reference the granted
+	// objects.
+	//
+	// +kubebuilder:validation:MinItems=1
+	Workspaces []string `json:"workspaces"`
+
+	// Resources are the granted objects.
+	//
+	// +kubebuilder:validation:MinItems=1
+	Resources []GrantedResource `json:"resources"`
+}
+
+// GrantedResource selects the objects of a resource which may be referenced.
+type GrantedResource struct {
+	GroupResource `json:",inline"`
+
+	// Namespace restricts the grant to the objects of a namespace. All namespaces are
+	// granted when empty.
+	//
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// ResourceNames restricts the grant to the objects with these names. All objects are
+	// granted when empty.
+	//
+	// +optional
+	ResourceNames []string `json:"resourceNames,omitempty"`
+}
+
+// ReferenceGrantList is a list of ReferenceGrant resources
+//
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type ReferenceGrantList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []ReferenceGrant `json:"items"`
+}
+
+// ObjectReference identifies an object of any workspace.
+type ObjectReference struct {
+	// Workspace is the name of the logical cluster of the object. The workspace of the
+	// referencing object when empty.
+	//
+	// +optional
+	Workspace string `json:"workspace,omitempty"`
+
+	GroupResource `json:",inline"`
+
+	// Version is the API version used to retrieve the object.
+	//
+	// +kubebuilder:validation:MinLength=1
+	Version string `json:"version"`
+
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
@@ -30,8 +30,15 @@ import (
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:scope=Cluster,categories=kcp
 // +kubebuilder:printcolumn:name="Location",type="string",JSONPath=`.metadata.name`,priority=1
-// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=`.status.conditions[?(@.type=="Ready")].status`,priority=2
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Reachable",type="string",JSONPath=`.status.conditions[?(@.type=="Reachable")].status`,priority=2
+// +kubebuilder:printcolumn:name="Last Heartbeat",type="date",JSONPath=`.status.lastHeartbeatTime`
+// +kubebuilder:printcolumn:name="Syncer Last Seen",type="date",JSONPath=`.status.syncerLastSeen`,priority=1
+// +kubebuilder:printcolumn:name="Nodes",type="integer",JSONPath=`.status.capacity.readyNodes`,priority=3
+// +kubebuilder:printcolumn:name="CPU",type="string",JSONPath=`.status.capacity.allocatable.cpu`,priority=3
+// +kubebuilder:printcolumn:name="Memory",type="string",JSONPath=`.status.capacity.allocatable.memory`,priority=3
 // +kubebuilder:printcolumn:name="Synced API resources",type="string",JSONPath=`.status.syncedResources`,priority=3
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
 
 type Cluster struct {
 	metav1.TypeMeta `json:",inline"`
@@ -49,7 +56,231 @@ type Cluster struct {
 
 // ClusterSpec holds the desired state of the Cluster (from the client).
 type ClusterSpec struct {
-	KubeConfig string `json:"kubeconfig"`
+	// KubeConfig is the kubeconfig used to reach the physical cluster. It is stored in
+	// plain form: prefer KubeConfigSecretRef.
+	// +optional
+	KubeConfig string `json:"kubeconfig,omitempty"`
+
+	// KubeConfigSecretRef references the Secret holding the kubeconfig used to reach the
+	// physical cluster, in the logical cluster of the Cluster. It takes precedence over
+	// KubeConfig. Changes to the Secret are picked up without re-creating the Cluster.
+	// +optional
+	KubeConfigSecretRef *KubeConfigSecretReference `json:"kubeconfigSecretRef,omitempty"`
+
+	// ConnectionMode is how the physical cluster is reached: Direct connects to it with the
+	// kubeconfig, Tunnel through a tunnel opened by the syncer running in the physical
+	// cluster, for clusters which don't accept inbound connections. The syncer of a
+	// tunneled cluster is not installed by kcp. Defaults to Direct.
+	// +optional
+	ConnectionMode ConnectionMode `json:"connectionMode,omitempty"`
+
+	// SyncerMode is how the syncer of the cluster is run: Pull installs it as a Deployment
+	// in the physical cluster, Push runs it within the cluster controller and None does not
+	// run any syncer. Defaults to the mode the cluster controller is configured with.
+	// +optional
+	SyncerMode SyncerMode `json:"syncerMode,omitempty"`
+
+	// ProbeInterval is how often the cluster is probed for its health. Defaults to one
+	// minute.
+	// +optional
+	ProbeInterval *metav1.Duration `json:"probeInterval,omitempty"`
+
+	// CapacityScrapeInterval is how often the capacity of the cluster is scraped from its
+	// nodes. Defaults to five minutes.
+	// +optional
+	CapacityScrapeInterval *metav1.Duration `json:"capacityScrapeInterval,omitempty"`
+
+	// SyncPolicy restricts the objects synced to the cluster. All the objects labeled for
+	// the cluster are synced if nil.
+	// +optional
+	SyncPolicy *SyncPolicy `json:"syncPolicy,omitempty"`
+
+	// SyncRateLimit overrides the rate limit of the requests the syncer makes to the
+	// physical cluster, configured on the cluster controller, e.g. so that a large churn in
+	// the logical cluster doesn't overwhelm a small cluster.
+	// +optional
+	SyncRateLimit *SyncRateLimit `json:"syncRateLimit,omitempty"`
+
+	// DeletionPolicy is what happens to the objects synced to the physical cluster when the
+	// Cluster is deleted: Delete removes them along with the namespaces created for them,
+	// Orphan leaves them in place. The syncer is removed in both cases. Defaults to Delete.
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+}
+
+// DeletionPolicy is what happens to the objects synced to a cluster when it is deleted.
+// +kubebuilder:validation:Enum=Delete;Orphan
+type DeletionPolicy string
+
+const (
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	DeletionPolicyOrphan DeletionPolicy = "Orphan"
+)
+
+const (
+	// ClusterFinalizer holds the deletion of a Cluster until the syncer and, depending on
+	// its DeletionPolicy, the synced objects are removed from the physical cluster.
+	ClusterFinalizer = "cluster.kcp.dev/cleanup"
+
+	// ForceDeleteAnnotation, when set on a Cluster being deleted, skips the cleanup of the
+	// physical cluster, e.g. when it is not reachable anymore.
+	ForceDeleteAnnotation = "cluster.kcp.dev/force-delete"
+)
+
+// SyncRateLimit limits the requests the syncer makes to a physical cluster.
+type SyncRateLimit struct {
+	// QPS is the sustained number of requests per second made to the cluster.
+	// +kubebuilder:validation:Minimum=1
+	QPS int32 `json:"qps"`
+
+	// Burst is the number of requests which can be made at once above QPS. Defaults to
+	// twice QPS.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Burst int32 `json:"burst,omitempty"`
+
+	// BatchInterval is how long the changes of an object are batched before they are
+	// written to the cluster: an object changed several times within the interval is only
+	// written once.
+	// +optional
+	BatchInterval *metav1.Duration `json:"batchInterval,omitempty"`
+}
+
+// SyncPolicy restricts the objects synced to a cluster, among the objects labeled for it.
+// Objects which stop matching the policy are removed from the cluster.
+type SyncPolicy struct {
+	// Resources restricts the synced resources to these, in the same format as the
+	// resources to sync, e.g. "deployments.apps". All the resources to sync are synced if
+	// empty.
+	// +optional
+	Resources []string `json:"resources,omitempty"`
+
+	// ExcludedResources are never synced, in the same format as Resources.
+	// +optional
+	ExcludedResources []string `json:"excludedResources,omitempty"`
+
+	// NamespaceSelector selects the namespaces whose objects are synced. Objects of all
+	// namespaces are synced if nil.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// ObjectSelector selects the synced objects by their labels. All objects are synced if
+	// nil.
+	// +optional
+	ObjectSelector *metav1.LabelSelector `json:"objectSelector,omitempty"`
+
+	// Directions restricts the directions the objects of some resources are synced in. The
+	// objects of the other resources are synced both ways. Objects which stop being synced
+	// down are left in the cluster.
+	// +optional
+	Directions []ResourceSyncDirection `json:"directions,omitempty"`
+
+	// Deletions decide what happens to the objects of some resources in the cluster when
+	// their upstream object is deleted, or stops being synced to the cluster. The objects
+	// of the other resources are deleted in the background.
+	// +optional
+	Deletions []ResourceDeletionPolicy `json:"deletions,omitempty"`
+}
+
+// ResourceDeletionPolicy is what happens to the objects of a resource in the cluster when
+// their upstream object is deleted.
+type ResourceDeletionPolicy struct {
+	// Resource is the resource, in the same format as the resources to sync.
+	Resource string `json:"resource"`
+
+	// Policy is what happens to the objects of the resource in the cluster.
+	Policy ObjectDeletionPolicy `json:"policy"`
+}
+
+// ObjectDeletionPolicy is what happens to an object in the cluster when its upstream
+// object is deleted.
+// +kubebuilder:validation:Enum=Background;Foreground;Orphan;Retain
+type ObjectDeletionPolicy string
+
+const (
+	// ObjectDeletionPolicyBackground deletes the object right away, and its dependents,
+	// like the Pods of a Deployment, in the background.
+	ObjectDeletionPolicyBackground ObjectDeletionPolicy = "Background"
+	// ObjectDeletionPolicyForeground deletes the object once its dependents are deleted.
+	ObjectDeletionPolicyForeground ObjectDeletionPolicy = "Foreground"
+	// ObjectDeletionPolicyOrphan deletes the object, but leaves its dependents running.
+	ObjectDeletionPolicyOrphan ObjectDeletionPolicy = "Orphan"
+	// ObjectDeletionPolicyRetain leaves the object in the cluster, labeled with
+	// RetainedLabel, and no longer managed by the syncer.
+	ObjectDeletionPolicyRetain ObjectDeletionPolicy = "Retain"
+)
+
+// RetainedLabel is set on the objects left in a cluster by the Retain deletion policy.
+const RetainedLabel = "kcp.dev/retained"
+
+// ResourceSyncDirection is the direction the objects of a resource are synced in.
+type ResourceSyncDirection struct {
+	// Resource is the resource, in the same format as the resources to sync.
+	Resource string `json:"resource"`
+
+	// Direction is the direction the objects of the resource are synced in.
+	Direction SyncDirectionPolicy `json:"direction"`
+}
+
+// SyncDirectionPolicy is the direction the objects of a resource are synced in.
+// +kubebuilder:validation:Enum=SpecDown;StatusUp;Both;Off
+type SyncDirectionPolicy string
+
+const (
+	// SyncDirectionPolicySpecDown syncs the objects to the cluster, without syncing their
+	// status back.
+	SyncDirectionPolicySpecDown SyncDirectionPolicy = "SpecDown"
+	// SyncDirectionPolicyStatusUp only syncs the status of the objects from the cluster,
+	// and imports the objects selected for upsyncing, without writing them to the cluster.
+	SyncDirectionPolicyStatusUp SyncDirectionPolicy = "StatusUp"
+	// SyncDirectionPolicyBoth syncs the objects to the cluster and their status back.
+	SyncDirectionPolicyBoth SyncDirectionPolicy = "Both"
+	// SyncDirectionPolicyOff syncs nothing.
+	SyncDirectionPolicyOff SyncDirectionPolicy = "Off"
+)
+
+// SyncsDown returns whether the objects are synced to the cluster.
+func (d SyncDirectionPolicy) SyncsDown() bool {
+	return d == SyncDirectionPolicyBoth || d == SyncDirectionPolicySpecDown
+}
+
+// SyncsUp returns whether the status of the objects is synced from the cluster.
+func (d SyncDirectionPolicy) SyncsUp() bool {
+	return d == SyncDirectionPolicyBoth || d == SyncDirectionPolicyStatusUp
+}
+
+// ConnectionMode is how a physical cluster is reached.
+// +kubebuilder:validation:Enum=Direct;Tunnel
+type ConnectionMode string
+
+const (
+	ConnectionModeDirect ConnectionMode = "Direct"
+	ConnectionModeTunnel ConnectionMode = "Tunnel"
+)
+
+// SyncerMode is how the syncer of a cluster is run.
+// +kubebuilder:validation:Enum=Pull;Push;None
+type SyncerMode string
+
+const (
+	SyncerModePull SyncerMode = "Pull"
+	SyncerModePush SyncerMode = "Push"
+	SyncerModeNone SyncerMode = "None"
+)
+
+// KubeConfigSecretReference references a key of a Secret holding a kubeconfig.
+type KubeConfigSecretReference struct {
+	// Namespace of the Secret.
+	// +kubebuilder:validation:MinLength=1
+	Namespace string `json:"namespace"`
+
+	// Name of the Secret.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Key of the kubeconfig in the Secret data. Defaults to "kubeconfig".
+	// +optional
+	Key string `json:"key,omitempty"`
 }
 
 // ClusterStatus communicates the observed state of the Cluster (from the controller).
@@ -58,23 +289,68 @@ type ClusterStatus struct {
 
 	// +optional
 	SyncedResources []string `json:"syncedResources,omitempty"`
+
+	// LastHeartbeatTime is the last time the cluster responded to a probe.
+	// +optional
+	LastHeartbeatTime *metav1.Time `json:"lastHeartbeatTime,omitempty"`
+
+	// SyncerLastSeen is the last time the syncer of the cluster reported being alive. The
+	// SyncerHealthy condition tells whether it did recently enough.
+	// +optional
+	SyncerLastSeen *metav1.Time `json:"syncerLastSeen,omitempty"`
+
+	// Capacity is the capacity of the cluster, aggregated over its nodes. The
+	// CapacityCurrent condition tells whether it is up to date.
+	// +optional
+	Capacity *ClusterCapacity `json:"capacity,omitempty"`
+}
+
+// ClusterCapacity is the capacity of a cluster, aggregated over its nodes.
+type ClusterCapacity struct {
+	// Nodes is the number of nodes of the cluster.
+	Nodes int32 `json:"nodes"`
+
+	// ReadyNodes is the number of nodes of the cluster which are ready and schedulable.
+	ReadyNodes int32 `json:"readyNodes"`
+
+	// Capacity is the total capacity of the nodes of the cluster.
+	// +optional
+	Capacity corev1.ResourceList `json:"capacity,omitempty"`
+
+	// Allocatable is the total of the resources of the ready and schedulable nodes which
+	// are available to pods.
+	// +optional
+	Allocatable corev1.ResourceList `json:"allocatable,omitempty"`
+
+	// LastScrapeTime is the last time the capacity was scraped from the cluster.
+	LastScrapeTime metav1.Time `json:"lastScrapeTime"`
 }
 
 func (cs *ClusterStatus) SetConditionReady(status corev1.ConditionStatus, reason, message string) {
+	cs.SetCondition(ClusterConditionReady, status, reason, message)
+}
+
+// SetCondition sets the condition of the given type, updating its transition time when
+// its status changes.
+func (cs *ClusterStatus) SetCondition(conditionType ConditionType, status corev1.ConditionStatus, reason, message string) {
 	for idx, cond := range cs.Conditions {
-		if cond.Type == ClusterConditionReady {
+		if cond.Type == conditionType {
+			lastTransitionTime := cond.LastTransitionTime
+			if cond.Status != status {
+				lastTransitionTime = metav1.Now()
+			}
 			cs.Conditions[idx] = Condition{
-				Type:               ClusterConditionReady,
+				Type:               conditionType,
 				Status:             status,
 				Reason:             reason,
 				Message:            message,
-				LastTransitionTime: cond.LastTransitionTime,
+				LastTransitionTime: lastTransitionTime,
 			}
 			return
 		}
 	}
 	cs.Conditions = append(cs.Conditions, Condition{
-		Type:               ClusterConditionReady,
+		Type:               conditionType,
 		Status:             status,
 		Reason:             reason,
 		Message:            message,
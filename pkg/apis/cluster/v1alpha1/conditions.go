@@ -32,10 +32,41 @@ func (c Conditions) HasReady() bool {
 	return false
 }
 
+// Get returns the condition of the given type, or nil.
+func (c Conditions) Get(conditionType ConditionType) *Condition {
+	for i := range c {
+		if c[i].Type == conditionType {
+			return &c[i]
+		}
+	}
+	return nil
+}
+
+// IsTrue returns whether the condition of the given type is true.
+func (c Conditions) IsTrue(conditionType ConditionType) bool {
+	cond := c.Get(conditionType)
+	return cond != nil && cond.Status == corev1.ConditionTrue
+}
+
 type ConditionType string
 
 const (
+	// ClusterConditionReady is true when the cluster is reachable, its API server is
+	// healthy and its syncer is ready.
 	ClusterConditionReady = ConditionType("Ready")
+	// ClusterConditionReachable is true when the last probe of the cluster got a response.
+	ClusterConditionReachable = ConditionType("Reachable")
+	// ClusterConditionAPIServerHealthy is true when the API server of the cluster reported
+	// being ready at the last probe.
+	ClusterConditionAPIServerHealthy = ConditionType("APIServerHealthy")
+	// ClusterConditionSyncerReady is true when the syncer of the cluster is running.
+	ClusterConditionSyncerReady = ConditionType("SyncerReady")
+	// ClusterConditionSyncerHealthy is true when the syncer of the cluster sent a heartbeat
+	// recently, and false when its heartbeats went stale.
+	ClusterConditionSyncerHealthy = ConditionType("SyncerHealthy")
+	// ClusterConditionCapacityCurrent is true when the capacity of the cluster was scraped
+	// recently enough to be relied upon.
+	ClusterConditionCapacityCurrent = ConditionType("CapacityCurrent")
 )
 
 // TODO: Use metav1.Condition (available in v1.19+)
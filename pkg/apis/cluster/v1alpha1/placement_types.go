@@ -0,0 +1,113 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ClusterLabel is set on objects to the name of the Cluster they are synced to.
+	ClusterLabel = "kcp.dev/cluster"
+	// PlacementAnnotation is set on objects to the name of the Placement which scheduled
+	// them to a Cluster.
+	PlacementAnnotation = "kcp.dev/placement"
+	// NamespacePlacementAnnotation is set on objects labeled for the Cluster of their
+	// namespace, to the name of the Placement which scheduled the namespace.
+	NamespacePlacementAnnotation = "kcp.dev/namespace-placement"
+)
+
+// Placement schedules the objects of a logical cluster to the Clusters of that logical
+// cluster. Objects which are not yet assigned to a Cluster get the ClusterLabel of a
+// ready Cluster chosen according to the spread policy. When several Placements select
+// an object, the first one by name is used.
+//
+// Placements of "namespaces" schedule whole namespaces: the namespace gets the
+// ClusterLabel, and so do the objects in it which are not labeled by hand. Such
+// namespaces are scheduled again when their Cluster stays unready for too long.
+//
+// +crd
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:scope=Cluster,categories=kcp
+type Placement struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec holds the desired state.
+	// +optional
+	Spec PlacementSpec `json:"spec,omitempty"`
+}
+
+// SpreadPolicy decides how objects are distributed across the eligible Clusters.
+// +kubebuilder:validation:Enum=Spread;Pack
+type SpreadPolicy string
+
+const (
+	// SpreadPolicySpread places objects on the eligible Cluster with the fewest objects.
+	SpreadPolicySpread SpreadPolicy = "Spread"
+	// SpreadPolicyPack places objects on the eligible Cluster with the most objects, until
+	// it is full.
+	SpreadPolicyPack SpreadPolicy = "Pack"
+)
+
+// PlacementSpec holds the desired state of the Placement.
+type PlacementSpec struct {
+	// Resources are the resources of the placed objects, in the same format as the synced
+	// resources, e.g. "deployments.apps". "namespaces" places whole namespaces along with
+	// their objects.
+	// +kubebuilder:validation:MinItems=1
+	Resources []string `json:"resources"`
+
+	// NamespaceSelector selects the namespaces of the placed objects. All namespaces are
+	// selected if nil.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// ObjectSelector selects the placed objects by their labels. All objects are selected
+	// if nil.
+	// +optional
+	ObjectSelector *metav1.LabelSelector `json:"objectSelector,omitempty"`
+
+	// ClusterSelector selects the Clusters objects are placed on. All Clusters are
+	// selected if nil.
+	// +optional
+	ClusterSelector *metav1.LabelSelector `json:"clusterSelector,omitempty"`
+
+	// SpreadPolicy decides how objects are distributed across the selected Clusters.
+	// +optional
+	// +kubebuilder:default=Spread
+	SpreadPolicy SpreadPolicy `json:"spreadPolicy,omitempty"`
+
+	// MaxObjectsPerCluster is the maximum number of objects placed on a Cluster. Clusters
+	// holding that many objects are not eligible anymore. Unlimited if zero.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxObjectsPerCluster int32 `json:"maxObjectsPerCluster,omitempty"`
+}
+
+// PlacementList is a list of Placement resources
+//
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type PlacementList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []Placement `json:"items"`
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SyncTransform describes changes applied by the syncer to the objects of a logical
+// cluster as they are synced to physical clusters, or to their status as it is synced
+// back. The transforms matching an object are applied in the order of their names.
+//
+// +crd
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:scope=Cluster,categories=kcp
+type SyncTransform struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec holds the desired state.
+	// +optional
+	Spec SyncTransformSpec `json:"spec,omitempty"`
+}
+
+// SyncDirection is the direction in which objects are synced.
+// +kubebuilder:validation:Enum=Down;Up
+type SyncDirection string
+
+const (
+	// SyncDirectionDown is the direction of objects synced from kcp to physical clusters.
+	SyncDirectionDown SyncDirection = "Down"
+	// SyncDirectionUp is the direction of the status synced from physical clusters to kcp.
+	SyncDirectionUp SyncDirection = "Up"
+)
+
+// SyncTransformSpec holds the desired state of the SyncTransform.
+type SyncTransformSpec struct {
+	// Clusters are the names of the Clusters the transform applies to. The transform
+	// applies to all Clusters if empty.
+	// +optional
+	Clusters []string `json:"clusters,omitempty"`
+
+	// Resources are the resources the transform applies to, in the same format as the
+	// synced resources, e.g. "deployments.apps". The transform applies to all synced
+	// resources if empty.
+	// +optional
+	Resources []string `json:"resources,omitempty"`
+
+	// Direction is the direction of the sync the transform applies to.
+	// +optional
+	// +kubebuilder:default=Down
+	Direction SyncDirection `json:"direction,omitempty"`
+
+	// RemoveFields are the dot-separated paths of the fields removed from the objects,
+	// e.g. "spec.template.spec.nodeSelector".
+	// +optional
+	RemoveFields []string `json:"removeFields,omitempty"`
+
+	// MoveFields are the fields moved to another path of the objects. They are moved
+	// after RemoveFields were removed.
+	// +optional
+	MoveFields []FieldMove `json:"moveFields,omitempty"`
+
+	// Labels are set on the objects.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations are set on the objects.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// Namespaces maps the namespaces of the logical cluster to the namespaces the objects
+	// are synced to. Only applies to the Down direction: the status of the objects is
+	// synced back to their original namespace.
+	// +optional
+	Namespaces map[string]string `json:"namespaces,omitempty"`
+}
+
+// FieldMove moves a field to another path.
+type FieldMove struct {
+	// From is the dot-separated path of the moved field.
+	// +kubebuilder:validation:MinLength=1
+	From string `json:"from"`
+
+	// To is the dot-separated path the field is moved to.
+	// +kubebuilder:validation:MinLength=1
+	To string `json:"to"`
+}
+
+// SyncTransformList is a list of SyncTransform resources
+//
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type SyncTransformList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []SyncTransform `json:"items"`
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logicalcluster
+
+import (
+	"k8s.io/apimachinery/pkg/util/rand"
+
+	"github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+)
+
+// generatedLength is the length of the generated names of logical clusters. They are made
+// of lowercase consonants and digits, so are DNS labels unlikely to collide with a word.
+const generatedLength = 16
+
+// Of returns the name of the logical cluster holding the content of the workspace: the
+// generated one once the workspace is reconciled, the name of the workspace before.
+func Of(workspace *v1alpha1.Workspace) string {
+	if workspace.Status.Cluster != "" {
+		return workspace.Status.Cluster
+	}
+	return workspace.Name
+}
+
+// Generate returns a new name for the logical cluster of a workspace.
+func Generate() string {
+	return rand.String(generatedLength)
+}
+
+// Renamed returns whether the workspace is the old one of a rename, which is deleted
+// without deleting its logical cluster.
+func Renamed(workspace *v1alpha1.Workspace) bool {
+	_, renamed := workspace.Annotations[v1alpha1.WorkspaceRenamedToAnnotation]
+	return renamed
+}
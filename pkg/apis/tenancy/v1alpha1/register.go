@@ -49,6 +49,15 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&WorkspaceList{},
 		&WorkspaceShard{},
 		&WorkspaceShardList{},
+		&WorkspaceType{},
+		&WorkspaceTypeList{},
+		&WorkspaceRoleBinding{},
+		&WorkspaceRoleBindingList{},
+		&AuditSink{},
+		&AuditSinkList{},
+		&ClientCertificateAuthority{},
+		&ClientCertificateAuthorityList{},
+		&WorkspaceUsage{},
 	)
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
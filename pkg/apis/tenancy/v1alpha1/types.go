@@ -18,6 +18,8 @@ package v1alpha1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -29,6 +31,11 @@ import (
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:scope=Cluster,categories=kcp
+// +kubebuilder:printcolumn:name="Type",type="string",JSONPath=`.spec.type`,priority=1
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Shard",type="string",JSONPath=`.status.location.current`
+// +kubebuilder:printcolumn:name="URL",type="string",JSONPath=`.status.baseURL`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
 type Workspace struct {
 	metav1.TypeMeta `json:",inline"`
 	// +optional
@@ -45,6 +52,21 @@ type Workspace struct {
 type WorkspaceSpec struct {
 	// +optional
 	ReadOnly bool `json:"readOnly,omitempty"`
+
+	// InheritFrom is the name of another workspace of the same organization whose
+	// CustomResourceDefinitions are also served in this workspace. Objects of the
+	// inherited types are stored in this workspace; only their definitions are shared.
+	//
+	// +optional
+	InheritFrom string `json:"inheritFrom,omitempty"`
+
+	// Type is the name of the WorkspaceType of the same organization defining the
+	// RBAC objects created in the workspace when it is initialized. When empty, the
+	// default admin, edit and view ClusterRoles are created and the admin role is
+	// bound to the creator of the workspace.
+	//
+	// +optional
+	Type string `json:"type,omitempty"`
 }
 
 // WorkspacePhaseType is the type of the current phase of the workspace
@@ -54,18 +76,20 @@ const (
 	WorkspacePhaseInitializing WorkspacePhaseType = "Initializing"
 	WorkspacePhaseActive       WorkspacePhaseType = "Active"
 	WorkspacePhaseTerminating  WorkspacePhaseType = "Terminating"
+	WorkspacePhaseHibernated   WorkspacePhaseType = "Hibernated"
 )
 
 // WorkspaceStatus communicates the observed state of the Workspace.
 type WorkspaceStatus struct {
-	// Phase of the workspace  (Initializing / Active / Terminating)
+	// Phase of the workspace  (Initializing / Active / Terminating / Hibernated)
 	Phase WorkspacePhaseType `json:"phase,omitempty"`
 	// +optional
 	Conditions []WorkspaceCondition `json:"conditions,omitempty"`
 
 	// Base URL where this Workspace can be targeted.
-	// This will generally be of the form: https://<workspace shard server>/cluster/<workspace name>.
+	// This will generally be of the form: https://<workspace shard server>/clusters/<logical cluster>.
 	// But a workspace could also be targetable by a unique hostname in the future.
+	// It is empty as long as the workspace is not scheduled to a shard.
 	//
 	// +kubebuilder:validation:Pattern:https://[^/].*
 	BaseURL string `json:"baseURL"`
@@ -74,8 +98,46 @@ type WorkspaceStatus struct {
 	//
 	// +optional
 	Location WorkspaceLocation `json:"location,omitempty"`
+
+	// Cluster is the name of the logical cluster holding the content of the workspace.
+	// It is generated when the workspace is first reconciled, and never changes, even
+	// when the workspace is renamed. Workspaces created before logical cluster names
+	// were generated keep the logical cluster named after them.
+	//
+	// +optional
+	Cluster string `json:"cluster,omitempty"`
+
+	// PreviousNames are the names the workspace had before being renamed. Requests to
+	// the old paths are redirected to the workspace until the given time.
+	//
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	PreviousNames []WorkspacePreviousName `json:"previousNames,omitempty"`
 }
 
+// WorkspacePreviousName is a name a workspace had before being renamed.
+type WorkspacePreviousName struct {
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// Until is the time after which the name isn't redirected to the workspace anymore.
+	Until metav1.Time `json:"until"`
+}
+
+const (
+	// WorkspaceRenamedFromAnnotation is set on the workspace created by a rename to the old
+	// name of the workspace. The workspace controller moves the logical cluster and the
+	// status of the old workspace to the new one, and deletes the old one.
+	WorkspaceRenamedFromAnnotation = "tenancy.kcp.dev/renamed-from"
+	// WorkspaceRenamedToAnnotation is set on the old workspace of a rename to the new name,
+	// right before it is deleted: its logical cluster lives on in the new workspace.
+	WorkspaceRenamedToAnnotation = "tenancy.kcp.dev/renamed-to"
+	// WorkspaceHomeOfAnnotation is set on the home workspace of a user to the name of the
+	// user, who owns it. Home workspaces are created by kcp on the first request of their
+	// user to the ~ logical cluster.
+	WorkspaceHomeOfAnnotation = "tenancy.kcp.dev/home-of"
+)
+
 // WorkspaceConditionType defines the condition of the workspace
 type WorkspaceConditionType string
 
@@ -86,6 +148,27 @@ const (
 	// WorkspaceReasonUnschedulable reason in WorkspaceScheduled WorkspaceCondition means that the scheduler
 	// can't schedule the workspace right now, for example due to insufficient resources in the cluster.
 	WorkspaceReasonUnschedulable = "Unschedulable"
+
+	// WorkspaceRBACBootstrapped represents status of the creation of the default RBAC objects of this workspace.
+	WorkspaceRBACBootstrapped WorkspaceConditionType = "RBACBootstrapped"
+	// WorkspaceReasonTypeNotFound reason in WorkspaceRBACBootstrapped WorkspaceCondition means that the
+	// WorkspaceType of the workspace does not exist.
+	WorkspaceReasonTypeNotFound = "WorkspaceTypeNotFound"
+
+	// WorkspaceHibernated represents whether the workspace is hibernated because it has not been used for a while.
+	// Writes and watches to a hibernated workspace are rejected, and its watches are ended, until it has been woken up by a request.
+	WorkspaceHibernated WorkspaceConditionType = "Hibernated"
+	// WorkspaceReasonIdle reason in WorkspaceHibernated WorkspaceCondition means that no request was served
+	// for the workspace for longer than the idle timeout.
+	WorkspaceReasonIdle = "Idle"
+
+	// WorkspaceShardCompatible represents whether the workspace can be moved to its target shard.
+	// It is only set once a move was requested.
+	WorkspaceShardCompatible WorkspaceConditionType = "ShardCompatible"
+	// WorkspaceReasonIncompatibleShard reason in WorkspaceShardCompatible WorkspaceCondition means that the
+	// target shard runs a kcp version or lacks feature gates that the current shard of the workspace can't
+	// be moved from, so the move is refused.
+	WorkspaceReasonIncompatibleShard = "IncompatibleShard"
 )
 
 // WorkspaceCondition represents workspace's condition
@@ -156,6 +239,9 @@ type WorkspaceList struct {
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:scope=Cluster,categories=kcp
+// +kubebuilder:printcolumn:name="URL",type="string",JSONPath=`.spec.baseURL`
+// +kubebuilder:printcolumn:name="Version",type="string",JSONPath=`.status.version`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
 type WorkspaceShard struct {
 	metav1.TypeMeta `json:",inline"`
 	// +optional
@@ -170,6 +256,28 @@ type WorkspaceShard struct {
 
 // WorkspaceShardSpec holds the desired state of the WorkspaceShard.
 type WorkspaceShardSpec struct {
+	// BaseURL is the external address at which clients reach this shard,
+	// e.g. https://shard-1.kcp.dev. The base URL of each workspace scheduled
+	// to this shard is derived from it.
+	//
+	// +optional
+	BaseURL string `json:"baseURL,omitempty"`
+
+	// Credentials references the Secret holding the kubeconfig used to access this shard,
+	// in its "kubeconfig" key. The credentials of shards in the root logical cluster are
+	// issued and rotated by the shard itself.
+	//
+	// +optional
+	Credentials *SecretReference `json:"credentials,omitempty"`
+}
+
+// SecretReference identifies a Secret in the logical cluster of the referencing object.
+type SecretReference struct {
+	// +kubebuilder:validation:MinLength=1
+	Namespace string `json:"namespace"`
+
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
 }
 
 // WorkspaceShardStatus communicates the observed state of the WorkspaceShard.
@@ -177,6 +285,19 @@ type WorkspaceShardStatus struct {
 	// Set of integer resources that workspaces can be scheduled into
 	// +optional
 	Capacity corev1.ResourceList `json:"capacity,omitempty"`
+
+	// Version is the kcp version the shard runs, as published by the shard itself.
+	// Shards which did not publish a version are assumed to be compatible with all
+	// others.
+	//
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// FeatureGates are the names of the feature gates enabled on the shard, as
+	// published by the shard itself.
+	//
+	// +optional
+	FeatureGates []string `json:"featureGates,omitempty"`
 }
 
 // WorkspaceShardList is a list of Workspace shards
@@ -188,3 +309,346 @@ type WorkspaceShardList struct {
 
 	Items []WorkspaceShard `json:"items"`
 }
+
+// WorkspaceType describes the RBAC objects created in the workspaces referencing it
+// when they are initialized.
+//
+// +crd
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:scope=Cluster,categories=kcp
+type WorkspaceType struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// +optional
+	Spec WorkspaceTypeSpec `json:"spec,omitempty"`
+}
+
+// WorkspaceTypeSpec holds the desired state of the WorkspaceType.
+type WorkspaceTypeSpec struct {
+	// ClusterRoles are created in every workspace of this type.
+	//
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	ClusterRoles []WorkspaceClusterRole `json:"clusterRoles,omitempty"`
+
+	// OwnerClusterRoles are the names of the ClusterRoles bound to the user who
+	// created a workspace of this type.
+	//
+	// +optional
+	OwnerClusterRoles []string `json:"ownerClusterRoles,omitempty"`
+
+	// Resources trims the resources served in the workspaces of this type, like the
+	// built-in resources which make no sense in a workspace.
+	//
+	// +optional
+	Resources *WorkspaceResources `json:"resources,omitempty"`
+
+	// Limits caps the size of the objects stored in the workspaces of this type, instead
+	// of the limits of the server.
+	//
+	// +optional
+	Limits *WorkspaceLimits `json:"limits,omitempty"`
+}
+
+// WorkspaceLimits caps the size of the objects stored in the workspaces of a
+// WorkspaceType. Unset limits are those of the server.
+type WorkspaceLimits struct {
+	// MaxObjectSize is the maximum size of an object, encoded as JSON.
+	//
+	// +optional
+	MaxObjectSize *resource.Quantity `json:"maxObjectSize,omitempty"`
+
+	// MaxManagedFieldsSize is the maximum size of the managedFields of an object, encoded
+	// as JSON.
+	//
+	// +optional
+	MaxManagedFieldsSize *resource.Quantity `json:"maxManagedFieldsSize,omitempty"`
+
+	// MaxAnnotationsSize is the maximum total size of the keys and values of the
+	// annotations of an object.
+	//
+	// +optional
+	MaxAnnotationsSize *resource.Quantity `json:"maxAnnotationsSize,omitempty"`
+}
+
+// WorkspaceResources selects the resources served in the workspaces of a WorkspaceType.
+// Excluded resources are neither served nor discovered, unsupported resources are
+// discovered but not served.
+type WorkspaceResources struct {
+	// Excluded resources are not served. A resource of "*" excludes all the resources of
+	// its group.
+	//
+	// +optional
+	Excluded []metav1.GroupResource `json:"excluded,omitempty"`
+
+	// Enabled resources are served even if their group is excluded.
+	//
+	// +optional
+	Enabled []metav1.GroupResource `json:"enabled,omitempty"`
+
+	// Unsupported resources and subresources, like "pods/exec", are discovered but their
+	// requests are rejected with an error explaining that kcp can't serve them natively,
+	// in addition to those kcp rejects by default. A resource of "*" rejects all the
+	// resources of its group.
+	//
+	// +optional
+	Unsupported []metav1.GroupResource `json:"unsupported,omitempty"`
+
+	// Supported resources and subresources are served even if they are unsupported by
+	// default, like the subresources of pods served by a virtual workspace.
+	//
+	// +optional
+	Supported []metav1.GroupResource `json:"supported,omitempty"`
+}
+
+// WorkspaceClusterRole is a ClusterRole created in the workspaces of a WorkspaceType.
+type WorkspaceClusterRole struct {
+	// Name of the ClusterRole.
+	//
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Rules of the ClusterRole.
+	//
+	// +optional
+	Rules []rbacv1.PolicyRule `json:"rules,omitempty"`
+}
+
+// WorkspaceTypeList is a list of WorkspaceType resources
+//
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type WorkspaceTypeList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []WorkspaceType `json:"items"`
+}
+
+// WorkspaceRoleBinding grants users and groups verbs on child workspaces of the workspace
+// it lives in. The verbs are enforced before any request reaches the RBAC of the child
+// workspaces.
+//
+// +crd
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:scope=Cluster,categories=kcp
+type WorkspaceRoleBinding struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec WorkspaceRoleBindingSpec `json:"spec"`
+}
+
+// WorkspaceRoleBindingSpec holds the verbs granted on workspaces, and to whom.
+type WorkspaceRoleBindingSpec struct {
+	// Workspaces are the names of the child workspaces the verbs are granted on. "*"
+	// stands for all of them.
+	//
+	// +kubebuilder:validation:MinItems=1
+	Workspaces []string `json:"workspaces"`
+
+	// Verbs granted on the workspaces.
+	//
+	// +kubebuilder:validation:MinItems=1
+	Verbs []WorkspaceVerb `json:"verbs"`
+
+	// Subjects are the users and groups the verbs are granted to.
+	//
+	// +kubebuilder:validation:MinItems=1
+	Subjects []rbacv1.Subject `json:"subjects"`
+}
+
+// WorkspaceVerb is a verb granted on a workspace by a WorkspaceRoleBinding.
+//
+// +kubebuilder:validation:Enum=access;admin;create-child
+type WorkspaceVerb string
+
+const (
+	// WorkspaceVerbAccess allows sending requests to the workspace, which are then
+	// authorized by the RBAC of the workspace.
+	WorkspaceVerbAccess WorkspaceVerb = "access"
+	// WorkspaceVerbAdmin allows any request to the workspace, regardless of its RBAC.
+	WorkspaceVerbAdmin WorkspaceVerb = "admin"
+	// WorkspaceVerbCreateChild allows creating Workspaces in the workspace, in addition to
+	// the access to it.
+	WorkspaceVerbCreateChild WorkspaceVerb = "create-child"
+)
+
+// WorkspaceRoleBindingList is a list of WorkspaceRoleBinding resources
+//
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type WorkspaceRoleBindingList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []WorkspaceRoleBinding `json:"items"`
+}
+
+// AuditSink sends the audit events of the requests to the workspace it lives in to a
+// webhook of the tenant. Events of other workspaces are never sent.
+//
+// +crd
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:scope=Cluster,categories=kcp
+type AuditSink struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec AuditSinkSpec `json:"spec"`
+}
+
+// AuditSinkSpec holds the webhook receiving the audit events, and which events it receives.
+type AuditSinkSpec struct {
+	// Webhook receives the audit events in batches, as an audit.k8s.io/v1 EventList.
+	Webhook AuditSinkWebhook `json:"webhook"`
+
+	// Level is the level of detail of the events: Metadata, Request or RequestResponse.
+	//
+	// +optional
+	// +kubebuilder:default=Metadata
+	// +kubebuilder:validation:Enum=Metadata;Request;RequestResponse
+	Level string `json:"level,omitempty"`
+
+	// Verbs filters the events by request verb. All verbs are sent if empty.
+	//
+	// +optional
+	Verbs []string `json:"verbs,omitempty"`
+
+	// Resources filters the events by resource, e.g. "secrets" or "deployments.apps".
+	// Events of all resources and non-resource URLs are sent if empty.
+	//
+	// +optional
+	Resources []string `json:"resources,omitempty"`
+}
+
+// AuditSinkWebhook is the HTTPS endpoint of an AuditSink.
+type AuditSinkWebhook struct {
+	// URL of the webhook.
+	//
+	// +kubebuilder:validation:Pattern=`^https://`
+	URL string `json:"url"`
+
+	// CABundle verifies the certificate of the webhook, instead of the system roots.
+	//
+	// +optional
+	CABundle []byte `json:"caBundle,omitempty"`
+}
+
+// AuditSinkList is a list of AuditSink resources
+//
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type AuditSinkList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []AuditSink `json:"items"`
+}
+
+// ClientCertificateAuthority trusts the client certificates issued by a certificate
+// authority of the tenant for the requests to the workspace it lives in and to all the
+// workspaces beneath it, like those of an organization. Requests to other workspaces
+// never authenticate with it.
+//
+// +crd
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:scope=Cluster,categories=kcp
+type ClientCertificateAuthority struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ClientCertificateAuthoritySpec `json:"spec"`
+}
+
+// ClientCertificateAuthoritySpec holds the certificates of the authority, and how the
+// users of its client certificates are named.
+type ClientCertificateAuthoritySpec struct {
+	// CABundle holds the PEM encoded certificates verifying the client certificates.
+	CABundle []byte `json:"caBundle"`
+
+	// UsernamePrefix is prepended to the common name of the client certificates to name
+	// their users, e.g. "acme:", so that they don't collide with the users of other
+	// authorities. Users named with the system: prefix are never authenticated.
+	//
+	// +optional
+	UsernamePrefix string `json:"usernamePrefix,omitempty"`
+
+	// GroupsPrefix is prepended to the organizations of the client certificates to name
+	// the groups of their users. Groups named with the system: prefix are dropped.
+	//
+	// +optional
+	GroupsPrefix string `json:"groupsPrefix,omitempty"`
+}
+
+// ClientCertificateAuthorityList is a list of ClientCertificateAuthority resources
+//
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type ClientCertificateAuthorityList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []ClientCertificateAuthority `json:"items"`
+}
+
+// WorkspaceUsage reports the objects stored in a workspace and the API activity in it.
+// It is served by the usage subresource of Workspaces.
+//
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type WorkspaceUsage struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Resources lists the number and size of the objects of every resource stored in
+	// the workspace.
+	//
+	// +optional
+	Resources []ResourceUsage `json:"resources,omitempty"`
+
+	// StorageBytes is the serialized size of all objects stored in the workspace.
+	StorageBytes int64 `json:"storageBytes"`
+
+	// ObservedTime is when the objects of the workspace were last counted.
+	//
+	// +optional
+	ObservedTime *metav1.Time `json:"observedTime,omitempty"`
+
+	// Requests is the number of API requests to the workspace served by this shard
+	// since it started.
+	Requests int64 `json:"requests"`
+
+	// RecentRequests is the number of API requests to the workspace served by this
+	// shard over the last five minutes.
+	RecentRequests int64 `json:"recentRequests"`
+
+	// LastActivityTime is when the last API request to the workspace was served by
+	// this shard.
+	//
+	// +optional
+	LastActivityTime *metav1.Time `json:"lastActivityTime,omitempty"`
+}
+
+// ResourceUsage is the number and size of the objects of a resource in a workspace.
+type ResourceUsage struct {
+	Group    string `json:"group"`
+	Version  string `json:"version"`
+	Resource string `json:"resource"`
+
+	// Count is the number of objects.
+	Count int64 `json:"count"`
+
+	// StorageBytes is the serialized size of the objects.
+	StorageBytes int64 `json:"storageBytes"`
+}
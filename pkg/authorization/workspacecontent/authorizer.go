@@ -0,0 +1,208 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package workspacecontent authorizes the requests to the logical cluster of a workspace
+// with the WorkspaceRoleBindings of its parent workspace, before they reach the RBAC of
+// the workspace.
+package workspacecontent
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/kcp-dev/kcp/pkg/admission/workspaceowner"
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	tenancyinformer "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/indexers"
+	"github.com/kcp-dev/kcp/pkg/serviceaccount"
+)
+
+const clusterIndex = "cluster"
+
+// Authorizer decides on the requests to the logical clusters of the workspaces:
+//
+//   - admin allows any request to the workspace,
+//   - create-child allows creating Workspaces in the workspace,
+//   - access, or any of the above, defers to the RBAC of the workspace,
+//   - without any of them, the request is denied.
+//
+// The owner of a workspace, the privileged users and the tokens issued within the workspace
+// have access to it. Requests to logical clusters which are not workspaces are left to
+// RBAC, and so are all requests until the informers are installed, except those of
+// authenticated users to the clusters index, which are allowed.
+type Authorizer struct {
+	lock             sync.RWMutex
+	workspaceIndexer cache.Indexer
+	bindingIndexer   cache.Indexer
+	hasSynced        func() bool
+}
+
+// NewAuthorizer returns an Authorizer without any opinion until its informers are installed.
+func NewAuthorizer() *Authorizer {
+	return &Authorizer{}
+}
+
+// Install makes the authorizer decide from the given informers once they have synced.
+func (a *Authorizer) Install(workspaceInformer tenancyinformer.WorkspaceInformer, bindingInformer tenancyinformer.WorkspaceRoleBindingInformer) error {
+	if err := indexers.AddIfNotPresent(workspaceInformer.Informer().GetIndexer(), cache.Indexers{
+		indexers.WorkspaceCluster: indexers.IndexWorkspaceByCluster,
+		indexers.WorkspaceParent:  indexers.IndexWorkspaceByParent,
+	}); err != nil {
+		return fmt.Errorf("failed to add indexer for Workspace: %w", err)
+	}
+	if err := indexers.AddIfNotPresent(bindingInformer.Informer().GetIndexer(), cache.Indexers{
+		clusterIndex: func(obj interface{}) ([]string, error) {
+			if binding, ok := obj.(*tenancyv1alpha1.WorkspaceRoleBinding); ok {
+				return []string{binding.ClusterName}, nil
+			}
+			return []string{}, nil
+		},
+	}); err != nil {
+		return fmt.Errorf("failed to add indexer for WorkspaceRoleBinding: %w", err)
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.workspaceIndexer = workspaceInformer.Informer().GetIndexer()
+	a.bindingIndexer = bindingInformer.Informer().GetIndexer()
+	a.hasSynced = func() bool {
+		return workspaceInformer.Informer().HasSynced() && bindingInformer.Informer().HasSynced()
+	}
+	return nil
+}
+
+func (a *Authorizer) Authorize(ctx context.Context, attr authorizer.Attributes) (authorizer.Decision, string, error) {
+	if u := attr.GetUser(); u != nil && isAuthenticated(u) && !attr.IsResourceRequest() && attr.GetPath() == ClustersIndexPath && attr.GetVerb() == "get" {
+		// the index only lists the workspaces the user has access to
+		return authorizer.DecisionAllow, "users may list the workspaces they have access to", nil
+	}
+
+	a.lock.RLock()
+	workspaceIndexer, bindingIndexer, hasSynced := a.workspaceIndexer, a.bindingIndexer, a.hasSynced
+	a.lock.RUnlock()
+	if hasSynced == nil || !hasSynced() {
+		return authorizer.DecisionNoOpinion, "", nil
+	}
+
+	cluster := genericapirequest.ClusterFrom(ctx)
+	u := attr.GetUser()
+	if cluster == nil || cluster.Wildcard || u == nil || isPrivileged(u) || issuedWithin(u, cluster.Name) {
+		return authorizer.DecisionNoOpinion, "", nil
+	}
+
+	workspaces, err := workspaceIndexer.ByIndex(indexers.WorkspaceCluster, cluster.Name)
+	if err != nil {
+		return authorizer.DecisionNoOpinion, "", err
+	}
+	if len(workspaces) == 0 {
+		return authorizer.DecisionNoOpinion, "", nil
+	}
+	workspace := workspaces[0].(*tenancyv1alpha1.Workspace)
+
+	verbs, err := workspaceVerbs(bindingIndexer, workspace, u)
+	if err != nil {
+		return authorizer.DecisionNoOpinion, "", err
+	}
+
+	switch {
+	case verbs[tenancyv1alpha1.WorkspaceVerbAdmin]:
+		return authorizer.DecisionAllow, fmt.Sprintf("admin of workspace %q", workspace.Name), nil
+	case verbs[tenancyv1alpha1.WorkspaceVerbCreateChild] && isWorkspaceCreation(attr):
+		return authorizer.DecisionAllow, fmt.Sprintf("allowed to create children of workspace %q", workspace.Name), nil
+	case len(verbs) > 0:
+		return authorizer.DecisionNoOpinion, "", nil
+	default:
+		return authorizer.DecisionDeny, fmt.Sprintf("no access to workspace %q", workspace.Name), nil
+	}
+}
+
+// workspaceVerbs returns the verbs the owner annotation and the WorkspaceRoleBindings of
+// the parent grant the user on the workspace.
+func workspaceVerbs(bindingIndexer cache.Indexer, workspace *tenancyv1alpha1.Workspace, u user.Info) (map[tenancyv1alpha1.WorkspaceVerb]bool, error) {
+	verbs := map[tenancyv1alpha1.WorkspaceVerb]bool{}
+	if workspace.Annotations[workspaceowner.OwnerAnnotation] == u.GetName() {
+		verbs[tenancyv1alpha1.WorkspaceVerbAccess] = true
+	}
+	bindings, err := bindingIndexer.ByIndex(clusterIndex, workspace.ClusterName)
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range bindings {
+		binding := obj.(*tenancyv1alpha1.WorkspaceRoleBinding)
+		if covers(binding, workspace.Name) && boundTo(binding, u) {
+			for _, verb := range binding.Spec.Verbs {
+				verbs[verb] = true
+			}
+		}
+	}
+	return verbs, nil
+}
+
+func isPrivileged(u user.Info) bool {
+	for _, group := range u.GetGroups() {
+		if group == user.SystemPrivilegedGroup {
+			return true
+		}
+	}
+	return false
+}
+
+// issuedWithin returns whether the user is a service account of the logical cluster. The
+// user tokens minted for the logical cluster don't count, since anyone with access to the
+// workspace may mint them, including the users whose access was revoked since.
+func issuedWithin(u user.Info, clusterName string) bool {
+	clusterNames := u.GetExtra()[serviceaccount.ServiceAccountClusterNameExtraKey]
+	return len(clusterNames) == 1 && clusterNames[0] == clusterName
+}
+
+func covers(binding *tenancyv1alpha1.WorkspaceRoleBinding, workspaceName string) bool {
+	for _, name := range binding.Spec.Workspaces {
+		if name == "*" || name == workspaceName {
+			return true
+		}
+	}
+	return false
+}
+
+func boundTo(binding *tenancyv1alpha1.WorkspaceRoleBinding, u user.Info) bool {
+	for _, subject := range binding.Spec.Subjects {
+		switch subject.Kind {
+		case rbacv1.UserKind:
+			if subject.Name == u.GetName() {
+				return true
+			}
+		case rbacv1.GroupKind:
+			for _, group := range u.GetGroups() {
+				if subject.Name == group {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func isWorkspaceCreation(attr authorizer.Attributes) bool {
+	return attr.IsResourceRequest() && attr.GetVerb() == "create" &&
+		attr.GetAPIGroup() == tenancyv1alpha1.SchemeGroupVersion.Group && attr.GetResource() == "workspaces" && attr.GetSubresource() == ""
+}
@@ -0,0 +1,251 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workspacecontent
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/kcp-dev/kcp/pkg/admission/workspaceowner"
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/indexers"
+	"github.com/kcp-dev/kcp/pkg/serviceaccount"
+)
+
+func newTestAuthorizer(t *testing.T) *Authorizer {
+	workspaceIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		indexers.WorkspaceCluster: indexers.IndexWorkspaceByCluster,
+		indexers.WorkspaceParent:  indexers.IndexWorkspaceByParent,
+	})
+	bindingIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{clusterIndex: func(obj interface{}) ([]string, error) {
+		return []string{obj.(*tenancyv1alpha1.WorkspaceRoleBinding).ClusterName}, nil
+	}})
+	for _, obj := range []interface{}{
+		&tenancyv1alpha1.Workspace{ObjectMeta: metav1.ObjectMeta{
+			Name: "team", ClusterName: "org",
+			Annotations: map[string]string{workspaceowner.OwnerAnnotation: "owner"},
+		}, Status: tenancyv1alpha1.WorkspaceStatus{Cluster: "k7c2q9x4"}},
+		&tenancyv1alpha1.Workspace{ObjectMeta: metav1.ObjectMeta{Name: "infra", ClusterName: "org"}},
+		// a child of team, in its logical cluster
+		&tenancyv1alpha1.Workspace{ObjectMeta: metav1.ObjectMeta{Name: "dev", ClusterName: "k7c2q9x4"}},
+	} {
+		if err := workspaceIndexer.Add(obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, obj := range []interface{}{
+		&tenancyv1alpha1.WorkspaceRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "developers", ClusterName: "org"},
+			Spec: tenancyv1alpha1.WorkspaceRoleBindingSpec{
+				Workspaces: []string{"*"},
+				Verbs:      []tenancyv1alpha1.WorkspaceVerb{tenancyv1alpha1.WorkspaceVerbAccess, tenancyv1alpha1.WorkspaceVerbCreateChild},
+				Subjects:   []rbacv1.Subject{{Kind: rbacv1.GroupKind, Name: "developers"}},
+			},
+		},
+		&tenancyv1alpha1.WorkspaceRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "admins", ClusterName: "org"},
+			Spec: tenancyv1alpha1.WorkspaceRoleBindingSpec{
+				Workspaces: []string{"team"},
+				Verbs:      []tenancyv1alpha1.WorkspaceVerb{tenancyv1alpha1.WorkspaceVerbAdmin},
+				Subjects:   []rbacv1.Subject{{Kind: rbacv1.UserKind, Name: "alice"}},
+			},
+		},
+		// bindings of other workspaces don't grant anything
+		&tenancyv1alpha1.WorkspaceRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "admins", ClusterName: "other"},
+			Spec: tenancyv1alpha1.WorkspaceRoleBindingSpec{
+				Workspaces: []string{"*"},
+				Verbs:      []tenancyv1alpha1.WorkspaceVerb{tenancyv1alpha1.WorkspaceVerbAdmin},
+				Subjects:   []rbacv1.Subject{{Kind: rbacv1.UserKind, Name: "mallory"}},
+			},
+		},
+	} {
+		if err := bindingIndexer.Add(obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &Authorizer{workspaceIndexer: workspaceIndexer, bindingIndexer: bindingIndexer, hasSynced: func() bool { return true }}
+}
+
+func TestAuthorize(t *testing.T) {
+	a := newTestAuthorizer(t)
+
+	getPods := authorizer.AttributesRecord{Verb: "list", Resource: "pods", ResourceRequest: true}
+	getIndex := authorizer.AttributesRecord{Verb: "get", Path: ClustersIndexPath}
+	createWorkspace := authorizer.AttributesRecord{Verb: "create", APIGroup: tenancyv1alpha1.SchemeGroupVersion.Group, Resource: "workspaces", ResourceRequest: true}
+	for _, tc := range []struct {
+		name     string
+		cluster  string
+		user     *user.DefaultInfo
+		attr     authorizer.AttributesRecord
+		expected authorizer.Decision
+	}{
+		{name: "admin", cluster: "k7c2q9x4", user: &user.DefaultInfo{Name: "alice"}, attr: getPods, expected: authorizer.DecisionAllow},
+		{name: "access", cluster: "k7c2q9x4", user: &user.DefaultInfo{Name: "bob", Groups: []string{"developers"}}, attr: getPods, expected: authorizer.DecisionNoOpinion},
+		{name: "create-child", cluster: "k7c2q9x4", user: &user.DefaultInfo{Name: "bob", Groups: []string{"developers"}}, attr: createWorkspace, expected: authorizer.DecisionAllow},
+		{name: "owner", cluster: "k7c2q9x4", user: &user.DefaultInfo{Name: "owner"}, attr: getPods, expected: authorizer.DecisionNoOpinion},
+		{name: "privileged", cluster: "k7c2q9x4", user: &user.DefaultInfo{Name: "root", Groups: []string{user.SystemPrivilegedGroup}}, attr: getPods, expected: authorizer.DecisionNoOpinion},
+		{name: "no access", cluster: "k7c2q9x4", user: &user.DefaultInfo{Name: "mallory"}, attr: getPods, expected: authorizer.DecisionDeny},
+		{name: "service account", cluster: "k7c2q9x4", user: &user.DefaultInfo{Name: "system:serviceaccount:default:robot", Extra: map[string][]string{serviceaccount.ServiceAccountClusterNameExtraKey: {"k7c2q9x4"}}}, attr: getPods, expected: authorizer.DecisionNoOpinion},
+		{name: "user token", cluster: "k7c2q9x4", user: &user.DefaultInfo{Name: "mallory", Extra: map[string][]string{serviceaccount.ClusterNameExtraKey: {"k7c2q9x4"}}}, attr: getPods, expected: authorizer.DecisionDeny},
+		{name: "not a workspace", cluster: "org", user: &user.DefaultInfo{Name: "mallory"}, attr: getPods, expected: authorizer.DecisionNoOpinion},
+		{name: "clusters index", user: &user.DefaultInfo{Name: "mallory", Groups: []string{user.AllAuthenticated}}, attr: getIndex, expected: authorizer.DecisionAllow},
+		{name: "anonymous clusters index", user: &user.DefaultInfo{Name: user.Anonymous}, attr: getIndex, expected: authorizer.DecisionNoOpinion},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			attr := tc.attr
+			attr.User = tc.user
+			ctx := genericapirequest.WithCluster(context.Background(), genericapirequest.Cluster{Name: tc.cluster})
+			decision, _, err := a.Authorize(ctx, attr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if decision != tc.expected {
+				t.Errorf("expected decision %v, got %v", tc.expected, decision)
+			}
+		})
+	}
+}
+
+func TestWorkspaces(t *testing.T) {
+	a := newTestAuthorizer(t)
+	for _, tc := range []struct {
+		name     string
+		user     *user.DefaultInfo
+		verb     tenancyv1alpha1.WorkspaceVerb
+		expected []string
+	}{
+		{name: "admin has access", user: &user.DefaultInfo{Name: "alice"}, verb: tenancyv1alpha1.WorkspaceVerbAccess, expected: []string{"team"}},
+		{name: "admin", user: &user.DefaultInfo{Name: "alice"}, verb: tenancyv1alpha1.WorkspaceVerbCreateChild, expected: []string{"team"}},
+		{name: "group", user: &user.DefaultInfo{Name: "bob", Groups: []string{"developers"}}, verb: tenancyv1alpha1.WorkspaceVerbCreateChild, expected: []string{"infra", "team"}},
+		{name: "not admin", user: &user.DefaultInfo{Name: "bob", Groups: []string{"developers"}}, verb: tenancyv1alpha1.WorkspaceVerbAdmin, expected: []string{}},
+		{name: "owner", user: &user.DefaultInfo{Name: "owner"}, verb: tenancyv1alpha1.WorkspaceVerbAccess, expected: []string{"team"}},
+		{name: "privileged", user: &user.DefaultInfo{Name: "root", Groups: []string{user.SystemPrivilegedGroup}}, verb: tenancyv1alpha1.WorkspaceVerbAdmin, expected: []string{"infra", "team"}},
+		{name: "no access", user: &user.DefaultInfo{Name: "mallory"}, verb: tenancyv1alpha1.WorkspaceVerbAccess, expected: []string{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			workspaces, err := a.Workspaces("org", tc.user, tc.verb)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.expected, workspaces); diff != "" {
+				t.Errorf("unexpected workspaces (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTree(t *testing.T) {
+	a := newTestAuthorizer(t)
+	admin := []tenancyv1alpha1.WorkspaceVerb{tenancyv1alpha1.WorkspaceVerbAdmin}
+	for _, tc := range []struct {
+		name     string
+		user     *user.DefaultInfo
+		depth    int
+		expected *WorkspaceTree
+	}{
+		{
+			name: "children of workspaces without access are left out",
+			user: &user.DefaultInfo{Name: "alice"},
+			expected: &WorkspaceTree{Name: "org", Cluster: "org", Children: []*WorkspaceTree{
+				{Name: "infra", Cluster: "infra"},
+				{Name: "team", Cluster: "k7c2q9x4", Verbs: admin, Children: []*WorkspaceTree{
+					{Name: "dev", Cluster: "dev"},
+				}},
+			}},
+		},
+		{
+			name:  "depth",
+			user:  &user.DefaultInfo{Name: "root", Groups: []string{user.SystemPrivilegedGroup}},
+			depth: 1,
+			expected: &WorkspaceTree{Name: "org", Cluster: "org", Children: []*WorkspaceTree{
+				{Name: "infra", Cluster: "infra", Verbs: admin},
+				{Name: "team", Cluster: "k7c2q9x4", Verbs: admin},
+			}},
+		},
+		{
+			name: "no access",
+			user: &user.DefaultInfo{Name: "mallory"},
+			expected: &WorkspaceTree{Name: "org", Cluster: "org", Children: []*WorkspaceTree{
+				{Name: "infra", Cluster: "infra"},
+				{Name: "team", Cluster: "k7c2q9x4"},
+			}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tree, err := a.Tree("org", tc.user, tc.depth)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.expected, tree); diff != "" {
+				t.Errorf("unexpected tree (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIndex(t *testing.T) {
+	a := newTestAuthorizer(t)
+	admin := []tenancyv1alpha1.WorkspaceVerb{tenancyv1alpha1.WorkspaceVerbAdmin}
+	root := &user.DefaultInfo{Name: "root", Groups: []string{user.SystemPrivilegedGroup}}
+
+	first, err := a.Index(root, "", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []ClusterIndexEntry{
+		{Name: "dev", Parent: "k7c2q9x4", Cluster: "dev", Verbs: admin},
+		{Name: "infra", Parent: "org", Cluster: "infra", Verbs: admin},
+	}
+	if diff := cmp.Diff(expected, first.Items); diff != "" {
+		t.Errorf("unexpected first page (-want +got):\n%s", diff)
+	}
+	if first.Continue == "" {
+		t.Fatal("expected a continue token")
+	}
+	after, err := base64.RawURLEncoding.DecodeString(first.Continue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := a.Index(root, string(after), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []ClusterIndexEntry{{Name: "team", Parent: "org", Cluster: "k7c2q9x4", Verbs: admin}}
+	if diff := cmp.Diff(&ClusterIndex{Items: expected}, second); diff != "" {
+		t.Errorf("unexpected second page (-want +got):\n%s", diff)
+	}
+
+	// workspaces without access are left out
+	index, err := a.Index(&user.DefaultInfo{Name: "alice"}, "", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&ClusterIndex{Items: []ClusterIndexEntry{{Name: "team", Parent: "org", Cluster: "k7c2q9x4", Verbs: admin}}}, index); diff != "" {
+		t.Errorf("unexpected index (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workspacecontent
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"k8s.io/apiserver/pkg/authentication/user"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/tools/clusters"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+)
+
+// ClustersIndexPath is the non-resource path serving the index of the workspaces the
+// requesting user has access to, across all logical clusters.
+const ClustersIndexPath = "/clusters"
+
+// maxIndexLimit is the largest page of the index, also used when no limit is given.
+const maxIndexLimit = 500
+
+// ClusterIndex is a page of the workspaces a user has access to.
+type ClusterIndex struct {
+	Items []ClusterIndexEntry `json:"items"`
+	// Continue is set when there are more workspaces, and is passed as the continue query
+	// parameter to get the next page.
+	Continue string `json:"continue,omitempty"`
+}
+
+// ClusterIndexEntry is a workspace a user has access to.
+type ClusterIndexEntry struct {
+	// Name is the name of the workspace.
+	Name string `json:"name"`
+	// Parent is the logical cluster the Workspace object lives in.
+	Parent string `json:"parent"`
+	// Cluster is the name of the logical cluster of the workspace.
+	Cluster string                             `json:"cluster"`
+	Type    string                             `json:"type,omitempty"`
+	Phase   tenancyv1alpha1.WorkspacePhaseType `json:"phase,omitempty"`
+	URL     string                             `json:"url,omitempty"`
+	// Verbs are the verbs the user has on the workspace.
+	Verbs []tenancyv1alpha1.WorkspaceVerb `json:"verbs"`
+}
+
+// WithClustersIndex serves GET requests to the clusters index path with the ClusterIndex of
+// the requesting user, sorted by parent and name, and paginated with the limit and continue
+// query parameters, so that CLIs and consoles list the workspaces of a user without any
+// permission to list Workspaces across logical clusters. Every authenticated user is
+// authorized for it. It must be wrapped by the authentication and authorization filters.
+func WithClustersIndex(handler http.Handler, a *Authorizer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		info, ok := genericapirequest.RequestInfoFrom(req.Context())
+		if !ok || info.IsResourceRequest || info.Path != ClustersIndexPath {
+			handler.ServeHTTP(w, req)
+			return
+		}
+		if info.Verb != "get" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		u, ok := genericapirequest.UserFrom(req.Context())
+		if !ok {
+			http.Error(w, "no user to list the workspaces of", http.StatusBadRequest)
+			return
+		}
+		limit := maxIndexLimit
+		if value := req.URL.Query().Get("limit"); value != "" {
+			var err error
+			if limit, err = strconv.Atoi(value); err != nil || limit < 0 {
+				http.Error(w, fmt.Sprintf("invalid limit %q", value), http.StatusBadRequest)
+				return
+			}
+			if limit == 0 || limit > maxIndexLimit {
+				limit = maxIndexLimit
+			}
+		}
+		var after string
+		if value := req.URL.Query().Get("continue"); value != "" {
+			decoded, err := base64.RawURLEncoding.DecodeString(value)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("invalid continue %q", value), http.StatusBadRequest)
+				return
+			}
+			after = string(decoded)
+		}
+
+		index, err := a.Index(u, after, limit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(index)
+	})
+}
+
+// Index returns up to limit of the workspaces the user has access to, sorted by parent and
+// name, after the workspace of the given cluster-aware key if any.
+func (a *Authorizer) Index(u user.Info, after string, limit int) (*ClusterIndex, error) {
+	a.lock.RLock()
+	workspaceIndexer, bindingIndexer, hasSynced := a.workspaceIndexer, a.bindingIndexer, a.hasSynced
+	a.lock.RUnlock()
+	if hasSynced == nil || !hasSynced() {
+		return nil, errors.New("workspaces are not synced yet")
+	}
+
+	workspaces := map[string]*tenancyv1alpha1.Workspace{}
+	var keys []string
+	for _, obj := range workspaceIndexer.List() {
+		workspace := obj.(*tenancyv1alpha1.Workspace)
+		key := clusters.ToClusterAwareKey(workspace.ClusterName, workspace.Name)
+		if after != "" && key <= after {
+			continue
+		}
+		workspaces[key] = workspace
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	index := &ClusterIndex{Items: []ClusterIndexEntry{}}
+	for _, key := range keys {
+		node, err := treeNode(bindingIndexer, workspaces[key], u)
+		if err != nil {
+			return nil, err
+		}
+		if len(node.Verbs) == 0 {
+			continue
+		}
+		if len(index.Items) == limit {
+			last := index.Items[len(index.Items)-1]
+			index.Continue = base64.RawURLEncoding.EncodeToString([]byte(clusters.ToClusterAwareKey(last.Parent, last.Name)))
+			break
+		}
+		index.Items = append(index.Items, ClusterIndexEntry{
+			Name:    node.Name,
+			Parent:  workspaces[key].ClusterName,
+			Cluster: node.Cluster,
+			Type:    node.Type,
+			Phase:   node.Phase,
+			URL:     node.URL,
+			Verbs:   node.Verbs,
+		})
+	}
+	return index, nil
+}
+
+// isAuthenticated returns whether the user was authenticated, which all users but the
+// anonymous one are.
+func isAuthenticated(u user.Info) bool {
+	for _, group := range u.GetGroups() {
+		if group == user.AllAuthenticated {
+			return true
+		}
+	}
+	return false
+}
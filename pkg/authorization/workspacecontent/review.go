@@ -0,0 +1,138 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workspacecontent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"k8s.io/apiserver/pkg/authentication/user"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+
+	"github.com/kcp-dev/kcp/pkg/apis/tenancy/helpers/logicalcluster"
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/indexers"
+)
+
+// WorkspaceAccessReviewsPath is the non-resource path serving WorkspaceAccessReviews.
+const WorkspaceAccessReviewsPath = "/workspaceaccessreviews"
+
+// WorkspaceAccessReview asks which child workspaces of a workspace a user has a verb on.
+type WorkspaceAccessReview struct {
+	Spec   WorkspaceAccessReviewSpec   `json:"spec"`
+	Status WorkspaceAccessReviewStatus `json:"status,omitempty"`
+}
+
+// WorkspaceAccessReviewSpec is the user and the verb under review.
+type WorkspaceAccessReviewSpec struct {
+	// User is the user under review, the requesting user if empty.
+	User string `json:"user,omitempty"`
+	// Groups are the groups of the user under review, ignored for the requesting user.
+	Groups []string `json:"groups,omitempty"`
+	// Verb is the verb under review, access by default.
+	Verb tenancyv1alpha1.WorkspaceVerb `json:"verb,omitempty"`
+}
+
+// WorkspaceAccessReviewStatus lists the workspaces the user has the verb on.
+type WorkspaceAccessReviewStatus struct {
+	Workspaces []string `json:"workspaces"`
+}
+
+// WithWorkspaceAccessReviews serves POST requests to the workspace access reviews path of a
+// logical cluster with the names of the Workspaces of that logical cluster the user of the
+// WorkspaceAccessReview in the body has the verb on, for UIs listing the workspaces of a
+// user. It must be wrapped by the authentication and authorization filters.
+func WithWorkspaceAccessReviews(handler http.Handler, a *Authorizer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		info, ok := genericapirequest.RequestInfoFrom(req.Context())
+		if !ok || info.IsResourceRequest || info.Path != WorkspaceAccessReviewsPath {
+			handler.ServeHTTP(w, req)
+			return
+		}
+		if info.Verb != "post" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		cluster := genericapirequest.ClusterFrom(req.Context())
+		if cluster == nil || cluster.Wildcard {
+			http.Error(w, "workspace access reviews require a logical cluster", http.StatusBadRequest)
+			return
+		}
+
+		review := &WorkspaceAccessReview{}
+		if err := json.NewDecoder(req.Body).Decode(review); err != nil {
+			http.Error(w, fmt.Sprintf("invalid WorkspaceAccessReview: %v", err), http.StatusBadRequest)
+			return
+		}
+		var u user.Info = &user.DefaultInfo{Name: review.Spec.User, Groups: review.Spec.Groups}
+		if review.Spec.User == "" {
+			if u, ok = genericapirequest.UserFrom(req.Context()); !ok {
+				http.Error(w, "no user to review", http.StatusBadRequest)
+				return
+			}
+		}
+		verb := review.Spec.Verb
+		if verb == "" {
+			verb = tenancyv1alpha1.WorkspaceVerbAccess
+		}
+
+		workspaces, err := a.Workspaces(cluster.Name, u, verb)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		review.Status.Workspaces = workspaces
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(review)
+	})
+}
+
+// Workspaces returns the sorted names of the Workspaces of the given logical cluster the
+// user has the verb on: admin implies the other verbs, and any verb implies access.
+func (a *Authorizer) Workspaces(clusterName string, u user.Info, verb tenancyv1alpha1.WorkspaceVerb) ([]string, error) {
+	a.lock.RLock()
+	workspaceIndexer, bindingIndexer, hasSynced := a.workspaceIndexer, a.bindingIndexer, a.hasSynced
+	a.lock.RUnlock()
+	if hasSynced == nil || !hasSynced() {
+		return nil, errors.New("workspaces are not synced yet")
+	}
+
+	children, err := workspaceIndexer.ByIndex(indexers.WorkspaceParent, clusterName)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, obj := range children {
+		workspace := obj.(*tenancyv1alpha1.Workspace)
+		if isPrivileged(u) || issuedWithin(u, logicalcluster.Of(workspace)) {
+			names = append(names, workspace.Name)
+			continue
+		}
+		verbs, err := workspaceVerbs(bindingIndexer, workspace, u)
+		if err != nil {
+			return nil, err
+		}
+		if verbs[tenancyv1alpha1.WorkspaceVerbAdmin] || verbs[verb] || (verb == tenancyv1alpha1.WorkspaceVerbAccess && len(verbs) > 0) {
+			names = append(names, workspace.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
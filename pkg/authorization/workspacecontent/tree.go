@@ -0,0 +1,181 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workspacecontent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"k8s.io/apiserver/pkg/authentication/user"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/kcp-dev/kcp/pkg/apis/tenancy/helpers/logicalcluster"
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/indexers"
+)
+
+// WorkspaceTreePath is the non-resource path serving the tree of the workspaces below a
+// logical cluster.
+const WorkspaceTreePath = "/workspacetree"
+
+// WorkspaceTree is a workspace and its descendants.
+type WorkspaceTree struct {
+	// Name is the name of the workspace.
+	Name string `json:"name"`
+	// Cluster is the name of the logical cluster of the workspace.
+	Cluster string                             `json:"cluster,omitempty"`
+	Type    string                             `json:"type,omitempty"`
+	Phase   tenancyv1alpha1.WorkspacePhaseType `json:"phase,omitempty"`
+	Shard   string                             `json:"shard,omitempty"`
+	URL     string                             `json:"url,omitempty"`
+	// Verbs are the verbs the user has on the workspace, none if they have no access to it.
+	// The children of workspaces without access are not listed.
+	Verbs []tenancyv1alpha1.WorkspaceVerb `json:"verbs,omitempty"`
+	// Children are the child workspaces, sorted by name.
+	Children []*WorkspaceTree `json:"children,omitempty"`
+}
+
+// WithWorkspaceTree serves GET requests to the workspace tree path of a logical cluster with
+// the WorkspaceTree of that logical cluster for the requesting user, down to the depth given
+// in the query if any, so that clients get the whole hierarchy in one request instead of
+// listing the workspaces of each workspace. It must be wrapped by the authentication and
+// authorization filters.
+func WithWorkspaceTree(handler http.Handler, a *Authorizer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		info, ok := genericapirequest.RequestInfoFrom(req.Context())
+		if !ok || info.IsResourceRequest || info.Path != WorkspaceTreePath {
+			handler.ServeHTTP(w, req)
+			return
+		}
+		if info.Verb != "get" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		cluster := genericapirequest.ClusterFrom(req.Context())
+		if cluster == nil || cluster.Name == "" || cluster.Wildcard {
+			http.Error(w, "workspace trees require a logical cluster", http.StatusBadRequest)
+			return
+		}
+		u, ok := genericapirequest.UserFrom(req.Context())
+		if !ok {
+			http.Error(w, "no user to build the tree for", http.StatusBadRequest)
+			return
+		}
+		var depth int
+		if value := req.URL.Query().Get("depth"); value != "" {
+			var err error
+			if depth, err = strconv.Atoi(value); err != nil || depth < 0 {
+				http.Error(w, fmt.Sprintf("invalid depth %q", value), http.StatusBadRequest)
+				return
+			}
+		}
+
+		tree, err := a.Tree(cluster.Name, u, depth)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(tree)
+	})
+}
+
+// Tree returns the tree of the workspaces below the given logical cluster, as seen by the
+// user, down to the given depth, or the whole tree if zero. The tree only descends into the
+// workspaces the user has access to.
+func (a *Authorizer) Tree(clusterName string, u user.Info, depth int) (*WorkspaceTree, error) {
+	a.lock.RLock()
+	workspaceIndexer, bindingIndexer, hasSynced := a.workspaceIndexer, a.bindingIndexer, a.hasSynced
+	a.lock.RUnlock()
+	if hasSynced == nil || !hasSynced() {
+		return nil, errors.New("workspaces are not synced yet")
+	}
+
+	root := &WorkspaceTree{Name: clusterName, Cluster: clusterName}
+	workspaces, err := workspaceIndexer.ByIndex(indexers.WorkspaceCluster, clusterName)
+	if err != nil {
+		return nil, err
+	}
+	if len(workspaces) > 0 {
+		if root, err = treeNode(bindingIndexer, workspaces[0].(*tenancyv1alpha1.Workspace), u); err != nil {
+			return nil, err
+		}
+	}
+	// the user reached the logical cluster, so has access to it
+	visited := map[string]bool{clusterName: true}
+	if err := addChildren(workspaceIndexer, bindingIndexer, root, u, depth, visited); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+func addChildren(workspaceIndexer, bindingIndexer cache.Indexer, parent *WorkspaceTree, u user.Info, depth int, visited map[string]bool) error {
+	children, err := workspaceIndexer.ByIndex(indexers.WorkspaceParent, parent.Cluster)
+	if err != nil {
+		return err
+	}
+	for _, obj := range children {
+		child, err := treeNode(bindingIndexer, obj.(*tenancyv1alpha1.Workspace), u)
+		if err != nil {
+			return err
+		}
+		parent.Children = append(parent.Children, child)
+		if len(child.Verbs) == 0 || depth == 1 || visited[child.Cluster] {
+			continue
+		}
+		visited[child.Cluster] = true
+		if err := addChildren(workspaceIndexer, bindingIndexer, child, u, depth-1, visited); err != nil {
+			return err
+		}
+	}
+	sort.Slice(parent.Children, func(i, j int) bool {
+		return parent.Children[i].Name < parent.Children[j].Name
+	})
+	return nil
+}
+
+func treeNode(bindingIndexer cache.Indexer, workspace *tenancyv1alpha1.Workspace, u user.Info) (*WorkspaceTree, error) {
+	node := &WorkspaceTree{
+		Name:    workspace.Name,
+		Cluster: logicalcluster.Of(workspace),
+		Type:    workspace.Spec.Type,
+		Phase:   workspace.Status.Phase,
+		Shard:   workspace.Status.Location.Current,
+		URL:     workspace.Status.BaseURL,
+	}
+	switch {
+	case isPrivileged(u):
+		node.Verbs = []tenancyv1alpha1.WorkspaceVerb{tenancyv1alpha1.WorkspaceVerbAdmin}
+	case issuedWithin(u, logicalcluster.Of(workspace)):
+		node.Verbs = []tenancyv1alpha1.WorkspaceVerb{tenancyv1alpha1.WorkspaceVerbAccess}
+	default:
+		verbs, err := workspaceVerbs(bindingIndexer, workspace, u)
+		if err != nil {
+			return nil, err
+		}
+		for verb := range verbs {
+			node.Verbs = append(node.Verbs, verb)
+		}
+		sort.Slice(node.Verbs, func(i, j int) bool { return node.Verbs[i] < node.Verbs[j] })
+	}
+	return node, nil
+}
@@ -0,0 +1,368 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package backup exports the objects of workspaces through the API into a portable
+// archive, and imports them again, into the same or another kcp instance. Unlike etcd
+// snapshots, archives hold the objects of single tenants, without the state kcp derives
+// from them.
+//
+// An archive is a tar file holding one JSON List per workspace and resource, named
+// <workspace>/<group>/<version>/<resource>.json, with the "core" group for the legacy
+// API group. Archives are canonical: the entries are sorted by name, and neither the
+// entries nor the objects hold the time or the state of the server they were read from,
+// so that the archives of unchanged workspaces are identical.
+package backup
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/kcp-dev/kcp/pkg/client/clustercontext"
+)
+
+// RootWorkspace names the root logical cluster, served without a /clusters/... suffix.
+const RootWorkspace = "root"
+
+const coreGroup = "core"
+
+// skippedResources are not backed up: they are recorded by the server rather than
+// declared by tenants.
+var skippedResources = sets.NewString("events", "events.events.k8s.io")
+
+// restoreRetry waits for the resources of restored CustomResourceDefinitions to be served.
+var restoreRetry = wait.Backoff{Steps: 10, Duration: 100 * time.Millisecond, Factor: 1.5}
+
+// entryModTime is the modification time of all archive entries.
+var entryModTime = time.Unix(0, 0)
+
+// configFor returns the config of the given workspace of the kcp server reached with the
+// given config, whose host must not have a /clusters/... suffix.
+func configFor(config *rest.Config, workspace string) *rest.Config {
+	if workspace == RootWorkspace {
+		return rest.CopyConfig(config)
+	}
+	return clustercontext.ConfigFor(config, workspace)
+}
+
+// Backup writes the objects of the given workspaces of the kcp server reached with the given
+// config as an archive to out, and the progress to log.
+func Backup(ctx context.Context, config *rest.Config, workspaces []string, out io.Writer, log io.Writer) error {
+	return backup(ctx, workspaces, func(workspace string) *rest.Config {
+		return configFor(config, workspace)
+	}, out, log)
+}
+
+func backup(ctx context.Context, workspaces []string, configFor func(workspace string) *rest.Config, out io.Writer, log io.Writer) error {
+	tw := tar.NewWriter(out)
+	for _, workspace := range workspaces {
+		workspaceConfig := configFor(workspace)
+		discoveryClient, err := discovery.NewDiscoveryClientForConfig(workspaceConfig)
+		if err != nil {
+			return err
+		}
+		client, err := dynamic.NewForConfig(workspaceConfig)
+		if err != nil {
+			return err
+		}
+		resources, err := backedUpResources(discoveryClient)
+		if err != nil {
+			return fmt.Errorf("failed to discover the resources of workspace %q: %w", workspace, err)
+		}
+		if err := backupWorkspace(ctx, tw, workspace, resources, client, log); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// backedUpResources returns the resources of the discovery which can be restored.
+func backedUpResources(client discovery.DiscoveryInterface) ([]schema.GroupVersionResource, error) {
+	lists, err := client.ServerPreferredResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, err
+	}
+	var resources []schema.GroupVersionResource
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			return nil, err
+		}
+		for _, resource := range list.APIResources {
+			verbs := sets.NewString(resource.Verbs...)
+			if strings.Contains(resource.Name, "/") || !verbs.HasAll("list", "create") {
+				continue
+			}
+			gvr := gv.WithResource(resource.Name)
+			if skippedResources.Has(gvr.GroupResource().String()) {
+				continue
+			}
+			resources = append(resources, gvr)
+		}
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		return entryName("", resources[i]) < entryName("", resources[j])
+	})
+	return resources, nil
+}
+
+func backupWorkspace(ctx context.Context, tw *tar.Writer, workspace string, resources []schema.GroupVersionResource, client dynamic.Interface, log io.Writer) error {
+	for _, gvr := range resources {
+		list := &unstructured.UnstructuredList{Object: map[string]interface{}{"apiVersion": "v1", "kind": "List"}}
+		opts := metav1.ListOptions{Limit: 500}
+		for {
+			page, err := client.Resource(gvr).List(ctx, opts)
+			if err != nil {
+				return fmt.Errorf("failed to list %s of workspace %q: %w", gvr.GroupResource(), workspace, err)
+			}
+			for _, item := range page.Items {
+				if isGenerated(item) {
+					continue
+				}
+				sanitize(&item)
+				list.Items = append(list.Items, item)
+			}
+			if opts.Continue = page.GetContinue(); opts.Continue == "" {
+				break
+			}
+		}
+		if len(list.Items) == 0 {
+			continue
+		}
+		sort.Slice(list.Items, func(i, j int) bool {
+			if list.Items[i].GetNamespace() != list.Items[j].GetNamespace() {
+				return list.Items[i].GetNamespace() < list.Items[j].GetNamespace()
+			}
+			return list.Items[i].GetName() < list.Items[j].GetName()
+		})
+
+		data, err := list.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    entryName(workspace, gvr),
+			Mode:    0600,
+			Size:    int64(len(data)),
+			ModTime: entryModTime,
+		}); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+		fmt.Fprintf(log, "Backed up %d %s of workspace %q\n", len(list.Items), gvr.GroupResource(), workspace)
+	}
+	return nil
+}
+
+// isGenerated returns whether the object is generated by the server of each workspace, or
+// by the controller owning it, and must not be restored.
+func isGenerated(obj unstructured.Unstructured) bool {
+	if metav1.GetControllerOfNoCopy(&obj) != nil {
+		return true
+	}
+	if obj.GetAPIVersion() == "v1" && obj.GetKind() == "Secret" {
+		secretType, _, _ := unstructured.NestedString(obj.Object, "type")
+		return secretType == string(corev1.SecretTypeServiceAccountToken)
+	}
+	return false
+}
+
+// sanitize drops the metadata which is specific to the server the object was read from,
+// and the status, which is observed by the server rather than declared. Owner references
+// are dropped too, since the owners get other UIDs when they are restored and the garbage
+// collector would delete objects referencing the old ones.
+func sanitize(obj *unstructured.Unstructured) {
+	obj.SetResourceVersion("")
+	obj.SetUID("")
+	obj.SetSelfLink("")
+	obj.SetCreationTimestamp(metav1.Time{})
+	obj.SetManagedFields(nil)
+	obj.SetOwnerReferences(nil)
+	obj.SetClusterName("")
+	unstructured.RemoveNestedField(obj.Object, "metadata", "generation")
+	unstructured.RemoveNestedField(obj.Object, "status")
+}
+
+func entryName(workspace string, gvr schema.GroupVersionResource) string {
+	group := gvr.Group
+	if group == "" {
+		group = coreGroup
+	}
+	return path.Join(workspace, group, gvr.Version, gvr.Resource+".json")
+}
+
+func parseEntryName(name string) (string, schema.GroupVersionResource, error) {
+	parts := strings.Split(strings.TrimSuffix(name, ".json"), "/")
+	if len(parts) != 4 || !strings.HasSuffix(name, ".json") {
+		return "", schema.GroupVersionResource{}, fmt.Errorf("unexpected archive entry %q", name)
+	}
+	group := parts[1]
+	if group == coreGroup {
+		group = ""
+	}
+	return parts[0], schema.GroupVersionResource{Group: group, Version: parts[2], Resource: parts[3]}, nil
+}
+
+// Restore creates the objects of the archive read from in, in the workspaces of the kcp
+// server reached with the given config, and writes the progress to log. Only the given
+// workspaces are restored, unless none are given. Existing objects are left untouched.
+func Restore(ctx context.Context, config *rest.Config, in io.Reader, workspaces []string, log io.Writer) error {
+	return restore(ctx, in, workspaces, func(workspace string) (dynamic.Interface, error) {
+		return dynamic.NewForConfig(configFor(config, workspace))
+	}, log)
+}
+
+// RestoreInto creates the objects of the given workspace of the archive read from in, in
+// the workspace reached with the given config, and writes the progress to log. The archive
+// may only hold a single workspace when none is given. Existing objects are left untouched.
+func RestoreInto(ctx context.Context, workspaceConfig *rest.Config, in io.Reader, from string, log io.Writer) error {
+	var workspaces []string
+	if from != "" {
+		workspaces = []string{from}
+	}
+	entries, err := readEntries(in, workspaces)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.workspace != entries[0].workspace {
+			return fmt.Errorf("the archive holds workspaces %q and %q, select the one to restore", entries[0].workspace, e.workspace)
+		}
+	}
+	return restoreEntries(ctx, entries, func(string) (dynamic.Interface, error) {
+		return dynamic.NewForConfig(workspaceConfig)
+	}, log)
+}
+
+type entry struct {
+	workspace string
+	gvr       schema.GroupVersionResource
+	list      *unstructured.UnstructuredList
+}
+
+func restore(ctx context.Context, in io.Reader, workspaces []string, clientFor func(workspace string) (dynamic.Interface, error), log io.Writer) error {
+	entries, err := readEntries(in, workspaces)
+	if err != nil {
+		return err
+	}
+	return restoreEntries(ctx, entries, clientFor, log)
+}
+
+// readEntries reads the entries of the given workspaces of the archive, or of all
+// workspaces if none are given, in the order they are restored in.
+func readEntries(in io.Reader, workspaces []string) ([]entry, error) {
+	selected := sets.NewString(workspaces...)
+	var entries []entry
+	tr := tar.NewReader(in)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read the archive: %w", err)
+		}
+		workspace, gvr, err := parseEntryName(header.Name)
+		if err != nil {
+			return nil, err
+		}
+		if selected.Len() > 0 && !selected.Has(workspace) {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		list := &unstructured.UnstructuredList{}
+		if err := list.UnmarshalJSON(data); err != nil {
+			return nil, fmt.Errorf("failed to decode archive entry %q: %w", header.Name, err)
+		}
+		entries = append(entries, entry{workspace: workspace, gvr: gvr, list: list})
+	}
+
+	// the types and namespaces come before the objects using them
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].workspace != entries[j].workspace {
+			return entries[i].workspace < entries[j].workspace
+		}
+		return restoreOrder(entries[i].gvr) < restoreOrder(entries[j].gvr)
+	})
+	return entries, nil
+}
+
+func restoreEntries(ctx context.Context, entries []entry, clientFor func(workspace string) (dynamic.Interface, error), log io.Writer) error {
+	var errs []error
+	clients := map[string]dynamic.Interface{}
+	for _, e := range entries {
+		client, found := clients[e.workspace]
+		if !found {
+			var err error
+			if client, err = clientFor(e.workspace); err != nil {
+				return err
+			}
+			clients[e.workspace] = client
+		}
+		created, existing := 0, 0
+		for i := range e.list.Items {
+			item := &e.list.Items[i]
+			err := retry.OnError(restoreRetry, apierrors.IsNotFound, func() error {
+				_, err := client.Resource(e.gvr).Namespace(item.GetNamespace()).Create(ctx, item, metav1.CreateOptions{})
+				return err
+			})
+			switch {
+			case err == nil:
+				created++
+			case apierrors.IsAlreadyExists(err):
+				existing++
+			default:
+				errs = append(errs, fmt.Errorf("failed to restore %s %s/%s in workspace %q: %w", e.gvr.GroupResource(), item.GetNamespace(), item.GetName(), e.workspace, err))
+			}
+		}
+		fmt.Fprintf(log, "Restored %d %s of workspace %q, %d already existed\n", created, e.gvr.GroupResource(), e.workspace, existing)
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func restoreOrder(gvr schema.GroupVersionResource) int {
+	switch gvr.GroupResource().String() {
+	case "customresourcedefinitions.apiextensions.k8s.io":
+		return 0
+	case "namespaces":
+		return 1
+	default:
+		return 2
+	}
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+var (
+	namespaces = schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+	configMaps = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	secrets    = schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
+	listKinds  = map[schema.GroupVersionResource]string{
+		namespaces: "NamespaceList",
+		configMaps: "ConfigMapList",
+		secrets:    "SecretList",
+	}
+)
+
+func newObject(apiVersion, kind, namespace, name string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	obj.SetResourceVersion("42")
+	obj.SetUID("uid")
+	return obj
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	owned := newObject("v1", "ConfigMap", "default", "owned")
+	controller := true
+	owned.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "v1", Kind: "ConfigMap", Name: "config", UID: "uid", Controller: &controller}})
+	token := newObject("v1", "Secret", "default", "token")
+	token.Object["type"] = "kubernetes.io/service-account-token"
+
+	source := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds,
+		newObject("v1", "Namespace", "", "default"),
+		newObject("v1", "ConfigMap", "default", "config"),
+		owned,
+		token,
+	)
+
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	// the namespaces are restored first whatever their order in the archive
+	if err := backupWorkspace(context.Background(), tw, "team", []schema.GroupVersionResource{configMaps, secrets, namespaces}, source, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	target := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds,
+		// already in the target, left untouched
+		newObject("v1", "ConfigMap", "default", "config"),
+	)
+	var restoredWorkspaces []string
+	if err := restore(context.Background(), &archive, nil, func(workspace string) (dynamic.Interface, error) {
+		restoredWorkspaces = append(restoredWorkspaces, workspace)
+		return target, nil
+	}, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if len(restoredWorkspaces) != 1 || restoredWorkspaces[0] != "team" {
+		t.Errorf("expected workspace team to be restored, got %v", restoredWorkspaces)
+	}
+
+	var creates []string
+	for _, action := range target.Actions() {
+		if action.GetVerb() == "create" {
+			creates = append(creates, action.GetResource().Resource)
+		}
+	}
+	if want := []string{"namespaces", "configmaps"}; len(creates) != len(want) || creates[0] != want[0] || creates[1] != want[1] {
+		t.Errorf("expected creates of %v, got %v", want, creates)
+	}
+	restoredConfigMaps, err := target.Resource(configMaps).Namespace("default").List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(restoredConfigMaps.Items) != 1 || restoredConfigMaps.Items[0].GetName() != "config" {
+		t.Errorf("expected only the config ConfigMap, got %v", restoredConfigMaps.Items)
+	}
+	restoredSecrets, err := target.Resource(secrets).Namespace("default").List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(restoredSecrets.Items) != 0 {
+		t.Errorf("expected the token Secret not to be restored, got %v", restoredSecrets.Items)
+	}
+	namespace, err := target.Resource(namespaces).Get(context.Background(), "default", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if namespace.GetResourceVersion() != "" || namespace.GetUID() != "" {
+		t.Errorf("expected the server metadata to be dropped, got %v", namespace.Object["metadata"])
+	}
+}
+
+func TestBackupIsCanonical(t *testing.T) {
+	withStatus := newObject("v1", "Namespace", "", "default")
+	withStatus.Object["status"] = map[string]interface{}{"phase": "Active"}
+	source := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds,
+		withStatus,
+		newObject("v1", "ConfigMap", "default", "b"),
+		newObject("v1", "ConfigMap", "default", "a"),
+	)
+
+	archive := func(resources []schema.GroupVersionResource) []byte {
+		var out bytes.Buffer
+		tw := tar.NewWriter(&out)
+		if err := backupWorkspace(context.Background(), tw, "team", resources, source, io.Discard); err != nil {
+			t.Fatal(err)
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return out.Bytes()
+	}
+	first := archive([]schema.GroupVersionResource{namespaces, configMaps})
+	if second := archive([]schema.GroupVersionResource{namespaces, configMaps}); !bytes.Equal(first, second) {
+		t.Errorf("expected exports of the same workspace to be identical")
+	}
+	if bytes.Contains(first, []byte(`"status"`)) {
+		t.Errorf("expected the status to be dropped from the archive")
+	}
+}
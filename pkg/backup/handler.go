@@ -0,0 +1,312 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/handlers/responsewriters"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clusters"
+	"k8s.io/klog/v2"
+
+	tenancyapi "github.com/kcp-dev/kcp/pkg/apis/tenancy"
+	"github.com/kcp-dev/kcp/pkg/apis/tenancy/helpers/logicalcluster"
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	"github.com/kcp-dev/kcp/pkg/client/clustercontext"
+	"github.com/kcp-dev/kcp/pkg/sharding"
+)
+
+const (
+	// ExportSubresource is the subresource of Workspaces streaming their archive.
+	ExportSubresource = "export"
+	// ImportSubresource is the subresource of Workspaces importing an archive into them.
+	ImportSubresource = "import"
+
+	// ArchiveContentType is the content type of archives.
+	ArchiveContentType = "application/x-tar"
+)
+
+// ImportPhase is the phase of an ImportJob.
+type ImportPhase string
+
+const (
+	ImportPhaseRunning   ImportPhase = "Running"
+	ImportPhaseSucceeded ImportPhase = "Succeeded"
+	ImportPhaseFailed    ImportPhase = "Failed"
+)
+
+// ImportJob is the state of the import of an archive into a workspace.
+type ImportJob struct {
+	// Workspace is the workspace the archive is imported into.
+	Workspace string      `json:"workspace"`
+	Phase     ImportPhase `json:"phase"`
+	// Message tells why the import failed.
+	Message        string       `json:"message,omitempty"`
+	StartTime      metav1.Time  `json:"startTime"`
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// Log is the progress of the import, one line per restored resource.
+	Log string `json:"log,omitempty"`
+}
+
+// Archives exports the archives of workspaces and imports archives into them, through the
+// API of the kcp server reached with its config, as the user requesting them. It keeps the
+// last import job of every workspace.
+type Archives struct {
+	ctx       context.Context
+	config    *rest.Config
+	kcpClient kcpclient.ClusterInterface
+
+	lock sync.Mutex
+	jobs map[string]*ImportJob
+	logs map[string]*bytes.Buffer
+}
+
+// NewArchives returns Archives using the given privileged config, whose host must not have
+// a /clusters/... suffix, to look up workspaces and impersonate the requesting users.
+// Imports are cancelled when the given context is done.
+func NewArchives(ctx context.Context, config *rest.Config) (*Archives, error) {
+	kcpClient, err := kcpclient.NewClusterForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Archives{
+		ctx:       ctx,
+		config:    config,
+		kcpClient: kcpClient,
+		jobs:      map[string]*ImportJob{},
+		logs:      map[string]*bytes.Buffer{},
+	}, nil
+}
+
+// WithWorkspaceArchives serves the export and import subresources of Workspaces, reading
+// and writing the logical clusters of the workspaces as the requesting user:
+//
+//   - a GET of workspaces/<name>/export streams the archive of the workspace,
+//   - a POST of an archive to workspaces/<name>/import starts an ImportJob importing it
+//     into the workspace, and returns it with 202 Accepted,
+//   - a GET of workspaces/<name>/import returns the last ImportJob of the workspace.
+//
+// Archives holding several workspaces are imported from the one named by the workspace
+// query parameter, e.g. when the workspace had another name where it was exported. It must
+// be wrapped by the authentication and authorization filters, which authorize the
+// subresources like any other.
+func WithWorkspaceArchives(handler http.Handler, archives *Archives) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		info, ok := genericapirequest.RequestInfoFrom(req.Context())
+		if !ok || !isArchiveRequest(info) {
+			handler.ServeHTTP(w, req)
+			return
+		}
+		gv := schema.GroupVersion{Group: info.APIGroup, Version: info.APIVersion}
+		fail := func(err error) {
+			responsewriters.ErrorNegotiated(err, scheme.Codecs, gv, w, req)
+		}
+		cluster := genericapirequest.ClusterFrom(req.Context())
+		if cluster == nil || cluster.Name == "" || cluster.Wildcard {
+			fail(apierrors.NewBadRequest("workspace archives require a logical cluster"))
+			return
+		}
+		u, ok := genericapirequest.UserFrom(req.Context())
+		if !ok {
+			fail(apierrors.NewUnauthorized("workspace archives require an authenticated user"))
+			return
+		}
+		workspaceConfig, err := archives.workspaceConfig(req.Context(), cluster.Name, info.Name, u)
+		if err != nil {
+			fail(err)
+			return
+		}
+
+		switch {
+		case info.Subresource == ExportSubresource && info.Verb == "get":
+			archives.export(w, req, workspaceConfig, info.Name, fail)
+		case info.Subresource == ImportSubresource && info.Verb == "create":
+			job, err := archives.startImport(workspaceConfig, cluster.Name, info.Name, req.URL.Query().Get("workspace"), req.Body)
+			if err != nil {
+				fail(err)
+				return
+			}
+			responsewriters.WriteRawJSON(http.StatusAccepted, job, w)
+		case info.Subresource == ImportSubresource && info.Verb == "get":
+			job, found := archives.Job(cluster.Name, info.Name)
+			if !found {
+				fail(apierrors.NewNotFound(schema.GroupResource{Group: tenancyapi.GroupName, Resource: "workspaces/" + ImportSubresource}, info.Name))
+				return
+			}
+			responsewriters.WriteRawJSON(http.StatusOK, job, w)
+		default:
+			fail(apierrors.NewMethodNotSupported(schema.GroupResource{Group: tenancyapi.GroupName, Resource: "workspaces/" + info.Subresource}, info.Verb))
+		}
+	})
+}
+
+func isArchiveRequest(info *genericapirequest.RequestInfo) bool {
+	return info.IsResourceRequest && info.APIGroup == tenancyapi.GroupName && info.Resource == "workspaces" && info.Name != "" &&
+		(info.Subresource == ExportSubresource || info.Subresource == ImportSubresource)
+}
+
+// LongRunning makes the given check consider the exports long-running, so that the
+// archives of large workspaces are not cut by the request timeout.
+func LongRunning(check genericapirequest.LongRunningRequestCheck) genericapirequest.LongRunningRequestCheck {
+	return func(r *http.Request, info *genericapirequest.RequestInfo) bool {
+		if info != nil && isArchiveRequest(info) && info.Subresource == ExportSubresource {
+			return true
+		}
+		return check != nil && check(r, info)
+	}
+}
+
+// workspaceConfig returns the config of the logical cluster of the workspace of the given
+// logical cluster, impersonating the given user, so that the archives hold and import what
+// the user may read and write only. It returns an error unless the workspace exists.
+func (a *Archives) workspaceConfig(ctx context.Context, clusterName, name string, u user.Info) (*rest.Config, error) {
+	workspace, err := a.kcpClient.Cluster(clusterName).TenancyV1alpha1().Workspaces().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return sharding.Impersonating(clustercontext.ConfigFor(a.config, logicalcluster.Of(workspace)), u), nil
+}
+
+func (a *Archives) export(w http.ResponseWriter, req *http.Request, workspaceConfig *rest.Config, workspace string, fail func(error)) {
+	w.Header().Set("Content-Type", ArchiveContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", workspace+".tar"))
+	out := &writeTracker{Writer: w}
+	err := backup(req.Context(), []string{workspace}, func(string) *rest.Config { return workspaceConfig }, out, io.Discard)
+	if err != nil {
+		if !out.written {
+			fail(apierrors.NewInternalError(err))
+			return
+		}
+		// the client already got part of the archive, so the connection is aborted for it
+		// to notice that the archive is incomplete
+		klog.Errorf("Failed to export workspace %q: %v", workspace, err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// startImport starts importing the archive read from in, from the given workspace of the
+// archive if any, into the workspace of the given logical cluster reached with the given
+// config, unless an import into it is running already.
+func (a *Archives) startImport(workspaceConfig *rest.Config, clusterName, workspace, from string, in io.Reader) (*ImportJob, error) {
+	key := clusters.ToClusterAwareKey(clusterName, workspace)
+	a.lock.Lock()
+	if job, found := a.jobs[key]; found && job.Phase == ImportPhaseRunning {
+		a.lock.Unlock()
+		return nil, apierrors.NewConflict(schema.GroupResource{Group: tenancyapi.GroupName, Resource: "workspaces/" + ImportSubresource}, workspace, fmt.Errorf("an import is running already"))
+	}
+	job := &ImportJob{Workspace: workspace, Phase: ImportPhaseRunning, StartTime: metav1.Now()}
+	log := &bytes.Buffer{}
+	a.jobs[key], a.logs[key] = job, log
+	a.lock.Unlock()
+
+	// the archive is buffered in a file, since the import outlives the request
+	archive, err := bufferArchive(in)
+	if err != nil {
+		a.complete(key, err)
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("failed to read the archive: %v", err))
+	}
+	go func() {
+		defer os.Remove(archive.Name())
+		defer archive.Close()
+		err := RestoreInto(a.ctx, workspaceConfig, archive, from, &lockedWriter{lock: &a.lock, w: log})
+		a.complete(key, err)
+	}()
+
+	started, _ := a.Job(clusterName, workspace)
+	return started, nil
+}
+
+func bufferArchive(in io.Reader) (*os.File, error) {
+	archive, err := os.CreateTemp("", "kcp-import-*.tar")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(archive, in); err != nil {
+		archive.Close()
+		os.Remove(archive.Name())
+		return nil, err
+	}
+	if _, err := archive.Seek(0, io.SeekStart); err != nil {
+		archive.Close()
+		os.Remove(archive.Name())
+		return nil, err
+	}
+	return archive, nil
+}
+
+func (a *Archives) complete(key string, err error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	job := a.jobs[key]
+	now := metav1.Now()
+	job.CompletionTime = &now
+	if err != nil {
+		job.Phase, job.Message = ImportPhaseFailed, err.Error()
+	} else {
+		job.Phase = ImportPhaseSucceeded
+	}
+}
+
+// Job returns the last import job of the workspace of the given logical cluster.
+func (a *Archives) Job(clusterName, workspace string) (*ImportJob, bool) {
+	key := clusters.ToClusterAwareKey(clusterName, workspace)
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	job, found := a.jobs[key]
+	if !found {
+		return nil, false
+	}
+	copied := *job
+	copied.Log = a.logs[key].String()
+	return &copied, true
+}
+
+// writeTracker records whether anything was written.
+type writeTracker struct {
+	io.Writer
+	written bool
+}
+
+func (w *writeTracker) Write(p []byte) (int, error) {
+	w.written = true
+	return w.Writer.Write(p)
+}
+
+// lockedWriter writes with the lock held, so that logs can be read while being written.
+type lockedWriter struct {
+	lock *sync.Mutex
+	w    io.Writer
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.w.Write(p)
+}
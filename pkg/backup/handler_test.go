@@ -0,0 +1,230 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apiserver/pkg/authentication/user"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/rest"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	kcpfake "github.com/kcp-dev/kcp/pkg/client/clientset/versioned/fake"
+)
+
+// kcpClients holds a fake for each logical cluster.
+type kcpClients map[string]*kcpfake.Clientset
+
+func (c kcpClients) Cluster(name string) kcpclient.Interface {
+	if c[name] == nil {
+		c[name] = kcpfake.NewSimpleClientset()
+	}
+	return c[name]
+}
+
+// request is a request served by the fake kcp server.
+type request struct {
+	method, path, user string
+}
+
+// fakeServer serves a ConfigMap named after the logical cluster in every logical cluster,
+// and records the requests.
+type fakeServer struct {
+	lock     sync.Mutex
+	requests []request
+}
+
+func (s *fakeServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	s.lock.Lock()
+	s.requests = append(s.requests, request{method: req.Method, path: req.URL.Path, user: req.Header.Get(authenticationv1.ImpersonateUserHeader)})
+	s.lock.Unlock()
+
+	parts := strings.SplitN(strings.TrimPrefix(req.URL.Path, "/clusters/"), "/", 2)
+	if len(parts) != 2 {
+		http.NotFound(w, req)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case parts[1] == "api":
+		fmt.Fprint(w, `{"kind":"APIVersions","versions":["v1"]}`)
+	case parts[1] == "apis":
+		fmt.Fprint(w, `{"kind":"APIGroupList","groups":[]}`)
+	case parts[1] == "api/v1":
+		fmt.Fprint(w, `{"kind":"APIResourceList","groupVersion":"v1","resources":[{"name":"configmaps","namespaced":true,"kind":"ConfigMap","verbs":["list","create"]}]}`)
+	case parts[1] == "api/v1/configmaps" && req.Method == http.MethodGet:
+		fmt.Fprintf(w, `{"kind":"ConfigMapList","apiVersion":"v1","metadata":{},"items":[{"metadata":{"name":"from-%s","namespace":"default"}}]}`, parts[0])
+	case parts[1] == "api/v1/namespaces/default/configmaps" && req.Method == http.MethodPost:
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.Copy(w, req.Body)
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func TestWithWorkspaceArchives(t *testing.T) {
+	workspace := func(cluster, name, logicalCluster string) *tenancyv1alpha1.Workspace {
+		return &tenancyv1alpha1.Workspace{
+			ObjectMeta: metav1.ObjectMeta{Name: name, ClusterName: cluster},
+			Status:     tenancyv1alpha1.WorkspaceStatus{Cluster: logicalCluster},
+		}
+	}
+	alice := &user.DefaultInfo{Name: "alice", Groups: []string{user.AllAuthenticated}}
+
+	for _, tc := range []struct {
+		name             string
+		cluster          string
+		workspace        string
+		subresource      string
+		verb             string
+		expectedCode     int
+		expectedRequests []request
+	}{
+		{
+			name:         "export of a nested workspace",
+			cluster:      "a7c2m9e4",
+			workspace:    "team",
+			subresource:  ExportSubresource,
+			verb:         "get",
+			expectedCode: http.StatusOK,
+			expectedRequests: []request{
+				{method: http.MethodGet, path: "/clusters/n3s7t3am/api", user: "alice"},
+				{method: http.MethodGet, path: "/clusters/n3s7t3am/apis", user: "alice"},
+				{method: http.MethodGet, path: "/clusters/n3s7t3am/api/v1", user: "alice"},
+				{method: http.MethodGet, path: "/clusters/n3s7t3am/api/v1/configmaps", user: "alice"},
+			},
+		},
+		{
+			name:         "export of the workspace of the same name under root",
+			cluster:      "admin",
+			workspace:    "team",
+			subresource:  ExportSubresource,
+			verb:         "get",
+			expectedCode: http.StatusOK,
+			expectedRequests: []request{
+				{method: http.MethodGet, path: "/clusters/r0o7t3am/api", user: "alice"},
+				{method: http.MethodGet, path: "/clusters/r0o7t3am/apis", user: "alice"},
+				{method: http.MethodGet, path: "/clusters/r0o7t3am/api/v1", user: "alice"},
+				{method: http.MethodGet, path: "/clusters/r0o7t3am/api/v1/configmaps", user: "alice"},
+			},
+		},
+		{
+			name:         "import into a nested workspace",
+			cluster:      "a7c2m9e4",
+			workspace:    "team",
+			subresource:  ImportSubresource,
+			verb:         "create",
+			expectedCode: http.StatusAccepted,
+			expectedRequests: []request{
+				{method: http.MethodPost, path: "/clusters/n3s7t3am/api/v1/namespaces/default/configmaps", user: "alice"},
+			},
+		},
+		{
+			name:         "unknown workspace",
+			cluster:      "a7c2m9e4",
+			workspace:    "other",
+			subresource:  ExportSubresource,
+			verb:         "get",
+			expectedCode: http.StatusNotFound,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server := &fakeServer{}
+			backend := httptest.NewServer(server)
+			defer backend.Close()
+			archives := &Archives{
+				ctx:    context.Background(),
+				config: &rest.Config{Host: backend.URL},
+				kcpClient: kcpClients{
+					"admin":    kcpfake.NewSimpleClientset(workspace("admin", "acme", "a7c2m9e4"), workspace("admin", "team", "r0o7t3am")),
+					"a7c2m9e4": kcpfake.NewSimpleClientset(workspace("a7c2m9e4", "team", "n3s7t3am")),
+				},
+				jobs: map[string]*ImportJob{},
+				logs: map[string]*bytes.Buffer{},
+			}
+			handler := WithWorkspaceArchives(http.NotFoundHandler(), archives)
+
+			var body io.Reader
+			if tc.subresource == ImportSubresource {
+				var archive bytes.Buffer
+				tw := tar.NewWriter(&archive)
+				data := []byte(`{"apiVersion":"v1","kind":"List","items":[{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"settings","namespace":"default"}}]}`)
+				if err := tw.WriteHeader(&tar.Header{Name: "team/core/v1/configmaps.json", Mode: 0600, Size: int64(len(data))}); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := tw.Write(data); err != nil {
+					t.Fatal(err)
+				}
+				if err := tw.Close(); err != nil {
+					t.Fatal(err)
+				}
+				body = &archive
+			}
+			req := httptest.NewRequest(http.MethodGet, "/apis/tenancy.kcp.dev/v1alpha1/workspaces/"+tc.workspace+"/"+tc.subresource, body)
+			ctx := genericapirequest.WithCluster(req.Context(), genericapirequest.Cluster{Name: tc.cluster})
+			ctx = genericapirequest.WithUser(ctx, alice)
+			ctx = genericapirequest.WithRequestInfo(ctx, &genericapirequest.RequestInfo{
+				IsResourceRequest: true,
+				Verb:              tc.verb,
+				APIGroup:          "tenancy.kcp.dev",
+				APIVersion:        "v1alpha1",
+				Resource:          "workspaces",
+				Name:              tc.workspace,
+				Subresource:       tc.subresource,
+			})
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req.WithContext(ctx))
+
+			if w.Code != tc.expectedCode {
+				t.Fatalf("expected status %d, got %d: %s", tc.expectedCode, w.Code, w.Body.String())
+			}
+			if tc.subresource == ImportSubresource {
+				if err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+					job, found := archives.Job(tc.cluster, tc.workspace)
+					return found && job.Phase != ImportPhaseRunning, nil
+				}); err != nil {
+					t.Fatal(err)
+				}
+				if job, _ := archives.Job(tc.cluster, tc.workspace); job.Phase != ImportPhaseSucceeded {
+					t.Errorf("expected the import to succeed, got %+v", job)
+				}
+			}
+			server.lock.Lock()
+			defer server.lock.Unlock()
+			if diff := cmp.Diff(tc.expectedRequests, server.requests, cmp.AllowUnexported(request{})); diff != "" {
+				t.Errorf("unexpected requests (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
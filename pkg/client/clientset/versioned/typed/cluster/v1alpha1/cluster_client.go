@@ -28,6 +28,8 @@ import (
 type ClusterV1alpha1Interface interface {
 	RESTClient() rest.Interface
 	ClustersGetter
+	PlacementsGetter
+	SyncTransformsGetter
 }
 
 // ClusterV1alpha1Client is used to interact with features provided by the cluster.example.dev group.
@@ -40,6 +42,14 @@ func (c *ClusterV1alpha1Client) Clusters() ClusterInterface {
 	return newClusters(c)
 }
 
+func (c *ClusterV1alpha1Client) Placements() PlacementInterface {
+	return newPlacements(c)
+}
+
+func (c *ClusterV1alpha1Client) SyncTransforms() SyncTransformInterface {
+	return newSyncTransforms(c)
+}
+
 // NewForConfig creates a new ClusterV1alpha1Client for the given config.
 func NewForConfig(c *rest.Config) (*ClusterV1alpha1Client, error) {
 	config := *c
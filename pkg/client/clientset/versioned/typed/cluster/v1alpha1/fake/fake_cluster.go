@@ -20,6 +20,8 @@ package fake
 
 import (
 	"context"
+	json "encoding/json"
+	"fmt"
 
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	labels "k8s.io/apimachinery/pkg/labels"
@@ -29,6 +31,7 @@ import (
 	testing "k8s.io/client-go/testing"
 
 	v1alpha1 "github.com/kcp-dev/kcp/pkg/apis/cluster/v1alpha1"
+	clusterv1alpha1 "github.com/kcp-dev/kcp/pkg/client/applyconfiguration/cluster/v1alpha1"
 )
 
 // FakeClusters implements ClusterInterface
@@ -132,3 +135,46 @@ func (c *FakeClusters) Patch(ctx context.Context, name string, pt types.PatchTyp
 	}
 	return obj.(*v1alpha1.Cluster), err
 }
+
+// Apply takes the given apply declarative configuration, applies it and returns the applied cluster.
+func (c *FakeClusters) Apply(ctx context.Context, cluster *clusterv1alpha1.ClusterApplyConfiguration, opts v1.ApplyOptions) (result *v1alpha1.Cluster, err error) {
+	if cluster == nil {
+		return nil, fmt.Errorf("cluster provided to Apply must not be nil")
+	}
+	data, err := json.Marshal(cluster)
+	if err != nil {
+		return nil, err
+	}
+	name := cluster.Name
+	if name == nil {
+		return nil, fmt.Errorf("cluster.Name must be provided to Apply")
+	}
+	obj, err := c.Fake.
+		Invokes(testing.NewRootPatchSubresourceAction(clustersResource, *name, types.ApplyPatchType, data), &v1alpha1.Cluster{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.Cluster), err
+}
+
+// ApplyStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating ApplyStatus().
+func (c *FakeClusters) ApplyStatus(ctx context.Context, cluster *clusterv1alpha1.ClusterApplyConfiguration, opts v1.ApplyOptions) (result *v1alpha1.Cluster, err error) {
+	if cluster == nil {
+		return nil, fmt.Errorf("cluster provided to Apply must not be nil")
+	}
+	data, err := json.Marshal(cluster)
+	if err != nil {
+		return nil, err
+	}
+	name := cluster.Name
+	if name == nil {
+		return nil, fmt.Errorf("cluster.Name must be provided to Apply")
+	}
+	obj, err := c.Fake.
+		Invokes(testing.NewRootPatchSubresourceAction(clustersResource, *name, types.ApplyPatchType, data, "status"), &v1alpha1.Cluster{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.Cluster), err
+}
@@ -33,6 +33,14 @@ func (c *FakeClusterV1alpha1) Clusters() v1alpha1.ClusterInterface {
 	return &FakeClusters{c}
 }
 
+func (c *FakeClusterV1alpha1) Placements() v1alpha1.PlacementInterface {
+	return &FakePlacements{c}
+}
+
+func (c *FakeClusterV1alpha1) SyncTransforms() v1alpha1.SyncTransformInterface {
+	return &FakeSyncTransforms{c}
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeClusterV1alpha1) RESTClient() rest.Interface {
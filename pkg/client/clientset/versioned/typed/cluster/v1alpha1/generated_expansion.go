@@ -19,3 +19,7 @@ limitations under the License.
 package v1alpha1
 
 type ClusterExpansion interface{}
+
+type PlacementExpansion interface{}
+
+type SyncTransformExpansion interface{}
@@ -29,14 +29,30 @@ type FakeTenancyV1alpha1 struct {
 	*testing.Fake
 }
 
+func (c *FakeTenancyV1alpha1) AuditSinks() v1alpha1.AuditSinkInterface {
+	return &FakeAuditSinks{c}
+}
+
+func (c *FakeTenancyV1alpha1) ClientCertificateAuthorities() v1alpha1.ClientCertificateAuthorityInterface {
+	return &FakeClientCertificateAuthorities{c}
+}
+
 func (c *FakeTenancyV1alpha1) Workspaces() v1alpha1.WorkspaceInterface {
 	return &FakeWorkspaces{c}
 }
 
+func (c *FakeTenancyV1alpha1) WorkspaceRoleBindings() v1alpha1.WorkspaceRoleBindingInterface {
+	return &FakeWorkspaceRoleBindings{c}
+}
+
 func (c *FakeTenancyV1alpha1) WorkspaceShards() v1alpha1.WorkspaceShardInterface {
 	return &FakeWorkspaceShards{c}
 }
 
+func (c *FakeTenancyV1alpha1) WorkspaceTypes() v1alpha1.WorkspaceTypeInterface {
+	return &FakeWorkspaceTypes{c}
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeTenancyV1alpha1) RESTClient() rest.Interface {
@@ -20,6 +20,8 @@ package fake
 
 import (
 	"context"
+	json "encoding/json"
+	"fmt"
 
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	labels "k8s.io/apimachinery/pkg/labels"
@@ -29,6 +31,7 @@ import (
 	testing "k8s.io/client-go/testing"
 
 	v1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/client/applyconfiguration/tenancy/v1alpha1"
 )
 
 // FakeWorkspaceShards implements WorkspaceShardInterface
@@ -132,3 +135,46 @@ func (c *FakeWorkspaceShards) Patch(ctx context.Context, name string, pt types.P
 	}
 	return obj.(*v1alpha1.WorkspaceShard), err
 }
+
+// Apply takes the given apply declarative configuration, applies it and returns the applied workspaceShard.
+func (c *FakeWorkspaceShards) Apply(ctx context.Context, workspaceShard *tenancyv1alpha1.WorkspaceShardApplyConfiguration, opts v1.ApplyOptions) (result *v1alpha1.WorkspaceShard, err error) {
+	if workspaceShard == nil {
+		return nil, fmt.Errorf("workspaceShard provided to Apply must not be nil")
+	}
+	data, err := json.Marshal(workspaceShard)
+	if err != nil {
+		return nil, err
+	}
+	name := workspaceShard.Name
+	if name == nil {
+		return nil, fmt.Errorf("workspaceShard.Name must be provided to Apply")
+	}
+	obj, err := c.Fake.
+		Invokes(testing.NewRootPatchSubresourceAction(workspaceshardsResource, *name, types.ApplyPatchType, data), &v1alpha1.WorkspaceShard{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.WorkspaceShard), err
+}
+
+// ApplyStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating ApplyStatus().
+func (c *FakeWorkspaceShards) ApplyStatus(ctx context.Context, workspaceShard *tenancyv1alpha1.WorkspaceShardApplyConfiguration, opts v1.ApplyOptions) (result *v1alpha1.WorkspaceShard, err error) {
+	if workspaceShard == nil {
+		return nil, fmt.Errorf("workspaceShard provided to Apply must not be nil")
+	}
+	data, err := json.Marshal(workspaceShard)
+	if err != nil {
+		return nil, err
+	}
+	name := workspaceShard.Name
+	if name == nil {
+		return nil, fmt.Errorf("workspaceShard.Name must be provided to Apply")
+	}
+	obj, err := c.Fake.
+		Invokes(testing.NewRootPatchSubresourceAction(workspaceshardsResource, *name, types.ApplyPatchType, data, "status"), &v1alpha1.WorkspaceShard{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.WorkspaceShard), err
+}
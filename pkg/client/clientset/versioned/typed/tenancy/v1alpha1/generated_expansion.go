@@ -18,6 +18,14 @@ limitations under the License.
 
 package v1alpha1
 
+type AuditSinkExpansion interface{}
+
+type ClientCertificateAuthorityExpansion interface{}
+
 type WorkspaceExpansion interface{}
 
+type WorkspaceRoleBindingExpansion interface{}
+
 type WorkspaceShardExpansion interface{}
+
+type WorkspaceTypeExpansion interface{}
@@ -27,8 +27,12 @@ import (
 
 type TenancyV1alpha1Interface interface {
 	RESTClient() rest.Interface
+	AuditSinksGetter
+	ClientCertificateAuthoritiesGetter
 	WorkspacesGetter
+	WorkspaceRoleBindingsGetter
 	WorkspaceShardsGetter
+	WorkspaceTypesGetter
 }
 
 // TenancyV1alpha1Client is used to interact with features provided by the tenancy.kcp.dev group.
@@ -37,14 +41,30 @@ type TenancyV1alpha1Client struct {
 	cluster    string
 }
 
+func (c *TenancyV1alpha1Client) AuditSinks() AuditSinkInterface {
+	return newAuditSinks(c)
+}
+
+func (c *TenancyV1alpha1Client) ClientCertificateAuthorities() ClientCertificateAuthorityInterface {
+	return newClientCertificateAuthorities(c)
+}
+
 func (c *TenancyV1alpha1Client) Workspaces() WorkspaceInterface {
 	return newWorkspaces(c)
 }
 
+func (c *TenancyV1alpha1Client) WorkspaceRoleBindings() WorkspaceRoleBindingInterface {
+	return newWorkspaceRoleBindings(c)
+}
+
 func (c *TenancyV1alpha1Client) WorkspaceShards() WorkspaceShardInterface {
 	return newWorkspaceShards(c)
 }
 
+func (c *TenancyV1alpha1Client) WorkspaceTypes() WorkspaceTypeInterface {
+	return newWorkspaceTypes(c)
+}
+
 // NewForConfig creates a new TenancyV1alpha1Client for the given config.
 func NewForConfig(c *rest.Config) (*TenancyV1alpha1Client, error) {
 	config := *c
@@ -20,6 +20,8 @@ package v1alpha1
 
 import (
 	"context"
+	json "encoding/json"
+	"fmt"
 	"time"
 
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -28,6 +30,7 @@ import (
 	rest "k8s.io/client-go/rest"
 
 	v1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/client/applyconfiguration/tenancy/v1alpha1"
 	scheme "github.com/kcp-dev/kcp/pkg/client/clientset/versioned/scheme"
 )
 
@@ -48,6 +51,8 @@ type WorkspaceShardInterface interface {
 	List(ctx context.Context, opts v1.ListOptions) (*v1alpha1.WorkspaceShardList, error)
 	Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error)
 	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1alpha1.WorkspaceShard, err error)
+	Apply(ctx context.Context, workspaceShard *tenancyv1alpha1.WorkspaceShardApplyConfiguration, opts v1.ApplyOptions) (result *v1alpha1.WorkspaceShard, err error)
+	ApplyStatus(ctx context.Context, workspaceShard *tenancyv1alpha1.WorkspaceShardApplyConfiguration, opts v1.ApplyOptions) (result *v1alpha1.WorkspaceShard, err error)
 	WorkspaceShardExpansion
 }
 
@@ -193,3 +198,59 @@ func (c *workspaceShards) Patch(ctx context.Context, name string, pt types.Patch
 		Into(result)
 	return
 }
+
+// Apply takes the given apply declarative configuration, applies it and returns the applied workspaceShard.
+func (c *workspaceShards) Apply(ctx context.Context, workspaceShard *tenancyv1alpha1.WorkspaceShardApplyConfiguration, opts v1.ApplyOptions) (result *v1alpha1.WorkspaceShard, err error) {
+	if workspaceShard == nil {
+		return nil, fmt.Errorf("workspaceShard provided to Apply must not be nil")
+	}
+	patchOpts := opts.ToPatchOptions()
+	data, err := json.Marshal(workspaceShard)
+	if err != nil {
+		return nil, err
+	}
+	name := workspaceShard.Name
+	if name == nil {
+		return nil, fmt.Errorf("workspaceShard.Name must be provided to Apply")
+	}
+	result = &v1alpha1.WorkspaceShard{}
+	err = c.client.Patch(types.ApplyPatchType).
+		Cluster(c.cluster).
+		Resource("workspaceshards").
+		Name(*name).
+		VersionedParams(&patchOpts, scheme.ParameterCodec).
+		Body(data).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// ApplyStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating ApplyStatus().
+func (c *workspaceShards) ApplyStatus(ctx context.Context, workspaceShard *tenancyv1alpha1.WorkspaceShardApplyConfiguration, opts v1.ApplyOptions) (result *v1alpha1.WorkspaceShard, err error) {
+	if workspaceShard == nil {
+		return nil, fmt.Errorf("workspaceShard provided to Apply must not be nil")
+	}
+	patchOpts := opts.ToPatchOptions()
+	data, err := json.Marshal(workspaceShard)
+	if err != nil {
+		return nil, err
+	}
+
+	name := workspaceShard.Name
+	if name == nil {
+		return nil, fmt.Errorf("workspaceShard.Name must be provided to Apply")
+	}
+
+	result = &v1alpha1.WorkspaceShard{}
+	err = c.client.Patch(types.ApplyPatchType).
+		Cluster(c.cluster).
+		Resource("workspaceshards").
+		Name(*name).
+		SubResource("status").
+		VersionedParams(&patchOpts, scheme.ParameterCodec).
+		Body(data).
+		Do(ctx).
+		Into(result)
+	return
+}
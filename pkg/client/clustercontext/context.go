@@ -0,0 +1,137 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package clustercontext carries the logical cluster of requests in their context, so
+// that code serving several logical clusters passes the cluster along with the context
+// rather than as a separate argument to every function building a client.
+//
+// Clients built from a config wrapped with WrapConfig send each request to the logical
+// cluster of its context, if any:
+//
+//	config = clustercontext.WrapConfig(config)
+//	client := kubernetes.NewForConfigOrDie(config)
+//	ctx = clustercontext.WithCluster(ctx, "admin")
+//	client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+//
+// Alternatively, KubeClient, KcpClient and DynamicClient derive the client of the logical
+// cluster of a context from a cluster-aware client.
+package clustercontext
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+)
+
+type clusterKey struct{}
+
+// WithCluster returns a context carrying the given logical cluster.
+func WithCluster(ctx context.Context, cluster string) context.Context {
+	return context.WithValue(ctx, clusterKey{}, cluster)
+}
+
+// ClusterFrom returns the logical cluster carried by the context, if any.
+func ClusterFrom(ctx context.Context) (string, bool) {
+	cluster, ok := ctx.Value(clusterKey{}).(string)
+	return cluster, ok && cluster != ""
+}
+
+// ConfigFor returns a copy of the config whose requests go to the given logical cluster.
+// The config must not be scoped to a logical cluster already.
+func ConfigFor(config *rest.Config, cluster string) *rest.Config {
+	config = rest.CopyConfig(config)
+	config.Host = strings.TrimSuffix(config.Host, "/") + "/clusters/" + cluster
+	return config
+}
+
+// KubeClient returns the client of the logical cluster of the context, or the client
+// of no logical cluster if the context carries none.
+func KubeClient(ctx context.Context, client *kubernetes.Cluster) kubernetes.Interface {
+	cluster, _ := ClusterFrom(ctx)
+	return client.Cluster(cluster)
+}
+
+// KcpClient returns the client of the logical cluster of the context, or the client of
+// no logical cluster if the context carries none.
+func KcpClient(ctx context.Context, client *kcpclient.Cluster) kcpclient.Interface {
+	cluster, _ := ClusterFrom(ctx)
+	return client.Cluster(cluster)
+}
+
+// DynamicClient returns the client of the logical cluster of the context, or the client
+// of no logical cluster if the context carries none.
+func DynamicClient(ctx context.Context, client *dynamic.Cluster) dynamic.Interface {
+	cluster, _ := ClusterFrom(ctx)
+	return client.Cluster(cluster)
+}
+
+// WrapConfig returns a copy of the config whose requests go to the logical cluster of
+// their context, if any. Requests already sent to another logical cluster, because the
+// client or the config is scoped to it, fail rather than reach the wrong one.
+func WrapConfig(config *rest.Config) *rest.Config {
+	config = rest.CopyConfig(config)
+	basePath, scoped := "", ""
+	if host, err := url.Parse(config.Host); err == nil {
+		basePath = strings.TrimSuffix(host.Path, "/")
+	}
+	if i := strings.LastIndex(basePath, "/clusters/"); i >= 0 && !strings.Contains(basePath[i+len("/clusters/"):], "/") {
+		basePath, scoped = basePath[:i], basePath[i+len("/clusters/"):]
+	}
+	config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return &roundTripper{delegate: rt, basePath: basePath, scoped: scoped}
+	})
+	return config
+}
+
+// roundTripper sends requests to the logical cluster of their context.
+type roundTripper struct {
+	delegate http.RoundTripper
+	// basePath is the path of the server, before the logical cluster segments.
+	basePath string
+	// scoped is the logical cluster the config is scoped to, if any.
+	scoped string
+}
+
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	cluster, ok := ClusterFrom(req.Context())
+	if !ok || !strings.HasPrefix(req.URL.Path, rt.basePath+"/") {
+		return rt.delegate.RoundTrip(req)
+	}
+	path := strings.TrimPrefix(req.URL.Path, rt.basePath)
+	requested := rt.scoped
+	if requested == "" && strings.HasPrefix(path, "/clusters/") {
+		requested = strings.SplitN(strings.TrimPrefix(path, "/clusters/"), "/", 2)[0]
+	}
+	if requested != "" {
+		if requested != cluster {
+			return nil, fmt.Errorf("request to logical cluster %q with a context for logical cluster %q", requested, cluster)
+		}
+		return rt.delegate.RoundTrip(req)
+	}
+	// round trippers must not modify the request
+	req = req.Clone(req.Context())
+	req.URL.Path = rt.basePath + "/clusters/" + cluster + path
+	req.URL.RawPath = ""
+	return rt.delegate.RoundTrip(req)
+}
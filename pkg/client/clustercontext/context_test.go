@@ -0,0 +1,78 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clustercontext
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func TestWrapConfig(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		paths = append(paths, req.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"kind":"Namespace","apiVersion":"v1","metadata":{"name":"default"}}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		host     string
+		cluster  string
+		ctx      context.Context
+		expected string
+		err      string
+	}{
+		{name: "no cluster in context", host: server.URL, ctx: context.Background(), expected: "/api/v1/namespaces/default"},
+		{name: "cluster in context", host: server.URL, ctx: WithCluster(context.Background(), "admin"), expected: "/clusters/admin/api/v1/namespaces/default"},
+		{name: "server with path", host: server.URL + "/prefix/", ctx: WithCluster(context.Background(), "admin"), expected: "/prefix/clusters/admin/api/v1/namespaces/default"},
+		{name: "same cluster as the client", host: server.URL, cluster: "admin", ctx: WithCluster(context.Background(), "admin"), expected: "/clusters/admin/api/v1/namespaces/default"},
+		{name: "other cluster than the client", host: server.URL, cluster: "user", ctx: WithCluster(context.Background(), "admin"), err: `request to logical cluster "user" with a context for logical cluster "admin"`},
+		{name: "same cluster as the config", host: server.URL + "/clusters/admin", ctx: WithCluster(context.Background(), "admin"), expected: "/clusters/admin/api/v1/namespaces/default"},
+		{name: "other cluster than the config", host: server.URL + "/clusters/user", ctx: WithCluster(context.Background(), "admin"), err: `request to logical cluster "user" with a context for logical cluster "admin"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths = nil
+			client, err := kubernetes.NewClusterForConfig(WrapConfig(&rest.Config{Host: tt.host}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = client.Cluster(tt.cluster).CoreV1().Namespaces().Get(tt.ctx, "default", metav1.GetOptions{})
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(paths) != 1 || paths[0] != tt.expected {
+				t.Errorf("expected a request to %s, got %v", tt.expected, paths)
+			}
+		})
+	}
+}
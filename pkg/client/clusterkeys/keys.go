@@ -0,0 +1,61 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package clusterkeys encodes the keys of the objects of logical clusters in workqueues
+// and informer caches, and lists the objects of informers by logical cluster.
+//
+// Informers watching all logical clusters hold the objects of every logical cluster, so
+// keys must include the logical cluster for objects of the same name in different
+// logical clusters not to collide. Keys are [<namespace>/]<cluster>#$#<name>, which is
+// what cache.MetaNamespaceKeyFunc returns for objects of logical clusters, so keys of
+// this package are also the keys of the informer caches and of the generated listers.
+package clusterkeys
+
+import (
+	"fmt"
+
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clusters"
+)
+
+// Key returns the key of an object, or of the object of a tombstone, for use as the key
+// of workqueues.
+func Key(obj interface{}) (string, error) {
+	return cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+}
+
+// ToKey returns the key of the object of the given logical cluster, namespace and name.
+// The namespace is empty for cluster-scoped objects.
+func ToKey(clusterName, namespace, name string) string {
+	key := clusters.ToClusterAwareKey(clusterName, name)
+	if namespace != "" {
+		key = namespace + "/" + key
+	}
+	return key
+}
+
+// SplitClusterKey returns the logical cluster, namespace and name encoded in a key.
+func SplitClusterKey(key string) (clusterName, namespace, name string, err error) {
+	namespace, clusterAwareName, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return "", "", "", err
+	}
+	clusterName, name = clusters.SplitClusterAwareKey(clusterAwareName)
+	if name == "" {
+		return "", "", "", fmt.Errorf("unexpected key format: %q", key)
+	}
+	return clusterName, namespace, name, nil
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterkeys
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestKeys(t *testing.T) {
+	tests := []struct {
+		cluster, namespace, name string
+	}{
+		{cluster: "admin", name: "shard"},
+		{cluster: "admin", namespace: "default", name: "kubeconfig"},
+		{namespace: "default", name: "downstream"},
+	}
+	for _, tt := range tests {
+		obj := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{ClusterName: tt.cluster, Namespace: tt.namespace, Name: tt.name}}
+		key, err := Key(cache.DeletedFinalStateUnknown{Key: "ignored", Obj: obj})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key != "ignored" {
+			t.Errorf("expected the key of the tombstone, got %q", key)
+		}
+		key, err = Key(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := ToKey(tt.cluster, tt.namespace, tt.name); key != expected {
+			t.Errorf("expected key %q, got %q", expected, key)
+		}
+		cluster, namespace, name, err := SplitClusterKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cluster != tt.cluster || namespace != tt.namespace || name != tt.name {
+			t.Errorf("key %q split into %q %q %q", key, cluster, namespace, name)
+		}
+	}
+	if _, _, _, err := SplitClusterKey("a/b/c"); err == nil {
+		t.Errorf("expected an error splitting an invalid key")
+	}
+}
+
+func TestLister(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	if err := AddIndexers(indexer); err != nil {
+		t.Fatal(err)
+	}
+	for _, cluster := range []string{"admin", "user"} {
+		for _, namespace := range []string{"default", "other"} {
+			if err := indexer.Add(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{ClusterName: cluster, Namespace: namespace, Name: "config"}}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	lister := NewLister(indexer, corev1.Resource("configmaps"))
+
+	all, err := lister.List(labels.Everything())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 4 {
+		t.Errorf("expected 4 objects across clusters, got %d", len(all))
+	}
+	inCluster, err := lister.Cluster("user").List(labels.Everything())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"user/default/config", "user/other/config"}, names(inCluster)); diff != "" {
+		t.Errorf("unexpected objects of cluster (-want +got):\n%s", diff)
+	}
+	obj, err := lister.Cluster("user").ByNamespace("other").Get("config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"user/other/config"}, names([]runtime.Object{obj})); diff != "" {
+		t.Errorf("unexpected object (-want +got):\n%s", diff)
+	}
+	if _, err := lister.Cluster("org").ByNamespace("other").Get("config"); err == nil {
+		t.Errorf("expected an error getting an object of another cluster")
+	}
+}
+
+func names(objs []runtime.Object) []string {
+	var names []string
+	for _, obj := range objs {
+		configMap := obj.(*corev1.ConfigMap)
+		names = append(names, configMap.ClusterName+"/"+configMap.Namespace+"/"+configMap.Name)
+	}
+	sort.Strings(names)
+	return names
+}
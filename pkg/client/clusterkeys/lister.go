@@ -0,0 +1,159 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterkeys
+
+import (
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clusters"
+
+	"github.com/kcp-dev/kcp/pkg/indexers"
+)
+
+const (
+	// ClusterIndexName indexes objects by logical cluster.
+	ClusterIndexName = "cluster"
+	// ClusterNamespaceIndexName indexes objects by logical cluster and namespace.
+	ClusterNamespaceIndexName = "clusterNamespace"
+)
+
+// ClusterIndexFunc indexes objects by logical cluster.
+func ClusterIndexFunc(obj interface{}) ([]string, error) {
+	metaObj, err := meta.Accessor(obj)
+	if err != nil {
+		return []string{}, nil
+	}
+	return []string{metaObj.GetClusterName()}, nil
+}
+
+// ClusterNamespaceIndexFunc indexes namespaced objects by logical cluster and namespace.
+func ClusterNamespaceIndexFunc(obj interface{}) ([]string, error) {
+	metaObj, err := meta.Accessor(obj)
+	if err != nil || metaObj.GetNamespace() == "" {
+		return []string{}, nil
+	}
+	return []string{clusters.ToClusterAwareKey(metaObj.GetClusterName(), metaObj.GetNamespace())}, nil
+}
+
+// AddIndexers adds the indexes the listers of NewLister need to the indexer, unless it
+// already has them. It must be called before the informer of the indexer is started.
+func AddIndexers(indexer cache.Indexer) error {
+	return indexers.AddIfNotPresent(indexer, cache.Indexers{
+		ClusterIndexName:          ClusterIndexFunc,
+		ClusterNamespaceIndexName: ClusterNamespaceIndexFunc,
+	})
+}
+
+// ClusterLister lists the objects of a resource across logical clusters.
+type ClusterLister interface {
+	// List lists the objects of all logical clusters.
+	List(selector labels.Selector) ([]runtime.Object, error)
+	// Cluster returns a lister of the objects of the given logical cluster.
+	Cluster(name string) cache.GenericLister
+}
+
+// NewLister returns a lister of the objects of the indexer of an informer watching all
+// logical clusters, e.g. of the generated informers, which must have the indexes added
+// by AddIndexers. The listers of a logical cluster get objects by name, without the
+// cluster-aware keys the generated listers expect.
+func NewLister(indexer cache.Indexer, resource schema.GroupResource) ClusterLister {
+	return &clusterLister{indexer: indexer, resource: resource}
+}
+
+type clusterLister struct {
+	indexer  cache.Indexer
+	resource schema.GroupResource
+}
+
+func (l *clusterLister) List(selector labels.Selector) (ret []runtime.Object, err error) {
+	err = cache.ListAll(l.indexer, selector, func(obj interface{}) {
+		ret = append(ret, obj.(runtime.Object))
+	})
+	return ret, err
+}
+
+func (l *clusterLister) Cluster(name string) cache.GenericLister {
+	return &scopedLister{indexer: l.indexer, resource: l.resource, cluster: name}
+}
+
+// scopedLister lists the objects of a logical cluster.
+type scopedLister struct {
+	indexer  cache.Indexer
+	resource schema.GroupResource
+	cluster  string
+}
+
+func (l *scopedLister) List(selector labels.Selector) ([]runtime.Object, error) {
+	return listIndexed(l.indexer, ClusterIndexName, l.cluster, selector)
+}
+
+func (l *scopedLister) Get(name string) (runtime.Object, error) {
+	return get(l.indexer, l.resource, ToKey(l.cluster, "", name), name)
+}
+
+func (l *scopedLister) ByNamespace(namespace string) cache.GenericNamespaceLister {
+	return &scopedNamespaceLister{indexer: l.indexer, resource: l.resource, cluster: l.cluster, namespace: namespace}
+}
+
+// scopedNamespaceLister lists the objects of a namespace of a logical cluster.
+type scopedNamespaceLister struct {
+	indexer   cache.Indexer
+	resource  schema.GroupResource
+	cluster   string
+	namespace string
+}
+
+func (l *scopedNamespaceLister) List(selector labels.Selector) ([]runtime.Object, error) {
+	return listIndexed(l.indexer, ClusterNamespaceIndexName, clusters.ToClusterAwareKey(l.cluster, l.namespace), selector)
+}
+
+func (l *scopedNamespaceLister) Get(name string) (runtime.Object, error) {
+	return get(l.indexer, l.resource, ToKey(l.cluster, l.namespace, name), name)
+}
+
+func listIndexed(indexer cache.Indexer, indexName, indexKey string, selector labels.Selector) ([]runtime.Object, error) {
+	objs, err := indexer.ByIndex(indexName, indexKey)
+	if err != nil {
+		return nil, err
+	}
+	var ret []runtime.Object
+	for _, obj := range objs {
+		metaObj, err := meta.Accessor(obj)
+		if err != nil {
+			return nil, err
+		}
+		if selector.Matches(labels.Set(metaObj.GetLabels())) {
+			ret = append(ret, obj.(runtime.Object))
+		}
+	}
+	return ret, nil
+}
+
+func get(indexer cache.Indexer, resource schema.GroupResource, key, name string) (runtime.Object, error) {
+	obj, exists, err := indexer.GetByKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(resource, name)
+	}
+	return obj.(runtime.Object), nil
+}
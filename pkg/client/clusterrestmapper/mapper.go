@@ -0,0 +1,105 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package clusterrestmapper maps kinds to resources and back per logical cluster, since
+// each logical cluster serves its own CustomResourceDefinitions. The mappings are built
+// from the discovery of each logical cluster, and built again once its
+// CustomResourceDefinitions change.
+package clusterrestmapper
+
+import (
+	"sync"
+
+	crdinformer "k8s.io/apiextensions-apiserver/pkg/client/informers/externalversions/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// DiscoveryGetter returns the cached discovery of a logical cluster. It is implemented by
+// discoverycache.Cache.
+type DiscoveryGetter interface {
+	Cluster(clusterName string) (discovery.CachedDiscoveryInterface, error)
+}
+
+// NewMapper returns the RESTMappers of the logical clusters discovered with discovery,
+// reset on the changes of the CRDs seen by crdInformer, which must watch all logical
+// clusters.
+func NewMapper(discovery DiscoveryGetter, crdInformer crdinformer.CustomResourceDefinitionInformer) *Mapper {
+	m := &Mapper{
+		discovery: discovery,
+		mappers:   map[string]*restmapper.DeferredDiscoveryRESTMapper{},
+	}
+	crdInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    m.resetFor,
+		UpdateFunc: func(_, obj interface{}) { m.resetFor(obj) },
+		DeleteFunc: m.resetFor,
+	})
+	return m
+}
+
+// Mapper holds a RESTMapper per logical cluster.
+type Mapper struct {
+	discovery DiscoveryGetter
+
+	lock    sync.Mutex
+	mappers map[string]*restmapper.DeferredDiscoveryRESTMapper
+}
+
+// Cluster returns the RESTMapper of the logical cluster. Its mappings are discovered on
+// first use.
+func (m *Mapper) Cluster(clusterName string) (meta.RESTMapper, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if mapper, found := m.mappers[clusterName]; found {
+		return mapper, nil
+	}
+	client, err := m.discovery.Cluster(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(client)
+	m.mappers[clusterName] = mapper
+	return mapper, nil
+}
+
+// Reset drops the mappings of the logical cluster, and its cached discovery, which are
+// fetched again on the next use.
+func (m *Mapper) Reset(clusterName string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if mapper, found := m.mappers[clusterName]; found {
+		klog.V(4).Infof("Resetting the RESTMapper of logical cluster %s", clusterName)
+		mapper.Reset()
+	}
+}
+
+func (m *Mapper) resetFor(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	metaObj, err := meta.Accessor(obj)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	m.Reset(metaObj.GetClusterName())
+}
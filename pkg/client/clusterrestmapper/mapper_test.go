@@ -0,0 +1,84 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterrestmapper
+
+import (
+	"testing"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	discoveryfake "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/restmapper"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+type discoveryGetter map[string]discovery.CachedDiscoveryInterface
+
+func (g discoveryGetter) Cluster(clusterName string) (discovery.CachedDiscoveryInterface, error) {
+	return g[clusterName], nil
+}
+
+func TestMapper(t *testing.T) {
+	fakes := map[string]*clienttesting.Fake{}
+	getter := discoveryGetter{}
+	for _, clusterName := range []string{"admin", "user"} {
+		fakes[clusterName] = &clienttesting.Fake{Resources: []*metav1.APIResourceList{{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{{Name: "configmaps", Namespaced: true, Kind: "ConfigMap"}},
+		}}}
+		getter[clusterName] = memory.NewMemCacheClient(&discoveryfake.FakeDiscovery{Fake: fakes[clusterName]})
+	}
+	m := &Mapper{discovery: getter, mappers: map[string]*restmapper.DeferredDiscoveryRESTMapper{}}
+
+	widgets := schema.GroupVersionResource{Group: "example.dev", Version: "v1", Resource: "widgets"}
+	widget := schema.GroupKind{Group: "example.dev", Kind: "Widget"}
+	mapping := func(clusterName string, groupKind schema.GroupKind) (*meta.RESTMapping, error) {
+		mapper, err := m.Cluster(clusterName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return mapper.RESTMapping(groupKind)
+	}
+
+	if _, err := mapping("admin", schema.GroupKind{Kind: "ConfigMap"}); err != nil {
+		t.Fatalf("expected ConfigMap to be mapped, got %v", err)
+	}
+	if _, err := mapping("admin", widget); !meta.IsNoMatchError(err) {
+		t.Fatalf("expected Widget not to be mapped before its CRD is created, got %v", err)
+	}
+
+	fakes["admin"].Resources = append(fakes["admin"].Resources, &metav1.APIResourceList{
+		GroupVersion: widgets.GroupVersion().String(),
+		APIResources: []metav1.APIResource{{Name: widgets.Resource, Namespaced: true, Kind: widget.Kind}},
+	})
+	m.resetFor(&apiextensionsv1.CustomResourceDefinition{ObjectMeta: metav1.ObjectMeta{ClusterName: "admin", Name: "widgets.example.dev"}})
+
+	got, err := mapping("admin", widget)
+	if err != nil {
+		t.Fatalf("expected Widget to be mapped once its CRD is created, got %v", err)
+	}
+	if got.Resource != widgets || got.Scope.Name() != meta.RESTScopeNameNamespace {
+		t.Errorf("unexpected mapping of Widget: %s, scope %s", got.Resource, got.Scope.Name())
+	}
+	if _, err := mapping("user", widget); !meta.IsNoMatchError(err) {
+		t.Errorf("expected Widget not to be mapped in another logical cluster, got %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package discoverycache caches the discovery of each logical cluster in memory, for the
+// controllers running in the kcp process. The discovery of a logical cluster is only
+// fetched again once its CustomResourceDefinitions change.
+package discoverycache
+
+import (
+	"sync"
+
+	crdinformer "k8s.io/apiextensions-apiserver/pkg/client/informers/externalversions/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	"github.com/kcp-dev/kcp/pkg/client/clustercontext"
+)
+
+// NewCache returns a discovery cache for the logical clusters of the server reached with
+// config, invalidated on the changes of the CRDs seen by crdInformer, which must watch
+// all logical clusters.
+func NewCache(config *rest.Config, crdInformer crdinformer.CustomResourceDefinitionInformer) *Cache {
+	c := &Cache{
+		newClient: func(clusterName string) (discovery.DiscoveryInterface, error) {
+			return discovery.NewDiscoveryClientForConfig(clustercontext.ConfigFor(config, clusterName))
+		},
+		clients: map[string]discovery.CachedDiscoveryInterface{},
+	}
+	crdInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.invalidateFor,
+		UpdateFunc: func(_, obj interface{}) { c.invalidateFor(obj) },
+		DeleteFunc: c.invalidateFor,
+	})
+	return c
+}
+
+// Cache holds a cached discovery client per logical cluster.
+type Cache struct {
+	newClient func(clusterName string) (discovery.DiscoveryInterface, error)
+
+	lock    sync.Mutex
+	clients map[string]discovery.CachedDiscoveryInterface
+}
+
+// Cluster returns the cached discovery client of the logical cluster.
+func (c *Cache) Cluster(clusterName string) (discovery.CachedDiscoveryInterface, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if client, found := c.clients[clusterName]; found {
+		return client, nil
+	}
+	delegate, err := c.newClient(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	client := memory.NewMemCacheClient(delegate)
+	c.clients[clusterName] = client
+	return client, nil
+}
+
+// Invalidate drops the cached discovery of the logical cluster, which is fetched again on
+// the next use.
+func (c *Cache) Invalidate(clusterName string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if client, found := c.clients[clusterName]; found {
+		klog.V(4).Infof("Invalidating the discovery of logical cluster %s", clusterName)
+		client.Invalidate()
+	}
+}
+
+func (c *Cache) invalidateFor(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	metaObj, err := meta.Accessor(obj)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	c.Invalidate(metaObj.GetClusterName())
+}
+
+// ServerPreferredResources returns the preferred resources of the logical cluster.
+func (c *Cache) ServerPreferredResources(clusterName string) ([]*metav1.APIResourceList, error) {
+	client, err := c.Cluster(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	return client.ServerPreferredResources()
+}
+
+// ServerPreferredNamespacedResources returns the preferred namespaced resources of the
+// logical cluster.
+func (c *Cache) ServerPreferredNamespacedResources(clusterName string) ([]*metav1.APIResourceList, error) {
+	client, err := c.Cluster(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	return client.ServerPreferredNamespacedResources()
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discoverycache
+
+import (
+	"testing"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery"
+	discoveryfake "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+// countingDiscovery counts the discoveries of the API groups.
+type countingDiscovery struct {
+	*discoveryfake.FakeDiscovery
+	count int
+}
+
+func (d *countingDiscovery) ServerGroups() (*metav1.APIGroupList, error) {
+	d.count++
+	return d.FakeDiscovery.ServerGroups()
+}
+
+func TestCache(t *testing.T) {
+	clients := map[string]*countingDiscovery{}
+	c := &Cache{
+		newClient: func(clusterName string) (discovery.DiscoveryInterface, error) {
+			client := &countingDiscovery{FakeDiscovery: &discoveryfake.FakeDiscovery{Fake: &clienttesting.Fake{
+				Resources: []*metav1.APIResourceList{{
+					GroupVersion: "v1",
+					APIResources: []metav1.APIResource{{Name: "configmaps", Namespaced: true, Kind: "ConfigMap"}},
+				}},
+			}}}
+			clients[clusterName] = client
+			return client, nil
+		},
+		clients: map[string]discovery.CachedDiscoveryInterface{},
+	}
+
+	discover := func(clusterName string) {
+		if _, err := c.ServerPreferredNamespacedResources(clusterName); err != nil {
+			t.Fatal(err)
+		}
+	}
+	discover("admin")
+	discover("admin")
+	discover("user")
+	if clients["admin"].count != 1 || clients["user"].count != 1 {
+		t.Fatalf("expected the discovery of each logical cluster to be fetched once, got %d for admin and %d for user", clients["admin"].count, clients["user"].count)
+	}
+
+	c.invalidateFor(&apiextensionsv1.CustomResourceDefinition{ObjectMeta: metav1.ObjectMeta{ClusterName: "admin", Name: "widgets.example.dev"}})
+	discover("admin")
+	discover("user")
+	if clients["admin"].count != 2 || clients["user"].count != 1 {
+		t.Errorf("expected only the discovery of admin to be fetched again, got %d for admin and %d for user", clients["admin"].count, clients["user"].count)
+	}
+}
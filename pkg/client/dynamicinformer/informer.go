@@ -0,0 +1,215 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package dynamicinformer provides dynamic informers watching a resource across all
+// logical clusters with a single wildcard LIST/WATCH, and listers scoped to each logical
+// cluster, so that controllers don't need one informer per logical cluster.
+package dynamicinformer
+
+import (
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/kcp-dev/kcp/pkg/client/clusterkeys"
+)
+
+const (
+	// ClusterIndexName indexes objects by logical cluster.
+	ClusterIndexName = clusterkeys.ClusterIndexName
+	// ClusterNamespaceIndexName indexes objects by logical cluster and namespace.
+	ClusterNamespaceIndexName = clusterkeys.ClusterNamespaceIndexName
+)
+
+var (
+	// ClusterIndexFunc indexes objects by logical cluster.
+	ClusterIndexFunc = clusterkeys.ClusterIndexFunc
+	// ClusterNamespaceIndexFunc indexes namespaced objects by logical cluster and namespace.
+	ClusterNamespaceIndexFunc = clusterkeys.ClusterNamespaceIndexFunc
+)
+
+// ClusterDynamicSharedInformerFactory provides informers for the resources of all
+// logical clusters. Each informer watches its resource in all logical clusters at once,
+// and is shared by the views of the factory scoped to each logical cluster.
+type ClusterDynamicSharedInformerFactory interface {
+	Start(stopCh <-chan struct{})
+	WaitForCacheSync(stopCh <-chan struct{}) map[schema.GroupVersionResource]bool
+
+	// ForResource returns the informer of the resource across all logical clusters.
+	ForResource(gvr schema.GroupVersionResource) ClusterGenericInformer
+	// Cluster returns a view of the factory scoped to the given logical cluster.
+	Cluster(name string) dynamicinformer.DynamicSharedInformerFactory
+}
+
+// ClusterGenericInformer is an informer of a resource across all logical clusters.
+type ClusterGenericInformer interface {
+	// Informer returns the informer watching all logical clusters. The keys of its
+	// objects are cluster-aware.
+	Informer() cache.SharedIndexInformer
+	// Lister returns a lister of the objects of all logical clusters.
+	Lister() ClusterLister
+	// Cluster returns the informer scoped to the given logical cluster. Its Informer is
+	// the shared one, and its Lister only lists the objects of the logical cluster.
+	Cluster(name string) informers.GenericInformer
+}
+
+// ClusterLister lists the objects of a resource across logical clusters.
+type ClusterLister = clusterkeys.ClusterLister
+
+// NewClusterDynamicSharedInformerFactory returns a ClusterDynamicSharedInformerFactory
+// watching all namespaces of all logical clusters.
+func NewClusterDynamicSharedInformerFactory(client dynamic.ClusterInterface, defaultResync time.Duration) ClusterDynamicSharedInformerFactory {
+	return NewFilteredClusterDynamicSharedInformerFactory(client, defaultResync, metav1.NamespaceAll, nil)
+}
+
+// NewFilteredClusterDynamicSharedInformerFactory returns a
+// ClusterDynamicSharedInformerFactory watching the given namespace of all logical
+// clusters, with the given list options.
+func NewFilteredClusterDynamicSharedInformerFactory(client dynamic.ClusterInterface, defaultResync time.Duration, namespace string, tweakListOptions dynamicinformer.TweakListOptionsFunc) ClusterDynamicSharedInformerFactory {
+	return &clusterDynamicSharedInformerFactory{
+		client:           client.Cluster("*"),
+		defaultResync:    defaultResync,
+		namespace:        namespace,
+		tweakListOptions: tweakListOptions,
+		informers:        map[schema.GroupVersionResource]*clusterGenericInformer{},
+		startedInformers: map[schema.GroupVersionResource]bool{},
+	}
+}
+
+type clusterDynamicSharedInformerFactory struct {
+	client           dynamic.Interface
+	defaultResync    time.Duration
+	namespace        string
+	tweakListOptions dynamicinformer.TweakListOptionsFunc
+
+	lock      sync.Mutex
+	informers map[schema.GroupVersionResource]*clusterGenericInformer
+	// startedInformers is used for tracking which informers have been started.
+	// This allows Start() to be called multiple times safely.
+	startedInformers map[schema.GroupVersionResource]bool
+}
+
+func (f *clusterDynamicSharedInformerFactory) ForResource(gvr schema.GroupVersionResource) ClusterGenericInformer {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if informer, found := f.informers[gvr]; found {
+		return informer
+	}
+	informer := &clusterGenericInformer{
+		informer: dynamicinformer.NewFilteredDynamicInformer(f.client, gvr, f.namespace, f.defaultResync, cache.Indexers{
+			cache.NamespaceIndex:      cache.MetaNamespaceIndexFunc,
+			ClusterIndexName:          ClusterIndexFunc,
+			ClusterNamespaceIndexName: ClusterNamespaceIndexFunc,
+		}, f.tweakListOptions).Informer(),
+		resource: gvr.GroupResource(),
+	}
+	f.informers[gvr] = informer
+	return informer
+}
+
+func (f *clusterDynamicSharedInformerFactory) Cluster(name string) dynamicinformer.DynamicSharedInformerFactory {
+	return &scopedFactory{factory: f, cluster: name}
+}
+
+func (f *clusterDynamicSharedInformerFactory) Start(stopCh <-chan struct{}) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	for gvr, informer := range f.informers {
+		if !f.startedInformers[gvr] {
+			go informer.informer.Run(stopCh)
+			f.startedInformers[gvr] = true
+		}
+	}
+}
+
+func (f *clusterDynamicSharedInformerFactory) WaitForCacheSync(stopCh <-chan struct{}) map[schema.GroupVersionResource]bool {
+	informers := func() map[schema.GroupVersionResource]cache.SharedIndexInformer {
+		f.lock.Lock()
+		defer f.lock.Unlock()
+
+		informers := map[schema.GroupVersionResource]cache.SharedIndexInformer{}
+		for gvr, informer := range f.informers {
+			if f.startedInformers[gvr] {
+				informers[gvr] = informer.informer
+			}
+		}
+		return informers
+	}()
+
+	res := map[schema.GroupVersionResource]bool{}
+	for gvr, informer := range informers {
+		res[gvr] = cache.WaitForCacheSync(stopCh, informer.HasSynced)
+	}
+	return res
+}
+
+type clusterGenericInformer struct {
+	informer cache.SharedIndexInformer
+	resource schema.GroupResource
+}
+
+func (i *clusterGenericInformer) Informer() cache.SharedIndexInformer {
+	return i.informer
+}
+
+func (i *clusterGenericInformer) Lister() ClusterLister {
+	return clusterkeys.NewLister(i.informer.GetIndexer(), i.resource)
+}
+
+func (i *clusterGenericInformer) Cluster(name string) informers.GenericInformer {
+	return &scopedInformer{informer: i.informer, lister: i.Lister().Cluster(name)}
+}
+
+// scopedFactory is the view of a factory scoped to a logical cluster.
+type scopedFactory struct {
+	factory *clusterDynamicSharedInformerFactory
+	cluster string
+}
+
+func (f *scopedFactory) Start(stopCh <-chan struct{}) {
+	f.factory.Start(stopCh)
+}
+
+func (f *scopedFactory) WaitForCacheSync(stopCh <-chan struct{}) map[schema.GroupVersionResource]bool {
+	return f.factory.WaitForCacheSync(stopCh)
+}
+
+func (f *scopedFactory) ForResource(gvr schema.GroupVersionResource) informers.GenericInformer {
+	return f.factory.ForResource(gvr).Cluster(f.cluster)
+}
+
+// scopedInformer is an informer shared across logical clusters, with a lister scoped to
+// one of them.
+type scopedInformer struct {
+	informer cache.SharedIndexInformer
+	lister   cache.GenericLister
+}
+
+func (i *scopedInformer) Informer() cache.SharedIndexInformer {
+	return i.informer
+}
+
+func (i *scopedInformer) Lister() cache.GenericLister {
+	return i.lister
+}
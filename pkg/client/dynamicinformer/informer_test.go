@@ -0,0 +1,105 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dynamicinformer
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+// wildcardClient serves the objects of all logical clusters, whatever the cluster.
+type wildcardClient struct {
+	dynamic.Interface
+}
+
+func (c wildcardClient) Cluster(string) dynamic.Interface {
+	return c.Interface
+}
+
+func TestScopedListers(t *testing.T) {
+	configmaps := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	object := func(clusterName, namespace, name string) runtime.Object {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("v1")
+		obj.SetKind("ConfigMap")
+		obj.SetClusterName(clusterName)
+		obj.SetNamespace(namespace)
+		obj.SetName(name)
+		return obj
+	}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{configmaps: "ConfigMapList"},
+		object("admin", "default", "a"),
+		object("admin", "other", "b"),
+		object("user", "default", "c"),
+	)
+
+	factory := NewClusterDynamicSharedInformerFactory(wildcardClient{client}, 0)
+	informer := factory.ForResource(configmaps)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	factory.Start(stopCh)
+	factory.WaitForCacheSync(stopCh)
+
+	names := func(objs []runtime.Object, err error) []string {
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, obj := range objs {
+			metaObj, err := meta.Accessor(obj)
+			if err != nil {
+				t.Fatal(err)
+			}
+			names = append(names, metaObj.GetClusterName()+"/"+metaObj.GetNamespace()+"/"+metaObj.GetName())
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	if diff := cmp.Diff([]string{"admin/default/a", "admin/other/b", "user/default/c"}, names(informer.Lister().List(labels.Everything()))); diff != "" {
+		t.Errorf("unexpected objects of all clusters (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"admin/default/a", "admin/other/b"}, names(factory.Cluster("admin").ForResource(configmaps).Lister().List(labels.Everything()))); diff != "" {
+		t.Errorf("unexpected objects of cluster admin (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"user/default/c"}, names(informer.Lister().Cluster("user").ByNamespace("default").List(labels.Everything()))); diff != "" {
+		t.Errorf("unexpected objects of namespace default of cluster user (-want +got):\n%s", diff)
+	}
+
+	obj, err := informer.Cluster("user").Lister().ByNamespace("default").Get("c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"user/default/c"}, names([]runtime.Object{obj}, nil)); diff != "" {
+		t.Errorf("unexpected object (-want +got):\n%s", diff)
+	}
+	if _, err := informer.Cluster("admin").Lister().ByNamespace("default").Get("c"); !errors.IsNotFound(err) {
+		t.Errorf("expected NotFound, got %v", err)
+	}
+}
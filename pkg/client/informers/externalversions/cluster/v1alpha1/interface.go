@@ -26,6 +26,10 @@ import (
 type Interface interface {
 	// Clusters returns a ClusterInformer.
 	Clusters() ClusterInformer
+	// Placements returns a PlacementInformer.
+	Placements() PlacementInformer
+	// SyncTransforms returns a SyncTransformInformer.
+	SyncTransforms() SyncTransformInformer
 }
 
 type version struct {
@@ -43,3 +47,13 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 func (v *version) Clusters() ClusterInformer {
 	return &clusterInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
 }
+
+// Placements returns a PlacementInformer.
+func (v *version) Placements() PlacementInformer {
+	return &placementInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
+// SyncTransforms returns a SyncTransformInformer.
+func (v *version) SyncTransforms() SyncTransformInformer {
+	return &syncTransformInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
@@ -30,6 +30,7 @@ import (
 
 	versioned "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
 	apiresource "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/apiresource"
+	apis "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/apis"
 	cluster "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/cluster"
 	internalinterfaces "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/internalinterfaces"
 	tenancy "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/tenancy"
@@ -176,6 +177,7 @@ type SharedInformerFactory interface {
 	WaitForCacheSync(stopCh <-chan struct{}) map[reflect.Type]bool
 
 	Apiresource() apiresource.Interface
+	Apis() apis.Interface
 	Cluster() cluster.Interface
 	Tenancy() tenancy.Interface
 }
@@ -184,6 +186,10 @@ func (f *sharedInformerFactory) Apiresource() apiresource.Interface {
 	return apiresource.New(f, f.namespace, f.tweakListOptions)
 }
 
+func (f *sharedInformerFactory) Apis() apis.Interface {
+	return apis.New(f, f.namespace, f.tweakListOptions)
+}
+
 func (f *sharedInformerFactory) Cluster() cluster.Interface {
 	return cluster.New(f, f.namespace, f.tweakListOptions)
 }
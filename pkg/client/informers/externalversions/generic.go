@@ -25,6 +25,7 @@ import (
 	cache "k8s.io/client-go/tools/cache"
 
 	v1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apiresource/v1alpha1"
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
 	clusterv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/cluster/v1alpha1"
 	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
 )
@@ -61,15 +62,35 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 	case v1alpha1.SchemeGroupVersion.WithResource("negotiatedapiresources"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Apiresource().V1alpha1().NegotiatedAPIResources().Informer()}, nil
 
+		// Group=apis.kcp.dev, Version=v1alpha1
+	case apisv1alpha1.SchemeGroupVersion.WithResource("apibindings"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Apis().V1alpha1().APIBindings().Informer()}, nil
+	case apisv1alpha1.SchemeGroupVersion.WithResource("apiexports"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Apis().V1alpha1().APIExports().Informer()}, nil
+	case apisv1alpha1.SchemeGroupVersion.WithResource("referencegrants"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Apis().V1alpha1().ReferenceGrants().Informer()}, nil
+
 		// Group=cluster.example.dev, Version=v1alpha1
 	case clusterv1alpha1.SchemeGroupVersion.WithResource("clusters"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Cluster().V1alpha1().Clusters().Informer()}, nil
+	case clusterv1alpha1.SchemeGroupVersion.WithResource("placements"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Cluster().V1alpha1().Placements().Informer()}, nil
+	case clusterv1alpha1.SchemeGroupVersion.WithResource("synctransforms"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Cluster().V1alpha1().SyncTransforms().Informer()}, nil
 
 		// Group=tenancy.kcp.dev, Version=v1alpha1
+	case tenancyv1alpha1.SchemeGroupVersion.WithResource("auditsinks"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Tenancy().V1alpha1().AuditSinks().Informer()}, nil
+	case tenancyv1alpha1.SchemeGroupVersion.WithResource("clientcertificateauthorities"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Tenancy().V1alpha1().ClientCertificateAuthorities().Informer()}, nil
 	case tenancyv1alpha1.SchemeGroupVersion.WithResource("workspaces"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Tenancy().V1alpha1().Workspaces().Informer()}, nil
+	case tenancyv1alpha1.SchemeGroupVersion.WithResource("workspacerolebindings"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Tenancy().V1alpha1().WorkspaceRoleBindings().Informer()}, nil
 	case tenancyv1alpha1.SchemeGroupVersion.WithResource("workspaceshards"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Tenancy().V1alpha1().WorkspaceShards().Informer()}, nil
+	case tenancyv1alpha1.SchemeGroupVersion.WithResource("workspacetypes"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Tenancy().V1alpha1().WorkspaceTypes().Informer()}, nil
 
 	}
 
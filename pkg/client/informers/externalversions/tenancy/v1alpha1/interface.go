@@ -24,10 +24,18 @@ import (
 
 // Interface provides access to all the informers in this group version.
 type Interface interface {
+	// AuditSinks returns a AuditSinkInformer.
+	AuditSinks() AuditSinkInformer
+	// ClientCertificateAuthorities returns a ClientCertificateAuthorityInformer.
+	ClientCertificateAuthorities() ClientCertificateAuthorityInformer
 	// Workspaces returns a WorkspaceInformer.
 	Workspaces() WorkspaceInformer
+	// WorkspaceRoleBindings returns a WorkspaceRoleBindingInformer.
+	WorkspaceRoleBindings() WorkspaceRoleBindingInformer
 	// WorkspaceShards returns a WorkspaceShardInformer.
 	WorkspaceShards() WorkspaceShardInformer
+	// WorkspaceTypes returns a WorkspaceTypeInformer.
+	WorkspaceTypes() WorkspaceTypeInformer
 }
 
 type version struct {
@@ -41,12 +49,32 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 	return &version{factory: f, namespace: namespace, tweakListOptions: tweakListOptions}
 }
 
+// AuditSinks returns a AuditSinkInformer.
+func (v *version) AuditSinks() AuditSinkInformer {
+	return &auditSinkInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
+// ClientCertificateAuthorities returns a ClientCertificateAuthorityInformer.
+func (v *version) ClientCertificateAuthorities() ClientCertificateAuthorityInformer {
+	return &clientCertificateAuthorityInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
 // Workspaces returns a WorkspaceInformer.
 func (v *version) Workspaces() WorkspaceInformer {
 	return &workspaceInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
 }
 
+// WorkspaceRoleBindings returns a WorkspaceRoleBindingInformer.
+func (v *version) WorkspaceRoleBindings() WorkspaceRoleBindingInformer {
+	return &workspaceRoleBindingInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
 // WorkspaceShards returns a WorkspaceShardInformer.
 func (v *version) WorkspaceShards() WorkspaceShardInformer {
 	return &workspaceShardInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
 }
+
+// WorkspaceTypes returns a WorkspaceTypeInformer.
+func (v *version) WorkspaceTypes() WorkspaceTypeInformer {
+	return &workspaceTypeInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
@@ -21,3 +21,11 @@ package v1alpha1
 // ClusterListerExpansion allows custom methods to be added to
 // ClusterLister.
 type ClusterListerExpansion interface{}
+
+// PlacementListerExpansion allows custom methods to be added to
+// PlacementLister.
+type PlacementListerExpansion interface{}
+
+// SyncTransformListerExpansion allows custom methods to be added to
+// SyncTransformLister.
+type SyncTransformListerExpansion interface{}
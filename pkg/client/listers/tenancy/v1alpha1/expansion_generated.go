@@ -18,10 +18,26 @@ limitations under the License.
 
 package v1alpha1
 
+// AuditSinkListerExpansion allows custom methods to be added to
+// AuditSinkLister.
+type AuditSinkListerExpansion interface{}
+
+// ClientCertificateAuthorityListerExpansion allows custom methods to be added to
+// ClientCertificateAuthorityLister.
+type ClientCertificateAuthorityListerExpansion interface{}
+
 // WorkspaceListerExpansion allows custom methods to be added to
 // WorkspaceLister.
 type WorkspaceListerExpansion interface{}
 
+// WorkspaceRoleBindingListerExpansion allows custom methods to be added to
+// WorkspaceRoleBindingLister.
+type WorkspaceRoleBindingListerExpansion interface{}
+
 // WorkspaceShardListerExpansion allows custom methods to be added to
 // WorkspaceShardLister.
 type WorkspaceShardListerExpansion interface{}
+
+// WorkspaceTypeListerExpansion allows custom methods to be added to
+// WorkspaceTypeLister.
+type WorkspaceTypeListerExpansion interface{}
@@ -0,0 +1,201 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metadatainformer provides the metadata-only variant of the informers of
+// dynamicinformer: each informer watches the metadata of a resource across all logical
+// clusters with a single wildcard LIST/WATCH, and caches PartialObjectMetadata rather than
+// whole objects. Controllers which only read names, labels, annotations, owner references
+// or finalizers should use them, as they take a fraction of the memory of full objects.
+package metadatainformer
+
+import (
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/metadata/metadatainformer"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/kcp-dev/kcp/pkg/client/clusterkeys"
+	"github.com/kcp-dev/kcp/pkg/client/dynamicinformer"
+)
+
+// ClusterMetadataSharedInformerFactory provides metadata-only informers for the resources
+// of all logical clusters. Each informer watches its resource in all logical clusters at
+// once, and is shared by the views of the factory scoped to each logical cluster.
+type ClusterMetadataSharedInformerFactory interface {
+	Start(stopCh <-chan struct{})
+	WaitForCacheSync(stopCh <-chan struct{}) map[schema.GroupVersionResource]bool
+
+	// ForResource returns the informer of the resource across all logical clusters.
+	ForResource(gvr schema.GroupVersionResource) dynamicinformer.ClusterGenericInformer
+	// Cluster returns a view of the factory scoped to the given logical cluster.
+	Cluster(name string) metadatainformer.SharedInformerFactory
+}
+
+// NewClusterMetadataSharedInformerFactory returns a ClusterMetadataSharedInformerFactory
+// watching all namespaces of all logical clusters. The client must reach all logical
+// clusters, like those created for the "*" cluster with clustercontext.ConfigFor.
+func NewClusterMetadataSharedInformerFactory(client metadata.Interface, defaultResync time.Duration) ClusterMetadataSharedInformerFactory {
+	return NewFilteredClusterMetadataSharedInformerFactory(client, defaultResync, metav1.NamespaceAll, nil)
+}
+
+// NewFilteredClusterMetadataSharedInformerFactory returns a
+// ClusterMetadataSharedInformerFactory watching the given namespace of all logical
+// clusters, with the given list options.
+func NewFilteredClusterMetadataSharedInformerFactory(client metadata.Interface, defaultResync time.Duration, namespace string, tweakListOptions metadatainformer.TweakListOptionsFunc) ClusterMetadataSharedInformerFactory {
+	return &clusterMetadataSharedInformerFactory{
+		client:           client,
+		defaultResync:    defaultResync,
+		namespace:        namespace,
+		tweakListOptions: tweakListOptions,
+		informers:        map[schema.GroupVersionResource]*clusterGenericInformer{},
+		startedInformers: map[schema.GroupVersionResource]bool{},
+	}
+}
+
+// NewFilteredClusterMetadataInformer returns a metadata-only informer of the resource
+// across all logical clusters, which is not shared, so that it can be stopped on its own.
+// Its indexer has the cluster indexes of the listers, along with the given ones.
+func NewFilteredClusterMetadataInformer(client metadata.Interface, gvr schema.GroupVersionResource, namespace string, resyncPeriod time.Duration, indexers cache.Indexers, tweakListOptions metadatainformer.TweakListOptionsFunc) dynamicinformer.ClusterGenericInformer {
+	allIndexers := cache.Indexers{
+		cache.NamespaceIndex:                      cache.MetaNamespaceIndexFunc,
+		dynamicinformer.ClusterIndexName:          dynamicinformer.ClusterIndexFunc,
+		dynamicinformer.ClusterNamespaceIndexName: dynamicinformer.ClusterNamespaceIndexFunc,
+	}
+	for name, indexFunc := range indexers {
+		allIndexers[name] = indexFunc
+	}
+	return &clusterGenericInformer{
+		informer: metadatainformer.NewFilteredMetadataInformer(client, gvr, namespace, resyncPeriod, allIndexers, tweakListOptions).Informer(),
+		resource: gvr.GroupResource(),
+	}
+}
+
+type clusterMetadataSharedInformerFactory struct {
+	client           metadata.Interface
+	defaultResync    time.Duration
+	namespace        string
+	tweakListOptions metadatainformer.TweakListOptionsFunc
+
+	lock      sync.Mutex
+	informers map[schema.GroupVersionResource]*clusterGenericInformer
+	// startedInformers is used for tracking which informers have been started.
+	// This allows Start() to be called multiple times safely.
+	startedInformers map[schema.GroupVersionResource]bool
+}
+
+func (f *clusterMetadataSharedInformerFactory) ForResource(gvr schema.GroupVersionResource) dynamicinformer.ClusterGenericInformer {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if informer, found := f.informers[gvr]; found {
+		return informer
+	}
+	informer := NewFilteredClusterMetadataInformer(f.client, gvr, f.namespace, f.defaultResync, nil, f.tweakListOptions).(*clusterGenericInformer)
+	f.informers[gvr] = informer
+	return informer
+}
+
+func (f *clusterMetadataSharedInformerFactory) Cluster(name string) metadatainformer.SharedInformerFactory {
+	return &scopedFactory{factory: f, cluster: name}
+}
+
+func (f *clusterMetadataSharedInformerFactory) Start(stopCh <-chan struct{}) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	for gvr, informer := range f.informers {
+		if !f.startedInformers[gvr] {
+			go informer.informer.Run(stopCh)
+			f.startedInformers[gvr] = true
+		}
+	}
+}
+
+func (f *clusterMetadataSharedInformerFactory) WaitForCacheSync(stopCh <-chan struct{}) map[schema.GroupVersionResource]bool {
+	informers := func() map[schema.GroupVersionResource]cache.SharedIndexInformer {
+		f.lock.Lock()
+		defer f.lock.Unlock()
+
+		informers := map[schema.GroupVersionResource]cache.SharedIndexInformer{}
+		for gvr, informer := range f.informers {
+			if f.startedInformers[gvr] {
+				informers[gvr] = informer.informer
+			}
+		}
+		return informers
+	}()
+
+	res := map[schema.GroupVersionResource]bool{}
+	for gvr, informer := range informers {
+		res[gvr] = cache.WaitForCacheSync(stopCh, informer.HasSynced)
+	}
+	return res
+}
+
+type clusterGenericInformer struct {
+	informer cache.SharedIndexInformer
+	resource schema.GroupResource
+}
+
+func (i *clusterGenericInformer) Informer() cache.SharedIndexInformer {
+	return i.informer
+}
+
+func (i *clusterGenericInformer) Lister() dynamicinformer.ClusterLister {
+	return clusterkeys.NewLister(i.informer.GetIndexer(), i.resource)
+}
+
+func (i *clusterGenericInformer) Cluster(name string) informers.GenericInformer {
+	return &scopedInformer{informer: i.informer, lister: i.Lister().Cluster(name)}
+}
+
+// scopedFactory is the view of a factory scoped to a logical cluster.
+type scopedFactory struct {
+	factory *clusterMetadataSharedInformerFactory
+	cluster string
+}
+
+func (f *scopedFactory) Start(stopCh <-chan struct{}) {
+	f.factory.Start(stopCh)
+}
+
+func (f *scopedFactory) WaitForCacheSync(stopCh <-chan struct{}) map[schema.GroupVersionResource]bool {
+	return f.factory.WaitForCacheSync(stopCh)
+}
+
+func (f *scopedFactory) ForResource(gvr schema.GroupVersionResource) informers.GenericInformer {
+	return f.factory.ForResource(gvr).Cluster(f.cluster)
+}
+
+// scopedInformer is an informer shared across logical clusters, with a lister scoped to
+// one of them.
+type scopedInformer struct {
+	informer cache.SharedIndexInformer
+	lister   cache.GenericLister
+}
+
+func (i *scopedInformer) Informer() cache.SharedIndexInformer {
+	return i.informer
+}
+
+func (i *scopedInformer) Lister() cache.GenericLister {
+	return i.lister
+}
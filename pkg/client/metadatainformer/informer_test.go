@@ -0,0 +1,90 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metadatainformer
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	metadatafake "k8s.io/client-go/metadata/fake"
+)
+
+func TestScopedListers(t *testing.T) {
+	configmaps := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	object := func(clusterName, namespace, name string) runtime.Object {
+		return &metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+			ObjectMeta: metav1.ObjectMeta{ClusterName: clusterName, Namespace: namespace, Name: name},
+		}
+	}
+	scheme := runtime.NewScheme()
+	if err := metav1.AddMetaToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	client := metadatafake.NewSimpleMetadataClient(scheme,
+		object("admin", "default", "a"),
+		object("admin", "other", "b"),
+		object("user", "default", "c"),
+	)
+
+	factory := NewClusterMetadataSharedInformerFactory(client, 0)
+	informer := factory.ForResource(configmaps)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	factory.Start(stopCh)
+	factory.WaitForCacheSync(stopCh)
+
+	names := func(objs []runtime.Object, err error) []string {
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, obj := range objs {
+			metaObj, ok := obj.(*metav1.PartialObjectMetadata)
+			if !ok {
+				t.Fatalf("expected object metadata, got %T", obj)
+			}
+			names = append(names, metaObj.ClusterName+"/"+metaObj.Namespace+"/"+metaObj.Name)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	if diff := cmp.Diff([]string{"admin/default/a", "admin/other/b", "user/default/c"}, names(informer.Lister().List(labels.Everything()))); diff != "" {
+		t.Errorf("unexpected objects of all clusters (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"admin/default/a", "admin/other/b"}, names(factory.Cluster("admin").ForResource(configmaps).Lister().List(labels.Everything()))); diff != "" {
+		t.Errorf("unexpected objects of cluster admin (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"user/default/c"}, names(informer.Lister().Cluster("user").ByNamespace("default").List(labels.Everything()))); diff != "" {
+		t.Errorf("unexpected objects of namespace default of cluster user (-want +got):\n%s", diff)
+	}
+
+	if _, err := informer.Cluster("user").Lister().ByNamespace("default").Get("c"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := informer.Cluster("admin").Lister().ByNamespace("default").Get("c"); !errors.IsNotFound(err) {
+		t.Errorf("expected NotFound, got %v", err)
+	}
+}
@@ -0,0 +1,245 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package admin implements the admin commands of kcp, which give operators visibility into
+// the WorkspaceShards of an organization and the placement of its workspaces.
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/spf13/pflag"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+)
+
+// Options holds the kubeconfig of the organization the admin commands inspect.
+type Options struct {
+	// Kubeconfig is the kubeconfig file; the default loading rules apply when empty.
+	Kubeconfig string
+	// Context is the context to use instead of the current one.
+	Context string
+
+	Out io.Writer
+
+	newClient func(*rest.Config) (kcpclient.Interface, error)
+}
+
+// NewOptions returns Options writing their output to out.
+func NewOptions(out io.Writer) *Options {
+	return &Options{
+		Out: out,
+		newClient: func(config *rest.Config) (kcpclient.Interface, error) {
+			return kcpclient.NewForConfig(config)
+		},
+	}
+}
+
+// BindFlags binds the kubeconfig flags to the options.
+func (o *Options) BindFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.Kubeconfig, clientcmd.RecommendedConfigPathFlag, o.Kubeconfig, "Path to the kubeconfig file of kcp, whose context points at the organization workspace holding the shards.")
+	fs.StringVar(&o.Context, "context", o.Context, "The kubeconfig context to use.")
+}
+
+func (o *Options) restConfig() (*rest.Config, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = o.Kubeconfig
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{CurrentContext: o.Context}).ClientConfig()
+}
+
+func (o *Options) client() (kcpclient.Interface, error) {
+	config, err := o.restConfig()
+	if err != nil {
+		return nil, err
+	}
+	return o.newClient(config)
+}
+
+// fleet returns the shards and workspaces of the organization.
+func (o *Options) fleet(ctx context.Context) (kcpclient.Interface, []tenancyv1alpha1.WorkspaceShard, []tenancyv1alpha1.Workspace, error) {
+	client, err := o.client()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	shards, err := client.TenancyV1alpha1().WorkspaceShards().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	workspaces, err := client.TenancyV1alpha1().Workspaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return client, shards.Items, workspaces.Items, nil
+}
+
+// ListShards prints the shards of the organization, along with the number of workspaces
+// scheduled to each of them.
+func (o *Options) ListShards(ctx context.Context) error {
+	_, shards, workspaces, err := o.fleet(ctx)
+	if err != nil {
+		return err
+	}
+	scheduled := map[string]int{}
+	for _, workspace := range workspaces {
+		scheduled[workspace.Status.Location.Current]++
+	}
+
+	w := tabwriter.NewWriter(o.Out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tURL\tWORKSPACES\tCAPACITY")
+	for _, shard := range shards {
+		capacity := "<none>"
+		if len(shard.Status.Capacity) > 0 {
+			capacity = fmt.Sprintf("%v", shard.Status.Capacity)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", shard.Name, shard.Spec.BaseURL, scheduled[shard.Name], capacity)
+	}
+	if unscheduled := scheduled[""]; unscheduled > 0 {
+		fmt.Fprintf(w, "<unscheduled>\t\t%d\t\n", unscheduled)
+	}
+	return w.Flush()
+}
+
+// ListWorkspaces prints the workspaces of the organization and their placement, only those
+// scheduled to the given shard unless it is empty.
+func (o *Options) ListWorkspaces(ctx context.Context, shard string) error {
+	_, _, workspaces, err := o.fleet(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(o.Out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tPHASE\tSHARD\tTARGET\tURL")
+	for _, workspace := range workspaces {
+		location := workspace.Status.Location
+		if shard != "" && location.Current != shard {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", workspace.Name, workspace.Status.Phase, location.Current, location.Target, workspace.Status.BaseURL)
+	}
+	return w.Flush()
+}
+
+// Move is the move of a workspace to another shard.
+type Move struct {
+	Workspace string
+	From, To  string
+}
+
+// Rebalance moves workspaces from the most to the least loaded shards, until the numbers of
+// workspaces of the shards differ by one at most. The moves are set as the target location
+// of the workspaces, which the workspace controller then moves. With dryRun, the moves are
+// only printed.
+func (o *Options) Rebalance(ctx context.Context, dryRun bool) error {
+	client, shards, workspaces, err := o.fleet(ctx)
+	if err != nil {
+		return err
+	}
+	moves := planRebalance(shards, workspaces)
+	if len(moves) == 0 {
+		_, err := fmt.Fprintln(o.Out, "The shards are balanced.")
+		return err
+	}
+
+	for _, move := range moves {
+		if dryRun {
+			if _, err := fmt.Fprintf(o.Out, "Workspace %q would be moved from shard %q to %q.\n", move.Workspace, move.From, move.To); err != nil {
+				return err
+			}
+			continue
+		}
+		patch, err := json.Marshal(map[string]interface{}{
+			"status": map[string]interface{}{
+				"location": map[string]interface{}{"target": move.To},
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := client.TenancyV1alpha1().Workspaces().Patch(ctx, move.Workspace, types.MergePatchType, patch, metav1.PatchOptions{}, "status"); err != nil {
+			return fmt.Errorf("failed to move workspace %q to shard %q: %w", move.Workspace, move.To, err)
+		}
+		if _, err := fmt.Fprintf(o.Out, "Workspace %q is moving from shard %q to %q.\n", move.Workspace, move.From, move.To); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// planRebalance returns the moves evening out the numbers of workspaces scheduled to the
+// shards. Unscheduled and terminating workspaces, and those already moving, are left where
+// they are, but count towards the load of their shard, or of their target shard.
+func planRebalance(shards []tenancyv1alpha1.WorkspaceShard, workspaces []tenancyv1alpha1.Workspace) []Move {
+	if len(shards) < 2 {
+		return nil
+	}
+	load := map[string]int{}
+	movable := map[string][]string{}
+	for _, shard := range shards {
+		load[shard.Name] = 0
+	}
+	for _, workspace := range workspaces {
+		location := workspace.Status.Location
+		if _, found := load[location.Target]; found {
+			load[location.Target]++
+			continue
+		}
+		if _, found := load[location.Current]; !found {
+			continue
+		}
+		load[location.Current]++
+		if location.Target == "" && workspace.Status.Phase != tenancyv1alpha1.WorkspacePhaseTerminating {
+			movable[location.Current] = append(movable[location.Current], workspace.Name)
+		}
+	}
+	names := make([]string, 0, len(load))
+	for name := range load {
+		names = append(names, name)
+		sort.Strings(movable[name])
+	}
+	sort.Strings(names)
+
+	var moves []Move
+	for {
+		from, to := "", ""
+		for _, name := range names {
+			if len(movable[name]) > 0 && (from == "" || load[name] > load[from]) {
+				from = name
+			}
+			if to == "" || load[name] < load[to] {
+				to = name
+			}
+		}
+		if from == "" || load[from]-load[to] <= 1 {
+			return moves
+		}
+		last := len(movable[from]) - 1
+		moves = append(moves, Move{Workspace: movable[from][last], From: from, To: to})
+		movable[from] = movable[from][:last]
+		load[from]--
+		load[to]++
+	}
+}
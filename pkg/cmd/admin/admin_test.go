@@ -0,0 +1,105 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admin
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+)
+
+func newShard(name string) tenancyv1alpha1.WorkspaceShard {
+	return tenancyv1alpha1.WorkspaceShard{ObjectMeta: metav1.ObjectMeta{Name: name}}
+}
+
+func newWorkspace(name, current, target string, phase tenancyv1alpha1.WorkspacePhaseType) tenancyv1alpha1.Workspace {
+	return tenancyv1alpha1.Workspace{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: tenancyv1alpha1.WorkspaceStatus{
+			Phase:    phase,
+			Location: tenancyv1alpha1.WorkspaceLocation{Current: current, Target: target},
+		},
+	}
+}
+
+func TestPlanRebalance(t *testing.T) {
+	active := tenancyv1alpha1.WorkspacePhaseActive
+	for _, tc := range []struct {
+		name       string
+		shards     []tenancyv1alpha1.WorkspaceShard
+		workspaces []tenancyv1alpha1.Workspace
+		expected   []Move
+	}{
+		{
+			name:   "single shard",
+			shards: []tenancyv1alpha1.WorkspaceShard{newShard("a")},
+			workspaces: []tenancyv1alpha1.Workspace{
+				newWorkspace("one", "a", "", active),
+				newWorkspace("two", "a", "", active),
+			},
+		},
+		{
+			name:   "balanced",
+			shards: []tenancyv1alpha1.WorkspaceShard{newShard("a"), newShard("b")},
+			workspaces: []tenancyv1alpha1.Workspace{
+				newWorkspace("one", "a", "", active),
+				newWorkspace("two", "a", "", active),
+				newWorkspace("three", "b", "", active),
+			},
+		},
+		{
+			name:   "new shards are filled",
+			shards: []tenancyv1alpha1.WorkspaceShard{newShard("a"), newShard("b"), newShard("c")},
+			workspaces: []tenancyv1alpha1.Workspace{
+				newWorkspace("one", "a", "", active),
+				newWorkspace("two", "a", "", active),
+				newWorkspace("three", "a", "", active),
+				newWorkspace("four", "a", "", active),
+				newWorkspace("five", "a", "", active),
+				newWorkspace("unscheduled", "", "", tenancyv1alpha1.WorkspacePhaseInitializing),
+			},
+			expected: []Move{
+				{Workspace: "two", From: "a", To: "b"},
+				{Workspace: "three", From: "a", To: "c"},
+				{Workspace: "one", From: "a", To: "b"},
+			},
+		},
+		{
+			name:   "terminating and moving workspaces stay",
+			shards: []tenancyv1alpha1.WorkspaceShard{newShard("a"), newShard("b")},
+			workspaces: []tenancyv1alpha1.Workspace{
+				newWorkspace("one", "a", "", tenancyv1alpha1.WorkspacePhaseTerminating),
+				newWorkspace("two", "a", "b", active),
+				newWorkspace("three", "a", "", active),
+				newWorkspace("four", "a", "", active),
+			},
+			expected: []Move{
+				{Workspace: "three", From: "a", To: "b"},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.expected, planRebalance(tc.shards, tc.workspaces)); diff != "" {
+				t.Errorf("unexpected moves: %s", diff)
+			}
+		})
+	}
+}
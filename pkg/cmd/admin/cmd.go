@@ -0,0 +1,134 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admin
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kcp-dev/kcp/pkg/cmd/help"
+)
+
+// NewCmdAdmin returns the admin command of kcp, writing its output to out.
+func NewCmdAdmin(out io.Writer) *cobra.Command {
+	o := NewOptions(out)
+	cmd := &cobra.Command{
+		Use:   "admin",
+		Short: "Inspects the shards of an organization and mints credentials",
+		Long: help.Doc(`
+			Inspects the WorkspaceShards of the organization workspace the kubeconfig
+			context points at, and the shards its workspaces are scheduled to, and
+			mints kubeconfigs for its workspaces.
+		`),
+	}
+	o.BindFlags(cmd.PersistentFlags())
+
+	shardsCmd := &cobra.Command{
+		Use:     "shards",
+		Aliases: []string{"shard"},
+		Short:   "Manages the shards of the organization",
+	}
+	shardsCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "Lists the shards and the number of workspaces scheduled to each of them",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.ListShards(cmd.Context())
+		},
+	})
+	cmd.AddCommand(shardsCmd)
+
+	var shard string
+	workspacesCmd := &cobra.Command{
+		Use:     "workspaces",
+		Aliases: []string{"workspace", "ws"},
+		Short:   "Lists the workspaces and the shards they are scheduled to",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.ListWorkspaces(cmd.Context(), shard)
+		},
+	}
+	workspacesCmd.Flags().StringVar(&shard, "shard", shard, "Only lists the workspaces scheduled to this shard.")
+	cmd.AddCommand(workspacesCmd)
+
+	var dryRun bool
+	rebalanceCmd := &cobra.Command{
+		Use:   "rebalance",
+		Short: "Moves workspaces until the shards hold as many workspaces as each other",
+		Long: help.Doc(`
+			Moves workspaces from the most to the least loaded shards, until the
+			numbers of workspaces of the shards differ by one at most.
+
+			The moves are set as the target location of the workspaces, which the
+			workspace controller then moves. Workspaces which are terminating or
+			already moving are left where they are.
+		`),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.Rebalance(cmd.Context(), dryRun)
+		},
+	}
+	rebalanceCmd.Flags().BoolVar(&dryRun, "dry-run", dryRun, "Only prints the moves.")
+	cmd.AddCommand(rebalanceCmd)
+
+	kubeconfigOptions := &KubeconfigOptions{TTL: time.Hour}
+	var output string
+	kubeconfigCmd := &cobra.Command{
+		Use:   "kubeconfig",
+		Short: "Mints a kubeconfig valid for a workspace only",
+		Long: help.Doc(`
+			Mints a kubeconfig authenticating against a workspace only, for a limited
+			time, to onboard users and CI systems without sharing the admin
+			kubeconfig.
+
+			The kubeconfig is minted by kcp at the /kubeconfig path of the
+			workspace, for the user of the kubeconfig context, or with --user for
+			another user by impersonating them.
+
+			For example:
+
+			    kcp admin kubeconfig --user=alice --workspace=team --ttl=8h -o alice.kubeconfig
+		`),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if kubeconfigOptions.Workspace == "" {
+				return fmt.Errorf("--workspace is required")
+			}
+			data, err := o.MintKubeconfig(cmd.Context(), kubeconfigOptions)
+			if err != nil {
+				return err
+			}
+			if output == "-" {
+				_, err := o.Out.Write(data)
+				return err
+			}
+			return ioutil.WriteFile(output, data, 0600)
+		},
+	}
+	kubeconfigCmd.Flags().StringVar(&kubeconfigOptions.User, "user", kubeconfigOptions.User, "The user the kubeconfig authenticates as, instead of the user of the kubeconfig context. Requires the permission to impersonate them.")
+	kubeconfigCmd.Flags().StringSliceVar(&kubeconfigOptions.Groups, "group", kubeconfigOptions.Groups, "The groups of the user given with --user.")
+	kubeconfigCmd.Flags().StringVar(&kubeconfigOptions.Workspace, "workspace", kubeconfigOptions.Workspace, "The workspace the kubeconfig is valid for, by name or path, e.g. org:team. \"root\" is the root workspace.")
+	kubeconfigCmd.Flags().DurationVar(&kubeconfigOptions.TTL, "ttl", kubeconfigOptions.TTL, "How long the kubeconfig is valid, a day at most.")
+	kubeconfigCmd.Flags().StringVarP(&output, "output", "o", "-", "The file to write the kubeconfig to, or - for the standard output.")
+	cmd.AddCommand(kubeconfigCmd)
+
+	return cmd
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admin
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/kcp-dev/kcp/pkg/cmd/workspace"
+	"github.com/kcp-dev/kcp/pkg/serviceaccount"
+)
+
+// KubeconfigOptions select the user and the workspace of a minted kubeconfig.
+type KubeconfigOptions struct {
+	// User is the user the kubeconfig authenticates as. The kubeconfig is minted for the
+	// user of the kubeconfig context when empty, and by impersonating User otherwise.
+	User string
+	// Groups are the groups of the impersonated user.
+	Groups []string
+	// Workspace is the workspace the kubeconfig is valid for, by name or by path, e.g.
+	// org:team. Its logical cluster is the last segment of the path.
+	Workspace string
+	// TTL is how long the kubeconfig is valid, a day at most.
+	TTL time.Duration
+}
+
+// MintKubeconfig mints a kubeconfig with the credential-minting API of kcp, and returns it.
+// Unlike the admin kubeconfig, the kubeconfig is short-lived and only valid for the
+// workspace.
+func (o *Options) MintKubeconfig(ctx context.Context, opts *KubeconfigOptions) ([]byte, error) {
+	segments := strings.Split(opts.Workspace, ":")
+	logicalCluster := segments[len(segments)-1]
+	if logicalCluster == "" {
+		return nil, fmt.Errorf("invalid workspace %q", opts.Workspace)
+	}
+	if opts.TTL < time.Second {
+		return nil, fmt.Errorf("invalid ttl %s, it must be at least a second", opts.TTL)
+	}
+
+	config, err := o.restConfig()
+	if err != nil {
+		return nil, err
+	}
+	base, _, err := workspace.SplitServer(config.Host)
+	if err != nil {
+		return nil, err
+	}
+	config = rest.CopyConfig(config)
+	config.Host = base
+	if opts.User != "" {
+		config.Impersonate = rest.ImpersonationConfig{UserName: opts.User, Groups: opts.Groups}
+	} else if len(opts.Groups) > 0 {
+		return nil, fmt.Errorf("groups can only be given along with a user")
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	path := serviceaccount.KubeconfigPath
+	if logicalCluster != workspace.RootWorkspace {
+		path = "/clusters/" + logicalCluster + path
+	}
+	return client.Discovery().RESTClient().Get().
+		AbsPath(path).
+		Param("expirationSeconds", strconv.FormatInt(int64(opts.TTL.Seconds()), 10)).
+		DoRaw(ctx)
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admin
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMintKubeconfig(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/clusters/team/kubeconfig" {
+			http.NotFound(w, req)
+			return
+		}
+		if got := req.Header.Get("Impersonate-User"); got != "alice" {
+			t.Errorf("expected alice to be impersonated, got %q", got)
+		}
+		if got := req.URL.Query().Get("expirationSeconds"); got != "28800" {
+			t.Errorf("expected an expiration of 8h, got %q seconds", got)
+		}
+		_, _ = io.WriteString(w, "kubeconfig of alice")
+	}))
+	defer server.Close()
+
+	kubeconfig := filepath.Join(t.TempDir(), "kubeconfig")
+	// the context points at another workspace than the minted kubeconfig
+	if err := ioutil.WriteFile(kubeconfig, []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: kcp
+  cluster:
+    server: `+server.URL+`/clusters/org
+users:
+- name: admin
+  user:
+    token: admin-token
+contexts:
+- name: kcp
+  context:
+    cluster: kcp
+    user: admin
+current-context: kcp
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	o := NewOptions(io.Discard)
+	o.Kubeconfig = kubeconfig
+	data, err := o.MintKubeconfig(context.Background(), &KubeconfigOptions{
+		User:      "alice",
+		Workspace: "org:team",
+		TTL:       8 * time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "kubeconfig of alice" {
+		t.Errorf("unexpected kubeconfig %q", data)
+	}
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package backup implements the backup and restore commands of kcp.
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/kcp-dev/kcp/pkg/backup"
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	"github.com/kcp-dev/kcp/pkg/cmd/help"
+	"github.com/kcp-dev/kcp/pkg/cmd/workspace"
+)
+
+type options struct {
+	kubeconfig    string
+	context       string
+	workspaces    []string
+	allWorkspaces bool
+}
+
+func (o *options) bindFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.kubeconfig, clientcmd.RecommendedConfigPathFlag, o.kubeconfig, "Path to the kubeconfig file of the kcp server.")
+	fs.StringVar(&o.context, "context", o.context, "The kubeconfig context to use.")
+}
+
+// configs returns the config of the kubeconfig context, and the config of the kcp server
+// without the /clusters/... suffix of the workspace the context points at.
+func (o *options) configs() (*rest.Config, *rest.Config, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = o.kubeconfig
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{CurrentContext: o.context}).ClientConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	base, _, err := workspace.SplitServer(config.Host)
+	if err != nil {
+		return nil, nil, err
+	}
+	serverConfig := rest.CopyConfig(config)
+	serverConfig.Host = base
+	return config, serverConfig, nil
+}
+
+// selectedWorkspaces returns the workspaces given with --workspace, or with
+// --all-workspaces the child workspaces of the workspace of the context.
+func (o *options) selectedWorkspaces(ctx context.Context, config *rest.Config) ([]string, error) {
+	if !o.allWorkspaces {
+		return o.workspaces, nil
+	}
+	client, err := kcpclient.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	workspaces, err := client.TenancyV1alpha1().Workspaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(workspaces.Items))
+	for _, ws := range workspaces.Items {
+		names = append(names, ws.Name)
+	}
+	return names, nil
+}
+
+// NewCmdBackup returns the backup command, writing its progress to log.
+func NewCmdBackup(log io.Writer) *cobra.Command {
+	o := &options{}
+	var output string
+	cmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Exports the objects of workspaces into an archive",
+		Long: help.Doc(`
+			Exports the objects of workspaces through the API into a tar archive,
+			which 'kcp restore' imports into the same or another kcp instance.
+
+			Unlike etcd snapshots, the archive only holds the objects declared by the
+			tenants of the given workspaces: events, the tokens of service accounts
+			and the objects owned by a controller are left out, and so is the
+			metadata specific to the server, like UIDs and resource versions.
+		`),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(o.workspaces) == 0 && !o.allWorkspaces {
+				return fmt.Errorf("one of --workspace or --all-workspaces is required")
+			}
+			config, serverConfig, err := o.configs()
+			if err != nil {
+				return err
+			}
+			workspaces, err := o.selectedWorkspaces(cmd.Context(), config)
+			if err != nil {
+				return err
+			}
+
+			out := os.Stdout
+			if output != "-" {
+				if out, err = os.Create(output); err != nil {
+					return err
+				}
+				defer out.Close()
+			}
+			if err := backup.Backup(cmd.Context(), serverConfig, workspaces, out, log); err != nil {
+				return err
+			}
+			return out.Close()
+		},
+	}
+	o.bindFlags(cmd.Flags())
+	cmd.Flags().StringSliceVar(&o.workspaces, "workspace", o.workspaces, "The workspaces to back up, by name. \"root\" is the root workspace.")
+	cmd.Flags().BoolVar(&o.allWorkspaces, "all-workspaces", o.allWorkspaces, "Backs up all the child workspaces of the workspace of the kubeconfig context.")
+	cmd.Flags().StringVarP(&output, "output", "o", "-", "The archive file to write, or - for the standard output.")
+	return cmd
+}
+
+// NewCmdRestore returns the restore command, writing its progress to log.
+func NewCmdRestore(log io.Writer) *cobra.Command {
+	o := &options{}
+	var filename string
+	cmd := &cobra.Command{
+		Use:   "restore",
+		Short: "Imports the objects of an archive written by 'kcp backup'",
+		Long: help.Doc(`
+			Imports the objects of an archive written by 'kcp backup' into the
+			workspaces of the same name, on the kcp server of the kubeconfig.
+
+			Objects which already exist are left untouched, so that a restore can be
+			run again after a failure. CustomResourceDefinitions and namespaces are
+			restored before the other objects.
+		`),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, serverConfig, err := o.configs()
+			if err != nil {
+				return err
+			}
+
+			in := os.Stdin
+			if filename != "-" {
+				if in, err = os.Open(filename); err != nil {
+					return err
+				}
+				defer in.Close()
+			}
+			return backup.Restore(cmd.Context(), serverConfig, in, o.workspaces, log)
+		},
+	}
+	o.bindFlags(cmd.Flags())
+	cmd.Flags().StringSliceVar(&o.workspaces, "workspace", o.workspaces, "The workspaces of the archive to restore, by name. All workspaces of the archive are restored by default.")
+	cmd.Flags().StringVarP(&filename, "filename", "f", "-", "The archive file to read, or - for the standard input.")
+	return cmd
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package doctor implements the doctor command of kcp.
+package doctor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/kcp-dev/kcp/pkg/cmd/help"
+	"github.com/kcp-dev/kcp/pkg/doctor"
+)
+
+// NewCmdDoctor returns the doctor command, writing its report to out.
+func NewCmdDoctor(out io.Writer) *cobra.Command {
+	o := doctor.DefaultOptions()
+	var output string
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Diagnoses a kcp instance from its root directory",
+		Long: help.Doc(`
+			Diagnoses a kcp instance from its root directory, and reports:
+
+			- the files missing from the root directory, and the secrets readable by
+			  other users than their owner,
+			- the certificates expired or about to expire, in the root directory and
+			  in the admin kubeconfig,
+			- the health, alarms and database size of etcd, relative to its quota,
+			- the readiness checks of the server, whose post-start hooks start the
+			  controllers,
+			- the WorkspaceShards whose base URL cannot be reached.
+
+			Give it the flags given to 'kcp start' which locate the state of the
+			server. The command fails when any check reports an error.
+		`),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			report := doctor.Run(cmd.Context(), o)
+			if err := printReport(out, report, output); err != nil {
+				return err
+			}
+			if errors := report.Count(doctor.StatusError); errors > 0 {
+				return fmt.Errorf("%d checks failed", errors)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&o.RootDirectory, "root_directory", o.RootDirectory, "Root directory of the kcp server.")
+	cmd.Flags().StringVar(&o.KubeConfigPath, "kubeconfig_path", o.KubeConfigPath, "Path of the administrative kubeconfig, relative to the root directory.")
+	cmd.Flags().StringVar(&o.EtcdClientPort, "etcd_client_port", o.EtcdClientPort, "Client port of the in-process etcd.")
+	cmd.Flags().StringSliceVar(&o.EtcdEndpoints, "etcd-servers", o.EtcdEndpoints, "External etcd servers of the kcp server. The in-process etcd is checked if absent.")
+	cmd.Flags().StringVar(&o.EtcdKeyFile, "etcd-keyfile", o.EtcdKeyFile, "TLS key file used to secure etcd communication.")
+	cmd.Flags().StringVar(&o.EtcdCertFile, "etcd-certfile", o.EtcdCertFile, "TLS certification file used to secure etcd communication.")
+	cmd.Flags().StringVar(&o.EtcdCAFile, "etcd-cafile", o.EtcdCAFile, "TLS Certificate Authority file used to secure etcd communication.")
+	cmd.Flags().Int64Var(&o.EtcdQuotaBytes, "etcd-quota-bytes", o.EtcdQuotaBytes, "Backend quota of etcd, against which the size of its database is reported.")
+	cmd.Flags().DurationVar(&o.CertificateWarningPeriod, "certificate-warning-period", o.CertificateWarningPeriod, "How long before their expiration certificates are reported.")
+	cmd.Flags().DurationVar(&o.Timeout, "timeout", o.Timeout, "Timeout of each check reaching a server.")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Output format of the report: json or yaml. A table by default.")
+	return cmd
+}
+
+func printReport(out io.Writer, report *doctor.Report, output string) error {
+	switch output {
+	case "json":
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, string(data))
+		return err
+	case "yaml":
+		data, err := yaml.Marshal(report)
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(data)
+		return err
+	case "":
+		w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "CATEGORY\tCHECK\tSTATUS\tMESSAGE")
+		for _, result := range report.Results {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", result.Category, result.Check, result.Status, result.Message)
+		}
+		fmt.Fprintf(w, "\n%d OK, %d warnings, %d errors\n", report.Count(doctor.StatusOK), report.Count(doctor.StatusWarning), report.Count(doctor.StatusError))
+		return w.Flush()
+	default:
+		return fmt.Errorf("unsupported output format %q, expected json or yaml", output)
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package replay implements the replay command of kcp.
+package replay
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/kcp-dev/kcp/pkg/cmd/help"
+	"github.com/kcp-dev/kcp/pkg/cmd/workspace"
+	"github.com/kcp-dev/kcp/pkg/scenario"
+)
+
+// NewCmdReplay returns the replay command, writing the outcome of each request to out.
+func NewCmdReplay(out io.Writer) *cobra.Command {
+	var kubeconfig, context, file string
+	var o scenario.ReplayOptions
+	cmd := &cobra.Command{
+		Use:   "replay -f <script>",
+		Short: "Replays a recorded scenario of API requests against a kcp server",
+		Long: help.Doc(`
+			Replays the API requests of a scenario script against the kcp server of
+			the kubeconfig context, with its credentials, e.g. to reproduce the
+			scenario of an end-to-end test, recorded with KCP_E2E_RECORD_SCENARIOS,
+			or to generate load from real scenarios.
+
+			The paths of the requests are absolute, including the logical clusters
+			they were sent to, so the workspace the context points at does not
+			matter. The command fails at the first failed request, unless asked to
+			continue on errors.
+
+			For example:
+
+			    kcp replay -f scenario.yaml --preserve-timing
+		`),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if file == "" {
+				return fmt.Errorf("-f is required")
+			}
+			script, err := scenario.Load(file)
+			if err != nil {
+				return err
+			}
+			loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+			loadingRules.ExplicitPath = kubeconfig
+			config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{CurrentContext: context}).ClientConfig()
+			if err != nil {
+				return err
+			}
+			if config.Host, _, err = workspace.SplitServer(config.Host); err != nil {
+				return err
+			}
+			client, err := scenario.NewReplayClient(config)
+			if err != nil {
+				return err
+			}
+			failed, err := scenario.Replay(cmd.Context(), client, script, o, out)
+			if err != nil {
+				return err
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d requests failed", failed, len(script.Steps))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&kubeconfig, clientcmd.RecommendedConfigPathFlag, kubeconfig, "Path to the kubeconfig file of the kcp server.")
+	cmd.Flags().StringVar(&context, "context", context, "The kubeconfig context to use.")
+	cmd.Flags().StringVarP(&file, "filename", "f", file, "The scenario script to replay.")
+	cmd.Flags().BoolVar(&o.PreserveTiming, "preserve-timing", o.PreserveTiming, "Wait between the requests as long as when they were recorded.")
+	cmd.Flags().BoolVar(&o.ContinueOnError, "continue-on-error", o.ContinueOnError, "Go on with the next requests when a request fails.")
+	return cmd
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workload
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/kcp-dev/kcp/pkg/cmd/help"
+)
+
+// NewCmdWorkload returns the workload command of kcp.
+func NewCmdWorkload() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "workload",
+		Short: "Manages the physical clusters workloads are synced to",
+	}
+
+	o := &SyncOptions{
+		Resources:       []string{"deployments.apps"},
+		TokenExpiration: 365 * 24 * time.Hour,
+	}
+	var output string
+	syncCmd := &cobra.Command{
+		Use:   "sync <cluster-name>",
+		Short: "Enrolls a physical cluster, and writes the manifests running its syncer",
+		Long: help.Doc(`
+			Enrolls a physical cluster in the workspace of the kubeconfig context, and
+			writes the manifests to apply on the physical cluster to run its syncer.
+
+			The Cluster is registered with the Tunnel connection mode: the syncer
+			opens a tunnel to kcp, so neither kcp needs a kubeconfig of the physical
+			cluster, nor the physical cluster to accept inbound connections. The
+			syncer authenticates with the token of a service account of the
+			workspace, only allowed to sync the given resources.
+
+			For example:
+
+			    kcp workload sync east --syncer-image=<image> | kubectl --context=east apply -f -
+		`),
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if o.SyncerImage == "" {
+				return fmt.Errorf("--syncer-image is required")
+			}
+			out := os.Stdout
+			if output != "-" {
+				var err error
+				if out, err = os.Create(output); err != nil {
+					return err
+				}
+				defer out.Close()
+			}
+			if err := Sync(cmd.Context(), args[0], o, out); err != nil {
+				return err
+			}
+			return out.Close()
+		},
+	}
+	syncCmd.Flags().StringVar(&o.Kubeconfig, clientcmd.RecommendedConfigPathFlag, o.Kubeconfig, "Path to the kubeconfig file of kcp, whose context points at the workspace to enroll the cluster in.")
+	syncCmd.Flags().StringVar(&o.Context, "context", o.Context, "The kubeconfig context to use.")
+	syncCmd.Flags().StringVar(&o.SyncerImage, "syncer-image", o.SyncerImage, "The image of the syncer run on the physical cluster.")
+	syncCmd.Flags().StringSliceVar(&o.Resources, "resources", o.Resources, "The resources synced to the physical cluster.")
+	syncCmd.Flags().DurationVar(&o.TokenExpiration, "token-expiration", o.TokenExpiration, "How long the credentials of the syncer are valid. Run the command again to renew them.")
+	syncCmd.Flags().StringVarP(&output, "output", "o", "-", "The file to write the manifests to, or - for the standard output.")
+	cmd.AddCommand(syncCmd)
+
+	return cmd
+}
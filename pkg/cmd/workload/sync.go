@@ -0,0 +1,273 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package workload implements the workload commands of kcp, which enroll physical clusters
+// to sync workloads to.
+package workload
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/yaml"
+
+	clusterv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/cluster/v1alpha1"
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	"github.com/kcp-dev/kcp/pkg/cmd/workspace"
+	"github.com/kcp-dev/kcp/pkg/reconciler/cluster"
+	"github.com/kcp-dev/kcp/pkg/syncer"
+)
+
+// syncerNamespace is the namespace of the service accounts of syncers, in the logical
+// cluster of their Cluster.
+const syncerNamespace = "default"
+
+// SyncOptions configure the enrollment of a physical cluster.
+type SyncOptions struct {
+	// Kubeconfig and Context select the workspace of kcp the cluster is enrolled in.
+	Kubeconfig string
+	Context    string
+
+	// SyncerImage is the image of the syncer run on the physical cluster.
+	SyncerImage string
+	// Resources are the resources synced to the physical cluster.
+	Resources []string
+	// TokenExpiration is how long the credentials of the syncer are valid.
+	TokenExpiration time.Duration
+}
+
+// syncerName returns the name of the service account, and RBAC objects, of the syncer of
+// the given Cluster in kcp.
+func syncerName(clusterName string) string {
+	return "kcp-syncer-" + clusterName
+}
+
+// Sync registers the named Cluster, reached through a tunnel, in the workspace of the
+// kubeconfig context, creates the credentials of its syncer, and writes the manifests
+// running the syncer on the physical cluster to out.
+func Sync(ctx context.Context, clusterName string, o *SyncOptions, out io.Writer) error {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = o.Kubeconfig
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{CurrentContext: o.Context}).ClientConfig()
+	if err != nil {
+		return err
+	}
+	server, logicalCluster, err := workspace.SplitServer(config.Host)
+	if err != nil {
+		return err
+	}
+	if logicalCluster == workspace.RootWorkspace {
+		return fmt.Errorf("the kubeconfig context must point at a workspace, e.g. with 'kubectl kcp workspace use'")
+	}
+
+	kcpClient, err := kcpclient.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	kubeClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	if err := ensureCluster(ctx, kcpClient, clusterName); err != nil {
+		return err
+	}
+	token, err := syncerToken(ctx, kubeClient, clusterName, o.Resources, o.TokenExpiration)
+	if err != nil {
+		return err
+	}
+	kubeconfig, err := syncerKubeconfig(config, server, token)
+	if err != nil {
+		return err
+	}
+
+	objs := cluster.SyncerManifests(o.SyncerImage, kubeconfig, clusterName, logicalCluster, o.Resources, syncer.DefaultOptions())
+	return writeManifests(out, objs)
+}
+
+// ensureCluster creates the Cluster reached through the tunnel of its syncer, unless it
+// exists already.
+func ensureCluster(ctx context.Context, client kcpclient.Interface, clusterName string) error {
+	existing, err := client.ClusterV1alpha1().Clusters().Get(ctx, clusterName, metav1.GetOptions{})
+	if err == nil {
+		if existing.Spec.ConnectionMode != clusterv1alpha1.ConnectionModeTunnel {
+			return fmt.Errorf("cluster %q exists already with connection mode %q instead of %q", clusterName, existing.Spec.ConnectionMode, clusterv1alpha1.ConnectionModeTunnel)
+		}
+		return nil
+	} else if !apierrors.IsNotFound(err) {
+		return err
+	}
+	_, err = client.ClusterV1alpha1().Clusters().Create(ctx, &clusterv1alpha1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: clusterName},
+		Spec:       clusterv1alpha1.ClusterSpec{ConnectionMode: clusterv1alpha1.ConnectionModeTunnel},
+	}, metav1.CreateOptions{})
+	return err
+}
+
+// syncerRules returns the rules the syncer of a cluster needs in its logical cluster: reading
+// the Clusters, SyncTransforms and namespaces, syncing the given resources, their status and
+// the ConfigMaps and Secrets they reference, recording events and opening the tunnel.
+func syncerRules(resources []string) []rbacv1.PolicyRule {
+	resourcesWithStatus := sets.NewString()
+	apiGroups := sets.NewString()
+	for _, resource := range resources {
+		gr := schema.ParseGroupResource(resource)
+		resourcesWithStatus.Insert(gr.Resource, gr.Resource+"/status")
+		apiGroups.Insert(gr.Group)
+	}
+	return []rbacv1.PolicyRule{
+		{
+			Verbs:     []string{"get", "list", "watch"},
+			APIGroups: []string{clusterv1alpha1.SchemeGroupVersion.Group},
+			Resources: []string{"clusters", "synctransforms"},
+		},
+		{
+			Verbs:     []string{"get", "list", "watch"},
+			APIGroups: []string{""},
+			Resources: []string{"namespaces", "configmaps", "secrets"},
+		},
+		{
+			Verbs:     []string{"get", "list", "watch", "create", "update", "patch", "delete"},
+			APIGroups: apiGroups.List(),
+			Resources: resourcesWithStatus.List(),
+		},
+		{
+			Verbs:     []string{"create", "update", "patch"},
+			APIGroups: []string{""},
+			Resources: []string{"events"},
+		},
+		{
+			Verbs:           []string{"get"},
+			NonResourceURLs: []string{"/tunnels/*"},
+		},
+	}
+}
+
+// syncerToken creates the service account of the syncer of the cluster, bound to the rules
+// of the syncer, and returns a token for it valid for the given duration.
+func syncerToken(ctx context.Context, client kubernetes.Interface, clusterName string, resources []string, expiration time.Duration) (string, error) {
+	name := syncerName(clusterName)
+	if _, err := client.CoreV1().ServiceAccounts(syncerNamespace).Create(ctx, &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Namespace: syncerNamespace, Name: name},
+	}, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return "", err
+	}
+
+	clusterRole := &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Rules:      syncerRules(resources),
+	}
+	if _, err := client.RbacV1().ClusterRoles().Create(ctx, clusterRole, metav1.CreateOptions{}); err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return "", err
+		}
+		existing, err := client.RbacV1().ClusterRoles().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+		if !equality.Semantic.DeepEqual(existing.Rules, clusterRole.Rules) {
+			clusterRole.ResourceVersion = existing.ResourceVersion
+			if _, err := client.RbacV1().ClusterRoles().Update(ctx, clusterRole, metav1.UpdateOptions{}); err != nil {
+				return "", err
+			}
+		}
+	}
+	if _, err := client.RbacV1().ClusterRoleBindings().Create(ctx, &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Subjects: []rbacv1.Subject{{
+			Kind:      "ServiceAccount",
+			Name:      name,
+			Namespace: syncerNamespace,
+		}},
+		RoleRef: rbacv1.RoleRef{
+			Kind:     "ClusterRole",
+			Name:     name,
+			APIGroup: rbacv1.GroupName,
+		},
+	}, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return "", err
+	}
+
+	expirationSeconds := int64(expiration.Seconds())
+	tokenRequest, err := client.CoreV1().ServiceAccounts(syncerNamespace).CreateToken(ctx, name, &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{ExpirationSeconds: &expirationSeconds},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to request a token for the syncer: %w", err)
+	}
+	return tokenRequest.Status.Token, nil
+}
+
+// syncerKubeconfig returns the kubeconfig of the syncer, reaching the given kcp server with
+// the CA of config and the given token. The syncer adds the logical cluster to the URL.
+func syncerKubeconfig(config *rest.Config, server, token string) (string, error) {
+	caData := config.CAData
+	if len(caData) == 0 && config.CAFile != "" {
+		var err error
+		if caData, err = ioutil.ReadFile(config.CAFile); err != nil {
+			return "", err
+		}
+	}
+
+	kubeconfig := clientcmdapi.NewConfig()
+	kubeconfig.Clusters["kcp"] = &clientcmdapi.Cluster{
+		Server:                   server,
+		CertificateAuthorityData: caData,
+		TLSServerName:            config.TLSClientConfig.ServerName,
+		InsecureSkipTLSVerify:    config.TLSClientConfig.Insecure,
+	}
+	kubeconfig.AuthInfos["syncer"] = &clientcmdapi.AuthInfo{Token: token}
+	kubeconfig.Contexts["kcp"] = &clientcmdapi.Context{Cluster: "kcp", AuthInfo: "syncer"}
+	kubeconfig.CurrentContext = "kcp"
+	data, err := clientcmd.Write(*kubeconfig)
+	return string(data), err
+}
+
+// writeManifests writes the objects to out as a multi-document YAML stream.
+func writeManifests(out io.Writer, objs []runtime.Object) error {
+	for _, obj := range objs {
+		gvks, _, err := scheme.Scheme.ObjectKinds(obj)
+		if err != nil {
+			return err
+		}
+		obj.GetObjectKind().SetGroupVersionKind(gvks[0])
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(out, "---\n%s", data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workload
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	"github.com/kcp-dev/kcp/pkg/reconciler/cluster"
+	"github.com/kcp-dev/kcp/pkg/syncer"
+)
+
+func TestSyncerToken(t *testing.T) {
+	client := kubefake.NewSimpleClientset()
+	client.PrependReactor("create", "serviceaccounts", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "token" {
+			return false, nil, nil
+		}
+		return true, &authenticationv1.TokenRequest{Status: authenticationv1.TokenRequestStatus{Token: "token"}}, nil
+	})
+	if token, err := syncerToken(context.Background(), client, "east", []string{"deployments.apps"}, time.Hour); err != nil {
+		t.Fatal(err)
+	} else if token != "token" {
+		t.Errorf("expected the requested token, got %q", token)
+	}
+	// running the command again updates the rules to the new resources
+	if _, err := syncerToken(context.Background(), client, "east", []string{"deployments.apps", "services"}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	clusterRole, err := client.RbacV1().ClusterRoles().Get(context.Background(), "kcp-syncer-east", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	synced := clusterRole.Rules[2]
+	if diff := cmp.Diff([]string{"", "apps"}, synced.APIGroups); diff != "" {
+		t.Errorf("unexpected API groups: %s", diff)
+	}
+	if diff := cmp.Diff([]string{"deployments", "deployments/status", "services", "services/status"}, synced.Resources); diff != "" {
+		t.Errorf("unexpected resources: %s", diff)
+	}
+	binding, err := client.RbacV1().ClusterRoleBindings().Get(context.Background(), "kcp-syncer-east", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject := binding.Subjects[0]; subject.Namespace != syncerNamespace || subject.Name != "kcp-syncer-east" {
+		t.Errorf("unexpected subject %v", subject)
+	}
+}
+
+func TestWriteManifests(t *testing.T) {
+	var out bytes.Buffer
+	objs := cluster.SyncerManifests("syncer:latest", "kubeconfig", "east", "team", []string{"deployments.apps"}, syncer.DefaultOptions())
+	if err := writeManifests(&out, objs); err != nil {
+		t.Fatal(err)
+	}
+	manifests := out.String()
+	for _, expected := range []string{"kind: Namespace", "kind: ClusterRoleBinding", "kind: Secret", "kind: Deployment", "- -tunnel", "image: syncer:latest"} {
+		if !strings.Contains(manifests, expected) {
+			t.Errorf("expected %q in the manifests:\n%s", expected, manifests)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workspace
+
+import (
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kcp-dev/kcp/pkg/cmd/help"
+)
+
+// NewCmdWorkspace returns the workspace command of the kubectl kcp plugin, writing its
+// output to out.
+func NewCmdWorkspace(out io.Writer) *cobra.Command {
+	o := NewOptions(out)
+	cmd := &cobra.Command{
+		Use:     "workspace",
+		Aliases: []string{"ws", "workspaces"},
+		Short:   "Manages workspaces and the workspace of the kubeconfig",
+		Long: help.Doc(`
+			Manages the child workspaces of the current workspace, and points the
+			kubeconfig at the workspace to work in.
+
+			The current workspace is the one the server URL of the current kubeconfig
+			context points at, through its /clusters/<workspace> suffix. 'use' writes
+			the workspace.kcp.dev/current context pointing at the chosen workspace,
+			with the credentials of the context it started from, and makes it the
+			current context.
+
+			Without a subcommand, prints the current workspace.
+		`),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.Current()
+		},
+	}
+	o.BindFlags(cmd.PersistentFlags())
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "current",
+		Short: "Prints the current workspace",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.Current()
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "Lists the child workspaces of the current workspace",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.List(cmd.Context())
+		},
+	})
+
+	var depth int
+	treeCmd := &cobra.Command{
+		Use:   "tree",
+		Short: "Prints the hierarchy of the workspaces below the current workspace",
+		Long: help.Doc(`
+			Prints the hierarchy of the workspaces below the current workspace, with
+			their phase, their shard, and the verbs you have on them. The workspaces
+			you have no access to are listed without their children.
+
+			The hierarchy is aggregated by the server, in a single request.
+		`),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.Tree(cmd.Context(), depth)
+		},
+	}
+	treeCmd.Flags().IntVar(&depth, "depth", depth, "The number of levels of workspaces to print, all of them if zero.")
+	cmd.AddCommand(treeCmd)
+
+	var workspaceType string
+	var use bool
+	createCmd := &cobra.Command{
+		Use:   "create <workspace>",
+		Short: "Creates a child workspace of the current workspace",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.Create(cmd.Context(), args[0], workspaceType, use)
+		},
+	}
+	createCmd.Flags().StringVar(&workspaceType, "type", workspaceType, "The WorkspaceType of the new workspace.")
+	createCmd.Flags().BoolVar(&use, "use", use, "Uses the new workspace once it is created.")
+	cmd.AddCommand(createCmd)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "use <workspace>|root|-",
+		Short: "Points the kubeconfig at a child workspace, the root workspace, or the previous workspace",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.Use(cmd.Context(), args[0])
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "rename <workspace> <new name>",
+		Short: "Renames a child workspace of the current workspace, keeping its content",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.Rename(cmd.Context(), args[0], args[1])
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "delete <workspace>",
+		Short: "Deletes a child workspace of the current workspace",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.Delete(cmd.Context(), args[0])
+		},
+	})
+
+	return cmd
+}
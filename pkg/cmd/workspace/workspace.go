@@ -0,0 +1,408 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package workspace implements the workspace verbs of the kubectl kcp plugin, which point
+// the kubeconfig at the logical cluster of a workspace instead of having users edit the
+// /clusters/... suffix of server URLs.
+package workspace
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/pflag"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/authorization/workspacecontent"
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	"github.com/kcp-dev/kcp/pkg/workspacenames"
+)
+
+const (
+	// CurrentContext is the kubeconfig context, and cluster, pointing at the workspace used
+	// last. Its credentials are those of the context the workspace was chosen from.
+	CurrentContext = "workspace.kcp.dev/current"
+	// PreviousContext is the kubeconfig context, and cluster, pointing at the workspace used
+	// before the current one, so that `use -` goes back to it.
+	PreviousContext = "workspace.kcp.dev/previous"
+
+	// RootWorkspace names the root logical cluster, served without a /clusters/... suffix.
+	// It is a reserved workspace name.
+	RootWorkspace = "root"
+)
+
+// Options holds the kubeconfig the workspace verbs read and rewrite.
+type Options struct {
+	// Kubeconfig is the kubeconfig file; the default loading rules apply when empty.
+	Kubeconfig string
+	// Context is the context to start from instead of the current one.
+	Context string
+
+	Out io.Writer
+
+	newClient func(*rest.Config) (kcpclient.Interface, error)
+}
+
+// NewOptions returns Options writing their output to out.
+func NewOptions(out io.Writer) *Options {
+	return &Options{
+		Out: out,
+		newClient: func(config *rest.Config) (kcpclient.Interface, error) {
+			return kcpclient.NewForConfig(config)
+		},
+	}
+}
+
+// BindFlags binds the kubeconfig flags to the options.
+func (o *Options) BindFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.Kubeconfig, clientcmd.RecommendedConfigPathFlag, o.Kubeconfig, "Path to the kubeconfig file to use and rewrite.")
+	fs.StringVar(&o.Context, "context", o.Context, "The kubeconfig context to start from, instead of the current context.")
+}
+
+func (o *Options) pathOptions() *clientcmd.PathOptions {
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.LoadingRules.ExplicitPath = o.Kubeconfig
+	return pathOptions
+}
+
+// startingPoint returns the loaded kubeconfig, along with the name of the context to start
+// from and its cluster.
+func (o *Options) startingPoint() (*clientcmd.PathOptions, *clientcmdapi.Config, string, *clientcmdapi.Cluster, error) {
+	pathOptions := o.pathOptions()
+	config, err := pathOptions.GetStartingConfig()
+	if err != nil {
+		return nil, nil, "", nil, err
+	}
+	contextName := o.Context
+	if contextName == "" {
+		contextName = config.CurrentContext
+	}
+	kubeContext, found := config.Contexts[contextName]
+	if !found {
+		return nil, nil, "", nil, fmt.Errorf("context %q not found in the kubeconfig", contextName)
+	}
+	cluster, found := config.Clusters[kubeContext.Cluster]
+	if !found {
+		return nil, nil, "", nil, fmt.Errorf("cluster %q of context %q not found in the kubeconfig", kubeContext.Cluster, contextName)
+	}
+	return pathOptions, config, contextName, cluster, nil
+}
+
+func (o *Options) client(config *clientcmdapi.Config, contextName string) (kcpclient.Interface, error) {
+	restConfig, err := clientcmd.NewNonInteractiveClientConfig(*config, contextName, &clientcmd.ConfigOverrides{}, nil).ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	return o.newClient(restConfig)
+}
+
+// Current prints the workspace of the starting context.
+func (o *Options) Current() error {
+	_, _, _, cluster, err := o.startingPoint()
+	if err != nil {
+		return err
+	}
+	_, workspace, err := SplitServer(cluster.Server)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(o.Out, "Current workspace is %q.\n", workspace)
+	return err
+}
+
+// List prints the child workspaces of the current workspace.
+func (o *Options) List(ctx context.Context) error {
+	_, config, contextName, _, err := o.startingPoint()
+	if err != nil {
+		return err
+	}
+	client, err := o.client(config, contextName)
+	if err != nil {
+		return err
+	}
+	workspaces, err := client.TenancyV1alpha1().Workspaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(o.Out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tTYPE\tPHASE\tURL")
+	for _, workspace := range workspaces.Items {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", workspace.Name, workspace.Spec.Type, workspace.Status.Phase, workspace.Status.BaseURL)
+	}
+	return w.Flush()
+}
+
+// Tree prints the hierarchy of the workspaces below the current workspace, down to the given
+// depth, or the whole hierarchy if zero, with their phase, their shard, and the verbs the
+// user has on them. The workspaces the user has no access to are listed without their
+// children.
+func (o *Options) Tree(ctx context.Context, depth int) error {
+	_, config, contextName, _, err := o.startingPoint()
+	if err != nil {
+		return err
+	}
+	client, err := o.client(config, contextName)
+	if err != nil {
+		return err
+	}
+	// the server aggregates the tree, rather than the plugin listing each workspace
+	request := client.Discovery().RESTClient().Get().AbsPath(workspacecontent.WorkspaceTreePath)
+	if depth > 0 {
+		request = request.Param("depth", strconv.Itoa(depth))
+	}
+	data, err := request.DoRaw(ctx)
+	if err != nil {
+		return err
+	}
+	tree := &workspacecontent.WorkspaceTree{}
+	if err := json.Unmarshal(data, tree); err != nil {
+		return fmt.Errorf("invalid workspace tree: %w", err)
+	}
+	return printTree(o.Out, tree)
+}
+
+func printTree(out io.Writer, tree *workspacecontent.WorkspaceTree) error {
+	if _, err := fmt.Fprintln(out, treeLabel(tree)); err != nil {
+		return err
+	}
+	return printChildren(out, tree, "")
+}
+
+func printChildren(out io.Writer, tree *workspacecontent.WorkspaceTree, prefix string) error {
+	for i, child := range tree.Children {
+		branch, indent := "├── ", "│   "
+		if i == len(tree.Children)-1 {
+			branch, indent = "└── ", "    "
+		}
+		if _, err := fmt.Fprintf(out, "%s%s%s\n", prefix, branch, treeLabel(child)); err != nil {
+			return err
+		}
+		if err := printChildren(out, child, prefix+indent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// treeLabel returns the name of the workspace, along with its phase, its shard and the
+// verbs of the user on it.
+func treeLabel(tree *workspacecontent.WorkspaceTree) string {
+	var details []string
+	if tree.Phase != "" {
+		details = append(details, string(tree.Phase))
+	}
+	if tree.Shard != "" {
+		details = append(details, "shard "+tree.Shard)
+	}
+	if len(tree.Verbs) == 0 {
+		details = append(details, "no access")
+	} else {
+		verbs := make([]string, 0, len(tree.Verbs))
+		for _, verb := range tree.Verbs {
+			verbs = append(verbs, string(verb))
+		}
+		details = append(details, strings.Join(verbs, ","))
+	}
+	return fmt.Sprintf("%s [%s]", tree.Name, strings.Join(details, ", "))
+}
+
+// Create creates a child workspace of the given type in the current workspace, and uses it
+// if asked to.
+func (o *Options) Create(ctx context.Context, name, workspaceType string, use bool) error {
+	_, config, contextName, _, err := o.startingPoint()
+	if err != nil {
+		return err
+	}
+	client, err := o.client(config, contextName)
+	if err != nil {
+		return err
+	}
+	if _, err := client.TenancyV1alpha1().Workspaces().Create(ctx, &tenancyv1alpha1.Workspace{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       tenancyv1alpha1.WorkspaceSpec{Type: workspaceType},
+	}, metav1.CreateOptions{}); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(o.Out, "Workspace %q created.\n", name); err != nil {
+		return err
+	}
+	if !use {
+		return nil
+	}
+	return o.Use(ctx, name)
+}
+
+// Delete deletes a child workspace of the current workspace.
+func (o *Options) Delete(ctx context.Context, name string) error {
+	_, config, contextName, _, err := o.startingPoint()
+	if err != nil {
+		return err
+	}
+	client, err := o.client(config, contextName)
+	if err != nil {
+		return err
+	}
+	if err := client.TenancyV1alpha1().Workspaces().Delete(ctx, name, metav1.DeleteOptions{}); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(o.Out, "Workspace %q deleted.\n", name)
+	return err
+}
+
+// Rename renames a child workspace of the current workspace. Its previous name keeps
+// reaching it until the rename grace period of the server ends.
+func (o *Options) Rename(ctx context.Context, name, newName string) error {
+	_, config, contextName, _, err := o.startingPoint()
+	if err != nil {
+		return err
+	}
+	client, err := o.client(config, contextName)
+	if err != nil {
+		return err
+	}
+	if err := client.TenancyV1alpha1().RESTClient().Post().
+		Resource("workspaces").
+		Name(name).
+		SubResource(workspacenames.RenameSubresource).
+		Param("name", newName).
+		Do(ctx).
+		Error(); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(o.Out, "Workspace %q renamed to %q.\n", name, newName)
+	return err
+}
+
+// Use points the CurrentContext of the kubeconfig at a child workspace of the current
+// workspace, at the root workspace, or with "-" back at the previous workspace, and makes
+// it the current context.
+func (o *Options) Use(ctx context.Context, name string) error {
+	pathOptions, config, contextName, _, err := o.startingPoint()
+	if err != nil {
+		return err
+	}
+	if err := o.use(ctx, config, contextName, name); err != nil {
+		return err
+	}
+	if err := clientcmd.ModifyConfig(pathOptions, *config, true); err != nil {
+		return err
+	}
+	_, workspace, err := SplitServer(config.Clusters[CurrentContext].Server)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(o.Out, "Current workspace is %q.\n", workspace)
+	return err
+}
+
+// use switches the given kubeconfig from the given context to the named workspace.
+func (o *Options) use(ctx context.Context, config *clientcmdapi.Config, contextName, name string) error {
+	current := config.Contexts[contextName]
+	cluster := config.Clusters[current.Cluster]
+
+	if name == "-" {
+		previous, found := config.Contexts[PreviousContext]
+		if !found {
+			return fmt.Errorf("no previous workspace to go back to")
+		}
+		previousCluster, found := config.Clusters[previous.Cluster]
+		if !found {
+			return fmt.Errorf("cluster %q of context %q not found in the kubeconfig", previous.Cluster, PreviousContext)
+		}
+		switchTo(config, cluster, current.AuthInfo, previousCluster.DeepCopy(), previous.AuthInfo)
+		return nil
+	}
+
+	base, parent, err := SplitServer(cluster.Server)
+	if err != nil {
+		return err
+	}
+	server := base
+	if name != RootWorkspace {
+		client, err := o.client(config, contextName)
+		if err != nil {
+			return err
+		}
+		workspace, err := client.TenancyV1alpha1().Workspaces().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		server = workspace.Status.BaseURL
+		if server == "" && workspace.Status.Cluster != "" {
+			server = base + "/clusters/" + workspace.Status.Cluster
+		} else if server == "" {
+			// the name of a workspace is only unique in its parent
+			path := name
+			if parent != RootWorkspace {
+				path = parent + workspacenames.PathSeparator + name
+			}
+			server = base + "/clusters/" + path
+		}
+	}
+	next := cluster.DeepCopy()
+	next.Server = server
+	switchTo(config, cluster, current.AuthInfo, next, current.AuthInfo)
+	return nil
+}
+
+// switchTo records the given starting cluster and credentials as the PreviousContext, and
+// makes the CurrentContext with the given cluster and credentials the current context.
+func switchTo(config *clientcmdapi.Config, from *clientcmdapi.Cluster, fromAuthInfo string, to *clientcmdapi.Cluster, toAuthInfo string) {
+	config.Clusters[PreviousContext] = from.DeepCopy()
+	config.Contexts[PreviousContext] = newContext(PreviousContext, fromAuthInfo)
+	config.Clusters[CurrentContext] = to
+	config.Contexts[CurrentContext] = newContext(CurrentContext, toAuthInfo)
+	config.CurrentContext = CurrentContext
+}
+
+func newContext(cluster, authInfo string) *clientcmdapi.Context {
+	context := clientcmdapi.NewContext()
+	context.Cluster = cluster
+	context.AuthInfo = authInfo
+	return context
+}
+
+// SplitServer splits the URL of a server into the URL of the kcp server and the workspace
+// of its /clusters/... suffix, which is the RootWorkspace without suffix.
+func SplitServer(server string) (string, string, error) {
+	u, err := url.Parse(server)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid server URL %q: %w", server, err)
+	}
+	i := strings.Index(u.Path, "/clusters/")
+	if i == -1 {
+		return strings.TrimSuffix(server, "/"), RootWorkspace, nil
+	}
+	workspace := strings.TrimPrefix(u.Path[i:], "/clusters/")
+	if j := strings.Index(workspace, "/"); j != -1 {
+		workspace = workspace[:j]
+	}
+	u.Path = u.Path[:i]
+	u.RawPath = ""
+	return u.String(), workspace, nil
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workspace
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/authorization/workspacecontent"
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	kcpfake "github.com/kcp-dev/kcp/pkg/client/clientset/versioned/fake"
+)
+
+func TestUse(t *testing.T) {
+	config := clientcmdapi.NewConfig()
+	config.Clusters["admin"] = clientcmdapi.NewCluster()
+	config.Clusters["admin"].Server = "https://kcp:6443"
+	config.AuthInfos["admin"] = clientcmdapi.NewAuthInfo()
+	config.AuthInfos["admin"].Token = "token"
+	config.Contexts["admin"] = newContext("admin", "admin")
+	config.CurrentContext = "admin"
+
+	o := NewOptions(io.Discard)
+	o.newClient = func(*rest.Config) (kcpclient.Interface, error) {
+		return kcpfake.NewSimpleClientset(
+			&tenancyv1alpha1.Workspace{ObjectMeta: metav1.ObjectMeta{Name: "team"}, Status: tenancyv1alpha1.WorkspaceStatus{Cluster: "k7c2q9x4", BaseURL: "https://shard:6443/clusters/k7c2q9x4"}},
+			&tenancyv1alpha1.Workspace{ObjectMeta: metav1.ObjectMeta{Name: "unscheduled"}, Status: tenancyv1alpha1.WorkspaceStatus{Cluster: "m3n4p5q6"}},
+			&tenancyv1alpha1.Workspace{ObjectMeta: metav1.ObjectMeta{Name: "new"}},
+		), nil
+	}
+
+	steps := []struct {
+		use           string
+		wantServer    string
+		wantWorkspace string
+	}{
+		{use: "team", wantServer: "https://shard:6443/clusters/k7c2q9x4", wantWorkspace: "k7c2q9x4"},
+		{use: "unscheduled", wantServer: "https://shard:6443/clusters/m3n4p5q6", wantWorkspace: "m3n4p5q6"},
+		{use: "-", wantServer: "https://shard:6443/clusters/k7c2q9x4", wantWorkspace: "k7c2q9x4"},
+		{use: "new", wantServer: "https://shard:6443/clusters/k7c2q9x4:new", wantWorkspace: "k7c2q9x4:new"},
+		{use: "root", wantServer: "https://shard:6443", wantWorkspace: RootWorkspace},
+		{use: "new", wantServer: "https://shard:6443/clusters/new", wantWorkspace: "new"},
+		{use: "-", wantServer: "https://shard:6443", wantWorkspace: RootWorkspace},
+	}
+	for _, step := range steps {
+		if err := o.use(context.Background(), config, config.CurrentContext, step.use); err != nil {
+			t.Fatalf("use %s: %v", step.use, err)
+		}
+		if config.CurrentContext != CurrentContext {
+			t.Fatalf("use %s: expected current context %q, got %q", step.use, CurrentContext, config.CurrentContext)
+		}
+		server := config.Clusters[CurrentContext].Server
+		if server != step.wantServer {
+			t.Errorf("use %s: expected server %q, got %q", step.use, step.wantServer, server)
+		}
+		if _, workspace, err := SplitServer(server); err != nil || workspace != step.wantWorkspace {
+			t.Errorf("use %s: expected workspace %q, got %q (%v)", step.use, step.wantWorkspace, workspace, err)
+		}
+		if got := config.Contexts[CurrentContext].AuthInfo; got != "admin" {
+			t.Errorf("use %s: expected the admin credentials, got %q", step.use, got)
+		}
+	}
+}
+
+func TestPrintTree(t *testing.T) {
+	admin := []tenancyv1alpha1.WorkspaceVerb{tenancyv1alpha1.WorkspaceVerbAdmin}
+	tree := &workspacecontent.WorkspaceTree{Name: "org", Verbs: admin, Children: []*workspacecontent.WorkspaceTree{
+		{Name: "infra", Phase: tenancyv1alpha1.WorkspacePhaseActive, Shard: "shard-1"},
+		{Name: "team", Phase: tenancyv1alpha1.WorkspacePhaseActive, Shard: "shard-2", Verbs: admin, Children: []*workspacecontent.WorkspaceTree{
+			{Name: "dev", Phase: tenancyv1alpha1.WorkspacePhaseInitializing, Verbs: []tenancyv1alpha1.WorkspaceVerb{tenancyv1alpha1.WorkspaceVerbAccess, tenancyv1alpha1.WorkspaceVerbCreateChild}},
+		}},
+	}}
+	var out bytes.Buffer
+	if err := printTree(&out, tree); err != nil {
+		t.Fatal(err)
+	}
+	expected := `org [admin]
+├── infra [Active, shard shard-1, no access]
+└── team [Active, shard shard-2, admin]
+    └── dev [Initializing, access,create-child]
+`
+	if out.String() != expected {
+		t.Errorf("expected tree:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
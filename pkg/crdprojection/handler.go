@@ -0,0 +1,372 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crdprojection
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/watch"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/apiserver/pkg/util/wsstream"
+	"k8s.io/client-go/tools/clusters"
+)
+
+// WithProjections merges the projected CRDs into the wildcard lists and watches of
+// CustomResourceDefinitions, which are served as JSON for that purpose. Lists get the
+// projections appended to their last page, unless a CRD of the same name is listed in the
+// same logical cluster, and watches get events for the projections on top of the events
+// of the stored CRDs, stamped with the last resource version of the stream.
+func WithProjections(handler http.Handler, registry *Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		cluster := genericapirequest.ClusterFrom(req.Context())
+		info, ok := genericapirequest.RequestInfoFrom(req.Context())
+		if !ok || cluster == nil || !cluster.Wildcard || !isCRDCollection(info) || wsstream.IsWebSocketRequest(req) {
+			handler.ServeHTTP(w, req)
+			return
+		}
+		selector, err := newSelector(req)
+		if err != nil {
+			// the handler rejects the request
+			handler.ServeHTTP(w, req)
+			return
+		}
+
+		req = req.Clone(req.Context())
+		req.Header.Set("Accept", "application/json")
+		req.Header.Del("Accept-Encoding")
+		if info.Verb == "watch" {
+			serveWatch(handler, w, req, registry, selector)
+			return
+		}
+
+		recorder := &responseRecorder{header: http.Header{}, status: http.StatusOK}
+		handler.ServeHTTP(recorder, req)
+		body := recorder.body.Bytes()
+		if recorder.status == http.StatusOK && strings.HasPrefix(recorder.header.Get("Content-Type"), "application/json") {
+			projections, _ := registry.Projections()
+			if merged, err := mergeList(body, projections, selector); err == nil {
+				body = merged
+			}
+		}
+		for k, v := range recorder.header {
+			w.Header()[k] = v
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(recorder.status)
+		_, _ = w.Write(body)
+	})
+}
+
+func isCRDCollection(info *genericapirequest.RequestInfo) bool {
+	return info.IsResourceRequest &&
+		info.APIGroup == apiextensionsv1.GroupName && info.APIVersion == apiextensionsv1.SchemeGroupVersion.Version &&
+		info.Resource == "customresourcedefinitions" && info.Subresource == "" && info.Name == "" &&
+		(info.Verb == "list" || info.Verb == "watch")
+}
+
+// selector holds the label and field selectors of a request.
+type selector struct {
+	labels labels.Selector
+	fields fields.Selector
+}
+
+func newSelector(req *http.Request) (selector, error) {
+	query := req.URL.Query()
+	labelSelector, err := labels.Parse(query.Get("labelSelector"))
+	if err != nil {
+		return selector{}, err
+	}
+	fieldSelector, err := fields.ParseSelector(query.Get("fieldSelector"))
+	if err != nil {
+		return selector{}, err
+	}
+	return selector{labels: labelSelector, fields: fieldSelector}, nil
+}
+
+func (s selector) matches(crd *apiextensionsv1.CustomResourceDefinition) bool {
+	return s.labels.Matches(labels.Set(crd.Labels)) &&
+		s.fields.Matches(fields.Set{"metadata.name": crd.Name, "metadata.clusterName": crd.ClusterName})
+}
+
+// sortedKeys returns the keys of the projections in order, for stable responses.
+func sortedKeys(projections map[string]*apiextensionsv1.CustomResourceDefinition) []string {
+	keys := make([]string, 0, len(projections))
+	for key := range projections {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// mergeList appends the projections to the last page of a list of CRDs.
+func mergeList(body []byte, projections map[string]*apiextensionsv1.CustomResourceDefinition, selector selector) ([]byte, error) {
+	var list apiextensionsv1.CustomResourceDefinitionList
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, err
+	}
+	if list.Continue != "" || len(projections) == 0 {
+		return body, nil
+	}
+	listed := map[string]bool{}
+	for _, crd := range list.Items {
+		listed[clusters.ToClusterAwareKey(crd.ClusterName, crd.Name)] = true
+	}
+	for _, key := range sortedKeys(projections) {
+		if crd := projections[key]; !listed[key] && selector.matches(crd) {
+			list.Items = append(list.Items, *crd)
+		}
+	}
+	return json.Marshal(&list)
+}
+
+// watchEvent is a watch event as served in JSON.
+type watchEvent struct {
+	Type   watch.EventType `json:"type"`
+	Object json.RawMessage `json:"object"`
+}
+
+// serveWatch serves a watch of CRDs with the events of the projections.
+func serveWatch(handler http.Handler, w http.ResponseWriter, req *http.Request, registry *Registry, selector selector) {
+	watcher := &watchWriter{
+		ResponseWriter:  w,
+		registry:        registry,
+		selector:        selector,
+		started:         make(chan struct{}),
+		resourceVersion: req.URL.Query().Get("resourceVersion"),
+		sent:            map[string]*apiextensionsv1.CustomResourceDefinition{},
+		stored:          map[string]bool{},
+	}
+	done, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		watcher.run(done)
+	}()
+	handler.ServeHTTP(watcher, req)
+	close(done)
+	<-stopped
+}
+
+// watchWriter forwards the events of a watch of CRDs and adds the events of the
+// projections, starting with an ADDED event for each of them, since the projections may
+// have changed after the list the watch continues from.
+type watchWriter struct {
+	http.ResponseWriter
+	registry *Registry
+	selector selector
+	started  chan struct{}
+
+	lock      sync.Mutex
+	streaming bool
+	// broken is set when the stream couldn't be decoded, which is then passed through.
+	broken          bool
+	buf             []byte
+	resourceVersion string
+	// sent holds the projections last sent by key.
+	sent map[string]*apiextensionsv1.CustomResourceDefinition
+	// stored holds the keys of the stored CRDs sent, which take precedence over projections.
+	stored map[string]bool
+}
+
+func (w *watchWriter) run(done <-chan struct{}) {
+	select {
+	case <-w.started:
+	case <-done:
+		return
+	}
+	for {
+		projections, changed := w.registry.Projections()
+		w.lock.Lock()
+		err := w.sync(projections)
+		w.lock.Unlock()
+		if err != nil {
+			return
+		}
+		select {
+		case <-changed:
+		case <-done:
+			return
+		}
+	}
+}
+
+func (w *watchWriter) WriteHeader(status int) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if status == http.StatusOK && !w.streaming && strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		w.streaming = true
+		close(w.started)
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *watchWriter) Write(data []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if !w.streaming || w.broken {
+		return w.ResponseWriter.Write(data)
+	}
+	w.buf = append(w.buf, data...)
+	for {
+		decoder := json.NewDecoder(bytes.NewReader(w.buf))
+		var event watchEvent
+		if err := decoder.Decode(&event); err == io.EOF || err == io.ErrUnexpectedEOF {
+			return len(data), nil
+		} else if err != nil {
+			w.broken = true
+			_, err := w.ResponseWriter.Write(w.buf)
+			w.buf = nil
+			return len(data), err
+		}
+		n := decoder.InputOffset()
+		raw := w.buf[:n]
+		w.buf = w.buf[n:]
+		if err := w.forward(raw, &event); err != nil {
+			return len(data), err
+		}
+	}
+}
+
+func (w *watchWriter) Flush() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.flush()
+}
+
+func (w *watchWriter) flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// forward writes an event of the stored CRDs, recording the resource version of the stream.
+func (w *watchWriter) forward(raw []byte, event *watchEvent) error {
+	var object struct {
+		Metadata struct {
+			Name            string `json:"name"`
+			ClusterName     string `json:"clusterName"`
+			ResourceVersion string `json:"resourceVersion"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(event.Object, &object); err == nil && object.Metadata.ResourceVersion != "" {
+		w.resourceVersion = object.Metadata.ResourceVersion
+	}
+	key := clusters.ToClusterAwareKey(object.Metadata.ClusterName, object.Metadata.Name)
+	switch event.Type {
+	case watch.Added, watch.Modified:
+		// the stored CRD replaced the projection of the same name in the informers
+		w.stored[key] = true
+		delete(w.sent, key)
+	case watch.Deleted:
+		delete(w.stored, key)
+	}
+	if _, err := w.ResponseWriter.Write(raw); err != nil {
+		return err
+	}
+	if event.Type == watch.Deleted {
+		// a projection shadowed by the deleted CRD is served again
+		projections, _ := w.registry.Projections()
+		if _, projected := projections[key]; projected {
+			return w.sync(projections)
+		}
+	}
+	return nil
+}
+
+// sync sends the events turning the projections last sent into the given ones.
+func (w *watchWriter) sync(projections map[string]*apiextensionsv1.CustomResourceDefinition) error {
+	sent := false
+	for _, key := range sortedKeys(projections) {
+		crd := projections[key]
+		if w.stored[key] || !w.selector.matches(crd) {
+			continue
+		}
+		previous, ok := w.sent[key]
+		if previous == crd {
+			continue
+		}
+		eventType := watch.Modified
+		if !ok {
+			eventType = watch.Added
+		}
+		if err := w.send(eventType, crd); err != nil {
+			return err
+		}
+		w.sent[key] = crd
+		sent = true
+	}
+	for _, key := range sortedKeys(w.sent) {
+		if crd, ok := projections[key]; ok && !w.stored[key] && w.selector.matches(crd) {
+			continue
+		}
+		if err := w.send(watch.Deleted, w.sent[key]); err != nil {
+			return err
+		}
+		delete(w.sent, key)
+		sent = true
+	}
+	if sent {
+		w.flush()
+	}
+	return nil
+}
+
+func (w *watchWriter) send(eventType watch.EventType, crd *apiextensionsv1.CustomResourceDefinition) error {
+	obj := crd.DeepCopy()
+	obj.APIVersion = apiextensionsv1.SchemeGroupVersion.String()
+	obj.Kind = "CustomResourceDefinition"
+	// the informers resume their watches from the resource version of the last event
+	obj.ResourceVersion = w.resourceVersion
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	data, err = json.Marshal(&watchEvent{Type: eventType, Object: data})
+	if err != nil {
+		return err
+	}
+	_, err = w.ResponseWriter.Write(append(data, '\n'))
+	return err
+}
+
+// responseRecorder holds a response in memory.
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	return r.body.Write(data)
+}
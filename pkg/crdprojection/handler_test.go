@@ -0,0 +1,150 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crdprojection
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+)
+
+func crd(cluster, name string) *apiextensionsv1.CustomResourceDefinition {
+	return &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: name, ClusterName: cluster, ResourceVersion: "3"},
+		Spec:       apiextensionsv1.CustomResourceDefinitionSpec{Group: "example.dev"},
+	}
+}
+
+func withCRDRequest(handler http.Handler, verb string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := genericapirequest.WithCluster(req.Context(), genericapirequest.Cluster{Name: "*", Wildcard: true})
+		ctx = genericapirequest.WithRequestInfo(ctx, &genericapirequest.RequestInfo{
+			IsResourceRequest: true,
+			Verb:              verb,
+			APIGroup:          apiextensionsv1.GroupName,
+			APIVersion:        "v1",
+			Resource:          "customresourcedefinitions",
+		})
+		handler.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
+
+func TestWithProjectionsList(t *testing.T) {
+	registry := NewRegistry()
+	registry.Set("org#$#team", []*apiextensionsv1.CustomResourceDefinition{
+		Project(crd("platform", "widgets.example.dev"), "team", nil),
+		Project(crd("platform", "gadgets.example.dev"), "team", nil),
+	})
+	handler := withCRDRequest(WithProjections(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if accept := req.Header.Get("Accept"); accept != "application/json" {
+			t.Errorf("expected the list to be requested as JSON, got %q", accept)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(&apiextensionsv1.CustomResourceDefinitionList{
+			ListMeta: metav1.ListMeta{Continue: req.URL.Query().Get("limit")},
+			Items:    []apiextensionsv1.CustomResourceDefinition{*crd("platform", "widgets.example.dev"), *crd("team", "gadgets.example.dev")},
+		})
+	}), registry), "list")
+
+	for _, tc := range []struct {
+		query    string
+		expected []string
+	}{
+		{"", []string{"platform/widgets.example.dev", "team/gadgets.example.dev", "team/widgets.example.dev"}},
+		{"?limit=2", []string{"platform/widgets.example.dev", "team/gadgets.example.dev"}},
+		{"?fieldSelector=metadata.clusterName%3Dplatform", []string{"platform/widgets.example.dev", "team/gadgets.example.dev"}},
+	} {
+		rw := httptest.NewRecorder()
+		handler.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/apis/apiextensions.k8s.io/v1/customresourcedefinitions"+tc.query, nil))
+		var list apiextensionsv1.CustomResourceDefinitionList
+		if err := json.Unmarshal(rw.Body.Bytes(), &list); err != nil {
+			t.Fatal(err)
+		}
+		var listed []string
+		for _, crd := range list.Items {
+			listed = append(listed, crd.ClusterName+"/"+crd.Name)
+		}
+		if diff := cmp.Diff(tc.expected, listed); diff != "" {
+			t.Errorf("unexpected crds listed with %q (-want +got):\n%s", tc.query, diff)
+		}
+	}
+}
+
+func TestWithProjectionsWatch(t *testing.T) {
+	registry := NewRegistry()
+	registry.Set("org#$#team", []*apiextensionsv1.CustomResourceDefinition{
+		Project(crd("platform", "widgets.example.dev"), "team", nil),
+	})
+	next := make(chan struct{})
+	server := httptest.NewServer(withCRDRequest(WithProjections(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		<-next
+		modified := crd("platform", "widgets.example.dev")
+		modified.ResourceVersion = "11"
+		_ = json.NewEncoder(w).Encode(&metav1.WatchEvent{Type: string(watch.Modified), Object: rawExtension(t, modified)})
+		w.(http.Flusher).Flush()
+		<-req.Context().Done()
+	}), registry), "watch"))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/apis/apiextensions.k8s.io/v1/customresourcedefinitions?watch=true&resourceVersion=10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	decoder := json.NewDecoder(resp.Body)
+	expectEvent := func(eventType watch.EventType, key, resourceVersion string) {
+		t.Helper()
+		var event watchEvent
+		if err := decoder.Decode(&event); err != nil {
+			t.Fatal(err)
+		}
+		var obj apiextensionsv1.CustomResourceDefinition
+		if err := json.Unmarshal(event.Object, &obj); err != nil {
+			t.Fatal(err)
+		}
+		if got := obj.ClusterName + "/" + obj.Name; event.Type != eventType || got != key || obj.ResourceVersion != resourceVersion {
+			t.Errorf("expected %s of %s at %s, got %s of %s at %s", eventType, key, resourceVersion, event.Type, got, obj.ResourceVersion)
+		}
+	}
+
+	expectEvent(watch.Added, "team/widgets.example.dev", "10")
+	close(next)
+	expectEvent(watch.Modified, "platform/widgets.example.dev", "11")
+	registry.Set("org#$#team", nil)
+	expectEvent(watch.Deleted, "team/widgets.example.dev", "11")
+}
+
+func rawExtension(t *testing.T, crd *apiextensionsv1.CustomResourceDefinition) runtime.RawExtension {
+	t.Helper()
+	data, err := json.Marshal(crd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return runtime.RawExtension{Raw: data}
+}
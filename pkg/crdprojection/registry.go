@@ -0,0 +1,131 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package crdprojection serves CustomResourceDefinitions of a logical cluster in other
+// logical clusters without copying them: the projected CRDs are merged into the wildcard
+// lists and watches of CRDs, which feed the informers of the apiextensions server and of
+// the controllers, so that they are resolved from their source whenever they are looked
+// up. Objects of the projected types are stored in the logical cluster they are created
+// in, like for the CRDs it defines itself.
+package crdprojection
+
+import (
+	"sort"
+	"sync"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/client-go/tools/clusters"
+)
+
+// ProjectedFromAnnotation is set on the projected CustomResourceDefinitions. Its value is
+// the logical cluster of the CRD they are projected from. Projected CRDs are not stored, so
+// controllers must not try to update them.
+const ProjectedFromAnnotation = "kcp.dev/projected-from"
+
+// IsProjected returns whether the CRD is projected from another logical cluster.
+func IsProjected(crd *apiextensionsv1.CustomResourceDefinition) bool {
+	_, projected := crd.Annotations[ProjectedFromAnnotation]
+	return projected
+}
+
+// Project returns the projection of the CRD into the given logical cluster, with the given
+// annotations on top of the ones of the CRD. The projection keeps the UID of its source, so
+// that the apiextensions server serves it with the same storage, which stores the objects
+// in the logical cluster of the request.
+func Project(crd *apiextensionsv1.CustomResourceDefinition, clusterName string, annotations map[string]string) *apiextensionsv1.CustomResourceDefinition {
+	projected := crd.DeepCopy()
+	projected.ClusterName = clusterName
+	projected.OwnerReferences = nil
+	projected.Finalizers = nil
+	projected.ManagedFields = nil
+	projected.Annotations = map[string]string{}
+	for k, v := range crd.Annotations {
+		projected.Annotations[k] = v
+	}
+	for k, v := range annotations {
+		projected.Annotations[k] = v
+	}
+	projected.Annotations[ProjectedFromAnnotation] = crd.ClusterName
+	return projected
+}
+
+// Registry holds the CRDs projected into logical clusters, by the object they are projected
+// for.
+type Registry struct {
+	lock        sync.RWMutex
+	projections map[string]map[string]*apiextensionsv1.CustomResourceDefinition
+	merged      map[string]*apiextensionsv1.CustomResourceDefinition
+	changed     chan struct{}
+}
+
+// NewRegistry returns a Registry without projections.
+func NewRegistry() *Registry {
+	return &Registry{
+		projections: map[string]map[string]*apiextensionsv1.CustomResourceDefinition{},
+		merged:      map[string]*apiextensionsv1.CustomResourceDefinition{},
+		changed:     make(chan struct{}),
+	}
+}
+
+// Set replaces the CRDs projected for the given owner, the key of the object they are
+// projected for. No CRDs removes the projections of the owner. When several owners project
+// a CRD of the same name into the same logical cluster, the first owner in lexical order
+// wins.
+func (r *Registry) Set(owner string, crds []*apiextensionsv1.CustomResourceDefinition) {
+	projections := map[string]*apiextensionsv1.CustomResourceDefinition{}
+	for _, crd := range crds {
+		projections[clusters.ToClusterAwareKey(crd.ClusterName, crd.Name)] = crd
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if equality.Semantic.DeepEqual(r.projections[owner], projections) {
+		return
+	}
+	if len(projections) == 0 {
+		delete(r.projections, owner)
+	} else {
+		r.projections[owner] = projections
+	}
+
+	owners := make([]string, 0, len(r.projections))
+	for owner := range r.projections {
+		owners = append(owners, owner)
+	}
+	// the projections of the first owners are merged last, overriding the others
+	sort.Sort(sort.Reverse(sort.StringSlice(owners)))
+	merged := map[string]*apiextensionsv1.CustomResourceDefinition{}
+	for _, owner := range owners {
+		for key, crd := range r.projections[owner] {
+			merged[key] = crd
+		}
+	}
+	if equality.Semantic.DeepEqual(r.merged, merged) {
+		return
+	}
+	r.merged = merged
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+// Projections returns the projected CRDs by cluster-aware key, which must not be modified,
+// and a channel closed on their next change.
+func (r *Registry) Projections() (map[string]*apiextensionsv1.CustomResourceDefinition, <-chan struct{}) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.merged, r.changed
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package crossworkspace resolves references from objects of one workspace to objects of
+// another workspace, which are only allowed when granted by a ReferenceGrant of the
+// referenced workspace.
+package crossworkspace
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clusters"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+	apisinformer "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/apis/v1alpha1"
+)
+
+const (
+	grantIndex = "grant"
+
+	// ReferencesAnnotation holds the JSON encoded list of ObjectReferences of an object to
+	// objects of other workspaces. They are validated on admission.
+	ReferencesAnnotation = "apis.kcp.dev/references"
+)
+
+// ErrNotInitialized is returned by a Resolver which was not initialized yet.
+var ErrNotInitialized = errors.New("cross-workspace references are not enabled")
+
+// ReferencesFrom returns the references of the ReferencesAnnotation of the given object.
+func ReferencesFrom(obj metav1.Object) ([]apisv1alpha1.ObjectReference, error) {
+	value, found := obj.GetAnnotations()[ReferencesAnnotation]
+	if !found {
+		return nil, nil
+	}
+	var refs []apisv1alpha1.ObjectReference
+	if err := json.Unmarshal([]byte(value), &refs); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", ReferencesAnnotation, err)
+	}
+	return refs, nil
+}
+
+// Resolver checks and resolves references across workspaces.
+type Resolver struct {
+	lock          sync.RWMutex
+	grantIndexer  cache.Indexer
+	hasSynced     cache.InformerSynced
+	dynamicClient dynamic.ClusterInterface
+}
+
+// NewResolver returns a Resolver which has to be initialized before use.
+func NewResolver() *Resolver {
+	return &Resolver{}
+}
+
+// Initialize makes the Resolver use the given cross-cluster ReferenceGrant informer and
+// the given client to retrieve referenced objects.
+func (r *Resolver) Initialize(grantInformer apisinformer.ReferenceGrantInformer, dynamicClient dynamic.ClusterInterface) error {
+	if err := grantInformer.Informer().AddIndexers(map[string]cache.IndexFunc{
+		grantIndex: func(obj interface{}) ([]string, error) {
+			grant, ok := obj.(*apisv1alpha1.ReferenceGrant)
+			if !ok {
+				return []string{}, nil
+			}
+			var keys []string
+			for _, workspace := range grant.Spec.Workspaces {
+				keys = append(keys, grantKey(grant.ClusterName, workspace))
+			}
+			return keys, nil
+		},
+	}); err != nil {
+		return fmt.Errorf("failed to add indexer for ReferenceGrant: %w", err)
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.grantIndexer = grantInformer.Informer().GetIndexer()
+	r.hasSynced = grantInformer.Informer().HasSynced
+	r.dynamicClient = dynamicClient
+	return nil
+}
+
+func grantKey(referencedWorkspace, referencingWorkspace string) string {
+	return clusters.ToClusterAwareKey(referencedWorkspace, referencingWorkspace)
+}
+
+// Allowed returns whether objects of the referencing workspace may reference the given
+// object. References within a workspace are always allowed.
+func (r *Resolver) Allowed(referencingWorkspace string, ref apisv1alpha1.ObjectReference) (bool, error) {
+	if ref.Workspace == "" || ref.Workspace == referencingWorkspace {
+		return true, nil
+	}
+
+	r.lock.RLock()
+	indexer, hasSynced := r.grantIndexer, r.hasSynced
+	r.lock.RUnlock()
+	if indexer == nil {
+		return false, ErrNotInitialized
+	}
+	if !hasSynced() {
+		return false, fmt.Errorf("reference grants are not synced yet")
+	}
+
+	grants, err := indexer.ByIndex(grantIndex, grantKey(ref.Workspace, referencingWorkspace))
+	if err != nil {
+		return false, err
+	}
+	for _, obj := range grants {
+		for _, granted := range obj.(*apisv1alpha1.ReferenceGrant).Spec.Resources {
+			if Grants(granted, ref) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// Grants returns whether the granted resource covers the referenced object.
+func Grants(granted apisv1alpha1.GrantedResource, ref apisv1alpha1.ObjectReference) bool {
+	if granted.GroupResource != ref.GroupResource {
+		return false
+	}
+	if granted.Namespace != "" && granted.Namespace != ref.Namespace {
+		return false
+	}
+	if len(granted.ResourceNames) == 0 {
+		return true
+	}
+	for _, name := range granted.ResourceNames {
+		if name == ref.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve retrieves the referenced object on behalf of an object of the referencing
+// workspace. It returns a Forbidden error if the reference is not granted.
+func (r *Resolver) Resolve(ctx context.Context, referencingWorkspace string, ref apisv1alpha1.ObjectReference) (*unstructured.Unstructured, error) {
+	gr := schema.GroupResource{Group: ref.Group, Resource: ref.Resource}
+	allowed, err := r.Allowed(referencingWorkspace, ref)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, apierrors.NewForbidden(gr, ref.Name, fmt.Errorf("workspace %q did not grant references from workspace %q", ref.Workspace, referencingWorkspace))
+	}
+
+	r.lock.RLock()
+	client := r.dynamicClient
+	r.lock.RUnlock()
+
+	workspace := ref.Workspace
+	if workspace == "" {
+		workspace = referencingWorkspace
+	}
+	return client.Cluster(workspace).Resource(gr.WithVersion(ref.Version)).Namespace(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crossworkspace
+
+import (
+	"testing"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+)
+
+func TestGrants(t *testing.T) {
+	secrets := apisv1alpha1.GroupResource{Resource: "secrets"}
+	ref := apisv1alpha1.ObjectReference{Workspace: "provider", GroupResource: secrets, Version: "v1", Namespace: "default", Name: "credentials"}
+
+	for _, tc := range []struct {
+		name    string
+		granted apisv1alpha1.GrantedResource
+		grants  bool
+	}{
+		{
+			name:    "all objects of the resource",
+			granted: apisv1alpha1.GrantedResource{GroupResource: secrets},
+			grants:  true,
+		},
+		{
+			name:    "other resource",
+			granted: apisv1alpha1.GrantedResource{GroupResource: apisv1alpha1.GroupResource{Resource: "configmaps"}},
+			grants:  false,
+		},
+		{
+			name:    "other namespace",
+			granted: apisv1alpha1.GrantedResource{GroupResource: secrets, Namespace: "kube-system"},
+			grants:  false,
+		},
+		{
+			name:    "named object",
+			granted: apisv1alpha1.GrantedResource{GroupResource: secrets, Namespace: "default", ResourceNames: []string{"other", "credentials"}},
+			grants:  true,
+		},
+		{
+			name:    "other names",
+			granted: apisv1alpha1.GrantedResource{GroupResource: secrets, ResourceNames: []string{"other"}},
+			grants:  false,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Grants(tc.granted, ref); got != tc.grants {
+				t.Errorf("expected grants=%v, got %v", tc.grants, got)
+			}
+		})
+	}
+}
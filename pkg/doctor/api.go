@@ -0,0 +1,174 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package doctor
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+)
+
+const (
+	categoryReadiness = "readiness"
+	categoryShards    = "shards"
+
+	// adminContext and crossClusterContext are contexts of the admin kubeconfig, pointing
+	// at the root logical cluster and at all the logical clusters.
+	adminContext        = "admin"
+	crossClusterContext = "cross-cluster"
+)
+
+// checkServer checks the readiness of the server and its controllers, and the connectivity
+// to the shards, with the admin kubeconfig.
+func checkServer(ctx context.Context, report *Report, o *Options) {
+	kubeconfig, err := clientcmd.LoadFromFile(filepath.Join(o.RootDirectory, o.KubeConfigPath))
+	if err != nil {
+		report.add(categoryReadiness, "server", StatusError, "cannot load the admin kubeconfig: %v", err)
+		return
+	}
+	config, err := restConfig(kubeconfig, adminContext, o)
+	if err != nil {
+		report.add(categoryReadiness, "server", StatusError, "%v", err)
+		return
+	}
+	if !checkReadiness(ctx, report, config) {
+		return
+	}
+	crossClusterConfig, err := restConfig(kubeconfig, crossClusterContext, o)
+	if err != nil {
+		report.add(categoryShards, "list", StatusError, "%v", err)
+		return
+	}
+	checkShards(ctx, report, crossClusterConfig, o)
+}
+
+func restConfig(kubeconfig *clientcmdapi.Config, context string, o *Options) (*rest.Config, error) {
+	config, err := clientcmd.NewNonInteractiveClientConfig(*kubeconfig, context, &clientcmd.ConfigOverrides{}, nil).ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	config.Timeout = o.Timeout
+	return config, nil
+}
+
+// checkReadiness reports the readiness checks of the server, whose post-start hooks start
+// the controllers. It returns whether the server could be reached.
+func checkReadiness(ctx context.Context, report *Report, config *rest.Config) bool {
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		report.add(categoryReadiness, "server", StatusError, "%v", err)
+		return false
+	}
+	// the body lists the checks even when some of them fail
+	body, err := client.Discovery().RESTClient().Get().AbsPath("/readyz").Param("verbose", "true").DoRaw(ctx)
+	results := parseReadyz(string(body))
+	if len(results) == 0 {
+		if err == nil {
+			report.add(categoryReadiness, "server", StatusOK, "ready")
+			return true
+		}
+		report.add(categoryReadiness, "server", StatusError, "unreachable at %s: %v", config.Host, err)
+		return false
+	}
+	report.Results = append(report.Results, results...)
+	return true
+}
+
+// parseReadyz returns the results of the checks listed by a verbose /readyz response, whose
+// lines look like "[+]poststarthook/install-workspace-controller ok".
+func parseReadyz(body string) []Result {
+	var results []Result
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) < 3 || line[0] != '[' || line[2] != ']' {
+			continue
+		}
+		check, message := line[3:], ""
+		if i := strings.Index(check, " "); i >= 0 {
+			check, message = check[:i], strings.TrimSpace(check[i+1:])
+		}
+		status := StatusOK
+		if line[1] != '+' {
+			status = StatusError
+		}
+		results = append(results, Result{Category: categoryReadiness, Check: check, Status: status, Message: message})
+	}
+	return results
+}
+
+// checkShards checks that the base URL of each WorkspaceShard answers. Any HTTP response
+// counts: the check is about connectivity, not about the credentials of the shards.
+func checkShards(ctx context.Context, report *Report, config *rest.Config, o *Options) {
+	client, err := kcpclient.NewForConfig(config)
+	if err != nil {
+		report.add(categoryShards, "list", StatusError, "%v", err)
+		return
+	}
+	shards, err := client.TenancyV1alpha1().WorkspaceShards().List(ctx, metav1.ListOptions{})
+	if apierrors.IsNotFound(err) {
+		report.add(categoryShards, "list", StatusOK, "no WorkspaceShards are served")
+		return
+	} else if err != nil {
+		report.add(categoryShards, "list", StatusError, "%v", err)
+		return
+	}
+	if len(shards.Items) == 0 {
+		report.add(categoryShards, "list", StatusOK, "no WorkspaceShards")
+		return
+	}
+
+	httpClient := &http.Client{
+		Timeout: o.Timeout,
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			// nolint:gosec
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+	for _, shard := range shards.Items {
+		check := shard.ClusterName + "/" + shard.Name
+		if shard.Spec.BaseURL == "" {
+			report.add(categoryShards, check, StatusWarning, "no base URL, the workspaces scheduled to it have no URL")
+			continue
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(shard.Spec.BaseURL, "/")+"/readyz", nil)
+		if err != nil {
+			report.add(categoryShards, check, StatusError, "invalid base URL %q: %v", shard.Spec.BaseURL, err)
+			continue
+		}
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			report.add(categoryShards, check, StatusError, "unreachable at %s: %v", shard.Spec.BaseURL, err)
+			continue
+		}
+		resp.Body.Close()
+		report.add(categoryShards, check, StatusOK, "reachable at %s (%s)", shard.Spec.BaseURL, resp.Status)
+	}
+}
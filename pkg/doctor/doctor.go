@@ -0,0 +1,118 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package doctor diagnoses a kcp instance from its state directory and its API: the layout
+// of the directory, the health and quota usage of etcd, the expiration of certificates, the
+// connectivity to shards and the readiness of controllers. Each check adds results to a
+// report, rather than failing on the first problem, so that operators get the whole picture.
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Status is the outcome of a check.
+type Status string
+
+const (
+	StatusOK      Status = "OK"
+	StatusWarning Status = "Warning"
+	StatusError   Status = "Error"
+)
+
+// Result is the outcome of a check of a single item, e.g. a file or a certificate.
+type Result struct {
+	// Category groups the results of related checks: layout, certificates, etcd,
+	// readiness or shards.
+	Category string `json:"category"`
+	// Check names the checked item within its category.
+	Check   string `json:"check"`
+	Status  Status `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// Report is the outcome of all the checks.
+type Report struct {
+	Results []Result `json:"results"`
+}
+
+func (r *Report) add(category, check string, status Status, format string, args ...interface{}) {
+	r.Results = append(r.Results, Result{Category: category, Check: check, Status: status, Message: fmt.Sprintf(format, args...)})
+}
+
+// Count returns the number of results with the given status.
+func (r *Report) Count(status Status) int {
+	n := 0
+	for _, result := range r.Results {
+		if result.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// Options locate the state and the API of the kcp instance, and bound the checks.
+type Options struct {
+	// RootDirectory is the root directory of the kcp server.
+	RootDirectory string
+	// EtcdDirectory is the directory of the embedded etcd, relative to the root directory.
+	EtcdDirectory string
+	// KubeConfigPath is the admin kubeconfig, relative to the root directory.
+	KubeConfigPath string
+
+	// EtcdEndpoints are the endpoints of an external etcd. The embedded etcd is checked when
+	// empty, with the credentials in its directory.
+	EtcdEndpoints []string
+	EtcdCertFile  string
+	EtcdKeyFile   string
+	EtcdCAFile    string
+	// EtcdClientPort is the client port of the embedded etcd.
+	EtcdClientPort string
+	// EtcdQuotaBytes is the backend quota of etcd; usage over 80% of it is reported.
+	EtcdQuotaBytes int64
+
+	// CertificateWarningPeriod is how long before their expiration certificates are
+	// reported.
+	CertificateWarningPeriod time.Duration
+	// Timeout bounds each check reaching a server.
+	Timeout time.Duration
+}
+
+// DefaultOptions returns the options matching the defaults of the kcp server.
+func DefaultOptions() *Options {
+	return &Options{
+		RootDirectory:  ".kcp",
+		EtcdDirectory:  "",
+		KubeConfigPath: "admin.kubeconfig",
+		EtcdClientPort: "2379",
+		// the default backend quota of etcd
+		EtcdQuotaBytes:           2 * 1024 * 1024 * 1024,
+		CertificateWarningPeriod: 7 * 24 * time.Hour,
+		Timeout:                  5 * time.Second,
+	}
+}
+
+// Run runs all the checks and returns their report.
+func Run(ctx context.Context, o *Options) *Report {
+	report := &Report{}
+	checkLayout(report, o)
+	checkCertificates(report, o, time.Now())
+	checkEtcd(ctx, report, o)
+	checkServer(ctx, report, o)
+	return report
+}
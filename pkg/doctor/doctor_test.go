@@ -0,0 +1,111 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package doctor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	certutil "k8s.io/client-go/util/cert"
+)
+
+func statuses(report *Report) map[string]Status {
+	statuses := map[string]Status{}
+	for _, result := range report.Results {
+		statuses[result.Category+" "+result.Check] = result.Status
+	}
+	return statuses
+}
+
+func TestFiles(t *testing.T) {
+	dir := t.TempDir()
+	cert, _, err := certutil.GenerateSelfSignedCertKey("kcp-client-ca", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, mode := range map[string]os.FileMode{
+		"admin.kubeconfig":     0600,
+		"client-ca.crt":        0644,
+		"client-ca.key":        0644,
+		"client-ca-bundle.crt": 0644,
+	} {
+		data := cert
+		if name == "admin.kubeconfig" {
+			data = []byte("apiVersion: v1\nkind: Config\n")
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name), data, mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	o := DefaultOptions()
+	o.RootDirectory = dir
+	// an external etcd leaves the etcd files out
+	o.EtcdEndpoints = []string{"https://etcd:2379"}
+	report := &Report{}
+	checkLayout(report, o)
+	checkCertificates(report, o, time.Now())
+
+	expected := map[string]Status{
+		"layout " + dir:                     StatusOK,
+		"layout admin.kubeconfig":           StatusOK,
+		"layout client-ca.crt":              StatusOK,
+		"layout client-ca.key":              StatusWarning,
+		"layout client-ca-bundle.crt":       StatusOK,
+		"layout service-account.key":        StatusWarning,
+		"layout service-account.pub":        StatusWarning,
+		"layout apiserver.crt":              StatusWarning,
+		"layout apiserver.key":              StatusWarning,
+		"certificates client-ca.crt":        StatusOK,
+		"certificates client-ca-bundle.crt": StatusOK,
+	}
+	if diff := cmp.Diff(expected, statuses(report)); diff != "" {
+		t.Errorf("unexpected results: %s", diff)
+	}
+
+	// the certificate expires in a year
+	report = &Report{}
+	checkCertificates(report, o, time.Now().Add(364*24*time.Hour))
+	expected = map[string]Status{
+		"certificates client-ca.crt":        StatusWarning,
+		"certificates client-ca-bundle.crt": StatusWarning,
+	}
+	if diff := cmp.Diff(expected, statuses(report)); diff != "" {
+		t.Errorf("unexpected results about to expire: %s", diff)
+	}
+}
+
+func TestParseReadyz(t *testing.T) {
+	results := parseReadyz(`[+]ping ok
+[+]etcd ok
+[-]poststarthook/install-workspace-controller failed: reason withheld
+readyz check failed
+`)
+	expected := []Result{
+		{Category: categoryReadiness, Check: "ping", Status: StatusOK, Message: "ok"},
+		{Category: categoryReadiness, Check: "etcd", Status: StatusOK, Message: "ok"},
+		{Category: categoryReadiness, Check: "poststarthook/install-workspace-controller", Status: StatusError, Message: "failed: reason withheld"},
+	}
+	if diff := cmp.Diff(expected, results); diff != "" {
+		t.Errorf("unexpected results: %s", diff)
+	}
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package doctor
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const categoryEtcd = "etcd"
+
+// etcdConfig returns the client config of the checked etcd: the external one if endpoints
+// are given, the embedded one otherwise.
+func etcdConfig(o *Options) (clientv3.Config, error) {
+	endpoints, certFile, keyFile, caFile := o.EtcdEndpoints, o.EtcdCertFile, o.EtcdKeyFile, o.EtcdCAFile
+	if len(endpoints) == 0 {
+		// the server authenticates with the peer certificate against the embedded etcd
+		secrets := filepath.Join(o.RootDirectory, o.EtcdDirectory, "secrets")
+		endpoints = []string{"https://localhost:" + o.EtcdClientPort}
+		certFile = filepath.Join(secrets, "peer", "cert.pem")
+		keyFile = filepath.Join(secrets, "peer", "key.pem")
+		caFile = filepath.Join(secrets, "ca", "cert.pem")
+	}
+
+	// like the server, skip the verification of etcd unless given its CA
+	tlsConfig := &tls.Config{InsecureSkipVerify: true}
+	if certFile != "" && keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return clientv3.Config{}, fmt.Errorf("failed to load x509 keypair: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	if caFile != "" {
+		caCert, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return clientv3.Config{}, fmt.Errorf("failed to read ca file: %w", err)
+		}
+		caPool := x509.NewCertPool()
+		caPool.AppendCertsFromPEM(caCert)
+		tlsConfig.RootCAs = caPool
+		tlsConfig.InsecureSkipVerify = false
+	}
+	return clientv3.Config{
+		Endpoints:   endpoints,
+		TLS:         tlsConfig,
+		DialTimeout: o.Timeout,
+	}, nil
+}
+
+// checkEtcd checks the status, the alarms and the database size of each etcd endpoint.
+func checkEtcd(ctx context.Context, report *Report, o *Options) {
+	config, err := etcdConfig(o)
+	if err != nil {
+		report.add(categoryEtcd, "client", StatusError, "%v", err)
+		return
+	}
+	client, err := clientv3.New(config)
+	if err != nil {
+		report.add(categoryEtcd, "client", StatusError, "%v", err)
+		return
+	}
+	defer client.Close()
+
+	for _, endpoint := range config.Endpoints {
+		statusCtx, cancel := context.WithTimeout(ctx, o.Timeout)
+		status, err := client.Status(statusCtx, endpoint)
+		cancel()
+		if err != nil {
+			report.add(categoryEtcd, endpoint, StatusError, "unreachable: %v", err)
+			continue
+		}
+		if len(status.Errors) > 0 {
+			report.add(categoryEtcd, endpoint, StatusError, "unhealthy: %s", strings.Join(status.Errors, ", "))
+		} else {
+			report.add(categoryEtcd, endpoint, StatusOK, "version %s, leader %x, raft index %d", status.Version, status.Leader, status.RaftIndex)
+		}
+
+		check := endpoint + " quota"
+		usage := float64(status.DbSize) / float64(o.EtcdQuotaBytes)
+		message := fmt.Sprintf("database of %s, %.0f%% of the quota of %s (%s in use)", byteSize(status.DbSize), usage*100, byteSize(o.EtcdQuotaBytes), byteSize(status.DbSizeInUse))
+		switch {
+		case usage >= 0.95:
+			report.add(categoryEtcd, check, StatusError, "%s, defragment or compact it", message)
+		case usage >= 0.8:
+			report.add(categoryEtcd, check, StatusWarning, "%s, defragment or compact it", message)
+		default:
+			report.add(categoryEtcd, check, StatusOK, "%s", message)
+		}
+	}
+
+	alarmCtx, cancel := context.WithTimeout(ctx, o.Timeout)
+	defer cancel()
+	alarms, err := client.AlarmList(alarmCtx)
+	if err != nil {
+		report.add(categoryEtcd, "alarms", StatusError, "%v", err)
+		return
+	}
+	if len(alarms.Alarms) == 0 {
+		report.add(categoryEtcd, "alarms", StatusOK, "none")
+		return
+	}
+	for _, alarm := range alarms.Alarms {
+		report.add(categoryEtcd, "alarms", StatusError, "%s alarm raised by member %x", alarm.Alarm, alarm.MemberID)
+	}
+}
+
+func byteSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%dB", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package doctor
+
+import (
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/tools/clientcmd"
+	certutil "k8s.io/client-go/util/cert"
+)
+
+const (
+	categoryLayout       = "layout"
+	categoryCertificates = "certificates"
+)
+
+// stateFile is a file the kcp server writes to its root directory.
+type stateFile struct {
+	// path is relative to the root directory.
+	path string
+	// required files are written by every server, the others depend on its configuration.
+	required bool
+	// secret files must only be readable by their owner.
+	secret bool
+	// certificate files hold PEM certificates whose expiration is checked.
+	certificate bool
+}
+
+// stateFiles returns the files of the root directory, as written by the kcp server.
+func stateFiles(o *Options) []stateFile {
+	files := []stateFile{
+		{path: o.KubeConfigPath, required: true, secret: true},
+		{path: "client-ca.crt", required: true, certificate: true},
+		{path: "client-ca.key", required: true, secret: true},
+		{path: "client-ca-bundle.crt", required: true, certificate: true},
+		{path: "service-account.key", secret: true},
+		{path: "service-account.pub"},
+		// the self-signed serving certificate, unless one is given to the server
+		{path: filepath.Join(o.EtcdDirectory, "apiserver.crt"), certificate: true},
+		{path: filepath.Join(o.EtcdDirectory, "apiserver.key"), secret: true},
+	}
+	if len(o.EtcdEndpoints) == 0 {
+		secrets := filepath.Join(o.EtcdDirectory, "secrets")
+		files = append(files,
+			stateFile{path: filepath.Join(o.EtcdDirectory, "member"), required: true},
+			stateFile{path: filepath.Join(secrets, "ca", "cert.pem"), required: true, certificate: true},
+			stateFile{path: filepath.Join(secrets, "ca", "key.pem"), required: true, secret: true},
+			stateFile{path: filepath.Join(secrets, "peer", "cert.pem"), required: true, certificate: true},
+			stateFile{path: filepath.Join(secrets, "peer", "key.pem"), required: true, secret: true},
+			stateFile{path: filepath.Join(secrets, "client", "cert.pem"), required: true, certificate: true},
+			stateFile{path: filepath.Join(secrets, "client", "key.pem"), required: true, secret: true},
+		)
+	}
+	return files
+}
+
+// checkLayout checks that the root directory holds the files of a server which has started,
+// and that its secrets are not readable by other users.
+func checkLayout(report *Report, o *Options) {
+	fi, err := os.Stat(o.RootDirectory)
+	if err != nil {
+		report.add(categoryLayout, o.RootDirectory, StatusError, "%v", err)
+		return
+	}
+	if !fi.IsDir() {
+		report.add(categoryLayout, o.RootDirectory, StatusError, "not a directory")
+		return
+	}
+	report.add(categoryLayout, o.RootDirectory, StatusOK, "root directory")
+
+	for _, file := range stateFiles(o) {
+		fi, err := os.Stat(filepath.Join(o.RootDirectory, file.path))
+		switch {
+		case os.IsNotExist(err) && file.required:
+			report.add(categoryLayout, file.path, StatusError, "missing, the server has not started in this root directory")
+		case os.IsNotExist(err):
+			report.add(categoryLayout, file.path, StatusWarning, "missing, which is expected only if the server is configured not to write it")
+		case err != nil:
+			report.add(categoryLayout, file.path, StatusError, "%v", err)
+		case file.secret && fi.Mode().Perm()&0077 != 0:
+			report.add(categoryLayout, file.path, StatusWarning, "readable by other users than its owner (mode %v)", fi.Mode().Perm())
+		default:
+			report.add(categoryLayout, file.path, StatusOK, "")
+		}
+	}
+}
+
+// checkCertificates checks the expiration of the certificates of the root directory, and of
+// the client certificates of the admin kubeconfig.
+func checkCertificates(report *Report, o *Options, now time.Time) {
+	for _, file := range stateFiles(o) {
+		if !file.certificate {
+			continue
+		}
+		certs, err := certutil.CertsFromFile(filepath.Join(o.RootDirectory, file.path))
+		if os.IsNotExist(err) {
+			// reported by the layout check
+			continue
+		} else if err != nil {
+			report.add(categoryCertificates, file.path, StatusError, "%v", err)
+			continue
+		}
+		checkExpiration(report, file.path, certs, o.CertificateWarningPeriod, now)
+	}
+
+	kubeconfig, err := clientcmd.LoadFromFile(filepath.Join(o.RootDirectory, o.KubeConfigPath))
+	if err != nil {
+		// reported by the layout check
+		return
+	}
+	for name, authInfo := range kubeconfig.AuthInfos {
+		if len(authInfo.ClientCertificateData) == 0 {
+			continue
+		}
+		check := fmt.Sprintf("%s (user %s)", o.KubeConfigPath, name)
+		certs, err := certutil.ParseCertsPEM(authInfo.ClientCertificateData)
+		if err != nil {
+			report.add(categoryCertificates, check, StatusError, "%v", err)
+			continue
+		}
+		checkExpiration(report, check, certs, o.CertificateWarningPeriod, now)
+	}
+}
+
+func checkExpiration(report *Report, check string, certs []*x509.Certificate, warningPeriod time.Duration, now time.Time) {
+	// the first certificate to expire determines when the file stops working
+	first := certs[0]
+	for _, cert := range certs[1:] {
+		if cert.NotAfter.Before(first.NotAfter) {
+			first = cert
+		}
+	}
+	subject := first.Subject.CommonName
+	if subject == "" {
+		subject = strings.Join(first.Subject.Organization, ",")
+	}
+	remaining := first.NotAfter.Sub(now)
+	switch {
+	case remaining <= 0:
+		report.add(categoryCertificates, check, StatusError, "certificate %q expired on %s", subject, first.NotAfter.UTC().Format(time.RFC3339))
+	case remaining < warningPeriod:
+		report.add(categoryCertificates, check, StatusWarning, "certificate %q expires in %s, on %s", subject, remaining.Round(time.Minute), first.NotAfter.UTC().Format(time.RFC3339))
+	default:
+		report.add(categoryCertificates, check, StatusOK, "certificate %q expires on %s", subject, first.NotAfter.UTC().Format(time.RFC3339))
+	}
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package events records Kubernetes Events in the logical cluster of the object they are
+// about, for controllers watching objects across logical clusters.
+package events
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+)
+
+// ClusterAnnotation is set on every recorded Event to the logical cluster it is recorded in.
+const ClusterAnnotation = "kcp.dev/cluster"
+
+// NewRecorder returns an EventRecorder for the given component, recording each Event in
+// the logical cluster of its object. Cluster-scoped objects get their Events in the
+// default namespace. The recorder stops when ctx is done.
+func NewRecorder(ctx context.Context, kubeClient kubernetes.ClusterInterface, scheme *runtime.Scheme, component string) record.EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartStructuredLogging(4)
+	broadcaster.StartRecordingToSink(&sink{ctx: ctx, client: kubeClient})
+	go func() {
+		<-ctx.Done()
+		broadcaster.Shutdown()
+	}()
+	return &recorder{
+		delegate: broadcaster.NewRecorder(scheme, corev1.EventSource{Component: component}),
+	}
+}
+
+type recorder struct {
+	delegate record.EventRecorder
+}
+
+func (r *recorder) Event(object runtime.Object, eventtype, reason, message string) {
+	r.AnnotatedEventf(object, nil, eventtype, reason, "%s", message)
+}
+
+func (r *recorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.AnnotatedEventf(object, nil, eventtype, reason, messageFmt, args...)
+}
+
+func (r *recorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	obj, err := meta.Accessor(object)
+	if err != nil {
+		klog.Errorf("could not record event %q about %T: %v", reason, object, err)
+		return
+	}
+	withCluster := map[string]string{ClusterAnnotation: obj.GetClusterName()}
+	for k, v := range annotations {
+		withCluster[k] = v
+	}
+	r.delegate.AnnotatedEventf(object, withCluster, eventtype, reason, messageFmt, args...)
+}
+
+// sink writes Events to the logical cluster named by their ClusterAnnotation.
+type sink struct {
+	ctx    context.Context
+	client kubernetes.ClusterInterface
+}
+
+func (s *sink) eventsFor(event *corev1.Event) (typedcorev1.EventInterface, error) {
+	clusterName := event.Annotations[ClusterAnnotation]
+	if clusterName == "" {
+		return nil, fmt.Errorf("event %s/%s has no %s annotation", event.Namespace, event.Name, ClusterAnnotation)
+	}
+	return s.client.Cluster(clusterName).CoreV1().Events(event.Namespace), nil
+}
+
+func (s *sink) Create(event *corev1.Event) (*corev1.Event, error) {
+	events, err := s.eventsFor(event)
+	if err != nil {
+		return nil, err
+	}
+	return events.Create(s.ctx, event, metav1.CreateOptions{})
+}
+
+func (s *sink) Update(event *corev1.Event) (*corev1.Event, error) {
+	events, err := s.eventsFor(event)
+	if err != nil {
+		return nil, err
+	}
+	return events.Update(s.ctx, event, metav1.UpdateOptions{})
+}
+
+func (s *sink) Patch(oldEvent *corev1.Event, data []byte) (*corev1.Event, error) {
+	events, err := s.eventsFor(oldEvent)
+	if err != nil {
+		return nil, err
+	}
+	return events.Patch(s.ctx, oldEvent.Name, types.StrategicMergePatchType, data, metav1.PatchOptions{})
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// kubeClients keeps a fake per logical cluster.
+type kubeClients struct {
+	lock    sync.Mutex
+	clients map[string]*kubefake.Clientset
+}
+
+func (c *kubeClients) Cluster(clusterName string) kubernetes.Interface {
+	return c.client(clusterName)
+}
+
+func (c *kubeClients) client(clusterName string) *kubefake.Clientset {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, ok := c.clients[clusterName]; !ok {
+		c.clients[clusterName] = kubefake.NewSimpleClientset()
+	}
+	return c.clients[clusterName]
+}
+
+func TestRecorderRecordsInLogicalCluster(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	kubeClient := &kubeClients{clients: map[string]*kubefake.Clientset{}}
+	recorder := NewRecorder(ctx, kubeClient, scheme.Scheme, "test-controller")
+
+	recorder.Event(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "default", ClusterName: "org_team"},
+	}, corev1.EventTypeNormal, "Created", "ConfigMap created.")
+
+	var events *corev1.EventList
+	if err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		var err error
+		events, err = kubeClient.client("org_team").CoreV1().Events("default").List(ctx, metav1.ListOptions{})
+		return err == nil && len(events.Items) > 0, err
+	}); err != nil {
+		t.Fatalf("expected an event in the logical cluster of the object: %v", err)
+	}
+	if event := events.Items[0]; event.Reason != "Created" || event.InvolvedObject.Name != "settings" || event.Annotations[ClusterAnnotation] != "org_team" {
+		t.Errorf("unexpected event %+v", event)
+	}
+	kubeClient.lock.Lock()
+	defer kubeClient.lock.Unlock()
+	for clusterName, client := range kubeClient.clients {
+		if clusterName != "org_team" && len(client.Actions()) > 0 {
+			t.Errorf("expected no event in logical cluster %q, got %v", clusterName, client.Actions())
+		}
+	}
+}
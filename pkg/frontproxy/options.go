@@ -0,0 +1,159 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frontproxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	genericapifilters "k8s.io/apiserver/pkg/endpoints/filters"
+	"k8s.io/apiserver/pkg/endpoints/handlers/responsewriters"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	genericapiserver "k8s.io/apiserver/pkg/server"
+	genericfilters "k8s.io/apiserver/pkg/server/filters"
+	genericapiserveroptions "k8s.io/apiserver/pkg/server/options"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/genericcontrolplane"
+
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	kcpexternalversions "github.com/kcp-dev/kcp/pkg/client/informers/externalversions"
+	"github.com/kcp-dev/kcp/pkg/sharding"
+)
+
+const resyncPeriod = 10 * time.Hour
+
+// DefaultOptions are the default options of the front proxy.
+func DefaultOptions() *Options {
+	o := &Options{
+		SecureServing:  genericapiserveroptions.NewSecureServingOptions(),
+		Authentication: genericapiserveroptions.NewDelegatingAuthenticationOptions(),
+	}
+	o.SecureServing.BindPort = 6443
+	o.SecureServing.ServerCert.CertDirectory = ".kcp-front-proxy"
+	o.SecureServing.ServerCert.PairName = "kcp-front-proxy"
+	// the shards are not Kubernetes clusters holding the authentication configuration
+	o.Authentication.SkipInClusterLookup = true
+	return o
+}
+
+// BindOptions binds the front proxy options to the flag set.
+func BindOptions(o *Options, fs *pflag.FlagSet) *Options {
+	o.SecureServing.AddFlags(fs)
+	o.Authentication.AddFlags(fs)
+	fs.StringVar(&o.ShardsKubeconfig, "shards-kubeconfig", o.ShardsKubeconfig, "Path to a kubeconfig with one context per shard, named after its WorkspaceShard, whose credentials are allowed to impersonate users. Its current context authenticates the users when --authentication-kubeconfig is not set.")
+	fs.StringVar(&o.RootShard, "root-shard", o.RootShard, "Context of the --shards-kubeconfig of the root shard, which holds the Workspaces and serves the requests to other logical clusters. Defaults to the current context.")
+	fs.StringVar(&o.ExternalHostname, "external-hostname", o.ExternalHostname, "The stable hostname the front proxy is reached with, for which a self-signed certificate is generated when no --tls-cert-file is given.")
+	return o
+}
+
+// Options are the options of the front proxy.
+type Options struct {
+	SecureServing  *genericapiserveroptions.SecureServingOptions
+	Authentication *genericapiserveroptions.DelegatingAuthenticationOptions
+
+	ShardsKubeconfig string
+	RootShard        string
+	ExternalHostname string
+}
+
+func (o *Options) Validate() error {
+	var errs []error
+	if o.ShardsKubeconfig == "" {
+		errs = append(errs, errors.New("--shards-kubeconfig is required"))
+	}
+	if o.ExternalHostname == "" && o.SecureServing.ServerCert.CertKey.CertFile == "" {
+		errs = append(errs, errors.New("--external-hostname is required without --tls-cert-file"))
+	}
+	errs = append(errs, o.SecureServing.Validate()...)
+	errs = append(errs, o.Authentication.Validate()...)
+	return utilerrors.NewAggregate(errs)
+}
+
+// Run serves the front proxy until the context is done.
+func (o *Options) Run(ctx context.Context) error {
+	if o.Authentication.RemoteKubeConfigFile == "" {
+		o.Authentication.RemoteKubeConfigFile = o.ShardsKubeconfig
+	}
+	if err := o.SecureServing.MaybeDefaultWithSelfSignedCerts(o.ExternalHostname, nil, nil); err != nil {
+		return fmt.Errorf("failed to generate a self-signed certificate: %w", err)
+	}
+	var servingInfo *genericapiserver.SecureServingInfo
+	if err := o.SecureServing.ApplyTo(&servingInfo); err != nil {
+		return err
+	}
+	authenticationInfo := genericapiserver.AuthenticationInfo{}
+	if err := o.Authentication.ApplyTo(&authenticationInfo, servingInfo, nil); err != nil {
+		return err
+	}
+
+	rootShard := o.RootShard
+	if rootShard == "" {
+		kubeconfig, err := (&clientcmd.ClientConfigLoadingRules{ExplicitPath: o.ShardsKubeconfig}).Load()
+		if err != nil {
+			return err
+		}
+		rootShard = kubeconfig.CurrentContext
+	}
+	loader, err := sharding.New(o.ShardsKubeconfig, nil)
+	if err != nil {
+		return err
+	}
+	rootConfig, found := loader.Clients()[genericcontrolplane.SanitizeClusterId(rootShard)]
+	if !found {
+		return fmt.Errorf("root shard %q not found in %s", rootShard, o.ShardsKubeconfig)
+	}
+	kcpClient, err := kcpclient.NewClusterForConfig(rootConfig)
+	if err != nil {
+		return err
+	}
+	kcpSharedInformerFactory := kcpexternalversions.NewSharedInformerFactoryWithOptions(kcpClient.Cluster("*"), resyncPeriod)
+	workspaceInformer := kcpSharedInformerFactory.Tenancy().V1alpha1().Workspaces().Informer()
+	proxy, err := NewProxy(loader, rootShard, workspaceInformer.GetIndexer())
+	if err != nil {
+		return err
+	}
+	kcpSharedInformerFactory.Start(ctx.Done())
+	if !cache.WaitForNamedCacheSync("kcp-front-proxy", ctx.Done(), workspaceInformer.HasSynced) {
+		return errors.New("failed to wait for the Workspaces of the root shard")
+	}
+
+	var handler http.Handler = proxy
+	handler = genericapifilters.WithAuthentication(handler, authenticationInfo.Authenticator, http.HandlerFunc(unauthorized), authenticationInfo.APIAudiences)
+	handler = genericfilters.WithPanicRecovery(handler, &genericapirequest.RequestInfoFactory{})
+	stopped, err := servingInfo.Serve(handler, 30*time.Second, ctx.Done())
+	if err != nil {
+		return err
+	}
+	klog.Infof("Serving the workspaces of %d shards", len(loader.Clients()))
+	<-stopped
+	return nil
+}
+
+func unauthorized(w http.ResponseWriter, req *http.Request) {
+	responsewriters.ErrorNegotiated(apierrors.NewUnauthorized("Unauthorized"), scheme.Codecs, schema.GroupVersion{}, w, req)
+}
@@ -0,0 +1,208 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package frontproxy implements kcp-front-proxy, which serves all the workspaces of the
+// shards of kcp under one stable URL: it authenticates the users and forwards their
+// requests to the shard holding the workspace of their /clusters/<name> path,
+// impersonating them.
+package frontproxy
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"path"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/endpoints/handlers/responsewriters"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/genericcontrolplane"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/homeworkspace"
+	"github.com/kcp-dev/kcp/pkg/indexers"
+	"github.com/kcp-dev/kcp/pkg/sharding"
+	"github.com/kcp-dev/kcp/pkg/statuserrors"
+)
+
+// Proxy forwards the requests to the shards of kcp.
+type Proxy struct {
+	loader    *sharding.ClientLoader
+	rootShard string
+
+	workspaceIndexer cache.Indexer
+}
+
+// NewProxy returns a Proxy forwarding requests to the shards of the given loader, finding
+// the shards of workspaces with the given indexer of the Workspaces of the root shard. The
+// requests to other logical clusters, across logical clusters, and outside of any logical
+// cluster go to the root shard, which fans them out as needed.
+func NewProxy(loader *sharding.ClientLoader, rootShard string, workspaceIndexer cache.Indexer) (*Proxy, error) {
+	if err := indexers.AddIfNotPresent(workspaceIndexer, cache.Indexers{
+		indexers.WorkspaceName:         indexers.IndexWorkspaceByName,
+		indexers.WorkspacePreviousName: indexers.IndexWorkspaceByPreviousName,
+		indexers.WorkspaceHomeOf:       indexers.IndexWorkspaceByHomeOf,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to add indexer for Workspace: %w", err)
+	}
+	return &Proxy{
+		loader:           loader,
+		rootShard:        genericcontrolplane.SanitizeClusterId(rootShard),
+		workspaceIndexer: workspaceIndexer,
+	}, nil
+}
+
+// ServeHTTP forwards the authenticated request to its shard.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	fail := func(err error) {
+		responsewriters.ErrorNegotiated(err, scheme.Codecs, schema.GroupVersion{}, w, req)
+	}
+	u, ok := genericapirequest.UserFrom(req.Context())
+	if !ok {
+		fail(apierrors.NewInternalError(errors.New("no user")))
+		return
+	}
+	for header := range req.Header {
+		if strings.HasPrefix(header, "Impersonate-") {
+			// the shards only see the user of the proxy impersonating the requesting user
+			fail(apierrors.NewBadRequest("impersonation is not supported through the front proxy"))
+			return
+		}
+	}
+	name := clusterName(req)
+	if name == homeworkspace.ClusterName {
+		home, err := p.home(u.GetName())
+		if err != nil {
+			fail(err)
+			return
+		}
+		name = home
+	}
+	shard, err := p.shardFor(name)
+	if err != nil {
+		fail(err)
+		return
+	}
+	config, found := p.loader.Clients()[shard]
+	if !found {
+		fail(statuserrors.NewShardUnavailable(shard, errors.New("no credentials for the shard")))
+		return
+	}
+	p.forward(w, req, shard, sharding.Impersonating(config, u), fail)
+}
+
+// clusterName returns the name the request addresses its logical cluster with, or an empty
+// string if it is not addressed to a logical cluster.
+func clusterName(req *http.Request) string {
+	if strings.HasPrefix(req.URL.Path, "/clusters/") {
+		name := strings.TrimPrefix(req.URL.Path, "/clusters/")
+		if i := strings.Index(name, "/"); i != -1 {
+			name = name[:i]
+		}
+		return name
+	}
+	return req.Header.Get("X-Kubernetes-Cluster")
+}
+
+// home returns the name of the home workspace of the given user, or an empty string when it
+// doesn't exist yet: the root shard creates it.
+func (p *Proxy) home(userName string) (string, error) {
+	workspaces, err := p.workspaceIndexer.ByIndex(indexers.WorkspaceHomeOf, userName)
+	if err != nil {
+		return "", apierrors.NewInternalError(err)
+	}
+	for _, obj := range workspaces {
+		workspace := obj.(*tenancyv1alpha1.Workspace)
+		if _, renamed := workspace.Annotations[tenancyv1alpha1.WorkspaceRenamedToAnnotation]; !renamed {
+			return workspace.Name, nil
+		}
+	}
+	return "", nil
+}
+
+// shardFor returns the shard serving the logical cluster addressed with the given name:
+// the shard of the workspace of that name, the one prefixing the names of the logical
+// clusters of shards, or the root shard.
+func (p *Proxy) shardFor(name string) (string, error) {
+	if name == "" || name == "*" {
+		return p.rootShard, nil
+	}
+	if identifier, _, err := genericcontrolplane.ParseClusterName(name); err == nil && identifier != "" {
+		return identifier, nil
+	}
+	workspaces, err := p.workspaceIndexer.ByIndex(indexers.WorkspaceName, name)
+	if err != nil {
+		return "", apierrors.NewInternalError(err)
+	}
+	if len(workspaces) == 0 {
+		// the shard of a renamed workspace redirects its previous name
+		if workspaces, err = p.workspaceIndexer.ByIndex(indexers.WorkspacePreviousName, name); err != nil {
+			return "", apierrors.NewInternalError(err)
+		}
+	}
+	if len(workspaces) == 0 {
+		return p.rootShard, nil
+	}
+	workspace := workspaces[0].(*tenancyv1alpha1.Workspace)
+	if workspace.Status.Location.Current == "" {
+		return "", statuserrors.NewWorkspaceNotReady(workspace.Name, "the workspace is not scheduled to a shard yet")
+	}
+	return genericcontrolplane.SanitizeClusterId(workspace.Status.Location.Current), nil
+}
+
+// forward proxies the request to the shard with the given config, streaming watches and
+// upgraded connections.
+func (p *Proxy) forward(w http.ResponseWriter, req *http.Request, shard string, config *rest.Config, fail func(error)) {
+	target, err := url.Parse(config.Host)
+	if err != nil {
+		fail(statuserrors.NewShardUnavailable(shard, err))
+		return
+	}
+	transport, err := rest.TransportFor(config)
+	if err != nil {
+		fail(statuserrors.NewShardUnavailable(shard, err))
+		return
+	}
+	proxy := &httputil.ReverseProxy{
+		Director: func(out *http.Request) {
+			out.URL.Scheme = target.Scheme
+			out.URL.Host = target.Host
+			out.URL.Path = path.Join("/", target.Path, req.URL.Path)
+			if strings.HasSuffix(req.URL.Path, "/") && !strings.HasSuffix(out.URL.Path, "/") {
+				out.URL.Path += "/"
+			}
+			out.URL.RawPath = ""
+			out.Host = target.Host
+			// the credentials of the shard replace those of the user
+			out.Header.Del("Authorization")
+		},
+		Transport:     transport,
+		FlushInterval: -1,
+		ErrorHandler: func(w http.ResponseWriter, _ *http.Request, err error) {
+			klog.Errorf("Failed to proxy %s %s to shard %q: %v", req.Method, req.URL.Path, shard, err)
+			fail(statuserrors.NewShardUnavailable(shard, err))
+		},
+	}
+	proxy.ServeHTTP(w, req)
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frontproxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/tools/cache"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/indexers"
+	"github.com/kcp-dev/kcp/pkg/sharding"
+)
+
+func TestProxy(t *testing.T) {
+	type forwarded struct {
+		shard, path, authorization, impersonated string
+	}
+	var got forwarded
+	shard := func(name string) *httptest.Server {
+		return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			got = forwarded{shard: name, path: req.URL.Path, authorization: req.Header.Get("Authorization"), impersonated: req.Header.Get("Impersonate-User")}
+			_, _ = io.WriteString(w, "{}")
+		}))
+	}
+	root, east := shard("root"), shard("us-east")
+	defer root.Close()
+	defer east.Close()
+
+	kubeconfig := filepath.Join(t.TempDir(), "shards.kubeconfig")
+	if err := os.WriteFile(kubeconfig, []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: root
+  cluster:
+    server: `+root.URL+`
+    insecure-skip-tls-verify: true
+- name: us-east
+  cluster:
+    server: `+east.URL+`
+    insecure-skip-tls-verify: true
+users:
+- name: proxy
+  user:
+    token: proxy-token
+contexts:
+- name: root
+  context: {cluster: root, user: proxy}
+- name: us-east
+  context: {cluster: us-east, user: proxy}
+current-context: root
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	loader, err := sharding.New(kubeconfig, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	workspaceIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		indexers.WorkspaceName:         indexers.IndexWorkspaceByName,
+		indexers.WorkspacePreviousName: indexers.IndexWorkspaceByPreviousName,
+		indexers.WorkspaceHomeOf:       indexers.IndexWorkspaceByHomeOf,
+	})
+	for _, obj := range []interface{}{
+		&tenancyv1alpha1.Workspace{
+			ObjectMeta: metav1.ObjectMeta{Name: "team", ClusterName: "org"},
+			Status: tenancyv1alpha1.WorkspaceStatus{
+				Cluster:       "k7c2q9x4",
+				Location:      tenancyv1alpha1.WorkspaceLocation{Current: "us-east"},
+				PreviousNames: []tenancyv1alpha1.WorkspacePreviousName{{Name: "platform"}},
+			},
+		},
+		&tenancyv1alpha1.Workspace{ObjectMeta: metav1.ObjectMeta{Name: "pending", ClusterName: "org"}},
+		&tenancyv1alpha1.Workspace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "alice-2bd806c9", ClusterName: "admin",
+				Annotations: map[string]string{tenancyv1alpha1.WorkspaceHomeOfAnnotation: "alice"},
+			},
+			Status: tenancyv1alpha1.WorkspaceStatus{Cluster: "p3m8w2z6", Location: tenancyv1alpha1.WorkspaceLocation{Current: "us-east"}},
+		},
+	} {
+		if err := workspaceIndexer.Add(obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	proxy, err := NewProxy(loader, "root", workspaceIndexer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		path         string
+		expectedCode int
+		expected     forwarded
+	}{
+		{path: "/clusters/team/api/v1/namespaces", expectedCode: http.StatusOK, expected: forwarded{shard: "us-east", path: "/clusters/team/api/v1/namespaces"}},
+		{path: "/clusters/platform/api/v1/namespaces", expectedCode: http.StatusOK, expected: forwarded{shard: "us-east", path: "/clusters/platform/api/v1/namespaces"}},
+		{path: "/clusters/us-east---admin/api", expectedCode: http.StatusOK, expected: forwarded{shard: "us-east", path: "/clusters/us-east---admin/api"}},
+		{path: "/clusters/admin/api", expectedCode: http.StatusOK, expected: forwarded{shard: "root", path: "/clusters/admin/api"}},
+		{path: "/clusters/*/apis/apps/v1/deployments", expectedCode: http.StatusOK, expected: forwarded{shard: "root", path: "/clusters/*/apis/apps/v1/deployments"}},
+		{path: "/clusters/~/api", expectedCode: http.StatusOK, expected: forwarded{shard: "us-east", path: "/clusters/~/api"}},
+		{path: "/version", expectedCode: http.StatusOK, expected: forwarded{shard: "root", path: "/version"}},
+		{path: "/clusters/pending/api", expectedCode: http.StatusServiceUnavailable},
+		{path: "/clusters/us-west---admin/api", expectedCode: http.StatusServiceUnavailable},
+	} {
+		t.Run(tc.path, func(t *testing.T) {
+			got = forwarded{}
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			req.Header.Set("Authorization", "Bearer user-token")
+			req = req.WithContext(genericapirequest.WithUser(req.Context(), &user.DefaultInfo{Name: "alice"}))
+			w := httptest.NewRecorder()
+			proxy.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedCode {
+				t.Fatalf("expected status %d, got %d: %s", tc.expectedCode, w.Code, w.Body.String())
+			}
+			if tc.expectedCode != http.StatusOK {
+				return
+			}
+			tc.expected.authorization, tc.expected.impersonated = "Bearer proxy-token", "alice"
+			if got != tc.expected {
+				t.Errorf("expected %+v, got %+v", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package homeworkspace
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/apiserver/pkg/endpoints/handlers/responsewriters"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+)
+
+// ClusterName is the name addressing the home workspace of the requesting user.
+const ClusterName = "~"
+
+const pathPrefix = "/clusters/" + ClusterName + "/"
+
+// WithHomeWorkspace serves the requests to /clusters/~/ with the home workspace of the
+// requesting user, creating it on the first request. The path of the request is rewritten
+// to the path of the home workspace before the logical cluster is resolved, and so before
+// the authentication and authorization filters, which the user is authenticated again by:
+// the authenticated user must be allowed to impersonate the user impersonated by the
+// request, if any, in the parent of the home workspaces.
+func WithHomeWorkspace(handler http.Handler, authn authenticator.Request, authz authorizer.Authorizer, p *Provisioner) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !strings.HasPrefix(req.URL.Path, pathPrefix) {
+			handler.ServeHTTP(w, req)
+			return
+		}
+		fail := func(err error) {
+			responsewriters.ErrorNegotiated(err, scheme.Codecs, schema.GroupVersion{}, w, req)
+		}
+		userName, err := homeUser(req, authn, authz, p.parentCluster())
+		if err != nil {
+			fail(err)
+			return
+		}
+		name, err := p.Home(userName)
+		if err != nil {
+			fail(err)
+			return
+		}
+
+		req.URL.Path = "/clusters/" + name + "/" + strings.TrimPrefix(req.URL.Path, pathPrefix)
+		if req.URL.RawPath != "" {
+			// the escaped path starts with /clusters/~/ as well, unless ~ was escaped
+			rest := strings.TrimPrefix(req.URL.RawPath, "/clusters/")
+			if i := strings.Index(rest, "/"); i != -1 {
+				req.URL.RawPath = "/clusters/" + name + rest[i:]
+			}
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
+
+// homeUser returns the name of the user whose home workspace the request addresses.
+func homeUser(req *http.Request, authn authenticator.Request, authz authorizer.Authorizer, parent string) (string, error) {
+	resp, ok, err := authn.AuthenticateRequest(req)
+	if err != nil || !ok {
+		return "", apierrors.NewUnauthorized("Unauthorized")
+	}
+	if !isAuthenticated(resp.User) {
+		return "", apierrors.NewForbidden(tenancyv1alpha1.Resource("workspaces"), ClusterName, fmt.Errorf("anonymous users have no home workspace"))
+	}
+	impersonated := req.Header.Get(authenticationv1.ImpersonateUserHeader)
+	if impersonated == "" || parent == "" {
+		return resp.User.GetName(), nil
+	}
+	ctx := genericapirequest.WithCluster(req.Context(), genericapirequest.Cluster{Name: parent})
+	decision, reason, err := authz.Authorize(ctx, authorizer.AttributesRecord{
+		User:            resp.User,
+		Verb:            "impersonate",
+		Resource:        "users",
+		Name:            impersonated,
+		ResourceRequest: true,
+	})
+	if err != nil || decision != authorizer.DecisionAllow {
+		return "", apierrors.NewForbidden(schema.GroupResource{Resource: "users"}, impersonated, fmt.Errorf("user %q cannot impersonate it: %s", resp.User.GetName(), reason))
+	}
+	return impersonated, nil
+}
+
+func isAuthenticated(u user.Info) bool {
+	for _, group := range u.GetGroups() {
+		if group == user.AllAuthenticated {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package homeworkspace
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/indexers"
+)
+
+func TestName(t *testing.T) {
+	for userName, expected := range map[string]string{
+		"alice":                          "alice-2bd806c9",
+		"Alice@example.com":              "alice-example-com-cdbc73c2",
+		"system:serviceaccount:ns:robot": "system-serviceaccount-ns-robot-a014e600",
+		"::":                             "home-71546855",
+	} {
+		if name := Name(userName); name != expected {
+			t.Errorf("expected home workspace name %q for %q, got %q", expected, userName, name)
+		}
+	}
+}
+
+func TestWithHomeWorkspace(t *testing.T) {
+	workspaceIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		indexers.WorkspaceName:   indexers.IndexWorkspaceByName,
+		indexers.WorkspaceHomeOf: indexers.IndexWorkspaceByHomeOf,
+	})
+	for _, obj := range []interface{}{
+		&tenancyv1alpha1.Workspace{ObjectMeta: metav1.ObjectMeta{
+			Name: "home-of-alice", ClusterName: "admin",
+			Annotations: map[string]string{tenancyv1alpha1.WorkspaceHomeOfAnnotation: "alice"},
+		}},
+		// the workspace alice renamed, which is being deleted
+		&tenancyv1alpha1.Workspace{ObjectMeta: metav1.ObjectMeta{
+			Name: "alice-2bd806c9", ClusterName: "admin",
+			Annotations: map[string]string{
+				tenancyv1alpha1.WorkspaceHomeOfAnnotation:    "alice",
+				tenancyv1alpha1.WorkspaceRenamedToAnnotation: "home-of-alice",
+			},
+		}},
+		// someone took the name of the home workspace of mallory
+		&tenancyv1alpha1.Workspace{ObjectMeta: metav1.ObjectMeta{Name: Name("mallory"), ClusterName: "admin"}},
+	} {
+		if err := workspaceIndexer.Add(obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer queue.ShutDown()
+	p := &Provisioner{queue: queue, workspaceIndexer: workspaceIndexer, hasSynced: func() bool { return true }, parent: "admin"}
+
+	authn := authenticator.RequestFunc(func(req *http.Request) (*authenticator.Response, bool, error) {
+		name := req.Header.Get("Authorization")
+		if name == "" {
+			return &authenticator.Response{User: &user.DefaultInfo{Name: user.Anonymous, Groups: []string{user.AllUnauthenticated}}}, true, nil
+		}
+		return &authenticator.Response{User: &user.DefaultInfo{Name: name, Groups: []string{user.AllAuthenticated}}}, true, nil
+	})
+	authz := authorizer.AuthorizerFunc(func(ctx context.Context, a authorizer.Attributes) (authorizer.Decision, string, error) {
+		if a.GetUser().GetName() == "root" && a.GetVerb() == "impersonate" {
+			return authorizer.DecisionAllow, "", nil
+		}
+		return authorizer.DecisionNoOpinion, "", nil
+	})
+	var served string
+	handler := WithHomeWorkspace(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		served = req.URL.Path
+	}), authn, authz, p)
+
+	for _, tc := range []struct {
+		name          string
+		path          string
+		user          string
+		impersonate   string
+		expectedCode  int
+		expectedPath  string
+		expectedQueue int
+	}{
+		{name: "home", path: "/clusters/~/api/v1/namespaces", user: "alice", expectedCode: http.StatusOK, expectedPath: "/clusters/home-of-alice/api/v1/namespaces"},
+		{name: "other cluster", path: "/clusters/team/api", user: "alice", expectedCode: http.StatusOK, expectedPath: "/clusters/team/api"},
+		{name: "impersonated", path: "/clusters/~/api", user: "root", impersonate: "alice", expectedCode: http.StatusOK, expectedPath: "/clusters/home-of-alice/api"},
+		{name: "impersonation not allowed", path: "/clusters/~/api", user: "bob", impersonate: "alice", expectedCode: http.StatusForbidden},
+		{name: "anonymous", path: "/clusters/~/api", expectedCode: http.StatusForbidden},
+		{name: "name taken", path: "/clusters/~/api", user: "mallory", expectedCode: http.StatusConflict},
+		{name: "created", path: "/clusters/~/api", user: "bob", expectedCode: http.StatusServiceUnavailable, expectedQueue: 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			served = ""
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.user != "" {
+				req.Header.Set("Authorization", tc.user)
+			}
+			if tc.impersonate != "" {
+				req.Header.Set("Impersonate-User", tc.impersonate)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedCode {
+				t.Fatalf("expected status %d, got %d: %s", tc.expectedCode, w.Code, w.Body.String())
+			}
+			if served != tc.expectedPath {
+				t.Errorf("expected path %q, got %q", tc.expectedPath, served)
+			}
+			if queue.Len() != tc.expectedQueue {
+				t.Errorf("expected %d users queued, got %d", tc.expectedQueue, queue.Len())
+			}
+			if tc.expectedCode == http.StatusServiceUnavailable && w.Header().Get("Retry-After") == "" {
+				t.Errorf("expected a Retry-After header")
+			}
+		})
+	}
+}
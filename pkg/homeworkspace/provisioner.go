@@ -0,0 +1,288 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package homeworkspace gives every user a personal workspace, reached under the ~ logical
+// cluster: the first request of a user to ~ creates the home workspace of the user under a
+// configured parent, with the WorkspaceType of home workspaces defining its quotas and RBAC,
+// and the following requests are served by it.
+package homeworkspace
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clusters"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	tenancyinformer "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/tenancy/v1alpha1"
+	tenancylister "github.com/kcp-dev/kcp/pkg/client/listers/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/indexers"
+	"github.com/kcp-dev/kcp/pkg/reconciler/workspacerbac"
+	"github.com/kcp-dev/kcp/pkg/statuserrors"
+)
+
+const controllerName = "homeworkspace"
+
+// DefaultWorkspaceType is the WorkspaceType of home workspaces, created in the parent of the
+// home workspaces when it does not exist: the owner of a home workspace administers it, and
+// its objects are kept smaller than those of other workspaces. It is only created once, and
+// may be changed by the administrators of the parent afterwards.
+var DefaultWorkspaceType = tenancyv1alpha1.WorkspaceTypeSpec{
+	ClusterRoles:      workspacerbac.DefaultWorkspaceType.ClusterRoles,
+	OwnerClusterRoles: workspacerbac.DefaultWorkspaceType.OwnerClusterRoles,
+	Limits: &tenancyv1alpha1.WorkspaceLimits{
+		MaxObjectSize:        resource.NewQuantity(512*1024, resource.BinarySI),
+		MaxManagedFieldsSize: resource.NewQuantity(128*1024, resource.BinarySI),
+		MaxAnnotationsSize:   resource.NewQuantity(64*1024, resource.BinarySI),
+	},
+}
+
+var reInvalidName = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// Name returns the name of the home workspace created for the given user: the user name
+// made a valid workspace name, followed by a hash of the user name telling apart the users
+// whose names are made the same.
+func Name(userName string) string {
+	hash := sha256.Sum256([]byte(userName))
+	prefix := strings.Trim(reInvalidName.ReplaceAllString(strings.ToLower(userName), "-"), "-")
+	if len(prefix) > 40 {
+		prefix = strings.TrimRight(prefix[:40], "-")
+	}
+	if prefix == "" {
+		prefix = "home"
+	}
+	return prefix + "-" + hex.EncodeToString(hash[:4])
+}
+
+// Provisioner finds the home workspaces of users, and creates those which do not exist yet.
+// There are no home workspaces until it is installed.
+type Provisioner struct {
+	lock                sync.RWMutex
+	queue               workqueue.RateLimitingInterface
+	kcpClient           kcpclient.ClusterInterface
+	workspaceIndexer    cache.Indexer
+	workspaceTypeLister tenancylister.WorkspaceTypeLister
+	hasSynced           func() bool
+	parent              string
+	workspaceType       string
+}
+
+// NewProvisioner returns a Provisioner which doesn't find any home workspace until it is
+// installed.
+func NewProvisioner() *Provisioner {
+	return &Provisioner{}
+}
+
+// Install makes the provisioner find the home workspaces with the given informers, and
+// create them in the parent logical cluster with the given WorkspaceType, once it is
+// started.
+func (p *Provisioner) Install(
+	kcpClient kcpclient.ClusterInterface,
+	workspaceInformer tenancyinformer.WorkspaceInformer,
+	workspaceTypeInformer tenancyinformer.WorkspaceTypeInformer,
+	parent string,
+	workspaceType string,
+) error {
+	if err := indexers.AddIfNotPresent(workspaceInformer.Informer().GetIndexer(), cache.Indexers{
+		indexers.WorkspaceName:   indexers.IndexWorkspaceByName,
+		indexers.WorkspaceHomeOf: indexers.IndexWorkspaceByHomeOf,
+	}); err != nil {
+		return fmt.Errorf("failed to add indexer for Workspace: %w", err)
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.queue = workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	p.kcpClient = kcpClient
+	p.workspaceIndexer = workspaceInformer.Informer().GetIndexer()
+	p.workspaceTypeLister = workspaceTypeInformer.Lister()
+	p.hasSynced = func() bool {
+		return workspaceInformer.Informer().HasSynced() && workspaceTypeInformer.Informer().HasSynced()
+	}
+	p.parent = parent
+	p.workspaceType = workspaceType
+	return nil
+}
+
+// Home returns the name of the home workspace of the given user. When the user has no home
+// workspace yet, it is queued for creation and a retryable WorkspaceNotReady error is
+// returned, unless another workspace has the name of its home workspace.
+func (p *Provisioner) Home(userName string) (string, error) {
+	p.lock.RLock()
+	queue, hasSynced := p.queue, p.hasSynced
+	p.lock.RUnlock()
+	if queue == nil {
+		return "", errors.NewNotFound(tenancyv1alpha1.Resource("workspaces"), "~")
+	}
+	if !hasSynced() {
+		return "", statuserrors.NewWorkspaceNotReady("~", "the home workspaces are not synced yet")
+	}
+
+	workspace, err := p.home(userName)
+	if err != nil {
+		return "", err
+	}
+	if workspace != nil {
+		return workspace.Name, nil
+	}
+	name := Name(userName)
+	taken, err := p.workspaceIndexer.ByIndex(indexers.WorkspaceName, name)
+	if err != nil {
+		return "", err
+	}
+	if len(taken) > 0 {
+		return "", errors.NewConflict(tenancyv1alpha1.Resource("workspaces"), name, fmt.Errorf("the workspace is not the home workspace of %q", userName))
+	}
+	queue.Add(userName)
+	return "", statuserrors.NewWorkspaceNotReady(name, "the home workspace is being created")
+}
+
+// parentCluster returns the logical cluster of the home workspaces, or an empty string until
+// the provisioner is installed.
+func (p *Provisioner) parentCluster() string {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.parent
+}
+
+// home returns the home workspace of the given user, if any. A renamed home workspace is
+// found under its new name while the old one is being deleted.
+func (p *Provisioner) home(userName string) (*tenancyv1alpha1.Workspace, error) {
+	objs, err := p.workspaceIndexer.ByIndex(indexers.WorkspaceHomeOf, userName)
+	if err != nil {
+		return nil, err
+	}
+	var homes []*tenancyv1alpha1.Workspace
+	for _, obj := range objs {
+		workspace := obj.(*tenancyv1alpha1.Workspace)
+		if _, renamed := workspace.Annotations[tenancyv1alpha1.WorkspaceRenamedToAnnotation]; renamed {
+			continue
+		}
+		homes = append(homes, workspace)
+	}
+	if len(homes) == 0 {
+		return nil, nil
+	}
+	sort.Slice(homes, func(i, j int) bool {
+		return clusters.ToClusterAwareKey(homes[i].ClusterName, homes[i].Name) < clusters.ToClusterAwareKey(homes[j].ClusterName, homes[j].Name)
+	})
+	return homes[0], nil
+}
+
+// Start creates the home workspaces of the users queued by Home, until the context is done.
+func (p *Provisioner) Start(ctx context.Context, numThreads int) {
+	p.lock.RLock()
+	queue, hasSynced := p.queue, p.hasSynced
+	p.lock.RUnlock()
+	if queue == nil {
+		return
+	}
+	defer runtime.HandleCrash()
+	defer queue.ShutDown()
+
+	klog.Info("Starting home workspace controller")
+	defer klog.Info("Shutting down home workspace controller")
+
+	if !cache.WaitForNamedCacheSync(controllerName, ctx.Done(), hasSynced) {
+		klog.Warning("Failed to wait for caches to sync")
+		return
+	}
+
+	for i := 0; i < numThreads; i++ {
+		go wait.Until(func() { p.startWorker(ctx) }, time.Second, ctx.Done())
+	}
+
+	<-ctx.Done()
+}
+
+func (p *Provisioner) startWorker(ctx context.Context) {
+	for p.processNextWorkItem(ctx) {
+	}
+}
+
+func (p *Provisioner) processNextWorkItem(ctx context.Context) bool {
+	// Wait until there is a new item in the working queue
+	k, quit := p.queue.Get()
+	if quit {
+		return false
+	}
+	userName := k.(string)
+
+	// No matter what, tell the queue we're done with this key, to unblock
+	// other workers.
+	defer p.queue.Done(userName)
+
+	if err := p.process(ctx, userName); err != nil {
+		runtime.HandleError(fmt.Errorf("%q controller failed to create the home workspace of %q, err: %w", controllerName, userName, err))
+		p.queue.AddRateLimited(userName)
+		return true
+	}
+	p.queue.Forget(userName)
+	return true
+}
+
+// process creates the home workspace of the given user, and the WorkspaceType of home
+// workspaces if it doesn't exist.
+func (p *Provisioner) process(ctx context.Context, userName string) error {
+	if workspace, err := p.home(userName); err != nil || workspace != nil {
+		return err
+	}
+
+	tenancyClient := p.kcpClient.Cluster(p.parent).TenancyV1alpha1()
+	if _, err := p.workspaceTypeLister.Get(clusters.ToClusterAwareKey(p.parent, p.workspaceType)); errors.IsNotFound(err) {
+		workspaceType := &tenancyv1alpha1.WorkspaceType{
+			ObjectMeta: metav1.ObjectMeta{Name: p.workspaceType},
+			Spec:       *DefaultWorkspaceType.DeepCopy(),
+		}
+		if _, err := tenancyClient.WorkspaceTypes().Create(ctx, workspaceType, metav1.CreateOptions{}); err != nil && !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create WorkspaceType %q: %w", p.workspaceType, err)
+		}
+	} else if err != nil {
+		return err
+	}
+
+	workspace := &tenancyv1alpha1.Workspace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        Name(userName),
+			Annotations: map[string]string{tenancyv1alpha1.WorkspaceHomeOfAnnotation: userName},
+		},
+		Spec: tenancyv1alpha1.WorkspaceSpec{Type: p.workspaceType},
+	}
+	if _, err := tenancyClient.Workspaces().Create(ctx, workspace, metav1.CreateOptions{}); errors.IsAlreadyExists(err) {
+		// either the informer is behind, or Home tells the user that the name is taken
+		return nil
+	} else if err != nil {
+		return err
+	}
+	klog.Infof("created home workspace %q of user %q", workspace.Name, userName)
+	return nil
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package indexers holds the indexers shared by the controllers watching the same
+// informers.
+package indexers
+
+import (
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clusters"
+
+	"github.com/kcp-dev/kcp/pkg/apis/tenancy/helpers/logicalcluster"
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+)
+
+const (
+	// WorkspaceName indexes Workspaces by name, which is the path they are reached under.
+	WorkspaceName = "workspaceName"
+	// WorkspaceCluster indexes Workspaces by the name of their logical cluster.
+	WorkspaceCluster = "workspaceCluster"
+	// WorkspacePreviousName indexes Workspaces by the names they had before being renamed.
+	WorkspacePreviousName = "workspacePreviousName"
+	// WorkspaceType indexes Workspaces by the cluster aware key of their WorkspaceType.
+	WorkspaceType = "workspaceType"
+	// WorkspaceParent indexes Workspaces by the logical cluster they are created in, which
+	// is the logical cluster of their parent workspace.
+	WorkspaceParent = "workspaceParent"
+	// WorkspaceHomeOf indexes home Workspaces by the name of their user.
+	WorkspaceHomeOf = "workspaceHomeOf"
+)
+
+// IndexWorkspaceByName is the index function of WorkspaceName.
+func IndexWorkspaceByName(obj interface{}) ([]string, error) {
+	if workspace, ok := obj.(*tenancyv1alpha1.Workspace); ok {
+		return []string{workspace.Name}, nil
+	}
+	return []string{}, nil
+}
+
+// IndexWorkspaceByCluster is the index function of WorkspaceCluster.
+func IndexWorkspaceByCluster(obj interface{}) ([]string, error) {
+	if workspace, ok := obj.(*tenancyv1alpha1.Workspace); ok {
+		return []string{logicalcluster.Of(workspace)}, nil
+	}
+	return []string{}, nil
+}
+
+// IndexWorkspaceByPreviousName is the index function of WorkspacePreviousName.
+func IndexWorkspaceByPreviousName(obj interface{}) ([]string, error) {
+	workspace, ok := obj.(*tenancyv1alpha1.Workspace)
+	if !ok {
+		return []string{}, nil
+	}
+	names := make([]string, 0, len(workspace.Status.PreviousNames))
+	for _, previous := range workspace.Status.PreviousNames {
+		names = append(names, previous.Name)
+	}
+	return names, nil
+}
+
+// IndexWorkspaceByType is the index function of WorkspaceType.
+func IndexWorkspaceByType(obj interface{}) ([]string, error) {
+	if workspace, ok := obj.(*tenancyv1alpha1.Workspace); ok && workspace.Spec.Type != "" {
+		return []string{clusters.ToClusterAwareKey(workspace.ClusterName, workspace.Spec.Type)}, nil
+	}
+	return []string{}, nil
+}
+
+// IndexWorkspaceByParent is the index function of WorkspaceParent.
+func IndexWorkspaceByParent(obj interface{}) ([]string, error) {
+	if workspace, ok := obj.(*tenancyv1alpha1.Workspace); ok {
+		return []string{workspace.ClusterName}, nil
+	}
+	return []string{}, nil
+}
+
+// IndexWorkspaceByHomeOf is the index function of WorkspaceHomeOf.
+func IndexWorkspaceByHomeOf(obj interface{}) ([]string, error) {
+	if workspace, ok := obj.(*tenancyv1alpha1.Workspace); ok && workspace.Annotations[tenancyv1alpha1.WorkspaceHomeOfAnnotation] != "" {
+		return []string{workspace.Annotations[tenancyv1alpha1.WorkspaceHomeOfAnnotation]}, nil
+	}
+	return []string{}, nil
+}
+
+// AddIfNotPresent adds the indexers which the indexer doesn't have yet. Shared informers
+// are indexed by several controllers, which must agree on what an index name stands for.
+func AddIfNotPresent(indexer cache.Indexer, indexers cache.Indexers) error {
+	existing := indexer.GetIndexers()
+	toAdd := cache.Indexers{}
+	for name, indexFunc := range indexers {
+		if _, found := existing[name]; !found {
+			toAdd[name] = indexFunc
+		}
+	}
+	if len(toAdd) == 0 {
+		return nil
+	}
+	return indexer.AddIndexers(toAdd)
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package listlimits keeps the LISTs across all logical clusters from pulling the whole
+// storage into memory: they are paginated by the server when the client doesn't, their
+// pages are kept below a maximum number of objects, and their responses below a maximum
+// size. The lists hitting these limits are counted by kind of user, and logged with the
+// name of the user, so that the clients listing too much can be found.
+package listlimits
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/authentication/serviceaccount"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/handlers/responsewriters"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/klog/v2"
+)
+
+// Limits are the limits of the LISTs across all logical clusters. Zero disables a limit.
+type Limits struct {
+	// DefaultLimit is the number of objects per page of the lists which set no limit.
+	DefaultLimit int64
+	// MaxLimit is the maximum number of objects per page. Higher limits are lowered to it.
+	MaxLimit int64
+	// MaxResponseBytes is the maximum size of a page, as sent to the client. Larger pages
+	// are rejected with 413 Request Entity Too Large, asking for a lower limit.
+	MaxResponseBytes int64
+}
+
+// errResponseTooLarge is returned to the encoder of a response larger than allowed.
+var errResponseTooLarge = errors.New("the response is larger than allowed")
+
+// WithLimits applies the limits to the LISTs across all logical clusters. Requests of
+// privileged system users, like the informers of kcp controllers, are passed to handler as
+// is. It must be wrapped by the authentication filter.
+func WithLimits(handler http.Handler, limits Limits) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		cluster := genericapirequest.ClusterFrom(req.Context())
+		info, ok := genericapirequest.RequestInfoFrom(req.Context())
+		if cluster == nil || !cluster.Wildcard || !ok || !info.IsResourceRequest || info.Verb != "list" {
+			handler.ServeHTTP(w, req)
+			return
+		}
+		u, ok := genericapirequest.UserFrom(req.Context())
+		if !ok || sets.NewString(u.GetGroups()...).Has(user.SystemPrivilegedGroup) {
+			handler.ServeHTTP(w, req)
+			return
+		}
+		resource := schema.GroupResource{Group: info.APIGroup, Resource: info.Resource}
+		oversized := func(reason string) {
+			oversizedRequests.WithLabelValues(userKind(u), info.APIGroup, info.Resource, reason).Inc()
+			klog.V(2).Infof("The list of %s across all logical clusters by %q hit the %s limit", resource, u.GetName(), reason)
+		}
+
+		query := req.URL.Query()
+		limit, err := strconv.ParseInt(query.Get("limit"), 10, 64)
+		switch {
+		case query.Get("limit") != "" && (err != nil || limit < 0):
+			// the storage rejects it
+		case limit == 0 && limits.DefaultLimit > 0:
+			oversized(reasonUnpaginated)
+			query.Set("limit", strconv.FormatInt(limits.DefaultLimit, 10))
+			req.URL.RawQuery = query.Encode()
+		case limits.MaxLimit > 0 && limit > limits.MaxLimit:
+			oversized(reasonLimit)
+			query.Set("limit", strconv.FormatInt(limits.MaxLimit, 10))
+			req.URL.RawQuery = query.Encode()
+		}
+
+		if limits.MaxResponseBytes <= 0 {
+			handler.ServeHTTP(w, req)
+			return
+		}
+		lw := &limitedResponseWriter{ResponseWriter: w, max: limits.MaxResponseBytes, code: http.StatusOK}
+		lw.reject = func() {
+			oversized(reasonResponseBytes)
+			// the response may have been about to be compressed
+			w.Header().Del("Content-Encoding")
+			err := apierrors.NewRequestEntityTooLargeError(fmt.Sprintf("the list of %s across all logical clusters is larger than %d bytes: list it with a lower limit", info.Resource, limits.MaxResponseBytes))
+			responsewriters.ErrorNegotiated(err, scheme.Codecs, schema.GroupVersion{Group: info.APIGroup, Version: info.APIVersion}, w, req)
+		}
+		handler.ServeHTTP(lw, req)
+		lw.flushHeader()
+	})
+}
+
+// userKind returns the kind of the user, which the lists hitting the limits are counted by:
+// their names are unbounded.
+func userKind(u user.Info) string {
+	switch {
+	case strings.HasPrefix(u.GetName(), serviceaccount.ServiceAccountUsernamePrefix):
+		return userKindServiceAccount
+	case strings.HasPrefix(u.GetName(), "system:"):
+		return userKindSystem
+	default:
+		return userKindUser
+	}
+}
+
+// limitedResponseWriter holds the status of the response until its body is written, to
+// reject the response instead when the body is larger than allowed. A response larger
+// than allowed after some of its body was sent is cut off.
+type limitedResponseWriter struct {
+	http.ResponseWriter
+	max    int64
+	reject func()
+
+	code        int
+	wroteHeader bool
+	written     int64
+	exceeded    bool
+}
+
+func (w *limitedResponseWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.code = code
+	}
+}
+
+func (w *limitedResponseWriter) Write(p []byte) (int, error) {
+	if w.exceeded {
+		return 0, errResponseTooLarge
+	}
+	if w.written+int64(len(p)) > w.max {
+		w.exceeded = true
+		if !w.wroteHeader {
+			w.wroteHeader = true
+			w.reject()
+		}
+		return 0, errResponseTooLarge
+	}
+	w.flushHeader()
+	n, err := w.ResponseWriter.Write(p)
+	w.written += int64(n)
+	return n, err
+}
+
+// flushHeader sends the status of the response, unless it was sent already.
+func (w *limitedResponseWriter) flushHeader() {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.ResponseWriter.WriteHeader(w.code)
+	}
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package listlimits
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"k8s.io/apiserver/pkg/authentication/user"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/component-base/metrics/testutil"
+)
+
+func TestWithLimits(t *testing.T) {
+	limits := Limits{DefaultLimit: 500, MaxLimit: 1000, MaxResponseBytes: 10}
+	var served string
+	handler := WithLimits(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		served = req.URL.Query().Get("limit")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(req.URL.Query().Get("body")))
+	}), limits)
+
+	alice := &user.DefaultInfo{Name: "alice", Groups: []string{user.AllAuthenticated}}
+	controller := &user.DefaultInfo{Name: "system:serviceaccount:default:controller", Groups: []string{user.AllAuthenticated}}
+	syncer := &user.DefaultInfo{Name: "system:kcp:syncer", Groups: []string{user.AllAuthenticated}}
+	admin := &user.DefaultInfo{Name: "admin", Groups: []string{user.SystemPrivilegedGroup}}
+	wildcard := &genericapirequest.Cluster{Name: "root", Wildcard: true}
+	for _, tc := range []struct {
+		name          string
+		query         string
+		verb          string
+		cluster       *genericapirequest.Cluster
+		user          user.Info
+		userKind      string
+		expectedCode  int
+		expectedLimit string
+		oversized     string
+	}{
+		{name: "unpaginated", verb: "list", cluster: wildcard, user: alice, userKind: userKindUser, expectedCode: http.StatusOK, expectedLimit: "500", oversized: reasonUnpaginated},
+		{name: "zero limit", query: "limit=0", verb: "list", cluster: wildcard, user: alice, userKind: userKindUser, expectedCode: http.StatusOK, expectedLimit: "500", oversized: reasonUnpaginated},
+		{name: "paginated", query: "limit=50", verb: "list", cluster: wildcard, user: alice, expectedCode: http.StatusOK, expectedLimit: "50"},
+		{name: "limit above maximum", query: "limit=5000", verb: "list", cluster: wildcard, user: alice, userKind: userKindUser, expectedCode: http.StatusOK, expectedLimit: "1000", oversized: reasonLimit},
+		{name: "invalid limit", query: "limit=-1", verb: "list", cluster: wildcard, user: alice, expectedCode: http.StatusOK, expectedLimit: "-1"},
+		{name: "response too large", query: "limit=50&body=0123456789a", verb: "list", cluster: wildcard, user: alice, userKind: userKindUser, expectedCode: http.StatusRequestEntityTooLarge, expectedLimit: "50", oversized: reasonResponseBytes},
+		{name: "service account", verb: "list", cluster: wildcard, user: controller, userKind: userKindServiceAccount, expectedCode: http.StatusOK, expectedLimit: "500", oversized: reasonUnpaginated},
+		{name: "system user", verb: "list", cluster: wildcard, user: syncer, userKind: userKindSystem, expectedCode: http.StatusOK, expectedLimit: "500", oversized: reasonUnpaginated},
+		{name: "privileged user", query: "body=0123456789a", verb: "list", cluster: wildcard, user: admin, expectedCode: http.StatusOK},
+		{name: "single logical cluster", query: "body=0123456789a", verb: "list", cluster: &genericapirequest.Cluster{Name: "root"}, user: alice, expectedCode: http.StatusOK},
+		{name: "watch", verb: "watch", cluster: wildcard, user: alice, expectedCode: http.StatusOK},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			oversizedRequests.Reset()
+			served = ""
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/configmaps?"+tc.query, nil)
+			ctx := genericapirequest.WithCluster(req.Context(), *tc.cluster)
+			ctx = genericapirequest.WithUser(ctx, tc.user)
+			ctx = genericapirequest.WithRequestInfo(ctx, &genericapirequest.RequestInfo{IsResourceRequest: true, Verb: tc.verb, APIVersion: "v1", Resource: "configmaps"})
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req.WithContext(ctx))
+
+			if w.Code != tc.expectedCode {
+				t.Fatalf("expected status %d, got %d: %s", tc.expectedCode, w.Code, w.Body.String())
+			}
+			if served != tc.expectedLimit {
+				t.Errorf("expected limit %q, got %q", tc.expectedLimit, served)
+			}
+			if tc.expectedCode == http.StatusRequestEntityTooLarge && !strings.Contains(w.Body.String(), "list it with a lower limit") {
+				t.Errorf("unexpected body: %s", w.Body.String())
+			}
+			for _, reason := range []string{reasonUnpaginated, reasonLimit, reasonResponseBytes} {
+				value, err := testutil.GetCounterMetricValue(oversizedRequests.WithLabelValues(tc.userKind, "", "configmaps", reason))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if expected := map[bool]float64{true: 1}[reason == tc.oversized]; value != expected {
+					t.Errorf("expected %v oversized requests for reason %s, got %v", expected, reason, value)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package listlimits
+
+import (
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+const metricsSubsystem = "wildcard_list"
+
+const (
+	// reasonUnpaginated is the reason of the lists without a limit, which were given the
+	// default one.
+	reasonUnpaginated = "unpaginated"
+	// reasonLimit is the reason of the lists with a limit above the maximum, which was
+	// lowered to it.
+	reasonLimit = "limit"
+	// reasonResponseBytes is the reason of the lists whose response was larger than allowed.
+	reasonResponseBytes = "response_bytes"
+)
+
+const (
+	// userKindServiceAccount is the kind of the service accounts.
+	userKindServiceAccount = "serviceaccount"
+	// userKindSystem is the kind of the other users of the system, like the components of kcp.
+	userKindSystem = "system"
+	// userKindUser is the kind of all other users.
+	userKindUser = "user"
+)
+
+var oversizedRequests = metrics.NewCounterVec(
+	&metrics.CounterOpts{
+		Subsystem:      metricsSubsystem,
+		Name:           "oversized_requests_total",
+		Help:           "Number of LISTs across all logical clusters which were not paginated, asked for more objects than allowed per page, or whose response was larger than allowed, by kind of user and resource.",
+		StabilityLevel: metrics.ALPHA,
+	},
+	[]string{"user_kind", "group", "resource", "reason"},
+)
+
+func init() {
+	legacyregistry.MustRegister(oversizedRequests)
+}
@@ -0,0 +1,290 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package podproxy lets users of a logical cluster reach the Pods its workloads run in the
+// physical clusters, for the log, exec, attach and portforward subresources of Pods, which
+// kcp doesn't serve itself.
+package podproxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/apimachinery/pkg/util/proxy"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/endpoints/handlers/responsewriters"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/transport"
+	"k8s.io/klog/v2"
+
+	clusterv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/cluster/v1alpha1"
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	clusterlisters "github.com/kcp-dev/kcp/pkg/client/listers/cluster/v1alpha1"
+	clusterreconciler "github.com/kcp-dev/kcp/pkg/reconciler/cluster"
+	"github.com/kcp-dev/kcp/pkg/syncer"
+	"github.com/kcp-dev/kcp/pkg/tunnel"
+)
+
+// Subresources are the subresources of Pods proxied to the physical clusters.
+var Subresources = sets.NewString("log", "exec", "attach", "portforward")
+
+var podsGVR = corev1.SchemeGroupVersion.WithResource("pods")
+
+// Proxy finds the physical cluster running a Pod of a logical cluster, and forwards the
+// requests of the Pod subresources to it, with the credentials kcp uses to reach the
+// cluster.
+type Proxy struct {
+	// config is the privileged config of the kcp server, whose host must not have a
+	// /clusters/... suffix.
+	config            *rest.Config
+	tunnels           *tunnel.Server
+	namespaceStrategy syncer.NamespaceStrategy
+}
+
+// NewProxy returns a Proxy reading the Clusters of logical clusters with the given
+// privileged config, and reaching the tunneled clusters through the tunnels. The namespace
+// strategy must be the one of the syncers, to find the namespaces Pods run in.
+func NewProxy(config *rest.Config, tunnels *tunnel.Server, namespaceStrategy syncer.NamespaceStrategy) *Proxy {
+	return &Proxy{
+		config:            config,
+		tunnels:           tunnels,
+		namespaceStrategy: namespaceStrategy,
+	}
+}
+
+// WithPodSubresources serves the log, exec, attach and portforward subresources of Pods by
+// forwarding them to the physical cluster running the Pod. It must be wrapped by the
+// authentication and authorization filters, which authorize the subresources with the
+// RBAC of the logical cluster, like any other request.
+func WithPodSubresources(handler http.Handler, p *Proxy) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		info, ok := genericapirequest.RequestInfoFrom(req.Context())
+		if !ok || !isPodSubresourceRequest(info) {
+			handler.ServeHTTP(w, req)
+			return
+		}
+		gv := schema.GroupVersion{Group: info.APIGroup, Version: info.APIVersion}
+		fail := func(err error) {
+			responsewriters.ErrorNegotiated(err, scheme.Codecs, gv, w, req)
+		}
+		cluster := genericapirequest.ClusterFrom(req.Context())
+		if cluster == nil || cluster.Name == "" || cluster.Wildcard {
+			fail(apierrors.NewBadRequest("pod subresources require a logical cluster"))
+			return
+		}
+
+		target, err := p.find(req.Context(), cluster.Name, info.Namespace, info.Name)
+		if err != nil {
+			fail(err)
+			return
+		}
+		if target.tunneled && httpstream.IsUpgradeRequest(req) {
+			fail(apierrors.NewBadRequest(fmt.Sprintf("pods/%s is not supported through the tunnel of cluster %s", info.Subresource, target.clusterID)))
+			return
+		}
+		p.forward(w, req, target, info.Subresource, fail)
+	})
+}
+
+func isPodSubresourceRequest(info *genericapirequest.RequestInfo) bool {
+	return info.IsResourceRequest && info.APIGroup == "" && info.Resource == "pods" && info.Name != "" && info.Namespace != "" &&
+		Subresources.Has(info.Subresource)
+}
+
+// target is the Pod of a logical cluster in the physical cluster running it.
+type target struct {
+	clusterID string
+	config    *rest.Config
+	tunneled  bool
+	namespace string
+	name      string
+}
+
+// find returns the first ready Cluster of the logical cluster running a Pod of the given
+// namespace and name, for a workload synced from the logical cluster. Pods of the same
+// name synced from other logical clusters are not found.
+func (p *Proxy) find(ctx context.Context, logicalCluster, namespace, name string) (*target, error) {
+	kcpClient, err := kcpclient.NewClusterForConfig(p.config)
+	if err != nil {
+		return nil, err
+	}
+	clusterList, err := kcpClient.Cluster(logicalCluster).ClusterV1alpha1().Clusters().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	transforms, err := kcpClient.Cluster(logicalCluster).ClusterV1alpha1().SyncTransforms().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	transformIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for i := range transforms.Items {
+		if err := transformIndexer.Add(&transforms.Items[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range clusterList.Items {
+		cluster := &clusterList.Items[i]
+		if !cluster.Status.Conditions.IsTrue(clusterv1alpha1.ClusterConditionReady) {
+			continue
+		}
+		transformer := syncer.NewTransformer(cluster.Name, logicalCluster, p.namespaceStrategy, clusterlisters.NewSyncTransformLister(transformIndexer))
+		downstreamNamespace, err := transformer.DownstreamNamespace(podsGVR, namespace)
+		if err != nil {
+			continue
+		}
+		config, err := p.configFor(ctx, cluster)
+		if err != nil {
+			klog.Errorf("Failed to reach cluster %s of logical cluster %s for the subresources of pod %s/%s: %v", cluster.Name, logicalCluster, namespace, name, err)
+			continue
+		}
+		client, err := dynamic.NewForConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		kubeClient, err := kubernetes.NewForConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(kubeClient.Discovery()))
+		found, err := syncer.IsSyncedOrOwned(ctx, client, mapper, corev1.ObjectReference{
+			APIVersion: "v1",
+			Kind:       "Pod",
+			Namespace:  downstreamNamespace,
+			Name:       name,
+		}, cluster.Name, logicalCluster)
+		if err != nil {
+			klog.Errorf("Failed to look for pod %s/%s of logical cluster %s in cluster %s: %v", namespace, name, logicalCluster, cluster.Name, err)
+			continue
+		}
+		if found {
+			return &target{
+				clusterID: cluster.Name,
+				config:    config,
+				tunneled:  cluster.Spec.ConnectionMode == clusterv1alpha1.ConnectionModeTunnel,
+				namespace: downstreamNamespace,
+				name:      name,
+			}, nil
+		}
+	}
+	return nil, apierrors.NewNotFound(podsGVR.GroupResource(), name)
+}
+
+// configFor returns the config reaching the physical cluster, through its tunnel or with
+// its kubeconfig, like the cluster controller does.
+func (p *Proxy) configFor(ctx context.Context, cluster *clusterv1alpha1.Cluster) (*rest.Config, error) {
+	if cluster.Spec.ConnectionMode == clusterv1alpha1.ConnectionModeTunnel {
+		if p.tunnels == nil {
+			return nil, fmt.Errorf("tunnels are not served")
+		}
+		return p.tunnels.Config(cluster.ClusterName, cluster.Name)
+	}
+	kubeClient, err := kubernetes.NewClusterForConfig(p.config)
+	if err != nil {
+		return nil, err
+	}
+	kubeConfig, err := clusterreconciler.KubeConfigFor(cluster, func(namespace, name string) (*corev1.Secret, error) {
+		return kubeClient.Cluster(cluster.ClusterName).CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return clientcmd.RESTConfigFromKubeConfig(kubeConfig)
+}
+
+// forward proxies the request to the subresource of the Pod in its physical cluster. The
+// credentials of the user for kcp are not forwarded: the request is made with the ones
+// of the cluster.
+func (p *Proxy) forward(w http.ResponseWriter, req *http.Request, target *target, subresource string, fail func(error)) {
+	location, err := url.Parse(target.config.Host)
+	if err != nil {
+		fail(apierrors.NewInternalError(err))
+		return
+	}
+	location.Path = path.Join(location.Path, "/api/v1/namespaces", target.namespace, "pods", target.name, subresource)
+	location.RawQuery = req.URL.RawQuery
+
+	roundTripper, err := rest.TransportFor(target.config)
+	if err != nil {
+		fail(apierrors.NewInternalError(err))
+		return
+	}
+	handler := proxy.NewUpgradeAwareHandler(location, roundTripper, false, false, &responder{fail: fail})
+	if !target.tunneled {
+		upgradeTransport, err := upgradeTransportFor(target.config)
+		if err != nil {
+			fail(apierrors.NewInternalError(err))
+			return
+		}
+		handler.UpgradeTransport = upgradeTransport
+	}
+
+	forwarded := req.Clone(req.Context())
+	forwarded.Header.Del("Authorization")
+	for key := range forwarded.Header {
+		if strings.HasPrefix(key, "Impersonate-") {
+			forwarded.Header.Del(key)
+		}
+	}
+	forwarded.Header.Del("X-Kubernetes-Cluster")
+	klog.V(4).Infof("Forwarding pods/%s of %s/%s to cluster %s", subresource, target.namespace, target.name, target.clusterID)
+	handler.ServeHTTP(w, forwarded)
+}
+
+// upgradeTransportFor returns the transport of the upgraded connections of exec, attach
+// and portforward, authenticated with the given config.
+func upgradeTransportFor(config *rest.Config) (proxy.UpgradeRequestRoundTripper, error) {
+	transportConfig, err := config.TransportConfig()
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := transport.TLSConfigFor(transportConfig)
+	if err != nil {
+		return nil, err
+	}
+	connection := utilnet.SetOldTransportDefaults(&http.Transport{TLSClientConfig: tlsConfig})
+	upgrader, err := transport.HTTPWrappersForConfig(transportConfig, proxy.MirrorRequest)
+	if err != nil {
+		return nil, err
+	}
+	return proxy.NewUpgradeRequestRoundTripper(connection, upgrader), nil
+}
+
+type responder struct {
+	fail func(error)
+}
+
+func (r *responder) Error(_ http.ResponseWriter, _ *http.Request, err error) {
+	r.fail(apierrors.NewServiceUnavailable(err.Error()))
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podproxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"k8s.io/client-go/rest"
+)
+
+func TestForward(t *testing.T) {
+	var got *http.Request
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got = req
+		_, _ = io.WriteString(w, "log line\n")
+	}))
+	defer backend.Close()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/namespaces/default/pods/web-1-a/log?container=web&follow=true", nil)
+	req.Header.Set("Authorization", "Bearer user-token")
+	req.Header.Set("Impersonate-User", "admin")
+	req.Header.Set("X-Kubernetes-Cluster", "admin")
+	w := httptest.NewRecorder()
+
+	p := &Proxy{}
+	p.forward(w, req, &target{
+		clusterID: "us-east1",
+		config:    &rest.Config{Host: backend.URL, BearerToken: "cluster-token"},
+		namespace: "kcp-1a2b3c4d-default",
+		name:      "web-1-a",
+	}, "log", func(err error) { t.Fatalf("unexpected error: %v", err) })
+
+	if w.Code != http.StatusOK || w.Body.String() != "log line\n" {
+		t.Fatalf("unexpected response %d: %q", w.Code, w.Body.String())
+	}
+	if got == nil {
+		t.Fatal("expected the request to be forwarded")
+	}
+	if want := "/api/v1/namespaces/kcp-1a2b3c4d-default/pods/web-1-a/log"; got.URL.Path != want {
+		t.Errorf("expected the request to be forwarded to %s, got %s", want, got.URL.Path)
+	}
+	if want := "container=web&follow=true"; got.URL.RawQuery != want {
+		t.Errorf("expected the query %s, got %s", want, got.URL.RawQuery)
+	}
+	if auth := got.Header.Get("Authorization"); auth != "Bearer cluster-token" {
+		t.Errorf("expected the credentials of the cluster, got %q", auth)
+	}
+	for _, header := range []string{"Impersonate-User", "X-Kubernetes-Cluster"} {
+		if value := got.Header.Get(header); value != "" {
+			t.Errorf("expected header %s not to be forwarded, got %q", header, value)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package priorityandfairness queues the requests of kcp with API Priority and Fairness,
+// configured by the FlowSchemas and PriorityLevelConfigurations of the root logical
+// cluster. Next to the upstream configuration, kcp bootstraps a priority level for its
+// own components, and one for the requests to workspaces, in which each logical cluster
+// is a flow queued fairly with the others. Privileged users, like the loopback client and
+// the controllers of kcp, stay exempt as upstream, so that tenants never hold them up.
+package priorityandfairness
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	flowcontrolv1beta1 "k8s.io/api/flowcontrol/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	utilflowcontrol "k8s.io/apiserver/pkg/util/flowcontrol"
+	fq "k8s.io/apiserver/pkg/util/flowcontrol/fairqueuing"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	flowcontrolclient "k8s.io/client-go/kubernetes/typed/flowcontrol/v1beta1"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+
+	"github.com/kcp-dev/kcp/pkg/reconciler/shardcredentials"
+)
+
+const (
+	// SystemName names the FlowSchema and the priority level of the requests of the kcp
+	// components which are not privileged, like the shards proxying requests to each other.
+	SystemName = "kcp-system"
+	// WorkspacesName names the FlowSchema and the priority level of the requests of the
+	// tenants, in which the requests to each logical cluster are a flow.
+	WorkspacesName = "kcp-workspaces"
+)
+
+// PriorityLevels are the priority levels bootstrapped by kcp.
+var PriorityLevels = []*flowcontrolv1beta1.PriorityLevelConfiguration{
+	priorityLevel(SystemName, 40, 64),
+	priorityLevel(WorkspacesName, 100, 128),
+}
+
+// FlowSchemas are the FlowSchemas bootstrapped by kcp. The shards match right after the
+// exempt FlowSchema, while the other users only match after the suggested upstream
+// FlowSchemas, like those of nodes, leader election and service accounts, and before the
+// global-default one (9900) catching the remaining requests.
+var FlowSchemas = []*flowcontrolv1beta1.FlowSchema{
+	flowSchema(SystemName, 2, flowcontrolv1beta1.FlowDistinguisherMethodByUserType, shardcredentials.ShardGroup),
+	flowSchema(WorkspacesName, 9800, flowcontrolv1beta1.FlowDistinguisherMethodByNamespaceType, "system:authenticated", "system:unauthenticated"),
+}
+
+func priorityLevel(name string, shares, queues int32) *flowcontrolv1beta1.PriorityLevelConfiguration {
+	return &flowcontrolv1beta1.PriorityLevelConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: flowcontrolv1beta1.PriorityLevelConfigurationSpec{
+			Type: flowcontrolv1beta1.PriorityLevelEnablementLimited,
+			Limited: &flowcontrolv1beta1.LimitedPriorityLevelConfiguration{
+				AssuredConcurrencyShares: shares,
+				LimitResponse: flowcontrolv1beta1.LimitResponse{
+					Type: flowcontrolv1beta1.LimitResponseTypeQueue,
+					Queuing: &flowcontrolv1beta1.QueuingConfiguration{
+						Queues:           queues,
+						HandSize:         6,
+						QueueLengthLimit: 50,
+					},
+				},
+			},
+		},
+	}
+}
+
+func flowSchema(name string, precedence int32, distinguisher flowcontrolv1beta1.FlowDistinguisherMethodType, groups ...string) *flowcontrolv1beta1.FlowSchema {
+	subjects := make([]flowcontrolv1beta1.Subject, 0, len(groups))
+	for _, group := range groups {
+		subjects = append(subjects, flowcontrolv1beta1.Subject{
+			Kind:  flowcontrolv1beta1.SubjectKindGroup,
+			Group: &flowcontrolv1beta1.GroupSubject{Name: group},
+		})
+	}
+	return &flowcontrolv1beta1.FlowSchema{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: flowcontrolv1beta1.FlowSchemaSpec{
+			PriorityLevelConfiguration: flowcontrolv1beta1.PriorityLevelConfigurationReference{Name: name},
+			MatchingPrecedence:         precedence,
+			DistinguisherMethod:        &flowcontrolv1beta1.FlowDistinguisherMethod{Type: distinguisher},
+			Rules: []flowcontrolv1beta1.PolicyRulesWithSubjects{{
+				Subjects: subjects,
+				ResourceRules: []flowcontrolv1beta1.ResourcePolicyRule{{
+					Verbs:        []string{flowcontrolv1beta1.VerbAll},
+					APIGroups:    []string{flowcontrolv1beta1.APIGroupAll},
+					Resources:    []string{flowcontrolv1beta1.ResourceAll},
+					ClusterScope: true,
+					Namespaces:   []string{flowcontrolv1beta1.NamespaceEvery},
+				}},
+				NonResourceRules: []flowcontrolv1beta1.NonResourcePolicyRule{{
+					Verbs:           []string{flowcontrolv1beta1.VerbAll},
+					NonResourceURLs: []string{flowcontrolv1beta1.NonResourceAll},
+				}},
+			}},
+		},
+	}
+}
+
+// Bootstrap returns the Run function of a startup step creating the FlowSchemas and the
+// priority levels of kcp which don't exist. Existing ones are left as they are, so that
+// administrators can tune them.
+func Bootstrap(client flowcontrolclient.FlowcontrolV1beta1Interface) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return wait.PollImmediateUntilWithContext(ctx, time.Second, func(ctx context.Context) (bool, error) {
+			if err := bootstrap(ctx, client); err != nil {
+				klog.Errorf("Failed to bootstrap the priority and fairness configuration of kcp, will retry: %v", err)
+				return false, nil
+			}
+			return true, nil
+		})
+	}
+}
+
+func bootstrap(ctx context.Context, client flowcontrolclient.FlowcontrolV1beta1Interface) error {
+	// the priority levels first, so that the FlowSchemas don't dangle
+	for _, pl := range PriorityLevels {
+		if _, err := client.PriorityLevelConfigurations().Create(ctx, pl, metav1.CreateOptions{}); err != nil && !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create PriorityLevelConfiguration %q: %w", pl.Name, err)
+		}
+	}
+	for _, fs := range FlowSchemas {
+		if _, err := client.FlowSchemas().Create(ctx, fs, metav1.CreateOptions{}); err != nil && !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create FlowSchema %q: %w", fs.Name, err)
+		}
+	}
+	return nil
+}
+
+// New returns the flow control of the server reached with the loopback config, and the
+// informer factory of its configuration, which must be started. The requests to a logical
+// cluster are seen as requests to a namespace named after the logical cluster by the
+// FlowSchemas, so that the FlowSchemas distinguishing flows by namespace queue the logical
+// clusters fairly.
+func New(loopbackConfig *rest.Config, serverConcurrencyLimit int, requestWaitLimit time.Duration) (utilflowcontrol.Interface, informers.SharedInformerFactory, error) {
+	client, err := kubernetes.NewForConfig(loopbackConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	informerFactory := informers.NewSharedInformerFactory(client, 0)
+	flowControl := utilflowcontrol.New(informerFactory, client.FlowcontrolV1beta1(), serverConcurrencyLimit, requestWaitLimit)
+	return &workspaceFlows{Interface: flowControl}, informerFactory, nil
+}
+
+// workspaceFlows sets the namespace of the digests of the requests to a logical cluster to
+// the logical cluster, as the flows of FlowSchemas can only be told apart by namespace or
+// user.
+type workspaceFlows struct {
+	utilflowcontrol.Interface
+}
+
+func (f *workspaceFlows) Handle(ctx context.Context, requestDigest utilflowcontrol.RequestDigest,
+	noteFn func(fs *flowcontrolv1beta1.FlowSchema, pl *flowcontrolv1beta1.PriorityLevelConfiguration),
+	queueNoteFn fq.QueueNoteFn,
+	execFn func(),
+) {
+	if cluster := genericapirequest.ClusterFrom(ctx); cluster != nil && cluster.Name != "" && !cluster.Wildcard && requestDigest.RequestInfo != nil {
+		info := *requestDigest.RequestInfo
+		info.Namespace = cluster.Name
+		requestDigest.RequestInfo = &info
+	}
+	f.Interface.Handle(ctx, requestDigest, noteFn, queueNoteFn, execFn)
+}
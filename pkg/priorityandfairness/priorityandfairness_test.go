@@ -0,0 +1,146 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package priorityandfairness
+
+import (
+	"context"
+	"testing"
+
+	flowcontrolv1beta1 "k8s.io/api/flowcontrol/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	flowcontrolbootstrap "k8s.io/apiserver/pkg/apis/flowcontrol/bootstrap"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	utilflowcontrol "k8s.io/apiserver/pkg/util/flowcontrol"
+	fq "k8s.io/apiserver/pkg/util/flowcontrol/fairqueuing"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+type digests struct {
+	utilflowcontrol.Interface
+	namespaces []string
+}
+
+func (d *digests) Handle(ctx context.Context, requestDigest utilflowcontrol.RequestDigest,
+	noteFn func(fs *flowcontrolv1beta1.FlowSchema, pl *flowcontrolv1beta1.PriorityLevelConfiguration),
+	queueNoteFn fq.QueueNoteFn,
+	execFn func(),
+) {
+	d.namespaces = append(d.namespaces, requestDigest.RequestInfo.Namespace)
+	execFn()
+}
+
+func TestWorkspaceFlows(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		cluster   *genericapirequest.Cluster
+		namespace string
+		expected  string
+	}{
+		{name: "no cluster", namespace: "default", expected: "default"},
+		{name: "cluster", cluster: &genericapirequest.Cluster{Name: "org:team"}, namespace: "kube-system", expected: "org:team"},
+		{name: "cluster scoped", cluster: &genericapirequest.Cluster{Name: "org:team"}, expected: "org:team"},
+		{name: "wildcard", cluster: &genericapirequest.Cluster{Name: "*", Wildcard: true}, namespace: "default", expected: "default"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			d := &digests{}
+			f := &workspaceFlows{Interface: d}
+			ctx := context.Background()
+			if tc.cluster != nil {
+				ctx = genericapirequest.WithCluster(ctx, *tc.cluster)
+			}
+			info := &genericapirequest.RequestInfo{IsResourceRequest: true, Namespace: tc.namespace}
+			executed := false
+			f.Handle(ctx, utilflowcontrol.RequestDigest{RequestInfo: info}, nil, nil, func() { executed = true })
+			if !executed {
+				t.Fatal("expected the request to be executed")
+			}
+			if len(d.namespaces) != 1 || d.namespaces[0] != tc.expected {
+				t.Errorf("expected namespace %q, got %v", tc.expected, d.namespaces)
+			}
+			if info.Namespace != tc.namespace {
+				t.Errorf("expected the request info not to be changed, got namespace %q", info.Namespace)
+			}
+		})
+	}
+}
+
+func TestBootstrap(t *testing.T) {
+	tuned := priorityLevel(WorkspacesName, 10, 16)
+	client := fake.NewSimpleClientset(tuned)
+	if err := Bootstrap(client.FlowcontrolV1beta1())(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	pls, err := client.FlowcontrolV1beta1().PriorityLevelConfigurations().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pls.Items) != len(PriorityLevels) {
+		t.Errorf("expected %d priority levels, got %d", len(PriorityLevels), len(pls.Items))
+	}
+	pl, err := client.FlowcontrolV1beta1().PriorityLevelConfigurations().Get(context.Background(), WorkspacesName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shares := pl.Spec.Limited.AssuredConcurrencyShares; shares != 10 {
+		t.Errorf("expected the existing priority level to be kept, got %d shares", shares)
+	}
+	for _, fs := range FlowSchemas {
+		if _, err := client.FlowcontrolV1beta1().FlowSchemas().Get(context.Background(), fs.Name, metav1.GetOptions{}); err != nil {
+			t.Errorf("expected FlowSchema %q: %v", fs.Name, err)
+		}
+	}
+}
+
+func TestFlowSchemaPrecedence(t *testing.T) {
+	var workspaces, system *flowcontrolv1beta1.FlowSchema
+	for _, fs := range FlowSchemas {
+		switch fs.Name {
+		case WorkspacesName:
+			workspaces = fs
+		case SystemName:
+			system = fs
+		}
+	}
+	if workspaces == nil || system == nil {
+		t.Fatalf("expected the %s and %s FlowSchemas", WorkspacesName, SystemName)
+	}
+
+	// the kcp-workspaces FlowSchema matches all users, so the upstream ones must come first
+	for _, fs := range flowcontrolbootstrap.SuggestedFlowSchemas {
+		if fs.Name == flowcontrolbootstrap.SuggestedFlowSchemaGlobalDefault.Name {
+			if fs.Spec.MatchingPrecedence <= workspaces.Spec.MatchingPrecedence {
+				t.Errorf("expected FlowSchema %q (%d) to match after %q (%d)", fs.Name, fs.Spec.MatchingPrecedence, WorkspacesName, workspaces.Spec.MatchingPrecedence)
+			}
+			continue
+		}
+		if fs.Spec.MatchingPrecedence >= workspaces.Spec.MatchingPrecedence {
+			t.Errorf("expected suggested FlowSchema %q (%d) to match before %q (%d)", fs.Name, fs.Spec.MatchingPrecedence, WorkspacesName, workspaces.Spec.MatchingPrecedence)
+		}
+	}
+	for _, fs := range flowcontrolbootstrap.MandatoryFlowSchemas {
+		if fs.Name == flowcontrolv1beta1.FlowSchemaNameExempt && fs.Spec.MatchingPrecedence >= system.Spec.MatchingPrecedence {
+			t.Errorf("expected FlowSchema %q (%d) to match before %q (%d)", fs.Name, fs.Spec.MatchingPrecedence, SystemName, system.Spec.MatchingPrecedence)
+		}
+		if fs.Name == flowcontrolv1beta1.FlowSchemaNameCatchAll && fs.Spec.MatchingPrecedence <= workspaces.Spec.MatchingPrecedence {
+			t.Errorf("expected FlowSchema %q (%d) to match after %q (%d)", fs.Name, fs.Spec.MatchingPrecedence, WorkspacesName, workspaces.Spec.MatchingPrecedence)
+		}
+	}
+	if system.Spec.MatchingPrecedence >= workspaces.Spec.MatchingPrecedence {
+		t.Errorf("expected %q to match the shards before %q", SystemName, WorkspacesName)
+	}
+}
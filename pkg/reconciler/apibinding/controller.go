@@ -0,0 +1,542 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apibinding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	crdinformer "k8s.io/apiextensions-apiserver/pkg/client/informers/externalversions/apiextensions/v1"
+	crdlister "k8s.io/apiextensions-apiserver/pkg/client/listers/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clusters"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	"github.com/kcp-dev/kcp/pkg/admission/apibinder"
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	"github.com/kcp-dev/kcp/pkg/client/clusterkeys"
+	apisinformer "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/apis/v1alpha1"
+	apislister "github.com/kcp-dev/kcp/pkg/client/listers/apis/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/crdprojection"
+	"github.com/kcp-dev/kcp/pkg/workspacenames"
+)
+
+const (
+	clusterIndex   = "cluster"
+	controllerName = "apibinding"
+
+	// BoundByAnnotation is set on the CustomResourceDefinitions served in a workspace on
+	// behalf of an APIBinding. Its value is the name of that APIBinding.
+	BoundByAnnotation = "apis.kcp.dev/bound-by"
+
+	// PermissionClaimsPrefix prefixes the name of the ClusterRole and ClusterRoleBinding
+	// granting the accepted permission claims of an APIBinding to the provider of the
+	// APIExport, followed by the name of the APIBinding.
+	PermissionClaimsPrefix = "apis.kcp.dev:permission-claims:"
+)
+
+// ProviderGroup returns the group the accepted permission claims on an APIExport are
+// granted to in the workspaces binding it. The provider of the export reaches those
+// workspaces with an identity of that group.
+func ProviderGroup(exportClusterName, exportName string) string {
+	return "system:kcp:apiexport:" + exportClusterName + ":" + exportName
+}
+
+// WorkspacePaths resolves the paths of workspaces, like org:team, to their logical
+// clusters.
+type WorkspacePaths interface {
+	Resolve(path string) (clusterName string, renamedTo string, err error)
+}
+
+// NewController returns a controller which establishes APIBindings: the
+// CustomResourceDefinitions of the resources exported by the referenced APIExport are
+// projected into the logical cluster of the APIBinding through the registry, so that they
+// are served from their definition in the provider workspace.
+//
+// A binding is only established when its binder, recorded by the apibinder admission
+// plugin, may bind the APIExport in the provider workspace, and may do anything with the
+// resources of the accepted permission claims in the workspace of the binding, which are
+// then granted to the ProviderGroup of the export, until they are withdrawn.
+func NewController(
+	kcpClient kcpclient.ClusterInterface,
+	kubeClient kubernetes.ClusterInterface,
+	apiBindingInformer apisinformer.APIBindingInformer,
+	apiExportInformer apisinformer.APIExportInformer,
+	crdInformer crdinformer.CustomResourceDefinitionInformer,
+	workspacePaths WorkspacePaths,
+	registry *crdprojection.Registry,
+) (*Controller, error) {
+	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+
+	c := &Controller{
+		queue:             queue,
+		kcpClient:         kcpClient,
+		kubeClient:        kubeClient,
+		workspacePaths:    workspacePaths,
+		registry:          registry,
+		apiBindingIndexer: apiBindingInformer.Informer().GetIndexer(),
+		apiBindingLister:  apiBindingInformer.Lister(),
+		apiExportLister:   apiExportInformer.Lister(),
+		crdLister:         crdInformer.Lister(),
+		syncChecks: []cache.InformerSynced{
+			apiBindingInformer.Informer().HasSynced,
+			apiExportInformer.Informer().HasSynced,
+			crdInformer.Informer().HasSynced,
+		},
+	}
+
+	apiBindingInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { c.enqueue(obj) },
+		UpdateFunc: func(_, obj interface{}) { c.enqueue(obj) },
+		DeleteFunc: func(obj interface{}) { c.enqueue(obj) },
+	})
+	if err := c.apiBindingIndexer.AddIndexers(map[string]cache.IndexFunc{
+		clusterIndex: func(obj interface{}) ([]string, error) {
+			if binding, ok := obj.(*apisv1alpha1.APIBinding); ok {
+				return []string{binding.ClusterName}, nil
+			}
+			return []string{}, nil
+		},
+	}); err != nil {
+		return nil, fmt.Errorf("failed to add indexer for APIBinding: %w", err)
+	}
+
+	apiExportInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { c.enqueueExportBindings(obj) },
+		UpdateFunc: func(_, obj interface{}) { c.enqueueExportBindings(obj) },
+		DeleteFunc: func(obj interface{}) { c.enqueueExportBindings(obj) },
+	})
+
+	crdInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { c.enqueueCRD(obj) },
+		UpdateFunc: func(_, obj interface{}) { c.enqueueCRD(obj) },
+		DeleteFunc: func(obj interface{}) { c.enqueueCRD(obj) },
+	})
+
+	return c, nil
+}
+
+// Controller watches APIBindings, APIExports and CustomResourceDefinitions in order to
+// serve the APIs bound by every APIBinding in its workspace.
+type Controller struct {
+	queue workqueue.RateLimitingInterface
+
+	kcpClient  kcpclient.ClusterInterface
+	kubeClient kubernetes.ClusterInterface
+
+	workspacePaths WorkspacePaths
+	registry       *crdprojection.Registry
+
+	apiBindingIndexer cache.Indexer
+	apiBindingLister  apislister.APIBindingLister
+	apiExportLister   apislister.APIExportLister
+
+	crdLister crdlister.CustomResourceDefinitionLister
+
+	syncChecks []cache.InformerSynced
+}
+
+func (c *Controller) enqueue(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	klog.V(4).Infof("queueing apibinding %q", key)
+	c.queue.Add(key)
+}
+
+// enqueueExportBindings queues the APIBindings referencing the APIExport obj.
+func (c *Controller) enqueueExportBindings(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	clusterName, name := clusters.SplitClusterAwareKey(key)
+	c.enqueueReferencing(clusterName, name)
+}
+
+// enqueueReferencing queues the APIBindings whose reference resolves to the given logical
+// cluster, and to the APIExport of the given name unless it is empty. The references are
+// paths, which are resolved again every time since workspaces come and go.
+func (c *Controller) enqueueReferencing(clusterName, exportName string) {
+	for _, obj := range c.apiBindingIndexer.List() {
+		binding := obj.(*apisv1alpha1.APIBinding)
+		ref := binding.Spec.Reference
+		if exportName != "" && ref.ExportName != exportName {
+			continue
+		}
+		if resolved, _, err := c.workspacePaths.Resolve(ref.Workspace); err == nil && resolved == clusterName {
+			c.enqueue(binding)
+		}
+	}
+}
+
+// enqueueCRD queues the APIBindings which may export a changed CRD, and the ones of its
+// logical cluster, where it may conflict with a bound CRD of the same name, including the
+// projections of other bindings.
+func (c *Controller) enqueueCRD(obj interface{}) {
+	crd, ok := obj.(*apiextensionsv1.CustomResourceDefinition)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			klog.V(2).Infof("Couldn't get object from tombstone %#v", obj)
+			return
+		}
+		crd, ok = tombstone.Obj.(*apiextensionsv1.CustomResourceDefinition)
+		if !ok {
+			klog.V(2).Infof("Tombstone contained object that is not a CustomResourceDefinition: %#v", obj)
+			return
+		}
+	}
+	bindings, err := c.apiBindingIndexer.ByIndex(clusterIndex, crd.ClusterName)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	for _, binding := range bindings {
+		c.enqueue(binding)
+	}
+	c.enqueueReferencing(crd.ClusterName, "")
+}
+
+func (c *Controller) Start(ctx context.Context, numThreads int) {
+	defer runtime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	klog.Info("Starting APIBinding controller")
+	defer klog.Info("Shutting down APIBinding controller")
+
+	if !cache.WaitForNamedCacheSync(controllerName, ctx.Done(), c.syncChecks...) {
+		klog.Warning("Failed to wait for caches to sync")
+		return
+	}
+
+	for i := 0; i < numThreads; i++ {
+		go wait.Until(func() { c.startWorker(ctx) }, time.Second, ctx.Done())
+	}
+
+	<-ctx.Done()
+}
+
+func (c *Controller) startWorker(ctx context.Context) {
+	for c.processNextWorkItem(ctx) {
+	}
+}
+
+func (c *Controller) processNextWorkItem(ctx context.Context) bool {
+	// Wait until there is a new item in the working queue
+	k, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	key := k.(string)
+
+	// No matter what, tell the queue we're done with this key, to unblock
+	// other workers.
+	defer c.queue.Done(key)
+
+	if err := c.process(ctx, key); err != nil {
+		runtime.HandleError(fmt.Errorf("%q controller failed to sync %q, err: %w", controllerName, key, err))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	c.queue.Forget(key)
+	return true
+}
+
+func (c *Controller) process(ctx context.Context, key string) error {
+	clusterName, _, name, err := clusterkeys.SplitClusterKey(key)
+	if err != nil {
+		klog.Errorf("invalid key: %q: %v", key, err)
+		return nil
+	}
+
+	obj, err := c.apiBindingLister.Get(key)
+	if apierrors.IsNotFound(err) {
+		// the binding is gone, stop serving its APIs
+		return c.unbind(ctx, clusterName, name)
+	} else if err != nil {
+		return err
+	}
+	previous := obj
+	obj = obj.DeepCopy()
+
+	if err := c.reconcile(ctx, obj); err != nil {
+		return err
+	}
+
+	if !equality.Semantic.DeepEqual(previous.Status, obj.Status) {
+		_, err := c.kcpClient.Cluster(clusterName).ApisV1alpha1().APIBindings().UpdateStatus(ctx, obj, metav1.UpdateOptions{})
+		return err
+	}
+	return nil
+}
+
+func (c *Controller) reconcile(ctx context.Context, binding *apisv1alpha1.APIBinding) error {
+	binding.Status.Phase = apisv1alpha1.APIBindingPhaseBinding
+	binding.Status.BoundResources = nil
+
+	ref := binding.Spec.Reference
+	exportCluster, _, err := c.workspacePaths.Resolve(ref.Workspace)
+	if errors.Is(err, workspacenames.ErrNotFound) || errors.Is(err, workspacenames.ErrNotInitialized) {
+		setCondition(binding, apisv1alpha1.APIExportValid, metav1.ConditionFalse, "WorkspaceNotFound", fmt.Sprintf("Workspace %q not found: %v.", ref.Workspace, err))
+		setCondition(binding, apisv1alpha1.BindingReady, metav1.ConditionFalse, "APIExportNotFound", "The referenced APIExport does not exist.")
+		return c.unbind(ctx, binding.ClusterName, binding.Name)
+	} else if err != nil {
+		return err
+	}
+	export, err := c.apiExportLister.Get(clusters.ToClusterAwareKey(exportCluster, ref.ExportName))
+	if apierrors.IsNotFound(err) {
+		setCondition(binding, apisv1alpha1.APIExportValid, metav1.ConditionFalse, "APIExportNotFound", fmt.Sprintf("APIExport %q not found in workspace %q.", ref.ExportName, ref.Workspace))
+		setCondition(binding, apisv1alpha1.BindingReady, metav1.ConditionFalse, "APIExportNotFound", "The referenced APIExport does not exist.")
+		return c.unbind(ctx, binding.ClusterName, binding.Name)
+	} else if err != nil {
+		return err
+	}
+
+	var exported []*apiextensionsv1.CustomResourceDefinition
+	for _, gr := range export.Spec.Resources {
+		crd, err := c.crdLister.Get(clusters.ToClusterAwareKey(exportCluster, crdName(gr)))
+		if apierrors.IsNotFound(err) {
+			setCondition(binding, apisv1alpha1.APIExportValid, metav1.ConditionFalse, "ResourceNotFound", fmt.Sprintf("No CustomResourceDefinition %q in workspace %q.", crdName(gr), ref.Workspace))
+			setCondition(binding, apisv1alpha1.BindingReady, metav1.ConditionFalse, "APIExportInvalid", "The referenced APIExport is invalid.")
+			return c.unbind(ctx, binding.ClusterName, binding.Name)
+		} else if err != nil {
+			return err
+		}
+		exported = append(exported, crd)
+	}
+	setCondition(binding, apisv1alpha1.APIExportValid, metav1.ConditionTrue, "APIExportValid", "The referenced APIExport is valid.")
+
+	binder, err := apibinder.Binder(binding)
+	if err != nil || binder == nil {
+		setCondition(binding, apisv1alpha1.BinderAuthorized, metav1.ConditionFalse, "BinderUnknown", fmt.Sprintf("The %s annotation is missing or invalid.", apibinder.BinderAnnotation))
+		setCondition(binding, apisv1alpha1.BindingReady, metav1.ConditionFalse, "BinderNotAuthorized", "The binder is not authorized to bind the APIExport.")
+		return c.unbind(ctx, binding.ClusterName, binding.Name)
+	}
+	allowed, err := c.authorized(ctx, binder, exportCluster, authorizationv1.ResourceAttributes{
+		Verb:     "bind",
+		Group:    apisv1alpha1.SchemeGroupVersion.Group,
+		Resource: "apiexports",
+		Name:     ref.ExportName,
+	})
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		setCondition(binding, apisv1alpha1.BinderAuthorized, metav1.ConditionFalse, "BindNotAllowed", fmt.Sprintf("User %q cannot bind APIExport %q in workspace %q.", binder.Username, ref.ExportName, ref.Workspace))
+		setCondition(binding, apisv1alpha1.BindingReady, metav1.ConditionFalse, "BinderNotAuthorized", "The binder is not authorized to bind the APIExport.")
+		return c.unbind(ctx, binding.ClusterName, binding.Name)
+	}
+	setCondition(binding, apisv1alpha1.BinderAuthorized, metav1.ConditionTrue, "BindAllowed", "The binder is authorized to bind the APIExport.")
+
+	for _, claim := range export.Spec.PermissionClaims {
+		if !claimAccepted(binding, claim) {
+			setCondition(binding, apisv1alpha1.PermissionClaimsAccepted, metav1.ConditionFalse, "ClaimNotAccepted", fmt.Sprintf("Permission claim on %q is not accepted.", crdName(claim.GroupResource)))
+			setCondition(binding, apisv1alpha1.BindingReady, metav1.ConditionFalse, "PermissionClaimsNotAccepted", "Not all permission claims of the APIExport are accepted.")
+			return c.unbind(ctx, binding.ClusterName, binding.Name)
+		}
+		// accepting a claim grants the provider access the binder must have itself
+		allowed, err := c.authorized(ctx, binder, binding.ClusterName, authorizationv1.ResourceAttributes{
+			Verb:     "*",
+			Group:    claim.Group,
+			Resource: claim.Resource,
+		})
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			setCondition(binding, apisv1alpha1.PermissionClaimsAccepted, metav1.ConditionFalse, "ClaimNotAuthorized", fmt.Sprintf("User %q cannot grant the permission claim on %q.", binder.Username, crdName(claim.GroupResource)))
+			setCondition(binding, apisv1alpha1.BindingReady, metav1.ConditionFalse, "PermissionClaimsNotAccepted", "Not all permission claims of the APIExport are accepted.")
+			return c.unbind(ctx, binding.ClusterName, binding.Name)
+		}
+	}
+	if err := c.grantClaims(ctx, binding.ClusterName, binding.Name, ProviderGroup(exportCluster, ref.ExportName), export.Spec.PermissionClaims); err != nil {
+		return err
+	}
+	setCondition(binding, apisv1alpha1.PermissionClaimsAccepted, metav1.ConditionTrue, "ClaimsAccepted", "All permission claims are accepted and granted to the provider.")
+
+	var projections []*apiextensionsv1.CustomResourceDefinition
+	var conflict string
+	for _, crd := range exported {
+		existing, err := c.crdLister.Get(clusters.ToClusterAwareKey(binding.ClusterName, crd.Name))
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		if err == nil && (!crdprojection.IsProjected(existing) || existing.Annotations[BoundByAnnotation] != binding.Name) {
+			if conflict == "" {
+				conflict = crd.Name
+			}
+			continue
+		}
+		projections = append(projections, crdprojection.Project(crd, binding.ClusterName, map[string]string{BoundByAnnotation: binding.Name}))
+		binding.Status.BoundResources = append(binding.Status.BoundResources, apisv1alpha1.GroupResource{
+			Group:    crd.Spec.Group,
+			Resource: crd.Spec.Names.Plural,
+		})
+	}
+	c.registry.Set(projectionOwner(binding.ClusterName, binding.Name), projections)
+	if conflict != "" {
+		setCondition(binding, apisv1alpha1.BindingReady, metav1.ConditionFalse, "NamingConflict", fmt.Sprintf("CustomResourceDefinition %q already exists in this workspace.", conflict))
+		return nil
+	}
+	binding.Status.Phase = apisv1alpha1.APIBindingPhaseBound
+	setCondition(binding, apisv1alpha1.BindingReady, metav1.ConditionTrue, "Bound", "All exported resources are served in this workspace.")
+	return nil
+}
+
+// unbind stops serving the APIs of the named APIBinding in the given logical cluster, and
+// revokes the permission claims granted to the provider.
+func (c *Controller) unbind(ctx context.Context, clusterName, bindingName string) error {
+	c.registry.Set(projectionOwner(clusterName, bindingName), nil)
+	return c.grantClaims(ctx, clusterName, bindingName, "", nil)
+}
+
+// projectionOwner returns the owner of the projections of an APIBinding in the registry,
+// which is shared with the owners of other kinds.
+func projectionOwner(clusterName, bindingName string) string {
+	return "apibindings/" + clusters.ToClusterAwareKey(clusterName, bindingName)
+}
+
+// grantClaims grants the provider group everything on the resources of the claims in the
+// given logical cluster, with a ClusterRole and ClusterRoleBinding named after the
+// APIBinding. No claims removes them.
+func (c *Controller) grantClaims(ctx context.Context, clusterName, bindingName, providerGroup string, claims []apisv1alpha1.PermissionClaim) error {
+	rbacClient := c.kubeClient.Cluster(clusterName).RbacV1()
+	name := PermissionClaimsPrefix + bindingName
+
+	if len(claims) == 0 {
+		if err := rbacClient.ClusterRoleBindings().Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		if err := rbacClient.ClusterRoles().Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+
+	rules := make([]rbacv1.PolicyRule, 0, len(claims))
+	for _, claim := range claims {
+		rules = append(rules, rbacv1.PolicyRule{
+			APIGroups: []string{claim.Group},
+			Resources: []string{claim.Resource},
+			Verbs:     []string{rbacv1.VerbAll},
+		})
+	}
+	role, err := rbacClient.ClusterRoles().Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		role = &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: name}, Rules: rules}
+		if _, err := rbacClient.ClusterRoles().Create(ctx, role, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("failed to create ClusterRole %q in logical cluster %q: %w", name, clusterName, err)
+		}
+	} else if err != nil {
+		return err
+	} else if !equality.Semantic.DeepEqual(role.Rules, rules) {
+		role = role.DeepCopy()
+		role.Rules = rules
+		if _, err := rbacClient.ClusterRoles().Update(ctx, role, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+	}
+
+	subjects := []rbacv1.Subject{{Kind: rbacv1.GroupKind, APIGroup: rbacv1.GroupName, Name: providerGroup}}
+	binding, err := rbacClient.ClusterRoleBindings().Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		binding = &rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Subjects:   subjects,
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: name},
+		}
+		if _, err := rbacClient.ClusterRoleBindings().Create(ctx, binding, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("failed to create ClusterRoleBinding %q in logical cluster %q: %w", name, clusterName, err)
+		}
+	} else if err != nil {
+		return err
+	} else if !equality.Semantic.DeepEqual(binding.Subjects, subjects) {
+		binding = binding.DeepCopy()
+		binding.Subjects = subjects
+		if _, err := rbacClient.ClusterRoleBindings().Update(ctx, binding, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// authorized reviews whether the user is allowed the given attributes in the given
+// logical cluster.
+func (c *Controller) authorized(ctx context.Context, u *authenticationv1.UserInfo, clusterName string, attributes authorizationv1.ResourceAttributes) (bool, error) {
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: &attributes,
+			User:               u.Username,
+			UID:                u.UID,
+			Groups:             u.Groups,
+		},
+	}
+	if len(u.Extra) > 0 {
+		review.Spec.Extra = map[string]authorizationv1.ExtraValue{}
+		for key, value := range u.Extra {
+			review.Spec.Extra[key] = authorizationv1.ExtraValue(value)
+		}
+	}
+	review, err := c.kubeClient.Cluster(clusterName).AuthorizationV1().SubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+	if err != nil {
+		return false, err
+	}
+	return review.Status.Allowed, nil
+}
+
+// crdName returns the name of the CustomResourceDefinition defining gr.
+func crdName(gr apisv1alpha1.GroupResource) string {
+	return gr.Resource + "." + gr.Group
+}
+
+func claimAccepted(binding *apisv1alpha1.APIBinding, claim apisv1alpha1.PermissionClaim) bool {
+	for _, accepted := range binding.Spec.AcceptedPermissionClaims {
+		if accepted == claim {
+			return true
+		}
+	}
+	return false
+}
+
+func setCondition(binding *apisv1alpha1.APIBinding, conditionType string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&binding.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		ObservedGeneration: binding.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
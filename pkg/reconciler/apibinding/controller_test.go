@@ -0,0 +1,347 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apibinding
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	crdlister "k8s.io/apiextensions-apiserver/pkg/client/listers/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clusters"
+
+	"github.com/kcp-dev/kcp/pkg/admission/apibinder"
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	kcpfake "github.com/kcp-dev/kcp/pkg/client/clientset/versioned/fake"
+	apislister "github.com/kcp-dev/kcp/pkg/client/listers/apis/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/crdprojection"
+	"github.com/kcp-dev/kcp/pkg/workspacenames"
+)
+
+type kcpClients struct {
+	*kcpfake.Clientset
+}
+
+func (c kcpClients) Cluster(string) kcpclient.Interface {
+	return c.Clientset
+}
+
+// kubeClients keeps a fake per logical cluster, which reviews the access of users with
+// the given rules, written as <logical cluster>/<user>/<verb>/<resource>.
+type kubeClients struct {
+	rules   []string
+	clients map[string]*kubefake.Clientset
+}
+
+func (c *kubeClients) Cluster(clusterName string) kubernetes.Interface {
+	return c.client(clusterName)
+}
+
+func (c *kubeClients) client(clusterName string) *kubefake.Clientset {
+	if client, ok := c.clients[clusterName]; ok {
+		return client
+	}
+	client := kubefake.NewSimpleClientset()
+	client.PrependReactor("create", "subjectaccessreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		review := action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview).DeepCopy()
+		attributes := review.Spec.ResourceAttributes
+		for _, rule := range c.rules {
+			if rule == clusterName+"/"+review.Spec.User+"/"+attributes.Verb+"/"+attributes.Resource {
+				review.Status.Allowed = true
+			}
+		}
+		return true, review, nil
+	})
+	c.clients[clusterName] = client
+	return client
+}
+
+// workspacePaths resolves the paths of the workspaces which exist.
+type workspacePaths map[string]string
+
+func (paths workspacePaths) Resolve(path string) (string, string, error) {
+	clusterName, ok := paths[path]
+	if !ok {
+		return "", "", workspacenames.ErrNotFound
+	}
+	return clusterName, "", nil
+}
+
+func crd(cluster, resource string) *apiextensionsv1.CustomResourceDefinition {
+	return &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: resource + ".example.dev", ClusterName: cluster},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: "example.dev",
+			Names: apiextensionsv1.CustomResourceDefinitionNames{Plural: resource},
+		},
+	}
+}
+
+func TestProcess(t *testing.T) {
+	alice := `{"username":"alice","groups":["system:authenticated"]}`
+	binding := func(binder, workspace, exportName string, accepted ...apisv1alpha1.PermissionClaim) *apisv1alpha1.APIBinding {
+		binding := &apisv1alpha1.APIBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "widgets", ClusterName: "consumer"},
+			Spec: apisv1alpha1.APIBindingSpec{
+				Reference:                apisv1alpha1.ExportReference{Workspace: workspace, ExportName: exportName},
+				AcceptedPermissionClaims: accepted,
+			},
+		}
+		if binder != "" {
+			binding.Annotations = map[string]string{apibinder.BinderAnnotation: binder}
+		}
+		return binding
+	}
+	configMaps := apisv1alpha1.PermissionClaim{GroupResource: apisv1alpha1.GroupResource{Resource: "configmaps"}}
+	exports := []*apisv1alpha1.APIExport{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "widgets", ClusterName: "provider"},
+			Spec:       apisv1alpha1.APIExportSpec{Resources: []apisv1alpha1.GroupResource{{Group: "example.dev", Resource: "widgets"}}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "gadgets", ClusterName: "provider"},
+			Spec:       apisv1alpha1.APIExportSpec{Resources: []apisv1alpha1.GroupResource{{Group: "example.dev", Resource: "gadgets"}}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "claiming", ClusterName: "provider"},
+			Spec: apisv1alpha1.APIExportSpec{
+				Resources:        []apisv1alpha1.GroupResource{{Group: "example.dev", Resource: "widgets"}},
+				PermissionClaims: []apisv1alpha1.PermissionClaim{configMaps},
+			},
+		},
+	}
+	providerCRDs := []*apiextensionsv1.CustomResourceDefinition{crd("provider", "widgets"), crd("provider", "gadgets")}
+	bindRules := []string{"provider/alice/bind/apiexports"}
+	bound := crdprojection.Project(crd("provider", "widgets"), "consumer", map[string]string{BoundByAnnotation: "widgets"})
+
+	for _, tc := range []struct {
+		name              string
+		binding           *apisv1alpha1.APIBinding
+		consumerCRDs      []*apiextensionsv1.CustomResourceDefinition
+		granted           bool
+		rules             []string
+		expectedProjected []string
+		expectedGrantedTo string
+		expectedPhase     apisv1alpha1.APIBindingPhaseType
+		expectedReason    string
+	}{
+		{
+			name:              "bind",
+			binding:           binding(alice, "org:provider", "widgets"),
+			rules:             bindRules,
+			expectedProjected: []string{"widgets.example.dev"},
+			expectedPhase:     apisv1alpha1.APIBindingPhaseBound,
+			expectedReason:    "Bound",
+		},
+		{
+			name:              "rebind",
+			binding:           binding(alice, "org:provider", "gadgets"),
+			consumerCRDs:      []*apiextensionsv1.CustomResourceDefinition{bound},
+			rules:             bindRules,
+			expectedProjected: []string{"gadgets.example.dev"},
+			expectedPhase:     apisv1alpha1.APIBindingPhaseBound,
+			expectedReason:    "Bound",
+		},
+		{
+			name:         "unbind",
+			consumerCRDs: []*apiextensionsv1.CustomResourceDefinition{bound, crd("consumer", "gadgets")},
+			granted:      true,
+		},
+		{
+			name:           "unknown workspace",
+			binding:        binding(alice, "org:unknown", "widgets"),
+			consumerCRDs:   []*apiextensionsv1.CustomResourceDefinition{bound},
+			rules:          bindRules,
+			expectedPhase:  apisv1alpha1.APIBindingPhaseBinding,
+			expectedReason: "APIExportNotFound",
+		},
+		{
+			name:           "binder not allowed to bind",
+			binding:        binding(`{"username":"mallory"}`, "org:provider", "widgets"),
+			consumerCRDs:   []*apiextensionsv1.CustomResourceDefinition{bound},
+			rules:          bindRules,
+			expectedPhase:  apisv1alpha1.APIBindingPhaseBinding,
+			expectedReason: "BinderNotAuthorized",
+		},
+		{
+			name:           "unknown binder",
+			binding:        binding("", "org:provider", "widgets"),
+			rules:          bindRules,
+			expectedPhase:  apisv1alpha1.APIBindingPhaseBinding,
+			expectedReason: "BinderNotAuthorized",
+		},
+		{
+			name:           "naming conflict",
+			binding:        binding(alice, "org:provider", "widgets"),
+			consumerCRDs:   []*apiextensionsv1.CustomResourceDefinition{crd("consumer", "widgets")},
+			rules:          bindRules,
+			expectedPhase:  apisv1alpha1.APIBindingPhaseBinding,
+			expectedReason: "NamingConflict",
+		},
+		{
+			name:           "claim not accepted",
+			binding:        binding(alice, "org:provider", "claiming"),
+			rules:          append([]string{"consumer/alice/*/configmaps"}, bindRules...),
+			expectedPhase:  apisv1alpha1.APIBindingPhaseBinding,
+			expectedReason: "PermissionClaimsNotAccepted",
+		},
+		{
+			name:           "claim withdrawn",
+			binding:        binding(alice, "org:provider", "claiming"),
+			consumerCRDs:   []*apiextensionsv1.CustomResourceDefinition{bound},
+			granted:        true,
+			rules:          append([]string{"consumer/alice/*/configmaps"}, bindRules...),
+			expectedPhase:  apisv1alpha1.APIBindingPhaseBinding,
+			expectedReason: "PermissionClaimsNotAccepted",
+		},
+		{
+			name:           "claim accepted by a binder without access",
+			binding:        binding(alice, "org:provider", "claiming", configMaps),
+			rules:          append([]string{"provider/alice/*/configmaps"}, bindRules...),
+			expectedPhase:  apisv1alpha1.APIBindingPhaseBinding,
+			expectedReason: "PermissionClaimsNotAccepted",
+		},
+		{
+			name:              "claim accepted",
+			binding:           binding(alice, "org:provider", "claiming", configMaps),
+			rules:             append([]string{"consumer/alice/*/configmaps"}, bindRules...),
+			expectedProjected: []string{"widgets.example.dev"},
+			expectedGrantedTo: "system:kcp:apiexport:provider:claiming",
+			expectedPhase:     apisv1alpha1.APIBindingPhaseBound,
+			expectedReason:    "Bound",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			apiBindingIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			var kcpObjects []runtime.Object
+			if tc.binding != nil {
+				if err := apiBindingIndexer.Add(tc.binding); err != nil {
+					t.Fatal(err)
+				}
+				kcpObjects = append(kcpObjects, tc.binding)
+			}
+			apiExportIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			for _, export := range exports {
+				if err := apiExportIndexer.Add(export); err != nil {
+					t.Fatal(err)
+				}
+			}
+			crdIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			registry := crdprojection.NewRegistry()
+			for _, crd := range append(append([]*apiextensionsv1.CustomResourceDefinition{}, providerCRDs...), tc.consumerCRDs...) {
+				if err := crdIndexer.Add(crd); err != nil {
+					t.Fatal(err)
+				}
+				if crd.Annotations[BoundByAnnotation] == "widgets" {
+					registry.Set(projectionOwner("consumer", "widgets"), []*apiextensionsv1.CustomResourceDefinition{crd})
+				}
+			}
+			kubeClient := &kubeClients{rules: tc.rules, clients: map[string]*kubefake.Clientset{}}
+			if tc.granted {
+				name := PermissionClaimsPrefix + "widgets"
+				tracker := kubeClient.client("consumer").Tracker()
+				if err := tracker.Add(&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: name}}); err != nil {
+					t.Fatal(err)
+				}
+				if err := tracker.Add(&rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: name}}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			kcpClient := kcpClients{Clientset: kcpfake.NewSimpleClientset(kcpObjects...)}
+			c := &Controller{
+				kcpClient:         kcpClient,
+				kubeClient:        kubeClient,
+				workspacePaths:    workspacePaths{"org:provider": "provider"},
+				registry:          registry,
+				apiBindingIndexer: apiBindingIndexer,
+				apiBindingLister:  apislister.NewAPIBindingLister(apiBindingIndexer),
+				apiExportLister:   apislister.NewAPIExportLister(apiExportIndexer),
+				crdLister:         crdlister.NewCustomResourceDefinitionLister(crdIndexer),
+			}
+
+			if err := c.process(context.Background(), clusters.ToClusterAwareKey("consumer", "widgets")); err != nil {
+				t.Fatal(err)
+			}
+
+			projections, _ := registry.Projections()
+			var projected []string
+			for _, crd := range projections {
+				if crd.ClusterName != "consumer" || crd.Annotations[BoundByAnnotation] != "widgets" || crd.Annotations[crdprojection.ProjectedFromAnnotation] != "provider" {
+					t.Errorf("expected crd %q to be projected from the provider for the binding, got %s with %v", crd.Name, crd.ClusterName, crd.Annotations)
+				}
+				projected = append(projected, crd.Name)
+			}
+			sort.Strings(projected)
+			if diff := cmp.Diff(tc.expectedProjected, projected); diff != "" {
+				t.Errorf("unexpected projected crds (-want +got):\n%s", diff)
+			}
+
+			rbacClient := kubeClient.client("consumer").RbacV1()
+			name := PermissionClaimsPrefix + "widgets"
+			roleBinding, err := rbacClient.ClusterRoleBindings().Get(context.Background(), name, metav1.GetOptions{})
+			if tc.expectedGrantedTo == "" {
+				if !apierrors.IsNotFound(err) {
+					t.Errorf("expected no permission claims to be granted, got %v, %v", roleBinding, err)
+				}
+			} else {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if diff := cmp.Diff([]rbacv1.Subject{{Kind: rbacv1.GroupKind, APIGroup: rbacv1.GroupName, Name: tc.expectedGrantedTo}}, roleBinding.Subjects); diff != "" {
+					t.Errorf("unexpected subjects granted the permission claims (-want +got):\n%s", diff)
+				}
+				role, err := rbacClient.ClusterRoles().Get(context.Background(), name, metav1.GetOptions{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if diff := cmp.Diff([]rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"*"}}}, role.Rules); diff != "" {
+					t.Errorf("unexpected permission claims granted (-want +got):\n%s", diff)
+				}
+			}
+
+			if tc.binding == nil {
+				return
+			}
+			var status apisv1alpha1.APIBindingStatus
+			for _, action := range kcpClient.Actions() {
+				if update, ok := action.(clienttesting.UpdateAction); ok && update.GetSubresource() == "status" {
+					status = update.GetObject().(*apisv1alpha1.APIBinding).Status
+				}
+			}
+			if status.Phase != tc.expectedPhase {
+				t.Errorf("expected phase %q, got %q", tc.expectedPhase, status.Phase)
+			}
+			if ready := meta.FindStatusCondition(status.Conditions, apisv1alpha1.BindingReady); ready == nil || ready.Reason != tc.expectedReason {
+				t.Errorf("expected the Ready condition with reason %q, got %+v", tc.expectedReason, ready)
+			}
+		})
+	}
+}
@@ -0,0 +1,288 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiinheritance
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	crdinformer "k8s.io/apiextensions-apiserver/pkg/client/informers/externalversions/apiextensions/v1"
+	crdlister "k8s.io/apiextensions-apiserver/pkg/client/listers/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clusters"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	"github.com/kcp-dev/kcp/pkg/apis/tenancy/helpers/logicalcluster"
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	tenancyinformer "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/tenancy/v1alpha1"
+	tenancylister "github.com/kcp-dev/kcp/pkg/client/listers/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/crdprojection"
+	"github.com/kcp-dev/kcp/pkg/indexers"
+)
+
+const (
+	inheritFromIndex = "inheritFrom"
+	clusterIndex     = "cluster"
+	controllerName   = "apiinheritance"
+
+	// InheritedFromAnnotation is set on the CustomResourceDefinitions that are projected
+	// into a workspace from the workspace named in its spec.inheritFrom. Its value is the
+	// name of that workspace.
+	InheritedFromAnnotation = "tenancy.kcp.dev/inherited-from"
+)
+
+// NewController returns a controller which resolves spec.inheritFrom of Workspaces: the
+// CustomResourceDefinitions of the workspace inherited from are projected into the logical
+// cluster of the inheriting workspace through the registry, so that the apiextensions
+// server of that logical cluster serves the inherited types from their definition in the
+// parent workspace. Nothing is copied, so the inherited CRDs follow their source and are
+// gone with it.
+//
+// Inheritance is not transitive, and CRDs created directly in the inheriting workspace take
+// precedence over inherited ones of the same name.
+func NewController(
+	workspaceInformer tenancyinformer.WorkspaceInformer,
+	crdInformer crdinformer.CustomResourceDefinitionInformer,
+	registry *crdprojection.Registry,
+) (*Controller, error) {
+	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+
+	c := &Controller{
+		queue:            queue,
+		registry:         registry,
+		workspaceIndexer: workspaceInformer.Informer().GetIndexer(),
+		workspaceLister:  workspaceInformer.Lister(),
+		crdIndexer:       crdInformer.Informer().GetIndexer(),
+		crdLister:        crdInformer.Lister(),
+		syncChecks: []cache.InformerSynced{
+			workspaceInformer.Informer().HasSynced,
+			crdInformer.Informer().HasSynced,
+		},
+	}
+
+	workspaceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { c.enqueue(obj) },
+		UpdateFunc: func(_, obj interface{}) { c.enqueue(obj) },
+		DeleteFunc: func(obj interface{}) { c.enqueue(obj) },
+	})
+	if err := indexers.AddIfNotPresent(c.workspaceIndexer, cache.Indexers{
+		inheritFromIndex: func(obj interface{}) ([]string, error) {
+			if workspace, ok := obj.(*tenancyv1alpha1.Workspace); ok && workspace.Spec.InheritFrom != "" {
+				return []string{clusters.ToClusterAwareKey(workspace.ClusterName, workspace.Spec.InheritFrom)}, nil
+			}
+			return []string{}, nil
+		},
+		indexers.WorkspaceCluster: indexers.IndexWorkspaceByCluster,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to add indexer for Workspace: %w", err)
+	}
+
+	crdInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { c.enqueueCRD(obj) },
+		UpdateFunc: func(_, obj interface{}) { c.enqueueCRD(obj) },
+		DeleteFunc: func(obj interface{}) { c.enqueueCRD(obj) },
+	})
+	if err := c.crdIndexer.AddIndexers(map[string]cache.IndexFunc{
+		clusterIndex: func(obj interface{}) ([]string, error) {
+			if crd, ok := obj.(*apiextensionsv1.CustomResourceDefinition); ok {
+				return []string{crd.ClusterName}, nil
+			}
+			return []string{}, nil
+		},
+	}); err != nil {
+		return nil, fmt.Errorf("failed to add indexer for CustomResourceDefinition: %w", err)
+	}
+
+	return c, nil
+}
+
+// Controller watches Workspaces and CustomResourceDefinitions in order to serve inherited
+// APIs in every Workspace which has spec.inheritFrom set.
+type Controller struct {
+	queue workqueue.RateLimitingInterface
+
+	registry *crdprojection.Registry
+
+	workspaceIndexer cache.Indexer
+	workspaceLister  tenancylister.WorkspaceLister
+
+	crdIndexer cache.Indexer
+	crdLister  crdlister.CustomResourceDefinitionLister
+
+	syncChecks []cache.InformerSynced
+}
+
+func (c *Controller) enqueue(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	klog.V(4).Infof("queueing workspace %q", key)
+	c.queue.Add(key)
+}
+
+// enqueueCRD queues the workspaces inheriting from the logical cluster of a changed CRD,
+// and the workspaces of that logical cluster, whose own CRDs shadow inherited ones.
+func (c *Controller) enqueueCRD(obj interface{}) {
+	crd, ok := obj.(*apiextensionsv1.CustomResourceDefinition)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			klog.V(2).Infof("Couldn't get object from tombstone %#v", obj)
+			return
+		}
+		crd, ok = tombstone.Obj.(*apiextensionsv1.CustomResourceDefinition)
+		if !ok {
+			klog.V(2).Infof("Tombstone contained object that is not a CustomResourceDefinition: %#v", obj)
+			return
+		}
+	}
+	if crdprojection.IsProjected(crd) {
+		return
+	}
+	workspaces, err := c.workspaceIndexer.ByIndex(indexers.WorkspaceCluster, crd.ClusterName)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	for _, obj := range workspaces {
+		c.enqueue(obj)
+		source := obj.(*tenancyv1alpha1.Workspace)
+		inheriting, err := c.workspaceIndexer.ByIndex(inheritFromIndex, clusters.ToClusterAwareKey(source.ClusterName, source.Name))
+		if err != nil {
+			runtime.HandleError(err)
+			return
+		}
+		for _, workspace := range inheriting {
+			c.enqueue(workspace)
+		}
+	}
+}
+
+func (c *Controller) Start(ctx context.Context, numThreads int) {
+	defer runtime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	klog.Info("Starting API inheritance controller")
+	defer klog.Info("Shutting down API inheritance controller")
+
+	if !cache.WaitForNamedCacheSync(controllerName, ctx.Done(), c.syncChecks...) {
+		klog.Warning("Failed to wait for caches to sync")
+		return
+	}
+
+	for i := 0; i < numThreads; i++ {
+		go wait.Until(func() { c.startWorker(ctx) }, time.Second, ctx.Done())
+	}
+
+	<-ctx.Done()
+}
+
+func (c *Controller) startWorker(ctx context.Context) {
+	for c.processNextWorkItem(ctx) {
+	}
+}
+
+func (c *Controller) processNextWorkItem(ctx context.Context) bool {
+	// Wait until there is a new item in the working queue
+	k, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	key := k.(string)
+
+	// No matter what, tell the queue we're done with this key, to unblock
+	// other workers.
+	defer c.queue.Done(key)
+
+	if err := c.process(ctx, key); err != nil {
+		runtime.HandleError(fmt.Errorf("%q controller failed to sync %q, err: %w", controllerName, key, err))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	c.queue.Forget(key)
+	return true
+}
+
+func (c *Controller) process(ctx context.Context, key string) error {
+	workspace, err := c.workspaceLister.Get(key)
+	if errors.IsNotFound(err) {
+		c.registry.Set(key, nil)
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if workspace.Status.Cluster == "" {
+		return nil // the workspace controller hasn't assigned its logical cluster yet
+	}
+	source := workspace.Spec.InheritFrom
+	if workspace.DeletionTimestamp != nil || source == "" {
+		// a renamed workspace holding the same logical cluster projects the CRDs on its own
+		c.registry.Set(key, nil)
+		return nil
+	}
+
+	sourceWorkspace, err := c.workspaceLister.Get(clusters.ToClusterAwareKey(workspace.ClusterName, source))
+	if errors.IsNotFound(err) {
+		c.registry.Set(key, nil)
+		return nil
+	} else if err != nil {
+		return err
+	}
+	projections, err := c.projections(workspace.Status.Cluster, source, logicalcluster.Of(sourceWorkspace))
+	if err != nil {
+		return err
+	}
+	c.registry.Set(key, projections)
+	return nil
+}
+
+// projections returns the CRDs defined in the logical cluster of the source workspace,
+// projected into the target logical cluster, but for the ones the target defines itself.
+func (c *Controller) projections(target, source, sourceCluster string) ([]*apiextensionsv1.CustomResourceDefinition, error) {
+	if sourceCluster == target {
+		return nil, nil
+	}
+	sourceCRDs, err := c.crdIndexer.ByIndex(clusterIndex, sourceCluster)
+	if err != nil {
+		return nil, err
+	}
+	var projections []*apiextensionsv1.CustomResourceDefinition
+	for _, obj := range sourceCRDs {
+		crd := obj.(*apiextensionsv1.CustomResourceDefinition)
+		if crdprojection.IsProjected(crd) || crd.DeletionTimestamp != nil {
+			continue
+		}
+		existing, err := c.crdLister.Get(clusters.ToClusterAwareKey(target, crd.Name))
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, err
+		}
+		if err == nil && !crdprojection.IsProjected(existing) {
+			klog.V(2).Infof("workspace %q defines crd %q itself, not inheriting it from %q", target, crd.Name, source)
+			continue
+		}
+		projections = append(projections, crdprojection.Project(crd, target, map[string]string{InheritedFromAnnotation: source}))
+	}
+	return projections, nil
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiinheritance
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	crdlister "k8s.io/apiextensions-apiserver/pkg/client/listers/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clusters"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	tenancylister "github.com/kcp-dev/kcp/pkg/client/listers/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/crdprojection"
+	"github.com/kcp-dev/kcp/pkg/indexers"
+)
+
+func crd(cluster, name string) *apiextensionsv1.CustomResourceDefinition {
+	return &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: name, ClusterName: cluster},
+		Spec:       apiextensionsv1.CustomResourceDefinitionSpec{Group: "example.dev"},
+	}
+}
+
+func TestProcess(t *testing.T) {
+	deleted := metav1.Now()
+	source := &tenancyv1alpha1.Workspace{ObjectMeta: metav1.ObjectMeta{Name: "platform", ClusterName: "org"}, Status: tenancyv1alpha1.WorkspaceStatus{Cluster: "p1a2t3f4"}}
+	inheriting := &tenancyv1alpha1.Workspace{ObjectMeta: metav1.ObjectMeta{Name: "team", ClusterName: "org"}, Spec: tenancyv1alpha1.WorkspaceSpec{InheritFrom: "platform"}, Status: tenancyv1alpha1.WorkspaceStatus{Cluster: "k7c2q9x4"}}
+	deleting := inheriting.DeepCopy()
+	deleting.DeletionTimestamp = &deleted
+	notInheriting := inheriting.DeepCopy()
+	notInheriting.Spec.InheritFrom = ""
+	deletedSource := crd("p1a2t3f4", "things.example.dev")
+	deletedSource.DeletionTimestamp = &deleted
+	sourceCRDs := []*apiextensionsv1.CustomResourceDefinition{
+		crd("p1a2t3f4", "widgets.example.dev"),
+		crd("p1a2t3f4", "gadgets.example.dev"),
+		deletedSource,
+		crdprojection.Project(crd("r0o0t0c0", "parts.example.dev"), "p1a2t3f4", nil),
+	}
+
+	for _, tc := range []struct {
+		name       string
+		workspaces []*tenancyv1alpha1.Workspace
+		crds       []*apiextensionsv1.CustomResourceDefinition
+		expected   []string
+	}{
+		{
+			name:       "inherit",
+			workspaces: []*tenancyv1alpha1.Workspace{inheriting},
+			crds:       append(sourceCRDs, crd("k7c2q9x4", "gadgets.example.dev")),
+			expected:   []string{clusters.ToClusterAwareKey("k7c2q9x4", "widgets.example.dev")},
+		},
+		{
+			name:       "stop inheriting",
+			workspaces: []*tenancyv1alpha1.Workspace{notInheriting},
+			crds:       sourceCRDs,
+		},
+		{
+			name:       "delete",
+			workspaces: []*tenancyv1alpha1.Workspace{deleting},
+			crds:       sourceCRDs,
+		},
+		{
+			name: "missing source",
+			workspaces: []*tenancyv1alpha1.Workspace{{
+				ObjectMeta: metav1.ObjectMeta{Name: "team", ClusterName: "org"},
+				Spec:       tenancyv1alpha1.WorkspaceSpec{InheritFrom: "missing"},
+				Status:     tenancyv1alpha1.WorkspaceStatus{Cluster: "k7c2q9x4"},
+			}},
+			crds: sourceCRDs,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			workspaceIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{indexers.WorkspaceCluster: indexers.IndexWorkspaceByCluster})
+			for _, workspace := range append([]*tenancyv1alpha1.Workspace{source}, tc.workspaces...) {
+				if err := workspaceIndexer.Add(workspace); err != nil {
+					t.Fatal(err)
+				}
+			}
+			crdIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{clusterIndex: func(obj interface{}) ([]string, error) {
+				return []string{obj.(*apiextensionsv1.CustomResourceDefinition).ClusterName}, nil
+			}})
+			for _, crd := range tc.crds {
+				if err := crdIndexer.Add(crd); err != nil {
+					t.Fatal(err)
+				}
+			}
+			key := clusters.ToClusterAwareKey("org", "team")
+			registry := crdprojection.NewRegistry()
+			// projections left from a previous reconciliation are replaced
+			registry.Set(key, []*apiextensionsv1.CustomResourceDefinition{crdprojection.Project(crd("p1a2t3f4", "gone.example.dev"), "k7c2q9x4", nil)})
+			c := &Controller{
+				registry:         registry,
+				workspaceIndexer: workspaceIndexer,
+				workspaceLister:  tenancylister.NewWorkspaceLister(workspaceIndexer),
+				crdIndexer:       crdIndexer,
+				crdLister:        crdlister.NewCustomResourceDefinitionLister(crdIndexer),
+			}
+
+			if err := c.process(context.Background(), key); err != nil {
+				t.Fatal(err)
+			}
+
+			projections, _ := registry.Projections()
+			var projected []string
+			for key, crd := range projections {
+				if crd.Annotations[InheritedFromAnnotation] != "platform" || crd.Annotations[crdprojection.ProjectedFromAnnotation] != "p1a2t3f4" {
+					t.Errorf("expected crd %q to be annotated as inherited from platform, got %v", key, crd.Annotations)
+				}
+				projected = append(projected, key)
+			}
+			sort.Strings(projected)
+			if diff := cmp.Diff(tc.expected, projected); diff != "" {
+				t.Errorf("unexpected projected crds (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiresource
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	apiresourcev1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apiresource/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/schemacompat"
+)
+
+// checkSpecCompatibility checks that the parts of an imported API resource other than its
+// schema are compatible with the negotiated API resource: the scope and kind must be the
+// same, and the subresources of the negotiated API resource must be served.
+func checkSpecCompatibility(negotiated, imported apiresourcev1alpha1.CommonAPIResourceSpec) error {
+	if negotiated.Scope != imported.Scope {
+		return fmt.Errorf("scope %q is not the negotiated scope %q", imported.Scope, negotiated.Scope)
+	}
+	if negotiated.Kind != imported.Kind {
+		return fmt.Errorf("kind %q is not the negotiated kind %q", imported.Kind, negotiated.Kind)
+	}
+	if negotiated.ListKind != "" && imported.ListKind != "" && negotiated.ListKind != imported.ListKind {
+		return fmt.Errorf("list kind %q is not the negotiated list kind %q", imported.ListKind, negotiated.ListKind)
+	}
+
+	importedSubResources := sets.NewString()
+	for _, subResource := range imported.SubResources {
+		importedSubResources.Insert(subResource.Name)
+	}
+	missing := sets.NewString()
+	for _, subResource := range negotiated.SubResources {
+		if !importedSubResources.Has(subResource.Name) {
+			missing.Insert(subResource.Name)
+		}
+	}
+	if missing.Len() > 0 {
+		return fmt.Errorf("negotiated subresources %v are not served", missing.List())
+	}
+	return nil
+}
+
+// setNarrowing records in the status of an imported API resource the fields its schema
+// has, but which the schema of the negotiated API resource drops or narrows: these can't be
+// used in kcp, and so are never synced to the location. Incompatible imports don't report
+// any.
+func setNarrowing(imported *apiresourcev1alpha1.APIResourceImport, negotiated *apiresourcev1alpha1.NegotiatedAPIResource) error {
+	imported.Status.DroppedFields, imported.Status.NarrowedFields = nil, nil
+	if negotiated == nil || !imported.IsConditionTrue(apiresourcev1alpha1.Compatible) {
+		return nil
+	}
+	importedSchema, err := imported.Spec.GetSchema()
+	if err != nil {
+		return err
+	}
+	negotiatedSchema, err := negotiated.Spec.GetSchema()
+	if err != nil {
+		return err
+	}
+	imported.Status.DroppedFields, imported.Status.NarrowedFields = schemacompat.Narrowing(importedSchema, negotiatedSchema)
+	return nil
+}
+
+// updateIncompatibilities records the incompatibilities of the checked locations in the
+// status of the negotiated API resource, a nil incompatibility meaning the location is
+// compatible. If all is true, the other locations are not imported anymore and are
+// removed from the incompatibilities.
+func (c *Controller) updateIncompatibilities(ctx context.Context, negotiated *apiresourcev1alpha1.NegotiatedAPIResource, incompatibilities map[string]*apiresourcev1alpha1.Incompatibility, all bool) error {
+	if negotiated == nil {
+		return nil
+	}
+	negotiated = negotiated.DeepCopy()
+	if all {
+		for _, existing := range negotiated.Status.Incompatibilities {
+			if _, checked := incompatibilities[existing.Location]; !checked {
+				incompatibilities[existing.Location] = nil
+			}
+		}
+	}
+
+	locations := make([]string, 0, len(incompatibilities))
+	for location := range incompatibilities {
+		locations = append(locations, location)
+	}
+	sort.Strings(locations)
+	changed := false
+	for _, location := range locations {
+		if negotiated.SetIncompatibility(location, incompatibilities[location]) {
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	_, err := c.kcpClient.ApiresourceV1alpha1().NegotiatedAPIResources().UpdateStatus(ctx, negotiated, metav1.UpdateOptions{})
+	return err
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiresource
+
+import (
+	"testing"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+
+	apiresourcev1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apiresource/v1alpha1"
+)
+
+func TestCheckSpecCompatibility(t *testing.T) {
+	spec := func(mutate func(spec *apiresourcev1alpha1.CommonAPIResourceSpec)) apiresourcev1alpha1.CommonAPIResourceSpec {
+		spec := apiresourcev1alpha1.CommonAPIResourceSpec{
+			Scope: apiextensionsv1.NamespaceScoped,
+			CustomResourceDefinitionNames: apiextensionsv1.CustomResourceDefinitionNames{
+				Plural:   "deployments",
+				Kind:     "Deployment",
+				ListKind: "DeploymentList",
+			},
+			SubResources: apiresourcev1alpha1.SubResources{{Name: "status"}},
+		}
+		if mutate != nil {
+			mutate(&spec)
+		}
+		return spec
+	}
+
+	for _, tt := range []struct {
+		name       string
+		imported   apiresourcev1alpha1.CommonAPIResourceSpec
+		compatible bool
+	}{
+		{name: "same", imported: spec(nil), compatible: true},
+		{name: "more subresources", imported: spec(func(spec *apiresourcev1alpha1.CommonAPIResourceSpec) {
+			spec.SubResources = append(spec.SubResources, apiresourcev1alpha1.SubResource{Name: "scale"})
+		}), compatible: true},
+		{name: "no list kind", imported: spec(func(spec *apiresourcev1alpha1.CommonAPIResourceSpec) {
+			spec.ListKind = ""
+		}), compatible: true},
+		{name: "other scope", imported: spec(func(spec *apiresourcev1alpha1.CommonAPIResourceSpec) {
+			spec.Scope = apiextensionsv1.ClusterScoped
+		})},
+		{name: "other kind", imported: spec(func(spec *apiresourcev1alpha1.CommonAPIResourceSpec) {
+			spec.Kind = "Deploy"
+		})},
+		{name: "missing subresource", imported: spec(func(spec *apiresourcev1alpha1.CommonAPIResourceSpec) {
+			spec.SubResources = nil
+		})},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkSpecCompatibility(spec(nil), tt.imported)
+			if compatible := err == nil; compatible != tt.compatible {
+				t.Errorf("expected compatible %v, got error %v", tt.compatible, err)
+			}
+		})
+	}
+}
@@ -22,6 +22,7 @@ import (
 
 	crdhelpers "k8s.io/apiextensions-apiserver/pkg/apihelpers"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/runtime"
@@ -455,8 +456,11 @@ func (c *Controller) ensureAPIResourceCompatibility(ctx context.Context, cluster
 	}
 
 	var apiResourceImportUpdateStatusFuncs []func() error
+	incompatibilities := map[string]*apiresourcev1alpha1.Incompatibility{}
 
 	for _, apiResourceImport := range apiResourcesImports {
+		location := apiResourceImport.Spec.Location
+		incompatibilities[location] = nil
 		if newNegotiatedAPIResource == nil {
 			newNegotiatedAPIResource = &apiresourcev1alpha1.NegotiatedAPIResource{
 				ObjectMeta: metav1.ObjectMeta{
@@ -486,8 +490,6 @@ func (c *Controller) ensureAPIResourceCompatibility(ctx context.Context, cluster
 			allowUpdateNegotiatedSchema := !newNegotiatedAPIResource.IsConditionTrue(apiresourcev1alpha1.Enforced) &&
 				apiResourceImport.Spec.SchemaUpdateStrategy.CanUpdate(newNegotiatedAPIResource.IsConditionTrue(apiresourcev1alpha1.Published))
 
-			// TODO Also check compatibility of non-schema things like group, names, short names, category, resourcescope, subresources, colums etc...
-
 			importSchema, err := apiResourceImport.Spec.GetSchema()
 			if err != nil {
 				klog.Errorf("Error in %s: %v", runtime.GetCaller(), err)
@@ -500,15 +502,25 @@ func (c *Controller) ensureAPIResourceCompatibility(ctx context.Context, cluster
 			}
 
 			apiResourceImport = apiResourceImport.DeepCopy()
-			lcd, err := schemacompat.EnsureStructuralSchemaCompatibility(field.NewPath(newNegotiatedAPIResource.Spec.Kind), negotiatedSchema, importSchema, allowUpdateNegotiatedSchema)
+			var lcd *apiextensionsv1.JSONSchemaProps
+			reason := "IncompatibleSpec"
+			err = checkSpecCompatibility(newNegotiatedAPIResource.Spec.CommonAPIResourceSpec, apiResourceImport.Spec.CommonAPIResourceSpec)
+			if err == nil {
+				reason = "IncompatibleSchema"
+				lcd, err = schemacompat.EnsureStructuralSchemaCompatibility(field.NewPath(newNegotiatedAPIResource.Spec.Kind), negotiatedSchema, importSchema, allowUpdateNegotiatedSchema)
+			}
 			if err != nil {
 				apiResourceImport.SetCondition(apiresourcev1alpha1.APIResourceImportCondition{
-					Type:   apiresourcev1alpha1.Compatible,
-					Status: metav1.ConditionFalse,
-					Reason: "IncompatibleSchema",
-					// TODO: improve error message.
+					Type:    apiresourcev1alpha1.Compatible,
+					Status:  metav1.ConditionFalse,
+					Reason:  reason,
 					Message: err.Error(),
 				})
+				incompatibilities[location] = &apiresourcev1alpha1.Incompatibility{
+					Location: location,
+					Reason:   reason,
+					Message:  err.Error(),
+				}
 			} else {
 				apiResourceImport.SetCondition(apiresourcev1alpha1.APIResourceImportCondition{
 					Type:    apiresourcev1alpha1.Compatible,
@@ -544,6 +556,10 @@ func (c *Controller) ensureAPIResourceCompatibility(ctx context.Context, cluster
 				return err
 			}
 			apiResourceImport.SetResourceVersion(lastOne.GetResourceVersion())
+			if err := setNarrowing(apiResourceImport, newNegotiatedAPIResource); err != nil {
+				klog.Errorf("Error in %s: %v", runtime.GetCaller(), err)
+				return err
+			}
 			if _, err := c.kcpClient.ApiresourceV1alpha1().APIResourceImports().UpdateStatus(ctx, apiResourceImport, metav1.UpdateOptions{}); err != nil {
 				klog.Errorf("Error in %s: %v", runtime.GetCaller(), err)
 				return err
@@ -551,6 +567,7 @@ func (c *Controller) ensureAPIResourceCompatibility(ctx context.Context, cluster
 			return nil
 		})
 	}
+	current := negotiatedAPIResource
 	if negotiatedAPIResource == nil {
 		existing, err := c.kcpClient.ApiresourceV1alpha1().NegotiatedAPIResources().Create(ctx, newNegotiatedAPIResource, metav1.CreateOptions{})
 		if k8serrors.IsAlreadyExists(err) {
@@ -562,18 +579,25 @@ func (c *Controller) ensureAPIResourceCompatibility(ctx context.Context, cluster
 		}
 		if len(newNegotiatedAPIResource.Status.Conditions) > 0 {
 			existing.Status = newNegotiatedAPIResource.Status
-			_, err = c.kcpClient.ApiresourceV1alpha1().NegotiatedAPIResources().UpdateStatus(ctx, existing, metav1.UpdateOptions{})
+			existing, err = c.kcpClient.ApiresourceV1alpha1().NegotiatedAPIResources().UpdateStatus(ctx, existing, metav1.UpdateOptions{})
 			if err != nil {
 				klog.Errorf("Error in %s: %v", runtime.GetCaller(), err)
 				return err
 			}
 		}
+		current = existing
 	} else if updatedNegotiatedSchema {
-		if _, err := c.kcpClient.ApiresourceV1alpha1().NegotiatedAPIResources().Update(ctx, newNegotiatedAPIResource, metav1.UpdateOptions{}); err != nil {
+		if current, err = c.kcpClient.ApiresourceV1alpha1().NegotiatedAPIResources().Update(ctx, newNegotiatedAPIResource, metav1.UpdateOptions{}); err != nil {
 			klog.Errorf("Error in %s: %v", runtime.GetCaller(), err)
 			return err
 		}
 	}
+	// When all the imports were checked, the locations which aren't imported anymore are
+	// not incompatible anymore either.
+	if err := c.updateIncompatibilities(ctx, current, incompatibilities, apiResourceImport == nil); err != nil {
+		klog.Errorf("Error in %s: %v", runtime.GetCaller(), err)
+		return err
+	}
 	for _, apiResourceImportUpdateStatusFunc := range apiResourceImportUpdateStatusFuncs {
 		if err := apiResourceImportUpdateStatusFunc(); err != nil {
 			return err
@@ -788,25 +812,34 @@ func (c *Controller) publishNegotiatedResource(ctx context.Context, clusterName
 	return nil
 }
 
-// updateStatusOnRelatedAPIResourceImports udates the status of related compatible APIResourceImports, to set the `Available` condition to `true`
+// updateStatusOnRelatedAPIResourceImports udates the status of related compatible APIResourceImports, to set the `Available` condition to `true`,
+// and to report the fields the negotiated schema drops or narrows
 func (c *Controller) updateStatusOnRelatedAPIResourceImports(ctx context.Context, clusterName string, gvr metav1.GroupVersionResource, negotiatedApiResource *apiresourcev1alpha1.NegotiatedAPIResource) error {
 	publishedCondition := negotiatedApiResource.FindCondition(apiresourcev1alpha1.Published)
-	if publishedCondition != nil {
-		objs, err := c.apiResourceImportIndexer.ByIndex(clusterNameAndGVRIndexName, GetClusterNameAndGVRIndexKey(clusterName, gvr))
-		if err != nil {
-			klog.Errorf("Error in %s: %v", runtime.GetCaller(), err)
-			return err
-		}
-		for _, obj := range objs {
-			apiResourceImport := obj.(*apiresourcev1alpha1.APIResourceImport).DeepCopy()
+	objs, err := c.apiResourceImportIndexer.ByIndex(clusterNameAndGVRIndexName, GetClusterNameAndGVRIndexKey(clusterName, gvr))
+	if err != nil {
+		klog.Errorf("Error in %s: %v", runtime.GetCaller(), err)
+		return err
+	}
+	for _, obj := range objs {
+		original := obj.(*apiresourcev1alpha1.APIResourceImport)
+		apiResourceImport := original.DeepCopy()
+		if publishedCondition != nil {
 			apiResourceImport.SetCondition(apiresourcev1alpha1.APIResourceImportCondition{
 				Type:   apiresourcev1alpha1.Available,
 				Status: publishedCondition.Status,
 			})
-			if _, err := c.kcpClient.ApiresourceV1alpha1().APIResourceImports().UpdateStatus(ctx, apiResourceImport, metav1.UpdateOptions{}); err != nil {
-				klog.Errorf("Error in %s: %v", runtime.GetCaller(), err)
-				return err
-			}
+		}
+		if err := setNarrowing(apiResourceImport, negotiatedApiResource); err != nil {
+			klog.Errorf("Error in %s: %v", runtime.GetCaller(), err)
+			return err
+		}
+		if equality.Semantic.DeepEqual(original.Status, apiResourceImport.Status) {
+			continue
+		}
+		if _, err := c.kcpClient.ApiresourceV1alpha1().APIResourceImports().UpdateStatus(ctx, apiResourceImport, metav1.UpdateOptions{}); err != nil {
+			klog.Errorf("Error in %s: %v", runtime.GetCaller(), err)
+			return err
 		}
 	}
 	return nil
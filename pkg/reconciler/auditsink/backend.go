@@ -0,0 +1,227 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auditsink
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/util/sets"
+	auditinternal "k8s.io/apiserver/pkg/apis/audit"
+	auditv1 "k8s.io/apiserver/pkg/apis/audit/v1"
+	"k8s.io/apiserver/pkg/audit"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/apiserver/pkg/util/webhook"
+	"k8s.io/apiserver/plugin/pkg/audit/buffered"
+	auditwebhook "k8s.io/apiserver/plugin/pkg/audit/webhook"
+	"k8s.io/client-go/rest"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+)
+
+// ClusterAnnotation is the audit annotation holding the logical cluster of a request.
+const ClusterAnnotation = "tenancy.kcp.dev/cluster"
+
+// batchConfig bounds the events buffered for each sink: once the buffer of a slow or
+// unavailable webhook is full, its events are dropped rather than slowing requests down.
+var batchConfig = buffered.BatchConfig{
+	BufferSize:     1000,
+	MaxBatchSize:   100,
+	MaxBatchWait:   5 * time.Second,
+	ThrottleEnable: true,
+	ThrottleQPS:    1,
+	ThrottleBurst:  5,
+	AsyncDelegate:  true,
+}
+
+// WithClusterAnnotation records the logical cluster of the request in its audit event, so
+// that the event is sent to the sinks of that logical cluster.
+func WithClusterAnnotation(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := audit.WithAuditAnnotations(req.Context())
+		if cluster := genericapirequest.ClusterFrom(ctx); cluster != nil && !cluster.Wildcard {
+			audit.AddAuditAnnotation(ctx, ClusterAnnotation, cluster.Name)
+		}
+		handler.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
+
+// Backend is an audit backend sending the events of each logical cluster to the AuditSinks
+// of that logical cluster only.
+type Backend struct {
+	lock   sync.RWMutex
+	sinks  map[string]map[string]*sink
+	stopCh <-chan struct{}
+}
+
+// NewBackend returns a Backend without any sink until they are set by the controller.
+func NewBackend() *Backend {
+	return &Backend{sinks: map[string]map[string]*sink{}}
+}
+
+var _ audit.Backend = &Backend{}
+
+// sink is the filter and the buffered webhook backend of an AuditSink.
+type sink struct {
+	level     auditinternal.Level
+	verbs     sets.String
+	resources sets.String
+	backend   audit.Backend
+}
+
+func newSink(spec tenancyv1alpha1.AuditSinkSpec) (*sink, error) {
+	config := &rest.Config{
+		Host:            spec.Webhook.URL,
+		TLSClientConfig: rest.TLSClientConfig{CAData: spec.Webhook.CABundle},
+		Timeout:         30 * time.Second,
+		// events are throttled by the buffered backend instead
+		QPS: -1,
+	}
+	codec := audit.Codecs.LegacyCodec(auditv1.SchemeGroupVersion)
+	config.ContentConfig.NegotiatedSerializer = serializer.NegotiatedSerializerWrapper(runtime.SerializerInfo{Serializer: codec})
+	client, err := rest.UnversionedRESTClientFor(config)
+	if err != nil {
+		return nil, err
+	}
+
+	level := auditinternal.Level(spec.Level)
+	if level == "" {
+		level = auditinternal.LevelMetadata
+	}
+	backend := auditwebhook.NewDynamicBackend(client, webhook.DefaultRetryBackoffWithInitialDelay(auditwebhook.DefaultInitialBackoffDelay))
+	return &sink{
+		level:     level,
+		verbs:     sets.NewString(spec.Verbs...),
+		resources: sets.NewString(spec.Resources...),
+		backend:   buffered.NewBackend(backend, batchConfig),
+	}, nil
+}
+
+// matches returns whether the event passes the filters of the sink.
+func (s *sink) matches(ev *auditinternal.Event) bool {
+	if s.verbs.Len() > 0 && !s.verbs.Has(ev.Verb) {
+		return false
+	}
+	if s.resources.Len() > 0 {
+		if ev.ObjectRef == nil {
+			return false
+		}
+		resource := ev.ObjectRef.Resource
+		if ev.ObjectRef.APIGroup != "" {
+			resource += "." + ev.ObjectRef.APIGroup
+		}
+		if !s.resources.Has(resource) {
+			return false
+		}
+	}
+	return true
+}
+
+// trim returns a copy of the event without the objects above the level of the sink.
+func (s *sink) trim(ev *auditinternal.Event) *auditinternal.Event {
+	ev = ev.DeepCopy()
+	if s.level.Less(auditinternal.LevelRequestResponse) {
+		ev.ResponseObject = nil
+	}
+	if s.level.Less(auditinternal.LevelRequest) {
+		ev.RequestObject = nil
+	}
+	if ev.Level.GreaterOrEqual(s.level) {
+		ev.Level = s.level
+	}
+	return ev
+}
+
+// set replaces the sink with the given name of a logical cluster, removing it if nil.
+func (b *Backend) set(clusterName, name string, s *sink) {
+	b.lock.Lock()
+	sinks := b.sinks[clusterName]
+	old := sinks[name]
+	switch {
+	case s != nil && sinks == nil:
+		b.sinks[clusterName] = map[string]*sink{name: s}
+	case s != nil:
+		sinks[name] = s
+	default:
+		delete(sinks, name)
+		if len(sinks) == 0 {
+			delete(b.sinks, clusterName)
+		}
+	}
+	stopCh := b.stopCh
+	b.lock.Unlock()
+
+	if s != nil && stopCh != nil {
+		_ = s.backend.Run(stopCh)
+	}
+	if old != nil {
+		// flushes the buffered events in the background
+		go old.backend.Shutdown()
+	}
+}
+
+// Run starts the sinks, and those set later on.
+func (b *Backend) Run(stopCh <-chan struct{}) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.stopCh = stopCh
+	for _, sinks := range b.sinks {
+		for _, s := range sinks {
+			if err := s.backend.Run(stopCh); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Shutdown flushes the events buffered by the sinks.
+func (b *Backend) Shutdown() {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	for _, sinks := range b.sinks {
+		for _, s := range sinks {
+			s.backend.Shutdown()
+		}
+	}
+}
+
+// ProcessEvents sends each event to the matching sinks of its logical cluster. Events are
+// buffered by each sink, so a slow webhook never blocks the requests.
+func (b *Backend) ProcessEvents(events ...*auditinternal.Event) bool {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	for _, ev := range events {
+		clusterName, ok := ev.Annotations[ClusterAnnotation]
+		if !ok {
+			continue
+		}
+		for _, s := range b.sinks[clusterName] {
+			if s.matches(ev) {
+				s.backend.ProcessEvents(s.trim(ev))
+			}
+		}
+	}
+	return true
+}
+
+func (b *Backend) String() string {
+	return "auditsinks"
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auditsink
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	auditinternal "k8s.io/apiserver/pkg/apis/audit"
+	"k8s.io/apiserver/plugin/pkg/audit/fake"
+)
+
+func TestProcessEvents(t *testing.T) {
+	var received []*auditinternal.Event
+	backend := NewBackend()
+	backend.set("team", "secrets", &sink{
+		level:     auditinternal.LevelMetadata,
+		verbs:     sets.NewString(),
+		resources: sets.NewString("secrets"),
+		backend: &fake.Backend{OnRequest: func(events []*auditinternal.Event) {
+			received = append(received, events...)
+		}},
+	})
+
+	newEvent := func(clusterName, resource string) *auditinternal.Event {
+		return &auditinternal.Event{
+			Level:         auditinternal.LevelRequest,
+			Verb:          "get",
+			ObjectRef:     &auditinternal.ObjectReference{Resource: resource},
+			RequestObject: &runtime.Unknown{Raw: []byte("{}")},
+			Annotations:   map[string]string{ClusterAnnotation: clusterName},
+		}
+	}
+	backend.ProcessEvents(
+		newEvent("team", "secrets"),
+		newEvent("team", "configmaps"),
+		newEvent("other", "secrets"),
+		&auditinternal.Event{Verb: "get", ObjectRef: &auditinternal.ObjectReference{Resource: "secrets"}},
+	)
+
+	if len(received) != 1 {
+		t.Fatalf("expected the single event of the secrets of the logical cluster, got %d events", len(received))
+	}
+	if received[0].Level != auditinternal.LevelMetadata || received[0].RequestObject != nil {
+		t.Errorf("expected the event to be trimmed to the Metadata level, got %s with request object %v", received[0].Level, received[0].RequestObject)
+	}
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package auditsink sends the audit events of the requests to each workspace to the
+// AuditSinks registered by the tenant in that workspace.
+package auditsink
+
+import (
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	tenancyinformer "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/tenancy/v1alpha1"
+)
+
+// NewController returns a controller keeping the sinks of the backend in sync with the
+// AuditSinks of all logical clusters.
+func NewController(auditSinkInformer tenancyinformer.AuditSinkInformer, backend *Backend) *Controller {
+	c := &Controller{backend: backend}
+	auditSinkInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) { c.updateBackend(obj, false) },
+		UpdateFunc: func(old, obj interface{}) {
+			if old.(*tenancyv1alpha1.AuditSink).Generation != obj.(*tenancyv1alpha1.AuditSink).Generation {
+				c.updateBackend(obj, false)
+			}
+		},
+		DeleteFunc: func(obj interface{}) { c.updateBackend(obj, true) },
+	})
+	return c
+}
+
+// Controller watches AuditSinks in order to set up the sinks of the backend.
+type Controller struct {
+	backend *Backend
+}
+
+func (c *Controller) updateBackend(obj interface{}, deleted bool) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	auditSink, ok := obj.(*tenancyv1alpha1.AuditSink)
+	if !ok {
+		return
+	}
+	if deleted {
+		c.backend.set(auditSink.ClusterName, auditSink.Name, nil)
+		return
+	}
+	s, err := newSink(auditSink.Spec)
+	if err != nil {
+		runtime.HandleError(err)
+		c.backend.set(auditSink.ClusterName, auditSink.Name, nil)
+		return
+	}
+	klog.V(4).Infof("Sending the audit events of logical cluster %s to %s", auditSink.ClusterName, auditSink.Spec.Webhook.URL)
+	c.backend.set(auditSink.ClusterName, auditSink.Name, s)
+}
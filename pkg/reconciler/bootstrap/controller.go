@@ -0,0 +1,168 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package bootstrap keeps the objects of a set of manifests in the logical clusters they
+// are meant for, re-applying them periodically so that drift is corrected.
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	"github.com/kcp-dev/kcp/config"
+)
+
+const controllerName = "bootstrap-manifests"
+
+// FieldManager is the field manager of the objects applied from the bootstrap manifests.
+const FieldManager = controllerName
+
+// NewController returns a controller applying the manifests of each subdirectory of dir
+// to the logical cluster named after it, every interval, with the clients of cfg.
+//
+//	dir/
+//	  admin/          manifests of the logical cluster admin
+//	    crds.yaml
+//	    rbac.yaml
+//	  user/           manifests of the logical cluster user
+//	    ...
+func NewController(cfg *rest.Config, dir string, interval time.Duration) (*Controller, error) {
+	dynamicClient, err := dynamic.NewClusterForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	kubeClient, err := kubernetes.NewClusterForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Controller{
+		queue:         workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), controllerName),
+		dynamicClient: dynamicClient,
+		kubeClient:    kubeClient,
+		dir:           dir,
+		interval:      interval,
+		bootstrappers: map[string]*config.Bootstrapper{},
+	}, nil
+}
+
+// Controller applies the manifests of a logical cluster, keyed by its name.
+type Controller struct {
+	queue workqueue.RateLimitingInterface
+
+	dynamicClient dynamic.ClusterInterface
+	kubeClient    *kubernetes.Cluster
+	dir           string
+	interval      time.Duration
+
+	// bootstrappers keep the discovered resources of each logical cluster between
+	// resyncs.
+	bootstrappers     map[string]*config.Bootstrapper
+	bootstrappersLock sync.Mutex
+}
+
+// bootstrapperFor returns the bootstrapper of the logical cluster. It's only used by the
+// worker processing the logical cluster, since the queue never hands out a key to two
+// workers at once.
+func (c *Controller) bootstrapperFor(clusterName string) *config.Bootstrapper {
+	c.bootstrappersLock.Lock()
+	defer c.bootstrappersLock.Unlock()
+
+	bootstrapper, found := c.bootstrappers[clusterName]
+	if !found {
+		bootstrapper = config.NewBootstrapper(c.dynamicClient.Cluster(clusterName), c.kubeClient.Cluster(clusterName).Discovery())
+		bootstrapper.FieldManager = FieldManager
+		c.bootstrappers[clusterName] = bootstrapper
+	}
+	return bootstrapper
+}
+
+// enqueueAll enqueues the logical clusters having manifests, including those added to
+// the directory since the last time.
+func (c *Controller) enqueueAll(ctx context.Context) {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		runtime.HandleError(fmt.Errorf("could not read the bootstrap manifests: %w", err))
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			c.queue.Add(entry.Name())
+		}
+	}
+}
+
+func (c *Controller) Start(ctx context.Context, numThreads int) {
+	defer runtime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	klog.Info("Starting BootstrapManifests controller")
+	defer klog.Info("Shutting down BootstrapManifests controller")
+
+	go wait.UntilWithContext(ctx, c.enqueueAll, c.interval)
+
+	for i := 0; i < numThreads; i++ {
+		go wait.Until(func() { c.startWorker(ctx) }, time.Second, ctx.Done())
+	}
+
+	<-ctx.Done()
+}
+
+func (c *Controller) startWorker(ctx context.Context) {
+	for c.processNextWorkItem(ctx) {
+	}
+}
+
+func (c *Controller) processNextWorkItem(ctx context.Context) bool {
+	// Wait until there is a new item in the working queue
+	k, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	key := k.(string)
+
+	// No matter what, tell the queue we're done with this key, to unblock
+	// other workers.
+	defer c.queue.Done(key)
+
+	if err := c.process(ctx, key); err != nil {
+		runtime.HandleError(fmt.Errorf("%q controller failed to sync %q, err: %w", controllerName, key, err))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	c.queue.Forget(key)
+	return true
+}
+
+func (c *Controller) process(ctx context.Context, clusterName string) error {
+	results, err := c.bootstrapperFor(clusterName).Bootstrap(ctx, config.FromDirectory(filepath.Join(c.dir, clusterName)))
+	for _, result := range results {
+		klog.V(4).Infof("Bootstrapping logical cluster %s: %s", clusterName, result)
+	}
+	return err
+}
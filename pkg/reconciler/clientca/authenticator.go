@@ -0,0 +1,247 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package clientca authenticates the client certificates issued by the
+// ClientCertificateAuthorities of the tenants, for the requests to the workspace of each
+// authority and to the workspaces beneath it only, so that organizations bring their own
+// identities without sharing a client CA with the other tenants.
+package clientca
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/user"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clusters"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	tenancyinformer "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/indexers"
+)
+
+// AuthorityExtraKey is the user extra holding the cluster aware key of the
+// ClientCertificateAuthority which authenticated the user.
+const AuthorityExtraKey = "tenancy.kcp.dev/client-certificate-authority"
+
+// Authenticator authenticates the requests to a logical cluster whose client certificate is
+// issued by a ClientCertificateAuthority of that logical cluster, or of the logical cluster
+// of one of its ancestor workspaces. Requests across all logical clusters are never
+// authenticated, and neither are any requests until the informers are installed.
+type Authenticator struct {
+	lock             sync.RWMutex
+	workspaceIndexer cache.Indexer
+	hasSynced        func() bool
+	// authorities are keyed by logical cluster and name
+	authorities map[string]map[string]*authority
+}
+
+// authority is a ClientCertificateAuthority, with its certificates parsed.
+type authority struct {
+	key            string
+	roots          *x509.CertPool
+	usernamePrefix string
+	groupsPrefix   string
+}
+
+// NewAuthenticator returns an Authenticator which authenticates no request until its
+// informers are installed.
+func NewAuthenticator() *Authenticator {
+	return &Authenticator{authorities: map[string]map[string]*authority{}}
+}
+
+// Install makes the authenticator trust the ClientCertificateAuthorities of the given
+// informer, beneath the workspaces of the other one, once they have synced.
+func (a *Authenticator) Install(workspaceInformer tenancyinformer.WorkspaceInformer, authorityInformer tenancyinformer.ClientCertificateAuthorityInformer) error {
+	if err := indexers.AddIfNotPresent(workspaceInformer.Informer().GetIndexer(), cache.Indexers{
+		indexers.WorkspaceCluster: indexers.IndexWorkspaceByCluster,
+	}); err != nil {
+		return fmt.Errorf("failed to add indexer for Workspace: %w", err)
+	}
+	authorityInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) { a.update(obj, false) },
+		UpdateFunc: func(old, obj interface{}) {
+			if old.(*tenancyv1alpha1.ClientCertificateAuthority).Generation != obj.(*tenancyv1alpha1.ClientCertificateAuthority).Generation {
+				a.update(obj, false)
+			}
+		},
+		DeleteFunc: func(obj interface{}) { a.update(obj, true) },
+	})
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.workspaceIndexer = workspaceInformer.Informer().GetIndexer()
+	a.hasSynced = func() bool {
+		return workspaceInformer.Informer().HasSynced() && authorityInformer.Informer().HasSynced()
+	}
+	return nil
+}
+
+func (a *Authenticator) update(obj interface{}, deleted bool) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	ca, ok := obj.(*tenancyv1alpha1.ClientCertificateAuthority)
+	if !ok {
+		return
+	}
+	var trusted *authority
+	if !deleted {
+		roots := x509.NewCertPool()
+		if roots.AppendCertsFromPEM(ca.Spec.CABundle) {
+			trusted = &authority{
+				key:            clusters.ToClusterAwareKey(ca.ClusterName, ca.Name),
+				roots:          roots,
+				usernamePrefix: ca.Spec.UsernamePrefix,
+				groupsPrefix:   ca.Spec.GroupsPrefix,
+			}
+		} else {
+			runtime.HandleError(fmt.Errorf("ClientCertificateAuthority %q of logical cluster %q holds no PEM encoded certificate", ca.Name, ca.ClusterName))
+		}
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if trusted == nil {
+		delete(a.authorities[ca.ClusterName], ca.Name)
+		if len(a.authorities[ca.ClusterName]) == 0 {
+			delete(a.authorities, ca.ClusterName)
+		}
+		return
+	}
+	if a.authorities[ca.ClusterName] == nil {
+		a.authorities[ca.ClusterName] = map[string]*authority{}
+	}
+	a.authorities[ca.ClusterName][ca.Name] = trusted
+}
+
+// AuthenticateRequest implements authenticator.Request.
+func (a *Authenticator) AuthenticateRequest(req *http.Request) (*authenticator.Response, bool, error) {
+	if req.TLS == nil || len(req.TLS.PeerCertificates) == 0 {
+		return nil, false, nil
+	}
+	cluster := genericapirequest.ClusterFrom(req.Context())
+	if cluster == nil || cluster.Name == "" || cluster.Wildcard {
+		return nil, false, nil
+	}
+	a.lock.RLock()
+	workspaceIndexer, hasSynced := a.workspaceIndexer, a.hasSynced
+	a.lock.RUnlock()
+	if hasSynced == nil || !hasSynced() {
+		return nil, false, nil
+	}
+
+	lineage, err := lineage(workspaceIndexer, cluster.Name)
+	if err != nil {
+		return nil, false, err
+	}
+	cert := req.TLS.PeerCertificates[0]
+	opts := x509.VerifyOptions{
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	for _, intermediate := range req.TLS.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(intermediate)
+	}
+	for _, clusterName := range lineage {
+		for _, trusted := range a.trusted(clusterName) {
+			opts.Roots = trusted.roots
+			if _, err := cert.Verify(opts); err != nil {
+				continue
+			}
+			u, err := trusted.user(cert)
+			if err != nil {
+				return nil, false, err
+			}
+			return &authenticator.Response{User: u}, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// trusted returns the authorities of the given logical cluster, sorted by name.
+func (a *Authenticator) trusted(clusterName string) []*authority {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	names := make([]string, 0, len(a.authorities[clusterName]))
+	for name := range a.authorities[clusterName] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	trusted := make([]*authority, 0, len(names))
+	for _, name := range names {
+		trusted = append(trusted, a.authorities[clusterName][name])
+	}
+	return trusted
+}
+
+// user returns the user of a client certificate issued by the authority: the common name
+// is its name, and the organizations its groups, both prefixed. Certificates naming system
+// users are refused, and system groups are dropped, so that tenants never issue privileged
+// identities.
+func (a *authority) user(cert *x509.Certificate) (user.Info, error) {
+	name := a.usernamePrefix + cert.Subject.CommonName
+	if cert.Subject.CommonName == "" || strings.HasPrefix(name, "system:") {
+		return nil, fmt.Errorf("ClientCertificateAuthority %s cannot authenticate user %q", a.key, name)
+	}
+	groups := make([]string, 0, len(cert.Subject.Organization)+1)
+	for _, organization := range cert.Subject.Organization {
+		if group := a.groupsPrefix + organization; !strings.HasPrefix(group, "system:") {
+			groups = append(groups, group)
+		}
+	}
+	return &user.DefaultInfo{
+		Name:   name,
+		Groups: append(groups, user.AllAuthenticated),
+		Extra:  map[string][]string{AuthorityExtraKey: {a.key}},
+	}, nil
+}
+
+// lineage returns the given logical cluster followed by the logical clusters of its ancestor
+// workspaces. The walk stops at a logical cluster which is not the one of a workspace, or
+// which workspaces of different parents claim.
+func lineage(workspaceIndexer cache.Indexer, clusterName string) ([]string, error) {
+	lineage := []string{clusterName}
+	seen := map[string]bool{clusterName: true}
+	for {
+		workspaces, err := workspaceIndexer.ByIndex(indexers.WorkspaceCluster, clusterName)
+		if err != nil {
+			return nil, err
+		}
+		parents := map[string]bool{}
+		for _, obj := range workspaces {
+			parents[obj.(*tenancyv1alpha1.Workspace).ClusterName] = true
+		}
+		if len(parents) != 1 {
+			return lineage, nil
+		}
+		for parent := range parents {
+			clusterName = parent
+		}
+		if seen[clusterName] {
+			return lineage, nil
+		}
+		seen[clusterName] = true
+		lineage = append(lineage, clusterName)
+	}
+}
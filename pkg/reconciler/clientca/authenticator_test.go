@@ -0,0 +1,179 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clientca
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/tools/cache"
+	certutil "k8s.io/client-go/util/cert"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/indexers"
+)
+
+func newCA(t *testing.T, name string) (*x509.Certificate, crypto.Signer) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := certutil.NewSelfSignedCACert(certutil.Config{CommonName: name}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func newClientCert(t *testing.T, caCert *x509.Certificate, caKey crypto.Signer, name string, groups ...string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name, Organization: groups},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, key.Public(), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestAuthenticateRequest(t *testing.T) {
+	// root contains the organizations acme and globex, and acme contains the team workspace
+	workspaceIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		indexers.WorkspaceCluster: indexers.IndexWorkspaceByCluster,
+	})
+	for _, workspace := range []*tenancyv1alpha1.Workspace{
+		{ObjectMeta: metav1.ObjectMeta{Name: "acme", ClusterName: "root"}, Status: tenancyv1alpha1.WorkspaceStatus{Cluster: "acme-cluster"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "globex", ClusterName: "root"}, Status: tenancyv1alpha1.WorkspaceStatus{Cluster: "globex-cluster"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "team", ClusterName: "acme-cluster"}, Status: tenancyv1alpha1.WorkspaceStatus{Cluster: "team-cluster"}},
+		// workspaces of both organizations claiming the same logical cluster
+		{ObjectMeta: metav1.ObjectMeta{Name: "shared", ClusterName: "acme-cluster"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "shared", ClusterName: "globex-cluster"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "lab", ClusterName: "globex-cluster"}, Status: tenancyv1alpha1.WorkspaceStatus{Cluster: "lab-cluster"}},
+	} {
+		if err := workspaceIndexer.Add(workspace); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a := NewAuthenticator()
+	a.workspaceIndexer, a.hasSynced = workspaceIndexer, func() bool { return true }
+
+	acmeCert, acmeKey := newCA(t, "acme")
+	globexCert, globexKey := newCA(t, "globex")
+	otherCert, otherKey := newCA(t, "other")
+	for _, ca := range []struct {
+		cluster string
+		cert    *x509.Certificate
+		prefix  string
+	}{{"acme-cluster", acmeCert, "acme:"}, {"globex-cluster", globexCert, ""}} {
+		bundle, err := certutil.EncodeCertificates(ca.cert)
+		if err != nil {
+			t.Fatal(err)
+		}
+		a.update(&tenancyv1alpha1.ClientCertificateAuthority{
+			ObjectMeta: metav1.ObjectMeta{Name: "corp", ClusterName: ca.cluster},
+			Spec: tenancyv1alpha1.ClientCertificateAuthoritySpec{
+				CABundle:       bundle,
+				UsernamePrefix: ca.prefix,
+				GroupsPrefix:   ca.prefix,
+			},
+		}, false)
+	}
+
+	alice := newClientCert(t, acmeCert, acmeKey, "alice", "developers", "system:masters")
+	for _, tc := range []struct {
+		name          string
+		cluster       *genericapirequest.Cluster
+		cert          *x509.Certificate
+		expectedUser  string
+		expectedError bool
+	}{
+		{name: "organization", cluster: &genericapirequest.Cluster{Name: "acme-cluster"}, cert: alice, expectedUser: "acme:alice"},
+		{name: "beneath the organization", cluster: &genericapirequest.Cluster{Name: "team-cluster"}, cert: alice, expectedUser: "acme:alice"},
+		{name: "other organization", cluster: &genericapirequest.Cluster{Name: "globex-cluster"}, cert: alice},
+		{name: "above the organization", cluster: &genericapirequest.Cluster{Name: "root"}, cert: alice},
+		{name: "wildcard", cluster: &genericapirequest.Cluster{Name: "*", Wildcard: true}, cert: alice},
+		{name: "no cluster", cert: alice},
+		{name: "unknown authority", cluster: &genericapirequest.Cluster{Name: "acme-cluster"}, cert: newClientCert(t, otherCert, otherKey, "bob")},
+		{name: "beneath the other organization", cluster: &genericapirequest.Cluster{Name: "lab-cluster"}, cert: newClientCert(t, globexCert, globexKey, "carol", "system:masters"), expectedUser: "carol"},
+		{name: "claimed by several parents", cluster: &genericapirequest.Cluster{Name: "shared"}, cert: alice},
+		{name: "system user", cluster: &genericapirequest.Cluster{Name: "globex-cluster"}, cert: newClientCert(t, globexCert, globexKey, "system:admin"), expectedError: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api", nil)
+			req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{tc.cert}}
+			if tc.cluster != nil {
+				req = req.WithContext(genericapirequest.WithCluster(req.Context(), *tc.cluster))
+			}
+			resp, ok, err := a.AuthenticateRequest(req)
+			if tc.expectedError != (err != nil) {
+				t.Fatalf("expected error %v, got %v", tc.expectedError, err)
+			}
+			if tc.expectedUser == "" {
+				if ok {
+					t.Errorf("expected no user, got %q", resp.User.GetName())
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("expected user %q to be authenticated", tc.expectedUser)
+			}
+			if name := resp.User.GetName(); name != tc.expectedUser {
+				t.Errorf("expected user %q, got %q", tc.expectedUser, name)
+			}
+			expectedGroups := map[string][]string{
+				"acme:alice": {"acme:developers", "acme:system:masters", "system:authenticated"},
+				"carol":      {"system:authenticated"},
+			}[tc.expectedUser]
+			if groups := resp.User.GetGroups(); !reflect.DeepEqual(groups, expectedGroups) {
+				t.Errorf("expected groups %v, got %v", expectedGroups, groups)
+			}
+		})
+	}
+
+	a.update(&tenancyv1alpha1.ClientCertificateAuthority{ObjectMeta: metav1.ObjectMeta{Name: "corp", ClusterName: "acme-cluster"}}, true)
+	req := httptest.NewRequest("GET", "/api", nil)
+	req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{alice}}
+	req = req.WithContext(genericapirequest.WithCluster(req.Context(), genericapirequest.Cluster{Name: "acme-cluster"}))
+	if _, ok, _ := a.AuthenticateRequest(req); ok {
+		t.Errorf("expected no user once the authority is deleted")
+	}
+}
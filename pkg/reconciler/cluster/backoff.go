@@ -0,0 +1,91 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/clusters"
+	"k8s.io/client-go/util/workqueue"
+
+	clusterv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/cluster/v1alpha1"
+)
+
+const (
+	// minRetryBackoff and maxRetryBackoff bound the backoff of the reconciliations of a
+	// cluster which failed or is not ready.
+	minRetryBackoff = 5 * time.Second
+	maxRetryBackoff = 10 * time.Minute
+
+	// resyncJitter spreads the periodic reconciliations of ready clusters, so that
+	// clusters registered together are not probed together.
+	resyncJitter = 0.1
+
+	// retryEventThreshold is the number of consecutive retries of a cluster after which,
+	// and then every that many retries, a Warning event is recorded on the Cluster.
+	retryEventThreshold = 5
+)
+
+// newRateLimiter returns the rate limiter of the cluster workqueue, backing off
+// exponentially per cluster. There is no overall rate limit, so that a misbehaving
+// cluster doesn't delay the others.
+func newRateLimiter() workqueue.RateLimiter {
+	return workqueue.NewItemExponentialFailureRateLimiter(minRetryBackoff, maxRetryBackoff)
+}
+
+// requeue schedules the next reconciliation of the cluster: after the jittered probe
+// interval if it is ready, with backoff otherwise.
+func (c *Controller) requeue(key string, cluster *clusterv1alpha1.Cluster) {
+	if !cluster.Status.Conditions.IsTrue(clusterv1alpha1.ClusterConditionReady) {
+		message := "Cluster is not ready"
+		if ready := cluster.Status.Conditions.Get(clusterv1alpha1.ClusterConditionReady); ready != nil && ready.Message != "" {
+			message = ready.Message
+		}
+		c.retry(key, message)
+		return
+	}
+
+	logicalCluster, name := clusters.SplitClusterAwareKey(key)
+	consecutiveFailures.WithLabelValues(logicalCluster, name).Set(0)
+	c.queue.Forget(key)
+	c.enqueueKeyAfter(key, wait.Jitter(probeInterval(cluster), resyncJitter))
+}
+
+// retry reconciles the cluster again with backoff, recording an event on the Cluster when
+// it keeps failing.
+func (c *Controller) retry(key, reason string) {
+	failures := c.queue.NumRequeues(key) + 1
+	delay := c.rateLimiter.When(key)
+	c.tracker.added(key, delay)
+	c.queue.AddAfter(key, delay)
+
+	logicalCluster, name := clusters.SplitClusterAwareKey(key)
+	retries.WithLabelValues(logicalCluster, name).Inc()
+	consecutiveFailures.WithLabelValues(logicalCluster, name).Set(float64(failures))
+
+	if failures%retryEventThreshold != 0 {
+		return
+	}
+	obj, exists, err := c.clusterIndexer.GetByKey(key)
+	if err != nil || !exists {
+		return
+	}
+	c.recorder.Eventf(obj.(*clusterv1alpha1.Cluster), corev1.EventTypeWarning, "RetryingReconcile",
+		"Cluster failed %d times in a row, retrying in %v: %s", failures, delay.Round(time.Second), reason)
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	clusterv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/cluster/v1alpha1"
+)
+
+const (
+	// DefaultCapacityScrapeInterval is how often the capacity of clusters without explicit
+	// scrape interval is scraped.
+	DefaultCapacityScrapeInterval = 5 * time.Minute
+
+	// capacityStaleIntervals is the number of scrape intervals after which the capacity of
+	// a cluster is stale.
+	capacityStaleIntervals = 3
+)
+
+func capacityScrapeInterval(cluster *clusterv1alpha1.Cluster) time.Duration {
+	if cluster.Spec.CapacityScrapeInterval != nil && cluster.Spec.CapacityScrapeInterval.Duration > 0 {
+		return cluster.Spec.CapacityScrapeInterval.Duration
+	}
+	return DefaultCapacityScrapeInterval
+}
+
+// updateCapacity scrapes the capacity of the cluster from its nodes when the last scrape
+// is older than the scrape interval, and records whether the capacity is current. A nil
+// client means the cluster is not reachable: the capacity is only checked for staleness.
+func updateCapacity(ctx context.Context, client kubernetes.Interface, cluster *clusterv1alpha1.Cluster, now time.Time) {
+	interval := capacityScrapeInterval(cluster)
+	if capacity := cluster.Status.Capacity; client != nil && (capacity == nil || now.Sub(capacity.LastScrapeTime.Time) >= interval) {
+		nodes, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+		if err == nil {
+			cluster.Status.Capacity = aggregateCapacity(nodes.Items, now)
+			cluster.Status.SetCondition(clusterv1alpha1.ClusterConditionCapacityCurrent, corev1.ConditionTrue, "CapacityScraped", "")
+			return
+		}
+		if cluster.Status.Capacity == nil {
+			cluster.Status.SetCondition(clusterv1alpha1.ClusterConditionCapacityCurrent, corev1.ConditionFalse,
+				"ScrapeFailed",
+				fmt.Sprintf("Error listing nodes: %v", err))
+			return
+		}
+	}
+
+	capacity := cluster.Status.Capacity
+	if capacity == nil {
+		cluster.Status.SetCondition(clusterv1alpha1.ClusterConditionCapacityCurrent, corev1.ConditionUnknown,
+			"NotScraped",
+			"Capacity was not scraped yet")
+		return
+	}
+	if age := now.Sub(capacity.LastScrapeTime.Time); age >= capacityStaleIntervals*interval {
+		cluster.Status.SetCondition(clusterv1alpha1.ClusterConditionCapacityCurrent, corev1.ConditionFalse,
+			"CapacityStale",
+			fmt.Sprintf("Capacity was last scraped %v ago", age.Round(time.Second)))
+	}
+}
+
+// aggregateCapacity sums the capacity of all the nodes, and the allocatable resources of
+// the ready and schedulable ones.
+func aggregateCapacity(nodes []corev1.Node, now time.Time) *clusterv1alpha1.ClusterCapacity {
+	capacity := &clusterv1alpha1.ClusterCapacity{
+		Capacity:       corev1.ResourceList{},
+		Allocatable:    corev1.ResourceList{},
+		LastScrapeTime: metav1.NewTime(now),
+	}
+	for i := range nodes {
+		node := &nodes[i]
+		capacity.Nodes++
+		addResources(capacity.Capacity, node.Status.Capacity)
+		if node.Spec.Unschedulable || !nodeReady(node) {
+			continue
+		}
+		capacity.ReadyNodes++
+		addResources(capacity.Allocatable, node.Status.Allocatable)
+	}
+	return capacity
+}
+
+func addResources(total, resources corev1.ResourceList) {
+	for name, quantity := range resources {
+		sum := total[name]
+		sum.Add(quantity)
+		total[name] = sum
+	}
+}
+
+func nodeReady(node *corev1.Node) bool {
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == corev1.NodeReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
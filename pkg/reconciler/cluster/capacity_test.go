@@ -0,0 +1,100 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	clusterv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/cluster/v1alpha1"
+)
+
+func TestUpdateCapacity(t *testing.T) {
+	node := func(name string, ready, unschedulable bool, cpu, memory string) *corev1.Node {
+		resources := corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse(cpu),
+			corev1.ResourceMemory: resource.MustParse(memory),
+		}
+		status := corev1.ConditionFalse
+		if ready {
+			status = corev1.ConditionTrue
+		}
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       corev1.NodeSpec{Unschedulable: unschedulable},
+			Status: corev1.NodeStatus{
+				Capacity:    resources,
+				Allocatable: resources,
+				Conditions:  []corev1.NodeCondition{{Type: corev1.NodeReady, Status: status}},
+			},
+		}
+	}
+	client := fake.NewSimpleClientset(
+		node("a", true, false, "2", "4Gi"),
+		node("b", true, false, "1500m", "2Gi"),
+		node("not-ready", false, false, "4", "8Gi"),
+		node("cordoned", true, true, "4", "8Gi"),
+	)
+	cluster := &clusterv1alpha1.Cluster{}
+	now := time.Now()
+
+	updateCapacity(context.Background(), client, cluster, now)
+	capacity := cluster.Status.Capacity
+	if capacity == nil {
+		t.Fatal("expected the capacity to be scraped")
+	}
+	if capacity.Nodes != 4 || capacity.ReadyNodes != 2 {
+		t.Errorf("expected 2 ready nodes out of 4, got %d out of %d", capacity.ReadyNodes, capacity.Nodes)
+	}
+	for name, tt := range map[string]struct {
+		got  resource.Quantity
+		want string
+	}{
+		"cpu capacity":       {capacity.Capacity[corev1.ResourceCPU], "11500m"},
+		"memory capacity":    {capacity.Capacity[corev1.ResourceMemory], "22Gi"},
+		"cpu allocatable":    {capacity.Allocatable[corev1.ResourceCPU], "3500m"},
+		"memory allocatable": {capacity.Allocatable[corev1.ResourceMemory], "6Gi"},
+	} {
+		if tt.got.Cmp(resource.MustParse(tt.want)) != 0 {
+			t.Errorf("expected %s %s, got %s", name, tt.want, tt.got.String())
+		}
+	}
+	if !cluster.Status.Conditions.IsTrue(clusterv1alpha1.ClusterConditionCapacityCurrent) {
+		t.Error("expected the capacity to be current")
+	}
+
+	// Within the scrape interval, the capacity is not scraped again.
+	if err := client.CoreV1().Nodes().Delete(context.Background(), "a", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	updateCapacity(context.Background(), client, cluster, now.Add(time.Minute))
+	if got := cluster.Status.Capacity.Nodes; got != 4 {
+		t.Errorf("expected the capacity not to be scraped again, got %d nodes", got)
+	}
+
+	// Unreachable clusters end up with a stale capacity.
+	updateCapacity(context.Background(), nil, cluster, now.Add(capacityStaleIntervals*DefaultCapacityScrapeInterval))
+	if cond := cluster.Status.Conditions.Get(clusterv1alpha1.ClusterConditionCapacityCurrent); cond == nil || cond.Status != corev1.ConditionFalse || cond.Reason != "CapacityStale" {
+		t.Errorf("expected the capacity to be stale, got %v", cond)
+	}
+}
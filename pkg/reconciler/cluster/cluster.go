@@ -22,10 +22,12 @@ import (
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/klog/v2"
 
@@ -35,7 +37,6 @@ import (
 )
 
 const (
-	pollInterval     = time.Minute
 	numSyncerThreads = 2
 )
 
@@ -43,25 +44,78 @@ func (c *Controller) reconcile(ctx context.Context, cluster *clusterv1alpha1.Clu
 	klog.Infof("reconciling cluster %q", cluster.Name)
 
 	logicalCluster := cluster.GetClusterName()
+	syncerMode := c.syncerModeFor(cluster)
 
-	// Get client from kubeconfig
-	cfg, err := clientcmd.RESTConfigFromKubeConfig([]byte(cluster.Spec.KubeConfig))
-	if err != nil {
-		klog.Errorf("invalid kubeconfig: %v", err)
-		cluster.Status.SetConditionReady(corev1.ConditionFalse,
-			"InvalidKubeConfig",
-			fmt.Sprintf("Invalid kubeconfig: %v", err))
-		return nil // Don't retry.
+	var cfg *rest.Config
+	if cluster.Spec.ConnectionMode == clusterv1alpha1.ConnectionModeTunnel {
+		if c.tunnels == nil {
+			klog.Errorf("cluster %q is tunneled, but tunnels are not served", cluster.Name)
+			cluster.Status.SetConditionReady(corev1.ConditionFalse,
+				"TunnelsNotSupported",
+				"Tunnels are only supported by the cluster controller running in kcp")
+			return nil // Don't retry.
+		}
+		tunnelConfig, err := c.tunnels.Config(logicalCluster, cluster.Name)
+		if err != nil {
+			klog.Errorf("tunnel of cluster %q is not connected", cluster.Name)
+			cluster.Status.SetCondition(clusterv1alpha1.ClusterConditionReachable, corev1.ConditionFalse,
+				"TunnelNotConnected",
+				"The syncer of the cluster has not connected its tunnel")
+			cluster.Status.SetCondition(clusterv1alpha1.ClusterConditionAPIServerHealthy, corev1.ConditionUnknown,
+				"ClusterUnreachable",
+				"Cluster is unreachable")
+			updateCapacity(ctx, nil, cluster, time.Now())
+			setReady(cluster)
+			return nil // Retried when the tunnel connects.
+		}
+		cfg = tunnelConfig
+	} else {
+		kubeConfig, err := c.kubeConfigFor(cluster)
+		if err != nil {
+			klog.Errorf("error resolving kubeconfig: %v", err)
+			cluster.Status.SetConditionReady(corev1.ConditionFalse,
+				"ErrorResolvingKubeConfig",
+				fmt.Sprintf("Error resolving kubeconfig: %v", err))
+			return nil // Retried when the secret changes.
+		}
+
+		// Get client from kubeconfig
+		cfg, err = clientcmd.RESTConfigFromKubeConfig(kubeConfig)
+		if err != nil {
+			klog.Errorf("invalid kubeconfig: %v", err)
+			cluster.Status.SetConditionReady(corev1.ConditionFalse,
+				"InvalidKubeConfig",
+				fmt.Sprintf("Invalid kubeconfig: %v", err))
+			return nil // Don't retry.
+		}
+
+		if c.kubeConfigChanged(cluster.Name, kubeConfig) {
+			// Reconnect to the cluster with the new kubeconfig.
+			klog.Infof("kubeconfig of cluster %q changed, reconnecting", cluster.Name)
+			if apiImporter := c.apiImporters[cluster.Name]; apiImporter != nil {
+				apiImporter.Stop()
+				delete(c.apiImporters, cluster.Name)
+			}
+			cluster.Status.SyncedResources = nil
+		}
 	}
 	client, err := kubernetes.NewForConfig(cfg)
 	if err != nil {
 		klog.Errorf("error creating client: %v", err)
 		cluster.Status.SetConditionReady(corev1.ConditionFalse,
 			"ErrorCreatingClient",
-			fmt.Sprintf("Error creating client from kubeconfig: %v", err))
+			fmt.Sprintf("Error creating client: %v", err))
 		return nil // Don't retry.
 	}
 
+	if !probe(ctx, client, cluster) {
+		klog.Errorf("cluster %q is unreachable", cluster.Name)
+		updateCapacity(ctx, nil, cluster, time.Now())
+		setReady(cluster)
+		return nil
+	}
+	updateCapacity(ctx, client, cluster, time.Now())
+
 	if c.apiImporters[cluster.Name] == nil {
 		apiImporter, err := c.StartAPIImporter(cfg, cluster.Name, logicalCluster, time.Minute)
 		if err != nil {
@@ -107,10 +161,11 @@ func (c *Controller) reconcile(ctx context.Context, cluster *clusterv1alpha1.Clu
 		}.String())
 	}
 
-	if !sets.NewString(cluster.Status.SyncedResources...).Equal(groupResources) {
+	syncerOptions := c.syncerOptionsFor(cluster)
+	if !sets.NewString(cluster.Status.SyncedResources...).Equal(groupResources) || c.syncerOptionsChanged(cluster.Name, syncerOptions) {
 		kubeConfig := c.kubeconfig.DeepCopy()
 
-		switch c.syncerMode {
+		switch syncerMode {
 		case SyncerModePush:
 			upstream, err := clientcmd.NewNonInteractiveClientConfig(*kubeConfig, "admin", &clientcmd.ConfigOverrides{}, nil).ClientConfig()
 			if err != nil {
@@ -121,18 +176,10 @@ func (c *Controller) reconcile(ctx context.Context, cluster *clusterv1alpha1.Clu
 				return nil // Don't retry.
 			}
 
-			downstream, err := clientcmd.RESTConfigFromKubeConfig([]byte(cluster.Spec.KubeConfig))
-			if err != nil {
-				klog.Errorf("error getting cluster kubeconfig: %v", err)
-				cluster.Status.SetConditionReady(corev1.ConditionFalse,
-					"ErrorStartingSyncer",
-					fmt.Sprintf("Error starting syncer: %v", err))
-				return nil // Don't retry.
-			}
-
-			newSyncer, err := syncer.StartSyncer(upstream, downstream, groupResources, cluster.Name, logicalCluster, numSyncerThreads)
+			newSyncer, err := syncer.StartSyncer(upstream, cfg, groupResources, cluster.Name, logicalCluster, numSyncerThreads, syncerOptions)
 			if err != nil {
 				klog.Errorf("error starting syncer in push mode: %v", err)
+				c.recorder.Eventf(cluster, corev1.EventTypeWarning, "ErrorStartingSyncer", "Error starting syncer: %v", err)
 				cluster.Status.SetConditionReady(corev1.ConditionFalse,
 					"ErrorStartingSyncer",
 					fmt.Sprintf("Error starting syncer: %v", err))
@@ -150,6 +197,19 @@ func (c *Controller) reconcile(ctx context.Context, cluster *clusterv1alpha1.Clu
 				"SyncerReady",
 				"Syncer ready")
 		case SyncerModePull:
+			if c.syncerImage == "" {
+				klog.Errorf("no syncer image configured to install on cluster %q", cluster.Name)
+				cluster.Status.SetConditionReady(corev1.ConditionFalse,
+					"ErrorInstallingSyncer",
+					"No syncer image is configured in the cluster controller")
+				return nil // Don't retry.
+			}
+			if s := c.syncers[cluster.Name]; s != nil {
+				// the cluster was switched from push mode
+				s.Stop()
+				delete(c.syncers, cluster.Name)
+			}
+
 			kubeConfig.CurrentContext = "admin"
 			bytes, err := clientcmd.Write(*kubeConfig)
 			if err != nil {
@@ -159,7 +219,7 @@ func (c *Controller) reconcile(ctx context.Context, cluster *clusterv1alpha1.Clu
 					fmt.Sprintf("Error installing syncer: %v", err))
 				return nil // Don't retry.
 			}
-			if err := installSyncer(ctx, client, c.syncerImage, string(bytes), cluster.Name, logicalCluster, groupResources.List()); err != nil {
+			if err := installSyncer(ctx, client, c.syncerImage, string(bytes), cluster.Name, logicalCluster, groupResources.List(), syncerOptions); err != nil {
 				klog.Errorf("error installing syncer: %v", err)
 				cluster.Status.SetConditionReady(corev1.ConditionFalse,
 					"ErrorInstallingSyncer",
@@ -178,39 +238,136 @@ func (c *Controller) reconcile(ctx context.Context, cluster *clusterv1alpha1.Clu
 				"Syncer ready")
 		}
 		cluster.Status.SyncedResources = groupResources.List()
+		c.recordSyncerOptions(cluster.Name, syncerOptions)
 	}
 
 	if cluster.Status.Conditions.HasReady() {
-		if c.syncerMode == SyncerModePull {
-			if err := healthcheckSyncer(ctx, client, logicalCluster); err != nil {
-				klog.Error("syncer not yet ready")
-				cluster.Status.SetConditionReady(corev1.ConditionFalse,
+		switch syncerMode {
+		case SyncerModePull:
+			if rolledOut, message, err := syncerRolloutStatus(ctx, client, logicalCluster); err != nil {
+				klog.Errorf("error getting syncer rollout status: %v", err)
+				if k8serrors.IsNotFound(err) {
+					// reinstall the syncer at the next reconciliation
+					cluster.Status.SyncedResources = nil
+				}
+				cluster.Status.SetCondition(clusterv1alpha1.ClusterConditionSyncerReady, corev1.ConditionFalse,
 					"SyncerNotReady",
 					err.Error())
+			} else if !rolledOut {
+				klog.Infof("syncer of cluster %s in logical cluster %s not yet rolled out: %s", cluster.Name, logicalCluster, message)
+				cluster.Status.SetCondition(clusterv1alpha1.ClusterConditionSyncerReady, corev1.ConditionFalse,
+					"SyncerRollingOut",
+					message)
 			} else {
 				klog.Infof("started pull mode syncer for cluster %s in logical cluster %s!", cluster.Name, logicalCluster)
-				cluster.Status.SetConditionReady(corev1.ConditionTrue,
+				cluster.Status.SetCondition(clusterv1alpha1.ClusterConditionSyncerReady, corev1.ConditionTrue,
 					"SyncerReady",
 					"Syncer ready")
 			}
-		} else {
-			klog.Infof("healthy push mode syncer running for cluster %s in logical cluster %s!", cluster.Name, logicalCluster)
-			cluster.Status.SetConditionReady(corev1.ConditionTrue,
+		case SyncerModePush:
+			if c.syncers[cluster.Name] == nil {
+				cluster.Status.SetCondition(clusterv1alpha1.ClusterConditionSyncerReady, corev1.ConditionFalse,
+					"SyncerNotRunning",
+					"Syncer is not running")
+			} else {
+				klog.Infof("healthy push mode syncer running for cluster %s in logical cluster %s!", cluster.Name, logicalCluster)
+				cluster.Status.SetCondition(clusterv1alpha1.ClusterConditionSyncerReady, corev1.ConditionTrue,
+					"SyncerReady",
+					"Syncer ready")
+			}
+		case SyncerModeNone:
+			cluster.Status.SetCondition(clusterv1alpha1.ClusterConditionSyncerReady, corev1.ConditionTrue,
 				"SyncerReady",
 				"Syncer ready")
 		}
+		setReady(cluster)
 	}
 
-	// Enqueue another check later
-	key, err := cache.MetaNamespaceKeyFunc(cluster)
-	if err != nil {
-		klog.Error(err)
-	} else {
-		c.queue.AddAfter(key, pollInterval)
-	}
 	return nil
 }
 
+// syncerModeFor returns the syncer mode of the cluster, defaulting to the mode of the
+// controller. The syncer of tunneled clusters is not run by the controller.
+func (c *Controller) syncerModeFor(cluster *clusterv1alpha1.Cluster) SyncerMode {
+	return syncerModeOf(cluster, c.syncerMode)
+}
+
+func syncerModeOf(cluster *clusterv1alpha1.Cluster, defaultMode SyncerMode) SyncerMode {
+	if cluster.Spec.ConnectionMode == clusterv1alpha1.ConnectionModeTunnel {
+		// The syncer of a tunneled cluster runs there already: it opened the tunnel.
+		return SyncerModeNone
+	}
+	switch cluster.Spec.SyncerMode {
+	case clusterv1alpha1.SyncerModePull:
+		return SyncerModePull
+	case clusterv1alpha1.SyncerModePush:
+		return SyncerModePush
+	case clusterv1alpha1.SyncerModeNone:
+		return SyncerModeNone
+	}
+	return defaultMode
+}
+
+// syncerOptionsFor returns the options of the syncer of the cluster: the options of the
+// controller, with the rate limit of the cluster if it overrides it.
+func (c *Controller) syncerOptionsFor(cluster *clusterv1alpha1.Cluster) syncer.Options {
+	options := c.syncerOptions
+	if limit := cluster.Spec.SyncRateLimit; limit != nil {
+		options.QPS = float32(limit.QPS)
+		options.Burst = int(limit.Burst)
+		if options.Burst == 0 {
+			options.Burst = 2 * int(limit.QPS)
+		}
+		if limit.BatchInterval != nil {
+			options.BatchInterval = limit.BatchInterval.Duration
+		}
+	}
+	return options
+}
+
+// syncerOptionsChanged returns whether the syncer of the cluster was started with other
+// options, and must be restarted.
+func (c *Controller) syncerOptionsChanged(clusterName string, options syncer.Options) bool {
+	c.syncerOptionsLock.Lock()
+	defer c.syncerOptionsLock.Unlock()
+
+	previous, found := c.appliedSyncerOptions[clusterName]
+	return found && !equality.Semantic.DeepEqual(previous, options)
+}
+
+// recordSyncerOptions records the options the syncer of the cluster was started with.
+func (c *Controller) recordSyncerOptions(clusterName string, options syncer.Options) {
+	c.syncerOptionsLock.Lock()
+	defer c.syncerOptionsLock.Unlock()
+	c.appliedSyncerOptions[clusterName] = options
+}
+
+func (c *Controller) forgetSyncerOptions(clusterName string) {
+	c.syncerOptionsLock.Lock()
+	defer c.syncerOptionsLock.Unlock()
+	delete(c.appliedSyncerOptions, clusterName)
+}
+
+// recordEvents records Events for the changes of readiness between the previous and the
+// reconciled status of a cluster. A cluster which isn't ready gets a warning with the reason
+// of its Ready condition each time that reason changes.
+func (c *Controller) recordEvents(previous, cluster *clusterv1alpha1.Cluster) {
+	from, to := previous.Status.Conditions.Get(clusterv1alpha1.ClusterConditionReady), cluster.Status.Conditions.Get(clusterv1alpha1.ClusterConditionReady)
+	switch {
+	case to == nil:
+	case to.Status == corev1.ConditionTrue:
+		if from == nil || from.Status != corev1.ConditionTrue {
+			c.recorder.Event(cluster, corev1.EventTypeNormal, "Ready", "Cluster is ready.")
+		}
+	case from == nil || from.Status != to.Status || from.Reason != to.Reason:
+		message := to.Message
+		if message == "" {
+			message = "Cluster is not ready."
+		}
+		c.recorder.Event(cluster, corev1.EventTypeWarning, to.Reason, message)
+	}
+}
+
 func (c *Controller) cleanup(ctx context.Context, deletedCluster *clusterv1alpha1.Cluster) {
 	klog.Infof("cleanup resources for cluster %q", deletedCluster.Name)
 
@@ -219,27 +376,10 @@ func (c *Controller) cleanup(ctx context.Context, deletedCluster *clusterv1alpha
 		delete(c.apiImporters, deletedCluster.Name)
 	}
 
-	switch c.syncerMode {
-	case SyncerModePull:
-		// Get client from kubeconfig
-		cfg, err := clientcmd.RESTConfigFromKubeConfig([]byte(deletedCluster.Spec.KubeConfig))
-		if err != nil {
-			klog.Errorf("invalid kubeconfig: %v", err)
-			return
-		}
-		client, err := kubernetes.NewForConfig(cfg)
-		if err != nil {
-			klog.Errorf("error creating client: %v", err)
-			return
-		}
+	c.forgetKubeConfig(deletedCluster.Name)
+	c.forgetSyncerOptions(deletedCluster.Name)
 
-		uninstallSyncer(ctx, client)
-	case SyncerModePush:
-		s, ok := c.syncers[deletedCluster.Name]
-		if !ok {
-			klog.Errorf("could not find syncer for cluster %q", deletedCluster.Name)
-			return
-		}
+	if s, ok := c.syncers[deletedCluster.Name]; ok {
 		klog.Infof("stopping syncer for cluster %q", deletedCluster.Name)
 		s.Stop()
 		delete(c.syncers, deletedCluster.Name)
@@ -0,0 +1,90 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	clusterv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/cluster/v1alpha1"
+)
+
+func TestRecordEvents(t *testing.T) {
+	withReady := func(status corev1.ConditionStatus, reason, message string) *clusterv1alpha1.Cluster {
+		cluster := &clusterv1alpha1.Cluster{}
+		if status != "" {
+			cluster.Status.SetConditionReady(status, reason, message)
+		}
+		return cluster
+	}
+
+	tests := []struct {
+		name     string
+		previous *clusterv1alpha1.Cluster
+		current  *clusterv1alpha1.Cluster
+		want     []string
+	}{
+		{
+			name:     "becomes ready",
+			previous: withReady("", "", ""),
+			current:  withReady(corev1.ConditionTrue, "SyncerReady", "Syncer ready"),
+			want:     []string{"Normal Ready Cluster is ready."},
+		},
+		{
+			name:     "stays ready",
+			previous: withReady(corev1.ConditionTrue, "SyncerReady", "Syncer ready"),
+			current:  withReady(corev1.ConditionTrue, "SyncerReady", "Syncer ready"),
+		},
+		{
+			name:     "becomes unreachable",
+			previous: withReady(corev1.ConditionTrue, "SyncerReady", "Syncer ready"),
+			current:  withReady(corev1.ConditionFalse, "ProbeFailed", "Cluster is unreachable: timeout"),
+			want:     []string{"Warning ProbeFailed Cluster is unreachable: timeout"},
+		},
+		{
+			name:     "stays unreachable",
+			previous: withReady(corev1.ConditionFalse, "ProbeFailed", "Cluster is unreachable: timeout"),
+			current:  withReady(corev1.ConditionFalse, "ProbeFailed", "Cluster is unreachable: refused"),
+		},
+		{
+			name:     "fails for another reason",
+			previous: withReady(corev1.ConditionFalse, "ProbeFailed", "Cluster is unreachable: timeout"),
+			current:  withReady(corev1.ConditionFalse, "SyncerNotReady", ""),
+			want:     []string{"Warning SyncerNotReady Cluster is not ready."},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			c := &Controller{recorder: recorder}
+			c.recordEvents(tt.previous, tt.current)
+			close(recorder.Events)
+
+			var got []string
+			for event := range recorder.Events {
+				got = append(got, event)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("unexpected events (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
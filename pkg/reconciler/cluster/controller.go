@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"sync"
 	"time"
 
 	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
@@ -29,8 +30,12 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/tools/clusters"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 	"k8s.io/kubernetes/pkg/api/genericcontrolplanescheme"
@@ -42,6 +47,7 @@ import (
 	clusterinformer "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/cluster/v1alpha1"
 	"github.com/kcp-dev/kcp/pkg/reconciler/apiresource"
 	"github.com/kcp-dev/kcp/pkg/syncer"
+	"github.com/kcp-dev/kcp/pkg/tunnel"
 )
 
 type SyncerMode int
@@ -52,6 +58,8 @@ const (
 	SyncerModeNone
 
 	controllerName = "cluster"
+	// fieldManager owns the fields of the clusters applied by the controller.
+	fieldManager = "kcp-cluster-controller"
 )
 
 const GVRForLocationInLogicalClusterIndexName = "GVRForLocationInLogicalCluster"
@@ -70,17 +78,25 @@ func GetLocationInLogicalClusterIndexKey(location, clusterName string) string {
 // server it reaches using the REST client.
 //
 // When new Clusters are found, the syncer will be run there using the given image.
+// Clusters which fail or are not ready are retried with exponential backoff, and the
+// recorder records events on the Clusters which keep failing. Tunneled Clusters are
+// reached through the tunnels of the server, if any.
 func NewController(
 	apiExtensionsClient apiextensionsclient.Interface,
 	kcpClient kcpclient.Interface,
 	clusterInformer clusterinformer.ClusterInformer,
 	apiResourceImportInformer apiresourceinformer.APIResourceImportInformer,
+	secretInformer coreinformers.SecretInformer,
 	syncerImage string,
 	kubeconfig clientcmdapi.Config,
 	resourcesToSync []string,
 	syncerMode SyncerMode,
+	syncerOptions syncer.Options,
+	recorder record.EventRecorder,
+	tunnels *tunnel.Server,
 ) (*Controller, error) {
-	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	rateLimiter := newRateLimiter()
+	queue := workqueue.NewNamedRateLimitingQueue(rateLimiter, controllerName)
 
 	var genericControlPlaneResources []schema.GroupVersionResource
 
@@ -115,28 +131,72 @@ func NewController(
 
 	c := &Controller{
 		queue:                    queue,
+		rateLimiter:              rateLimiter,
+		tracker:                  newQueueTracker(),
+		recorder:                 recorder,
+		tunnels:                  tunnels,
 		apiExtensionsClient:      apiExtensionsClient,
 		kcpClient:                kcpClient,
 		clusterIndexer:           clusterInformer.Informer().GetIndexer(),
 		apiresourceImportIndexer: apiResourceImportInformer.Informer().GetIndexer(),
+		secretLister:             secretInformer.Lister(),
 		syncChecks: []cache.InformerSynced{
 			clusterInformer.Informer().HasSynced,
 			apiResourceImportInformer.Informer().HasSynced,
+			secretInformer.Informer().HasSynced,
 		},
 		syncerImage:                  syncerImage,
 		kubeconfig:                   kubeconfig,
 		resourcesToSync:              resourcesToSync,
 		syncerMode:                   syncerMode,
+		syncerOptions:                syncerOptions,
 		syncers:                      map[string]*syncer.Syncer{},
 		apiImporters:                 map[string]*APIImporter{},
+		kubeConfigs:                  map[string][]byte{},
+		appliedSyncerOptions:         map[string]syncer.Options{},
 		genericControlPlaneResources: genericControlPlaneResources,
 	}
 
 	clusterInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc:    func(obj interface{}) { c.enqueue(obj) },
-		UpdateFunc: func(_, obj interface{}) { c.enqueue(obj) },
+		AddFunc: func(obj interface{}) { c.enqueue(obj) },
+		UpdateFunc: func(oldObj, obj interface{}) {
+			// Status updates, like heartbeats, are made by the controller itself: only
+			// reconcile changes of the spec and deletions, so that they don't reset the
+			// backoff.
+			oldCluster, ok := oldObj.(*clusterv1alpha1.Cluster)
+			if !ok {
+				return
+			}
+			cluster, ok := obj.(*clusterv1alpha1.Cluster)
+			if !ok {
+				return
+			}
+			if !equality.Semantic.DeepEqual(oldCluster.Spec, cluster.Spec) ||
+				!equality.Semantic.DeepEqual(oldCluster.DeletionTimestamp, cluster.DeletionTimestamp) ||
+				oldCluster.Annotations[clusterv1alpha1.ForceDeleteAnnotation] != cluster.Annotations[clusterv1alpha1.ForceDeleteAnnotation] {
+				c.enqueue(obj)
+			}
+		},
 		DeleteFunc: func(obj interface{}) { c.deletedCluster(obj) },
 	})
+	if err := c.clusterIndexer.AddIndexers(map[string]cache.IndexFunc{
+		kubeConfigSecretIndex: indexKubeConfigSecret,
+	}); err != nil {
+		return nil, fmt.Errorf("Failed to add indexer for Cluster: %w", err)
+	}
+
+	secretInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { c.enqueueSecretRelatedClusters(obj) },
+		UpdateFunc: func(_, obj interface{}) { c.enqueueSecretRelatedClusters(obj) },
+		DeleteFunc: func(obj interface{}) { c.enqueueSecretRelatedClusters(obj) },
+	})
+
+	if tunnels != nil {
+		// Reconcile tunneled clusters as soon as their tunnel connects or disconnects.
+		tunnels.OnChange(func(logicalCluster, clusterID string) {
+			c.enqueueKeyAfter(clusters.ToClusterAwareKey(logicalCluster, clusterID), 0)
+		})
+	}
 
 	apiResourceImportInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		UpdateFunc: func(_, obj interface{}) {
@@ -168,17 +228,27 @@ func NewController(
 
 type Controller struct {
 	queue                        workqueue.RateLimitingInterface
+	rateLimiter                  workqueue.RateLimiter
+	tracker                      *queueTracker
+	recorder                     record.EventRecorder
+	tunnels                      *tunnel.Server
 	apiExtensionsClient          apiextensionsclient.Interface
 	kcpClient                    kcpclient.Interface
 	clusterIndexer               cache.Indexer
 	apiresourceImportIndexer     cache.Indexer
+	secretLister                 corelisters.SecretLister
 	syncChecks                   []cache.InformerSynced
 	syncerImage                  string
 	kubeconfig                   clientcmdapi.Config
 	resourcesToSync              []string
 	syncerMode                   SyncerMode
+	syncerOptions                syncer.Options
 	syncers                      map[string]*syncer.Syncer
 	apiImporters                 map[string]*APIImporter
+	kubeConfigs                  map[string][]byte
+	kubeConfigsLock              sync.Mutex
+	appliedSyncerOptions         map[string]syncer.Options
+	syncerOptionsLock            sync.Mutex
 	genericControlPlaneResources []schema.GroupVersionResource
 }
 
@@ -188,9 +258,15 @@ func (c *Controller) enqueue(obj interface{}) {
 		runtime.HandleError(err)
 		return
 	}
+	c.tracker.added(key, 0)
 	c.queue.Add(key)
 }
 
+func (c *Controller) enqueueKeyAfter(key string, duration time.Duration) {
+	c.tracker.added(key, duration)
+	c.queue.AddAfter(key, duration)
+}
+
 func (c *Controller) enqueueAPIResourceImportRelatedCluster(obj interface{}) {
 	var apiResourceImport *apiresourcev1alpha1.APIResourceImport
 	switch typedObj := obj.(type) {
@@ -248,12 +324,11 @@ func (c *Controller) processNextWorkItem(ctx context.Context) bool {
 	// other workers.
 	defer c.queue.Done(key)
 
+	c.tracker.processing(key)
 	if err := c.process(ctx, key); err != nil {
 		runtime.HandleError(fmt.Errorf("%q controller failed to sync %q, err: %w", controllerName, key, err))
-		c.queue.AddRateLimited(key)
-		return true
+		c.retry(key, err.Error())
 	}
-	c.queue.Forget(key)
 	return true
 }
 
@@ -265,21 +340,45 @@ func (c *Controller) process(ctx context.Context, key string) error {
 
 	if !exists {
 		klog.Errorf("Object with key %q was deleted", key)
+		c.queue.Forget(key)
 		return nil
 	}
 	current := obj.(*clusterv1alpha1.Cluster).DeepCopy()
+
+	if current.DeletionTimestamp != nil {
+		released, err := c.deregister(ctx, current)
+		if err != nil {
+			return fmt.Errorf("failed to clean up cluster: %w", err)
+		}
+		if !released {
+			c.retry(key, "Waiting for the cleanup of the physical cluster")
+		}
+		return nil
+	}
+	current, err = c.ensureFinalizer(ctx, current)
+	if err != nil {
+		return err
+	}
 	previous := current.DeepCopy()
 
-	if err := c.reconcile(ctx, current); err != nil {
+	logicalCluster, name := clusters.SplitClusterAwareKey(key)
+	start := time.Now()
+	err = c.reconcile(ctx, current)
+	reconcileDuration.WithLabelValues(logicalCluster, name).Observe(time.Since(start).Seconds())
+	if err != nil {
 		return err
 	}
 
 	// If the object being reconciled changed as a result, update it.
 	if !equality.Semantic.DeepEqual(previous.Status, current.Status) {
-		_, uerr := c.kcpClient.ClusterV1alpha1().Clusters().UpdateStatus(ctx, current, metav1.UpdateOptions{})
-		return uerr
+		c.recordEvents(previous, current)
+		if _, err := c.kcpClient.ClusterV1alpha1().Clusters().UpdateStatus(ctx, current, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
 	}
 
+	// Probe the cluster again later.
+	c.requeue(key, current)
 	return nil
 }
 
@@ -298,6 +397,10 @@ func (c *Controller) deletedCluster(obj interface{}) {
 		}
 	}
 	klog.V(4).Infof("Deleting cluster %q", castObj.Name)
+	if key, err := cache.MetaNamespaceKeyFunc(castObj); err == nil {
+		c.queue.Forget(key)
+		forgetClusterMetrics(key)
+	}
 	ctx := context.TODO()
 	c.cleanup(ctx, castObj)
 }
@@ -0,0 +1,197 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+
+	clusterv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/cluster/v1alpha1"
+	clusterapply "github.com/kcp-dev/kcp/pkg/client/applyconfiguration/cluster/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/syncer"
+)
+
+func hasFinalizer(cluster *clusterv1alpha1.Cluster) bool {
+	for _, finalizer := range cluster.Finalizers {
+		if finalizer == clusterv1alpha1.ClusterFinalizer {
+			return true
+		}
+	}
+	return false
+}
+
+// ensureFinalizer adds the cleanup finalizer to the cluster, returning the updated cluster.
+// The finalizer is applied rather than updated, so that it doesn't conflict with the
+// concurrent changes of the cluster.
+func (c *Controller) ensureFinalizer(ctx context.Context, cluster *clusterv1alpha1.Cluster) (*clusterv1alpha1.Cluster, error) {
+	if hasFinalizer(cluster) {
+		return cluster, nil
+	}
+	ctx = request.WithCluster(ctx, request.Cluster{Name: cluster.ClusterName})
+	finalizer := clusterapply.Cluster(cluster.Name).WithFinalizers(clusterv1alpha1.ClusterFinalizer)
+	return c.kcpClient.ClusterV1alpha1().Clusters().Apply(ctx, finalizer, metav1.ApplyOptions{FieldManager: fieldManager, Force: true})
+}
+
+// deregister removes the syncer and, depending on the deletion policy of the cluster, the
+// synced objects from the physical cluster of a deleted Cluster, then releases it. It
+// returns whether the cluster was released.
+func (c *Controller) deregister(ctx context.Context, cluster *clusterv1alpha1.Cluster) (bool, error) {
+	if !hasFinalizer(cluster) {
+		return true, nil
+	}
+
+	if _, force := cluster.Annotations[clusterv1alpha1.ForceDeleteAnnotation]; force {
+		klog.Infof("cluster %q is force deleted, skipping the cleanup of the physical cluster", cluster.Name)
+		c.recorder.Event(cluster, corev1.EventTypeWarning, "CleanupSkipped", "Cluster force deleted, synced objects are left on the physical cluster")
+	} else {
+		done, err := c.cleanupDownstream(ctx, cluster)
+		if err != nil || !done {
+			return false, err
+		}
+	}
+
+	c.cleanup(ctx, cluster)
+
+	cluster = cluster.DeepCopy()
+	var finalizers []string
+	for _, finalizer := range cluster.Finalizers {
+		if finalizer != clusterv1alpha1.ClusterFinalizer {
+			finalizers = append(finalizers, finalizer)
+		}
+	}
+	cluster.Finalizers = finalizers
+	if _, err := c.kcpClient.ClusterV1alpha1().Clusters().Update(ctx, cluster, metav1.UpdateOptions{}); err != nil {
+		return false, err
+	}
+	klog.Infof("cluster %q deregistered", cluster.Name)
+	c.recorder.Event(cluster, corev1.EventTypeNormal, "Deregistered", "Cluster deregistered, its syncer was removed.")
+	return true, nil
+}
+
+// cleanupDownstream stops syncing to the physical cluster, then deletes the synced
+// objects unless they are orphaned. It returns whether the cleanup is complete.
+func (c *Controller) cleanupDownstream(ctx context.Context, cluster *clusterv1alpha1.Cluster) (bool, error) {
+	logicalCluster := cluster.GetClusterName()
+
+	// Stop syncing first, so that the deleted objects are not synced again.
+	if s := c.syncers[cluster.Name]; s != nil {
+		s.Stop()
+		delete(c.syncers, cluster.Name)
+	}
+	if c.syncerModeFor(cluster) == SyncerModeNone && cluster.Spec.DeletionPolicy == clusterv1alpha1.DeletionPolicyOrphan {
+		return true, nil
+	}
+
+	cfg, err := c.restConfigFor(cluster)
+	if err != nil {
+		return false, err
+	}
+	client, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return false, err
+	}
+
+	if c.syncerModeFor(cluster) == SyncerModePull {
+		gone, err := uninstallSyncer(ctx, client, logicalCluster)
+		if err != nil {
+			return false, fmt.Errorf("failed to uninstall the syncer: %w", err)
+		}
+		if !gone {
+			klog.Infof("waiting for the syncer of cluster %q to be uninstalled", cluster.Name)
+			return false, nil
+		}
+	}
+
+	if cluster.Spec.DeletionPolicy == clusterv1alpha1.DeletionPolicyOrphan {
+		return true, nil
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		return false, err
+	}
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(client.Discovery()))
+	var gvrs []schema.GroupVersionResource
+	for _, resource := range cluster.Status.SyncedResources {
+		gvr, err := mapper.ResourceFor(schema.ParseGroupResource(resource).WithVersion(""))
+		if meta.IsNoMatchError(err) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		gvrs = append(gvrs, gvr)
+	}
+	// The ConfigMaps and Secrets synced along with the objects referencing them.
+	for _, gvr := range syncer.DependencyResources {
+		if !containsGVR(gvrs, gvr) {
+			gvrs = append(gvrs, gvr)
+		}
+	}
+
+	remaining, err := syncer.DeleteSynced(ctx, dynamicClient, gvrs, cluster.Name, logicalCluster)
+	if err != nil {
+		return false, err
+	}
+	if remaining > 0 {
+		klog.Infof("waiting for the deletion of %d objects synced to cluster %q", remaining, cluster.Name)
+		return false, nil
+	}
+	return true, nil
+}
+
+// restConfigFor returns the config to reach the physical cluster, through its tunnel for
+// tunneled clusters and with its kubeconfig otherwise.
+func (c *Controller) restConfigFor(cluster *clusterv1alpha1.Cluster) (*rest.Config, error) {
+	if cluster.Spec.ConnectionMode == clusterv1alpha1.ConnectionModeTunnel {
+		if c.tunnels == nil {
+			return nil, fmt.Errorf("cluster %q is tunneled, but tunnels are not served", cluster.Name)
+		}
+		return c.tunnels.Config(cluster.GetClusterName(), cluster.Name)
+	}
+	kubeConfig, err := c.kubeConfigFor(cluster)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := clientcmd.RESTConfigFromKubeConfig(kubeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig: %w", err)
+	}
+	return cfg, nil
+}
+
+func containsGVR(gvrs []schema.GroupVersionResource, gvr schema.GroupVersionResource) bool {
+	for _, g := range gvrs {
+		if g == gvr {
+			return true
+		}
+	}
+	return false
+}
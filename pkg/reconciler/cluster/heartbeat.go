@@ -0,0 +1,212 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	clusterv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/cluster/v1alpha1"
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	clusterinformer "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/cluster/v1alpha1"
+)
+
+const (
+	heartbeatControllerName = "syncer-heartbeat"
+
+	// DefaultSyncerHeartbeatTimeout is how long after its last heartbeat a syncer is
+	// considered lost, a few heartbeat periods of the syncer.
+	DefaultSyncerHeartbeatTimeout = 2 * time.Minute
+)
+
+// NewHeartbeatController returns a controller setting the SyncerHealthy condition of the
+// Clusters whose syncer is expected to run, from the heartbeats the syncer records in their
+// status: clusters whose syncer was not seen for longer than the timeout get a false
+// condition and a Warning event. This tells whether syncers kcp can't reach, like syncers in
+// pull mode or behind tunnels, are alive.
+func NewHeartbeatController(
+	kcpClient kcpclient.Interface,
+	clusterInformer clusterinformer.ClusterInformer,
+	syncerMode SyncerMode,
+	timeout time.Duration,
+	recorder record.EventRecorder,
+) *HeartbeatController {
+	c := &HeartbeatController{
+		queue:          workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), heartbeatControllerName),
+		kcpClient:      kcpClient,
+		clusterIndexer: clusterInformer.Informer().GetIndexer(),
+		syncChecks:     []cache.InformerSynced{clusterInformer.Informer().HasSynced},
+		syncerMode:     syncerMode,
+		timeout:        timeout,
+		recorder:       recorder,
+		now:            time.Now,
+	}
+
+	clusterInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) { c.enqueue(obj) },
+		UpdateFunc: func(oldObj, obj interface{}) {
+			oldCluster, ok := oldObj.(*clusterv1alpha1.Cluster)
+			if !ok {
+				return
+			}
+			cluster, ok := obj.(*clusterv1alpha1.Cluster)
+			if !ok {
+				return
+			}
+			if !equality.Semantic.DeepEqual(oldCluster.Status.SyncerLastSeen, cluster.Status.SyncerLastSeen) ||
+				!equality.Semantic.DeepEqual(oldCluster.Spec, cluster.Spec) {
+				c.enqueue(obj)
+			}
+		},
+	})
+
+	return c
+}
+
+type HeartbeatController struct {
+	queue          workqueue.RateLimitingInterface
+	kcpClient      kcpclient.Interface
+	clusterIndexer cache.Indexer
+	syncChecks     []cache.InformerSynced
+	syncerMode     SyncerMode
+	timeout        time.Duration
+	recorder       record.EventRecorder
+	now            func() time.Time
+}
+
+func (c *HeartbeatController) enqueue(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	c.queue.Add(key)
+}
+
+func (c *HeartbeatController) Start(ctx context.Context, numThreads int) {
+	defer utilruntime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	klog.Info("Starting syncer heartbeat controller")
+	defer klog.Info("Shutting down syncer heartbeat controller")
+
+	if !cache.WaitForNamedCacheSync(heartbeatControllerName, ctx.Done(), c.syncChecks...) {
+		klog.Warning("Failed to wait for caches to sync")
+		return
+	}
+
+	for i := 0; i < numThreads; i++ {
+		go wait.Until(func() { c.startWorker(ctx) }, time.Second, ctx.Done())
+	}
+
+	<-ctx.Done()
+}
+
+func (c *HeartbeatController) startWorker(ctx context.Context) {
+	for c.processNextWorkItem(ctx) {
+	}
+}
+
+func (c *HeartbeatController) processNextWorkItem(ctx context.Context) bool {
+	k, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	key := k.(string)
+	defer c.queue.Done(key)
+
+	if err := c.process(ctx, key); err != nil {
+		utilruntime.HandleError(fmt.Errorf("%q controller failed to sync %q, err: %w", heartbeatControllerName, key, err))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	c.queue.Forget(key)
+	return true
+}
+
+func (c *HeartbeatController) process(ctx context.Context, key string) error {
+	obj, exists, err := c.clusterIndexer.GetByKey(key)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return nil
+	}
+	current := obj.(*clusterv1alpha1.Cluster).DeepCopy()
+	if current.DeletionTimestamp != nil || !expectsHeartbeats(current, c.syncerMode) {
+		return nil
+	}
+	previous := current.Status.Conditions.Get(clusterv1alpha1.ClusterConditionSyncerHealthy).DeepCopy()
+
+	// the heartbeat is checked again when it would go stale
+	if recheck := setSyncerHealthy(current, c.now(), c.timeout); recheck > 0 {
+		c.queue.AddAfter(key, recheck)
+	}
+
+	cond := current.Status.Conditions.Get(clusterv1alpha1.ClusterConditionSyncerHealthy)
+	if previous != nil && previous.Status == cond.Status && previous.Reason == cond.Reason && previous.Message == cond.Message {
+		return nil
+	}
+	switch {
+	case cond.Status == corev1.ConditionFalse:
+		c.recorder.Event(current, corev1.EventTypeWarning, cond.Reason, cond.Message)
+	case cond.Status == corev1.ConditionTrue && previous != nil && previous.Status == corev1.ConditionFalse:
+		c.recorder.Event(current, corev1.EventTypeNormal, cond.Reason, "Syncer heartbeats resumed.")
+	}
+	_, err = c.kcpClient.ClusterV1alpha1().Clusters().UpdateStatus(ctx, current, metav1.UpdateOptions{})
+	return err
+}
+
+// expectsHeartbeats returns whether the syncer of the cluster is expected to run and
+// report heartbeats.
+func expectsHeartbeats(cluster *clusterv1alpha1.Cluster, defaultMode SyncerMode) bool {
+	return cluster.Spec.ConnectionMode == clusterv1alpha1.ConnectionModeTunnel || syncerModeOf(cluster, defaultMode) != SyncerModeNone
+}
+
+// setSyncerHealthy sets the SyncerHealthy condition of the cluster from the last heartbeat
+// of its syncer, and returns in how long the heartbeat goes stale if it is current.
+func setSyncerHealthy(cluster *clusterv1alpha1.Cluster, now time.Time, timeout time.Duration) time.Duration {
+	lastSeen := cluster.Status.SyncerLastSeen
+	if lastSeen == nil {
+		cluster.Status.SetCondition(clusterv1alpha1.ClusterConditionSyncerHealthy, corev1.ConditionUnknown,
+			"NoHeartbeat",
+			"No heartbeat was received from the syncer yet")
+		return 0
+	}
+	age := now.Sub(lastSeen.Time)
+	if age > timeout {
+		cluster.Status.SetCondition(clusterv1alpha1.ClusterConditionSyncerHealthy, corev1.ConditionFalse,
+			"HeartbeatStale",
+			fmt.Sprintf("No heartbeat was received from the syncer since %s", lastSeen.UTC().Format(time.RFC3339)))
+		return 0
+	}
+	cluster.Status.SetCondition(clusterv1alpha1.ClusterConditionSyncerHealthy, corev1.ConditionTrue,
+		"HeartbeatCurrent",
+		"")
+	return timeout - age + time.Second
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clusterv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/cluster/v1alpha1"
+)
+
+func TestSetSyncerHealthy(t *testing.T) {
+	now := time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC)
+	seen := func(ago time.Duration) *metav1.Time {
+		t := metav1.NewTime(now.Add(-ago))
+		return &t
+	}
+
+	tests := []struct {
+		name        string
+		lastSeen    *metav1.Time
+		wantStatus  corev1.ConditionStatus
+		wantReason  string
+		wantRecheck time.Duration
+	}{
+		{name: "never seen", wantStatus: corev1.ConditionUnknown, wantReason: "NoHeartbeat"},
+		{name: "current", lastSeen: seen(30 * time.Second), wantStatus: corev1.ConditionTrue, wantReason: "HeartbeatCurrent", wantRecheck: 91 * time.Second},
+		{name: "stale", lastSeen: seen(3 * time.Minute), wantStatus: corev1.ConditionFalse, wantReason: "HeartbeatStale"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := &clusterv1alpha1.Cluster{}
+			cluster.Status.SyncerLastSeen = tt.lastSeen
+			if recheck := setSyncerHealthy(cluster, now, 2*time.Minute); recheck != tt.wantRecheck {
+				t.Errorf("expected a recheck in %v, got %v", tt.wantRecheck, recheck)
+			}
+			cond := cluster.Status.Conditions.Get(clusterv1alpha1.ClusterConditionSyncerHealthy)
+			if cond == nil || cond.Status != tt.wantStatus || cond.Reason != tt.wantReason {
+				t.Errorf("expected condition %s/%s, got %v", tt.wantStatus, tt.wantReason, cond)
+			}
+
+			for _, conditionType := range []clusterv1alpha1.ConditionType{
+				clusterv1alpha1.ClusterConditionReachable,
+				clusterv1alpha1.ClusterConditionAPIServerHealthy,
+				clusterv1alpha1.ClusterConditionSyncerReady,
+			} {
+				cluster.Status.SetCondition(conditionType, corev1.ConditionTrue, "", "")
+			}
+			setReady(cluster)
+			if ready, wantReady := cluster.Status.Conditions.IsTrue(clusterv1alpha1.ClusterConditionReady), tt.wantStatus != corev1.ConditionFalse; ready != wantReady {
+				t.Errorf("expected ready %v, got %v", wantReady, ready)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"bytes"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clusters"
+
+	clusterv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/cluster/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/client/clusterkeys"
+)
+
+const (
+	// DefaultKubeConfigSecretKey is the key of the kubeconfig in Secrets referenced by
+	// Clusters without an explicit key.
+	DefaultKubeConfigSecretKey = "kubeconfig"
+
+	kubeConfigSecretIndex = "kubeConfigSecret"
+)
+
+func kubeConfigSecretIndexKey(clusterName, namespace, name string) string {
+	return clusterkeys.ToKey(clusterName, namespace, name)
+}
+
+// indexKubeConfigSecret indexes Clusters by the Secret holding their kubeconfig.
+func indexKubeConfigSecret(obj interface{}) ([]string, error) {
+	if cluster, ok := obj.(*clusterv1alpha1.Cluster); ok && cluster.Spec.KubeConfigSecretRef != nil {
+		ref := cluster.Spec.KubeConfigSecretRef
+		return []string{kubeConfigSecretIndexKey(cluster.ClusterName, ref.Namespace, ref.Name)}, nil
+	}
+	return []string{}, nil
+}
+
+// enqueueSecretRelatedClusters queues the Clusters referencing a changed Secret, so that
+// their connections are reloaded.
+func (c *Controller) enqueueSecretRelatedClusters(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	secret, ok := obj.(*corev1.Secret)
+	if !ok {
+		return
+	}
+	objs, err := c.clusterIndexer.ByIndex(kubeConfigSecretIndex, kubeConfigSecretIndexKey(secret.ClusterName, secret.Namespace, secret.Name))
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	for _, obj := range objs {
+		c.enqueue(obj)
+	}
+}
+
+// kubeConfigFor returns the raw kubeconfig used to reach the physical cluster, read from
+// the referenced Secret if any.
+func (c *Controller) kubeConfigFor(cluster *clusterv1alpha1.Cluster) ([]byte, error) {
+	return KubeConfigFor(cluster, func(namespace, name string) (*corev1.Secret, error) {
+		return c.secretLister.Secrets(namespace).Get(clusters.ToClusterAwareKey(cluster.ClusterName, name))
+	})
+}
+
+// KubeConfigFor returns the raw kubeconfig used to reach the physical cluster, read with
+// getSecret from the referenced Secret, in the logical cluster of the Cluster, if any.
+func KubeConfigFor(cluster *clusterv1alpha1.Cluster, getSecret func(namespace, name string) (*corev1.Secret, error)) ([]byte, error) {
+	ref := cluster.Spec.KubeConfigSecretRef
+	if ref == nil {
+		return []byte(cluster.Spec.KubeConfig), nil
+	}
+	secret, err := getSecret(ref.Namespace, ref.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kubeconfig secret %s/%s: %w", ref.Namespace, ref.Name, err)
+	}
+	key := ref.Key
+	if key == "" {
+		key = DefaultKubeConfigSecretKey
+	}
+	kubeConfig, ok := secret.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("kubeconfig secret %s/%s has no key %q", ref.Namespace, ref.Name, key)
+	}
+	return kubeConfig, nil
+}
+
+// kubeConfigChanged records the kubeconfig used for the cluster, and returns whether it
+// differs from the one used before.
+func (c *Controller) kubeConfigChanged(clusterName string, kubeConfig []byte) bool {
+	c.kubeConfigsLock.Lock()
+	defer c.kubeConfigsLock.Unlock()
+
+	previous, found := c.kubeConfigs[clusterName]
+	c.kubeConfigs[clusterName] = kubeConfig
+	return found && !bytes.Equal(previous, kubeConfig)
+}
+
+func (c *Controller) forgetKubeConfig(clusterName string) {
+	c.kubeConfigsLock.Lock()
+	defer c.kubeConfigsLock.Unlock()
+	delete(c.kubeConfigs, clusterName)
+}
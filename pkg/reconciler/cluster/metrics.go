@@ -0,0 +1,135 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/client-go/tools/clusters"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	// registers the metrics of the named workqueues
+	_ "k8s.io/component-base/metrics/prometheus/workqueue"
+)
+
+const metricsSubsystem = "cluster_controller"
+
+var (
+	queueDepth = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Subsystem:      metricsSubsystem,
+			Name:           "queue_depth",
+			Help:           "Number of pending reconciliations of a cluster in the workqueue.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"logical_cluster", "cluster"},
+	)
+	queueLatency = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Subsystem:      metricsSubsystem,
+			Name:           "queue_latency_seconds",
+			Help:           "How long a cluster stays in the workqueue after it is due for reconciliation.",
+			Buckets:        metrics.ExponentialBuckets(0.001, 4, 10),
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"logical_cluster", "cluster"},
+	)
+	reconcileDuration = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Subsystem:      metricsSubsystem,
+			Name:           "reconcile_duration_seconds",
+			Help:           "How long reconciling a cluster takes.",
+			Buckets:        metrics.ExponentialBuckets(0.01, 2, 12),
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"logical_cluster", "cluster"},
+	)
+	retries = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      metricsSubsystem,
+			Name:           "retries_total",
+			Help:           "Number of reconciliations of a cluster retried with backoff because it failed or was not ready.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"logical_cluster", "cluster"},
+	)
+	consecutiveFailures = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Subsystem:      metricsSubsystem,
+			Name:           "consecutive_failures",
+			Help:           "Number of reconciliations in a row after which a cluster was not ready.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"logical_cluster", "cluster"},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(queueDepth, queueLatency, reconcileDuration, retries, consecutiveFailures)
+}
+
+// queueTracker tracks when clusters are due for reconciliation, for the per-cluster
+// workqueue metrics.
+type queueTracker struct {
+	lock sync.Mutex
+	due  map[string]time.Time
+}
+
+func newQueueTracker() *queueTracker {
+	return &queueTracker{due: map[string]time.Time{}}
+}
+
+// added records that the cluster of the key is due for reconciliation after the delay.
+func (t *queueTracker) added(key string, delay time.Duration) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	due := time.Now().Add(delay)
+	if existing, found := t.due[key]; found && existing.Before(due) {
+		return
+	}
+	t.due[key] = due
+	logicalCluster, name := clusters.SplitClusterAwareKey(key)
+	queueDepth.WithLabelValues(logicalCluster, name).Set(1)
+}
+
+// processing records that the cluster of the key is being reconciled.
+func (t *queueTracker) processing(key string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	logicalCluster, name := clusters.SplitClusterAwareKey(key)
+	if due, found := t.due[key]; found {
+		if latency := time.Since(due); latency > 0 {
+			queueLatency.WithLabelValues(logicalCluster, name).Observe(latency.Seconds())
+		}
+		delete(t.due, key)
+	}
+	queueDepth.WithLabelValues(logicalCluster, name).Set(0)
+}
+
+// forgetClusterMetrics drops the metrics of a deleted cluster.
+func forgetClusterMetrics(key string) {
+	logicalCluster, name := clusters.SplitClusterAwareKey(key)
+	labels := map[string]string{"logical_cluster": logicalCluster, "cluster": name}
+	queueDepth.Delete(labels)
+	queueLatency.Delete(labels)
+	reconcileDuration.Delete(labels)
+	retries.Delete(labels)
+	consecutiveFailures.Delete(labels)
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	clusterv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/cluster/v1alpha1"
+)
+
+const (
+	// DefaultProbeInterval is how often clusters without explicit probe interval are probed.
+	DefaultProbeInterval = time.Minute
+
+	probeTimeout = 10 * time.Second
+)
+
+func probeInterval(cluster *clusterv1alpha1.Cluster) time.Duration {
+	if cluster.Spec.ProbeInterval != nil && cluster.Spec.ProbeInterval.Duration > 0 {
+		return cluster.Spec.ProbeInterval.Duration
+	}
+	return DefaultProbeInterval
+}
+
+// probe checks the readiness endpoint of the cluster's API server and records the outcome
+// in the Reachable and APIServerHealthy conditions. A response with an error status means
+// the cluster is reachable but unhealthy, whereas no response means it is unreachable.
+// It returns whether the cluster is reachable.
+func probe(ctx context.Context, client kubernetes.Interface, cluster *clusterv1alpha1.Cluster) bool {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	_, err := client.Discovery().RESTClient().Get().AbsPath("/readyz").DoRaw(ctx)
+	if err == nil {
+		now := metav1.Now()
+		cluster.Status.LastHeartbeatTime = &now
+		cluster.Status.SetCondition(clusterv1alpha1.ClusterConditionReachable, corev1.ConditionTrue, "ProbeSucceeded", "")
+		cluster.Status.SetCondition(clusterv1alpha1.ClusterConditionAPIServerHealthy, corev1.ConditionTrue, "APIServerReady", "")
+		return true
+	}
+
+	if _, ok := err.(apierrors.APIStatus); ok {
+		now := metav1.Now()
+		cluster.Status.LastHeartbeatTime = &now
+		cluster.Status.SetCondition(clusterv1alpha1.ClusterConditionReachable, corev1.ConditionTrue, "ProbeSucceeded", "")
+		cluster.Status.SetCondition(clusterv1alpha1.ClusterConditionAPIServerHealthy, corev1.ConditionFalse,
+			"APIServerNotReady",
+			fmt.Sprintf("API server is not ready: %v", err))
+		return true
+	}
+
+	cluster.Status.SetCondition(clusterv1alpha1.ClusterConditionReachable, corev1.ConditionFalse,
+		"ProbeFailed",
+		fmt.Sprintf("Cluster is unreachable: %v", err))
+	cluster.Status.SetCondition(clusterv1alpha1.ClusterConditionAPIServerHealthy, corev1.ConditionUnknown,
+		"ClusterUnreachable",
+		"Cluster is unreachable")
+	return false
+}
+
+// setReady aggregates the Reachable, APIServerHealthy and SyncerReady conditions into the
+// Ready condition, reporting the first one which is not true. A cluster whose syncer
+// heartbeats went stale is not ready either.
+func setReady(cluster *clusterv1alpha1.Cluster) {
+	for _, conditionType := range []clusterv1alpha1.ConditionType{
+		clusterv1alpha1.ClusterConditionReachable,
+		clusterv1alpha1.ClusterConditionAPIServerHealthy,
+		clusterv1alpha1.ClusterConditionSyncerReady,
+	} {
+		cond := cluster.Status.Conditions.Get(conditionType)
+		if cond == nil || cond.Status != corev1.ConditionTrue {
+			reason, message := string(conditionType)+"Unknown", ""
+			if cond != nil {
+				reason, message = cond.Reason, cond.Message
+			}
+			cluster.Status.SetConditionReady(corev1.ConditionFalse, reason, message)
+			return
+		}
+	}
+	if cond := cluster.Status.Conditions.Get(clusterv1alpha1.ClusterConditionSyncerHealthy); cond != nil && cond.Status == corev1.ConditionFalse {
+		cluster.Status.SetConditionReady(corev1.ConditionFalse, cond.Reason, cond.Message)
+		return
+	}
+	cluster.Status.SetConditionReady(corev1.ConditionTrue, "SyncerReady", "Syncer ready")
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"errors"
 	"runtime"
+	"time"
 
 	"github.com/spf13/pflag"
 
@@ -27,6 +28,12 @@ import (
 	apiextensionsv1client "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/typed/apiextensions/v1"
 	crdexternalversions "k8s.io/apiextensions-apiserver/pkg/client/informers/externalversions"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	kubescheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/metadata"
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 	"k8s.io/kubernetes/pkg/genericcontrolplane/clientutils"
@@ -35,10 +42,18 @@ import (
 	apiresourceapi "github.com/kcp-dev/kcp/pkg/apis/apiresource"
 	clusterapi "github.com/kcp-dev/kcp/pkg/apis/cluster"
 	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	kcpscheme "github.com/kcp-dev/kcp/pkg/client/clientset/versioned/scheme"
+	"github.com/kcp-dev/kcp/pkg/client/clustercontext"
 	kcpexternalversions "github.com/kcp-dev/kcp/pkg/client/informers/externalversions"
+	"github.com/kcp-dev/kcp/pkg/events"
 	"github.com/kcp-dev/kcp/pkg/reconciler/apiresource"
+	"github.com/kcp-dev/kcp/pkg/reconciler/placement"
+	"github.com/kcp-dev/kcp/pkg/syncer"
+	"github.com/kcp-dev/kcp/pkg/tunnel"
 )
 
+const resyncPeriod = 10 * time.Hour
+
 // DefaultOptions are the default options for the cluster controller.
 func DefaultOptions() *Options {
 	return &Options{
@@ -48,6 +63,15 @@ func DefaultOptions() *Options {
 		AutoPublishAPIs: false,
 		NumThreads:      runtime.NumCPU(),
 		ResourcesToSync: []string{"deployments.apps"},
+
+		SyncerConflictPolicy:    string(syncer.DefaultOptions().ConflictPolicy),
+		SyncerResyncPeriod:      syncer.DefaultOptions().ResyncPeriod,
+		SyncerNamespaceStrategy: string(syncer.DefaultOptions().NamespaceStrategy),
+		SyncerQPS:               syncer.DefaultOptions().QPS,
+		SyncerBurst:             syncer.DefaultOptions().Burst,
+		SyncerBatchInterval:     syncer.DefaultOptions().BatchInterval,
+		SyncerHeartbeatPeriod:   syncer.DefaultOptions().HeartbeatPeriod,
+		SyncerHeartbeatTimeout:  DefaultSyncerHeartbeatTimeout,
 	}
 }
 
@@ -59,6 +83,16 @@ func BindOptions(o *Options, fs *pflag.FlagSet) *Options {
 	fs.BoolVar(&o.AutoPublishAPIs, "auto_publish_apis", o.AutoPublishAPIs, "If true, the APIs imported from physical clusters will be published automatically as CRDs")
 	fs.IntVar(&o.NumThreads, "cluster_controller_threads", o.NumThreads, "Number of threads to use for the cluster controller.")
 	fs.StringSliceVar(&o.ResourcesToSync, "resources_to_sync", o.ResourcesToSync, "Provides the list of resources that should be synced from KCP logical cluster to underlying physical clusters")
+	fs.StringVar(&o.SyncerConflictPolicy, "syncer_conflict_policy", o.SyncerConflictPolicy, "Which side wins when objects synced to physical clusters are changed there: UpstreamWins overwrites the changes, DownstreamWins keeps them until the object changes in KCP")
+	fs.DurationVar(&o.SyncerResyncPeriod, "syncer_resync_period", o.SyncerResyncPeriod, "How often syncers sync all objects again to detect changes made in physical clusters. Zero disables periodic resyncs.")
+	fs.StringVar(&o.SyncerNamespaceStrategy, "syncer_namespace_strategy", o.SyncerNamespaceStrategy, "Which namespaces of physical clusters objects are synced to: Identity uses the namespace of the same name, WorkspacePrefix prefixes it with a hash of the logical cluster, so that logical clusters sharing a physical cluster don't collide.")
+	fs.Float32Var(&o.SyncerQPS, "syncer_qps", o.SyncerQPS, "Sustained number of requests per second syncers make to each physical cluster, unless overridden by the syncRateLimit of the Cluster. Zero leaves the client defaults.")
+	fs.IntVar(&o.SyncerBurst, "syncer_burst", o.SyncerBurst, "Number of requests syncers can make at once to each physical cluster above --syncer_qps.")
+	fs.DurationVar(&o.SyncerBatchInterval, "syncer_batch_interval", o.SyncerBatchInterval, "How long syncers batch the changes of an object before syncing it to physical clusters. Zero syncs every change right away.")
+	fs.DurationVar(&o.SyncerHeartbeatPeriod, "syncer_heartbeat_period", o.SyncerHeartbeatPeriod, "How often syncers record that they are alive in the status of their Cluster. Zero disables heartbeats.")
+	fs.DurationVar(&o.SyncerHeartbeatTimeout, "syncer_heartbeat_timeout", o.SyncerHeartbeatTimeout, "How long after the last heartbeat of its syncer a Cluster is considered to have lost its syncer. Zero disables the detection of stale syncers.")
+	fs.BoolVar(&o.SyncerMirrorEvents, "syncer_mirror_events", o.SyncerMirrorEvents, "If true, syncers mirror the Events of physical clusters about synced objects and their dependents, like the Pods of a Deployment, into KCP.")
+	fs.StringVar(&o.SyncerUpsyncSelector, "syncer_upsync_selector", o.SyncerUpsyncSelector, "Label selector of pre-existing objects in physical clusters which syncers import into KCP. Empty disables importing.")
 	return o
 }
 
@@ -70,15 +104,60 @@ type Options struct {
 	AutoPublishAPIs bool
 	NumThreads      int
 	ResourcesToSync []string
+
+	SyncerConflictPolicy    string
+	SyncerResyncPeriod      time.Duration
+	SyncerUpsyncSelector    string
+	SyncerNamespaceStrategy string
+	SyncerQPS               float32
+	SyncerBurst             int
+	SyncerBatchInterval     time.Duration
+	SyncerHeartbeatPeriod   time.Duration
+	SyncerHeartbeatTimeout  time.Duration
+	SyncerMirrorEvents      bool
 }
 
 func (o *Options) Validate() error {
 	if o.PullMode && o.PushMode {
 		return errors.New("can't set both --push_mode and --pull_mode")
 	}
+	if _, err := syncer.ParseConflictPolicy(o.SyncerConflictPolicy); err != nil {
+		return err
+	}
+	if _, err := syncer.ParseUpsyncSelector(o.SyncerUpsyncSelector); err != nil {
+		return err
+	}
+	if _, err := syncer.ParseNamespaceStrategy(o.SyncerNamespaceStrategy); err != nil {
+		return err
+	}
+	if o.SyncerQPS < 0 || o.SyncerBurst < 0 || o.SyncerBatchInterval < 0 {
+		return errors.New("--syncer_qps, --syncer_burst and --syncer_batch_interval must not be negative")
+	}
+	if o.SyncerHeartbeatPeriod < 0 || o.SyncerHeartbeatTimeout < 0 {
+		return errors.New("--syncer_heartbeat_period and --syncer_heartbeat_timeout must not be negative")
+	}
+	if o.SyncerHeartbeatTimeout > 0 && o.SyncerHeartbeatTimeout <= o.SyncerHeartbeatPeriod {
+		return errors.New("--syncer_heartbeat_timeout must be longer than --syncer_heartbeat_period")
+	}
 	return nil
 }
 
+func (o *Options) syncerOptions() syncer.Options {
+	conflictPolicy, _ := syncer.ParseConflictPolicy(o.SyncerConflictPolicy)
+	namespaceStrategy, _ := syncer.ParseNamespaceStrategy(o.SyncerNamespaceStrategy)
+	return syncer.Options{
+		ConflictPolicy:    conflictPolicy,
+		ResyncPeriod:      o.SyncerResyncPeriod,
+		UpsyncSelector:    o.SyncerUpsyncSelector,
+		NamespaceStrategy: namespaceStrategy,
+		QPS:               o.SyncerQPS,
+		Burst:             o.SyncerBurst,
+		BatchInterval:     o.SyncerBatchInterval,
+		HeartbeatPeriod:   o.SyncerHeartbeatPeriod,
+		MirrorEvents:      o.SyncerMirrorEvents,
+	}
+}
+
 func (o *Options) Complete(kubeconfig clientcmdapi.Config, kcpSharedInformerFactory kcpexternalversions.SharedInformerFactory, crdSharedInformerFactory crdexternalversions.SharedInformerFactory) *Config {
 	return &Config{
 		Options:                  o,
@@ -90,6 +169,11 @@ func (o *Options) Complete(kubeconfig clientcmdapi.Config, kcpSharedInformerFact
 
 type Config struct {
 	*Options
+
+	// Tunnels holds the tunnels of the clusters behind firewalls. Tunneled clusters can
+	// only be reached when it is set.
+	Tunnels *tunnel.Server
+
 	kubeconfig               clientcmdapi.Config
 	kcpSharedInformerFactory kcpexternalversions.SharedInformerFactory
 	crdSharedInformerFactory crdexternalversions.SharedInformerFactory
@@ -101,6 +185,8 @@ func (c *Config) Start(ctx context.Context) error {
 		{Group: apiresourceapi.GroupName, Kind: "apiresourceimports"},
 		{Group: apiresourceapi.GroupName, Kind: "negotiatedapiresources"},
 		{Group: clusterapi.GroupName, Kind: "clusters"},
+		{Group: clusterapi.GroupName, Kind: "synctransforms"},
+		{Group: clusterapi.GroupName, Kind: "placements"},
 	}
 	for _, contextName := range []string{"admin", "user"} {
 		logicalClusterConfig, err := clientcmd.NewNonInteractiveClientConfig(c.kubeconfig, contextName, &clientcmd.ConfigOverrides{}, nil).ClientConfig()
@@ -125,6 +211,13 @@ func (c *Config) Start(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	// kubeconfig secrets are looked up in the logical cluster of each Cluster
+	kubeClient, err := kubernetes.NewClusterForConfig(adminConfig)
+	if err != nil {
+		return err
+	}
+	kubeSharedInformerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(kubeClient.Cluster("*"), resyncPeriod)
+
 	clientutils.EnableMultiCluster(adminConfig, nil, true, "clusters", "customresourcedefinitions", "apiresourceimports", "negotiatedapiresources")
 
 	apiExtensionsClient := apiextensionsclient.NewForConfigOrDie(adminConfig)
@@ -135,15 +228,30 @@ func (c *Config) Start(ctx context.Context) error {
 		kcpClient,
 		c.kcpSharedInformerFactory.Cluster().V1alpha1().Clusters(),
 		c.kcpSharedInformerFactory.Apiresource().V1alpha1().APIResourceImports(),
+		kubeSharedInformerFactory.Core().V1().Secrets(),
 		c.SyncerImage,
 		c.kubeconfig,
 		c.ResourcesToSync,
 		syncerMode,
+		c.syncerOptions(),
+		events.NewRecorder(ctx, kubeClient, kcpscheme.Scheme, "cluster-controller"),
+		c.Tunnels,
 	)
 	if err != nil {
 		return err
 	}
 
+	var heartbeatController *HeartbeatController
+	if c.SyncerHeartbeatTimeout > 0 {
+		heartbeatController = NewHeartbeatController(
+			kcpClient,
+			c.kcpSharedInformerFactory.Cluster().V1alpha1().Clusters(),
+			syncerMode,
+			c.SyncerHeartbeatTimeout,
+			events.NewRecorder(ctx, kubeClient, kcpscheme.Scheme, "syncer-heartbeat-controller"),
+		)
+	}
+
 	apiresourceController, err := apiresource.NewController(
 		apiExtensionsClient,
 		kcpClient,
@@ -156,10 +264,58 @@ func (c *Config) Start(ctx context.Context) error {
 		return err
 	}
 
+	dynamicClient, err := dynamic.NewClusterForConfig(adminConfig)
+	if err != nil {
+		return err
+	}
+	// the objects to place are watched in all logical clusters, but only their metadata
+	metadataClient, err := metadata.NewForConfig(clustercontext.ConfigFor(adminConfig, "*"))
+	if err != nil {
+		return err
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(adminConfig)
+	if err != nil {
+		return err
+	}
+	placementController, err := placement.NewController(
+		dynamicClient,
+		metadataClient,
+		discoveryClient,
+		c.kcpSharedInformerFactory.Cluster().V1alpha1().Placements(),
+		c.kcpSharedInformerFactory.Cluster().V1alpha1().Clusters(),
+		kubeSharedInformerFactory.Core().V1().Namespaces(),
+		events.NewRecorder(ctx, kubeClient, kcpscheme.Scheme, "placement-controller"),
+		c.ResourcesToSync,
+	)
+	if err != nil {
+		return err
+	}
+
+	namespaceController, err := placement.NewNamespaceController(
+		kubeClient,
+		dynamicClient,
+		metadataClient,
+		discoveryClient,
+		c.kcpSharedInformerFactory.Cluster().V1alpha1().Placements(),
+		c.kcpSharedInformerFactory.Cluster().V1alpha1().Clusters(),
+		kubeSharedInformerFactory.Core().V1().Namespaces(),
+		events.NewRecorder(ctx, kubeClient, kubescheme.Scheme, "namespace-scheduler"),
+		c.ResourcesToSync,
+	)
+	if err != nil {
+		return err
+	}
+
 	c.kcpSharedInformerFactory.Start(ctx.Done())
 	c.crdSharedInformerFactory.Start(ctx.Done())
+	kubeSharedInformerFactory.Start(ctx.Done())
 	go clusterController.Start(ctx, c.NumThreads)
+	if heartbeatController != nil {
+		go heartbeatController.Start(ctx, c.NumThreads)
+	}
 	go apiresourceController.Start(ctx, c.NumThreads)
+	go placementController.Start(ctx, c.NumThreads)
+	go namespaceController.Start(ctx, c.NumThreads)
 
 	return nil
 }
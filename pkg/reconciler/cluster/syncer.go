@@ -19,6 +19,7 @@ package cluster
 import (
 	"context"
 	"fmt"
+	"strconv"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -26,6 +27,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/equality"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/kubernetes"
@@ -44,35 +46,25 @@ func syncerWorkloadName(logicalCluster string) string {
 	return syncerPrefix + "-from-" + logicalCluster
 }
 
-func syncerConfigMapName(logicalCluster string) string {
+func syncerSecretName(logicalCluster string) string {
 	return "kubeconfig-for-" + logicalCluster
 }
 
-// installSyncer installs the syncer image on the target cluster.
-//
-// It takes the syncer image name to run, and the kubeconfig of the kcp
-func installSyncer(ctx context.Context, client kubernetes.Interface, syncerImage, kubeconfig, clusterID, logicalCluster string, groupResourcesToSync []string) error {
-	// Create Namespace
-	if _, err := client.CoreV1().Namespaces().Create(ctx, &corev1.Namespace{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: syncerNS,
-		},
-	}, metav1.CreateOptions{}); err != nil && !k8serrors.IsAlreadyExists(err) {
-		return err
-	}
-
-	// Create ServiceAccount.
-	if _, err := client.CoreV1().ServiceAccounts(syncerNS).Create(ctx, &corev1.ServiceAccount{
-		ObjectMeta: metav1.ObjectMeta{
-			Namespace: syncerNS,
-			Name:      syncerSAName,
-		},
-	}, metav1.CreateOptions{}); err != nil && !k8serrors.IsAlreadyExists(err) {
-		return err
-	}
-
-	// Create or Update ClusterRole
+// syncerObjects are the objects running the syncer of a logical cluster on a physical
+// cluster. The namespace, service account and RBAC are shared by the syncers of all
+// logical clusters.
+type syncerObjects struct {
+	namespace          *corev1.Namespace
+	serviceAccount     *corev1.ServiceAccount
+	clusterRole        *rbacv1.ClusterRole
+	clusterRoleBinding *rbacv1.ClusterRoleBinding
+	secret             *corev1.Secret
+	deployment         *appsv1.Deployment
+}
 
+// newSyncerObjects returns the objects running the syncer image on the physical cluster, with
+// the given kubeconfig of kcp. The syncer opens a tunnel to kcp if asked to.
+func newSyncerObjects(syncerImage, kubeconfig, clusterID, logicalCluster string, groupResourcesToSync []string, options syncer.Options, tunnel bool) *syncerObjects {
 	resourcesWithStatus := sets.NewString()
 	apiGroups := sets.NewString()
 
@@ -82,23 +74,201 @@ func installSyncer(ctx context.Context, client kubernetes.Interface, syncerImage
 		apiGroups.Insert(gr.Group)
 	}
 
-	clusterRole := &rbacv1.ClusterRole{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: syncerSAName,
+	args := []string{
+		"-cluster", clusterID,
+		"-from_kubeconfig", "/kcp/kubeconfig",
+		"-from_cluster", logicalCluster,
+		"-conflict_policy", string(options.ConflictPolicy),
+		"-resync_period", options.ResyncPeriod.String(),
+		"-namespace_strategy", string(options.NamespaceStrategy),
+		"-qps", strconv.FormatFloat(float64(options.QPS), 'f', -1, 32),
+		"-burst", strconv.Itoa(options.Burst),
+		"-batch_interval", options.BatchInterval.String(),
+		"-heartbeat_period", options.HeartbeatPeriod.String(),
+	}
+	if options.UpsyncSelector != "" {
+		args = append(args, "-upsync_selector", options.UpsyncSelector)
+	}
+	if options.MirrorEvents {
+		args = append(args, "-mirror_events")
+	}
+	if tunnel {
+		args = append(args, "-tunnel")
+	}
+	args = append(args, groupResourcesToSync...)
+
+	rules := []rbacv1.PolicyRule{
+		{
+			Verbs:     []string{"create"},
+			APIGroups: []string{""},
+			Resources: []string{"namespaces"},
+		},
+		{
+			Verbs:     []string{"list", "watch", "create", "update", "patch", "get", "delete"},
+			Resources: resourcesWithStatus.List(),
+			APIGroups: apiGroups.List(),
 		},
-		Rules: []rbacv1.PolicyRule{
-			{
-				Verbs:     []string{"create"},
-				APIGroups: []string{""},
-				Resources: []string{"namespaces"},
+		{
+			// ConfigMaps and Secrets referenced by synced objects are synced with them.
+			Verbs:     []string{"list", "create", "patch", "get", "delete"},
+			APIGroups: []string{""},
+			Resources: []string{"configmaps", "secrets"},
+		},
+	}
+	if apiGroups.Has(rbacv1.GroupName) {
+		// Synced roles may grant permissions the syncer doesn't have itself.
+		rules = append(rules, rbacv1.PolicyRule{
+			Verbs:     []string{"escalate", "bind"},
+			APIGroups: []string{rbacv1.GroupName},
+			Resources: []string{"clusterroles", "roles"},
+		})
+	}
+	if options.MirrorEvents {
+		// Events are mirrored when their involved object, or one of its owners, is synced.
+		rules = append(rules, rbacv1.PolicyRule{
+			Verbs:     []string{"list", "watch"},
+			APIGroups: []string{""},
+			Resources: []string{"events"},
+		}, rbacv1.PolicyRule{
+			Verbs:     []string{"get"},
+			APIGroups: []string{"*"},
+			Resources: []string{"*"},
+		})
+	}
+
+	var one int32 = 1
+	return &syncerObjects{
+		namespace: &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: syncerNS,
+			},
+		},
+		serviceAccount: &corev1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: syncerNS,
+				Name:      syncerSAName,
+			},
+		},
+		clusterRole: &rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: syncerSAName,
+			},
+			Rules: rules,
+		},
+		clusterRoleBinding: &rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: syncerSAName,
+			},
+			Subjects: []rbacv1.Subject{
+				{
+					Kind:      "ServiceAccount",
+					Name:      syncerSAName,
+					Namespace: syncerNS,
+				},
+			},
+			RoleRef: rbacv1.RoleRef{
+				Kind:     "ClusterRole",
+				Name:     syncerSAName,
+				APIGroup: "rbac.authorization.k8s.io",
+			},
+		},
+		// Populate a Secret with the kubeconfig to reach the kcp, to be
+		// mounted into the syncer's Pod.
+		secret: &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: syncerNS,
+				Name:      syncerSecretName(logicalCluster),
 			},
-			{
-				Verbs:     []string{"list", "watch", "create", "update", "get", "delete"},
-				Resources: resourcesWithStatus.List(),
-				APIGroups: apiGroups.List(),
+			Type: corev1.SecretTypeOpaque,
+			Data: map[string][]byte{
+				"kubeconfig": []byte(kubeconfig),
+			},
+		},
+		deployment: &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: syncerNS,
+				Name:      syncerWorkloadName(logicalCluster),
+			},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &one,
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"app": syncerWorkloadName(logicalCluster),
+					},
+				},
+				Strategy: appsv1.DeploymentStrategy{
+					Type: appsv1.RecreateDeploymentStrategyType,
+				},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{
+							"app": syncerWorkloadName(logicalCluster),
+						},
+					},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{
+							Name:  "syncer",
+							Image: syncerImage,
+							Args:  args,
+							VolumeMounts: []corev1.VolumeMount{{
+								Name:      "kubeconfig",
+								MountPath: "/kcp",
+								ReadOnly:  true,
+							}},
+							TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+							Env: []corev1.EnvVar{{
+								Name: syncer.SyncerNamespaceKey,
+								ValueFrom: &corev1.EnvVarSource{
+									FieldRef: &corev1.ObjectFieldSelector{
+										FieldPath: "metadata.namespace",
+									},
+								},
+							}},
+						}},
+						Volumes: []corev1.Volume{{
+							Name: "kubeconfig",
+							VolumeSource: corev1.VolumeSource{
+								Secret: &corev1.SecretVolumeSource{
+									SecretName: syncerSecretName(logicalCluster),
+									Items: []corev1.KeyToPath{{
+										Key: "kubeconfig", Path: "kubeconfig",
+									}},
+								},
+							},
+						}},
+						ServiceAccountName: syncerSAName,
+					},
+				},
 			},
 		},
 	}
+}
+
+// SyncerManifests returns the objects to apply on a physical cluster registered with the
+// Tunnel connection mode, to run its syncer there with the given kubeconfig of kcp.
+func SyncerManifests(syncerImage, kubeconfig, clusterID, logicalCluster string, groupResourcesToSync []string, options syncer.Options) []runtime.Object {
+	objs := newSyncerObjects(syncerImage, kubeconfig, clusterID, logicalCluster, groupResourcesToSync, options, true)
+	return []runtime.Object{objs.namespace, objs.serviceAccount, objs.clusterRole, objs.clusterRoleBinding, objs.secret, objs.deployment}
+}
+
+// installSyncer installs the syncer image on the target cluster.
+//
+// It takes the syncer image name to run, and the kubeconfig of the kcp
+func installSyncer(ctx context.Context, client kubernetes.Interface, syncerImage, kubeconfig, clusterID, logicalCluster string, groupResourcesToSync []string, options syncer.Options) error {
+	objs := newSyncerObjects(syncerImage, kubeconfig, clusterID, logicalCluster, groupResourcesToSync, options, false)
+
+	// Create Namespace
+	if _, err := client.CoreV1().Namespaces().Create(ctx, objs.namespace, metav1.CreateOptions{}); err != nil && !k8serrors.IsAlreadyExists(err) {
+		return err
+	}
+
+	// Create ServiceAccount.
+	if _, err := client.CoreV1().ServiceAccounts(syncerNS).Create(ctx, objs.serviceAccount, metav1.CreateOptions{}); err != nil && !k8serrors.IsAlreadyExists(err) {
+		return err
+	}
+
+	// Create or Update ClusterRole
+	clusterRole := objs.clusterRole
 	if _, err := client.RbacV1().ClusterRoles().Create(ctx, clusterRole, metav1.CreateOptions{}); err != nil {
 		if !k8serrors.IsAlreadyExists(err) {
 			return err
@@ -117,40 +287,14 @@ func installSyncer(ctx context.Context, client kubernetes.Interface, syncerImage
 
 	// Create ClusterRoleBinding
 
-	if _, err := client.RbacV1().ClusterRoleBindings().Create(ctx, &rbacv1.ClusterRoleBinding{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: syncerSAName,
-		},
-		Subjects: []rbacv1.Subject{
-			{
-				Kind:      "ServiceAccount",
-				Name:      syncerSAName,
-				Namespace: syncerNS,
-			},
-		},
-		RoleRef: rbacv1.RoleRef{
-			Kind:     "ClusterRole",
-			Name:     syncerSAName,
-			APIGroup: "rbac.authorization.k8s.io",
-		},
-	}, metav1.CreateOptions{}); err != nil && !k8serrors.IsAlreadyExists(err) {
+	if _, err := client.RbacV1().ClusterRoleBindings().Create(ctx, objs.clusterRoleBinding, metav1.CreateOptions{}); err != nil && !k8serrors.IsAlreadyExists(err) {
 		return err
 	}
 
-	// Populate a ConfigMap with the kubeconfig to reach the kcp, to be
-	// mounted into the syncer's Pod.
-	configMap := &corev1.ConfigMap{
-		ObjectMeta: metav1.ObjectMeta{
-			Namespace: syncerNS,
-			Name:      syncerConfigMapName(logicalCluster),
-		},
-		Data: map[string]string{
-			"kubeconfig": kubeconfig,
-		},
-	}
-	if _, err := client.CoreV1().ConfigMaps(syncerNS).Create(ctx, configMap, metav1.CreateOptions{}); err != nil {
+	secret := objs.secret
+	if _, err := client.CoreV1().Secrets(syncerNS).Create(ctx, secret, metav1.CreateOptions{}); err != nil {
 		if k8serrors.IsAlreadyExists(err) {
-			if configMap, err = client.CoreV1().ConfigMaps(syncerNS).Update(ctx, configMap, metav1.UpdateOptions{}); err != nil {
+			if secret, err = client.CoreV1().Secrets(syncerNS).Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
 				return err
 			}
 		} else {
@@ -158,76 +302,10 @@ func installSyncer(ctx context.Context, client kubernetes.Interface, syncerImage
 		}
 	}
 
-	args := []string{
-		"-cluster", clusterID,
-		"-from_kubeconfig", "/kcp/kubeconfig",
-		"-from_cluster", logicalCluster,
-	}
-	args = append(args, groupResourcesToSync...)
-
-	var one int32 = 1
 	// Create or Update Deployment
-	deployment := &appsv1.Deployment{
-		ObjectMeta: metav1.ObjectMeta{
-			Namespace: syncerNS,
-			Name:      syncerWorkloadName(logicalCluster),
-		},
-		Spec: appsv1.DeploymentSpec{
-			Replicas: &one,
-			Selector: &metav1.LabelSelector{
-				MatchLabels: map[string]string{
-					"app": syncerWorkloadName(logicalCluster),
-				},
-			},
-			Strategy: appsv1.DeploymentStrategy{
-				Type: appsv1.RecreateDeploymentStrategyType,
-			},
-			Template: corev1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Labels: map[string]string{
-						"app": syncerWorkloadName(logicalCluster),
-					},
-					Annotations: map[string]string{
-						"kubeconfig/version": configMap.ResourceVersion,
-					},
-				},
-				Spec: corev1.PodSpec{
-					Containers: []corev1.Container{{
-						Name:  "syncer",
-						Image: syncerImage,
-						Args:  args,
-						VolumeMounts: []corev1.VolumeMount{{
-							Name:      "kubeconfig",
-							MountPath: "/kcp",
-							ReadOnly:  true,
-						}},
-						TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
-						Env: []corev1.EnvVar{{
-							Name: syncer.SyncerNamespaceKey,
-							ValueFrom: &corev1.EnvVarSource{
-								FieldRef: &corev1.ObjectFieldSelector{
-									FieldPath: "metadata.namespace",
-								},
-							},
-						}},
-					}},
-					Volumes: []corev1.Volume{{
-						Name: "kubeconfig",
-						VolumeSource: corev1.VolumeSource{
-							ConfigMap: &corev1.ConfigMapVolumeSource{
-								LocalObjectReference: corev1.LocalObjectReference{
-									Name: syncerConfigMapName(logicalCluster),
-								},
-								Items: []corev1.KeyToPath{{
-									Key: "kubeconfig", Path: "kubeconfig",
-								}},
-							},
-						},
-					}},
-					ServiceAccountName: syncerSAName,
-				},
-			},
-		},
+	deployment := objs.deployment
+	deployment.Spec.Template.Annotations = map[string]string{
+		"kubeconfig/version": secret.ResourceVersion,
 	}
 	if _, err := client.AppsV1().Deployments(syncerNS).Create(ctx, deployment, metav1.CreateOptions{}); err != nil {
 		if k8serrors.IsAlreadyExists(err) {
@@ -243,27 +321,55 @@ func installSyncer(ctx context.Context, client kubernetes.Interface, syncerImage
 	return nil
 }
 
-// uninstallSyncer uninstalls the syncer from the target cluster by deleting the syncer namespace.
-func uninstallSyncer(ctx context.Context, client kubernetes.Interface) {
-	if err := client.CoreV1().Namespaces().Delete(ctx, syncerNS, metav1.DeleteOptions{}); err != nil {
-		klog.Errorf("Deleting namespace %q: %v", syncerNS, err)
+// uninstallSyncer uninstalls the syncer of the logical cluster from the target cluster, and
+// returns whether it is gone. The syncer namespace and RBAC are shared with the syncers of
+// the other logical clusters and are kept.
+func uninstallSyncer(ctx context.Context, client kubernetes.Interface, logicalCluster string) (bool, error) {
+	// The Deployment is only gone once its Pods are, so that the syncer doesn't sync
+	// anything anymore.
+	foreground := metav1.DeletePropagationForeground
+	if err := client.AppsV1().Deployments(syncerNS).Delete(ctx, syncerWorkloadName(logicalCluster), metav1.DeleteOptions{
+		PropagationPolicy: &foreground,
+	}); err != nil && !k8serrors.IsNotFound(err) {
+		return false, err
 	}
+	if err := client.CoreV1().Secrets(syncerNS).Delete(ctx, syncerSecretName(logicalCluster), metav1.DeleteOptions{}); err != nil && !k8serrors.IsNotFound(err) {
+		return false, err
+	}
+
+	_, err := client.AppsV1().Deployments(syncerNS).Get(ctx, syncerWorkloadName(logicalCluster), metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return true, nil
+	}
+	return false, err
 }
 
-func healthcheckSyncer(ctx context.Context, client kubernetes.Interface, logicalCluster string) error {
-	pods, err := client.CoreV1().Pods(syncerNS).List(ctx, metav1.ListOptions{LabelSelector: "app=" + syncerWorkloadName(logicalCluster)})
+// syncerRolloutStatus returns whether the syncer Deployment on the target cluster has
+// been rolled out, and a message describing the rollout progress otherwise.
+func syncerRolloutStatus(ctx context.Context, client kubernetes.Interface, logicalCluster string) (bool, string, error) {
+	deployment, err := client.AppsV1().Deployments(syncerNS).Get(ctx, syncerWorkloadName(logicalCluster), metav1.GetOptions{})
 	if err != nil {
-		return err
+		return false, "", err
 	}
-	if len(pods.Items) == 0 {
-		return fmt.Errorf("Syncer pod not ready: not syncer pod found")
+
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
 	}
-	if len(pods.Items) > 1 {
-		return fmt.Errorf("Syncer pod not ready: there should be only 1 syncer pod")
+	for _, cond := range deployment.Status.Conditions {
+		if cond.Type == appsv1.DeploymentProgressing && cond.Reason == "ProgressDeadlineExceeded" {
+			return false, fmt.Sprintf("Syncer deployment %q exceeded its progress deadline", deployment.Name), nil
+		}
 	}
-	pod := pods.Items[0]
-	if pod.Status.Phase != corev1.PodRunning {
-		return fmt.Errorf("Syncer pod not ready: %s", pod.Status.Phase)
+	switch {
+	case deployment.Status.ObservedGeneration < deployment.Generation:
+		return false, "Waiting for the syncer deployment spec update to be observed", nil
+	case deployment.Status.UpdatedReplicas < replicas:
+		return false, fmt.Sprintf("Waiting for the syncer deployment rollout: %d of %d updated replicas", deployment.Status.UpdatedReplicas, replicas), nil
+	case deployment.Status.Replicas > deployment.Status.UpdatedReplicas:
+		return false, fmt.Sprintf("Waiting for the syncer deployment rollout: %d old replicas are pending termination", deployment.Status.Replicas-deployment.Status.UpdatedReplicas), nil
+	case deployment.Status.AvailableReplicas < deployment.Status.UpdatedReplicas:
+		return false, fmt.Sprintf("Waiting for the syncer deployment rollout: %d of %d updated replicas are available", deployment.Status.AvailableReplicas, deployment.Status.UpdatedReplicas), nil
 	}
-	return nil
+	return true, "", nil
 }
@@ -0,0 +1,112 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	clusterv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/cluster/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/syncer"
+)
+
+func TestSyncerRolloutStatus(t *testing.T) {
+	one := int32(1)
+	deployment := func(generation, observedGeneration int64, status appsv1.DeploymentStatus) *appsv1.Deployment {
+		status.ObservedGeneration = observedGeneration
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: syncerNS, Name: syncerWorkloadName("admin"), Generation: generation},
+			Spec:       appsv1.DeploymentSpec{Replicas: &one},
+			Status:     status,
+		}
+	}
+
+	tests := []struct {
+		name       string
+		deployment *appsv1.Deployment
+		rolledOut  bool
+		notFound   bool
+	}{
+		{name: "missing", notFound: true},
+		{name: "not observed", deployment: deployment(2, 1, appsv1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1})},
+		{name: "not updated", deployment: deployment(2, 2, appsv1.DeploymentStatus{Replicas: 1})},
+		{name: "old replicas", deployment: deployment(2, 2, appsv1.DeploymentStatus{Replicas: 2, UpdatedReplicas: 1, AvailableReplicas: 1})},
+		{name: "not available", deployment: deployment(2, 2, appsv1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1})},
+		{name: "rolled out", deployment: deployment(2, 2, appsv1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1}), rolledOut: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			if tt.deployment != nil {
+				client = fake.NewSimpleClientset(tt.deployment)
+			}
+			rolledOut, message, err := syncerRolloutStatus(context.Background(), client, "admin")
+			if tt.notFound {
+				if !k8serrors.IsNotFound(err) {
+					t.Fatalf("expected not found error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if rolledOut != tt.rolledOut {
+				t.Errorf("expected rolled out %v, got %v (%s)", tt.rolledOut, rolledOut, message)
+			}
+			if !rolledOut && message == "" {
+				t.Error("expected a message describing the rollout progress")
+			}
+		})
+	}
+}
+
+func TestSyncerOptionsFor(t *testing.T) {
+	c := &Controller{syncerOptions: syncer.Options{QPS: 10, Burst: 20, BatchInterval: time.Second}}
+
+	tests := []struct {
+		name  string
+		limit *clusterv1alpha1.SyncRateLimit
+		want  syncer.Options
+	}{
+		{name: "controller defaults", want: syncer.Options{QPS: 10, Burst: 20, BatchInterval: time.Second}},
+		{
+			name:  "overridden",
+			limit: &clusterv1alpha1.SyncRateLimit{QPS: 2, Burst: 3, BatchInterval: &metav1.Duration{Duration: 5 * time.Second}},
+			want:  syncer.Options{QPS: 2, Burst: 3, BatchInterval: 5 * time.Second},
+		},
+		{
+			name:  "default burst and batch interval",
+			limit: &clusterv1alpha1.SyncRateLimit{QPS: 2},
+			want:  syncer.Options{QPS: 2, Burst: 4, BatchInterval: time.Second},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := &clusterv1alpha1.Cluster{Spec: clusterv1alpha1.ClusterSpec{SyncRateLimit: tt.limit}}
+			if got := c.syncerOptionsFor(cluster); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected options %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
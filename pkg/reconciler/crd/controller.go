@@ -0,0 +1,288 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apiextensions-apiserver/pkg/apihelpers"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	crdinformer "k8s.io/apiextensions-apiserver/pkg/client/informers/externalversions/apiextensions/v1"
+	crdlister "k8s.io/apiextensions-apiserver/pkg/client/listers/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clusters"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	"github.com/kcp-dev/kcp/pkg/crdprojection"
+)
+
+const (
+	groupResourceIndex = "groupResource"
+	controllerName     = "crd"
+
+	// LogicalClusterLabel is set on every CustomResourceDefinition to the name of the
+	// logical cluster it was created in, so that CRDs can be selected per logical
+	// cluster when listing them across clusters.
+	LogicalClusterLabel = "kcp.dev/logical-cluster"
+
+	// SchemaConflict is a CustomResourceDefinition condition that is true when another
+	// logical cluster defines the same group and resource with a different schema.
+	SchemaConflict apiextensionsv1.CustomResourceDefinitionConditionType = "SchemaConflict"
+	// SchemaConflictReasonMismatch is the reason set on the SchemaConflict condition
+	// when the definitions differ.
+	SchemaConflictReasonMismatch = "SchemaMismatch"
+)
+
+// NewController returns a controller which keeps CustomResourceDefinitions bound to their
+// logical cluster and reports definitions of the same group and resource that differ
+// between logical clusters.
+//
+// Each logical cluster only serves and discovers the CRDs it owns, so such definitions do
+// not break one another. They are reported nonetheless, since objects of that type can no
+// longer be moved or synced between those logical clusters as-is.
+func NewController(
+	apiExtensionsClient apiextensionsclient.ClusterInterface,
+	crdInformer crdinformer.CustomResourceDefinitionInformer,
+) (*Controller, error) {
+	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+
+	c := &Controller{
+		queue:               queue,
+		apiExtensionsClient: apiExtensionsClient,
+		crdIndexer:          crdInformer.Informer().GetIndexer(),
+		crdLister:           crdInformer.Lister(),
+		syncChecks: []cache.InformerSynced{
+			crdInformer.Informer().HasSynced,
+		},
+	}
+
+	crdInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { c.enqueueGroupResource(obj) },
+		UpdateFunc: func(_, obj interface{}) { c.enqueueGroupResource(obj) },
+		DeleteFunc: func(obj interface{}) { c.enqueueGroupResource(obj) },
+	})
+	if err := c.crdIndexer.AddIndexers(map[string]cache.IndexFunc{
+		groupResourceIndex: func(obj interface{}) ([]string, error) {
+			if crd, ok := obj.(*apiextensionsv1.CustomResourceDefinition); ok {
+				return []string{groupResourceKey(crd)}, nil
+			}
+			return []string{}, nil
+		},
+	}); err != nil {
+		return nil, fmt.Errorf("failed to add indexer for CustomResourceDefinition: %w", err)
+	}
+
+	return c, nil
+}
+
+// Controller watches CustomResourceDefinitions across all logical clusters.
+type Controller struct {
+	queue workqueue.RateLimitingInterface
+
+	apiExtensionsClient apiextensionsclient.ClusterInterface
+	crdIndexer          cache.Indexer
+	crdLister           crdlister.CustomResourceDefinitionLister
+
+	syncChecks []cache.InformerSynced
+}
+
+func groupResourceKey(crd *apiextensionsv1.CustomResourceDefinition) string {
+	return crd.Spec.Names.Plural + "." + crd.Spec.Group
+}
+
+// enqueueGroupResource queues every CRD defining the same group and resource as obj,
+// since a change to one of them may create or resolve a conflict for the others.
+func (c *Controller) enqueueGroupResource(obj interface{}) {
+	crd, ok := obj.(*apiextensionsv1.CustomResourceDefinition)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			klog.V(2).Infof("Couldn't get object from tombstone %#v", obj)
+			return
+		}
+		crd, ok = tombstone.Obj.(*apiextensionsv1.CustomResourceDefinition)
+		if !ok {
+			klog.V(2).Infof("Tombstone contained object that is not a CustomResourceDefinition: %#v", obj)
+			return
+		}
+	}
+	crds, err := c.crdIndexer.ByIndex(groupResourceIndex, groupResourceKey(crd))
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	for _, crd := range crds {
+		key, err := cache.MetaNamespaceKeyFunc(crd)
+		if err != nil {
+			runtime.HandleError(err)
+			return
+		}
+		klog.V(4).Infof("queueing crd %q", key)
+		c.queue.Add(key)
+	}
+}
+
+func (c *Controller) Start(ctx context.Context, numThreads int) {
+	defer runtime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	klog.Info("Starting CRD controller")
+	defer klog.Info("Shutting down CRD controller")
+
+	if !cache.WaitForNamedCacheSync(controllerName, ctx.Done(), c.syncChecks...) {
+		klog.Warning("Failed to wait for caches to sync")
+		return
+	}
+
+	for i := 0; i < numThreads; i++ {
+		go wait.Until(func() { c.startWorker(ctx) }, time.Second, ctx.Done())
+	}
+
+	<-ctx.Done()
+}
+
+func (c *Controller) startWorker(ctx context.Context) {
+	for c.processNextWorkItem(ctx) {
+	}
+}
+
+func (c *Controller) processNextWorkItem(ctx context.Context) bool {
+	// Wait until there is a new item in the working queue
+	k, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	key := k.(string)
+
+	// No matter what, tell the queue we're done with this key, to unblock
+	// other workers.
+	defer c.queue.Done(key)
+
+	if err := c.process(ctx, key); err != nil {
+		runtime.HandleError(fmt.Errorf("%q controller failed to sync %q, err: %w", controllerName, key, err))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	c.queue.Forget(key)
+	return true
+}
+
+func (c *Controller) process(ctx context.Context, key string) error {
+	obj, err := c.crdLister.Get(key)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil // object deleted before we handled it
+		}
+		return err
+	}
+	if crdprojection.IsProjected(obj) {
+		return nil // projected CRDs are not stored, and reported in their own logical cluster
+	}
+	clusterName, _ := clusters.SplitClusterAwareKey(key)
+	client := c.apiExtensionsClient.Cluster(clusterName).ApiextensionsV1().CustomResourceDefinitions()
+
+	if obj.Labels[LogicalClusterLabel] != obj.ClusterName {
+		crd := obj.DeepCopy()
+		if crd.Labels == nil {
+			crd.Labels = map[string]string{}
+		}
+		crd.Labels[LogicalClusterLabel] = crd.ClusterName
+		// the label update triggers another reconciliation, which then checks for conflicts
+		_, err := client.Update(ctx, crd, metav1.UpdateOptions{})
+		return err
+	}
+
+	conflicts, err := c.conflictingClusters(obj)
+	if err != nil {
+		return err
+	}
+	crd := obj.DeepCopy()
+	if len(conflicts) > 0 {
+		apihelpers.SetCRDCondition(crd, apiextensionsv1.CustomResourceDefinitionCondition{
+			Type:    SchemaConflict,
+			Status:  apiextensionsv1.ConditionTrue,
+			Reason:  SchemaConflictReasonMismatch,
+			Message: fmt.Sprintf("%s is defined differently in logical clusters: %s", groupResourceKey(crd), strings.Join(conflicts, ", ")),
+		})
+	} else if apihelpers.FindCRDCondition(crd, SchemaConflict) != nil {
+		apihelpers.SetCRDCondition(crd, apiextensionsv1.CustomResourceDefinitionCondition{
+			Type:    SchemaConflict,
+			Status:  apiextensionsv1.ConditionFalse,
+			Reason:  "NoConflict",
+			Message: "No other logical cluster defines this resource differently.",
+		})
+	}
+	if equality.Semantic.DeepEqual(obj.Status, crd.Status) {
+		return nil
+	}
+	if len(conflicts) > 0 {
+		klog.Infof("crd %q conflicts with the definitions in logical clusters %v", key, conflicts)
+	}
+	_, err = client.UpdateStatus(ctx, crd, metav1.UpdateOptions{})
+	return err
+}
+
+// conflictingClusters returns the sorted names of the other logical clusters which
+// define the group and resource of crd differently.
+func (c *Controller) conflictingClusters(crd *apiextensionsv1.CustomResourceDefinition) ([]string, error) {
+	others, err := c.crdIndexer.ByIndex(groupResourceIndex, groupResourceKey(crd))
+	if err != nil {
+		return nil, err
+	}
+	var conflicts []string
+	for _, obj := range others {
+		other, ok := obj.(*apiextensionsv1.CustomResourceDefinition)
+		if !ok || other.ClusterName == crd.ClusterName || crdprojection.IsProjected(other) {
+			continue
+		}
+		if !Compatible(crd, other) {
+			conflicts = append(conflicts, other.ClusterName)
+		}
+	}
+	sort.Strings(conflicts)
+	return conflicts, nil
+}
+
+// Compatible returns whether two CustomResourceDefinitions for the same group and
+// resource agree on kind, scope and the schema of every version they both define.
+func Compatible(crd, other *apiextensionsv1.CustomResourceDefinition) bool {
+	if crd.Spec.Names.Kind != other.Spec.Names.Kind || crd.Spec.Scope != other.Spec.Scope {
+		return false
+	}
+	for _, version := range crd.Spec.Versions {
+		for _, otherVersion := range other.Spec.Versions {
+			if version.Name != otherVersion.Name {
+				continue
+			}
+			if !equality.Semantic.DeepEqual(version.Schema, otherVersion.Schema) {
+				return false
+			}
+		}
+	}
+	return true
+}
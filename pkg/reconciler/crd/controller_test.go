@@ -0,0 +1,86 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crd
+
+import (
+	"testing"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+func newCRD(scope apiextensionsv1.ResourceScope, versions map[string]string) *apiextensionsv1.CustomResourceDefinition {
+	crd := &apiextensionsv1.CustomResourceDefinition{
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: "example.dev",
+			Names: apiextensionsv1.CustomResourceDefinitionNames{Plural: "widgets", Kind: "Widget"},
+			Scope: scope,
+		},
+	}
+	for name, fieldType := range versions {
+		crd.Spec.Versions = append(crd.Spec.Versions, apiextensionsv1.CustomResourceDefinitionVersion{
+			Name: name,
+			Schema: &apiextensionsv1.CustomResourceValidation{
+				OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+					Type: "object",
+					Properties: map[string]apiextensionsv1.JSONSchemaProps{
+						"spec": {Type: fieldType},
+					},
+				},
+			},
+		})
+	}
+	return crd
+}
+
+func TestCompatible(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		crd, other *apiextensionsv1.CustomResourceDefinition
+		compatible bool
+	}{
+		{
+			name:       "identical",
+			crd:        newCRD(apiextensionsv1.NamespaceScoped, map[string]string{"v1": "object"}),
+			other:      newCRD(apiextensionsv1.NamespaceScoped, map[string]string{"v1": "object"}),
+			compatible: true,
+		},
+		{
+			name:       "different scope",
+			crd:        newCRD(apiextensionsv1.NamespaceScoped, map[string]string{"v1": "object"}),
+			other:      newCRD(apiextensionsv1.ClusterScoped, map[string]string{"v1": "object"}),
+			compatible: false,
+		},
+		{
+			name:       "different schema for a shared version",
+			crd:        newCRD(apiextensionsv1.NamespaceScoped, map[string]string{"v1": "object"}),
+			other:      newCRD(apiextensionsv1.NamespaceScoped, map[string]string{"v1": "string"}),
+			compatible: false,
+		},
+		{
+			name:       "no shared version",
+			crd:        newCRD(apiextensionsv1.NamespaceScoped, map[string]string{"v1": "object"}),
+			other:      newCRD(apiextensionsv1.NamespaceScoped, map[string]string{"v2": "string"}),
+			compatible: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Compatible(tc.crd, tc.other); got != tc.compatible {
+				t.Errorf("expected compatible=%v, got %v", tc.compatible, got)
+			}
+		})
+	}
+}
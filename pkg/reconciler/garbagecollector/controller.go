@@ -0,0 +1,275 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package garbagecollector deletes the objects of all logical clusters whose owners are
+// gone, following the owner references of their metadata like the garbage collector of
+// kube-controller-manager.
+package garbagecollector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	crdinformer "k8s.io/apiextensions-apiserver/pkg/client/informers/externalversions/apiextensions/v1"
+	crdlister "k8s.io/apiextensions-apiserver/pkg/client/listers/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clusters"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	"github.com/kcp-dev/kcp/pkg/client/clustercontext"
+	"github.com/kcp-dev/kcp/pkg/client/clusterrestmapper"
+)
+
+const (
+	controllerName = "garbage-collector"
+
+	// uidIndex indexes objects by their logical cluster and UID.
+	uidIndex = "uid"
+	// ownerIndex indexes objects by the logical cluster and UIDs of their owners.
+	ownerIndex = "owner"
+
+	// discoveryInterval is how often the deletable resources are looked up, to watch new
+	// ones and stop watching those which are not served anymore.
+	discoveryInterval = 30 * time.Second
+)
+
+// NewController returns a garbage collector for the objects of all logical clusters of
+// the server reached with config. It watches the metadata of every deletable resource,
+// built-in or defined by a CustomResourceDefinition in any logical cluster.
+func NewController(
+	config *rest.Config,
+	discoveryClient discovery.DiscoveryInterface,
+	crdInformer crdinformer.CustomResourceDefinitionInformer,
+	restMapper *clusterrestmapper.Mapper,
+) (*Controller, error) {
+	wildcardClient, err := metadataClientFor(config, "*")
+	if err != nil {
+		return nil, err
+	}
+
+	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), controllerName)
+
+	c := &Controller{
+		queue:           queue,
+		wildcardClient:  wildcardClient,
+		discoveryClient: discoveryClient,
+		crdLister:       crdInformer.Lister(),
+		monitors:        map[schema.GroupKind]*monitor{},
+		syncChecks: []cache.InformerSynced{
+			crdInformer.Informer().HasSynced,
+		},
+	}
+	c.clientFor = func(cluster string) (metadata.Interface, error) {
+		return metadataClientFor(config, cluster)
+	}
+	c.mapperFor = restMapper.Cluster
+
+	return c, nil
+}
+
+// Controller deletes the objects whose owners are gone, and finishes the foreground and
+// orphaning deletions of owners.
+type Controller struct {
+	queue workqueue.RateLimitingInterface
+
+	// clientFor returns a metadata client for the given logical cluster.
+	clientFor func(cluster string) (metadata.Interface, error)
+	// mapperFor returns the RESTMapper of the given logical cluster, which resolves the
+	// owners looked up on the server.
+	mapperFor       func(cluster string) (meta.RESTMapper, error)
+	wildcardClient  metadata.Interface
+	discoveryClient discovery.DiscoveryInterface
+	crdLister       crdlister.CustomResourceDefinitionLister
+
+	// monitors watch the metadata of the deletable resources, by kind.
+	monitors     map[schema.GroupKind]*monitor
+	monitorsLock sync.RWMutex
+
+	syncChecks []cache.InformerSynced
+}
+
+// queueKey identifies an object to collect.
+type queueKey struct {
+	groupKind schema.GroupKind
+	key       string
+}
+
+// metadataClientFor returns a metadata client for the given logical cluster, or all of
+// them for "*".
+func metadataClientFor(config *rest.Config, cluster string) (metadata.Interface, error) {
+	return metadata.NewForConfig(clustercontext.ConfigFor(config, cluster))
+}
+
+func indexUID(obj interface{}) ([]string, error) {
+	metaObj, err := meta.Accessor(obj)
+	if err != nil {
+		return []string{}, nil
+	}
+	return []string{uidKey(metaObj.GetClusterName(), metaObj.GetUID())}, nil
+}
+
+func indexOwners(obj interface{}) ([]string, error) {
+	metaObj, err := meta.Accessor(obj)
+	if err != nil {
+		return []string{}, nil
+	}
+	owners := make([]string, 0, len(metaObj.GetOwnerReferences()))
+	for _, ref := range metaObj.GetOwnerReferences() {
+		owners = append(owners, uidKey(metaObj.GetClusterName(), ref.UID))
+	}
+	return owners, nil
+}
+
+// uidKey identifies an object by UID across logical clusters.
+func uidKey(logicalCluster string, uid types.UID) string {
+	return clusters.ToClusterAwareKey(logicalCluster, string(uid))
+}
+
+func (c *Controller) enqueue(groupKind schema.GroupKind, obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	c.queue.Add(queueKey{groupKind: groupKind, key: key})
+}
+
+// enqueueIndexed enqueues the objects of all watched resources found under the given
+// index key.
+func (c *Controller) enqueueIndexed(indexName, indexKey string) {
+	c.monitorsLock.RLock()
+	defer c.monitorsLock.RUnlock()
+
+	for groupKind, m := range c.monitors {
+		objs, err := m.informer.GetIndexer().ByIndex(indexName, indexKey)
+		if err != nil {
+			runtime.HandleError(err)
+			continue
+		}
+		for _, obj := range objs {
+			c.enqueue(groupKind, obj)
+		}
+	}
+}
+
+// onChange enqueues the object if it has owners, or if its deletion waits for the
+// garbage collector.
+func (c *Controller) onChange(groupKind schema.GroupKind, obj interface{}) {
+	metaObj, err := meta.Accessor(obj)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	if len(metaObj.GetOwnerReferences()) > 0 || (metaObj.GetDeletionTimestamp() != nil && hasGCFinalizer(metaObj)) {
+		c.enqueue(groupKind, obj)
+	}
+}
+
+// onDelete enqueues the dependents of the deleted object, which may have to be deleted
+// too, and its owners, whose foreground deletion may be waiting for it.
+func (c *Controller) onDelete(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	metaObj, err := meta.Accessor(obj)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	c.enqueueIndexed(ownerIndex, uidKey(metaObj.GetClusterName(), metaObj.GetUID()))
+	for _, ref := range metaObj.GetOwnerReferences() {
+		c.enqueueIndexed(uidIndex, uidKey(metaObj.GetClusterName(), ref.UID))
+	}
+}
+
+func (c *Controller) Start(ctx context.Context, numThreads int) {
+	defer runtime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	klog.Info("Starting GarbageCollector controller")
+	defer klog.Info("Shutting down GarbageCollector controller")
+
+	if !cache.WaitForNamedCacheSync(controllerName, ctx.Done(), c.syncChecks...) {
+		klog.Warning("Failed to wait for caches to sync")
+		return
+	}
+
+	go wait.UntilWithContext(ctx, c.syncMonitors, discoveryInterval)
+
+	for i := 0; i < numThreads; i++ {
+		go wait.Until(func() { c.startWorker(ctx) }, time.Second, ctx.Done())
+	}
+
+	<-ctx.Done()
+	c.stopMonitors()
+}
+
+func (c *Controller) startWorker(ctx context.Context) {
+	for c.processNextWorkItem(ctx) {
+	}
+}
+
+func (c *Controller) processNextWorkItem(ctx context.Context) bool {
+	// Wait until there is a new item in the working queue
+	k, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	key := k.(queueKey)
+
+	// No matter what, tell the queue we're done with this key, to unblock
+	// other workers.
+	defer c.queue.Done(key)
+
+	if err := c.process(ctx, key); err != nil {
+		runtime.HandleError(fmt.Errorf("%q controller failed to sync %q of %s, err: %w", controllerName, key.key, key.groupKind, err))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	c.queue.Forget(key)
+	return true
+}
+
+func (c *Controller) process(ctx context.Context, key queueKey) error {
+	m := c.monitor(key.groupKind)
+	if m == nil {
+		return nil
+	}
+	obj, exists, err := m.informer.GetIndexer().GetByKey(key.key)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return nil
+	}
+	metaObj, ok := obj.(*metav1.PartialObjectMetadata)
+	if !ok {
+		return fmt.Errorf("unexpected object %T", obj)
+	}
+	return c.collect(ctx, m.resource, metaObj)
+}
@@ -0,0 +1,341 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package garbagecollector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/klog/v2"
+)
+
+// ownerState is what the garbage collector makes of an owner of an object.
+type ownerState int
+
+const (
+	// ownerSolid owners exist and keep their dependents.
+	ownerSolid ownerState = iota
+	// ownerDangling owners are gone.
+	ownerDangling
+	// ownerWaiting owners are being deleted in the foreground, once their dependents are.
+	ownerWaiting
+)
+
+func hasFinalizer(obj metav1.Object, finalizer string) bool {
+	for _, f := range obj.GetFinalizers() {
+		if f == finalizer {
+			return true
+		}
+	}
+	return false
+}
+
+// hasGCFinalizer returns whether the deletion of the object waits for the garbage
+// collector to delete or orphan its dependents.
+func hasGCFinalizer(obj metav1.Object) bool {
+	return hasFinalizer(obj, metav1.FinalizerOrphanDependents) || hasFinalizer(obj, metav1.FinalizerDeleteDependents)
+}
+
+// collect deletes the object if none of its owners is left, and finishes its deletion
+// if it is waiting for its dependents.
+func (c *Controller) collect(ctx context.Context, r resource, obj *metav1.PartialObjectMetadata) error {
+	if obj.DeletionTimestamp != nil {
+		switch {
+		case hasFinalizer(obj, metav1.FinalizerOrphanDependents):
+			return c.orphanDependents(ctx, r, obj)
+		case hasFinalizer(obj, metav1.FinalizerDeleteDependents):
+			return c.deleteDependents(ctx, r, obj)
+		}
+		return nil
+	}
+	if len(obj.OwnerReferences) == 0 {
+		return nil
+	}
+
+	var solid, dangling, waiting []metav1.OwnerReference
+	for _, ref := range obj.OwnerReferences {
+		state, err := c.ownerState(ctx, obj, ref)
+		if err != nil {
+			return err
+		}
+		switch state {
+		case ownerSolid:
+			solid = append(solid, ref)
+		case ownerDangling:
+			dangling = append(dangling, ref)
+		case ownerWaiting:
+			waiting = append(waiting, ref)
+		}
+	}
+
+	switch {
+	case len(solid) == 0:
+		// Owners being deleted in the foreground wait for the dependents of their
+		// dependents too.
+		policy := metav1.DeletePropagationBackground
+		if len(waiting) > 0 && len(c.dependents(obj)) > 0 {
+			policy = metav1.DeletePropagationForeground
+		}
+		return c.delete(ctx, r, obj, policy)
+	case len(dangling) > 0 || len(waiting) > 0:
+		// The object stays for its other owners: it doesn't reference the deleted ones
+		// anymore, nor does it block the deletion of the ones being deleted.
+		refs := ownerReferencesWithout(obj.OwnerReferences, dangling, waiting)
+		if equality.Semantic.DeepEqual(refs, obj.OwnerReferences) {
+			return nil
+		}
+		if err := c.updateOwnerReferences(ctx, r, obj, refs); err != nil {
+			return err
+		}
+		for _, ref := range waiting {
+			c.enqueueIndexed(uidIndex, uidKey(obj.ClusterName, ref.UID))
+		}
+	}
+	return nil
+}
+
+// ownerReferencesWithout returns the owner references without the dangling ones, and
+// with the waiting ones not blocking the deletion of their owner anymore.
+func ownerReferencesWithout(refs, dangling, waiting []metav1.OwnerReference) []metav1.OwnerReference {
+	matches := func(ref metav1.OwnerReference, refs []metav1.OwnerReference) bool {
+		for _, r := range refs {
+			if r.UID == ref.UID {
+				return true
+			}
+		}
+		return false
+	}
+	result := make([]metav1.OwnerReference, 0, len(refs))
+	for _, ref := range refs {
+		if matches(ref, dangling) {
+			continue
+		}
+		if matches(ref, waiting) {
+			unblocked := false
+			ref.BlockOwnerDeletion = &unblocked
+		}
+		result = append(result, ref)
+	}
+	return result
+}
+
+// ownerState looks up the owner of the object, in the cache first and on the server when
+// it is missing there, since the cache may be behind.
+func (c *Controller) ownerState(ctx context.Context, dependent *metav1.PartialObjectMetadata, ref metav1.OwnerReference) (ownerState, error) {
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		klog.V(2).Infof("Invalid owner reference of %s|%s/%s: %v", dependent.ClusterName, dependent.Namespace, dependent.Name, err)
+		return ownerSolid, nil
+	}
+	m := c.monitor(schema.GroupKind{Group: gv.Group, Kind: ref.Kind})
+	if m == nil {
+		// Objects are only deleted when their owners are known to be gone.
+		klog.V(4).Infof("Owner %s %s of %s|%s/%s is not watched", ref.Kind, ref.Name, dependent.ClusterName, dependent.Namespace, dependent.Name)
+		return ownerSolid, nil
+	}
+
+	var owner metav1.Object
+	cached, err := m.informer.GetIndexer().ByIndex(uidIndex, uidKey(dependent.ClusterName, ref.UID))
+	if err != nil {
+		return ownerSolid, err
+	}
+	if len(cached) > 0 {
+		if owner, err = meta.Accessor(cached[0]); err != nil {
+			return ownerSolid, err
+		}
+	} else {
+		// The kind may be served by another resource in the logical cluster of the
+		// dependent than the one watched, since each logical cluster has its own CRDs.
+		mapper, err := c.mapperFor(dependent.ClusterName)
+		if err != nil {
+			return ownerSolid, err
+		}
+		mapping, err := mapper.RESTMapping(schema.GroupKind{Group: gv.Group, Kind: ref.Kind}, gv.Version)
+		if meta.IsNoMatchError(err) {
+			klog.V(4).Infof("Owner %s %s of %s|%s/%s is not served in its logical cluster", ref.Kind, ref.Name, dependent.ClusterName, dependent.Namespace, dependent.Name)
+			return ownerSolid, nil
+		}
+		if err != nil {
+			return ownerSolid, err
+		}
+		namespaced := mapping.Scope.Name() == meta.RESTScopeNameNamespace
+		if namespaced && dependent.Namespace == "" {
+			// Cluster-scoped objects can't be owned by namespaced ones.
+			return ownerDangling, nil
+		}
+		namespace := ""
+		if namespaced {
+			namespace = dependent.Namespace
+		}
+		client, err := c.clientFor(dependent.ClusterName)
+		if err != nil {
+			return ownerSolid, err
+		}
+		live, err := client.Resource(mapping.Resource).Namespace(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			return ownerDangling, nil
+		}
+		if err != nil {
+			return ownerSolid, err
+		}
+		if live.UID != ref.UID {
+			return ownerDangling, nil
+		}
+		owner = live
+	}
+
+	if owner.GetDeletionTimestamp() != nil && hasFinalizer(owner, metav1.FinalizerDeleteDependents) {
+		return ownerWaiting, nil
+	}
+	return ownerSolid, nil
+}
+
+// dependents returns the watched objects owned by the object, by kind.
+func (c *Controller) dependents(obj *metav1.PartialObjectMetadata) map[schema.GroupKind][]*metav1.PartialObjectMetadata {
+	c.monitorsLock.RLock()
+	defer c.monitorsLock.RUnlock()
+
+	dependents := map[schema.GroupKind][]*metav1.PartialObjectMetadata{}
+	for groupKind, m := range c.monitors {
+		objs, err := m.informer.GetIndexer().ByIndex(ownerIndex, uidKey(obj.ClusterName, obj.UID))
+		if err != nil {
+			runtime.HandleError(err)
+			continue
+		}
+		for _, dep := range objs {
+			if dependent, ok := dep.(*metav1.PartialObjectMetadata); ok {
+				dependents[groupKind] = append(dependents[groupKind], dependent)
+			}
+		}
+	}
+	return dependents
+}
+
+// deleteDependents lets the dependents of the object being deleted in the foreground be
+// deleted, and finishes its deletion once those blocking it are gone.
+func (c *Controller) deleteDependents(ctx context.Context, r resource, obj *metav1.PartialObjectMetadata) error {
+	blocking := 0
+	for groupKind, dependents := range c.dependents(obj) {
+		for _, dependent := range dependents {
+			for _, ref := range dependent.OwnerReferences {
+				if ref.UID != obj.UID || ref.BlockOwnerDeletion == nil || !*ref.BlockOwnerDeletion {
+					continue
+				}
+				blocking++
+				if dependent.DeletionTimestamp == nil {
+					c.enqueue(groupKind, dependent)
+				}
+			}
+		}
+	}
+	if blocking > 0 {
+		// The deletion of each dependent enqueues the object again.
+		return nil
+	}
+	return c.removeFinalizer(ctx, r, obj, metav1.FinalizerDeleteDependents)
+}
+
+// orphanDependents removes the owner references to the object being deleted from its
+// dependents, and finishes its deletion.
+func (c *Controller) orphanDependents(ctx context.Context, r resource, obj *metav1.PartialObjectMetadata) error {
+	var errs []error
+	for groupKind, dependents := range c.dependents(obj) {
+		m := c.monitor(groupKind)
+		if m == nil {
+			continue
+		}
+		for _, dependent := range dependents {
+			refs := ownerReferencesWithout(dependent.OwnerReferences, []metav1.OwnerReference{{UID: obj.UID}}, nil)
+			if err := c.updateOwnerReferences(ctx, m.resource, dependent, refs); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return utilerrors.NewAggregate(errs)
+	}
+	return c.removeFinalizer(ctx, r, obj, metav1.FinalizerOrphanDependents)
+}
+
+func (c *Controller) delete(ctx context.Context, r resource, obj *metav1.PartialObjectMetadata, policy metav1.DeletionPropagation) error {
+	client, err := c.clientFor(obj.ClusterName)
+	if err != nil {
+		return err
+	}
+	uid := obj.UID
+	err = client.Resource(r.gvr).Namespace(obj.Namespace).Delete(ctx, obj.Name, metav1.DeleteOptions{
+		Preconditions:     &metav1.Preconditions{UID: &uid},
+		PropagationPolicy: &policy,
+	})
+	if errors.IsNotFound(err) || errors.IsConflict(err) {
+		// Gone or replaced in the meantime.
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete %s %s|%s/%s: %w", r.gvr.Resource, obj.ClusterName, obj.Namespace, obj.Name, err)
+	}
+	klog.Infof("Deleted %s %s|%s/%s whose owners are gone", r.gvr.Resource, obj.ClusterName, obj.Namespace, obj.Name)
+	return nil
+}
+
+// updateOwnerReferences replaces the owner references of the object, unless it changed
+// in the meantime.
+func (c *Controller) updateOwnerReferences(ctx context.Context, r resource, obj *metav1.PartialObjectMetadata, refs []metav1.OwnerReference) error {
+	return c.patchMetadata(ctx, r, obj, map[string]interface{}{
+		"resourceVersion": obj.ResourceVersion,
+		"ownerReferences": refs,
+	})
+}
+
+// removeFinalizer removes the finalizer from the object, unless it changed in the
+// meantime.
+func (c *Controller) removeFinalizer(ctx context.Context, r resource, obj *metav1.PartialObjectMetadata, finalizer string) error {
+	finalizers := make([]string, 0, len(obj.Finalizers))
+	for _, f := range obj.Finalizers {
+		if f != finalizer {
+			finalizers = append(finalizers, f)
+		}
+	}
+	return c.patchMetadata(ctx, r, obj, map[string]interface{}{
+		"resourceVersion": obj.ResourceVersion,
+		"finalizers":      finalizers,
+	})
+}
+
+func (c *Controller) patchMetadata(ctx context.Context, r resource, obj *metav1.PartialObjectMetadata, metadata map[string]interface{}) error {
+	patch, err := json.Marshal(map[string]interface{}{"metadata": metadata})
+	if err != nil {
+		return err
+	}
+	client, err := c.clientFor(obj.ClusterName)
+	if err != nil {
+		return err
+	}
+	if _, err := client.Resource(r.gvr).Namespace(obj.Namespace).Patch(ctx, obj.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to patch %s %s|%s/%s: %w", r.gvr.Resource, obj.ClusterName, obj.Namespace, obj.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package garbagecollector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/metadata"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestCollect(t *testing.T) {
+	replicaSets := resource{gvr: schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "replicasets"}, namespaced: true}
+	pods := resource{gvr: schema.GroupVersionResource{Version: "v1", Resource: "pods"}, namespaced: true}
+
+	object := func(name, uid string, owners ...string) *metav1.PartialObjectMetadata {
+		obj := &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{
+			ClusterName: "admin",
+			Namespace:   "default",
+			Name:        name,
+			UID:         types.UID(uid),
+		}}
+		for _, owner := range owners {
+			obj.OwnerReferences = append(obj.OwnerReferences, metav1.OwnerReference{
+				APIVersion: "apps/v1",
+				Kind:       "ReplicaSet",
+				Name:       owner,
+				UID:        types.UID(owner + "-uid"),
+			})
+		}
+		return obj
+	}
+	monitorOf := func(r resource, objs ...*metav1.PartialObjectMetadata) *monitor {
+		informer := cache.NewSharedIndexInformer(&cache.ListWatch{}, &metav1.PartialObjectMetadata{}, 0, cache.Indexers{
+			uidIndex:   indexUID,
+			ownerIndex: indexOwners,
+		})
+		for _, obj := range objs {
+			if err := informer.GetIndexer().Add(obj); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return &monitor{resource: r, informer: informer}
+	}
+
+	dependents := []*metav1.PartialObjectMetadata{
+		object("owned", "pod-1", "live"),
+		object("orphaned", "pod-2", "gone"),
+		object("partially-orphaned", "pod-3", "live", "gone"),
+		object("unowned", "pod-4"),
+	}
+	client := metadatafake.NewSimpleMetadataClient(runtime.NewScheme())
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "ReplicaSet"}, meta.RESTScopeNamespace)
+	c := &Controller{
+		clientFor: func(string) (metadata.Interface, error) { return client, nil },
+		mapperFor: func(string) (meta.RESTMapper, error) { return mapper, nil },
+		monitors: map[schema.GroupKind]*monitor{
+			{Group: "apps", Kind: "ReplicaSet"}: monitorOf(replicaSets, object("live", "live-uid")),
+			{Kind: "Pod"}:                       monitorOf(pods, dependents...),
+		},
+	}
+	for _, dependent := range dependents {
+		if err := c.collect(context.Background(), pods, dependent); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []string
+	for _, action := range client.Actions() {
+		switch action := action.(type) {
+		case clienttesting.DeleteActionImpl:
+			got = append(got, "delete "+action.GetName())
+		case clienttesting.PatchActionImpl:
+			got = append(got, "patch "+action.GetName()+" "+string(action.GetPatch()))
+		}
+	}
+	want := []string{
+		"delete orphaned",
+		`patch partially-orphaned {"metadata":{"ownerReferences":[{"apiVersion":"apps/v1","kind":"ReplicaSet","name":"live","uid":"live-uid"}],"resourceVersion":""}}`,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected actions (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package garbagecollector
+
+import (
+	"context"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	"github.com/kcp-dev/kcp/pkg/client/metadatainformer"
+)
+
+// ignoredResources are not collected: their objects are not owned, and there are many of
+// them.
+var ignoredResources = map[schema.GroupResource]bool{
+	{Group: "", Resource: "events"}:              true,
+	{Group: "events.k8s.io", Resource: "events"}: true,
+}
+
+// resource is a deletable resource.
+type resource struct {
+	gvr        schema.GroupVersionResource
+	namespaced bool
+}
+
+// monitor watches the metadata of the objects of a resource across logical clusters.
+type monitor struct {
+	resource
+	informer cache.SharedIndexInformer
+	stopCh   chan struct{}
+}
+
+// deletableResources returns the resources which can be listed, watched and deleted, by
+// kind: the resources served by the server and those defined by the
+// CustomResourceDefinitions of any logical cluster.
+func (c *Controller) deletableResources() (map[schema.GroupKind]resource, error) {
+	resources := map[schema.GroupKind]resource{}
+
+	lists, err := discovery.ServerPreferredResources(c.discoveryClient)
+	if err != nil && len(lists) == 0 {
+		return nil, err
+	}
+	if err != nil {
+		klog.V(2).Infof("Discovering the deletable resources partially failed: %v", err)
+	}
+	lists = discovery.FilteredBy(discovery.SupportsAllVerbs{Verbs: []string{"delete", "list", "watch"}}, lists)
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			klog.Errorf("Invalid group version %q: %v", list.GroupVersion, err)
+			continue
+		}
+		for _, r := range list.APIResources {
+			gvr := gv.WithResource(r.Name)
+			if ignoredResources[gvr.GroupResource()] {
+				continue
+			}
+			resources[schema.GroupKind{Group: gv.Group, Kind: r.Kind}] = resource{gvr: gvr, namespaced: r.Namespaced}
+		}
+	}
+
+	crds, err := c.crdLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, crd := range crds {
+		groupKind := schema.GroupKind{Group: crd.Spec.Group, Kind: crd.Spec.Names.Kind}
+		if _, found := resources[groupKind]; found {
+			continue
+		}
+		for _, version := range crd.Spec.Versions {
+			if version.Served && version.Storage {
+				resources[groupKind] = resource{
+					gvr:        schema.GroupVersionResource{Group: crd.Spec.Group, Version: version.Name, Resource: crd.Spec.Names.Plural},
+					namespaced: crd.Spec.Scope == apiextensionsv1.NamespaceScoped,
+				}
+			}
+		}
+	}
+	return resources, nil
+}
+
+// syncMonitors starts watching the deletable resources which are not watched yet, and
+// stops watching those which are not served anymore.
+func (c *Controller) syncMonitors(ctx context.Context) {
+	resources, err := c.deletableResources()
+	if err != nil {
+		klog.Errorf("Failed to discover the deletable resources: %v", err)
+		return
+	}
+
+	c.monitorsLock.Lock()
+	defer c.monitorsLock.Unlock()
+
+	for groupKind, m := range c.monitors {
+		if r, found := resources[groupKind]; !found || r != m.resource {
+			close(m.stopCh)
+			delete(c.monitors, groupKind)
+			klog.Infof("Stopped collecting objects of resource %s", m.gvr)
+		}
+	}
+	for groupKind, r := range resources {
+		if _, found := c.monitors[groupKind]; found {
+			continue
+		}
+		c.monitors[groupKind] = c.startMonitor(groupKind, r)
+		klog.V(2).Infof("Collecting objects of resource %s", r.gvr)
+	}
+}
+
+func (c *Controller) startMonitor(groupKind schema.GroupKind, r resource) *monitor {
+	informer := metadatainformer.NewFilteredClusterMetadataInformer(c.wildcardClient, r.gvr, "", 0, cache.Indexers{
+		uidIndex:   indexUID,
+		ownerIndex: indexOwners,
+	}, nil).Informer()
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { c.onChange(groupKind, obj) },
+		UpdateFunc: func(_, obj interface{}) { c.onChange(groupKind, obj) },
+		DeleteFunc: func(obj interface{}) { c.onDelete(obj) },
+	})
+	m := &monitor{resource: r, informer: informer, stopCh: make(chan struct{})}
+	go informer.Run(m.stopCh)
+	return m
+}
+
+func (c *Controller) stopMonitors() {
+	c.monitorsLock.Lock()
+	defer c.monitorsLock.Unlock()
+
+	for groupKind, m := range c.monitors {
+		close(m.stopCh)
+		delete(c.monitors, groupKind)
+	}
+}
+
+// monitor returns the monitor of the given kind, or nil if it is not watched.
+func (c *Controller) monitor(groupKind schema.GroupKind) *monitor {
+	c.monitorsLock.RLock()
+	defer c.monitorsLock.RUnlock()
+	return c.monitors[groupKind]
+}
@@ -0,0 +1,246 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hibernation
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	"github.com/kcp-dev/kcp/pkg/apis/tenancy/helpers/conditions"
+	"github.com/kcp-dev/kcp/pkg/apis/tenancy/helpers/logicalcluster"
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	tenancyinformer "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/tenancy/v1alpha1"
+	tenancylister "github.com/kcp-dev/kcp/pkg/client/listers/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/indexers"
+)
+
+const controllerName = "hibernation"
+
+// LastActivityFunc returns when the last request for the given logical cluster was served,
+// or the zero time if none was.
+type LastActivityFunc func(clusterName string) time.Time
+
+// NewController returns a controller which hibernates Workspaces without any request for
+// longer than idleTimeout, and wakes them up again on the next request.
+func NewController(
+	kcpClient kcpclient.ClusterInterface,
+	workspaceInformer tenancyinformer.WorkspaceInformer,
+	lastActivity LastActivityFunc,
+	registry *Registry,
+	idleTimeout time.Duration,
+) (*Controller, error) {
+	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+
+	c := &Controller{
+		queue:            queue,
+		kcpClient:        kcpClient,
+		workspaceIndexer: workspaceInformer.Informer().GetIndexer(),
+		workspaceLister:  workspaceInformer.Lister(),
+		lastActivity:     lastActivity,
+		registry:         registry,
+		idleTimeout:      idleTimeout,
+		started:          time.Now(),
+		syncChecks: []cache.InformerSynced{
+			workspaceInformer.Informer().HasSynced,
+		},
+	}
+
+	workspaceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { c.updateRegistry(obj, false); c.enqueue(obj) },
+		UpdateFunc: func(_, obj interface{}) { c.updateRegistry(obj, false); c.enqueue(obj) },
+		DeleteFunc: func(obj interface{}) { c.updateRegistry(obj, true) },
+	})
+	if err := indexers.AddIfNotPresent(c.workspaceIndexer, cache.Indexers{
+		indexers.WorkspaceCluster: indexers.IndexWorkspaceByCluster,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to add indexer for Workspace: %w", err)
+	}
+
+	registry.lock.Lock()
+	registry.wake = c.wakeUp
+	registry.lock.Unlock()
+
+	return c, nil
+}
+
+// Controller watches Workspaces in order to hibernate the idle ones.
+type Controller struct {
+	queue workqueue.RateLimitingInterface
+
+	kcpClient        kcpclient.ClusterInterface
+	workspaceIndexer cache.Indexer
+	workspaceLister  tenancylister.WorkspaceLister
+
+	lastActivity LastActivityFunc
+	registry     *Registry
+	idleTimeout  time.Duration
+	started      time.Time
+
+	syncChecks []cache.InformerSynced
+}
+
+// updateRegistry records whether the logical cluster of a workspace is hibernated.
+func (c *Controller) updateRegistry(obj interface{}, deleted bool) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	if workspace, ok := obj.(*tenancyv1alpha1.Workspace); ok {
+		if deleted && logicalcluster.Renamed(workspace) {
+			// the logical cluster lives on in the renamed workspace
+			return
+		}
+		c.registry.set(logicalcluster.Of(workspace), !deleted && conditions.IsWorkspaceConditionTrue(workspace, tenancyv1alpha1.WorkspaceHibernated))
+	}
+}
+
+func (c *Controller) enqueue(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	klog.V(4).Infof("queueing workspace %q", key)
+	c.queue.Add(key)
+}
+
+// wakeUp queues the workspace of a logical cluster which got a request while hibernated.
+func (c *Controller) wakeUp(clusterName string) {
+	workspaces, err := c.workspaceIndexer.ByIndex(indexers.WorkspaceCluster, clusterName)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	for _, workspace := range workspaces {
+		c.enqueue(workspace)
+	}
+}
+
+func (c *Controller) Start(ctx context.Context, numThreads int) {
+	defer runtime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	klog.Info("Starting hibernation controller")
+	defer klog.Info("Shutting down hibernation controller")
+
+	if !cache.WaitForNamedCacheSync(controllerName, ctx.Done(), c.syncChecks...) {
+		klog.Warning("Failed to wait for caches to sync")
+		return
+	}
+
+	for i := 0; i < numThreads; i++ {
+		go wait.Until(func() { c.startWorker(ctx) }, time.Second, ctx.Done())
+	}
+
+	<-ctx.Done()
+}
+
+func (c *Controller) startWorker(ctx context.Context) {
+	for c.processNextWorkItem(ctx) {
+	}
+}
+
+func (c *Controller) processNextWorkItem(ctx context.Context) bool {
+	// Wait until there is a new item in the working queue
+	k, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	key := k.(string)
+
+	// No matter what, tell the queue we're done with this key, to unblock
+	// other workers.
+	defer c.queue.Done(key)
+
+	if err := c.process(ctx, key); err != nil {
+		runtime.HandleError(fmt.Errorf("%q controller failed to sync %q, err: %w", controllerName, key, err))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	c.queue.Forget(key)
+	return true
+}
+
+func (c *Controller) process(ctx context.Context, key string) error {
+	obj, err := c.workspaceLister.Get(key)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil // object deleted before we handled it
+		}
+		return err
+	}
+	if obj.Status.Phase != tenancyv1alpha1.WorkspacePhaseActive && obj.Status.Phase != tenancyv1alpha1.WorkspacePhaseHibernated {
+		return nil
+	}
+
+	workspace := obj.DeepCopy()
+	if remaining := c.reconcile(workspace, time.Now()); remaining > 0 {
+		c.queue.AddAfter(key, remaining)
+	}
+	if equality.Semantic.DeepEqual(obj.Status, workspace.Status) {
+		return nil
+	}
+	_, err = c.kcpClient.Cluster(workspace.ClusterName).TenancyV1alpha1().Workspaces().UpdateStatus(ctx, workspace, metav1.UpdateOptions{})
+	return err
+}
+
+// reconcile hibernates or wakes up the workspace, and returns how long it may stay idle
+// before it gets hibernated.
+func (c *Controller) reconcile(workspace *tenancyv1alpha1.Workspace, now time.Time) time.Duration {
+	// activity is not persisted, so workspaces get a full idle period after a restart
+	lastActive := c.started
+	if workspace.CreationTimestamp.After(lastActive) {
+		lastActive = workspace.CreationTimestamp.Time
+	}
+	if last := c.lastActivity(logicalcluster.Of(workspace)); last.After(lastActive) {
+		lastActive = last
+	}
+	remaining := c.idleTimeout - now.Sub(lastActive)
+	hibernated := conditions.IsWorkspaceConditionTrue(workspace, tenancyv1alpha1.WorkspaceHibernated)
+
+	switch {
+	case remaining <= 0 && !hibernated:
+		klog.Infof("hibernating workspace %q idle since %s", workspace.Name, lastActive)
+		conditions.SetWorkspaceCondition(workspace, tenancyv1alpha1.WorkspaceCondition{
+			Type:    tenancyv1alpha1.WorkspaceHibernated,
+			Status:  metav1.ConditionTrue,
+			Reason:  tenancyv1alpha1.WorkspaceReasonIdle,
+			Message: fmt.Sprintf("No request was served since %s.", lastActive.UTC().Format(time.RFC3339)),
+		})
+		workspace.Status.Phase = tenancyv1alpha1.WorkspacePhaseHibernated
+	case remaining > 0 && hibernated:
+		klog.Infof("waking up workspace %q", workspace.Name)
+		conditions.SetWorkspaceCondition(workspace, tenancyv1alpha1.WorkspaceCondition{
+			Type:    tenancyv1alpha1.WorkspaceHibernated,
+			Status:  metav1.ConditionFalse,
+			Reason:  "Active",
+			Message: "The workspace was woken up by a request.",
+		})
+		workspace.Status.Phase = tenancyv1alpha1.WorkspacePhaseActive
+	}
+	return remaining
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hibernation
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+)
+
+func TestReconcile(t *testing.T) {
+	start := time.Date(2021, 12, 1, 10, 0, 0, 0, time.UTC)
+	var lastActivity time.Time
+	c := &Controller{
+		lastActivity: func(string) time.Time { return lastActivity },
+		idleTimeout:  time.Hour,
+		started:      start,
+	}
+	workspace := &tenancyv1alpha1.Workspace{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", CreationTimestamp: metav1.NewTime(start.Add(-24 * time.Hour))},
+		Status:     tenancyv1alpha1.WorkspaceStatus{Phase: tenancyv1alpha1.WorkspacePhaseActive},
+	}
+
+	if remaining := c.reconcile(workspace, start.Add(30*time.Minute)); remaining != 30*time.Minute {
+		t.Errorf("expected the workspace to stay active for 30m after the start, got %s", remaining)
+	}
+	if workspace.Status.Phase != tenancyv1alpha1.WorkspacePhaseActive {
+		t.Fatalf("expected the workspace to be active, got %s", workspace.Status.Phase)
+	}
+
+	c.reconcile(workspace, start.Add(time.Hour))
+	if workspace.Status.Phase != tenancyv1alpha1.WorkspacePhaseHibernated {
+		t.Fatalf("expected the workspace to be hibernated, got %s", workspace.Status.Phase)
+	}
+
+	lastActivity = start.Add(2 * time.Hour)
+	if remaining := c.reconcile(workspace, lastActivity); remaining != time.Hour {
+		t.Errorf("expected the woken up workspace to stay active for 1h, got %s", remaining)
+	}
+	if workspace.Status.Phase != tenancyv1alpha1.WorkspacePhaseActive {
+		t.Fatalf("expected the workspace to be woken up, got %s", workspace.Status.Phase)
+	}
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hibernation
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/handlers/responsewriters"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	"github.com/kcp-dev/kcp/pkg/statuserrors"
+)
+
+// wakeUpSeconds is how long clients are asked to wait before retrying a request to a
+// hibernated workspace.
+const wakeUpSeconds int32 = 5
+
+var readOnlyVerbs = sets.NewString("get", "list")
+
+// Registry holds the logical clusters of the hibernated workspaces, and the watches served
+// for the others, which are ended when they are hibernated.
+type Registry struct {
+	lock       sync.RWMutex
+	hibernated sets.String
+	watches    map[string]map[*context.CancelFunc]struct{}
+	wake       func(clusterName string)
+}
+
+// NewRegistry returns a Registry without hibernated logical clusters.
+func NewRegistry() *Registry {
+	return &Registry{
+		hibernated: sets.NewString(),
+		watches:    map[string]map[*context.CancelFunc]struct{}{},
+	}
+}
+
+// IsHibernated returns whether the given logical cluster is hibernated.
+func (r *Registry) IsHibernated(clusterName string) bool {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.hibernated.Has(clusterName)
+}
+
+func (r *Registry) set(clusterName string, hibernated bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if !hibernated {
+		r.hibernated.Delete(clusterName)
+		return
+	}
+	r.hibernated.Insert(clusterName)
+	for cancel := range r.watches[clusterName] {
+		(*cancel)()
+	}
+	delete(r.watches, clusterName)
+}
+
+// addWatch records a watch served for the given logical cluster, unless it is hibernated,
+// and returns the function to call when it is over.
+func (r *Registry) addWatch(clusterName string, cancel context.CancelFunc) (func(), bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.hibernated.Has(clusterName) {
+		return nil, false
+	}
+	if r.watches[clusterName] == nil {
+		r.watches[clusterName] = map[*context.CancelFunc]struct{}{}
+	}
+	r.watches[clusterName][&cancel] = struct{}{}
+	return func() {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		delete(r.watches[clusterName], &cancel)
+		if len(r.watches[clusterName]) == 0 {
+			delete(r.watches, clusterName)
+		}
+	}, true
+}
+
+func (r *Registry) wakeUp(clusterName string) {
+	r.lock.RLock()
+	wake := r.wake
+	r.lock.RUnlock()
+	if wake != nil {
+		wake(clusterName)
+	}
+}
+
+// WithHibernation rejects writes and watches, and reads too if rejectReads is set, to
+// hibernated logical clusters with 425 Too Early, and wakes those up. The watches served for
+// a logical cluster are ended when it is hibernated, so that idle workspaces hold no watches,
+// which clients re-establish once it is woken up. Requests of privileged system users, like
+// the ones of kcp controllers, are always passed to handler. It must be wrapped by the
+// authentication filter.
+func WithHibernation(handler http.Handler, registry *Registry, rejectReads bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		cluster := genericapirequest.ClusterFrom(req.Context())
+		if cluster == nil || cluster.Name == "" || cluster.Wildcard {
+			handler.ServeHTTP(w, req)
+			return
+		}
+		if u, ok := genericapirequest.UserFrom(req.Context()); ok && sets.NewString(u.GetGroups()...).Has(user.SystemPrivilegedGroup) {
+			handler.ServeHTTP(w, req)
+			return
+		}
+		info, ok := genericapirequest.RequestInfoFrom(req.Context())
+		if ok && info.Verb == "watch" {
+			ctx, cancel := context.WithCancel(req.Context())
+			defer cancel()
+			if done, ok := registry.addWatch(cluster.Name, cancel); ok {
+				defer done()
+				handler.ServeHTTP(w, req.WithContext(ctx))
+				return
+			}
+		} else if !registry.IsHibernated(cluster.Name) || !ok || (!rejectReads && readOnlyVerbs.Has(info.Verb)) {
+			handler.ServeHTTP(w, req)
+			return
+		}
+
+		registry.wakeUp(cluster.Name)
+		err := statuserrors.NewWorkspaceHibernated(cluster.Name, wakeUpSeconds)
+		responsewriters.ErrorNegotiated(err, scheme.Codecs, schema.GroupVersion{Group: info.APIGroup, Version: info.APIVersion}, w, req)
+	})
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hibernation
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apiserver/pkg/authentication/user"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+)
+
+func TestWithHibernationEndsWatches(t *testing.T) {
+	registry := NewRegistry()
+	var woken []string
+	registry.wake = func(clusterName string) { woken = append(woken, clusterName) }
+	watching := make(chan struct{}, 1)
+	handler := WithHibernation(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		watching <- struct{}{}
+		<-req.Context().Done()
+	}), registry, false)
+
+	watch := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/configmaps?watch=true", nil)
+		ctx := genericapirequest.WithCluster(req.Context(), genericapirequest.Cluster{Name: "org_team"})
+		ctx = genericapirequest.WithUser(ctx, &user.DefaultInfo{Name: "alice", Groups: []string{user.AllAuthenticated}})
+		ctx = genericapirequest.WithRequestInfo(ctx, &genericapirequest.RequestInfo{IsResourceRequest: true, Verb: "watch", APIVersion: "v1", Resource: "configmaps"})
+		rw := httptest.NewRecorder()
+		handler.ServeHTTP(rw, req.WithContext(ctx))
+		return rw
+	}
+
+	ended := make(chan struct{})
+	go func() {
+		defer close(ended)
+		watch()
+	}()
+	<-watching
+	registry.set("org_team", true)
+	select {
+	case <-ended:
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("expected the watch to be ended by the hibernation")
+	}
+
+	if rw := watch(); rw.Code != http.StatusTooEarly {
+		t.Errorf("expected a watch of the hibernated workspace to be rejected with %d, got %d", http.StatusTooEarly, rw.Code)
+	}
+	if len(woken) != 1 || woken[0] != "org_team" {
+		t.Errorf("expected the watch to wake the workspace up, got %v", woken)
+	}
+}
@@ -0,0 +1,407 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clusters"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	clusterv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/cluster/v1alpha1"
+	clusterinformer "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/cluster/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/client/metadatainformer"
+	"github.com/kcp-dev/kcp/pkg/statuserrors"
+)
+
+const (
+	controllerName = "placement"
+
+	logicalClusterIndex = "logicalCluster"
+	placedOnIndex       = "placedOn"
+
+	resyncPeriod = 10 * time.Hour
+
+	// discoveryInterval is how often the resources which are not served yet are looked up.
+	discoveryInterval = time.Minute
+)
+
+// NewController returns a controller which schedules objects of the given resources to
+// Clusters, according to the Placements of their logical cluster. Objects are placed by
+// setting the ClusterLabel consumed by the syncer, and are placed again when their
+// Cluster is deleted. Objects which already have a ClusterLabel set by someone else are
+// left alone.
+func NewController(
+	dynamicClient dynamic.ClusterInterface,
+	metadataClient metadata.Interface,
+	discoveryClient discovery.DiscoveryInterface,
+	placementInformer clusterinformer.PlacementInformer,
+	clusterInformer clusterinformer.ClusterInformer,
+	namespaceInformer coreinformers.NamespaceInformer,
+	recorder record.EventRecorder,
+	resources []string,
+) (*Controller, error) {
+	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+
+	c := &Controller{
+		queue:            queue,
+		dynamicClient:    dynamicClient,
+		placementIndexer: placementInformer.Informer().GetIndexer(),
+		clusterIndexer:   clusterInformer.Informer().GetIndexer(),
+		namespaceLister:  namespaceInformer.Lister(),
+		recorder:         recorder,
+		syncChecks: []cache.InformerSynced{
+			placementInformer.Informer().HasSynced,
+			clusterInformer.Informer().HasSynced,
+			namespaceInformer.Informer().HasSynced,
+		},
+	}
+	c.objects = &watchedResources{
+		discoveryClient: discoveryClient,
+		informerFactory: metadatainformer.NewClusterMetadataSharedInformerFactory(metadataClient, resyncPeriod),
+		resources:       resources,
+		indexers: cache.Indexers{
+			logicalClusterIndex: indexLogicalCluster,
+			placedOnIndex:       indexPlacedOn,
+		},
+		handler:        c.enqueue,
+		objectIndexers: map[schema.GroupVersionResource]cache.Indexer{},
+		kinds:          map[schema.GroupVersionResource]schema.GroupVersionKind{},
+	}
+
+	placementInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { c.enqueueLogicalCluster(obj) },
+		UpdateFunc: func(_, obj interface{}) { c.enqueueLogicalCluster(obj) },
+	})
+	if err := addIndexer(c.placementIndexer, logicalClusterIndex, indexLogicalCluster); err != nil {
+		return nil, fmt.Errorf("failed to add indexer for Placement: %w", err)
+	}
+
+	clusterInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) { c.enqueueLogicalCluster(obj) },
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldCluster, newCluster := oldObj.(*clusterv1alpha1.Cluster), newObj.(*clusterv1alpha1.Cluster)
+			// Heartbeats don't change where objects can be placed.
+			if oldCluster.Status.Conditions.IsTrue(clusterv1alpha1.ClusterConditionReady) != newCluster.Status.Conditions.IsTrue(clusterv1alpha1.ClusterConditionReady) ||
+				!labelsEqual(oldCluster.Labels, newCluster.Labels) {
+				c.enqueueLogicalCluster(newObj)
+			}
+		},
+		DeleteFunc: func(obj interface{}) { c.enqueuePlacedOn(obj) },
+	})
+	if err := addIndexer(c.clusterIndexer, logicalClusterIndex, indexLogicalCluster); err != nil {
+		return nil, fmt.Errorf("failed to add indexer for Cluster: %w", err)
+	}
+
+	return c, nil
+}
+
+// Controller schedules objects to Clusters according to Placements.
+type Controller struct {
+	queue workqueue.RateLimitingInterface
+
+	dynamicClient dynamic.ClusterInterface
+
+	placementIndexer cache.Indexer
+	clusterIndexer   cache.Indexer
+	namespaceLister  corelisters.NamespaceLister
+
+	recorder record.EventRecorder
+
+	// objects are the objects of the resources to place.
+	objects *watchedResources
+
+	syncChecks []cache.InformerSynced
+}
+
+// queueKey identifies an object to place.
+type queueKey struct {
+	gvr schema.GroupVersionResource
+	key string
+}
+
+func indexLogicalCluster(obj interface{}) ([]string, error) {
+	metaObj, err := meta.Accessor(obj)
+	if err != nil {
+		return []string{}, nil
+	}
+	return []string{metaObj.GetClusterName()}, nil
+}
+
+func indexPlacedOn(obj interface{}) ([]string, error) {
+	metaObj, err := meta.Accessor(obj)
+	if err != nil {
+		return []string{}, nil
+	}
+	if cluster := metaObj.GetLabels()[clusterv1alpha1.ClusterLabel]; cluster != "" {
+		return []string{clusters.ToClusterAwareKey(metaObj.GetClusterName(), cluster)}, nil
+	}
+	return []string{}, nil
+}
+
+func labelsEqual(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if bv, ok := b[k]; !ok || bv != v {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Controller) enqueue(gvr schema.GroupVersionResource, obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	c.queue.Add(queueKey{gvr: gvr, key: key})
+}
+
+// enqueueIndexed enqueues the objects of all watched resources found under the given
+// index key.
+func (c *Controller) enqueueIndexed(indexName, indexKey string) {
+	for gvr, objs := range c.objects.byIndex(indexName, indexKey) {
+		for _, obj := range objs {
+			c.enqueue(gvr, obj)
+		}
+	}
+}
+
+// enqueueLogicalCluster enqueues the objects of the logical cluster of the given
+// Placement or Cluster.
+func (c *Controller) enqueueLogicalCluster(obj interface{}) {
+	metaObj, err := meta.Accessor(obj)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	c.enqueueIndexed(logicalClusterIndex, metaObj.GetClusterName())
+}
+
+// enqueuePlacedOn enqueues the objects placed on the given deleted Cluster.
+func (c *Controller) enqueuePlacedOn(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	cluster, ok := obj.(*clusterv1alpha1.Cluster)
+	if !ok {
+		klog.Errorf("Unexpected object %#v", obj)
+		return
+	}
+	c.enqueueIndexed(placedOnIndex, clusters.ToClusterAwareKey(cluster.ClusterName, cluster.Name))
+}
+
+func (c *Controller) Start(ctx context.Context, numThreads int) {
+	defer runtime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	klog.Info("Starting Placement controller")
+	defer klog.Info("Shutting down Placement controller")
+
+	if !cache.WaitForNamedCacheSync(controllerName, ctx.Done(), c.syncChecks...) {
+		klog.Warning("Failed to wait for caches to sync")
+		return
+	}
+
+	go wait.UntilWithContext(ctx, c.objects.watch, discoveryInterval)
+
+	for i := 0; i < numThreads; i++ {
+		go wait.Until(func() { c.startWorker(ctx) }, time.Second, ctx.Done())
+	}
+
+	<-ctx.Done()
+}
+
+func (c *Controller) startWorker(ctx context.Context) {
+	for c.processNextWorkItem(ctx) {
+	}
+}
+
+func (c *Controller) processNextWorkItem(ctx context.Context) bool {
+	// Wait until there is a new item in the working queue
+	k, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	key := k.(queueKey)
+
+	// No matter what, tell the queue we're done with this key, to unblock
+	// other workers.
+	defer c.queue.Done(key)
+
+	if err := c.process(ctx, key); statuserrors.IsPlacementFailed(err) {
+		// retrying won't help until the Clusters or Placements change, which enqueues the key again
+		klog.V(4).Infof("Not retrying %q of %s: %v", key.key, key.gvr, err)
+	} else if err != nil {
+		runtime.HandleError(fmt.Errorf("%q controller failed to sync %q of %s, err: %w", controllerName, key.key, key.gvr, err))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	c.queue.Forget(key)
+	return true
+}
+
+func (c *Controller) process(ctx context.Context, key queueKey) error {
+	indexer := c.objects.indexer(key.gvr)
+	if indexer == nil {
+		return nil
+	}
+	obj, exists, err := indexer.GetByKey(key.key)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return nil
+	}
+	return c.reconcile(ctx, key.gvr, obj.(*metav1.PartialObjectMetadata))
+}
+
+func (c *Controller) reconcile(ctx context.Context, gvr schema.GroupVersionResource, obj *metav1.PartialObjectMetadata) error {
+	logicalCluster := obj.GetClusterName()
+
+	if placedOn := obj.GetLabels()[clusterv1alpha1.ClusterLabel]; placedOn != "" {
+		// Only objects we placed are placed again, once their Cluster is gone.
+		if obj.GetAnnotations()[clusterv1alpha1.PlacementAnnotation] == "" {
+			return nil
+		}
+		_, exists, err := c.clusterIndexer.GetByKey(clusters.ToClusterAwareKey(logicalCluster, placedOn))
+		if err != nil || exists {
+			return err
+		}
+	}
+
+	placements, err := placementsIn(c.placementIndexer, logicalCluster)
+	if err != nil {
+		return err
+	}
+	if len(placements) == 0 {
+		return nil
+	}
+	namespace, err := c.namespaceLister.Get(clusters.ToClusterAwareKey(logicalCluster, obj.GetNamespace()))
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if namespace.Annotations[clusterv1alpha1.PlacementAnnotation] != "" {
+		// The objects of scheduled namespaces follow their namespace.
+		return nil
+	}
+	placement, err := placementFor(placements, gvr.GroupResource(), namespace.Labels, obj.GetLabels())
+	if err != nil || placement == nil {
+		return err
+	}
+
+	clusterObjs, err := c.clusterIndexer.ByIndex(logicalClusterIndex, logicalCluster)
+	if err != nil {
+		return err
+	}
+	candidates := make([]*clusterv1alpha1.Cluster, 0, len(clusterObjs))
+	placed := map[string]int{}
+	for _, clusterObj := range clusterObjs {
+		cluster := clusterObj.(*clusterv1alpha1.Cluster)
+		candidates = append(candidates, cluster)
+		placed[cluster.Name] = c.placedOn(logicalCluster, cluster.Name)
+	}
+
+	target, err := schedule(placement, candidates, placed)
+	if err != nil {
+		return err
+	}
+	if target == "" {
+		c.recorder.Eventf(c.objects.reference(gvr, obj), corev1.EventTypeWarning, "FailedPlacement", "No Cluster is eligible for Placement %q", placement.Name)
+		return statuserrors.NewPlacementFailed(placement.Name)
+	}
+
+	if err := c.place(ctx, gvr, obj, placement.Name, target); err != nil {
+		return err
+	}
+	c.recorder.Eventf(c.objects.reference(gvr, obj), corev1.EventTypeNormal, "Placed", "Placed on Cluster %q by Placement %q", target, placement.Name)
+	return nil
+}
+
+// placementsIn returns the Placements of the logical cluster.
+func placementsIn(placementIndexer cache.Indexer, logicalCluster string) ([]*clusterv1alpha1.Placement, error) {
+	objs, err := placementIndexer.ByIndex(logicalClusterIndex, logicalCluster)
+	if err != nil {
+		return nil, err
+	}
+	placements := make([]*clusterv1alpha1.Placement, 0, len(objs))
+	for _, obj := range objs {
+		placements = append(placements, obj.(*clusterv1alpha1.Placement))
+	}
+	return placements, nil
+}
+
+// placedOn returns the number of objects of all watched resources placed on the given
+// Cluster.
+func (c *Controller) placedOn(logicalCluster, cluster string) int {
+	count := 0
+	for _, objs := range c.objects.byIndex(placedOnIndex, clusters.ToClusterAwareKey(logicalCluster, cluster)) {
+		count += len(objs)
+	}
+	return count
+}
+
+// place sets the ClusterLabel of the object to the given Cluster. The resource version
+// is part of the patch, so that an object changed in the meantime is not placed based
+// on stale information.
+func (c *Controller) place(ctx context.Context, gvr schema.GroupVersionResource, obj *metav1.PartialObjectMetadata, placement, cluster string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"resourceVersion": obj.GetResourceVersion(),
+			"labels": map[string]string{
+				clusterv1alpha1.ClusterLabel: cluster,
+			},
+			"annotations": map[string]string{
+				clusterv1alpha1.PlacementAnnotation: placement,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := c.dynamicClient.Cluster(obj.GetClusterName()).Resource(gvr).Namespace(obj.GetNamespace()).
+		Patch(ctx, obj.GetName(), types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to place %s %s/%s on Cluster %q: %w", gvr.Resource, obj.GetNamespace(), obj.GetName(), cluster, err)
+	}
+	klog.Infof("Placed %s %s/%s of logical cluster %s on Cluster %q", gvr.Resource, obj.GetNamespace(), obj.GetName(), obj.GetClusterName(), cluster)
+	return nil
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clusters"
+
+	clusterv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/cluster/v1alpha1"
+	clusterinformer "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/cluster/v1alpha1"
+)
+
+// ErrDefaulterNotInitialized is returned by a Defaulter which was not initialized yet.
+var ErrDefaulterNotInitialized = errors.New("placement defaults are not enabled")
+
+// Defaulter chooses the Cluster of objects as they are created, so that they are synced
+// without waiting for the controllers of this package:
+//
+//   - objects in a namespace labeled for a Cluster get the ClusterLabel of the namespace,
+//   - other objects selected by a Placement get the ClusterLabel of its eligible Cluster,
+//     when there is only one and the Placement doesn't limit the objects per Cluster.
+//
+// Choosing among several Clusters needs the number of objects already placed on each,
+// so these objects are left to the Placement controller.
+type Defaulter struct {
+	lock             sync.RWMutex
+	placementIndexer cache.Indexer
+	clusterIndexer   cache.Indexer
+	namespaceLister  corelisters.NamespaceLister
+	resources        []string
+	hasSynced        func() bool
+}
+
+// NewDefaulter returns a Defaulter which has to be initialized before use.
+func NewDefaulter() *Defaulter {
+	return &Defaulter{}
+}
+
+// Initialize makes the Defaulter place the objects of the given synced resources with the
+// given cross-cluster informers.
+func (d *Defaulter) Initialize(
+	placementInformer clusterinformer.PlacementInformer,
+	clusterInformer clusterinformer.ClusterInformer,
+	namespaceInformer coreinformers.NamespaceInformer,
+	resources []string,
+) error {
+	if err := addIndexer(placementInformer.Informer().GetIndexer(), logicalClusterIndex, indexLogicalCluster); err != nil {
+		return fmt.Errorf("failed to add indexer for Placement: %w", err)
+	}
+	if err := addIndexer(clusterInformer.Informer().GetIndexer(), logicalClusterIndex, indexLogicalCluster); err != nil {
+		return fmt.Errorf("failed to add indexer for Cluster: %w", err)
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.placementIndexer = placementInformer.Informer().GetIndexer()
+	d.clusterIndexer = clusterInformer.Informer().GetIndexer()
+	d.namespaceLister = namespaceInformer.Lister()
+	d.resources = resources
+	d.hasSynced = func() bool {
+		return placementInformer.Informer().HasSynced() && clusterInformer.Informer().HasSynced() && namespaceInformer.Informer().HasSynced()
+	}
+	return nil
+}
+
+// Default sets the ClusterLabel of a new object of the given resource in the given logical
+// cluster, unless it is set already, and returns whether it did.
+func (d *Defaulter) Default(logicalCluster string, gr schema.GroupResource, obj metav1.Object) (bool, error) {
+	d.lock.RLock()
+	placementIndexer, clusterIndexer, namespaceLister, resources, hasSynced := d.placementIndexer, d.clusterIndexer, d.namespaceLister, d.resources, d.hasSynced
+	d.lock.RUnlock()
+	if hasSynced == nil {
+		return false, ErrDefaulterNotInitialized
+	}
+	if obj.GetNamespace() == "" || obj.GetLabels()[clusterv1alpha1.ClusterLabel] != "" || !syncsResource(resources, gr) {
+		return false, nil
+	}
+	if !hasSynced() {
+		return false, fmt.Errorf("placements are not synced yet")
+	}
+
+	namespace, err := namespaceLister.Get(clusters.ToClusterAwareKey(logicalCluster, obj.GetNamespace()))
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	if cluster := namespace.Labels[clusterv1alpha1.ClusterLabel]; cluster != "" {
+		annotations := map[string]string{}
+		if placement := namespace.Annotations[clusterv1alpha1.PlacementAnnotation]; placement != "" {
+			annotations[clusterv1alpha1.NamespacePlacementAnnotation] = placement
+		}
+		setPlacement(obj, cluster, annotations)
+		return true, nil
+	}
+	if namespace.Annotations[clusterv1alpha1.PlacementAnnotation] != "" {
+		// The namespace is about to be scheduled, and its objects will follow it.
+		return false, nil
+	}
+
+	placements, err := placementsIn(placementIndexer, logicalCluster)
+	if err != nil {
+		return false, err
+	}
+	placement, err := placementFor(placements, gr, namespace.Labels, obj.GetLabels())
+	if err != nil || placement == nil || placement.Spec.MaxObjectsPerCluster > 0 {
+		return false, err
+	}
+	clusterObjs, err := clusterIndexer.ByIndex(logicalClusterIndex, logicalCluster)
+	if err != nil {
+		return false, err
+	}
+	candidates := make([]*clusterv1alpha1.Cluster, 0, len(clusterObjs))
+	for _, clusterObj := range clusterObjs {
+		candidates = append(candidates, clusterObj.(*clusterv1alpha1.Cluster))
+	}
+	eligible, err := eligibleClusters(placement, candidates, nil)
+	if err != nil || len(eligible) != 1 {
+		return false, err
+	}
+	setPlacement(obj, eligible[0].Name, map[string]string{clusterv1alpha1.PlacementAnnotation: placement.Name})
+	return true, nil
+}
+
+// syncsResource returns whether the resource is one of the given synced resources, in the
+// format of the Placements.
+func syncsResource(resources []string, gr schema.GroupResource) bool {
+	for _, resource := range resources {
+		if resource == gr.String() || resource == gr.Resource {
+			return true
+		}
+	}
+	return false
+}
+
+func setPlacement(obj metav1.Object, cluster string, annotations map[string]string) {
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[clusterv1alpha1.ClusterLabel] = cluster
+	obj.SetLabels(labels)
+
+	if len(annotations) == 0 {
+		return
+	}
+	objAnnotations := obj.GetAnnotations()
+	if objAnnotations == nil {
+		objAnnotations = map[string]string{}
+	}
+	for k, v := range annotations {
+		objAnnotations[k] = v
+	}
+	obj.SetAnnotations(objAnnotations)
+}
@@ -0,0 +1,467 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clusters"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	clusterv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/cluster/v1alpha1"
+	clusterinformer "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/cluster/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/client/metadatainformer"
+	"github.com/kcp-dev/kcp/pkg/statuserrors"
+)
+
+const (
+	namespaceControllerName = "namespace-scheduling"
+
+	namespaceIndex = "namespace"
+
+	// unreadyGracePeriod is how long a Cluster may be unready before the namespaces
+	// scheduled on it are scheduled again, so that short disruptions don't move
+	// workloads around.
+	unreadyGracePeriod = 5 * time.Minute
+)
+
+// namespaces are the resource Placements name to schedule whole namespaces.
+var namespaces = schema.GroupResource{Resource: "namespaces"}
+
+// NewNamespaceController returns a controller which schedules namespaces to Clusters,
+// according to the Placements of their logical cluster which place "namespaces". The
+// objects of the given resources in a scheduled namespace are labeled for its Cluster,
+// unless they were labeled by hand. Namespaces are scheduled again when their Cluster
+// is deleted or stays unready for too long.
+func NewNamespaceController(
+	kubeClient *kubernetes.Cluster,
+	dynamicClient dynamic.ClusterInterface,
+	metadataClient metadata.Interface,
+	discoveryClient discovery.DiscoveryInterface,
+	placementInformer clusterinformer.PlacementInformer,
+	clusterInformer clusterinformer.ClusterInformer,
+	namespaceInformer coreinformers.NamespaceInformer,
+	recorder record.EventRecorder,
+	resources []string,
+) (*NamespaceController, error) {
+	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), namespaceControllerName)
+
+	c := &NamespaceController{
+		queue:            queue,
+		kubeClient:       kubeClient,
+		dynamicClient:    dynamicClient,
+		placementIndexer: placementInformer.Informer().GetIndexer(),
+		clusterIndexer:   clusterInformer.Informer().GetIndexer(),
+		namespaceIndexer: namespaceInformer.Informer().GetIndexer(),
+		recorder:         recorder,
+		syncChecks: []cache.InformerSynced{
+			placementInformer.Informer().HasSynced,
+			clusterInformer.Informer().HasSynced,
+			namespaceInformer.Informer().HasSynced,
+		},
+	}
+	c.objects = &watchedResources{
+		discoveryClient: discoveryClient,
+		informerFactory: metadatainformer.NewClusterMetadataSharedInformerFactory(metadataClient, resyncPeriod),
+		resources:       resources,
+		indexers: cache.Indexers{
+			namespaceIndex: indexNamespace,
+		},
+		handler:        func(_ schema.GroupVersionResource, obj interface{}) { c.enqueueNamespaceOf(obj) },
+		objectIndexers: map[schema.GroupVersionResource]cache.Indexer{},
+		kinds:          map[schema.GroupVersionResource]schema.GroupVersionKind{},
+	}
+
+	placementInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { c.enqueueLogicalCluster(obj) },
+		UpdateFunc: func(_, obj interface{}) { c.enqueueLogicalCluster(obj) },
+	})
+	if err := addIndexer(c.placementIndexer, logicalClusterIndex, indexLogicalCluster); err != nil {
+		return nil, fmt.Errorf("failed to add indexer for Placement: %w", err)
+	}
+
+	clusterInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) { c.enqueueLogicalCluster(obj) },
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldCluster, newCluster := oldObj.(*clusterv1alpha1.Cluster), newObj.(*clusterv1alpha1.Cluster)
+			if oldCluster.Status.Conditions.IsTrue(clusterv1alpha1.ClusterConditionReady) != newCluster.Status.Conditions.IsTrue(clusterv1alpha1.ClusterConditionReady) ||
+				!labelsEqual(oldCluster.Labels, newCluster.Labels) {
+				c.enqueueLogicalCluster(newObj)
+			}
+		},
+		DeleteFunc: func(obj interface{}) { c.enqueuePlacedOn(obj) },
+	})
+	if err := addIndexer(c.clusterIndexer, logicalClusterIndex, indexLogicalCluster); err != nil {
+		return nil, fmt.Errorf("failed to add indexer for Cluster: %w", err)
+	}
+
+	namespaceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { c.enqueue(obj) },
+		UpdateFunc: func(_, obj interface{}) { c.enqueue(obj) },
+	})
+	if err := c.namespaceIndexer.AddIndexers(cache.Indexers{
+		logicalClusterIndex: indexLogicalCluster,
+		placedOnIndex:       indexPlacedOn,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to add indexer for Namespace: %w", err)
+	}
+
+	return c, nil
+}
+
+// NamespaceController schedules namespaces to Clusters according to Placements.
+type NamespaceController struct {
+	queue workqueue.RateLimitingInterface
+
+	kubeClient    *kubernetes.Cluster
+	dynamicClient dynamic.ClusterInterface
+
+	placementIndexer cache.Indexer
+	clusterIndexer   cache.Indexer
+	namespaceIndexer cache.Indexer
+
+	recorder record.EventRecorder
+
+	// objects are the objects of the resources which follow their namespace.
+	objects *watchedResources
+
+	syncChecks []cache.InformerSynced
+}
+
+// addIndexer adds the index to the indexer, unless another controller of this package
+// sharing the informer added it already.
+func addIndexer(indexer cache.Indexer, name string, indexFunc cache.IndexFunc) error {
+	if _, found := indexer.GetIndexers()[name]; found {
+		return nil
+	}
+	return indexer.AddIndexers(cache.Indexers{name: indexFunc})
+}
+
+func indexNamespace(obj interface{}) ([]string, error) {
+	metaObj, err := meta.Accessor(obj)
+	if err != nil || metaObj.GetNamespace() == "" {
+		return []string{}, nil
+	}
+	return []string{clusters.ToClusterAwareKey(metaObj.GetClusterName(), metaObj.GetNamespace())}, nil
+}
+
+func (c *NamespaceController) enqueue(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	c.queue.Add(key)
+}
+
+// enqueueNamespaceOf enqueues the namespace of the given object.
+func (c *NamespaceController) enqueueNamespaceOf(obj interface{}) {
+	metaObj, err := meta.Accessor(obj)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	if metaObj.GetNamespace() != "" {
+		c.queue.Add(clusters.ToClusterAwareKey(metaObj.GetClusterName(), metaObj.GetNamespace()))
+	}
+}
+
+// enqueueIndexed enqueues the namespaces found under the given index key.
+func (c *NamespaceController) enqueueIndexed(indexName, indexKey string) {
+	objs, err := c.namespaceIndexer.ByIndex(indexName, indexKey)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	for _, obj := range objs {
+		c.enqueue(obj)
+	}
+}
+
+// enqueueLogicalCluster enqueues the namespaces of the logical cluster of the given
+// Placement or Cluster.
+func (c *NamespaceController) enqueueLogicalCluster(obj interface{}) {
+	metaObj, err := meta.Accessor(obj)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	c.enqueueIndexed(logicalClusterIndex, metaObj.GetClusterName())
+}
+
+// enqueuePlacedOn enqueues the namespaces scheduled on the given deleted Cluster.
+func (c *NamespaceController) enqueuePlacedOn(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	cluster, ok := obj.(*clusterv1alpha1.Cluster)
+	if !ok {
+		klog.Errorf("Unexpected object %#v", obj)
+		return
+	}
+	c.enqueueIndexed(placedOnIndex, clusters.ToClusterAwareKey(cluster.ClusterName, cluster.Name))
+}
+
+func (c *NamespaceController) Start(ctx context.Context, numThreads int) {
+	defer runtime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	klog.Info("Starting Namespace scheduling controller")
+	defer klog.Info("Shutting down Namespace scheduling controller")
+
+	if !cache.WaitForNamedCacheSync(namespaceControllerName, ctx.Done(), c.syncChecks...) {
+		klog.Warning("Failed to wait for caches to sync")
+		return
+	}
+
+	go wait.UntilWithContext(ctx, c.objects.watch, discoveryInterval)
+
+	for i := 0; i < numThreads; i++ {
+		go wait.Until(func() { c.startWorker(ctx) }, time.Second, ctx.Done())
+	}
+
+	<-ctx.Done()
+}
+
+func (c *NamespaceController) startWorker(ctx context.Context) {
+	for c.processNextWorkItem(ctx) {
+	}
+}
+
+func (c *NamespaceController) processNextWorkItem(ctx context.Context) bool {
+	// Wait until there is a new item in the working queue
+	k, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	key := k.(string)
+
+	// No matter what, tell the queue we're done with this key, to unblock
+	// other workers.
+	defer c.queue.Done(key)
+
+	if err := c.process(ctx, key); statuserrors.IsPlacementFailed(err) {
+		// retrying won't help until the Clusters or Placements change, which enqueues the key again
+		klog.V(4).Infof("Not retrying %q: %v", key, err)
+	} else if err != nil {
+		runtime.HandleError(fmt.Errorf("%q controller failed to sync %q, err: %w", namespaceControllerName, key, err))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	c.queue.Forget(key)
+	return true
+}
+
+func (c *NamespaceController) process(ctx context.Context, key string) error {
+	obj, exists, err := c.namespaceIndexer.GetByKey(key)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return nil
+	}
+	namespace := obj.(*corev1.Namespace)
+	if namespace.DeletionTimestamp != nil {
+		return nil
+	}
+
+	logicalCluster := namespace.ClusterName
+	placedOn := namespace.Labels[clusterv1alpha1.ClusterLabel]
+	if placedOn != "" {
+		// Only namespaces we scheduled are scheduled again.
+		if namespace.Annotations[clusterv1alpha1.PlacementAnnotation] == "" {
+			return nil
+		}
+		var cluster *clusterv1alpha1.Cluster
+		clusterObj, exists, err := c.clusterIndexer.GetByKey(clusters.ToClusterAwareKey(logicalCluster, placedOn))
+		if err != nil {
+			return err
+		}
+		if exists {
+			cluster = clusterObj.(*clusterv1alpha1.Cluster)
+		}
+		evict, after := evicts(cluster, time.Now(), unreadyGracePeriod)
+		if !evict {
+			if after > 0 {
+				c.queue.AddAfter(key, after)
+			}
+			return c.propagate(ctx, namespace, placedOn)
+		}
+	}
+
+	placements, err := placementsIn(c.placementIndexer, logicalCluster)
+	if err != nil {
+		return err
+	}
+	placement, err := placementFor(placements, namespaces, namespace.Labels, namespace.Labels)
+	if err != nil || placement == nil {
+		return err
+	}
+
+	clusterObjs, err := c.clusterIndexer.ByIndex(logicalClusterIndex, logicalCluster)
+	if err != nil {
+		return err
+	}
+	candidates := make([]*clusterv1alpha1.Cluster, 0, len(clusterObjs))
+	placed := map[string]int{}
+	for _, clusterObj := range clusterObjs {
+		cluster := clusterObj.(*clusterv1alpha1.Cluster)
+		if cluster.Name == placedOn {
+			continue
+		}
+		candidates = append(candidates, cluster)
+		scheduled, err := c.namespaceIndexer.ByIndex(placedOnIndex, clusters.ToClusterAwareKey(logicalCluster, cluster.Name))
+		if err != nil {
+			return err
+		}
+		placed[cluster.Name] = len(scheduled)
+	}
+
+	target, err := schedule(placement, candidates, placed)
+	if err != nil {
+		return err
+	}
+	if target == "" {
+		c.recorder.Eventf(namespace, corev1.EventTypeWarning, "FailedScheduling", "No Cluster is eligible for Placement %q", placement.Name)
+		return statuserrors.NewPlacementFailed(placement.Name)
+	}
+
+	if err := c.schedule(ctx, namespace, placement.Name, target); err != nil {
+		return err
+	}
+	if placedOn != "" {
+		c.recorder.Eventf(namespace, corev1.EventTypeNormal, "Rescheduled", "Moved from Cluster %q to Cluster %q by Placement %q", placedOn, target, placement.Name)
+	} else {
+		c.recorder.Eventf(namespace, corev1.EventTypeNormal, "Scheduled", "Scheduled on Cluster %q by Placement %q", target, placement.Name)
+	}
+	return nil
+}
+
+// evicts returns whether the namespaces scheduled on the Cluster must be scheduled
+// again, because it is gone, being deleted, or unready for longer than the grace period.
+// Otherwise it returns how long an unready Cluster keeps its namespaces.
+func evicts(cluster *clusterv1alpha1.Cluster, now time.Time, gracePeriod time.Duration) (bool, time.Duration) {
+	if cluster == nil || cluster.DeletionTimestamp != nil {
+		return true, 0
+	}
+	if cluster.Status.Conditions.IsTrue(clusterv1alpha1.ClusterConditionReady) {
+		return false, 0
+	}
+	condition := cluster.Status.Conditions.Get(clusterv1alpha1.ClusterConditionReady)
+	if condition == nil || condition.LastTransitionTime.IsZero() {
+		// Clusters which were never ready don't hold workloads yet.
+		return true, 0
+	}
+	if remaining := condition.LastTransitionTime.Add(gracePeriod).Sub(now); remaining > 0 {
+		return false, remaining
+	}
+	return true, 0
+}
+
+// schedule sets the ClusterLabel of the namespace to the given Cluster. Its objects are
+// labeled once the namespace is processed again.
+func (c *NamespaceController) schedule(ctx context.Context, namespace *corev1.Namespace, placement, cluster string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"resourceVersion": namespace.ResourceVersion,
+			"labels": map[string]string{
+				clusterv1alpha1.ClusterLabel: cluster,
+			},
+			"annotations": map[string]string{
+				clusterv1alpha1.PlacementAnnotation: placement,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := c.kubeClient.Cluster(namespace.ClusterName).CoreV1().Namespaces().
+		Patch(ctx, namespace.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to schedule namespace %s on Cluster %q: %w", namespace.Name, cluster, err)
+	}
+	klog.Infof("Scheduled namespace %s of logical cluster %s on Cluster %q", namespace.Name, namespace.ClusterName, cluster)
+	return nil
+}
+
+// followsNamespace returns whether the object must be labeled for the Cluster of its
+// namespace: objects which are not labeled yet, or were labeled by a scheduler.
+func followsNamespace(obj metav1.Object, cluster string) bool {
+	placedOn := obj.GetLabels()[clusterv1alpha1.ClusterLabel]
+	if placedOn == cluster {
+		return false
+	}
+	if placedOn == "" {
+		return true
+	}
+	annotations := obj.GetAnnotations()
+	return annotations[clusterv1alpha1.NamespacePlacementAnnotation] != "" || annotations[clusterv1alpha1.PlacementAnnotation] != ""
+}
+
+// propagate labels the objects of the scheduled namespace for its Cluster.
+func (c *NamespaceController) propagate(ctx context.Context, namespace *corev1.Namespace, cluster string) error {
+	var errs []error
+	for gvr, objs := range c.objects.byIndex(namespaceIndex, clusters.ToClusterAwareKey(namespace.ClusterName, namespace.Name)) {
+		for _, obj := range objs {
+			unstrob, ok := obj.(*metav1.PartialObjectMetadata)
+			if !ok || unstrob.GetDeletionTimestamp() != nil || !followsNamespace(unstrob, cluster) {
+				continue
+			}
+			patch, err := json.Marshal(map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"resourceVersion": unstrob.GetResourceVersion(),
+					"labels": map[string]string{
+						clusterv1alpha1.ClusterLabel: cluster,
+					},
+					"annotations": map[string]interface{}{
+						clusterv1alpha1.NamespacePlacementAnnotation: namespace.Annotations[clusterv1alpha1.PlacementAnnotation],
+						// The object follows its namespace rather than its own Placement.
+						clusterv1alpha1.PlacementAnnotation: nil,
+					},
+				},
+			})
+			if err != nil {
+				return err
+			}
+			if _, err := c.dynamicClient.Cluster(unstrob.GetClusterName()).Resource(gvr).Namespace(unstrob.GetNamespace()).
+				Patch(ctx, unstrob.GetName(), types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+				errs = append(errs, fmt.Errorf("failed to label %s %s/%s for Cluster %q: %w", gvr.Resource, unstrob.GetNamespace(), unstrob.GetName(), cluster, err))
+				continue
+			}
+			klog.V(2).Infof("Labeled %s %s/%s of logical cluster %s for Cluster %q of its namespace", gvr.Resource, unstrob.GetNamespace(), unstrob.GetName(), unstrob.GetClusterName(), cluster)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
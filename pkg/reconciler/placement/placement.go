@@ -0,0 +1,139 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	clusterv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/cluster/v1alpha1"
+)
+
+// selects returns whether the given selector matches the given labels. A nil selector
+// matches everything.
+func selects(selector *metav1.LabelSelector, set map[string]string) (bool, error) {
+	if selector == nil {
+		return true, nil
+	}
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return false, err
+	}
+	return s.Matches(labels.Set(set)), nil
+}
+
+// placesResource returns whether the Placement applies to objects of the given resource.
+func placesResource(placement *clusterv1alpha1.Placement, gr schema.GroupResource) bool {
+	for _, resource := range placement.Spec.Resources {
+		if resource == gr.String() || resource == gr.Resource {
+			return true
+		}
+	}
+	return false
+}
+
+// placementFor returns the first Placement by name which selects an object of the given
+// resource with the given labels, in a namespace with the given labels, or nil if none
+// does.
+func placementFor(placements []*clusterv1alpha1.Placement, gr schema.GroupResource, namespaceLabels, objectLabels map[string]string) (*clusterv1alpha1.Placement, error) {
+	sorted := make([]*clusterv1alpha1.Placement, len(placements))
+	copy(sorted, placements)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	for _, placement := range sorted {
+		if !placesResource(placement, gr) {
+			continue
+		}
+		if ok, err := selects(placement.Spec.NamespaceSelector, namespaceLabels); err != nil || !ok {
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if ok, err := selects(placement.Spec.ObjectSelector, objectLabels); err != nil || !ok {
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		return placement, nil
+	}
+	return nil, nil
+}
+
+// schedule returns the name of the Cluster an object is placed on according to the
+// Placement, given the number of objects already placed on each Cluster. Only ready
+// Clusters which are not being deleted, have ready nodes, are selected by the Placement
+// and are not full are eligible. It returns an empty name if no Cluster is eligible.
+func schedule(placement *clusterv1alpha1.Placement, clusters []*clusterv1alpha1.Cluster, placed map[string]int) (string, error) {
+	eligible, err := eligibleClusters(placement, clusters, placed)
+	if err != nil || len(eligible) == 0 {
+		return "", err
+	}
+
+	pack := placement.Spec.SpreadPolicy == clusterv1alpha1.SpreadPolicyPack
+	sort.Slice(eligible, func(i, j int) bool {
+		ci, cj := placed[eligible[i].Name], placed[eligible[j].Name]
+		if ci != cj {
+			if pack {
+				return ci > cj
+			}
+			return ci < cj
+		}
+		return eligible[i].Name < eligible[j].Name
+	})
+	return eligible[0].Name, nil
+}
+
+// eligibleClusters returns the Clusters an object can be placed on according to the
+// Placement, given the number of objects already placed on each Cluster.
+func eligibleClusters(placement *clusterv1alpha1.Placement, clusters []*clusterv1alpha1.Cluster, placed map[string]int) ([]*clusterv1alpha1.Cluster, error) {
+	var eligible []*clusterv1alpha1.Cluster
+	for _, cluster := range clusters {
+		if !cluster.Status.Conditions.IsTrue(clusterv1alpha1.ClusterConditionReady) || cluster.DeletionTimestamp != nil {
+			continue
+		}
+		if !hasReadyNodes(cluster) {
+			continue
+		}
+		if max := placement.Spec.MaxObjectsPerCluster; max > 0 && placed[cluster.Name] >= int(max) {
+			continue
+		}
+		ok, err := selects(placement.Spec.ClusterSelector, cluster.Labels)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			eligible = append(eligible, cluster)
+		}
+	}
+	return eligible, nil
+}
+
+// hasReadyNodes returns whether the cluster has ready and schedulable nodes according to
+// its current capacity. Clusters whose capacity is unknown or stale are assumed to have
+// some.
+func hasReadyNodes(cluster *clusterv1alpha1.Cluster) bool {
+	capacity := cluster.Status.Capacity
+	if capacity == nil || !cluster.Status.Conditions.IsTrue(clusterv1alpha1.ClusterConditionCapacityCurrent) {
+		return true
+	}
+	return capacity.ReadyNodes > 0
+}
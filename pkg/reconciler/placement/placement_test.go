@@ -0,0 +1,265 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	clusterv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/cluster/v1alpha1"
+)
+
+func TestSchedule(t *testing.T) {
+	cluster := func(name string, ready bool, labels map[string]string) *clusterv1alpha1.Cluster {
+		c := &clusterv1alpha1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+		if ready {
+			c.Status.SetConditionReady("True", "", "")
+		}
+		return c
+	}
+	clusters := []*clusterv1alpha1.Cluster{
+		cluster("east", true, map[string]string{"region": "us"}),
+		cluster("west", true, map[string]string{"region": "us"}),
+		cluster("europe", true, map[string]string{"region": "eu"}),
+		cluster("down", false, map[string]string{"region": "us"}),
+		cluster("no-nodes", true, map[string]string{"region": "eu", "nodes": "none"}),
+	}
+	clusters[4].Status.Capacity = &clusterv1alpha1.ClusterCapacity{Nodes: 2}
+	clusters[4].Status.SetCondition(clusterv1alpha1.ClusterConditionCapacityCurrent, "True", "", "")
+	placed := map[string]int{"east": 3, "west": 1, "europe": 0, "down": 0, "no-nodes": 0}
+
+	tests := []struct {
+		name string
+		spec clusterv1alpha1.PlacementSpec
+		want string
+	}{
+		{name: "spread", spec: clusterv1alpha1.PlacementSpec{}, want: "europe"},
+		{name: "spread with selector", spec: clusterv1alpha1.PlacementSpec{
+			ClusterSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"region": "us"}},
+		}, want: "west"},
+		{name: "pack", spec: clusterv1alpha1.PlacementSpec{SpreadPolicy: clusterv1alpha1.SpreadPolicyPack}, want: "east"},
+		{name: "pack until full", spec: clusterv1alpha1.PlacementSpec{
+			SpreadPolicy:         clusterv1alpha1.SpreadPolicyPack,
+			MaxObjectsPerCluster: 3,
+		}, want: "west"},
+		{name: "no ready nodes", spec: clusterv1alpha1.PlacementSpec{
+			ClusterSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"nodes": "none"}},
+		}, want: ""},
+		{name: "all full", spec: clusterv1alpha1.PlacementSpec{
+			ClusterSelector:      &metav1.LabelSelector{MatchLabels: map[string]string{"region": "us"}},
+			MaxObjectsPerCluster: 1,
+		}, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := schedule(&clusterv1alpha1.Placement{Spec: tt.spec}, clusters, placed)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPlacementFor(t *testing.T) {
+	placements := []*clusterv1alpha1.Placement{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "b-all"},
+			Spec:       clusterv1alpha1.PlacementSpec{Resources: []string{"deployments.apps"}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "a-prod"},
+			Spec: clusterv1alpha1.PlacementSpec{
+				Resources:         []string{"deployments.apps"},
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}},
+			},
+		},
+	}
+	deployments := schema.GroupResource{Group: "apps", Resource: "deployments"}
+
+	tests := []struct {
+		name            string
+		gr              schema.GroupResource
+		namespaceLabels map[string]string
+		want            string
+	}{
+		{name: "first by name", gr: deployments, namespaceLabels: map[string]string{"env": "prod"}, want: "a-prod"},
+		{name: "namespace not selected", gr: deployments, namespaceLabels: map[string]string{"env": "dev"}, want: "b-all"},
+		{name: "resource not placed", gr: schema.GroupResource{Resource: "configmaps"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := placementFor(placements, tt.gr, tt.namespaceLabels, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var name string
+			if got != nil {
+				name = got.Name
+			}
+			if name != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, name)
+			}
+		})
+	}
+}
+
+func TestEvicts(t *testing.T) {
+	now := time.Now()
+	cluster := func(ready bool, transitioned time.Duration) *clusterv1alpha1.Cluster {
+		status := corev1.ConditionFalse
+		if ready {
+			status = corev1.ConditionTrue
+		}
+		return &clusterv1alpha1.Cluster{Status: clusterv1alpha1.ClusterStatus{Conditions: clusterv1alpha1.Conditions{{
+			Type:               clusterv1alpha1.ClusterConditionReady,
+			Status:             status,
+			LastTransitionTime: metav1.NewTime(now.Add(-transitioned)),
+		}}}}
+	}
+	deleting := cluster(true, time.Hour)
+	deleting.DeletionTimestamp = &metav1.Time{Time: now}
+
+	tests := []struct {
+		name      string
+		cluster   *clusterv1alpha1.Cluster
+		wantEvict bool
+		wantAfter time.Duration
+	}{
+		{name: "gone", cluster: nil, wantEvict: true},
+		{name: "deleting", cluster: deleting, wantEvict: true},
+		{name: "ready", cluster: cluster(true, time.Hour)},
+		{name: "unready for a while", cluster: cluster(false, time.Minute), wantAfter: 4 * time.Minute},
+		{name: "unready for too long", cluster: cluster(false, 10*time.Minute), wantEvict: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evict, after := evicts(tt.cluster, now, 5*time.Minute)
+			if evict != tt.wantEvict || after != tt.wantAfter {
+				t.Errorf("expected (%v, %v), got (%v, %v)", tt.wantEvict, tt.wantAfter, evict, after)
+			}
+		})
+	}
+}
+
+func TestDefault(t *testing.T) {
+	newIndexer := func(objs ...interface{}) cache.Indexer {
+		indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{logicalClusterIndex: indexLogicalCluster})
+		for _, obj := range objs {
+			if err := indexer.Add(obj); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return indexer
+	}
+	readyCluster := func(name string, labels map[string]string) *clusterv1alpha1.Cluster {
+		c := &clusterv1alpha1.Cluster{ObjectMeta: metav1.ObjectMeta{ClusterName: "tenant", Name: name, Labels: labels}}
+		c.Status.SetConditionReady("True", "", "")
+		return c
+	}
+	d := &Defaulter{
+		placementIndexer: newIndexer(
+			&clusterv1alpha1.Placement{ObjectMeta: metav1.ObjectMeta{ClusterName: "tenant", Name: "eu"}, Spec: clusterv1alpha1.PlacementSpec{
+				Resources:         []string{"deployments.apps"},
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"region": "eu"}},
+				ClusterSelector:   &metav1.LabelSelector{MatchLabels: map[string]string{"region": "eu"}},
+			}},
+			&clusterv1alpha1.Placement{ObjectMeta: metav1.ObjectMeta{ClusterName: "tenant", Name: "us"}, Spec: clusterv1alpha1.PlacementSpec{
+				Resources:         []string{"deployments.apps"},
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"region": "us"}},
+				ClusterSelector:   &metav1.LabelSelector{MatchLabels: map[string]string{"region": "us"}},
+			}},
+		),
+		clusterIndexer: newIndexer(
+			readyCluster("europe", map[string]string{"region": "eu"}),
+			readyCluster("east", map[string]string{"region": "us"}),
+			readyCluster("west", map[string]string{"region": "us"}),
+		),
+		namespaceLister: corelisters.NewNamespaceLister(newIndexer(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{ClusterName: "tenant", Name: "scheduled",
+				Labels:      map[string]string{clusterv1alpha1.ClusterLabel: "east"},
+				Annotations: map[string]string{clusterv1alpha1.PlacementAnnotation: "namespaces"}}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{ClusterName: "tenant", Name: "eu", Labels: map[string]string{"region": "eu"}}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{ClusterName: "tenant", Name: "us", Labels: map[string]string{"region": "us"}}},
+		)),
+		resources: []string{"deployments.apps"},
+		hasSynced: func() bool { return true },
+	}
+	deployments := schema.GroupResource{Group: "apps", Resource: "deployments"}
+
+	tests := []struct {
+		name            string
+		gr              schema.GroupResource
+		obj             *metav1.ObjectMeta
+		wantLabels      map[string]string
+		wantAnnotations map[string]string
+	}{
+		{
+			name:            "namespace of a Cluster",
+			gr:              deployments,
+			obj:             &metav1.ObjectMeta{Namespace: "scheduled", Name: "app"},
+			wantLabels:      map[string]string{clusterv1alpha1.ClusterLabel: "east"},
+			wantAnnotations: map[string]string{clusterv1alpha1.NamespacePlacementAnnotation: "namespaces"},
+		},
+		{
+			name:            "single eligible Cluster",
+			gr:              deployments,
+			obj:             &metav1.ObjectMeta{Namespace: "eu", Name: "app"},
+			wantLabels:      map[string]string{clusterv1alpha1.ClusterLabel: "europe"},
+			wantAnnotations: map[string]string{clusterv1alpha1.PlacementAnnotation: "eu"},
+		},
+		{
+			name: "several eligible Clusters",
+			gr:   deployments,
+			obj:  &metav1.ObjectMeta{Namespace: "us", Name: "app"},
+		},
+		{
+			name:       "labeled by hand",
+			gr:         deployments,
+			obj:        &metav1.ObjectMeta{Namespace: "eu", Name: "app", Labels: map[string]string{clusterv1alpha1.ClusterLabel: "elsewhere"}},
+			wantLabels: map[string]string{clusterv1alpha1.ClusterLabel: "elsewhere"},
+		},
+		{
+			name: "not synced",
+			gr:   schema.GroupResource{Resource: "configmaps"},
+			obj:  &metav1.ObjectMeta{Namespace: "scheduled", Name: "config"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := d.Default("tenant", tt.gr, tt.obj); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.wantLabels, tt.obj.Labels); diff != "" {
+				t.Errorf("unexpected labels (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantAnnotations, tt.obj.Annotations); diff != "" {
+				t.Errorf("unexpected annotations (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
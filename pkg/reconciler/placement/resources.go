@@ -0,0 +1,132 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	"github.com/kcp-dev/kcp/pkg/client/metadatainformer"
+)
+
+// watchedResources watches the metadata of the objects of the resources to place, once
+// they are served: placing objects only takes their labels and annotations.
+type watchedResources struct {
+	discoveryClient discovery.DiscoveryInterface
+	informerFactory metadatainformer.ClusterMetadataSharedInformerFactory
+
+	// resources are the resources of the objects to watch.
+	resources []string
+	// indexers are added to the informer of every watched resource.
+	indexers cache.Indexers
+	// handler is called with the objects of the watched resources when they are added
+	// or updated.
+	handler func(gvr schema.GroupVersionResource, obj interface{})
+
+	objectIndexers     map[schema.GroupVersionResource]cache.Indexer
+	kinds              map[schema.GroupVersionResource]schema.GroupVersionKind
+	objectIndexersLock sync.RWMutex
+}
+
+// watch starts watching the resources which are served and not watched yet.
+func (w *watchedResources) watch(ctx context.Context) {
+	groupResources, err := restmapper.GetAPIGroupResources(w.discoveryClient)
+	if err != nil && len(groupResources) == 0 {
+		klog.Errorf("Failed to discover resources to place: %v", err)
+		return
+	}
+	mapper := restmapper.NewDiscoveryRESTMapper(groupResources)
+
+	w.objectIndexersLock.Lock()
+	defer w.objectIndexersLock.Unlock()
+
+	for _, resource := range w.resources {
+		gvr, err := mapper.ResourceFor(schema.ParseGroupResource(resource).WithVersion(""))
+		if err != nil {
+			klog.V(4).Infof("Resource %q is not served yet: %v", resource, err)
+			continue
+		}
+		if _, found := w.objectIndexers[gvr]; found {
+			continue
+		}
+		gvk, err := mapper.KindFor(gvr)
+		if err != nil {
+			klog.V(4).Infof("Kind of resource %s is not served yet: %v", gvr, err)
+			continue
+		}
+
+		informer := w.informerFactory.ForResource(gvr).Informer()
+		if err := informer.AddIndexers(w.indexers); err != nil {
+			runtime.HandleError(fmt.Errorf("failed to add indexer for %s: %w", gvr, err))
+			continue
+		}
+		informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    func(obj interface{}) { w.handler(gvr, obj) },
+			UpdateFunc: func(_, obj interface{}) { w.handler(gvr, obj) },
+		})
+		w.objectIndexers[gvr] = informer.GetIndexer()
+		w.kinds[gvr] = gvk
+		klog.Infof("Placing objects of resource %s", gvr)
+	}
+	w.informerFactory.Start(ctx.Done())
+}
+
+// indexer returns the indexer of the given resource, or nil if it is not watched.
+func (w *watchedResources) indexer(gvr schema.GroupVersionResource) cache.Indexer {
+	w.objectIndexersLock.RLock()
+	defer w.objectIndexersLock.RUnlock()
+	return w.objectIndexers[gvr]
+}
+
+// reference returns a copy of the watched metadata of an object of the given resource, with
+// the kind of the resource rather than PartialObjectMetadata, to record events about it.
+func (w *watchedResources) reference(gvr schema.GroupVersionResource, obj *metav1.PartialObjectMetadata) kruntime.Object {
+	w.objectIndexersLock.RLock()
+	defer w.objectIndexersLock.RUnlock()
+	ref := obj.DeepCopy()
+	ref.SetGroupVersionKind(w.kinds[gvr])
+	return ref
+}
+
+// byIndex returns the objects of all watched resources found under the given index key.
+func (w *watchedResources) byIndex(indexName, indexKey string) map[schema.GroupVersionResource][]interface{} {
+	w.objectIndexersLock.RLock()
+	defer w.objectIndexersLock.RUnlock()
+
+	objs := map[schema.GroupVersionResource][]interface{}{}
+	for gvr, indexer := range w.objectIndexers {
+		indexed, err := indexer.ByIndex(indexName, indexKey)
+		if err != nil {
+			runtime.HandleError(err)
+			continue
+		}
+		if len(indexed) > 0 {
+			objs[gvr] = indexed
+		}
+	}
+	return objs
+}
@@ -0,0 +1,209 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexclusion
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/endpoints/handlers/responsewriters"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+)
+
+// discoveryPath matches the discovery of the resources of a group version.
+var discoveryPath = regexp.MustCompile(`^/(api/v1|apis/([^/]+)/[^/]+)/?$`)
+
+// Exclusions are the resources not served in a logical cluster.
+type Exclusions struct {
+	excluded    []metav1.GroupResource
+	enabled     []metav1.GroupResource
+	unsupported []metav1.GroupResource
+	supported   []metav1.GroupResource
+}
+
+// NewExclusions returns the exclusions of the given resources of a WorkspaceType.
+func NewExclusions(resources *tenancyv1alpha1.WorkspaceResources) *Exclusions {
+	if resources == nil || (len(resources.Excluded) == 0 && len(resources.Unsupported) == 0 && len(resources.Supported) == 0) {
+		return nil
+	}
+	return &Exclusions{
+		excluded:    resources.Excluded,
+		enabled:     resources.Enabled,
+		unsupported: resources.Unsupported,
+		supported:   resources.Supported,
+	}
+}
+
+// Excludes returns whether the resource, or the resource of the subresource, is not
+// served.
+func (e *Exclusions) Excludes(gr schema.GroupResource) bool {
+	if e == nil {
+		return false
+	}
+	if i := strings.Index(gr.Resource, "/"); i != -1 {
+		gr.Resource = gr.Resource[:i]
+	}
+	return matches(e.excluded, gr) && !matches(e.enabled, gr)
+}
+
+func matches(grs []metav1.GroupResource, gr schema.GroupResource) bool {
+	for _, candidate := range grs {
+		if candidate.Group == gr.Group && (candidate.Resource == "*" || candidate.Resource == gr.Resource) {
+			return true
+		}
+	}
+	return false
+}
+
+// Registry holds the exclusions of the logical clusters of the workspaces.
+type Registry struct {
+	lock       sync.RWMutex
+	exclusions map[string]*Exclusions
+}
+
+// NewRegistry returns a Registry without exclusions.
+func NewRegistry() *Registry {
+	return &Registry{
+		exclusions: map[string]*Exclusions{},
+	}
+}
+
+// Exclusions returns the exclusions of the given logical cluster, nil if it serves all
+// resources.
+func (r *Registry) Exclusions(clusterName string) *Exclusions {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.exclusions[clusterName]
+}
+
+func (r *Registry) set(clusterName string, exclusions *Exclusions) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if exclusions == nil {
+		delete(r.exclusions, clusterName)
+	} else {
+		r.exclusions[clusterName] = exclusions
+	}
+}
+
+// WithResourceExclusion rejects the requests for the resources excluded from their logical
+// cluster with 404 Not Found, like for resources which aren't served at all, and removes
+// them from the discovery of the logical cluster. Requests for the unsupported resources
+// and subresources are rejected with 405 Method Not Allowed and an UnsupportedCause
+// explaining how to serve them instead. It must be wrapped by the authentication and
+// authorization filters.
+func WithResourceExclusion(handler http.Handler, registry *Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		cluster := genericapirequest.ClusterFrom(req.Context())
+		info, ok := genericapirequest.RequestInfoFrom(req.Context())
+		if !ok || cluster == nil || cluster.Wildcard {
+			handler.ServeHTTP(w, req)
+			return
+		}
+		exclusions := registry.Exclusions(cluster.Name)
+
+		if info.IsResourceRequest {
+			gr := schema.GroupResource{Group: info.APIGroup, Resource: info.Resource}
+			subresource := gr
+			if info.Subresource != "" {
+				subresource.Resource += "/" + info.Subresource
+			}
+			var err error
+			switch {
+			case exclusions.Excludes(gr):
+				err = apierrors.NewNotFound(gr, info.Name)
+			case exclusions.Unsupported(subresource):
+				err = NewUnsupportedError(subresource, info.Name)
+			default:
+				handler.ServeHTTP(w, req)
+				return
+			}
+			responsewriters.ErrorNegotiated(err, scheme.Codecs, schema.GroupVersion{Group: info.APIGroup, Version: info.APIVersion}, w, req)
+			return
+		}
+
+		if exclusions == nil {
+			handler.ServeHTTP(w, req)
+			return
+		}
+		match := discoveryPath.FindStringSubmatch(info.Path)
+		if match == nil || info.Verb != "get" {
+			handler.ServeHTTP(w, req)
+			return
+		}
+		recorder := &responseRecorder{header: http.Header{}, status: http.StatusOK}
+		handler.ServeHTTP(recorder, req)
+		body := recorder.body.Bytes()
+		if recorder.status == http.StatusOK && strings.HasPrefix(recorder.header.Get("Content-Type"), "application/json") && recorder.header.Get("Content-Encoding") == "" {
+			if filtered, err := filterDiscovery(body, match[2], exclusions); err == nil {
+				body = filtered
+			}
+		}
+		for k, v := range recorder.header {
+			w.Header()[k] = v
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(recorder.status)
+		_, _ = w.Write(body)
+	})
+}
+
+// filterDiscovery removes the excluded resources from the discovery of a group version.
+func filterDiscovery(body []byte, group string, exclusions *Exclusions) ([]byte, error) {
+	var list metav1.APIResourceList
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, err
+	}
+	resources := make([]metav1.APIResource, 0, len(list.APIResources))
+	for _, resource := range list.APIResources {
+		if !exclusions.Excludes(schema.GroupResource{Group: group, Resource: resource.Name}) {
+			resources = append(resources, resource)
+		}
+	}
+	list.APIResources = resources
+	return json.Marshal(&list)
+}
+
+// responseRecorder holds a response in memory.
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	return r.body.Write(data)
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexclusion
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+)
+
+func TestWithResourceExclusion(t *testing.T) {
+	registry := NewRegistry()
+	registry.set("tenant", NewExclusions(&tenancyv1alpha1.WorkspaceResources{
+		Excluded: []metav1.GroupResource{{Resource: "nodes"}, {Resource: "persistentvolumes"}, {Group: "storage.k8s.io", Resource: "*"}},
+		Enabled:  []metav1.GroupResource{{Group: "storage.k8s.io", Resource: "storageclasses"}},
+	}))
+
+	discovery := map[string]metav1.APIResourceList{
+		"/api/v1": {GroupVersion: "v1", APIResources: []metav1.APIResource{
+			{Name: "configmaps"}, {Name: "nodes"}, {Name: "nodes/status"}, {Name: "persistentvolumes"},
+		}},
+		"/apis/storage.k8s.io/v1": {GroupVersion: "storage.k8s.io/v1", APIResources: []metav1.APIResource{
+			{Name: "csidrivers"}, {Name: "storageclasses"},
+		}},
+	}
+	handler := WithResourceExclusion(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		list, ok := discovery[req.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(list)
+	}), registry)
+
+	serve := func(cluster string, info *genericapirequest.RequestInfo) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, info.Path, nil)
+		ctx := genericapirequest.WithCluster(req.Context(), genericapirequest.Cluster{Name: cluster})
+		ctx = genericapirequest.WithRequestInfo(ctx, info)
+		rw := httptest.NewRecorder()
+		handler.ServeHTTP(rw, req.WithContext(ctx))
+		return rw
+	}
+	resourceRequest := func(group, resource string) *genericapirequest.RequestInfo {
+		return &genericapirequest.RequestInfo{IsResourceRequest: true, Verb: "list", APIGroup: group, APIVersion: "v1", Resource: resource, Path: "/"}
+	}
+
+	for _, tc := range []struct {
+		cluster  string
+		info     *genericapirequest.RequestInfo
+		wantCode int
+	}{
+		{"tenant", resourceRequest("", "configmaps"), http.StatusOK},
+		{"tenant", resourceRequest("", "nodes"), http.StatusNotFound},
+		{"tenant", resourceRequest("storage.k8s.io", "csidrivers"), http.StatusNotFound},
+		{"tenant", resourceRequest("storage.k8s.io", "storageclasses"), http.StatusOK},
+		{"other", resourceRequest("", "nodes"), http.StatusOK},
+	} {
+		if rw := serve(tc.cluster, tc.info); rw.Code != tc.wantCode {
+			t.Errorf("expected %d for %s in %s, got %d", tc.wantCode, tc.info.Resource, tc.cluster, rw.Code)
+		}
+	}
+
+	discovered := func(cluster, path string) []string {
+		rw := serve(cluster, &genericapirequest.RequestInfo{Verb: "get", Path: path})
+		var list metav1.APIResourceList
+		if err := json.Unmarshal(rw.Body.Bytes(), &list); err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, resource := range list.APIResources {
+			names = append(names, resource.Name)
+		}
+		return names
+	}
+	if diff := cmp.Diff([]string{"configmaps"}, discovered("tenant", "/api/v1")); diff != "" {
+		t.Errorf("unexpected core resources (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"storageclasses"}, discovered("tenant", "/apis/storage.k8s.io/v1")); diff != "" {
+		t.Errorf("unexpected storage resources (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"configmaps", "nodes", "nodes/status", "persistentvolumes"}, discovered("other", "/api/v1")); diff != "" {
+		t.Errorf("unexpected core resources of another cluster (-want +got):\n%s", diff)
+	}
+}
+
+func TestUnsupported(t *testing.T) {
+	registry := NewRegistry()
+	registry.set("tenant", NewExclusions(&tenancyv1alpha1.WorkspaceResources{
+		Unsupported: []metav1.GroupResource{{Group: "batch", Resource: "jobs"}},
+		Supported:   []metav1.GroupResource{{Resource: "pods/log"}},
+	}))
+	handler := WithResourceExclusion(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}), registry)
+
+	for _, tc := range []struct {
+		cluster                      string
+		group, resource, subresource string
+		wantUnsupported              bool
+	}{
+		{cluster: "tenant", resource: "pods"},
+		{cluster: "tenant", resource: "pods", subresource: "exec", wantUnsupported: true},
+		{cluster: "tenant", resource: "pods", subresource: "log"},
+		{cluster: "tenant", group: "batch", resource: "jobs", wantUnsupported: true},
+		{cluster: "other", resource: "pods", subresource: "log", wantUnsupported: true},
+		{cluster: "other", group: "batch", resource: "jobs"},
+	} {
+		info := &genericapirequest.RequestInfo{IsResourceRequest: true, Verb: "get", APIGroup: tc.group, APIVersion: "v1", Resource: tc.resource, Subresource: tc.subresource, Name: "foo", Path: "/"}
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		ctx := genericapirequest.WithCluster(req.Context(), genericapirequest.Cluster{Name: tc.cluster})
+		ctx = genericapirequest.WithRequestInfo(ctx, info)
+		rw := httptest.NewRecorder()
+		handler.ServeHTTP(rw, req.WithContext(ctx))
+
+		if !tc.wantUnsupported {
+			if rw.Code != http.StatusOK {
+				t.Errorf("expected %s/%s in %s to be served, got %d", tc.resource, tc.subresource, tc.cluster, rw.Code)
+			}
+			continue
+		}
+		var status metav1.Status
+		if err := json.Unmarshal(rw.Body.Bytes(), &status); err != nil {
+			t.Fatal(err)
+		}
+		if rw.Code != http.StatusMethodNotAllowed || !IsUnsupported(&apierrors.StatusError{ErrStatus: status}) {
+			t.Errorf("expected %s/%s in %s to be unsupported, got %d: %s", tc.resource, tc.subresource, tc.cluster, rw.Code, rw.Body.String())
+		}
+	}
+}
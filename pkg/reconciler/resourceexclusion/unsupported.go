@@ -0,0 +1,92 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceexclusion
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// UnsupportedCause is the type of the cause of the errors returned for the resources
+// and subresources kcp can't serve natively.
+const UnsupportedCause metav1.CauseType = "UnsupportedByKCP"
+
+// unsupportedGuidance tells how to serve the unsupported resources instead.
+const unsupportedGuidance = "kcp doesn't run workloads itself; place them on a physical cluster, where the syncer runs them, and make this request to that cluster, " +
+	"see https://github.com/kcp-dev/kcp/blob/main/docs/architecture/README.md#syncer"
+
+// DefaultUnsupported are the subresources rejected in every logical cluster, which are
+// discovered wherever pods, services or nodes are served but need a kubelet or a network
+// that kcp doesn't have.
+var DefaultUnsupported = []metav1.GroupResource{
+	{Resource: "pods/attach"},
+	{Resource: "pods/exec"},
+	{Resource: "pods/log"},
+	{Resource: "pods/portforward"},
+	{Resource: "pods/proxy"},
+	{Resource: "services/proxy"},
+	{Resource: "nodes/proxy"},
+}
+
+// Unsupported returns whether the resource or subresource, like "pods/exec", is rejected
+// as unsupported.
+func (e *Exclusions) Unsupported(gr schema.GroupResource) bool {
+	if e == nil {
+		return matches(DefaultUnsupported, gr)
+	}
+	return (matches(DefaultUnsupported, gr) || matches(e.unsupported, gr)) && !matches(e.supported, gr)
+}
+
+// NewUnsupportedError returns the error of a request for an unsupported resource or
+// subresource.
+func NewUnsupportedError(gr schema.GroupResource, name string) *apierrors.StatusError {
+	return &apierrors.StatusError{ErrStatus: metav1.Status{
+		Status: metav1.StatusFailure,
+		Code:   http.StatusMethodNotAllowed,
+		Reason: metav1.StatusReasonMethodNotAllowed,
+		Details: &metav1.StatusDetails{
+			Group: gr.Group,
+			Kind:  gr.Resource,
+			Name:  name,
+			Causes: []metav1.StatusCause{{
+				Type:    UnsupportedCause,
+				Message: unsupportedGuidance,
+			}},
+		},
+		Message: fmt.Sprintf("%s is not supported by kcp: %s", gr.String(), unsupportedGuidance),
+	}}
+}
+
+// IsUnsupported returns whether the error was returned for an unsupported resource or
+// subresource.
+func IsUnsupported(err error) bool {
+	var statusErr apierrors.APIStatus
+	if !errors.As(err, &statusErr) || statusErr.Status().Details == nil {
+		return false
+	}
+	for _, cause := range statusErr.Status().Details.Causes {
+		if cause.Type == UnsupportedCause {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shardcredentials
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/request/bearertoken"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	tokenIndex = "token"
+
+	// ShardGroup is the group of the shards authenticated with their tokens. Shards
+	// proxy requests on behalf of their users, and are only allowed to impersonate them.
+	ShardGroup = "system:kcp:shards"
+)
+
+// Authenticator authenticates requests bearing the current or, during the grace period,
+// the previous token issued for a shard as a user named after the shard, member of
+// ShardGroup.
+type Authenticator struct {
+	lock        sync.RWMutex
+	controller  *Controller
+	secretIndex cache.Indexer
+	gracePeriod time.Duration
+	now         func() time.Time
+}
+
+// NewAuthenticator returns an Authenticator which accepts no token until it is initialized
+// by the controller.
+func NewAuthenticator() *Authenticator {
+	return &Authenticator{now: time.Now}
+}
+
+func (a *Authenticator) initialize(c *Controller, secretInformer coreinformers.SecretInformer) error {
+	if err := secretInformer.Informer().AddIndexers(map[string]cache.IndexFunc{
+		tokenIndex: func(obj interface{}) ([]string, error) {
+			secret, ok := obj.(*corev1.Secret)
+			if !ok || secret.Annotations[ShardAnnotation] == "" {
+				return []string{}, nil
+			}
+			var tokens []string
+			for _, key := range []string{TokenKey, PreviousTokenKey} {
+				if token := secret.Data[key]; len(token) > 0 {
+					tokens = append(tokens, string(token))
+				}
+			}
+			return tokens, nil
+		},
+	}); err != nil {
+		return fmt.Errorf("failed to add indexer for Secret: %w", err)
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.controller = c
+	a.secretIndex = secretInformer.Informer().GetIndexer()
+	a.gracePeriod = c.options.GracePeriod
+	return nil
+}
+
+// ImpersonationAuthorizer allows the members of ShardGroup to impersonate any user, group
+// or user extra, so that the destination shard authorizes the user of the proxied request.
+// It has no opinion on any other request.
+func ImpersonationAuthorizer() authorizer.Authorizer {
+	return authorizer.AuthorizerFunc(func(_ context.Context, attr authorizer.Attributes) (authorizer.Decision, string, error) {
+		if attr.GetUser() == nil || attr.GetVerb() != "impersonate" {
+			return authorizer.DecisionNoOpinion, "", nil
+		}
+		for _, group := range attr.GetUser().GetGroups() {
+			if group == ShardGroup {
+				return authorizer.DecisionAllow, "shards impersonate the users of proxied requests", nil
+			}
+		}
+		return authorizer.DecisionNoOpinion, "", nil
+	})
+}
+
+// Request returns the request authenticator.
+func (a *Authenticator) Request() authenticator.Request {
+	return bearertoken.New(authenticator.TokenFunc(a.authenticateToken))
+}
+
+func (a *Authenticator) authenticateToken(_ context.Context, token string) (*authenticator.Response, bool, error) {
+	a.lock.RLock()
+	c, index, gracePeriod := a.controller, a.secretIndex, a.gracePeriod
+	a.lock.RUnlock()
+	if index == nil || token == "" {
+		return nil, false, nil
+	}
+
+	secrets, err := index.ByIndex(tokenIndex, token)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, obj := range secrets {
+		secret := obj.(*corev1.Secret)
+		shardName, ok := c.referencingShard(secret)
+		if !ok {
+			continue
+		}
+		valid := subtle.ConstantTimeCompare(secret.Data[TokenKey], []byte(token)) == 1
+		if !valid && subtle.ConstantTimeCompare(secret.Data[PreviousTokenKey], []byte(token)) == 1 {
+			rotatedAt, err := time.Parse(time.RFC3339, secret.Annotations[RotatedAtAnnotation])
+			valid = err == nil && a.now().Before(rotatedAt.Add(gracePeriod))
+		}
+		if !valid {
+			continue
+		}
+		return &authenticator.Response{
+			User: &user.DefaultInfo{
+				Name:   "system:kcp:shard:" + shardName,
+				Groups: []string{ShardGroup, user.AllAuthenticated},
+			},
+		}, true, nil
+	}
+	return nil, false, nil
+}
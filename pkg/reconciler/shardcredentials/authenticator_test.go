@@ -0,0 +1,124 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shardcredentials
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	kcpfake "github.com/kcp-dev/kcp/pkg/client/clientset/versioned/fake"
+	kcpinformers "github.com/kcp-dev/kcp/pkg/client/informers/externalversions"
+)
+
+func TestAuthenticateToken(t *testing.T) {
+	rotatedAt := time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	shardInformer := kcpinformers.NewSharedInformerFactory(kcpfake.NewSimpleClientset(), 0).Tenancy().V1alpha1().WorkspaceShards()
+	secretInformer := kubeinformers.NewSharedInformerFactory(kubefake.NewSimpleClientset(), 0).Core().V1().Secrets()
+
+	authenticator := NewAuthenticator()
+	if _, err := NewController(nil, "root", nil, shardInformer, secretInformer, authenticator, Options{Interval: time.Hour, GracePeriod: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, shard := range []*tenancyv1alpha1.WorkspaceShard{
+		{ObjectMeta: metav1.ObjectMeta{ClusterName: "root", Name: "shard"}, Spec: tenancyv1alpha1.WorkspaceShardSpec{Credentials: &tenancyv1alpha1.SecretReference{Namespace: "default", Name: "shard-credentials"}}},
+		{ObjectMeta: metav1.ObjectMeta{ClusterName: "other", Name: "forged"}, Spec: tenancyv1alpha1.WorkspaceShardSpec{Credentials: &tenancyv1alpha1.SecretReference{Namespace: "default", Name: "forged-credentials"}}},
+	} {
+		if err := shardInformer.Informer().GetIndexer().Add(shard); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, secret := range []*corev1.Secret{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				ClusterName: "root", Namespace: "default", Name: "shard-credentials",
+				Annotations: map[string]string{ShardAnnotation: "shard", RotatedAtAnnotation: rotatedAt.Format(time.RFC3339)},
+			},
+			Data: map[string][]byte{TokenKey: []byte("current"), PreviousTokenKey: []byte("previous")},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				ClusterName: "other", Namespace: "default", Name: "forged-credentials",
+				Annotations: map[string]string{ShardAnnotation: "forged", RotatedAtAnnotation: rotatedAt.Format(time.RFC3339)},
+			},
+			Data: map[string][]byte{TokenKey: []byte("forged")},
+		},
+	} {
+		if err := secretInformer.Informer().GetIndexer().Add(secret); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		token    string
+		after    time.Duration
+		expected string
+	}{
+		{token: "current", after: 2 * time.Hour, expected: "system:kcp:shard:shard"},
+		{token: "previous", after: 30 * time.Minute, expected: "system:kcp:shard:shard"},
+		{token: "previous", after: 2 * time.Hour},
+		{token: "forged", after: 30 * time.Minute},
+		{token: "unknown", after: 30 * time.Minute},
+	}
+	for _, tt := range tests {
+		authenticator.now = func() time.Time { return rotatedAt.Add(tt.after) }
+		resp, ok, err := authenticator.authenticateToken(context.Background(), tt.token)
+		if err != nil {
+			t.Fatalf("token %q: unexpected error: %v", tt.token, err)
+		}
+		if tt.expected == "" {
+			if ok {
+				t.Errorf("token %q after %v: expected to be rejected, got user %q", tt.token, tt.after, resp.User.GetName())
+			}
+			continue
+		}
+		if !ok {
+			t.Errorf("token %q after %v: expected to be accepted", tt.token, tt.after)
+		} else if resp.User.GetName() != tt.expected {
+			t.Errorf("token %q after %v: expected user %q, got %q", tt.token, tt.after, tt.expected, resp.User.GetName())
+		}
+	}
+}
+
+func TestImpersonationAuthorizer(t *testing.T) {
+	shard := &user.DefaultInfo{Name: "system:kcp:shard:shard", Groups: []string{ShardGroup, user.AllAuthenticated}}
+	tests := []struct {
+		user     user.Info
+		verb     string
+		expected authorizer.Decision
+	}{
+		{user: shard, verb: "impersonate", expected: authorizer.DecisionAllow},
+		{user: shard, verb: "get", expected: authorizer.DecisionNoOpinion},
+		{user: &user.DefaultInfo{Name: "alice"}, verb: "impersonate", expected: authorizer.DecisionNoOpinion},
+	}
+	for _, tt := range tests {
+		attr := authorizer.AttributesRecord{User: tt.user, Verb: tt.verb, Resource: "users", ResourceRequest: true}
+		if decision, _, _ := ImpersonationAuthorizer().Authorize(context.Background(), attr); decision != tt.expected {
+			t.Errorf("%s by %q: expected decision %v, got %v", tt.verb, tt.user.GetName(), tt.expected, decision)
+		}
+	}
+}
@@ -0,0 +1,372 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shardcredentials
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/tools/clusters"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/client/clusterkeys"
+	tenancyinformer "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/tenancy/v1alpha1"
+	tenancylister "github.com/kcp-dev/kcp/pkg/client/listers/tenancy/v1alpha1"
+)
+
+const (
+	credentialsIndex = "credentials"
+	controllerName   = "shardcredentials"
+
+	// RotatedAtAnnotation is set on credentials Secrets to the time their token was last
+	// issued, in RFC 3339 format.
+	RotatedAtAnnotation = "tenancy.kcp.dev/rotated-at"
+	// ShardAnnotation is set on credentials Secrets to the name of the WorkspaceShard they
+	// give access to.
+	ShardAnnotation = "tenancy.kcp.dev/shard"
+
+	// KubeconfigKey is the key of the kubeconfig in credentials Secrets.
+	KubeconfigKey = "kubeconfig"
+	// TokenKey is the key of the current token in credentials Secrets.
+	TokenKey = "token"
+	// PreviousTokenKey is the key of the token issued before the current one, which is
+	// accepted until the end of the grace period.
+	PreviousTokenKey = "previous-token"
+)
+
+// Options configures the rotation of shard credentials.
+type Options struct {
+	// Interval is how often credentials are re-issued.
+	Interval time.Duration
+	// GracePeriod is how long the previous credentials stay valid after a rotation, so
+	// that consumers can pick up the new ones.
+	GracePeriod time.Duration
+}
+
+// ConfigConsumer is called with the new client config of a shard after its credentials
+// were rotated.
+type ConfigConsumer func(shardName string, config *rest.Config)
+
+// NewController returns a controller which issues and periodically rotates bearer tokens
+// for the WorkspaceShards of the root logical cluster referencing a credentials Secret,
+// and writes a kubeconfig using the token to that Secret. shardConfig holds the address
+// and CA of the shard used when the WorkspaceShard has no base URL.
+func NewController(
+	kubeClient *kubernetes.Cluster,
+	rootClusterName string,
+	shardConfig *rest.Config,
+	shardInformer tenancyinformer.WorkspaceShardInformer,
+	secretInformer coreinformers.SecretInformer,
+	authenticator *Authenticator,
+	options Options,
+	consumers ...ConfigConsumer,
+) (*Controller, error) {
+	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+
+	c := &Controller{
+		queue:           queue,
+		kubeClient:      kubeClient,
+		rootClusterName: rootClusterName,
+		shardConfig:     shardConfig,
+		shardIndexer:    shardInformer.Informer().GetIndexer(),
+		shardLister:     shardInformer.Lister(),
+		secretLister:    secretInformer.Lister(),
+		options:         options,
+		consumers:       consumers,
+		syncChecks: []cache.InformerSynced{
+			shardInformer.Informer().HasSynced,
+			secretInformer.Informer().HasSynced,
+		},
+	}
+
+	shardInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { c.enqueue(obj) },
+		UpdateFunc: func(_, obj interface{}) { c.enqueue(obj) },
+	})
+	if err := c.shardIndexer.AddIndexers(map[string]cache.IndexFunc{
+		credentialsIndex: func(obj interface{}) ([]string, error) {
+			if shard, ok := obj.(*tenancyv1alpha1.WorkspaceShard); ok && shard.Spec.Credentials != nil {
+				return []string{credentialsKey(shard.ClusterName, shard.Spec.Credentials.Namespace, shard.Spec.Credentials.Name)}, nil
+			}
+			return []string{}, nil
+		},
+	}); err != nil {
+		return nil, fmt.Errorf("failed to add indexer for WorkspaceShard: %w", err)
+	}
+
+	secretInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(_, obj interface{}) { c.enqueueSecret(obj) },
+		DeleteFunc: func(obj interface{}) { c.enqueueSecret(obj) },
+	})
+
+	if err := authenticator.initialize(c, secretInformer); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Controller watches WorkspaceShards and their credentials Secrets in order to rotate the
+// credentials.
+type Controller struct {
+	queue workqueue.RateLimitingInterface
+
+	kubeClient      *kubernetes.Cluster
+	rootClusterName string
+	shardConfig     *rest.Config
+
+	shardIndexer cache.Indexer
+	shardLister  tenancylister.WorkspaceShardLister
+	secretLister corelisters.SecretLister
+
+	options   Options
+	consumers []ConfigConsumer
+
+	syncChecks []cache.InformerSynced
+}
+
+func credentialsKey(clusterName, namespace, name string) string {
+	return clusterkeys.ToKey(clusterName, namespace, name)
+}
+
+func (c *Controller) enqueue(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	klog.V(4).Infof("queueing workspace shard %q", key)
+	c.queue.Add(key)
+}
+
+// enqueueSecret queues the shards referencing a changed credentials Secret, so that
+// modified or deleted credentials get re-issued.
+func (c *Controller) enqueueSecret(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	secret, ok := obj.(*corev1.Secret)
+	if !ok {
+		return
+	}
+	shards, err := c.shardIndexer.ByIndex(credentialsIndex, credentialsKey(secret.ClusterName, secret.Namespace, secret.Name))
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	for _, shard := range shards {
+		c.enqueue(shard)
+	}
+}
+
+// referencingShard returns the name of the root logical cluster shard referencing the
+// given Secret for its credentials, if any.
+func (c *Controller) referencingShard(secret *corev1.Secret) (string, bool) {
+	if secret.ClusterName != c.rootClusterName {
+		return "", false
+	}
+	shards, err := c.shardIndexer.ByIndex(credentialsIndex, credentialsKey(secret.ClusterName, secret.Namespace, secret.Name))
+	if err != nil || len(shards) == 0 {
+		return "", false
+	}
+	name := shards[0].(*tenancyv1alpha1.WorkspaceShard).Name
+	return name, secret.Annotations[ShardAnnotation] == name
+}
+
+func (c *Controller) Start(ctx context.Context, numThreads int) {
+	defer runtime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	klog.Info("Starting shard credentials controller")
+	defer klog.Info("Shutting down shard credentials controller")
+
+	if !cache.WaitForNamedCacheSync(controllerName, ctx.Done(), c.syncChecks...) {
+		klog.Warning("Failed to wait for caches to sync")
+		return
+	}
+
+	for i := 0; i < numThreads; i++ {
+		go wait.Until(func() { c.startWorker(ctx) }, time.Second, ctx.Done())
+	}
+
+	<-ctx.Done()
+}
+
+func (c *Controller) startWorker(ctx context.Context) {
+	for c.processNextWorkItem(ctx) {
+	}
+}
+
+func (c *Controller) processNextWorkItem(ctx context.Context) bool {
+	// Wait until there is a new item in the working queue
+	k, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	key := k.(string)
+
+	// No matter what, tell the queue we're done with this key, to unblock
+	// other workers.
+	defer c.queue.Done(key)
+
+	if err := c.process(ctx, key); err != nil {
+		runtime.HandleError(fmt.Errorf("%q controller failed to sync %q, err: %w", controllerName, key, err))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	c.queue.Forget(key)
+	return true
+}
+
+func (c *Controller) process(ctx context.Context, key string) error {
+	shard, err := c.shardLister.Get(key)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil // object deleted before we handled it
+		}
+		return err
+	}
+	if shard.ClusterName != c.rootClusterName || shard.Spec.Credentials == nil {
+		return nil
+	}
+
+	ref := shard.Spec.Credentials
+	secret, err := c.secretLister.Secrets(ref.Namespace).Get(clusters.ToClusterAwareKey(shard.ClusterName, ref.Name))
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	now := time.Now()
+	if secret != nil && secret.Annotations[ShardAnnotation] == shard.Name && len(secret.Data[TokenKey]) > 0 {
+		if rotatedAt, err := time.Parse(time.RFC3339, secret.Annotations[RotatedAtAnnotation]); err == nil {
+			if remaining := c.options.Interval - now.Sub(rotatedAt); remaining > 0 {
+				c.queue.AddAfter(key, remaining)
+				return nil
+			}
+		}
+	}
+
+	if err := c.rotate(ctx, shard, secret, now); err != nil {
+		return err
+	}
+	c.queue.AddAfter(key, c.options.Interval)
+	return nil
+}
+
+// rotate issues a new token for the shard, keeping the current one as previous token.
+func (c *Controller) rotate(ctx context.Context, shard *tenancyv1alpha1.WorkspaceShard, secret *corev1.Secret, now time.Time) error {
+	token, err := generateToken()
+	if err != nil {
+		return err
+	}
+	config := rest.AnonymousClientConfig(c.shardConfig)
+	if shard.Spec.BaseURL != "" {
+		config.Host = shard.Spec.BaseURL
+	}
+	config.BearerToken = token
+	kubeconfig, err := clientcmd.Write(kubeconfigFor(shard.Name, config))
+	if err != nil {
+		return err
+	}
+
+	ref := shard.Spec.Credentials
+	secrets := c.kubeClient.Cluster(shard.ClusterName).CoreV1().Secrets(ref.Namespace)
+	if secret == nil {
+		klog.Infof("issuing credentials of shard %q", shard.Name)
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ref.Namespace, Name: ref.Name},
+		}
+	} else {
+		klog.Infof("rotating credentials of shard %q", shard.Name)
+		secret = secret.DeepCopy()
+	}
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	previous := secret.Data[TokenKey]
+	if secret.Annotations[ShardAnnotation] != shard.Name {
+		// never accept tokens the controller did not issue
+		previous = nil
+	}
+	secret.Annotations[ShardAnnotation] = shard.Name
+	secret.Annotations[RotatedAtAnnotation] = now.UTC().Format(time.RFC3339)
+	secret.Type = corev1.SecretTypeOpaque
+	secret.Data = map[string][]byte{
+		KubeconfigKey: kubeconfig,
+		TokenKey:      []byte(token),
+	}
+	if len(previous) > 0 {
+		secret.Data[PreviousTokenKey] = previous
+	}
+
+	if secret.ResourceVersion == "" {
+		_, err = secrets.Create(ctx, secret, metav1.CreateOptions{})
+	} else {
+		_, err = secrets.Update(ctx, secret, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return err
+	}
+	for _, consumer := range c.consumers {
+		consumer(shard.Name, rest.CopyConfig(config))
+	}
+	return nil
+}
+
+func generateToken() (string, error) {
+	data := make([]byte, 32)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func kubeconfigFor(name string, config *rest.Config) clientcmdapi.Config {
+	return clientcmdapi.Config{
+		Clusters: map[string]*clientcmdapi.Cluster{
+			name: {
+				Server:                   config.Host,
+				CertificateAuthorityData: config.CAData,
+				TLSServerName:            config.ServerName,
+			},
+		},
+		AuthInfos: map[string]*clientcmdapi.AuthInfo{
+			name: {Token: config.BearerToken},
+		},
+		Contexts: map[string]*clientcmdapi.Context{
+			name: {Cluster: name, AuthInfo: name},
+		},
+		CurrentContext: name,
+	}
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shardversion
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/tools/clusters"
+	"k8s.io/kubernetes/pkg/genericcontrolplane"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	tenancylister "github.com/kcp-dev/kcp/pkg/client/listers/tenancy/v1alpha1"
+)
+
+// maxMinorSkew is how many minor versions shards of the same major version may be apart
+// and still work together.
+const maxMinorSkew = 1
+
+// Compatible returns whether workspaces can be moved from one shard to the other, and
+// requests served by one can be fanned out to the other, or why not. The kcp versions of
+// the shards must not be more than one minor version apart, and the target shard must
+// have all the feature gates of the source shard enabled. Shards which did not publish
+// their version are assumed to be compatible.
+func Compatible(from, to *tenancyv1alpha1.WorkspaceShard) (bool, string) {
+	if from.Status.Version == "" || to.Status.Version == "" {
+		return true, ""
+	}
+	fromVersion, err := utilversion.ParseGeneric(from.Status.Version)
+	if err != nil {
+		return false, fmt.Sprintf("shard %q publishes an invalid version %q", from.Name, from.Status.Version)
+	}
+	toVersion, err := utilversion.ParseGeneric(to.Status.Version)
+	if err != nil {
+		return false, fmt.Sprintf("shard %q publishes an invalid version %q", to.Name, to.Status.Version)
+	}
+	if fromVersion.Major() != toVersion.Major() || skew(fromVersion.Minor(), toVersion.Minor()) > maxMinorSkew {
+		return false, fmt.Sprintf("shard %q runs kcp %s, which is too far apart from kcp %s of shard %q", to.Name, to.Status.Version, from.Status.Version, from.Name)
+	}
+	if missing := sets.NewString(from.Status.FeatureGates...).Difference(sets.NewString(to.Status.FeatureGates...)); missing.Len() > 0 {
+		return false, fmt.Sprintf("shard %q lacks the feature gates %s enabled on shard %q", to.Name, strings.Join(missing.List(), ", "), from.Name)
+	}
+	return true, ""
+}
+
+func skew(a, b uint) uint {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// ProxyCompatibility returns a function telling why requests can't be proxied from the
+// shard named shardName to the shard with the given identifier, or an empty string if
+// they can. Identifiers are the sanitized names of the WorkspaceShards of the root logical
+// cluster, and localIdentifier identifies the proxying shard itself. Shards without a
+// WorkspaceShard are assumed to be compatible.
+func ProxyCompatibility(shardLister tenancylister.WorkspaceShardLister, rootClusterName, shardName, localIdentifier string) func(identifier string) string {
+	return func(identifier string) string {
+		if identifier == localIdentifier {
+			return ""
+		}
+		local, err := shardLister.Get(clusters.ToClusterAwareKey(rootClusterName, shardName))
+		if err != nil {
+			return ""
+		}
+		shards, err := shardLister.List(labels.Everything())
+		if err != nil {
+			return ""
+		}
+		for _, shard := range shards {
+			if shard.ClusterName != rootClusterName || genericcontrolplane.SanitizeClusterId(shard.Name) != identifier {
+				continue
+			}
+			if compatible, reason := Compatible(local, shard); !compatible {
+				return reason
+			}
+			return ""
+		}
+		return ""
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shardversion
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+)
+
+func shard(name, version string, featureGates ...string) *tenancyv1alpha1.WorkspaceShard {
+	return &tenancyv1alpha1.WorkspaceShard{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status:     tenancyv1alpha1.WorkspaceShardStatus{Version: version, FeatureGates: featureGates},
+	}
+}
+
+func TestCompatible(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		from, to   *tenancyv1alpha1.WorkspaceShard
+		compatible bool
+	}{
+		{
+			name:       "unpublished versions",
+			from:       shard("boston", ""),
+			to:         shard("atlanta", "v0.3.0"),
+			compatible: true,
+		},
+		{
+			name:       "same version",
+			from:       shard("boston", "v0.3.0", "A"),
+			to:         shard("atlanta", "v0.3.1", "A", "B"),
+			compatible: true,
+		},
+		{
+			name:       "one minor version apart",
+			from:       shard("boston", "v0.4.0"),
+			to:         shard("atlanta", "v0.3.2"),
+			compatible: true,
+		},
+		{
+			name: "two minor versions apart",
+			from: shard("boston", "v0.3.0"),
+			to:   shard("atlanta", "v0.5.0"),
+		},
+		{
+			name: "different major versions",
+			from: shard("boston", "v1.0.0"),
+			to:   shard("atlanta", "v0.9.0"),
+		},
+		{
+			name: "invalid version",
+			from: shard("boston", "v0.3.0"),
+			to:   shard("atlanta", "latest"),
+		},
+		{
+			name: "missing feature gate",
+			from: shard("boston", "v0.3.0", "A", "B"),
+			to:   shard("atlanta", "v0.3.0", "A"),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			compatible, reason := Compatible(tc.from, tc.to)
+			if compatible != tc.compatible {
+				t.Errorf("expected compatible to be %v, got %v: %s", tc.compatible, compatible, reason)
+			}
+			if !compatible && reason == "" {
+				t.Errorf("expected a reason for incompatible shards")
+			}
+		})
+	}
+}
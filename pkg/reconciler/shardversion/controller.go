@@ -0,0 +1,185 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package shardversion publishes the kcp version and the feature gates of a shard on its
+// WorkspaceShard, so that operations spanning several shards can check that the shards
+// are compatible first.
+package shardversion
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/component-base/featuregate"
+	"k8s.io/component-base/version"
+	"k8s.io/klog/v2"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	tenancyinformer "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/tenancy/v1alpha1"
+	tenancylister "github.com/kcp-dev/kcp/pkg/client/listers/tenancy/v1alpha1"
+)
+
+const controllerName = "shardversion"
+
+// NewController returns a controller which publishes the kcp version and the feature gates
+// enabled on this shard on the status of its WorkspaceShard, named shardName, in the root
+// logical cluster, whenever that status differs.
+func NewController(
+	kcpClient kcpclient.ClusterInterface,
+	rootClusterName string,
+	shardName string,
+	shardInformer tenancyinformer.WorkspaceShardInformer,
+	featureGate featuregate.MutableFeatureGate,
+) *Controller {
+	c := &Controller{
+		queue:           workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		kcpClient:       kcpClient,
+		rootClusterName: rootClusterName,
+		shardName:       shardName,
+		shardLister:     shardInformer.Lister(),
+		version:         version.Get().GitVersion,
+		featureGates:    EnabledFeatureGates(featureGate),
+		syncChecks: []cache.InformerSynced{
+			shardInformer.Informer().HasSynced,
+		},
+	}
+
+	shardInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: func(obj interface{}) bool {
+			shard, ok := obj.(*tenancyv1alpha1.WorkspaceShard)
+			return ok && shard.ClusterName == rootClusterName && shard.Name == shardName
+		},
+		Handler: cache.ResourceEventHandlerFuncs{
+			AddFunc:    func(obj interface{}) { c.enqueue(obj) },
+			UpdateFunc: func(_, obj interface{}) { c.enqueue(obj) },
+		},
+	})
+
+	return c
+}
+
+// EnabledFeatureGates returns the sorted names of the feature gates which are enabled and
+// not GA yet. GA features can't be disabled, so they don't tell shards apart.
+func EnabledFeatureGates(featureGate featuregate.MutableFeatureGate) []string {
+	var enabled []string
+	for feature, spec := range featureGate.GetAll() {
+		if spec.PreRelease != featuregate.GA && featureGate.Enabled(feature) {
+			enabled = append(enabled, string(feature))
+		}
+	}
+	sort.Strings(enabled)
+	return enabled
+}
+
+// Controller watches the WorkspaceShard of this shard in order to keep its version and
+// feature gates up to date.
+type Controller struct {
+	queue workqueue.RateLimitingInterface
+
+	kcpClient       kcpclient.ClusterInterface
+	rootClusterName string
+	shardName       string
+	shardLister     tenancylister.WorkspaceShardLister
+
+	version      string
+	featureGates []string
+
+	syncChecks []cache.InformerSynced
+}
+
+func (c *Controller) enqueue(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	klog.V(4).Infof("queueing workspace shard %q", key)
+	c.queue.Add(key)
+}
+
+func (c *Controller) Start(ctx context.Context, numThreads int) {
+	defer runtime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	klog.Infof("Starting shard version controller for shard %q", c.shardName)
+	defer klog.Info("Shutting down shard version controller")
+
+	if !cache.WaitForNamedCacheSync(controllerName, ctx.Done(), c.syncChecks...) {
+		klog.Warning("Failed to wait for caches to sync")
+		return
+	}
+
+	for i := 0; i < numThreads; i++ {
+		go wait.Until(func() { c.startWorker(ctx) }, time.Second, ctx.Done())
+	}
+
+	<-ctx.Done()
+}
+
+func (c *Controller) startWorker(ctx context.Context) {
+	for c.processNextWorkItem(ctx) {
+	}
+}
+
+func (c *Controller) processNextWorkItem(ctx context.Context) bool {
+	// Wait until there is a new item in the working queue
+	k, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	key := k.(string)
+
+	// No matter what, tell the queue we're done with this key, to unblock
+	// other workers.
+	defer c.queue.Done(key)
+
+	if err := c.process(ctx, key); err != nil {
+		runtime.HandleError(fmt.Errorf("%q controller failed to sync %q, err: %w", controllerName, key, err))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	c.queue.Forget(key)
+	return true
+}
+
+func (c *Controller) process(ctx context.Context, key string) error {
+	obj, err := c.shardLister.Get(key)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil // published once the WorkspaceShard gets created
+		}
+		return err
+	}
+	shard := obj.DeepCopy()
+	shard.Status.Version = c.version
+	shard.Status.FeatureGates = c.featureGates
+	if equality.Semantic.DeepEqual(obj.Status, shard.Status) {
+		return nil
+	}
+	klog.Infof("publishing kcp version %s of shard %q", c.version, c.shardName)
+	_, err = c.kcpClient.Cluster(c.rootClusterName).TenancyV1alpha1().WorkspaceShards().UpdateStatus(ctx, shard, metav1.UpdateOptions{})
+	return err
+}
@@ -20,10 +20,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"path"
 	"time"
 
 	jsonpatch "github.com/evanphx/json-patch"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -34,32 +37,51 @@ import (
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clusters"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 
 	"github.com/kcp-dev/kcp/pkg/apis/tenancy/helpers/conditions"
+	"github.com/kcp-dev/kcp/pkg/apis/tenancy/helpers/logicalcluster"
 	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
 	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	"github.com/kcp-dev/kcp/pkg/client/clusterkeys"
 	tenancyinformer "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/tenancy/v1alpha1"
 	tenancylister "github.com/kcp-dev/kcp/pkg/client/listers/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/reconciler/shardversion"
 )
 
 const (
 	currentShardIndex  = "shard"
+	targetShardIndex   = "targetShard"
 	unschedulableIndex = "unschedulable"
 	controllerName     = "workspace"
 )
 
+// NewController returns a controller which schedules Workspaces to WorkspaceShards. It
+// records Events about the lifecycle of every workspace in the logical cluster of the
+// workspace: its creation, phase changes, shard moves and deletion. The creations, moves
+// and deletions are also posted to the endpoint of the notifier, if it is enabled.
+//
+// It assigns every workspace the logical cluster holding its content. Renamed workspaces
+// take over the logical cluster of the workspace they are renamed from, which is then
+// deleted, and keep their previous name reachable for the rename grace period.
 func NewController(
 	kcpClient kcpclient.ClusterInterface,
 	workspaceInformer tenancyinformer.WorkspaceInformer,
 	workspaceShardInformer tenancyinformer.WorkspaceShardInformer,
+	recorder record.EventRecorder,
+	notifier *Notifier,
+	renameGracePeriod time.Duration,
 ) (*Controller, error) {
 	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
 
 	c := &Controller{
 		queue:                 queue,
 		kcpClient:             kcpClient,
+		recorder:              recorder,
+		notifier:              notifier,
+		renameGracePeriod:     renameGracePeriod,
 		workspaceIndexer:      workspaceInformer.Informer().GetIndexer(),
 		workspaceLister:       workspaceInformer.Lister(),
 		workspaceShardIndexer: workspaceShardInformer.Informer().GetIndexer(),
@@ -73,6 +95,7 @@ func NewController(
 	workspaceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc:    func(obj interface{}) { c.enqueue(obj) },
 		UpdateFunc: func(_, obj interface{}) { c.enqueue(obj) },
+		DeleteFunc: func(obj interface{}) { c.recordDeletion(obj) },
 	})
 	if err := c.workspaceIndexer.AddIndexers(map[string]cache.IndexFunc{
 		currentShardIndex: func(obj interface{}) ([]string, error) {
@@ -81,6 +104,12 @@ func NewController(
 			}
 			return []string{}, nil
 		},
+		targetShardIndex: func(obj interface{}) ([]string, error) {
+			if workspace, ok := obj.(*tenancyv1alpha1.Workspace); ok && workspace.Status.Location.Target != "" {
+				return []string{workspace.Status.Location.Target}, nil
+			}
+			return []string{}, nil
+		},
 		unschedulableIndex: func(obj interface{}) ([]string, error) {
 			if workspace, ok := obj.(*tenancyv1alpha1.Workspace); ok {
 				if conditions.IsWorkspaceUnschedulable(workspace) {
@@ -95,6 +124,7 @@ func NewController(
 
 	workspaceShardInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc:    func(obj interface{}) { c.enqueueAddedShard(obj) },
+		UpdateFunc: func(oldObj, obj interface{}) { c.enqueueUpdatedShard(oldObj, obj) },
 		DeleteFunc: func(obj interface{}) { c.enqueueDeletedShard(obj) },
 	})
 
@@ -107,9 +137,14 @@ type Controller struct {
 	queue workqueue.RateLimitingInterface
 
 	kcpClient        kcpclient.ClusterInterface
+	recorder         record.EventRecorder
+	notifier         *Notifier
 	workspaceIndexer cache.Indexer
 	workspaceLister  tenancylister.WorkspaceLister
 
+	// renameGracePeriod is how long the previous names of renamed workspaces are reachable
+	renameGracePeriod time.Duration
+
 	workspaceShardIndexer cache.Indexer
 	workspaceShardLister  tenancylister.WorkspaceShardLister
 
@@ -149,6 +184,47 @@ func (c *Controller) enqueueAddedShard(obj interface{}) {
 	}
 }
 
+func (c *Controller) enqueueUpdatedShard(oldObj, obj interface{}) {
+	oldShard, ok := oldObj.(*tenancyv1alpha1.WorkspaceShard)
+	if !ok {
+		runtime.HandleError(fmt.Errorf("got %T when handling updated WorkspaceShard", oldObj))
+		return
+	}
+	shard, ok := obj.(*tenancyv1alpha1.WorkspaceShard)
+	if !ok {
+		runtime.HandleError(fmt.Errorf("got %T when handling updated WorkspaceShard", obj))
+		return
+	}
+	versionChanged := oldShard.Status.Version != shard.Status.Version || !equality.Semantic.DeepEqual(oldShard.Status.FeatureGates, shard.Status.FeatureGates)
+	if oldShard.Spec.BaseURL == shard.Spec.BaseURL && !versionChanged {
+		return
+	}
+	klog.Infof("handling updated shard %q", shard.Name)
+	workspaces, err := c.workspaceIndexer.ByIndex(currentShardIndex, shard.Name)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	if versionChanged {
+		// the moves to or from the shard which were refused may be allowed now
+		moving, err := c.workspaceIndexer.ByIndex(targetShardIndex, shard.Name)
+		if err != nil {
+			runtime.HandleError(err)
+			return
+		}
+		workspaces = append(workspaces, moving...)
+	}
+	for _, workspace := range workspaces {
+		key, err := cache.MetaNamespaceKeyFunc(workspace)
+		if err != nil {
+			runtime.HandleError(err)
+			return
+		}
+		klog.Infof("queuing workspace %q on updated shard", key)
+		c.queue.Add(key)
+	}
+}
+
 func (c *Controller) enqueueDeletedShard(obj interface{}) {
 	shard, ok := obj.(*tenancyv1alpha1.WorkspaceShard)
 	if !ok {
@@ -192,6 +268,7 @@ func (c *Controller) Start(ctx context.Context, numThreads int) {
 		return
 	}
 
+	c.notifier.Start(ctx)
 	for i := 0; i < numThreads; i++ {
 		go wait.Until(func() { c.startWorker(ctx) }, time.Second, ctx.Done())
 	}
@@ -228,7 +305,7 @@ func (c *Controller) processNextWorkItem(ctx context.Context) bool {
 }
 
 func (c *Controller) process(ctx context.Context, key string) error {
-	namespace, clusterAwareName, err := cache.SplitMetaNamespaceKey(key)
+	clusterName, namespace, name, err := clusterkeys.SplitClusterKey(key)
 	if err != nil {
 		klog.Errorf("invalid key: %q: %v", key, err)
 		return nil
@@ -237,7 +314,6 @@ func (c *Controller) process(ctx context.Context, key string) error {
 		klog.Errorf("namespace %q found in key for cluster-wide Workspace object", namespace)
 		return nil
 	}
-	clusterName, name := clusters.SplitClusterAwareKey(clusterAwareName)
 
 	obj, err := c.workspaceLister.Get(key) // TODO: clients need a way to scope down the lister per-cluster
 	if err != nil {
@@ -252,6 +328,7 @@ func (c *Controller) process(ctx context.Context, key string) error {
 	if err := c.reconcile(ctx, obj); err != nil {
 		return err
 	}
+	c.recordEvents(previous, obj)
 
 	// If the object being reconciled changed as a result, update it.
 	if !equality.Semantic.DeepEqual(previous.Status, obj.Status) {
@@ -278,17 +355,24 @@ func (c *Controller) process(ctx context.Context, key string) error {
 			return fmt.Errorf("failed to create patch for workspace %q|%q/%q: %w", clusterName, namespace, name, err)
 		}
 		_, uerr := c.kcpClient.Cluster(clusterName).TenancyV1alpha1().Workspaces().Patch(ctx, obj.Name, types.MergePatchType, patchBytes, metav1.PatchOptions{}, "status")
-		return uerr
+		if uerr != nil {
+			return uerr
+		}
+	}
+	if expiry, found := nextExpiry(obj); found {
+		c.queue.AddAfter(key, time.Until(expiry))
 	}
 
-	return nil
+	return c.completeRename(ctx, obj)
 }
 
 func (c *Controller) reconcile(ctx context.Context, workspace *tenancyv1alpha1.Workspace) error {
-	if workspace.Status.BaseURL == "" {
-		// TODO: something sensible when we have some use for this field
-		workspace.Status.BaseURL = fmt.Sprintf("%s.workspaces.kcp.dev", workspace.Name)
+	if workspace.Status.Cluster == "" {
+		if err := c.assignCluster(workspace); err != nil {
+			return err
+		}
 	}
+	expirePreviousNames(workspace, time.Now())
 	if currentShard := workspace.Status.Location.Current; currentShard != "" {
 		// make sure current shard still exists
 		_, err := c.workspaceShardLister.Get(clusters.ToClusterAwareKey(workspace.ClusterName, currentShard))
@@ -312,10 +396,21 @@ func (c *Controller) reconcile(ctx context.Context, workspace *tenancyv1alpha1.W
 		}
 	}
 	if workspace.Status.Location.Target != "" && workspace.Status.Location.Current != workspace.Status.Location.Target {
-		klog.Infof("moving workspace %q from to %q", workspace.Name, workspace.Status.Location.Target)
-		workspace.Status.Location.Current = workspace.Status.Location.Target
-		workspace.Status.Location.Target = ""
+		allowed, err := c.allowMove(workspace)
+		if err != nil {
+			return err
+		}
+		if allowed {
+			klog.Infof("moving workspace %q from to %q", workspace.Name, workspace.Status.Location.Target)
+			workspace.Status.Location.Current = workspace.Status.Location.Target
+			workspace.Status.Location.Target = ""
+		}
+	}
+	baseURL, err := c.workspaceBaseURL(workspace)
+	if err != nil {
+		return err
 	}
+	workspace.Status.BaseURL = baseURL
 	now := time.Now()
 	if workspace.Status.Location.Current == "" {
 		workspace.Status.Phase = tenancyv1alpha1.WorkspacePhaseInitializing
@@ -332,6 +427,9 @@ func (c *Controller) reconcile(ctx context.Context, workspace *tenancyv1alpha1.W
 		}
 	} else {
 		workspace.Status.Phase = tenancyv1alpha1.WorkspacePhaseActive
+		if conditions.IsWorkspaceConditionTrue(workspace, tenancyv1alpha1.WorkspaceHibernated) {
+			workspace.Status.Phase = tenancyv1alpha1.WorkspacePhaseHibernated
+		}
 		if conditions.IsWorkspaceUnschedulable(workspace) {
 			klog.Infof("marking workspace %q scheduled", workspace.Name)
 			conditions.SetWorkspaceCondition(workspace, tenancyv1alpha1.WorkspaceCondition{
@@ -346,3 +444,233 @@ func (c *Controller) reconcile(ctx context.Context, workspace *tenancyv1alpha1.W
 	}
 	return nil
 }
+
+// assignCluster assigns the workspace its logical cluster: the one of the workspace it is
+// renamed from, a new one otherwise. Workspaces reconciled before logical clusters were
+// assigned keep the logical cluster named after them.
+func (c *Controller) assignCluster(workspace *tenancyv1alpha1.Workspace) error {
+	if from, renamed := workspace.Annotations[tenancyv1alpha1.WorkspaceRenamedFromAnnotation]; renamed {
+		old, err := c.workspaceLister.Get(clusters.ToClusterAwareKey(workspace.ClusterName, from))
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		if err == nil && old.Status.Cluster != "" {
+			if to, found := old.Annotations[tenancyv1alpha1.WorkspaceRenamedToAnnotation]; !found || to == workspace.Name {
+				klog.Infof("renaming workspace %q to %q", from, workspace.Name)
+				workspace.Status = *old.Status.DeepCopy()
+				previousNames := []tenancyv1alpha1.WorkspacePreviousName{}
+				for _, previous := range workspace.Status.PreviousNames {
+					if previous.Name != workspace.Name {
+						previousNames = append(previousNames, previous)
+					}
+				}
+				workspace.Status.PreviousNames = append(previousNames, tenancyv1alpha1.WorkspacePreviousName{
+					Name:  from,
+					Until: metav1.NewTime(time.Now().Add(c.renameGracePeriod)),
+				})
+				return nil
+			}
+		}
+		// the renamed workspace is gone, so is its content
+	}
+	if workspace.Status.Phase != "" {
+		workspace.Status.Cluster = workspace.Name
+	} else {
+		workspace.Status.Cluster = logicalcluster.Generate()
+	}
+	return nil
+}
+
+// expirePreviousNames drops the previous names of the workspace whose grace period ended.
+func expirePreviousNames(workspace *tenancyv1alpha1.Workspace, now time.Time) {
+	var previousNames []tenancyv1alpha1.WorkspacePreviousName
+	for _, previous := range workspace.Status.PreviousNames {
+		if now.Before(previous.Until.Time) {
+			previousNames = append(previousNames, previous)
+		}
+	}
+	workspace.Status.PreviousNames = previousNames
+}
+
+// nextExpiry returns when the grace period of the next previous name of the workspace ends.
+func nextExpiry(workspace *tenancyv1alpha1.Workspace) (time.Time, bool) {
+	var next time.Time
+	for _, previous := range workspace.Status.PreviousNames {
+		if next.IsZero() || previous.Until.Time.Before(next) {
+			next = previous.Until.Time
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// completeRename deletes the workspace the given one is renamed from, once the given one
+// holds its logical cluster. The deleted workspace is annotated with its new name first, so
+// that its logical cluster is not deleted along with it.
+func (c *Controller) completeRename(ctx context.Context, workspace *tenancyv1alpha1.Workspace) error {
+	from, renamed := workspace.Annotations[tenancyv1alpha1.WorkspaceRenamedFromAnnotation]
+	if !renamed || workspace.Status.Cluster == "" {
+		return nil
+	}
+	old, err := c.workspaceLister.Get(clusters.ToClusterAwareKey(workspace.ClusterName, from))
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if old.Status.Cluster != workspace.Status.Cluster || old.DeletionTimestamp != nil {
+		return nil
+	}
+	client := c.kcpClient.Cluster(workspace.ClusterName).TenancyV1alpha1().Workspaces()
+	if !logicalcluster.Renamed(old) {
+		patch, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"uid":             old.UID,
+				"resourceVersion": old.ResourceVersion,
+				"annotations":     map[string]string{tenancyv1alpha1.WorkspaceRenamedToAnnotation: workspace.Name},
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if old, err = client.Patch(ctx, old.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			return err
+		}
+	}
+	err = client.Delete(ctx, old.Name, metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: &old.UID}})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// allowMove returns whether the workspace can be moved from its current shard to its
+// target shard, and sets its WorkspaceShardCompatible condition accordingly. Moves of
+// workspaces which are not on a shard yet are always allowed.
+func (c *Controller) allowMove(workspace *tenancyv1alpha1.Workspace) (bool, error) {
+	if workspace.Status.Location.Current == "" {
+		return true, nil
+	}
+	from, err := c.workspaceShardLister.Get(clusters.ToClusterAwareKey(workspace.ClusterName, workspace.Status.Location.Current))
+	if errors.IsNotFound(err) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+	to, err := c.workspaceShardLister.Get(clusters.ToClusterAwareKey(workspace.ClusterName, workspace.Status.Location.Target))
+	if errors.IsNotFound(err) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	compatible, reason := shardversion.Compatible(from, to)
+	condition := conditions.FindWorkspaceCondition(workspace, tenancyv1alpha1.WorkspaceShardCompatible)
+	if !compatible {
+		message := fmt.Sprintf("Refusing to move the workspace: %s.", reason)
+		if condition == nil || condition.Status != metav1.ConditionFalse || condition.Message != message {
+			klog.Infof("refusing to move workspace %q to %q: %s", workspace.Name, to.Name, reason)
+			conditions.SetWorkspaceCondition(workspace, tenancyv1alpha1.WorkspaceCondition{
+				Type:          tenancyv1alpha1.WorkspaceShardCompatible,
+				Status:        metav1.ConditionFalse,
+				LastProbeTime: metav1.Now(),
+				Reason:        tenancyv1alpha1.WorkspaceReasonIncompatibleShard,
+				Message:       message,
+			})
+		}
+		return false, nil
+	}
+	if condition != nil && condition.Status != metav1.ConditionTrue {
+		conditions.SetWorkspaceCondition(workspace, tenancyv1alpha1.WorkspaceCondition{
+			Type:          tenancyv1alpha1.WorkspaceShardCompatible,
+			Status:        metav1.ConditionTrue,
+			LastProbeTime: metav1.Now(),
+			Reason:        "Compatible",
+			Message:       fmt.Sprintf("Shard %q is compatible with shard %q.", to.Name, from.Name),
+		})
+	}
+	return true, nil
+}
+
+// recordEvents records Events for the lifecycle changes between the previous and the
+// reconciled status of a workspace.
+func (c *Controller) recordEvents(previous, workspace *tenancyv1alpha1.Workspace) {
+	if from := workspace.Annotations[tenancyv1alpha1.WorkspaceRenamedFromAnnotation]; previous.Status.Cluster == "" && hasPreviousName(workspace, from) {
+		c.recorder.Eventf(workspace, corev1.EventTypeNormal, "Renamed", "Workspace renamed from %q.", from)
+		c.notifier.Notify(WorkspaceRenamed, workspace, "")
+		return
+	}
+	if previous.Status.Cluster == "" && workspace.Status.Cluster != "" {
+		// the logical cluster is assigned only once in the life of a workspace
+		c.recorder.Event(workspace, corev1.EventTypeNormal, "Created", "Workspace created.")
+		c.notifier.Notify(WorkspaceCreated, workspace, "")
+	} else if previous.Status.Phase != workspace.Status.Phase {
+		c.recorder.Eventf(workspace, corev1.EventTypeNormal, "PhaseChanged", "Workspace phase changed from %s to %s.", previous.Status.Phase, workspace.Status.Phase)
+	}
+
+	from, to := previous.Status.Location.Current, workspace.Status.Location.Current
+	switch {
+	case from == "" && to != "":
+		c.recorder.Eventf(workspace, corev1.EventTypeNormal, "Scheduled", "Workspace scheduled to shard %q.", to)
+	case from != "" && to == "":
+		c.recorder.Eventf(workspace, corev1.EventTypeWarning, "Descheduled", "Workspace descheduled from nonexistent shard %q.", from)
+	case from != to:
+		c.recorder.Eventf(workspace, corev1.EventTypeNormal, "Moved", "Workspace moved from shard %q to %q.", from, to)
+		c.notifier.Notify(WorkspaceMoved, workspace, from)
+	}
+
+	if !conditions.IsWorkspaceConditionFalse(previous, tenancyv1alpha1.WorkspaceShardCompatible) && conditions.IsWorkspaceConditionFalse(workspace, tenancyv1alpha1.WorkspaceShardCompatible) {
+		c.recorder.Event(workspace, corev1.EventTypeWarning, tenancyv1alpha1.WorkspaceReasonIncompatibleShard, conditions.FindWorkspaceCondition(workspace, tenancyv1alpha1.WorkspaceShardCompatible).Message)
+	}
+	if !conditions.IsWorkspaceUnschedulable(previous) && conditions.IsWorkspaceUnschedulable(workspace) {
+		c.recorder.Event(workspace, corev1.EventTypeWarning, tenancyv1alpha1.WorkspaceReasonUnschedulable, conditions.FindWorkspaceCondition(workspace, tenancyv1alpha1.WorkspaceScheduled).Message)
+	}
+}
+
+func hasPreviousName(workspace *tenancyv1alpha1.Workspace, name string) bool {
+	for _, previous := range workspace.Status.PreviousNames {
+		if previous.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Controller) recordDeletion(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	workspace, ok := obj.(*tenancyv1alpha1.Workspace)
+	if !ok {
+		klog.V(2).Infof("Deleted object is not a Workspace: %#v", obj)
+		return
+	}
+	if logicalcluster.Renamed(workspace) {
+		// the workspace lives on under its new name
+		return
+	}
+	c.recorder.Event(workspace, corev1.EventTypeNormal, "Deleted", "Workspace deleted.")
+	c.notifier.Notify(WorkspaceDeleted, workspace, "")
+}
+
+// workspaceBaseURL returns the URL under which the logical cluster of the workspace
+// is served by its current shard, or an empty string when the workspace is not
+// scheduled or the shard does not advertise an address. It addresses the logical
+// cluster rather than the name of the workspace, which is only unique in its parent.
+func (c *Controller) workspaceBaseURL(workspace *tenancyv1alpha1.Workspace) (string, error) {
+	if workspace.Status.Location.Current == "" {
+		return "", nil
+	}
+	shard, err := c.workspaceShardLister.Get(clusters.ToClusterAwareKey(workspace.ClusterName, workspace.Status.Location.Current))
+	if err != nil {
+		return "", err
+	}
+	if shard.Spec.BaseURL == "" {
+		return "", nil
+	}
+	u, err := url.Parse(shard.Spec.BaseURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid base URL %q for shard %q: %w", shard.Spec.BaseURL, shard.Name, err)
+	}
+	u.Path = path.Join(u.Path, "clusters", workspace.Status.Cluster)
+	return u.String(), nil
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workspace
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	tenancylister "github.com/kcp-dev/kcp/pkg/client/listers/tenancy/v1alpha1"
+)
+
+func TestAssignCluster(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	old := &tenancyv1alpha1.Workspace{
+		ObjectMeta: metav1.ObjectMeta{Name: "team", ClusterName: "org"},
+		Status: tenancyv1alpha1.WorkspaceStatus{
+			Cluster: "k7c2q9x4",
+			Phase:   tenancyv1alpha1.WorkspacePhaseActive,
+			PreviousNames: []tenancyv1alpha1.WorkspacePreviousName{
+				{Name: "platform", Until: metav1.NewTime(time.Now().Add(time.Hour))},
+				{Name: "infra", Until: metav1.NewTime(time.Now().Add(time.Hour))},
+			},
+		},
+	}
+	if err := indexer.Add(old); err != nil {
+		t.Fatal(err)
+	}
+	c := &Controller{workspaceLister: tenancylister.NewWorkspaceLister(indexer), renameGracePeriod: time.Hour}
+
+	renamed := func(from string) *tenancyv1alpha1.Workspace {
+		return &tenancyv1alpha1.Workspace{ObjectMeta: metav1.ObjectMeta{
+			Name: "platform", ClusterName: "org",
+			Annotations: map[string]string{tenancyv1alpha1.WorkspaceRenamedFromAnnotation: from},
+		}}
+	}
+
+	workspace := renamed("team")
+	if err := c.assignCluster(workspace); err != nil {
+		t.Fatal(err)
+	}
+	if workspace.Status.Cluster != "k7c2q9x4" {
+		t.Errorf("expected the logical cluster of the renamed workspace, got %q", workspace.Status.Cluster)
+	}
+	var names []string
+	for _, previous := range workspace.Status.PreviousNames {
+		names = append(names, previous.Name)
+	}
+	if len(names) != 2 || names[0] != "infra" || names[1] != "team" {
+		t.Errorf("expected the previous names infra and team, got %v", names)
+	}
+
+	workspace = renamed("gone")
+	if err := c.assignCluster(workspace); err != nil {
+		t.Fatal(err)
+	}
+	if workspace.Status.Cluster == "" || workspace.Status.Cluster == "k7c2q9x4" || workspace.Status.Cluster == "platform" {
+		t.Errorf("expected a new logical cluster, got %q", workspace.Status.Cluster)
+	}
+
+	workspace = &tenancyv1alpha1.Workspace{
+		ObjectMeta: metav1.ObjectMeta{Name: "legacy", ClusterName: "org"},
+		Status:     tenancyv1alpha1.WorkspaceStatus{Phase: tenancyv1alpha1.WorkspacePhaseActive},
+	}
+	if err := c.assignCluster(workspace); err != nil {
+		t.Fatal(err)
+	}
+	if workspace.Status.Cluster != "legacy" {
+		t.Errorf("expected workspaces reconciled before to keep the logical cluster named after them, got %q", workspace.Status.Cluster)
+	}
+}
+
+func TestExpirePreviousNames(t *testing.T) {
+	now := time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC)
+	workspace := &tenancyv1alpha1.Workspace{Status: tenancyv1alpha1.WorkspaceStatus{
+		PreviousNames: []tenancyv1alpha1.WorkspacePreviousName{
+			{Name: "expired", Until: metav1.NewTime(now.Add(-time.Minute))},
+			{Name: "later", Until: metav1.NewTime(now.Add(2 * time.Hour))},
+			{Name: "sooner", Until: metav1.NewTime(now.Add(time.Hour))},
+		},
+	}}
+	expirePreviousNames(workspace, now)
+	if len(workspace.Status.PreviousNames) != 2 {
+		t.Fatalf("expected the expired name to be dropped, got %v", workspace.Status.PreviousNames)
+	}
+	if next, found := nextExpiry(workspace); !found || !next.Equal(now.Add(time.Hour)) {
+		t.Errorf("expected the next expiry in an hour, got %v", next)
+	}
+}
+
+func TestWorkspaceBaseURL(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	if err := indexer.Add(&tenancyv1alpha1.WorkspaceShard{
+		ObjectMeta: metav1.ObjectMeta{Name: "boston", ClusterName: "org"},
+		Spec:       tenancyv1alpha1.WorkspaceShardSpec{BaseURL: "https://boston.kcp.dev/prefix"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	c := &Controller{workspaceShardLister: tenancylister.NewWorkspaceShardLister(indexer)}
+
+	workspace := &tenancyv1alpha1.Workspace{
+		ObjectMeta: metav1.ObjectMeta{Name: "team", ClusterName: "org"},
+		Status: tenancyv1alpha1.WorkspaceStatus{
+			Cluster:  "k7c2q9x4",
+			Location: tenancyv1alpha1.WorkspaceLocation{Current: "boston"},
+		},
+	}
+	baseURL, err := c.workspaceBaseURL(workspace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "https://boston.kcp.dev/prefix/clusters/k7c2q9x4"; baseURL != expected {
+		t.Errorf("expected the base URL of the logical cluster %q, got %q", expected, baseURL)
+	}
+
+	workspace.Status.Location.Current = ""
+	if baseURL, err := c.workspaceBaseURL(workspace); err != nil || baseURL != "" {
+		t.Errorf("expected no base URL for an unscheduled workspace, got %q, %v", baseURL, err)
+	}
+}
@@ -0,0 +1,241 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workspace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+)
+
+// NotificationFormat is the format of the notifications posted to the endpoint.
+type NotificationFormat string
+
+const (
+	// FormatJSON posts the Notification itself, as application/json.
+	FormatJSON NotificationFormat = "JSON"
+	// FormatCloudEvents posts the Notification as the data of a CloudEvent in the
+	// structured content mode, as application/cloudevents+json.
+	FormatCloudEvents NotificationFormat = "CloudEvents"
+)
+
+// ParseNotificationFormat returns the NotificationFormat of the given name.
+func ParseNotificationFormat(s string) (NotificationFormat, error) {
+	switch format := NotificationFormat(s); format {
+	case FormatJSON, FormatCloudEvents:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown notification format %q, must be %s or %s", s, FormatJSON, FormatCloudEvents)
+	}
+}
+
+// NotificationType is the type of a workspace lifecycle notification, also used as the type
+// of its CloudEvent.
+type NotificationType string
+
+const (
+	WorkspaceCreated NotificationType = "dev.kcp.workspace.created"
+	WorkspaceDeleted NotificationType = "dev.kcp.workspace.deleted"
+	WorkspaceMoved   NotificationType = "dev.kcp.workspace.moved"
+	WorkspaceRenamed NotificationType = "dev.kcp.workspace.renamed"
+)
+
+// Notification describes a change in the lifecycle of a workspace.
+type Notification struct {
+	ID   string           `json:"id"`
+	Type NotificationType `json:"type"`
+	// Time is the RFC 3339 time at which the change was observed.
+	Time string `json:"time"`
+	// Cluster is the logical cluster the Workspace object lives in.
+	Cluster       string `json:"cluster"`
+	Workspace     string `json:"workspace"`
+	UID           string `json:"uid"`
+	WorkspaceType string `json:"workspaceType,omitempty"`
+	// Shard is the current shard of the workspace.
+	Shard string `json:"shard,omitempty"`
+	// PreviousShard is the shard a moved workspace was moved from.
+	PreviousShard string `json:"previousShard,omitempty"`
+	// PreviousName is the name a renamed workspace was renamed from.
+	PreviousName string `json:"previousName,omitempty"`
+}
+
+// cloudEvent is a CloudEvent 1.0 in the structured content mode.
+type cloudEvent struct {
+	SpecVersion     string       `json:"specversion"`
+	ID              string       `json:"id"`
+	Source          string       `json:"source"`
+	Type            string       `json:"type"`
+	Subject         string       `json:"subject"`
+	Time            string       `json:"time"`
+	DataContentType string       `json:"datacontenttype"`
+	Data            Notification `json:"data"`
+}
+
+// notificationRetries is the number of times a notification is retried before it is dropped.
+const notificationRetries = 5
+
+// Notifier posts the lifecycle notifications of workspaces to an external endpoint, so that
+// they can trigger the provisioning of external resources. Notifications are posted in the
+// background, and retried with a backoff while the endpoint fails.
+type Notifier struct {
+	client *http.Client
+	url    string
+	format NotificationFormat
+	queue  workqueue.RateLimitingInterface
+}
+
+// NewNotifier returns a Notifier for the endpoint configured as the server of the current
+// context of the given kubeconfig file, along with its credentials. Without a file, the
+// returned Notifier is disabled.
+func NewNotifier(kubeconfigFile string, format NotificationFormat, timeout time.Duration) (*Notifier, error) {
+	if kubeconfigFile == "" {
+		return &Notifier{}, nil
+	}
+	config, err := clientcmd.BuildConfigFromFlags("", kubeconfigFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the workspace notifications kubeconfig: %w", err)
+	}
+	transport, err := rest.TransportFor(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Notifier{
+		client: &http.Client{Transport: transport, Timeout: timeout},
+		url:    config.Host,
+		format: format,
+		queue:  workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "workspace-notifications"),
+	}, nil
+}
+
+// Enabled returns whether the Notifier has an endpoint to post notifications to.
+func (n *Notifier) Enabled() bool {
+	return n != nil && n.client != nil
+}
+
+// Notify queues a notification of the given type about the workspace.
+func (n *Notifier) Notify(notificationType NotificationType, workspace *tenancyv1alpha1.Workspace, previousShard string) {
+	if !n.Enabled() {
+		return
+	}
+	n.queue.Add(Notification{
+		ID:            string(uuid.NewUUID()),
+		Type:          notificationType,
+		Time:          time.Now().UTC().Format(time.RFC3339),
+		Cluster:       workspace.ClusterName,
+		Workspace:     workspace.Name,
+		UID:           string(workspace.UID),
+		WorkspaceType: workspace.Spec.Type,
+		Shard:         workspace.Status.Location.Current,
+		PreviousShard: previousShard,
+		PreviousName:  previousName(notificationType, workspace),
+	})
+}
+
+func previousName(notificationType NotificationType, workspace *tenancyv1alpha1.Workspace) string {
+	if notificationType != WorkspaceRenamed {
+		return ""
+	}
+	return workspace.Annotations[tenancyv1alpha1.WorkspaceRenamedFromAnnotation]
+}
+
+// Start posts the queued notifications until the context is done.
+func (n *Notifier) Start(ctx context.Context) {
+	if !n.Enabled() {
+		return
+	}
+	go func() {
+		<-ctx.Done()
+		n.queue.ShutDown()
+	}()
+	go wait.Until(func() {
+		for n.processNextNotification(ctx) {
+		}
+	}, time.Second, ctx.Done())
+}
+
+func (n *Notifier) processNextNotification(ctx context.Context) bool {
+	item, quit := n.queue.Get()
+	if quit {
+		return false
+	}
+	defer n.queue.Done(item)
+
+	notification := item.(Notification)
+	err := n.send(ctx, notification)
+	if err == nil {
+		n.queue.Forget(item)
+		return true
+	}
+	if n.queue.NumRequeues(item) < notificationRetries {
+		klog.Errorf("Failed to post notification %s about workspace %s|%s, retrying: %v", notification.Type, notification.Cluster, notification.Workspace, err)
+		n.queue.AddRateLimited(item)
+		return true
+	}
+	n.queue.Forget(item)
+	runtime.HandleError(fmt.Errorf("dropping notification %s about workspace %s|%s after failed retries: %w", notification.Type, notification.Cluster, notification.Workspace, err))
+	return true
+}
+
+// send posts the notification in the format of the Notifier.
+func (n *Notifier) send(ctx context.Context, notification Notification) error {
+	var body interface{} = notification
+	contentType := "application/json"
+	if n.format == FormatCloudEvents {
+		body = cloudEvent{
+			SpecVersion:     "1.0",
+			ID:              notification.ID,
+			Source:          "/clusters/" + notification.Cluster,
+			Type:            string(notification.Type),
+			Subject:         notification.Workspace,
+			Time:            notification.Time,
+			DataContentType: "application/json",
+			Data:            notification,
+		}
+		contentType = "application/cloudevents+json"
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("notification endpoint answered with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workspace
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNotifierSend(t *testing.T) {
+	notification := Notification{
+		ID:            "42",
+		Type:          WorkspaceMoved,
+		Time:          "2021-10-01T00:00:00Z",
+		Cluster:       "admin",
+		Workspace:     "team",
+		UID:           "team-uid",
+		Shard:         "shard-2",
+		PreviousShard: "shard-1",
+	}
+	data := map[string]interface{}{
+		"id":            "42",
+		"type":          "dev.kcp.workspace.moved",
+		"time":          "2021-10-01T00:00:00Z",
+		"cluster":       "admin",
+		"workspace":     "team",
+		"uid":           "team-uid",
+		"shard":         "shard-2",
+		"previousShard": "shard-1",
+	}
+
+	tests := []struct {
+		format      NotificationFormat
+		contentType string
+		want        map[string]interface{}
+	}{
+		{format: FormatJSON, contentType: "application/json", want: data},
+		{format: FormatCloudEvents, contentType: "application/cloudevents+json", want: map[string]interface{}{
+			"specversion":     "1.0",
+			"id":              "42",
+			"source":          "/clusters/admin",
+			"type":            "dev.kcp.workspace.moved",
+			"subject":         "team",
+			"time":            "2021-10-01T00:00:00Z",
+			"datacontenttype": "application/json",
+			"data":            data,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var contentType string
+			var got map[string]interface{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				contentType = req.Header.Get("Content-Type")
+				if err := json.NewDecoder(req.Body).Decode(&got); err != nil {
+					t.Error(err)
+				}
+				w.WriteHeader(http.StatusAccepted)
+			}))
+			defer server.Close()
+
+			n := &Notifier{client: server.Client(), url: server.URL, format: tt.format}
+			if err := n.send(context.Background(), notification); err != nil {
+				t.Fatal(err)
+			}
+			if contentType != tt.contentType {
+				t.Errorf("expected content type %q, got %q", tt.contentType, contentType)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("unexpected body (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package workspacelimits records the limits of the size of the objects stored in the
+// workspaces, according to the limits of their WorkspaceType.
+package workspacelimits
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clusters"
+
+	"github.com/kcp-dev/kcp/pkg/apis/tenancy/helpers/logicalcluster"
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	tenancyinformer "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/tenancy/v1alpha1"
+	tenancylister "github.com/kcp-dev/kcp/pkg/client/listers/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/indexers"
+)
+
+// NewController returns a controller recording the limits of the logical cluster of every
+// Workspace in the registry, as defined by the WorkspaceType of the workspace.
+func NewController(
+	workspaceInformer tenancyinformer.WorkspaceInformer,
+	workspaceTypeInformer tenancyinformer.WorkspaceTypeInformer,
+	registry *Registry,
+) (*Controller, error) {
+	c := &Controller{
+		workspaceIndexer:    workspaceInformer.Informer().GetIndexer(),
+		workspaceTypeLister: workspaceTypeInformer.Lister(),
+		registry:            registry,
+	}
+
+	workspaceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { c.updateRegistry(obj, false) },
+		UpdateFunc: func(_, obj interface{}) { c.updateRegistry(obj, false) },
+		DeleteFunc: func(obj interface{}) { c.updateRegistry(obj, true) },
+	})
+	if err := indexers.AddIfNotPresent(c.workspaceIndexer, cache.Indexers{
+		indexers.WorkspaceType: indexers.IndexWorkspaceByType,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to add indexer for Workspace: %w", err)
+	}
+
+	workspaceTypeInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.updateWorkspacesOfType,
+		UpdateFunc: func(_, obj interface{}) { c.updateWorkspacesOfType(obj) },
+		DeleteFunc: c.updateWorkspacesOfType,
+	})
+
+	return c, nil
+}
+
+// Controller watches Workspaces and WorkspaceTypes in order to record the limits of the
+// logical cluster of every workspace.
+type Controller struct {
+	workspaceIndexer    cache.Indexer
+	workspaceTypeLister tenancylister.WorkspaceTypeLister
+
+	registry *Registry
+}
+
+// updateRegistry records the limits of the logical cluster of a workspace.
+func (c *Controller) updateRegistry(obj interface{}, deleted bool) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	workspace, ok := obj.(*tenancyv1alpha1.Workspace)
+	if !ok {
+		return
+	}
+	if deleted && logicalcluster.Renamed(workspace) {
+		// the logical cluster lives on in the renamed workspace
+		return
+	}
+	if deleted || workspace.Spec.Type == "" {
+		c.registry.set(logicalcluster.Of(workspace), nil)
+		return
+	}
+	workspaceType, err := c.workspaceTypeLister.Get(clusters.ToClusterAwareKey(workspace.ClusterName, workspace.Spec.Type))
+	if errors.IsNotFound(err) {
+		c.registry.set(logicalcluster.Of(workspace), nil)
+		return
+	} else if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	c.registry.set(logicalcluster.Of(workspace), workspaceType.Spec.Limits)
+}
+
+func (c *Controller) updateWorkspacesOfType(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	workspaces, err := c.workspaceIndexer.ByIndex(indexers.WorkspaceType, key)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	for _, workspace := range workspaces {
+		c.updateRegistry(workspace, false)
+	}
+}
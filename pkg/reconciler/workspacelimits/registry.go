@@ -0,0 +1,85 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workspacelimits
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+)
+
+// Limits are the maximum sizes, in bytes, of the objects of a logical cluster. Zero means
+// unlimited.
+type Limits struct {
+	MaxObjectSize        int64
+	MaxManagedFieldsSize int64
+	MaxAnnotationsSize   int64
+}
+
+// override returns the limits with those set by the WorkspaceLimits.
+func (l Limits) override(limits *tenancyv1alpha1.WorkspaceLimits) Limits {
+	if limits == nil {
+		return l
+	}
+	set := func(limit *int64, quantity *resource.Quantity) {
+		if quantity != nil {
+			*limit = quantity.Value()
+		}
+	}
+	set(&l.MaxObjectSize, limits.MaxObjectSize)
+	set(&l.MaxManagedFieldsSize, limits.MaxManagedFieldsSize)
+	set(&l.MaxAnnotationsSize, limits.MaxAnnotationsSize)
+	return l
+}
+
+// Registry holds the limits of the logical clusters of the workspaces.
+type Registry struct {
+	defaults Limits
+
+	lock   sync.RWMutex
+	limits map[string]Limits
+}
+
+// NewRegistry returns a Registry applying the default limits to every logical cluster.
+func NewRegistry(defaults Limits) *Registry {
+	return &Registry{
+		defaults: defaults,
+		limits:   map[string]Limits{},
+	}
+}
+
+// Limits returns the limits of the given logical cluster.
+func (r *Registry) Limits(clusterName string) Limits {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	if limits, found := r.limits[clusterName]; found {
+		return limits
+	}
+	return r.defaults
+}
+
+func (r *Registry) set(clusterName string, limits *tenancyv1alpha1.WorkspaceLimits) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if limits == nil {
+		delete(r.limits, clusterName)
+	} else {
+		r.limits[clusterName] = r.defaults.override(limits)
+	}
+}
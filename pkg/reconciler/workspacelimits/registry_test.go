@@ -0,0 +1,44 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workspacelimits
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+)
+
+func TestRegistry(t *testing.T) {
+	defaults := Limits{MaxObjectSize: 1024, MaxManagedFieldsSize: 512, MaxAnnotationsSize: 256}
+	registry := NewRegistry(defaults)
+	maxObjectSize := resource.MustParse("4Ki")
+	registry.set("tenant", &tenancyv1alpha1.WorkspaceLimits{MaxObjectSize: &maxObjectSize})
+
+	if got, want := registry.Limits("tenant"), (Limits{MaxObjectSize: 4096, MaxManagedFieldsSize: 512, MaxAnnotationsSize: 256}); got != want {
+		t.Errorf("expected limits %+v for tenant, got %+v", want, got)
+	}
+	if got := registry.Limits("other"); got != defaults {
+		t.Errorf("expected default limits %+v for other, got %+v", defaults, got)
+	}
+
+	registry.set("tenant", nil)
+	if got := registry.Limits("tenant"); got != defaults {
+		t.Errorf("expected default limits %+v once tenant is reset, got %+v", defaults, got)
+	}
+}
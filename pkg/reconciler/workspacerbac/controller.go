@@ -0,0 +1,392 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workspacerbac
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clusters"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	"github.com/kcp-dev/kcp/pkg/admission/workspaceowner"
+	"github.com/kcp-dev/kcp/pkg/apis/tenancy/helpers/conditions"
+	"github.com/kcp-dev/kcp/pkg/apis/tenancy/helpers/logicalcluster"
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	tenancyinformer "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/tenancy/v1alpha1"
+	tenancylister "github.com/kcp-dev/kcp/pkg/client/listers/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/indexers"
+)
+
+const (
+	controllerName = "workspacerbac"
+
+	// OwnerBindingPrefix prefixes the names of the ClusterRoleBindings granting the owner
+	// of a workspace its roles. It is followed by the name of the bound ClusterRole.
+	OwnerBindingPrefix = "workspace-owner:"
+
+	// WorkspaceFinalizer holds the deletion of a bootstrapped Workspace until its owner
+	// bindings and roles are removed from its logical cluster.
+	WorkspaceFinalizer = "tenancy.kcp.dev/rbac"
+)
+
+// DefaultWorkspaceType is used for workspaces which do not reference a WorkspaceType.
+var DefaultWorkspaceType = tenancyv1alpha1.WorkspaceTypeSpec{
+	ClusterRoles: []tenancyv1alpha1.WorkspaceClusterRole{
+		{
+			Name: "admin",
+			Rules: []rbacv1.PolicyRule{
+				{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"*"}},
+			},
+		},
+		{
+			Name: "edit",
+			Rules: []rbacv1.PolicyRule{
+				{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"get", "list", "watch", "create", "update", "patch", "delete", "deletecollection"}},
+			},
+		},
+		{
+			Name: "view",
+			Rules: []rbacv1.PolicyRule{
+				{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"get", "list", "watch"}},
+			},
+		},
+	},
+	OwnerClusterRoles: []string{"admin"},
+}
+
+// NewController returns a controller which creates the ClusterRoles of the WorkspaceType of
+// every new Workspace in the logical cluster of the workspace, and binds the owner of the
+// workspace to the owner roles of the type.
+//
+// The RBAC objects are only created once, when the workspace is initialized. They belong to
+// the workspace afterwards and may be changed or removed by its administrators. They are
+// removed with the workspace, unless its logical cluster moved to a renamed workspace.
+func NewController(
+	kcpClient kcpclient.ClusterInterface,
+	kubeClient kubernetes.ClusterInterface,
+	workspaceInformer tenancyinformer.WorkspaceInformer,
+	workspaceTypeInformer tenancyinformer.WorkspaceTypeInformer,
+) (*Controller, error) {
+	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+
+	c := &Controller{
+		queue:               queue,
+		kcpClient:           kcpClient,
+		kubeClient:          kubeClient,
+		workspaceIndexer:    workspaceInformer.Informer().GetIndexer(),
+		workspaceLister:     workspaceInformer.Lister(),
+		workspaceTypeLister: workspaceTypeInformer.Lister(),
+		syncChecks: []cache.InformerSynced{
+			workspaceInformer.Informer().HasSynced,
+			workspaceTypeInformer.Informer().HasSynced,
+		},
+	}
+
+	workspaceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { c.enqueue(obj) },
+		UpdateFunc: func(_, obj interface{}) { c.enqueue(obj) },
+	})
+	if err := indexers.AddIfNotPresent(c.workspaceIndexer, cache.Indexers{
+		indexers.WorkspaceType:    indexers.IndexWorkspaceByType,
+		indexers.WorkspaceCluster: indexers.IndexWorkspaceByCluster,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to add indexer for Workspace: %w", err)
+	}
+
+	workspaceTypeInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { c.enqueueWorkspaceType(obj) },
+		UpdateFunc: func(_, obj interface{}) { c.enqueueWorkspaceType(obj) },
+	})
+
+	return c, nil
+}
+
+// Controller watches Workspaces and WorkspaceTypes in order to bootstrap the RBAC objects of
+// every new Workspace.
+type Controller struct {
+	queue workqueue.RateLimitingInterface
+
+	kcpClient  kcpclient.ClusterInterface
+	kubeClient kubernetes.ClusterInterface
+
+	workspaceIndexer    cache.Indexer
+	workspaceLister     tenancylister.WorkspaceLister
+	workspaceTypeLister tenancylister.WorkspaceTypeLister
+
+	syncChecks []cache.InformerSynced
+}
+
+func (c *Controller) enqueue(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	klog.V(4).Infof("queueing workspace %q", key)
+	c.queue.Add(key)
+}
+
+// enqueueWorkspaceType queues the workspaces waiting for a WorkspaceType to show up.
+func (c *Controller) enqueueWorkspaceType(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	workspaces, err := c.workspaceIndexer.ByIndex(indexers.WorkspaceType, key)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	for _, workspace := range workspaces {
+		if !conditions.IsWorkspaceConditionTrue(workspace.(*tenancyv1alpha1.Workspace), tenancyv1alpha1.WorkspaceRBACBootstrapped) {
+			c.enqueue(workspace)
+		}
+	}
+}
+
+func (c *Controller) Start(ctx context.Context, numThreads int) {
+	defer runtime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	klog.Info("Starting workspace RBAC controller")
+	defer klog.Info("Shutting down workspace RBAC controller")
+
+	if !cache.WaitForNamedCacheSync(controllerName, ctx.Done(), c.syncChecks...) {
+		klog.Warning("Failed to wait for caches to sync")
+		return
+	}
+
+	for i := 0; i < numThreads; i++ {
+		go wait.Until(func() { c.startWorker(ctx) }, time.Second, ctx.Done())
+	}
+
+	<-ctx.Done()
+}
+
+func (c *Controller) startWorker(ctx context.Context) {
+	for c.processNextWorkItem(ctx) {
+	}
+}
+
+func (c *Controller) processNextWorkItem(ctx context.Context) bool {
+	// Wait until there is a new item in the working queue
+	k, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	key := k.(string)
+
+	// No matter what, tell the queue we're done with this key, to unblock
+	// other workers.
+	defer c.queue.Done(key)
+
+	if err := c.process(ctx, key); err != nil {
+		runtime.HandleError(fmt.Errorf("%q controller failed to sync %q, err: %w", controllerName, key, err))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	c.queue.Forget(key)
+	return true
+}
+
+func (c *Controller) process(ctx context.Context, key string) error {
+	obj, err := c.workspaceLister.Get(key)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil // object deleted before we handled it
+		}
+		return err
+	}
+	if obj.DeletionTimestamp != nil {
+		return c.cleanup(ctx, obj)
+	}
+	if obj.Status.Cluster == "" {
+		return nil // the workspace controller hasn't assigned its logical cluster yet
+	}
+	if !hasFinalizer(obj) {
+		workspace := obj.DeepCopy()
+		workspace.Finalizers = append(workspace.Finalizers, WorkspaceFinalizer)
+		if obj, err = c.kcpClient.Cluster(workspace.ClusterName).TenancyV1alpha1().Workspaces().Update(ctx, workspace, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+	}
+	if conditions.IsWorkspaceConditionTrue(obj, tenancyv1alpha1.WorkspaceRBACBootstrapped) {
+		return nil
+	}
+	workspace := obj.DeepCopy()
+	if err := c.reconcile(ctx, workspace); err != nil {
+		return err
+	}
+	if equality.Semantic.DeepEqual(obj.Status, workspace.Status) {
+		return nil
+	}
+	_, err = c.kcpClient.Cluster(workspace.ClusterName).TenancyV1alpha1().Workspaces().UpdateStatus(ctx, workspace, metav1.UpdateOptions{})
+	return err
+}
+
+func (c *Controller) reconcile(ctx context.Context, workspace *tenancyv1alpha1.Workspace) error {
+	spec, err := c.typeSpec(workspace)
+	if errors.IsNotFound(err) {
+		// wait for the type to be created
+		conditions.SetWorkspaceCondition(workspace, tenancyv1alpha1.WorkspaceCondition{
+			Type:    tenancyv1alpha1.WorkspaceRBACBootstrapped,
+			Status:  metav1.ConditionFalse,
+			Reason:  tenancyv1alpha1.WorkspaceReasonTypeNotFound,
+			Message: fmt.Sprintf("WorkspaceType %q does not exist.", workspace.Spec.Type),
+		})
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	rbacClient := c.kubeClient.Cluster(logicalcluster.Of(workspace)).RbacV1()
+	for _, role := range spec.ClusterRoles {
+		clusterRole := &rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{Name: role.Name},
+			Rules:      role.Rules,
+		}
+		if _, err := rbacClient.ClusterRoles().Create(ctx, clusterRole, metav1.CreateOptions{}); err != nil && !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create ClusterRole %q in workspace %q: %w", role.Name, workspace.Name, err)
+		}
+	}
+	if owner := workspace.Annotations[workspaceowner.OwnerAnnotation]; owner != "" {
+		for _, role := range spec.OwnerClusterRoles {
+			binding := &rbacv1.ClusterRoleBinding{
+				ObjectMeta: metav1.ObjectMeta{Name: OwnerBindingPrefix + role},
+				Subjects: []rbacv1.Subject{
+					{Kind: rbacv1.UserKind, APIGroup: rbacv1.GroupName, Name: owner},
+				},
+				RoleRef: rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: role},
+			}
+			if _, err := rbacClient.ClusterRoleBindings().Create(ctx, binding, metav1.CreateOptions{}); err != nil && !errors.IsAlreadyExists(err) {
+				return fmt.Errorf("failed to create ClusterRoleBinding %q in workspace %q: %w", binding.Name, workspace.Name, err)
+			}
+		}
+	}
+
+	klog.Infof("bootstrapped RBAC of workspace %q", workspace.Name)
+	conditions.SetWorkspaceCondition(workspace, tenancyv1alpha1.WorkspaceCondition{
+		Type:    tenancyv1alpha1.WorkspaceRBACBootstrapped,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Bootstrapped",
+		Message: "Default RBAC objects created.",
+	})
+	return nil
+}
+
+// typeSpec returns the spec of the WorkspaceType of the workspace.
+func (c *Controller) typeSpec(workspace *tenancyv1alpha1.Workspace) (tenancyv1alpha1.WorkspaceTypeSpec, error) {
+	if workspace.Spec.Type == "" {
+		return DefaultWorkspaceType, nil
+	}
+	workspaceType, err := c.workspaceTypeLister.Get(clusters.ToClusterAwareKey(workspace.ClusterName, workspace.Spec.Type))
+	if err != nil {
+		return tenancyv1alpha1.WorkspaceTypeSpec{}, err
+	}
+	return workspaceType.Spec, nil
+}
+
+// cleanup removes the owner bindings and the roles of the type of a deleted workspace from
+// its logical cluster, unless the logical cluster moved to a renamed workspace, then
+// releases the workspace.
+func (c *Controller) cleanup(ctx context.Context, workspace *tenancyv1alpha1.Workspace) error {
+	if !hasFinalizer(workspace) {
+		return nil
+	}
+	moved, err := c.clusterMoved(workspace)
+	if err != nil {
+		return err
+	}
+	if !moved {
+		rbacClient := c.kubeClient.Cluster(workspace.Status.Cluster).RbacV1()
+		bindings, err := rbacClient.ClusterRoleBindings().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		for _, binding := range bindings.Items {
+			if !strings.HasPrefix(binding.Name, OwnerBindingPrefix) {
+				continue
+			}
+			if err := rbacClient.ClusterRoleBindings().Delete(ctx, binding.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+		}
+		spec, err := c.typeSpec(workspace)
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		for _, role := range spec.ClusterRoles {
+			if err := rbacClient.ClusterRoles().Delete(ctx, role.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+		}
+		klog.Infof("removed RBAC of deleted workspace %q", workspace.Name)
+	}
+
+	workspace = workspace.DeepCopy()
+	var finalizers []string
+	for _, finalizer := range workspace.Finalizers {
+		if finalizer != WorkspaceFinalizer {
+			finalizers = append(finalizers, finalizer)
+		}
+	}
+	workspace.Finalizers = finalizers
+	_, err = c.kcpClient.Cluster(workspace.ClusterName).TenancyV1alpha1().Workspaces().Update(ctx, workspace, metav1.UpdateOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// clusterMoved returns whether the logical cluster of the deleted workspace is held by
+// another workspace, which it was renamed to.
+func (c *Controller) clusterMoved(deleted *tenancyv1alpha1.Workspace) (bool, error) {
+	workspaces, err := c.workspaceIndexer.ByIndex(indexers.WorkspaceCluster, deleted.Status.Cluster)
+	if err != nil {
+		return false, err
+	}
+	for _, obj := range workspaces {
+		workspace := obj.(*tenancyv1alpha1.Workspace)
+		if workspace.DeletionTimestamp == nil && workspace.Status.Cluster == deleted.Status.Cluster {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func hasFinalizer(workspace *tenancyv1alpha1.Workspace) bool {
+	for _, finalizer := range workspace.Finalizers {
+		if finalizer == WorkspaceFinalizer {
+			return true
+		}
+	}
+	return false
+}
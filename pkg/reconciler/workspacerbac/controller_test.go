@@ -0,0 +1,217 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workspacerbac
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clusters"
+
+	"github.com/kcp-dev/kcp/pkg/admission/workspaceowner"
+	"github.com/kcp-dev/kcp/pkg/apis/tenancy/helpers/conditions"
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	kcpfake "github.com/kcp-dev/kcp/pkg/client/clientset/versioned/fake"
+	tenancylister "github.com/kcp-dev/kcp/pkg/client/listers/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/indexers"
+)
+
+type kcpClients struct {
+	*kcpfake.Clientset
+}
+
+func (c kcpClients) Cluster(string) kcpclient.Interface {
+	return c.Clientset
+}
+
+// kubeClients holds a fake for each logical cluster.
+type kubeClients map[string]*kubefake.Clientset
+
+func (c kubeClients) Cluster(name string) kubernetes.Interface {
+	if c[name] == nil {
+		c[name] = kubefake.NewSimpleClientset()
+	}
+	return c[name]
+}
+
+func TestProcess(t *testing.T) {
+	deleted := metav1.Now()
+	workspace := func(name string, finalizers []string, deletionTimestamp *metav1.Time) *tenancyv1alpha1.Workspace {
+		return &tenancyv1alpha1.Workspace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				ClusterName:       "org",
+				Annotations:       map[string]string{workspaceowner.OwnerAnnotation: "alice"},
+				Finalizers:        finalizers,
+				DeletionTimestamp: deletionTimestamp,
+			},
+			Status: tenancyv1alpha1.WorkspaceStatus{Cluster: "k7c2q9x4"},
+		}
+	}
+	typed := workspace("team", nil, nil)
+	typed.Spec.Type = "team"
+	bootstrapped := []runtime.Object{
+		&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "admin"}},
+		&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "edit"}},
+		&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "view"}},
+		&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "custom"}},
+		&rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: OwnerBindingPrefix + "admin"}},
+		&rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: "custom"}},
+	}
+
+	for _, tc := range []struct {
+		name               string
+		workspaces         []*tenancyv1alpha1.Workspace
+		workspaceTypes     []*tenancyv1alpha1.WorkspaceType
+		existing           []runtime.Object
+		expectedRoles      []string
+		expectedBindings   []string
+		expectedFinalizers []string
+		expectedReason     string
+	}{
+		{
+			name:               "bootstrap",
+			workspaces:         []*tenancyv1alpha1.Workspace{workspace("team", nil, nil)},
+			expectedRoles:      []string{"admin", "edit", "view"},
+			expectedBindings:   []string{OwnerBindingPrefix + "admin"},
+			expectedFinalizers: []string{WorkspaceFinalizer},
+			expectedReason:     "Bootstrapped",
+		},
+		{
+			name:       "bootstrap with a type",
+			workspaces: []*tenancyv1alpha1.Workspace{typed},
+			workspaceTypes: []*tenancyv1alpha1.WorkspaceType{{
+				ObjectMeta: metav1.ObjectMeta{Name: "team", ClusterName: "org"},
+				Spec: tenancyv1alpha1.WorkspaceTypeSpec{
+					ClusterRoles:      []tenancyv1alpha1.WorkspaceClusterRole{{Name: "lead"}, {Name: "member"}},
+					OwnerClusterRoles: []string{"lead", "member"},
+				},
+			}},
+			expectedRoles:      []string{"lead", "member"},
+			expectedBindings:   []string{OwnerBindingPrefix + "lead", OwnerBindingPrefix + "member"},
+			expectedFinalizers: []string{WorkspaceFinalizer},
+			expectedReason:     "Bootstrapped",
+		},
+		{
+			name:               "missing type",
+			workspaces:         []*tenancyv1alpha1.Workspace{typed},
+			expectedFinalizers: []string{WorkspaceFinalizer},
+			expectedReason:     tenancyv1alpha1.WorkspaceReasonTypeNotFound,
+		},
+		{
+			name:             "clean up",
+			workspaces:       []*tenancyv1alpha1.Workspace{workspace("team", []string{WorkspaceFinalizer}, &deleted)},
+			existing:         bootstrapped,
+			expectedRoles:    []string{"custom"},
+			expectedBindings: []string{"custom"},
+		},
+		{
+			name: "clean up after a rename",
+			workspaces: []*tenancyv1alpha1.Workspace{
+				workspace("team", []string{WorkspaceFinalizer}, &deleted),
+				workspace("squad", []string{WorkspaceFinalizer}, nil),
+			},
+			existing:         bootstrapped,
+			expectedRoles:    []string{"admin", "custom", "edit", "view"},
+			expectedBindings: []string{"custom", OwnerBindingPrefix + "admin"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			workspaceIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{indexers.WorkspaceCluster: indexers.IndexWorkspaceByCluster})
+			for _, workspace := range tc.workspaces {
+				if err := workspaceIndexer.Add(workspace); err != nil {
+					t.Fatal(err)
+				}
+			}
+			workspaceTypeIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			for _, workspaceType := range tc.workspaceTypes {
+				if err := workspaceTypeIndexer.Add(workspaceType); err != nil {
+					t.Fatal(err)
+				}
+			}
+			kcpClient := kcpClients{Clientset: kcpfake.NewSimpleClientset(tc.workspaces[0])}
+			kubeClient := kubeClients{"k7c2q9x4": kubefake.NewSimpleClientset(tc.existing...)}
+			c := &Controller{
+				kcpClient:           kcpClient,
+				kubeClient:          kubeClient,
+				workspaceIndexer:    workspaceIndexer,
+				workspaceLister:     tenancylister.NewWorkspaceLister(workspaceIndexer),
+				workspaceTypeLister: tenancylister.NewWorkspaceTypeLister(workspaceTypeIndexer),
+			}
+
+			if err := c.process(context.Background(), clusters.ToClusterAwareKey("org", tc.workspaces[0].Name)); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(kubeClient) != 1 {
+				t.Errorf("expected only the logical cluster of the workspace to be changed, got %d logical clusters", len(kubeClient))
+			}
+			rbacClient := kubeClient["k7c2q9x4"].RbacV1()
+			roles, err := rbacClient.ClusterRoles().List(context.Background(), metav1.ListOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var roleNames []string
+			for _, role := range roles.Items {
+				roleNames = append(roleNames, role.Name)
+			}
+			bindings, err := rbacClient.ClusterRoleBindings().List(context.Background(), metav1.ListOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var bindingNames []string
+			for _, binding := range bindings.Items {
+				bindingNames = append(bindingNames, binding.Name)
+				if binding.Name == OwnerBindingPrefix+binding.RoleRef.Name && (len(binding.Subjects) != 1 || binding.Subjects[0].Name != "alice") {
+					t.Errorf("expected binding %q to bind the owner, got %v", binding.Name, binding.Subjects)
+				}
+			}
+			sort.Strings(roleNames)
+			sort.Strings(bindingNames)
+			if diff := cmp.Diff(tc.expectedRoles, roleNames); diff != "" {
+				t.Errorf("unexpected cluster roles (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedBindings, bindingNames); diff != "" {
+				t.Errorf("unexpected cluster role bindings (-want +got):\n%s", diff)
+			}
+
+			updated, err := kcpClient.TenancyV1alpha1().Workspaces().Get(context.Background(), tc.workspaces[0].Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.expectedFinalizers, updated.Finalizers); diff != "" {
+				t.Errorf("unexpected finalizers (-want +got):\n%s", diff)
+			}
+			if tc.expectedReason != "" {
+				condition := conditions.FindWorkspaceCondition(updated, tenancyv1alpha1.WorkspaceRBACBootstrapped)
+				if condition == nil || condition.Reason != tc.expectedReason {
+					t.Errorf("expected the %s condition with reason %q, got %+v", tenancyv1alpha1.WorkspaceRBACBootstrapped, tc.expectedReason, condition)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scenario
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+// Recorder records the mutating requests sent through the transports it wraps into a script.
+type Recorder struct {
+	start time.Time
+
+	lock  sync.Mutex
+	steps []Step
+}
+
+// NewRecorder returns a recorder whose recording starts now.
+func NewRecorder() *Recorder {
+	return &Recorder{start: time.Now()}
+}
+
+// RecordConfig returns a copy of the config whose requests are recorded.
+func (r *Recorder) RecordConfig(config *rest.Config) *rest.Config {
+	config = rest.CopyConfig(config)
+	config.Wrap(r.Wrap)
+	return config
+}
+
+// Wrap wraps the transport, recording the mutating requests sent through it.
+func (r *Recorder) Wrap(rt http.RoundTripper) http.RoundTripper {
+	return &recordingRoundTripper{recorder: r, delegate: rt}
+}
+
+// Script returns the script of the requests recorded so far, in the order they were sent.
+func (r *Recorder) Script() *Script {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return &Script{Steps: append([]Step(nil), r.steps...)}
+}
+
+type recordingRoundTripper struct {
+	recorder *Recorder
+	delegate http.RoundTripper
+}
+
+func (rt *recordingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch req.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return rt.delegate.RoundTrip(req)
+	}
+
+	step := Step{
+		After:       metav1.Duration{Duration: time.Since(rt.recorder.start)},
+		Method:      req.Method,
+		Path:        req.URL.Path,
+		Query:       req.URL.RawQuery,
+		ContentType: req.Header.Get("Content-Type"),
+	}
+	if req.Body != nil {
+		body, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		if json.Valid(body) {
+			step.Object = body
+		} else {
+			step.Body = string(body)
+		}
+	}
+
+	resp, err := rt.delegate.RoundTrip(req)
+	if resp != nil {
+		step.Status = resp.StatusCode
+	}
+	// requests which did not reach the server are not part of the scenario
+	if err == nil {
+		rt.recorder.lock.Lock()
+		rt.recorder.steps = append(rt.recorder.steps, step)
+		rt.recorder.lock.Unlock()
+	}
+	return resp, err
+}
+
+// WrappedRoundTripper returns the wrapped transport.
+func (rt *recordingRoundTripper) WrappedRoundTripper() http.RoundTripper {
+	return rt.delegate
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+)
+
+// ReplayOptions configure the replay of a script.
+type ReplayOptions struct {
+	// PreserveTiming waits between the requests as long as when they were recorded.
+	PreserveTiming bool
+	// ContinueOnError goes on with the next requests when a request fails, instead of
+	// stopping the replay.
+	ContinueOnError bool
+}
+
+// NewReplayClient returns a client to replay scripts against the server of the config, whose
+// host must be the root of the server.
+func NewReplayClient(config *rest.Config) (rest.Interface, error) {
+	config = rest.CopyConfig(config)
+	if config.NegotiatedSerializer == nil {
+		config.NegotiatedSerializer = scheme.Codecs.WithoutConversion()
+	}
+	return rest.UnversionedRESTClientFor(config)
+}
+
+// Replay sends the requests of the script with the client, whose base URL must be the root
+// of the server, since the paths of the script are absolute. The outcome of each request is
+// written to out. It fails if a request fails, unless asked to continue, in which case it
+// returns the number of failed requests.
+func Replay(ctx context.Context, client rest.Interface, script *Script, o ReplayOptions, out io.Writer) (int, error) {
+	start := time.Now()
+	failed := 0
+	for i := range script.Steps {
+		step := &script.Steps[i]
+		if o.PreserveTiming {
+			select {
+			case <-ctx.Done():
+				return failed, ctx.Err()
+			case <-time.After(time.Until(start.Add(step.After.Duration))):
+			}
+		}
+
+		req := client.Verb(step.Method).AbsPath(step.Path)
+		query, err := url.ParseQuery(step.Query)
+		if err != nil {
+			return failed, fmt.Errorf("step %d: invalid query %q: %w", i+1, step.Query, err)
+		}
+		for key, values := range query {
+			for _, value := range values {
+				req = req.Param(key, value)
+			}
+		}
+		if step.ContentType != "" {
+			req = req.SetHeader("Content-Type", step.ContentType)
+		}
+		if body := step.body(); len(body) > 0 {
+			req = req.Body(body)
+		}
+
+		var status int
+		err = req.Do(ctx).StatusCode(&status).Error()
+		fmt.Fprintf(out, "%d/%d %s %s: %s\n", i+1, len(script.Steps), step.Method, step.Path, describe(status, step.Status))
+		if err != nil {
+			if !o.ContinueOnError {
+				return failed, fmt.Errorf("step %d %s %s failed: %w", i+1, step.Method, step.Path, err)
+			}
+			failed++
+		}
+	}
+	return failed, nil
+}
+
+// describe describes the status of a replayed request, and the status it got when recorded
+// if different.
+func describe(status, recorded int) string {
+	description := fmt.Sprintf("%d %s", status, http.StatusText(status))
+	if recorded != 0 && recorded != status {
+		description += fmt.Sprintf(" (recorded %d %s)", recorded, http.StatusText(recorded))
+	}
+	return description
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package scenario records the API mutations performed by clients of kcp into scripts, and
+// replays them against any server, e.g. to reproduce the scenario of a test in a bug report
+// or to generate load from real scenarios.
+package scenario
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// Script is a sequence of API requests.
+type Script struct {
+	Steps []Step `json:"steps"`
+}
+
+// Step is a mutating API request, and the response it got when recorded.
+type Step struct {
+	// After is the time elapsed since the start of the recording when the request was sent.
+	After metav1.Duration `json:"after"`
+	// Method is the HTTP method of the request: POST, PUT, PATCH or DELETE.
+	Method string `json:"method"`
+	// Path is the path of the request from the root of the server, including the
+	// /clusters/<name> prefix of the logical cluster.
+	Path string `json:"path"`
+	// Query is the encoded query of the request.
+	Query string `json:"query,omitempty"`
+	// ContentType is the content type of the body of the request.
+	ContentType string `json:"contentType,omitempty"`
+	// Object is the body of the request, if JSON.
+	Object json.RawMessage `json:"object,omitempty"`
+	// Body is the body of the request, if not JSON, e.g. an apply patch in YAML.
+	Body string `json:"body,omitempty"`
+	// Status is the HTTP status code the request got when recorded.
+	Status int `json:"status,omitempty"`
+}
+
+// body returns the body of the request of the step.
+func (s *Step) body() []byte {
+	if len(s.Object) > 0 {
+		return s.Object
+	}
+	return []byte(s.Body)
+}
+
+// Load reads a script from a YAML file.
+func Load(path string) (*Script, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	script := &Script{}
+	if err := yaml.UnmarshalStrict(data, script); err != nil {
+		return nil, fmt.Errorf("invalid script %s: %w", path, err)
+	}
+	return script, nil
+}
+
+// WriteFile writes the script to a YAML file.
+func (s *Script) WriteFile(path string) error {
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scenario
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+type request struct {
+	Method, Path, Query, Body string
+}
+
+// server records the requests it gets, and answers them with the given status.
+func server(t *testing.T, status int) (*httptest.Server, func() []request) {
+	var lock sync.Mutex
+	var requests []request
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		lock.Lock()
+		requests = append(requests, request{Method: req.Method, Path: req.URL.Path, Query: req.URL.RawQuery, Body: string(bytes.TrimSpace(body))})
+		lock.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(s.Close)
+	return s, func() []request {
+		lock.Lock()
+		defer lock.Unlock()
+		return requests
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	ctx := context.Background()
+	recorded, _ := server(t, http.StatusCreated)
+	recorder := NewRecorder()
+	client, err := kubernetes.NewForConfig(recorder.RecordConfig(&rest.Config{Host: recorded.URL + "/clusters/team"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.CoreV1().ConfigMaps("default").Create(ctx, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "settings"}}, metav1.CreateOptions{DryRun: []string{metav1.DryRunAll}}); err != nil {
+		t.Fatal(err)
+	}
+	// reads are not recorded
+	if _, err := client.CoreV1().ConfigMaps("default").List(ctx, metav1.ListOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.CoreV1().ConfigMaps("default").Delete(ctx, "settings", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the script is written as YAML, whose objects have sorted keys
+	path := filepath.Join(t.TempDir(), "scenario.yaml")
+	if err := recorder.Script().WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+	script, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range script.Steps {
+		script.Steps[i].After = metav1.Duration{}
+	}
+	expected := []Step{
+		{Method: "POST", Path: "/clusters/team/api/v1/namespaces/default/configmaps", Query: "dryRun=All", ContentType: "application/json", Object: []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"creationTimestamp":null,"name":"settings"}}`), Status: http.StatusCreated},
+		{Method: "DELETE", Path: "/clusters/team/api/v1/namespaces/default/configmaps/settings", ContentType: "application/json", Object: []byte(`{"apiVersion":"v1","kind":"DeleteOptions"}`), Status: http.StatusCreated},
+	}
+	if diff := cmp.Diff(expected, script.Steps); diff != "" {
+		t.Fatalf("unexpected script (-want +got):\n%s", diff)
+	}
+
+	// the failed requests are counted when continuing on errors
+	replayed, requests := server(t, http.StatusConflict)
+	replayClient, err := NewReplayClient(&rest.Config{Host: replayed.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	failed, err := Replay(ctx, replayClient, script, ReplayOptions{ContinueOnError: true}, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed != 2 {
+		t.Errorf("expected 2 failed requests, got %d", failed)
+	}
+	expectedRequests := []request{
+		{Method: "POST", Path: "/clusters/team/api/v1/namespaces/default/configmaps", Query: "dryRun=All", Body: `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"creationTimestamp":null,"name":"settings"}}`},
+		{Method: "DELETE", Path: "/clusters/team/api/v1/namespaces/default/configmaps/settings", Body: `{"apiVersion":"v1","kind":"DeleteOptions"}`},
+	}
+	if diff := cmp.Diff(expectedRequests, requests()); diff != "" {
+		t.Errorf("unexpected replayed requests (-want +got):\n%s", diff)
+	}
+	if expected := "1/2 POST /clusters/team/api/v1/namespaces/default/configmaps: 409 Conflict (recorded 201 Created)\n"; !bytes.HasPrefix(out.Bytes(), []byte(expected)) {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+
+	// without continuing, the first failure stops the replay
+	if _, err := Replay(ctx, replayClient, script, ReplayOptions{}, ioutil.Discard); err == nil {
+		t.Error("expected the replay to fail")
+	}
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schemacompat
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Narrowing compares a schema to the schema negotiated from it, and reports what the
+// negotiated schema lost: the paths of the fields it dropped, and descriptions of the
+// fields whose values it narrowed, like enums missing values. Items of arrays and values
+// of maps are denoted by a "[*]" key. Both are sorted.
+func Narrowing(imported, negotiated *apiextensionsv1.JSONSchemaProps) (dropped, narrowed []string) {
+	narrowing(nil, imported, negotiated, &dropped, &narrowed)
+	sort.Strings(dropped)
+	sort.Strings(narrowed)
+	return dropped, narrowed
+}
+
+func narrowing(fldPath *field.Path, imported, negotiated *apiextensionsv1.JSONSchemaProps, dropped, narrowed *[]string) {
+	if imported == nil || negotiated == nil || negotiated.XPreserveUnknownFields != nil && *negotiated.XPreserveUnknownFields {
+		return
+	}
+
+	if len(negotiated.Enum) > 0 {
+		negotiatedValues := enumValues(negotiated.Enum)
+		if missing := enumValues(imported.Enum).Difference(negotiatedValues); len(imported.Enum) == 0 || missing.Len() > 0 {
+			*narrowed = append(*narrowed, fmt.Sprintf("%s: values narrowed to %s", pathString(fldPath), strings.Join(negotiatedValues.List(), ", ")))
+		}
+	}
+
+	for name := range imported.Properties {
+		importedProperty := imported.Properties[name]
+		childPath := fldPath.Child(name)
+		if negotiatedProperty, found := negotiated.Properties[name]; found {
+			narrowing(childPath, &importedProperty, &negotiatedProperty, dropped, narrowed)
+			continue
+		}
+		if negotiated.AdditionalProperties != nil {
+			if negotiated.AdditionalProperties.Schema != nil {
+				narrowing(childPath, &importedProperty, negotiated.AdditionalProperties.Schema, dropped, narrowed)
+				continue
+			}
+			if negotiated.AdditionalProperties.Allows {
+				continue
+			}
+		}
+		*dropped = append(*dropped, pathString(childPath))
+	}
+
+	if imported.AdditionalProperties != nil {
+		switch {
+		case negotiated.AdditionalProperties == nil || !negotiated.AdditionalProperties.Allows && negotiated.AdditionalProperties.Schema == nil:
+			*dropped = append(*dropped, pathString(fldPath.Key("*")))
+		case imported.AdditionalProperties.Schema != nil && negotiated.AdditionalProperties.Schema != nil:
+			narrowing(fldPath.Key("*"), imported.AdditionalProperties.Schema, negotiated.AdditionalProperties.Schema, dropped, narrowed)
+		case imported.AdditionalProperties.Schema == nil && negotiated.AdditionalProperties.Schema != nil:
+			*narrowed = append(*narrowed, fmt.Sprintf("%s: values narrowed to type %q", pathString(fldPath.Key("*")), negotiated.AdditionalProperties.Schema.Type))
+		}
+	}
+
+	if imported.Items != nil && imported.Items.Schema != nil && negotiated.Items != nil && negotiated.Items.Schema != nil {
+		narrowing(fldPath.Key("*"), imported.Items.Schema, negotiated.Items.Schema, dropped, narrowed)
+	}
+}
+
+func enumValues(enum []apiextensionsv1.JSON) sets.String {
+	values := sets.NewString()
+	for _, value := range enum {
+		values.Insert(string(value.Raw))
+	}
+	return values
+}
+
+func pathString(fldPath *field.Path) string {
+	if fldPath == nil {
+		return "."
+	}
+	return fldPath.String()
+}
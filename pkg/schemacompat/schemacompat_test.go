@@ -456,3 +456,60 @@ func TestCompatibility(t *testing.T) {
 func boolPtr(b bool) *bool {
 	return &b
 }
+
+func TestNarrowing(t *testing.T) {
+	imported := &apiextensionsv1.JSONSchemaProps{
+		Type: "object",
+		Properties: map[string]apiextensionsv1.JSONSchemaProps{
+			"spec": {
+				Type: "object",
+				Properties: map[string]apiextensionsv1.JSONSchemaProps{
+					"replicas": {Type: "integer"},
+					"strategy": {Type: "string", Enum: []apiextensionsv1.JSON{{Raw: []byte(`"Recreate"`)}, {Raw: []byte(`"RollingUpdate"`)}}},
+					"containers": {
+						Type: "array",
+						Items: &apiextensionsv1.JSONSchemaPropsOrArray{Schema: &apiextensionsv1.JSONSchemaProps{
+							Type: "object",
+							Properties: map[string]apiextensionsv1.JSONSchemaProps{
+								"name":  {Type: "string"},
+								"image": {Type: "string"},
+							},
+						}},
+					},
+				},
+			},
+		},
+	}
+	negotiated := &apiextensionsv1.JSONSchemaProps{
+		Type: "object",
+		Properties: map[string]apiextensionsv1.JSONSchemaProps{
+			"spec": {
+				Type: "object",
+				Properties: map[string]apiextensionsv1.JSONSchemaProps{
+					"strategy": {Type: "string", Enum: []apiextensionsv1.JSON{{Raw: []byte(`"Recreate"`)}}},
+					"containers": {
+						Type: "array",
+						Items: &apiextensionsv1.JSONSchemaPropsOrArray{Schema: &apiextensionsv1.JSONSchemaProps{
+							Type: "object",
+							Properties: map[string]apiextensionsv1.JSONSchemaProps{
+								"name": {Type: "string"},
+							},
+						}},
+					},
+				},
+			},
+		},
+	}
+
+	dropped, narrowed := Narrowing(imported, negotiated)
+	if diff := cmp.Diff([]string{"spec.containers[*].image", "spec.replicas"}, dropped); diff != "" {
+		t.Errorf("unexpected dropped fields (-want +got): %s", diff)
+	}
+	if diff := cmp.Diff([]string{`spec.strategy: values narrowed to "Recreate"`}, narrowed); diff != "" {
+		t.Errorf("unexpected narrowed fields (-want +got): %s", diff)
+	}
+
+	if dropped, narrowed := Narrowing(imported, imported); len(dropped) != 0 || len(narrowed) != 0 {
+		t.Errorf("expected no narrowing of the same schema, got %v and %v", dropped, narrowed)
+	}
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	"k8s.io/apiserver/pkg/authentication/user"
+	certutil "k8s.io/client-go/util/cert"
+	"k8s.io/client-go/util/keyutil"
+)
+
+const (
+	// AdminUserName is the user of the admin client certificate.
+	AdminUserName = "kcp-admin"
+
+	clientCACertFile   = "client-ca.crt"
+	clientCAKeyFile    = "client-ca.key"
+	clientCABundleFile = "client-ca-bundle.crt"
+)
+
+// adminCredentials are the client certificate and key of the admin user.
+type adminCredentials struct {
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// bootstrapAdmin loads or generates the client CA kept in dir, and issues a client
+// certificate valid for ttl to the admin user, member of system:masters. It returns the
+// credentials, and the file of the client CA bundle the server must trust: the CA of dir
+// followed by the CAs of extraCAFile, if any.
+func bootstrapAdmin(dir string, ttl time.Duration, extraCAFile string) (*adminCredentials, string, error) {
+	caCert, caKey, err := loadOrGenerateClientCA(dir)
+	if err != nil {
+		return nil, "", err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, "", err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).SetInt64(math.MaxInt64))
+	if err != nil {
+		return nil, "", err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName:   AdminUserName,
+			Organization: []string{user.SystemPrivilegedGroup},
+		},
+		// tolerate some clock skew between the clients and the server
+		NotBefore:   now.Add(-time.Minute),
+		NotAfter:    now.Add(ttl),
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, key.Public(), caKey)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to issue the admin client certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, "", err
+	}
+	certPEM, err := certutil.EncodeCertificates(cert)
+	if err != nil {
+		return nil, "", err
+	}
+	keyPEM, err := keyutil.MarshalPrivateKeyToPEM(key)
+	if err != nil {
+		return nil, "", err
+	}
+
+	bundle, err := certutil.EncodeCertificates(caCert)
+	if err != nil {
+		return nil, "", err
+	}
+	if extraCAFile != "" {
+		extra, err := ioutil.ReadFile(extraCAFile)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read the client CA file: %w", err)
+		}
+		bundle = append(bundle, extra...)
+	}
+	bundleFile := filepath.Join(dir, clientCABundleFile)
+	if err := certutil.WriteCert(bundleFile, bundle); err != nil {
+		return nil, "", err
+	}
+
+	return &adminCredentials{certPEM: certPEM, keyPEM: keyPEM}, bundleFile, nil
+}
+
+// loadOrGenerateClientCA returns the client CA kept in dir, generating it if missing.
+func loadOrGenerateClientCA(dir string) (*x509.Certificate, crypto.Signer, error) {
+	certFile := filepath.Join(dir, clientCACertFile)
+	keyData, generated, err := keyutil.LoadOrGenerateKeyFile(filepath.Join(dir, clientCAKeyFile))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load the client CA key: %w", err)
+	}
+	parsed, err := keyutil.ParsePrivateKeyPEM(keyData)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse the client CA key: %w", err)
+	}
+	key, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported client CA key type %T", parsed)
+	}
+
+	if !generated {
+		certs, err := certutil.CertsFromFile(certFile)
+		if err == nil && len(certs) > 0 {
+			return certs[0], key, nil
+		}
+		if err != nil && !os.IsNotExist(err) {
+			return nil, nil, fmt.Errorf("failed to load the client CA certificate: %w", err)
+		}
+	}
+
+	cert, err := certutil.NewSelfSignedCACert(certutil.Config{CommonName: "kcp-client-ca"}, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate the client CA certificate: %w", err)
+	}
+	data, err := certutil.EncodeCertificates(cert)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := certutil.WriteCert(certFile, data); err != nil {
+		return nil, nil, err
+	}
+	return cert, key, nil
+}
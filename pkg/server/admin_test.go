@@ -0,0 +1,61 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"crypto/x509"
+	"testing"
+	"time"
+
+	certutil "k8s.io/client-go/util/cert"
+)
+
+func TestBootstrapAdmin(t *testing.T) {
+	dir := t.TempDir()
+
+	verify := func(admin *adminCredentials, bundleFile string) {
+		t.Helper()
+		certs, err := certutil.ParseCertsPEM(admin.certPEM)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := certs[0].Subject.CommonName; got != AdminUserName {
+			t.Errorf("expected the certificate of %q, got %q", AdminUserName, got)
+		}
+		roots, err := certutil.NewPool(bundleFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := certs[0].Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}); err != nil {
+			t.Errorf("expected the certificate to be trusted by the client CA bundle: %v", err)
+		}
+	}
+
+	first, bundleFile, err := bootstrapAdmin(dir, time.Hour, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	verify(first, bundleFile)
+
+	// the client CA is kept across restarts, so certificates issued before remain valid
+	second, bundleFile, err := bootstrapAdmin(dir, time.Hour, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	verify(second, bundleFile)
+	verify(first, bundleFile)
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"k8s.io/apiserver/pkg/admission"
+	genericoptions "k8s.io/apiserver/pkg/server/options"
+
+	"github.com/kcp-dev/kcp/pkg/admission/apibinder"
+	"github.com/kcp-dev/kcp/pkg/admission/crossworkspacereferences"
+	"github.com/kcp-dev/kcp/pkg/admission/externalpolicy"
+	"github.com/kcp-dev/kcp/pkg/admission/negotiatedschemas"
+	"github.com/kcp-dev/kcp/pkg/admission/objectlimits"
+	"github.com/kcp-dev/kcp/pkg/admission/placementdefaults"
+	"github.com/kcp-dev/kcp/pkg/admission/reservednames"
+	"github.com/kcp-dev/kcp/pkg/admission/workspaceowner"
+	"github.com/kcp-dev/kcp/pkg/crossworkspace"
+	"github.com/kcp-dev/kcp/pkg/reconciler/placement"
+	"github.com/kcp-dev/kcp/pkg/reconciler/workspacelimits"
+	"github.com/kcp-dev/kcp/pkg/workspacenames"
+)
+
+// admissionPluginEntry groups the name of an admission plugin with the function
+// registering it.
+type admissionPluginEntry struct {
+	name     string
+	register func(plugins *admission.Plugins)
+}
+
+// builtInAdmissionPlugins returns the admission plugins of kcp, in the order they run
+// after the upstream plugins.
+func builtInAdmissionPlugins(c *Config, referenceResolver *crossworkspace.Resolver, placementDefaulter *placement.Defaulter, schemas *negotiatedschemas.Schemas, limits *workspacelimits.Registry, externalPolicy *externalpolicy.Webhook, workspaceNames *workspacenames.Resolver) ([]admissionPluginEntry, error) {
+	schemaMode, err := negotiatedschemas.ParseMode(c.NegotiatedSchemaAdmission)
+	if err != nil {
+		return nil, err
+	}
+
+	return []admissionPluginEntry{
+		{name: workspaceowner.PluginName, register: workspaceowner.Register},
+		{name: apibinder.PluginName, register: apibinder.Register},
+		{name: placementdefaults.PluginName, register: func(plugins *admission.Plugins) {
+			placementdefaults.Register(plugins, placementDefaulter)
+		}},
+		{name: reservednames.PluginName, register: func(plugins *admission.Plugins) {
+			reservednames.Register(plugins, c.ProtectedNamePrefixes, workspaceNames)
+		}},
+		{name: objectlimits.PluginName, register: func(plugins *admission.Plugins) {
+			objectlimits.Register(plugins, limits)
+		}},
+		{name: crossworkspacereferences.PluginName, register: func(plugins *admission.Plugins) {
+			crossworkspacereferences.Register(plugins, referenceResolver)
+		}},
+		{name: negotiatedschemas.PluginName, register: func(plugins *admission.Plugins) {
+			negotiatedschemas.Register(plugins, schemas, schemaMode)
+		}},
+		{name: externalpolicy.PluginName, register: func(plugins *admission.Plugins) {
+			externalpolicy.Register(plugins, externalPolicy)
+		}},
+	}, nil
+}
+
+// registerAdmissionPlugins registers the plugins and appends them to the recommended order,
+// so that they are enabled unless disabled with --disable-admission-plugins.
+func registerAdmissionPlugins(options *genericoptions.AdmissionOptions, entries ...admissionPluginEntry) {
+	for _, entry := range entries {
+		entry.register(options.Plugins)
+		options.RecommendedPluginOrder = append(options.RecommendedPluginOrder, entry.name)
+	}
+}
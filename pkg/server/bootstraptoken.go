@@ -0,0 +1,94 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/request/bearertoken"
+	"k8s.io/apiserver/pkg/authentication/user"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/clusters"
+	"k8s.io/kubernetes/plugin/pkg/auth/authenticator/token/bootstrap"
+)
+
+// bootstrapTokenAuthenticator authenticates the bootstrap tokens stored as Secrets in the
+// kube-system namespace of a system logical cluster, like kubeadm does, so that shards
+// and syncers can enroll without static credentials. It accepts no token until it is
+// initialized with the synced Secrets.
+type bootstrapTokenAuthenticator struct {
+	lock     sync.RWMutex
+	delegate authenticator.Token
+}
+
+func (a *bootstrapTokenAuthenticator) initialize(clusterName string, secretLister corev1listers.SecretLister) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.delegate = bootstrap.NewTokenAuthenticator(&clusterSecretNamespaceLister{
+		SecretNamespaceLister: secretLister.Secrets(metav1.NamespaceSystem),
+		clusterName:           clusterName,
+	})
+}
+
+// Request returns the request authenticator.
+func (a *bootstrapTokenAuthenticator) Request() authenticator.Request {
+	return bearertoken.New(authenticator.TokenFunc(a.authenticateToken))
+}
+
+func (a *bootstrapTokenAuthenticator) authenticateToken(ctx context.Context, token string) (*authenticator.Response, bool, error) {
+	a.lock.RLock()
+	delegate := a.delegate
+	a.lock.RUnlock()
+	if delegate == nil {
+		return nil, false, nil
+	}
+	return delegate.AuthenticateToken(ctx, token)
+}
+
+// clusterSecretNamespaceLister gets the Secrets of a single logical cluster out of a lister
+// keyed by cluster aware names.
+type clusterSecretNamespaceLister struct {
+	corev1listers.SecretNamespaceLister
+	clusterName string
+}
+
+func (l *clusterSecretNamespaceLister) Get(name string) (*corev1.Secret, error) {
+	return l.SecretNamespaceLister.Get(clusters.ToClusterAwareKey(l.clusterName, name))
+}
+
+// withAnonymousGroups adds groups to the anonymous user, so that unauthenticated clients
+// can be granted the few requests they need with RBAC bindings to these groups.
+func withAnonymousGroups(delegate authenticator.Request, groups []string) authenticator.Request {
+	return authenticator.RequestFunc(func(req *http.Request) (*authenticator.Response, bool, error) {
+		resp, ok, err := delegate.AuthenticateRequest(req)
+		if err != nil || !ok || resp.User.GetName() != user.Anonymous {
+			return resp, ok, err
+		}
+		resp.User = &user.DefaultInfo{
+			Name:   resp.User.GetName(),
+			UID:    resp.User.GetUID(),
+			Groups: append(append([]string{}, resp.User.GetGroups()...), groups...),
+			Extra:  resp.User.GetExtra(),
+		}
+		return resp, true, nil
+	})
+}
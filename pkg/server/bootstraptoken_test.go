@@ -0,0 +1,87 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/user"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestBootstrapTokenAuthenticator(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for clusterName, tokenID := range map[string]string{"root": "abcdef", "other": "ghijkl"} {
+		if err := indexer.Add(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "bootstrap-token-" + tokenID, Namespace: metav1.NamespaceSystem, ClusterName: clusterName},
+			Type:       corev1.SecretTypeBootstrapToken,
+			Data: map[string][]byte{
+				"token-id":                       []byte(tokenID),
+				"token-secret":                   []byte("0123456789abcdef"),
+				"usage-bootstrap-authentication": []byte("true"),
+			},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a := &bootstrapTokenAuthenticator{}
+	if _, ok, _ := a.authenticateToken(context.Background(), "abcdef.0123456789abcdef"); ok {
+		t.Errorf("expected no token to be accepted before initialization")
+	}
+	a.initialize("root", corev1listers.NewSecretLister(indexer))
+
+	for _, tc := range []struct {
+		token         string
+		authenticated bool
+	}{
+		{token: "abcdef.0123456789abcdef", authenticated: true},
+		{token: "abcdef.fedcba9876543210", authenticated: false},
+		// tokens of other logical clusters are ignored
+		{token: "ghijkl.0123456789abcdef", authenticated: false},
+	} {
+		t.Run(tc.token, func(t *testing.T) {
+			resp, ok, _ := a.authenticateToken(context.Background(), tc.token)
+			if ok != tc.authenticated {
+				t.Fatalf("expected authenticated=%v, got %v", tc.authenticated, ok)
+			}
+			if ok && resp.User.GetName() != "system:bootstrap:abcdef" {
+				t.Errorf("unexpected user %q", resp.User.GetName())
+			}
+		})
+	}
+}
+
+func TestAnonymousGroups(t *testing.T) {
+	anonymous := authenticator.RequestFunc(func(req *http.Request) (*authenticator.Response, bool, error) {
+		return &authenticator.Response{User: &user.DefaultInfo{Name: user.Anonymous, Groups: []string{user.AllUnauthenticated}}}, true, nil
+	})
+	resp, ok, err := withAnonymousGroups(anonymous, []string{"enrollment"}).AuthenticateRequest(&http.Request{})
+	if err != nil || !ok {
+		t.Fatalf("expected the anonymous user, got ok=%v err=%v", ok, err)
+	}
+	if diff := cmp.Diff([]string{user.AllUnauthenticated, "enrollment"}, resp.User.GetGroups()); diff != "" {
+		t.Errorf("unexpected groups (-want +got):\n%s", diff)
+	}
+}
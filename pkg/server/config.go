@@ -18,57 +18,156 @@ package server
 
 import (
 	"flag"
+	"time"
 
 	"github.com/spf13/pflag"
 
+	genericoptions "k8s.io/apiserver/pkg/server/options"
 	kubeoptions "k8s.io/kubernetes/pkg/kubeapiserver/options"
 
+	"github.com/kcp-dev/kcp/pkg/admission/externalpolicy"
+	"github.com/kcp-dev/kcp/pkg/admission/negotiatedschemas"
+	"github.com/kcp-dev/kcp/pkg/admission/reservednames"
 	"github.com/kcp-dev/kcp/pkg/etcd"
 	"github.com/kcp-dev/kcp/pkg/reconciler/cluster"
+	"github.com/kcp-dev/kcp/pkg/reconciler/workspace"
 )
 
 // DefaultConfig is the default behavior of the KCP server.
 func DefaultConfig() *Config {
+	// OIDC and service accounts are configured with the OIDC and ServiceAccounts fields
+	// instead
+	authentication := kubeoptions.NewBuiltInAuthenticationOptions().WithAll()
+	authentication.OIDC = nil
+	authentication.ServiceAccounts = nil
+
 	return &Config{
-		EtcdClientInfo:             etcd.ClientInfo{},
-		EtcdDirectory:              "",
-		EtcdPeerPort:               "2380",
-		EtcdClientPort:             "2379",
-		InstallClusterController:   false,
-		ClusterControllerOptions:   cluster.DefaultOptions(),
-		InstallWorkspaceController: false,
-		KubeConfigPath:             "admin.kubeconfig",
-		Listen:                     ":6443",
-		RootDirectory:              ".kcp",
-		ProfilerAddress:            "",
-		ShardKubeconfigFile:        "",
-		EnableSharding:             false,
-		Authentication:             kubeoptions.NewBuiltInAuthenticationOptions().WithAll(),
+		EtcdClientInfo:              etcd.ClientInfo{},
+		EtcdDirectory:               "",
+		EtcdPeerPort:                "2380",
+		EtcdClientPort:              "2379",
+		InstallClusterController:    false,
+		ClusterControllerOptions:    cluster.DefaultOptions(),
+		InstallWorkspaceController:  false,
+		InstallAPIBindingController: false,
+		WorkspaceIdleTimeout:        0,
+		HibernationRejectReads:      false,
+		ShardCredentialsRotation:    24 * time.Hour,
+		ShardCredentialsGracePeriod: time.Hour,
+		KubeConfigPath:              "admin.kubeconfig",
+		AdminCertificateTTL:         30 * 24 * time.Hour,
+		Listen:                      ":6443",
+		RootDirectory:               ".kcp",
+		ProfilerAddress:             "",
+		ShardKubeconfigFile:         "",
+		EnableSharding:              false,
+		ShardName:                   "",
+		CacheWildcardLists:          false,
+		WildcardListDefaultLimit:    500,
+		WildcardListMaxLimit:        5000,
+		WildcardListMaxBytes:        128 * 1024 * 1024,
+		EnablePriorityAndFairness:   true,
+		BootstrapManifests:          "",
+		BootstrapInterval:           time.Minute,
+		StartupStepTimeout:          5 * time.Minute,
+		Authentication:              authentication,
+		OIDC:                        DefaultOIDCConfig(),
+		ServiceAccounts:             DefaultServiceAccountConfig(),
+		AnonymousGroups:             nil,
+		Audit:                       genericoptions.NewAuditOptions(),
+		EnableAuditSinks:            false,
+		EnableWorkspaceClientCAs:    false,
+		EnableAdmissionPlugins:      nil,
+		DisableAdmissionPlugins:     nil,
+		ProtectedNamePrefixes:       reservednames.DefaultProtectedPrefixes,
+		NegotiatedSchemaAdmission:   string(negotiatedschemas.ModeWarn),
+		// the request size limit of etcd
+		MaxObjectSize:        1536 * 1024,
+		MaxManagedFieldsSize: 512 * 1024,
+		MaxAnnotationsSize:   256 * 1024,
+
+		ExternalPolicyConfigFile:    "",
+		ExternalPolicyTimeout:       10 * time.Second,
+		ExternalPolicyFailurePolicy: string(externalpolicy.Fail),
+
+		WorkspaceNotificationsConfigFile: "",
+		WorkspaceNotificationsFormat:     string(workspace.FormatJSON),
+		WorkspaceNotificationsTimeout:    10 * time.Second,
+		WorkspaceRenameGracePeriod:       7 * 24 * time.Hour,
+		HomeWorkspacesParent:             "",
+		HomeWorkspaceType:                "home",
+
+		UsageRetention: 30 * 24 * time.Hour,
 	}
 }
 
 // Config determines the behavior of the KCP server.
 type Config struct {
-	EtcdClientInfo             etcd.ClientInfo
-	EtcdDirectory              string
-	EtcdPeerPort               string
-	EtcdClientPort             string
-	InstallClusterController   bool
-	ClusterControllerOptions   *cluster.Options
-	InstallWorkspaceController bool
-	KubeConfigPath             string
-	Listen                     string
-	RootDirectory              string
-	ProfilerAddress            string
-	ShardKubeconfigFile        string
-	EnableSharding             bool
-	Authentication             *kubeoptions.BuiltInAuthenticationOptions
+	EtcdClientInfo              etcd.ClientInfo
+	EtcdDirectory               string
+	EtcdPeerPort                string
+	EtcdClientPort              string
+	InstallClusterController    bool
+	ClusterControllerOptions    *cluster.Options
+	InstallWorkspaceController  bool
+	InstallAPIBindingController bool
+	WorkspaceIdleTimeout        time.Duration
+	HibernationRejectReads      bool
+	ShardCredentialsRotation    time.Duration
+	ShardCredentialsGracePeriod time.Duration
+	KubeConfigPath              string
+	AdminCertificateTTL         time.Duration
+	Listen                      string
+	RootDirectory               string
+	ProfilerAddress             string
+	ShardKubeconfigFile         string
+	EnableSharding              bool
+	ShardName                   string
+	CacheWildcardLists          bool
+	WildcardListDefaultLimit    int64
+	WildcardListMaxLimit        int64
+	WildcardListMaxBytes        int64
+	EnablePriorityAndFairness   bool
+	BootstrapManifests          string
+	BootstrapInterval           time.Duration
+	StartupStepTimeout          time.Duration
+	Authentication              *kubeoptions.BuiltInAuthenticationOptions
+	OIDC                        *OIDCConfig
+	ServiceAccounts             *ServiceAccountConfig
+	AnonymousGroups             []string
+	Audit                       *genericoptions.AuditOptions
+	EnableAuditSinks            bool
+	EnableWorkspaceClientCAs    bool
+	EnableAdmissionPlugins      []string
+	DisableAdmissionPlugins     []string
+	ProtectedNamePrefixes       []string
+	NegotiatedSchemaAdmission   string
+	MaxObjectSize               int64
+	MaxManagedFieldsSize        int64
+	MaxAnnotationsSize          int64
+	ExternalPolicyConfigFile    string
+	ExternalPolicyTimeout       time.Duration
+	ExternalPolicyFailurePolicy string
+
+	WorkspaceNotificationsConfigFile string
+	WorkspaceNotificationsFormat     string
+	WorkspaceNotificationsTimeout    time.Duration
+	WorkspaceRenameGracePeriod       time.Duration
+	HomeWorkspacesParent             string
+	HomeWorkspaceType                string
+
+	UsageRetention time.Duration
 }
 
 func BindOptions(c *Config, fs *pflag.FlagSet) *Config {
 	fs.AddFlag(pflag.PFlagFromGoFlag(flag.CommandLine.Lookup("v")))
 	fs.BoolVar(&c.InstallClusterController, "install_cluster_controller", c.InstallClusterController, "Registers the sample cluster custom resource, and the related controller to allow registering physical clusters")
 	fs.BoolVar(&c.InstallWorkspaceController, "install_workspace_controller", c.InstallWorkspaceController, "Registers the workspace custom resource, and the related controller to allow scheduling workspaces to shards")
+	fs.BoolVar(&c.InstallAPIBindingController, "install_apibinding_controller", c.InstallAPIBindingController, "Registers the APIExport, APIBinding and ReferenceGrant custom resources, and the related controller to allow sharing APIs and objects between workspaces")
+	fs.DurationVar(&c.WorkspaceIdleTimeout, "workspace_idle_timeout", c.WorkspaceIdleTimeout, "Hibernates workspaces without any request for this long, ending their watches and rejecting writes and watches to them until they are woken up by a request. Requires the workspace controller. Zero disables hibernation.")
+	fs.BoolVar(&c.HibernationRejectReads, "hibernation_reject_reads", c.HibernationRejectReads, "Rejects reads from hibernated workspaces too, until they are woken up.")
+	fs.DurationVar(&c.ShardCredentialsRotation, "shard_credentials_rotation_interval", c.ShardCredentialsRotation, "Interval at which the credentials of WorkspaceShards referencing a credentials Secret are re-issued. Requires the workspace controller.")
+	fs.DurationVar(&c.ShardCredentialsGracePeriod, "shard_credentials_grace_period", c.ShardCredentialsGracePeriod, "Duration for which the previous credentials of a WorkspaceShard are still accepted after a rotation.")
 	fs.StringVar(&c.Listen, "listen", c.Listen, "Address:port to bind to")
 	fs.StringSliceVar(&c.EtcdClientInfo.Endpoints, "etcd-servers", c.EtcdClientInfo.Endpoints, "List of external etcd servers to connect with (scheme://ip:port), comma separated. If absent an in-process etcd will be created.")
 	fs.StringVar(&c.EtcdClientInfo.KeyFile, "etcd-keyfile", c.EtcdClientInfo.KeyFile, "TLS key file used to secure etcd communication.")
@@ -77,13 +176,46 @@ func BindOptions(c *Config, fs *pflag.FlagSet) *Config {
 	fs.StringVar(&c.ProfilerAddress, "profiler-address", c.ProfilerAddress, "[Address]:port to bind the profiler to.")
 	fs.StringVar(&c.ShardKubeconfigFile, "shard-kubeconfig-file", c.ShardKubeconfigFile, "Kubeconfig holding admin(!) credentials to peer kcp shards.")
 	fs.BoolVar(&c.EnableSharding, "enable-sharding", c.EnableSharding, "Enable delegating to peer kcp shards.")
+	fs.StringVar(&c.ShardName, "shard-name", c.ShardName, "Name of the WorkspaceShard of this kcp in the root logical cluster, on which its version and feature gates are published. Requests are not proxied to peer shards incompatible with it, and workspaces are not moved between incompatible shards. Requires the workspace controller.")
+	fs.BoolVar(&c.CacheWildcardLists, "cache_wildcard_lists", c.CacheWildcardLists, "Serves the lists of resources across all logical clusters, and the lists of single logical clusters accepting a stale read (resourceVersion=0), from memory once the resources have been listed across all logical clusters.")
+	fs.Int64Var(&c.WildcardListDefaultLimit, "wildcard-list-default-limit", c.WildcardListDefaultLimit, "Number of objects per page of the lists across all logical clusters which set no limit, except those of privileged system users. Zero leaves them unpaginated.")
+	fs.Int64Var(&c.WildcardListMaxLimit, "wildcard-list-max-limit", c.WildcardListMaxLimit, "Maximum number of objects per page of the lists across all logical clusters, except those of privileged system users. Higher limits are lowered to it. Zero means unlimited.")
+	fs.Int64Var(&c.WildcardListMaxBytes, "wildcard-list-max-response-bytes", c.WildcardListMaxBytes, "Maximum size in bytes of a page of the lists across all logical clusters, except those of privileged system users. Larger pages are rejected with 413 Request Entity Too Large. Zero means unlimited.")
+	fs.BoolVar(&c.EnablePriorityAndFairness, "enable-priority-and-fairness", c.EnablePriorityAndFairness, "Queues requests with API Priority and Fairness instead of the max-in-flight limits, in a priority level for the kcp components and one for the workspaces, in which each logical cluster is queued fairly with the others.")
+	fs.StringVar(&c.BootstrapManifests, "bootstrap-manifests", c.BootstrapManifests, "Directory with one subdirectory of manifests per logical cluster, named after it. The objects of the manifests are kept in their logical clusters, and re-applied periodically to correct drift.")
+	fs.DurationVar(&c.BootstrapInterval, "bootstrap-manifests-interval", c.BootstrapInterval, "Interval at which the bootstrap manifests are re-applied.")
+	fs.DurationVar(&c.StartupStepTimeout, "startup-step-timeout", c.StartupStepTimeout, "Timeout of each step of the startup of the controllers, like the bootstrap of their CRDs and the sync of their informers. A failed step and the steps requiring it fail their startup-<step> readyz check. Zero means no timeout.")
 	fs.StringVar(&c.RootDirectory, "root_directory", c.RootDirectory, "Root directory.")
 	fs.StringVar(&c.EtcdPeerPort, "etcd_peer_port", c.EtcdPeerPort, "Port for etcd peer communication.")
 	fs.StringVar(&c.EtcdClientPort, "etcd_client_port", c.EtcdClientPort, "Port for etcd client communication.")
 	fs.StringVar(&c.KubeConfigPath, "kubeconfig_path", c.KubeConfigPath, "Path to which the administrative kubeconfig should be written at startup.")
+	fs.StringSliceVar(&c.EnableAdmissionPlugins, "enable-admission-plugins", c.EnableAdmissionPlugins, "Admission plugins that should be enabled in addition to the default ones, upstream and kcp (tenancy.kcp.dev/..., apis.kcp.dev/...) ones alike. The order of plugins in this flag does not matter.")
+	fs.StringSliceVar(&c.DisableAdmissionPlugins, "disable-admission-plugins", c.DisableAdmissionPlugins, "Admission plugins that should be disabled although they are in the default enabled plugins list, upstream and kcp ones alike. The order of plugins in this flag does not matter.")
+	fs.StringSliceVar(&c.ProtectedNamePrefixes, "protected-name-prefixes", c.ProtectedNamePrefixes, "Prefixes of the names of the system workspaces and logical clusters, which only privileged users can create, in addition to the names reserved by kcp.")
+	fs.StringVar(&c.NegotiatedSchemaAdmission, "negotiated-schema-admission", c.NegotiatedSchemaAdmission, "What to do with the objects of APIs imported from physical clusters which use fields not supported by all the Clusters they can be synced to: Warn or Reject. Requires the cluster controller.")
+	fs.Int64Var(&c.MaxObjectSize, "max-object-size", c.MaxObjectSize, "Maximum size in bytes of an object encoded as JSON, unless the WorkspaceType of its workspace sets another limit. Zero means unlimited.")
+	fs.Int64Var(&c.MaxManagedFieldsSize, "max-managed-fields-size", c.MaxManagedFieldsSize, "Maximum size in bytes of the managedFields of an object encoded as JSON, unless the WorkspaceType of its workspace sets another limit. Zero means unlimited.")
+	fs.Int64Var(&c.MaxAnnotationsSize, "max-annotations-size", c.MaxAnnotationsSize, "Maximum total size in bytes of the annotations of an object, unless the WorkspaceType of its workspace sets another limit. Zero means unlimited.")
+	fs.StringVar(&c.ExternalPolicyConfigFile, "external-policy-config-file", c.ExternalPolicyConfigFile, "Kubeconfig of an external policy endpoint, like OPA, which validates the requests to all logical clusters. It receives admission.k8s.io/v1 AdmissionReviews with the logical cluster and workspace of each request in an additional cluster field.")
+	fs.DurationVar(&c.ExternalPolicyTimeout, "external-policy-timeout", c.ExternalPolicyTimeout, "Timeout of the requests to the external policy endpoint.")
+	fs.StringVar(&c.ExternalPolicyFailurePolicy, "external-policy-failure-policy", c.ExternalPolicyFailurePolicy, "What to do with requests when the external policy endpoint fails: Fail or Ignore.")
+	fs.StringVar(&c.WorkspaceNotificationsConfigFile, "workspace-notifications-config-file", c.WorkspaceNotificationsConfigFile, "Kubeconfig of an endpoint which the workspace controller notifies of the creation, deletion and moves of workspaces, e.g. to provision external resources.")
+	fs.StringVar(&c.WorkspaceNotificationsFormat, "workspace-notifications-format", c.WorkspaceNotificationsFormat, "Format of the workspace notifications: JSON, or CloudEvents for CloudEvents in the structured content mode.")
+	fs.DurationVar(&c.WorkspaceNotificationsTimeout, "workspace-notifications-timeout", c.WorkspaceNotificationsTimeout, "Timeout of the requests to the workspace notifications endpoint.")
+	fs.DurationVar(&c.WorkspaceRenameGracePeriod, "workspace-rename-grace-period", c.WorkspaceRenameGracePeriod, "How long requests to the old path of a renamed workspace are redirected to its new path.")
+	fs.StringVar(&c.HomeWorkspacesParent, "home-workspaces-parent", c.HomeWorkspacesParent, "Logical cluster in which the home workspace of each user is created on the first request of the user to /clusters/~. Defaults to the root logical cluster. Requires the workspace controller.")
+	fs.StringVar(&c.HomeWorkspaceType, "home-workspace-type", c.HomeWorkspaceType, "WorkspaceType of the home workspaces, defining their quotas and RBAC. It is created in the parent of the home workspaces with default limits and the default roles, the admin role being bound to the user, when it doesn't exist.")
+	fs.DurationVar(&c.UsageRetention, "usage-retention", c.UsageRetention, "How long the usage of workspaces neither active nor counted is kept in the usage.json file of the root directory, from which the request counters exported as workspace_* metrics are restored at startup. Zero disables the file.")
+	fs.BoolVar(&c.EnableAuditSinks, "enable_audit_sinks", c.EnableAuditSinks, "Sends the audit events of the requests to each workspace to the AuditSinks registered in that workspace. Requires the workspace controller. Without --audit-policy-file, events are recorded at the Metadata level.")
+	fs.BoolVar(&c.EnableWorkspaceClientCAs, "enable-workspace-client-cas", c.EnableWorkspaceClientCAs, "Authenticates the client certificates issued by the ClientCertificateAuthorities registered in a workspace, for the requests to that workspace and to the workspaces beneath it only. Requires the workspace controller.")
+	fs.DurationVar(&c.AdminCertificateTTL, "admin_certificate_ttl", c.AdminCertificateTTL, "Validity of the client certificate of the admin user, issued by the client CA of the root directory and written to the administrative kubeconfig at startup.")
 
 	c.ClusterControllerOptions = cluster.BindOptions(c.ClusterControllerOptions, fs)
 
 	c.Authentication.AddFlags(fs)
+	c.Audit.AddFlags(fs)
+	c.OIDC.bindOptions(fs)
+	c.ServiceAccounts.bindOptions(fs)
+	fs.StringSliceVar(&c.AnonymousGroups, "anonymous-groups", c.AnonymousGroups, "Groups added to the anonymous user when --anonymous-auth is enabled, so that RBAC can grant unauthenticated clients the requests of automated enrollment flows.")
 	return c
 }
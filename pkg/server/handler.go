@@ -17,20 +17,37 @@ limitations under the License.
 package server
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"regexp"
 	"strings"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apiserver/pkg/endpoints/handlers/responsewriters"
 	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
 	genericapiserver "k8s.io/apiserver/pkg/server"
+	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/kubernetes/pkg/genericcontrolplane"
+
+	"github.com/kcp-dev/kcp/pkg/watchcache"
+	"github.com/kcp-dev/kcp/pkg/workspacenames"
 )
 
 var reClusterName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,78}[a-z0-9]$`)
 
-func ServeHTTP(apiHandler http.Handler, c *genericapiserver.Config) func(w http.ResponseWriter, req *http.Request) {
+// ServeHTTP sets the logical cluster addressed by the request, through its /clusters/<path>
+// path or its X-Kubernetes-Cluster header, into its context. The paths of workspaces are
+// resolved to their logical clusters, and requests to the previous path of a renamed
+// workspace are marked to be redirected to its current path. The header names logical
+// clusters only.
+func ServeHTTP(apiHandler http.Handler, c *genericapiserver.Config, resolver *workspacenames.Resolver) func(w http.ResponseWriter, req *http.Request) {
 	return func(w http.ResponseWriter, req *http.Request) {
 		var clusterName string
+		var byPath bool
 		if path := req.URL.Path; strings.HasPrefix(path, "/clusters/") {
 			path = strings.TrimPrefix(path, "/clusters/")
 			i := strings.Index(path, "/")
@@ -39,6 +56,7 @@ func ServeHTTP(apiHandler http.Handler, c *genericapiserver.Config) func(w http.
 				return
 			}
 			clusterName, path = path[:i], path[i:]
+			byPath = true
 			req.URL.Path = path
 			for i := 0; i < 2 && len(req.URL.RawPath) > 1; i++ {
 				slash := strings.Index(req.URL.RawPath[1:], "/")
@@ -60,13 +78,104 @@ func ServeHTTP(apiHandler http.Handler, c *genericapiserver.Config) func(w http.
 		case "":
 			cluster.Name = genericcontrolplane.SanitizedClusterName(c.ExternalAddress, genericcontrolplane.RootClusterName)
 		default:
-			if !reClusterName.MatchString(clusterName) {
+			if !byPath {
+				// the header names the logical cluster itself
+				if !reClusterName.MatchString(clusterName) {
+					http.Error(w, "Unknown cluster", http.StatusNotFound)
+					return
+				}
+				cluster.Name = clusterName
+				break
+			}
+			for _, name := range strings.Split(clusterName, workspacenames.PathSeparator) {
+				if !reClusterName.MatchString(name) {
+					http.Error(w, "Unknown cluster", http.StatusNotFound)
+					return
+				}
+			}
+			resolved, renamedTo, err := resolver.Resolve(clusterName)
+			if errors.Is(err, workspacenames.ErrNotInitialized) {
+				w.Header().Set("Retry-After", "1")
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			} else if errors.Is(err, workspacenames.ErrNotFound) {
 				http.Error(w, "Unknown cluster", http.StatusNotFound)
 				return
+			} else if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			cluster.Name = resolved
+			if renamedTo != "" {
+				req = req.WithContext(workspacenames.WithRenamedTo(req.Context(), renamedTo))
 			}
-			cluster.Name = clusterName
 		}
 		ctx := genericapirequest.WithCluster(req.Context(), cluster)
 		apiHandler.ServeHTTP(w, req.WithContext(ctx))
 	}
 }
+
+// WithClusterNameFieldSelector serves the LISTs and WATCHes across all logical clusters
+// selecting a single logical cluster with the metadata.clusterName field, from the
+// storage of that logical cluster only. The storage doesn't know about the field: other
+// selections on it are rejected, unless the request is served from the watch cache.
+func WithClusterNameFieldSelector(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		info, ok := genericapirequest.RequestInfoFrom(req.Context())
+		cluster := genericapirequest.ClusterFrom(req.Context())
+		query := req.URL.Query()
+		fieldSelector := query.Get("fieldSelector")
+		if !ok || cluster == nil || !cluster.Wildcard || !info.IsResourceRequest || (info.Verb != "list" && info.Verb != "watch") || !strings.Contains(fieldSelector, watchcache.ClusterNameField) {
+			handler.ServeHTTP(w, req)
+			return
+		}
+		selector, err := fields.ParseSelector(fieldSelector)
+		if err != nil {
+			// the storage rejects it
+			handler.ServeHTTP(w, req)
+			return
+		}
+
+		clusterName, rest, err := splitClusterName(selector)
+		if err != nil {
+			responsewriters.ErrorNegotiated(apierrors.NewBadRequest(err.Error()), scheme.Codecs, schema.GroupVersion{Group: info.APIGroup, Version: info.APIVersion}, w, req)
+			return
+		}
+		if rest.Empty() {
+			query.Del("fieldSelector")
+		} else {
+			query.Set("fieldSelector", rest.String())
+		}
+		req.URL.RawQuery = query.Encode()
+		ctx := genericapirequest.WithCluster(req.Context(), genericapirequest.Cluster{Name: clusterName})
+		handler.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
+
+// splitClusterName returns the logical cluster selected by the metadata.clusterName
+// field, and the selection on the other fields.
+func splitClusterName(selector fields.Selector) (string, fields.Selector, error) {
+	var clusterName string
+	var rest []fields.Selector
+	for _, r := range selector.Requirements() {
+		if r.Field != watchcache.ClusterNameField {
+			if r.Operator == selection.NotEquals {
+				rest = append(rest, fields.OneTermNotEqualSelector(r.Field, r.Value))
+			} else {
+				rest = append(rest, fields.OneTermEqualSelector(r.Field, r.Value))
+			}
+			continue
+		}
+		if r.Operator == selection.NotEquals {
+			return "", nil, fmt.Errorf("%s can only be selected with an equality outside of the watch cache", watchcache.ClusterNameField)
+		}
+		if clusterName != "" && clusterName != r.Value {
+			return "", nil, fmt.Errorf("%s can only select a single logical cluster", watchcache.ClusterNameField)
+		}
+		clusterName = r.Value
+	}
+	if !reClusterName.MatchString(clusterName) {
+		return "", nil, fmt.Errorf("invalid logical cluster name %q", clusterName)
+	}
+	return clusterName, fields.AndSelectors(rest...), nil
+}
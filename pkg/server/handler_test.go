@@ -0,0 +1,59 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+func TestSplitClusterName(t *testing.T) {
+	for _, tc := range []struct {
+		selector    string
+		wantCluster string
+		wantRest    string
+		wantErr     bool
+	}{
+		{selector: "metadata.clusterName=admin", wantCluster: "admin"},
+		{selector: "metadata.clusterName==admin,metadata.name=foo,metadata.namespace!=bar", wantCluster: "admin", wantRest: "metadata.name=foo,metadata.namespace!=bar"},
+		{selector: "metadata.clusterName=admin,metadata.clusterName=admin", wantCluster: "admin"},
+		{selector: "metadata.clusterName=admin,metadata.clusterName=user", wantErr: true},
+		{selector: "metadata.clusterName!=admin", wantErr: true},
+		{selector: "metadata.clusterName=Admin", wantErr: true},
+	} {
+		t.Run(tc.selector, func(t *testing.T) {
+			selector, err := fields.ParseSelector(tc.selector)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cluster, rest, err := splitClusterName(selector)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got cluster %q", cluster)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cluster != tc.wantCluster || rest.String() != tc.wantRest {
+				t.Errorf("expected cluster %q and selector %q, got %q and %q", tc.wantCluster, tc.wantRest, cluster, rest.String())
+			}
+		})
+	}
+}
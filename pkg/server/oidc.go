@@ -0,0 +1,129 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/pflag"
+
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/group"
+	"k8s.io/apiserver/pkg/authentication/request/bearertoken"
+	"k8s.io/apiserver/pkg/server/dynamiccertificates"
+	"k8s.io/apiserver/plugin/pkg/authenticator/token/oidc"
+	cliflag "k8s.io/component-base/cli/flag"
+)
+
+// noUsernamePrefix is the username prefix disabling the prefixing of usernames.
+const noUsernamePrefix = "-"
+
+// OIDCConfig configures the authentication of users with the ID tokens of an OpenID
+// Connect provider, such as a corporate identity provider.
+type OIDCConfig struct {
+	// IssuerURL is the HTTPS URL of the provider. OIDC authentication is disabled if empty.
+	IssuerURL string
+	// ClientID is the client ID all the tokens must be issued for.
+	ClientID string
+	// CAFile holds the CA bundle verifying the provider, instead of the host's roots.
+	CAFile string
+	// UsernameClaim is the claim holding the username.
+	UsernameClaim string
+	// UsernamePrefix prefixes the usernames, in order to avoid clashes with other
+	// authenticators. It defaults to the issuer URL followed by "#", unless the username
+	// claim is "email". "-" disables prefixing.
+	UsernamePrefix string
+	// GroupsClaim is the claim holding the groups of the user, a string or an array of
+	// strings.
+	GroupsClaim string
+	// GroupsPrefix prefixes the groups of the claim.
+	GroupsPrefix string
+	// SigningAlgs are the accepted signing algorithms.
+	SigningAlgs []string
+	// RequiredClaims must be present in the tokens with the given values.
+	RequiredClaims map[string]string
+	// ExtraGroups are added to the groups of every user authenticated by the provider, so
+	// that they can all be bound by the RBAC of the workspaces.
+	ExtraGroups []string
+}
+
+// DefaultOIDCConfig returns an OIDCConfig with OIDC authentication disabled.
+func DefaultOIDCConfig() *OIDCConfig {
+	return &OIDCConfig{
+		UsernameClaim: "sub",
+		SigningAlgs:   []string{"RS256"},
+	}
+}
+
+func (c *OIDCConfig) bindOptions(fs *pflag.FlagSet) {
+	fs.StringVar(&c.IssuerURL, "oidc-issuer-url", c.IssuerURL, "The URL of the OpenID issuer, only HTTPS scheme will be accepted. If set, it will be used to verify the OIDC JSON Web Token (JWT).")
+	fs.StringVar(&c.ClientID, "oidc-client-id", c.ClientID, "The client ID for the OpenID Connect client, must be set if oidc-issuer-url is set.")
+	fs.StringVar(&c.CAFile, "oidc-ca-file", c.CAFile, "If set, the OpenID server's certificate will be verified by one of the authorities in the oidc-ca-file, otherwise the host's root CA set will be used.")
+	fs.StringVar(&c.UsernameClaim, "oidc-username-claim", c.UsernameClaim, "The OpenID claim to use as the user name.")
+	fs.StringVar(&c.UsernamePrefix, "oidc-username-prefix", c.UsernamePrefix, "If provided, all usernames will be prefixed with this value. If not provided, username claims other than 'email' are prefixed by the issuer URL to avoid clashes. To skip any prefixing, provide the value '-'.")
+	fs.StringVar(&c.GroupsClaim, "oidc-groups-claim", c.GroupsClaim, "If provided, the name of a custom OpenID Connect claim for specifying user groups. The claim value is expected to be a string or array of strings.")
+	fs.StringVar(&c.GroupsPrefix, "oidc-groups-prefix", c.GroupsPrefix, "If provided, all groups will be prefixed with this value to prevent conflicts with other authentication strategies.")
+	fs.StringSliceVar(&c.SigningAlgs, "oidc-signing-algs", c.SigningAlgs, "Comma-separated list of allowed JOSE asymmetric signing algorithms.")
+	fs.Var(cliflag.NewMapStringStringNoSplit(&c.RequiredClaims), "oidc-required-claim", "A key=value pair that describes a required claim in the ID Token. If set, the claim is verified to be present in the ID Token with a matching value. Repeat this flag to specify multiple claims.")
+	fs.StringSliceVar(&c.ExtraGroups, "oidc-extra-groups", c.ExtraGroups, "Groups added to every user authenticated with an OpenID Connect token, in addition to those of the groups claim.")
+}
+
+// newOIDCAuthenticator returns a request authenticator accepting the ID tokens of the
+// OIDC provider, or nil if OIDC authentication is disabled.
+func newOIDCAuthenticator(c *OIDCConfig) (authenticator.Request, error) {
+	if c == nil || c.IssuerURL == "" {
+		return nil, nil
+	}
+	if c.ClientID == "" {
+		return nil, errors.New("--oidc-client-id is required with --oidc-issuer-url")
+	}
+
+	opts := oidc.Options{
+		IssuerURL:            c.IssuerURL,
+		ClientID:             c.ClientID,
+		UsernameClaim:        c.UsernameClaim,
+		UsernamePrefix:       c.UsernamePrefix,
+		GroupsClaim:          c.GroupsClaim,
+		GroupsPrefix:         c.GroupsPrefix,
+		SupportedSigningAlgs: c.SigningAlgs,
+		RequiredClaims:       c.RequiredClaims,
+	}
+	if opts.UsernamePrefix == "" && opts.UsernameClaim != "email" {
+		opts.UsernamePrefix = opts.IssuerURL + "#"
+	}
+	if opts.UsernamePrefix == noUsernamePrefix {
+		opts.UsernamePrefix = ""
+	}
+	if c.CAFile != "" {
+		ca, err := dynamiccertificates.NewDynamicCAContentFromFile("oidc-authenticator", c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the OIDC CA file: %w", err)
+		}
+		opts.CAContentProvider = ca
+	}
+
+	tokenAuthenticator, err := oidc.New(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the OIDC authenticator: %w", err)
+	}
+	var token authenticator.Token = tokenAuthenticator
+	if len(c.ExtraGroups) > 0 {
+		token = group.NewTokenGroupAdder(token, c.ExtraGroups)
+	}
+	return bearertoken.New(token), nil
+}
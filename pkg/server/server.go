@@ -20,6 +20,7 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net"
@@ -38,30 +39,86 @@ import (
 	apiextensionsv1client "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/typed/apiextensions/v1"
 	crdexternalversions "k8s.io/apiextensions-apiserver/pkg/client/informers/externalversions"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apiserver/pkg/admission"
+	auditinternal "k8s.io/apiserver/pkg/apis/audit"
+	"k8s.io/apiserver/pkg/audit"
+	auditpolicy "k8s.io/apiserver/pkg/audit/policy"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/request/union"
+	authorizerunion "k8s.io/apiserver/pkg/authorization/union"
 	genericapiserver "k8s.io/apiserver/pkg/server"
+	genericoptions "k8s.io/apiserver/pkg/server/options"
 	"k8s.io/apiserver/pkg/storage/storagebackend"
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
+	utilflowcontrol "k8s.io/apiserver/pkg/util/flowcontrol"
 	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/metadata"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
 	"k8s.io/kubernetes/pkg/controller/namespace"
 	"k8s.io/kubernetes/pkg/genericcontrolplane"
+	"k8s.io/kubernetes/pkg/genericcontrolplane/clientutils"
 	"k8s.io/kubernetes/pkg/genericcontrolplane/options"
 
-	"github.com/kcp-dev/kcp/config"
+	"github.com/kcp-dev/kcp/pkg/admission/externalpolicy"
+	"github.com/kcp-dev/kcp/pkg/admission/negotiatedschemas"
+	apisapi "github.com/kcp-dev/kcp/pkg/apis/apis"
 	tenancyapi "github.com/kcp-dev/kcp/pkg/apis/tenancy"
+	"github.com/kcp-dev/kcp/pkg/authorization/workspacecontent"
+	"github.com/kcp-dev/kcp/pkg/backup"
 	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	kcpscheme "github.com/kcp-dev/kcp/pkg/client/clientset/versioned/scheme"
+	"github.com/kcp-dev/kcp/pkg/client/clusterrestmapper"
+	"github.com/kcp-dev/kcp/pkg/client/discoverycache"
 	kcpexternalversions "github.com/kcp-dev/kcp/pkg/client/informers/externalversions"
+	"github.com/kcp-dev/kcp/pkg/crdprojection"
+	"github.com/kcp-dev/kcp/pkg/crossworkspace"
 	"github.com/kcp-dev/kcp/pkg/etcd"
+	"github.com/kcp-dev/kcp/pkg/events"
+	"github.com/kcp-dev/kcp/pkg/homeworkspace"
+	"github.com/kcp-dev/kcp/pkg/listlimits"
+	"github.com/kcp-dev/kcp/pkg/podproxy"
+	"github.com/kcp-dev/kcp/pkg/priorityandfairness"
+	"github.com/kcp-dev/kcp/pkg/reconciler/apibinding"
+	"github.com/kcp-dev/kcp/pkg/reconciler/apiinheritance"
+	"github.com/kcp-dev/kcp/pkg/reconciler/auditsink"
+	"github.com/kcp-dev/kcp/pkg/reconciler/bootstrap"
+	"github.com/kcp-dev/kcp/pkg/reconciler/clientca"
+	"github.com/kcp-dev/kcp/pkg/reconciler/crd"
+	"github.com/kcp-dev/kcp/pkg/reconciler/garbagecollector"
+	"github.com/kcp-dev/kcp/pkg/reconciler/hibernation"
+	"github.com/kcp-dev/kcp/pkg/reconciler/placement"
+	"github.com/kcp-dev/kcp/pkg/reconciler/resourceexclusion"
+	"github.com/kcp-dev/kcp/pkg/reconciler/shardcredentials"
+	"github.com/kcp-dev/kcp/pkg/reconciler/shardversion"
 	"github.com/kcp-dev/kcp/pkg/reconciler/workspace"
+	"github.com/kcp-dev/kcp/pkg/reconciler/workspacelimits"
+	"github.com/kcp-dev/kcp/pkg/reconciler/workspacerbac"
+	"github.com/kcp-dev/kcp/pkg/serviceaccount"
 	"github.com/kcp-dev/kcp/pkg/sharding"
+	"github.com/kcp-dev/kcp/pkg/startup"
+	"github.com/kcp-dev/kcp/pkg/syncer"
+	"github.com/kcp-dev/kcp/pkg/tunnel"
+	"github.com/kcp-dev/kcp/pkg/usage"
+	"github.com/kcp-dev/kcp/pkg/watchcache"
+	"github.com/kcp-dev/kcp/pkg/workspacenames"
 )
 
 const resyncPeriod = 10 * time.Hour
 
+// usageInterval is how often the objects of every workspace are counted.
+const usageInterval = 5 * time.Minute
+
+// usageSaveInterval is how often the usage of the workspaces is saved to the root directory.
+const usageSaveInterval = time.Minute
+
 // Server manages the configuration and kcp api-server. It allows callers to easily use kcp
 // as a library rather than as a single binary. Using its constructor function, you can easily
 // setup a new api-server and start it:
@@ -80,6 +137,13 @@ type Server struct {
 	cfg              *Config
 	postStartHooks   []postStartHookEntry
 	preShutdownHooks []preShutdownHookEntry
+	admissionPlugins []admissionPluginEntry
+
+	// discoveryCache is shared by the controllers discovering the resources of logical
+	// clusters.
+	discoveryCache *discoverycache.Cache
+	// restMapper maps kinds to resources in each logical cluster, from the discovery cache.
+	restMapper *clusterrestmapper.Mapper
 }
 
 // postStartHookEntry groups a PostStartHookFunc with a name. We're not storing these hooks
@@ -181,7 +245,49 @@ func (s *Server) Run(ctx context.Context) error {
 
 	serverOptions := options.NewServerRunOptions()
 
-	serverOptions.Authentication = s.cfg.Authentication
+	// the admin authenticates with a client certificate of its own, instead of sharing the
+	// loopback token, so the client CA bundle trusted by the server includes its CA
+	authentication := *s.cfg.Authentication
+	var extraClientCA string
+	if authentication.ClientCert != nil {
+		extraClientCA = authentication.ClientCert.ClientCA
+	}
+	admin, clientCABundle, err := bootstrapAdmin(dir, s.cfg.AdminCertificateTTL, extraClientCA)
+	if err != nil {
+		return err
+	}
+	authentication.ClientCert = &genericoptions.ClientCertAuthenticationOptions{ClientCA: clientCABundle}
+	// service account tokens are bound to the audience of their logical cluster, so the
+	// API audiences are only the default audiences of the tokens requested without any
+	authentication.APIAudiences = nil
+	serverOptions.Authentication = &authentication
+
+	// the plugins of kcp run after the upstream ones, followed by those of the embedder
+	referenceResolver := crossworkspace.NewResolver()
+	placementDefaulter := placement.NewDefaulter()
+	negotiatedSchemas := negotiatedschemas.NewSchemas()
+	workspaceLimits := workspacelimits.NewRegistry(workspacelimits.Limits{
+		MaxObjectSize:        s.cfg.MaxObjectSize,
+		MaxManagedFieldsSize: s.cfg.MaxManagedFieldsSize,
+		MaxAnnotationsSize:   s.cfg.MaxAnnotationsSize,
+	})
+	externalPolicyFailurePolicy, err := externalpolicy.ParseFailurePolicy(s.cfg.ExternalPolicyFailurePolicy)
+	if err != nil {
+		return err
+	}
+	externalPolicy, err := externalpolicy.NewWebhook(s.cfg.ExternalPolicyConfigFile, s.cfg.ExternalPolicyTimeout, externalPolicyFailurePolicy)
+	if err != nil {
+		return err
+	}
+	workspaceNames := workspacenames.NewResolver()
+	builtInPlugins, err := builtInAdmissionPlugins(s.cfg, referenceResolver, placementDefaulter, negotiatedSchemas, workspaceLimits, externalPolicy, workspaceNames)
+	if err != nil {
+		return err
+	}
+	registerAdmissionPlugins(serverOptions.Admission, builtInPlugins...)
+	registerAdmissionPlugins(serverOptions.Admission, s.admissionPlugins...)
+	serverOptions.Admission.EnablePlugins = s.cfg.EnableAdmissionPlugins
+	serverOptions.Admission.DisablePlugins = s.cfg.DisableAdmissionPlugins
 
 	host, port, err := net.SplitHostPort(s.cfg.Listen)
 	if err != nil {
@@ -204,16 +310,198 @@ func (s *Server) Run(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	// the loopback client config only exists once the handler chain is built, so the
+	// clients looking up service accounts are set up there
+	var serviceAccountClients *kubernetes.Cluster
+	signingKeyFile := s.cfg.ServiceAccounts.signingKeyFile(dir)
+	tokenIssuer, err := serviceaccount.NewTokenIssuer(signingKeyFile, serviceaccount.Options{
+		Issuers:      s.cfg.ServiceAccounts.Issuers,
+		KeyFiles:     s.cfg.ServiceAccounts.KeyFiles,
+		APIAudiences: s.cfg.Authentication.APIAudiences,
+	}, func(clusterName string) (kubernetes.Interface, error) {
+		if serviceAccountClients == nil {
+			return nil, errors.New("service account clients are not initialized")
+		}
+		return serviceAccountClients.Cluster(clusterName), nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := writePublicKey(signingKeyFile, dir); err != nil {
+		return err
+	}
+	usageTracker := usage.NewTracker()
+	if s.cfg.UsageRetention > 0 {
+		if err := usageTracker.Persist(ctx, filepath.Join(dir, "usage.json"), usageSaveInterval, s.cfg.UsageRetention); err != nil {
+			return err
+		}
+	}
+	if err := legacyregistry.CustomRegister(usage.NewCollector(usageTracker)); err != nil {
+		klog.Warningf("Failed to register the usage metrics of workspaces: %v", err)
+	}
+	hibernationRegistry := hibernation.NewRegistry()
+	resourceExclusionRegistry := resourceexclusion.NewRegistry()
+	crdProjections := crdprojection.NewRegistry()
+	workspaceAuthorizer := workspacecontent.NewAuthorizer()
+	homeWorkspaces := homeworkspace.NewProvisioner()
+	tunnelServer := tunnel.NewServer()
+	shardAuthenticator := shardcredentials.NewAuthenticator()
+	clientCAs := clientca.NewAuthenticator()
+	bootstrapTokens := &bootstrapTokenAuthenticator{}
+	// the audit backends and policy are built upfront, in order to fail on invalid options
+	if errs := s.cfg.Audit.Validate(); len(errs) > 0 {
+		return utilerrors.NewAggregate(errs)
+	}
+	// the options of the cluster controller are validated upfront too, since the handler chain
+	// uses some of them
+	var namespaceStrategy syncer.NamespaceStrategy
+	if s.cfg.InstallClusterController {
+		if err := s.cfg.ClusterControllerOptions.Validate(); err != nil {
+			return err
+		}
+		strategy, err := syncer.ParseNamespaceStrategy(s.cfg.ClusterControllerOptions.SyncerNamespaceStrategy)
+		if err != nil {
+			return err
+		}
+		namespaceStrategy = strategy
+	}
+	auditConfig := &genericapiserver.Config{}
+	if err := s.cfg.Audit.ApplyTo(auditConfig); err != nil {
+		return err
+	}
+	auditSinks := auditsink.NewBackend()
+	if s.cfg.EnableAuditSinks {
+		if auditConfig.AuditPolicyChecker == nil {
+			auditConfig.AuditPolicyChecker = auditpolicy.FakeChecker(auditinternal.LevelMetadata, nil)
+		}
+		if auditConfig.AuditBackend == nil {
+			auditConfig.AuditBackend = auditSinks
+		} else {
+			auditConfig.AuditBackend = audit.Union(auditConfig.AuditBackend, auditSinks)
+		}
+	}
+	enableBootstrapTokens := s.cfg.Authentication.BootstrapToken != nil && s.cfg.Authentication.BootstrapToken.Enable
+	oidcAuthenticator, err := newOIDCAuthenticator(s.cfg.OIDC)
+	if err != nil {
+		return err
+	}
+	// the handler chain is built once per server of the chain, which share the flow control
+	// created with the loopback client config of the first one
+	var flowControl utilflowcontrol.Interface
 	serverOptions.BuildHandlerChainFunc = func(apiHandler http.Handler, c *genericapiserver.Config) (secure http.Handler) {
 		// we want a request to hit the chain like:
 		// - lcluster handler (this package's ServeHTTP)
 		// - shard proxy (sharding.ServeHTTP)
 		// - original handler chain
-		// the lcluster handler is a pass-through, not a delegate, so the wrapping looks weird
+		if clients, err := kubernetes.NewClusterForConfig(c.LoopbackClientConfig); err != nil {
+			klog.Errorf("failed to create service account clients: %v", err)
+		} else {
+			serviceAccountClients = clients
+		}
+		authenticators := []authenticator.Request{tokenIssuer.Authenticator(), shardAuthenticator.Request()}
+		if oidcAuthenticator != nil {
+			// users of the OIDC provider carry the groups of their tokens into every request
+			authenticators = append(authenticators, oidcAuthenticator)
+		}
+		if enableBootstrapTokens {
+			authenticators = append(authenticators, bootstrapTokens.Request())
+		}
+		if s.cfg.EnableWorkspaceClientCAs {
+			// the client CAs of the tenants only authenticate requests to their workspaces
+			authenticators = append(authenticators, clientCAs)
+		}
+		if c.Authentication.Authenticator != nil {
+			authenticators = append(authenticators, c.Authentication.Authenticator)
+		}
+		c.Authentication.Authenticator = union.New(authenticators...)
+		if len(s.cfg.AnonymousGroups) > 0 {
+			c.Authentication.Authenticator = withAnonymousGroups(c.Authentication.Authenticator, s.cfg.AnonymousGroups)
+		}
+		// the scope of scoped tokens, and the WorkspaceRoleBindings of the parent workspace are
+		// checked before the RBAC of a workspace
+		c.Authorization.Authorizer = authorizerunion.New(
+			serviceaccount.ScopeAuthorizer(),
+			// shards forward requests impersonating their users
+			shardcredentials.ImpersonationAuthorizer(),
+			workspaceAuthorizer,
+			c.Authorization.Authorizer,
+		)
+		// wildcard requests selecting a single logical cluster are served from its storage
+		apiHandler = WithClusterNameFieldSelector(apiHandler)
+		if s.cfg.CacheWildcardLists {
+			if listCache, err := watchcache.NewCache(c.LoopbackClientConfig, ctx.Done()); err != nil {
+				klog.Errorf("failed to create the list cache: %v", err)
+			} else {
+				// lists are served from the cache after authentication and authorization too
+				apiHandler = watchcache.WithCache(apiHandler, listCache)
+			}
+		}
+		// wildcard lists are paginated and kept small before they are served, from the cache or not
+		apiHandler = listlimits.WithLimits(apiHandler, listlimits.Limits{
+			DefaultLimit:     s.cfg.WildcardListDefaultLimit,
+			MaxLimit:         s.cfg.WildcardListMaxLimit,
+			MaxResponseBytes: s.cfg.WildcardListMaxBytes,
+		})
+		// the CRDs inherited by workspaces are projected into the wildcard lists and watches of
+		// CRDs, which the apiextensions server serves them from
+		apiHandler = crdprojection.WithProjections(apiHandler, crdProjections)
+		// token requests are served after authentication and authorization of the request
+		apiHandler = serviceaccount.WithTokenRequest(apiHandler, tokenIssuer)
+		// as are the kubeconfigs and scoped tokens minted for the requesting user
+		apiHandler = serviceaccount.WithKubeconfig(apiHandler, tokenIssuer, c.LoopbackClientConfig)
+		apiHandler = serviceaccount.WithScopedTokens(apiHandler, tokenIssuer)
+		// and the reviews of the workspaces a user has access to
+		apiHandler = workspacecontent.WithWorkspaceAccessReviews(apiHandler, workspaceAuthorizer)
+		apiHandler = workspacecontent.WithWorkspaceTree(apiHandler, workspaceAuthorizer)
+		apiHandler = workspacecontent.WithClustersIndex(apiHandler, workspaceAuthorizer)
+		// and the export and import of workspace archives
+		if archives, err := backup.NewArchives(ctx, c.LoopbackClientConfig); err != nil {
+			klog.Errorf("failed to create the workspace archives: %v", err)
+		} else {
+			apiHandler = backup.WithWorkspaceArchives(apiHandler, archives)
+		}
+		c.LongRunningFunc = backup.LongRunning(c.LongRunningFunc)
+		// and the rename of workspaces
+		apiHandler = workspacenames.WithWorkspaceRename(apiHandler, c.LoopbackClientConfig)
+		// and the subresources of the Pods run in physical clusters
+		if s.cfg.InstallClusterController {
+			apiHandler = podproxy.WithPodSubresources(apiHandler, podproxy.NewProxy(c.LoopbackClientConfig, tunnelServer, namespaceStrategy))
+		}
+		// so are the tunnels opened by the syncers of clusters behind firewalls
+		apiHandler = tunnel.WithTunnels(apiHandler, tunnelServer)
+		apiHandler = usage.WithUsage(apiHandler, usageTracker)
+		apiHandler = hibernation.WithHibernation(apiHandler, hibernationRegistry, s.cfg.HibernationRejectReads)
+		apiHandler = resourceexclusion.WithResourceExclusion(apiHandler, resourceExclusionRegistry)
+		// requests rejected while hibernated are tracked too, in order to wake the workspace up
+		apiHandler = usageTracker.WithRequestTracking(apiHandler)
+		// requests to the previous path of a renamed workspace are redirected once authorized
+		apiHandler = workspacenames.WithRedirects(apiHandler)
 		if s.cfg.EnableSharding {
 			apiHandler = http.HandlerFunc(sharding.ServeHTTP(apiHandler, clientLoader))
 		}
-		apiHandler = http.HandlerFunc(ServeHTTP(genericapiserver.DefaultBuildHandlerChain(apiHandler, c), c))
+		c.AuditBackend, c.AuditPolicyChecker = auditConfig.AuditBackend, auditConfig.AuditPolicyChecker
+		if s.cfg.EnablePriorityAndFairness && flowControl == nil {
+			if fc, informerFactory, err := priorityandfairness.New(c.LoopbackClientConfig, c.MaxRequestsInFlight+c.MaxMutatingRequestsInFlight, c.RequestTimeout/4); err != nil {
+				klog.Errorf("failed to create the priority and fairness flow control, falling back to max-in-flight limits: %v", err)
+			} else {
+				flowControl = fc
+				s.AddPostStartHook("start-priority-and-fairness-informers", func(context genericapiserver.PostStartHookContext) error {
+					informerFactory.Start(context.StopCh)
+					return nil
+				})
+			}
+		}
+		if flowControl != nil {
+			// requests are queued fairly after authentication, before authorization
+			c.FlowControl = flowControl
+		}
+		secureHandler := genericapiserver.DefaultBuildHandlerChain(apiHandler, c)
+		if c.AuditBackend != nil {
+			// audit events record their logical cluster, which tenant sinks filter on
+			secureHandler = auditsink.WithClusterAnnotation(secureHandler)
+		}
+		apiHandler = http.HandlerFunc(ServeHTTP(secureHandler, c, workspaceNames))
+		apiHandler = homeworkspace.WithHomeWorkspace(apiHandler, c.Authentication.Authenticator, c.Authorization.Authorizer, homeWorkspaces)
 
 		return apiHandler
 	}
@@ -235,10 +523,18 @@ func (s *Server) Run(ctx context.Context) error {
 		return err
 	}
 
+	userServer := server.LoopbackClientConfig.Host + "/clusters/" + genericcontrolplane.SanitizedClusterName(server.ExternalAddress, "user")
+	if s.cfg.InstallWorkspaceController {
+		userServer = server.LoopbackClientConfig.Host + "/clusters/" + homeworkspace.ClusterName
+	}
+
 	//Create Client and Shared
 	var clientConfig clientcmdapi.Config
 	clientConfig.AuthInfos = map[string]*clientcmdapi.AuthInfo{
-		"loopback": {Token: server.LoopbackClientConfig.BearerToken},
+		AdminUserName: {
+			ClientCertificateData: admin.certPEM,
+			ClientKeyData:         admin.keyPEM,
+		},
 	}
 	clientConfig.Clusters = map[string]*clientcmdapi.Cluster{
 		// cross-cluster is the virtual cluster running by default
@@ -253,17 +549,18 @@ func (s *Server) Run(ctx context.Context) error {
 			CertificateAuthorityData: server.LoopbackClientConfig.CAData,
 			TLSServerName:            server.LoopbackClientConfig.TLSClientConfig.ServerName,
 		},
-		// user is a virtual cluster that is lazily instantiated
+		// user is the home workspace of the admin user, created on its first request, or a
+		// virtual cluster that is lazily instantiated without the workspace controller
 		"user": {
-			Server:                   server.LoopbackClientConfig.Host + "/clusters/" + genericcontrolplane.SanitizedClusterName(server.ExternalAddress, "user"),
+			Server:                   userServer,
 			CertificateAuthorityData: server.LoopbackClientConfig.CAData,
 			TLSServerName:            server.LoopbackClientConfig.TLSClientConfig.ServerName,
 		},
 	}
 	clientConfig.Contexts = map[string]*clientcmdapi.Context{
-		"cross-cluster": {Cluster: "cross-cluster", AuthInfo: "loopback"},
-		"admin":         {Cluster: "admin", AuthInfo: "loopback"},
-		"user":          {Cluster: "user", AuthInfo: "loopback"},
+		"cross-cluster": {Cluster: "cross-cluster", AuthInfo: AdminUserName},
+		"admin":         {Cluster: "admin", AuthInfo: AdminUserName},
+		"user":          {Cluster: "user", AuthInfo: AdminUserName},
 	}
 	clientConfig.CurrentContext = "admin"
 
@@ -304,6 +601,22 @@ func (s *Server) Run(ctx context.Context) error {
 		return err
 	}
 
+	// the discovery of each logical cluster is cached for all the controllers of the
+	// process, until the CRDs of the logical cluster change
+	discoveryAPIExtensionsClient, err := apiextensionsclient.NewClusterForConfig(server.LoopbackClientConfig)
+	if err != nil {
+		return err
+	}
+	discoveryCRDSharedInformerFactory := crdexternalversions.NewSharedInformerFactoryWithOptions(discoveryAPIExtensionsClient.Cluster("*"), resyncPeriod)
+	s.discoveryCache = discoverycache.NewCache(server.LoopbackClientConfig, discoveryCRDSharedInformerFactory.Apiextensions().V1().CustomResourceDefinitions())
+	s.restMapper = clusterrestmapper.NewMapper(s.discoveryCache, discoveryCRDSharedInformerFactory.Apiextensions().V1().CustomResourceDefinitions())
+	if err := server.AddPostStartHook("start-discovery-cache", func(context genericapiserver.PostStartHookContext) error {
+		discoveryCRDSharedInformerFactory.Start(context.StopCh)
+		return nil
+	}); err != nil {
+		return err
+	}
+
 	// Add our custom hooks to the underlying api server
 	for _, entry := range s.postStartHooks {
 		err := server.AddPostStartHook(entry.name, entry.hook)
@@ -318,11 +631,11 @@ func (s *Server) Run(ctx context.Context) error {
 		}
 	}
 
-	if s.cfg.InstallClusterController {
-		if err := s.cfg.ClusterControllerOptions.Validate(); err != nil {
-			return err
-		}
+	// the controllers are started in steps, concurrently, as soon as the steps they require
+	// are done
+	controllers := startup.NewOrchestrator()
 
+	if s.cfg.InstallClusterController {
 		adminConfig, err := clientcmd.NewNonInteractiveClientConfig(clientConfig, "admin", &clientcmd.ConfigOverrides{}, nil).ClientConfig()
 		if err != nil {
 			return err
@@ -331,6 +644,24 @@ func (s *Server) Run(ctx context.Context) error {
 		kcpSharedInformerFactory := kcpexternalversions.NewSharedInformerFactoryWithOptions(kcpclient.NewForConfigOrDie(adminConfig), resyncPeriod)
 		crdSharedInformerFactory := crdexternalversions.NewSharedInformerFactoryWithOptions(apiextensionsclient.NewForConfigOrDie(adminConfig), resyncPeriod)
 
+		// created objects are labeled for the Cluster of their namespace or Placement
+		kubeClient, err := kubernetes.NewClusterForConfig(adminConfig)
+		if err != nil {
+			return err
+		}
+		kubeSharedInformerFactory := informers.NewSharedInformerFactoryWithOptions(kubeClient.Cluster("*"), resyncPeriod)
+		if err := placementDefaulter.Initialize(
+			kcpSharedInformerFactory.Cluster().V1alpha1().Placements(),
+			kcpSharedInformerFactory.Cluster().V1alpha1().Clusters(),
+			kubeSharedInformerFactory.Core().V1().Namespaces(),
+			s.cfg.ClusterControllerOptions.ResourcesToSync,
+		); err != nil {
+			return err
+		}
+		if err := negotiatedSchemas.Initialize(kcpSharedInformerFactory.Apiresource().V1alpha1().APIResourceImports()); err != nil {
+			return err
+		}
+
 		kubeconfig := clientConfig.DeepCopy()
 		for _, cluster := range kubeconfig.Clusters {
 			hostURL, err := url.Parse(cluster.Server)
@@ -341,12 +672,53 @@ func (s *Server) Run(ctx context.Context) error {
 			cluster.Server = hostURL.String()
 		}
 
-		if err := server.AddPostStartHook("install-cluster-controller", func(context genericapiserver.PostStartHookContext) error {
-			adaptedCtx := adaptContext(context)
-			return s.cfg.ClusterControllerOptions.Complete(*kubeconfig, kcpSharedInformerFactory, crdSharedInformerFactory).Start(adaptedCtx)
-		}); err != nil {
+		// the CRDs of the cluster controller are bootstrapped in the home workspace of the
+		// admin too, which the workspace controllers create
+		var requires []string
+		if s.cfg.InstallWorkspaceController {
+			requires = append(requires, "workspace-controllers")
+		}
+		controllers.Add(startup.Step{
+			Name:     "cluster-controller",
+			Requires: requires,
+			Timeout:  s.cfg.StartupStepTimeout,
+			Run: func(_ context.Context) error {
+				clusterControllerConfig := s.cfg.ClusterControllerOptions.Complete(*kubeconfig, kcpSharedInformerFactory, crdSharedInformerFactory)
+				clusterControllerConfig.Tunnels = tunnelServer
+				kubeSharedInformerFactory.Start(ctx.Done())
+				return clusterControllerConfig.Start(ctx)
+			},
+		})
+	}
+
+	if enableBootstrapTokens {
+		adminConfig, err := clientcmd.NewNonInteractiveClientConfig(clientConfig, "admin", &clientcmd.ConfigOverrides{}, nil).ClientConfig()
+		if err != nil {
 			return err
 		}
+		kubeClient, err := kubernetes.NewClusterForConfig(adminConfig)
+		if err != nil {
+			return err
+		}
+
+		// bootstrap tokens are kept in the kube-system namespace of the root logical cluster
+		rootClusterName := genericcontrolplane.SanitizedClusterName(server.ExternalAddress, genericcontrolplane.RootClusterName)
+		bootstrapInformerFactory := informers.NewSharedInformerFactoryWithOptions(kubeClient.Cluster(rootClusterName), resyncPeriod, informers.WithNamespace(metav1.NamespaceSystem))
+		secretInformer := bootstrapInformerFactory.Core().V1().Secrets()
+		secretInformer.Informer()
+
+		syncBootstrapInformers := startup.SyncInformers(ctx.Done(), bootstrapInformerFactory)
+		controllers.Add(startup.Step{
+			Name:    "bootstrap-token-authenticator",
+			Timeout: s.cfg.StartupStepTimeout,
+			Run: func(stepCtx context.Context) error {
+				if err := syncBootstrapInformers(stepCtx); err != nil {
+					return err
+				}
+				bootstrapTokens.initialize(rootClusterName, secretInformer.Lister())
+				return nil
+			},
+		})
 	}
 
 	if s.cfg.InstallWorkspaceController {
@@ -366,35 +738,347 @@ func (s *Server) Run(ctx context.Context) error {
 
 		kcpSharedInformerFactory := kcpexternalversions.NewSharedInformerFactoryWithOptions(crossClusterClient, resyncPeriod)
 
+		kubeClient, err := kubernetes.NewClusterForConfig(adminConfig)
+		if err != nil {
+			return err
+		}
+		notificationsFormat, err := workspace.ParseNotificationFormat(s.cfg.WorkspaceNotificationsFormat)
+		if err != nil {
+			return err
+		}
+		notifier, err := workspace.NewNotifier(s.cfg.WorkspaceNotificationsConfigFile, notificationsFormat, s.cfg.WorkspaceNotificationsTimeout)
+		if err != nil {
+			return err
+		}
 		workspaceController, err := workspace.NewController(
 			kcpClient,
 			kcpSharedInformerFactory.Tenancy().V1alpha1().Workspaces(),
 			kcpSharedInformerFactory.Tenancy().V1alpha1().WorkspaceShards(),
+			events.NewRecorder(ctx, kubeClient, kcpscheme.Scheme, "workspace-controller"),
+			notifier,
+			s.cfg.WorkspaceRenameGracePeriod,
+		)
+		if err != nil {
+			return err
+		}
+
+		apiExtensionsClient, err := apiextensionsclient.NewClusterForConfig(adminConfig)
+		if err != nil {
+			return err
+		}
+		crdSharedInformerFactory := crdexternalversions.NewSharedInformerFactoryWithOptions(apiExtensionsClient.Cluster(clusterAll), resyncPeriod)
+
+		apiInheritanceController, err := apiinheritance.NewController(
+			kcpSharedInformerFactory.Tenancy().V1alpha1().Workspaces(),
+			crdSharedInformerFactory.Apiextensions().V1().CustomResourceDefinitions(),
+			crdProjections,
+		)
+		if err != nil {
+			return err
+		}
+
+		workspaceRBACController, err := workspacerbac.NewController(
+			kcpClient,
+			kubeClient,
+			kcpSharedInformerFactory.Tenancy().V1alpha1().Workspaces(),
+			kcpSharedInformerFactory.Tenancy().V1alpha1().WorkspaceTypes(),
 		)
 		if err != nil {
 			return err
 		}
 
-		if err := server.AddPostStartHook("install-workspace-controller", func(context genericapiserver.PostStartHookContext) error {
-			// Register CRDs in both the admin and user logical clusters
-			requiredCrds := []metav1.GroupKind{
-				{Group: tenancyapi.GroupName, Kind: "workspaces"},
-				{Group: tenancyapi.GroupName, Kind: "workspaceshards"},
+		dynamicClient, err := dynamic.NewClusterForConfig(adminConfig)
+		if err != nil {
+			return err
+		}
+		usageController := usage.NewController(
+			dynamicClient,
+			s.discoveryCache.ServerPreferredResources,
+			kcpSharedInformerFactory.Tenancy().V1alpha1().Workspaces(),
+			usageTracker,
+			usageInterval,
+		)
+
+		rootClusterName := genericcontrolplane.SanitizedClusterName(server.ExternalAddress, genericcontrolplane.RootClusterName)
+		rootKubeSharedInformerFactory := informers.NewSharedInformerFactoryWithOptions(kubeClient.Cluster(rootClusterName), resyncPeriod)
+		var credentialsConsumers []shardcredentials.ConfigConsumer
+		if s.cfg.EnableSharding {
+			credentialsConsumers = append(credentialsConsumers, clientLoader.Set)
+		}
+		shardCredentialsController, err := shardcredentials.NewController(
+			kubeClient,
+			rootClusterName,
+			server.LoopbackClientConfig,
+			kcpSharedInformerFactory.Tenancy().V1alpha1().WorkspaceShards(),
+			rootKubeSharedInformerFactory.Core().V1().Secrets(),
+			shardAuthenticator,
+			shardcredentials.Options{
+				Interval:    s.cfg.ShardCredentialsRotation,
+				GracePeriod: s.cfg.ShardCredentialsGracePeriod,
+			},
+			credentialsConsumers...,
+		)
+		if err != nil {
+			return err
+		}
+
+		var shardVersionController *shardversion.Controller
+		if s.cfg.ShardName != "" {
+			shardVersionController = shardversion.NewController(
+				kcpClient,
+				rootClusterName,
+				s.cfg.ShardName,
+				kcpSharedInformerFactory.Tenancy().V1alpha1().WorkspaceShards(),
+				utilfeature.DefaultMutableFeatureGate,
+			)
+			if s.cfg.EnableSharding {
+				clientLoader.SetCompatibility(shardversion.ProxyCompatibility(
+					kcpSharedInformerFactory.Tenancy().V1alpha1().WorkspaceShards().Lister(),
+					rootClusterName,
+					s.cfg.ShardName,
+					genericcontrolplane.SanitizeClusterId(server.ExternalAddress),
+				))
 			}
-			crdClient := apiextensionsv1client.NewForConfigOrDie(adminConfig).CustomResourceDefinitions()
-			if err := config.BootstrapCustomResourceDefinitions(ctx, crdClient, requiredCrds); err != nil {
+		}
+
+		if s.cfg.EnableAuditSinks {
+			auditsink.NewController(kcpSharedInformerFactory.Tenancy().V1alpha1().AuditSinks(), auditSinks)
+		}
+		if s.cfg.EnableWorkspaceClientCAs {
+			if err := clientCAs.Install(
+				kcpSharedInformerFactory.Tenancy().V1alpha1().Workspaces(),
+				kcpSharedInformerFactory.Tenancy().V1alpha1().ClientCertificateAuthorities(),
+			); err != nil {
 				return err
 			}
+		}
 
-			kcpSharedInformerFactory.Start(context.StopCh)
-			kcpSharedInformerFactory.WaitForCacheSync(context.StopCh)
+		if err := workspaceAuthorizer.Install(
+			kcpSharedInformerFactory.Tenancy().V1alpha1().Workspaces(),
+			kcpSharedInformerFactory.Tenancy().V1alpha1().WorkspaceRoleBindings(),
+		); err != nil {
+			return err
+		}
+		if err := workspaceNames.Install(kcpSharedInformerFactory.Tenancy().V1alpha1().Workspaces(), rootClusterName); err != nil {
+			return err
+		}
+		homeParent := s.cfg.HomeWorkspacesParent
+		if homeParent == "" {
+			homeParent = rootClusterName
+		}
+		if err := homeWorkspaces.Install(
+			kcpClient,
+			kcpSharedInformerFactory.Tenancy().V1alpha1().Workspaces(),
+			kcpSharedInformerFactory.Tenancy().V1alpha1().WorkspaceTypes(),
+			homeParent,
+			s.cfg.HomeWorkspaceType,
+		); err != nil {
+			return err
+		}
 
-			go workspaceController.Start(ctx, 2)
+		if _, err := resourceexclusion.NewController(
+			kcpSharedInformerFactory.Tenancy().V1alpha1().Workspaces(),
+			kcpSharedInformerFactory.Tenancy().V1alpha1().WorkspaceTypes(),
+			resourceExclusionRegistry,
+		); err != nil {
+			return err
+		}
 
-			return nil
-		}); err != nil {
+		if _, err := workspacelimits.NewController(
+			kcpSharedInformerFactory.Tenancy().V1alpha1().Workspaces(),
+			kcpSharedInformerFactory.Tenancy().V1alpha1().WorkspaceTypes(),
+			workspaceLimits,
+		); err != nil {
 			return err
 		}
+
+		if err := externalPolicy.Initialize(kcpSharedInformerFactory.Tenancy().V1alpha1().Workspaces()); err != nil {
+			return err
+		}
+
+		var hibernationController *hibernation.Controller
+		if s.cfg.WorkspaceIdleTimeout > 0 {
+			hibernationController, err = hibernation.NewController(
+				kcpClient,
+				kcpSharedInformerFactory.Tenancy().V1alpha1().Workspaces(),
+				usageTracker.LastActivity,
+				hibernationRegistry,
+				s.cfg.WorkspaceIdleTimeout,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Register CRDs in the admin logical cluster
+		requiredCrds := []metav1.GroupKind{
+			{Group: tenancyapi.GroupName, Kind: "workspaces"},
+			{Group: tenancyapi.GroupName, Kind: "workspaceshards"},
+			{Group: tenancyapi.GroupName, Kind: "workspacetypes"},
+			{Group: tenancyapi.GroupName, Kind: "workspacerolebindings"},
+		}
+		if s.cfg.EnableAuditSinks {
+			requiredCrds = append(requiredCrds, metav1.GroupKind{Group: tenancyapi.GroupName, Kind: "auditsinks"})
+		}
+		if s.cfg.EnableWorkspaceClientCAs {
+			requiredCrds = append(requiredCrds, metav1.GroupKind{Group: tenancyapi.GroupName, Kind: "clientcertificateauthorities"})
+		}
+		crdClient, err := apiextensionsv1client.NewForConfig(adminConfig)
+		if err != nil {
+			return err
+		}
+		controllers.Add(startup.Step{
+			Name:    "workspace-crds",
+			Timeout: s.cfg.StartupStepTimeout,
+			Run:     startup.BootstrapCRDs(crdClient.CustomResourceDefinitions(), requiredCrds...),
+		}, startup.Step{
+			Name:     "workspace-informers",
+			Requires: []string{"workspace-crds"},
+			Timeout:  s.cfg.StartupStepTimeout,
+			Run:      startup.SyncInformers(ctx.Done(), kcpSharedInformerFactory, crdSharedInformerFactory, rootKubeSharedInformerFactory),
+		}, startup.Step{
+			Name:     "workspace-controllers",
+			Requires: []string{"workspace-informers"},
+			Run: func(_ context.Context) error {
+				go workspaceController.Start(ctx, 2)
+				go apiInheritanceController.Start(ctx, 2)
+				go workspaceRBACController.Start(ctx, 2)
+				go usageController.Start(ctx, 2)
+				go shardCredentialsController.Start(ctx, 2)
+				go homeWorkspaces.Start(ctx, 2)
+				if hibernationController != nil {
+					go hibernationController.Start(ctx, 2)
+				}
+				if shardVersionController != nil {
+					go shardVersionController.Start(ctx, 1)
+				}
+				return nil
+			},
+		})
+	}
+
+	if s.cfg.InstallAPIBindingController {
+		adminConfig, err := clientcmd.NewNonInteractiveClientConfig(clientConfig, "admin", &clientcmd.ConfigOverrides{}, nil).ClientConfig()
+		if err != nil {
+			return err
+		}
+
+		kcpClient, err := kcpclient.NewClusterForConfig(adminConfig)
+		if err != nil {
+			return err
+		}
+		kubeClient, err := kubernetes.NewClusterForConfig(adminConfig)
+		if err != nil {
+			return err
+		}
+		apiExtensionsClient, err := apiextensionsclient.NewClusterForConfig(adminConfig)
+		if err != nil {
+			return err
+		}
+
+		const clusterAll = "*"
+		kcpSharedInformerFactory := kcpexternalversions.NewSharedInformerFactoryWithOptions(kcpClient.Cluster(clusterAll), resyncPeriod)
+		crdSharedInformerFactory := crdexternalversions.NewSharedInformerFactoryWithOptions(apiExtensionsClient.Cluster(clusterAll), resyncPeriod)
+
+		apiBindingController, err := apibinding.NewController(
+			kcpClient,
+			kubeClient,
+			kcpSharedInformerFactory.Apis().V1alpha1().APIBindings(),
+			kcpSharedInformerFactory.Apis().V1alpha1().APIExports(),
+			crdSharedInformerFactory.Apiextensions().V1().CustomResourceDefinitions(),
+			workspaceNames,
+			crdProjections,
+		)
+		if err != nil {
+			return err
+		}
+
+		dynamicClient, err := dynamic.NewClusterForConfig(adminConfig)
+		if err != nil {
+			return err
+		}
+		if err := referenceResolver.Initialize(kcpSharedInformerFactory.Apis().V1alpha1().ReferenceGrants(), dynamicClient); err != nil {
+			return err
+		}
+
+		crdClient, err := apiextensionsv1client.NewForConfig(adminConfig)
+		if err != nil {
+			return err
+		}
+		controllers.Add(startup.Step{
+			Name:    "apibinding-crds",
+			Timeout: s.cfg.StartupStepTimeout,
+			Run: startup.BootstrapCRDs(crdClient.CustomResourceDefinitions(),
+				metav1.GroupKind{Group: apisapi.GroupName, Kind: "apiexports"},
+				metav1.GroupKind{Group: apisapi.GroupName, Kind: "apibindings"},
+				metav1.GroupKind{Group: apisapi.GroupName, Kind: "referencegrants"},
+			),
+		}, startup.Step{
+			Name:     "apibinding-informers",
+			Requires: []string{"apibinding-crds"},
+			Timeout:  s.cfg.StartupStepTimeout,
+			Run:      startup.SyncInformers(ctx.Done(), kcpSharedInformerFactory, crdSharedInformerFactory),
+		}, startup.Step{
+			Name:     "apibinding-controller",
+			Requires: []string{"apibinding-informers"},
+			Run: func(_ context.Context) error {
+				go apiBindingController.Start(ctx, 2)
+				return nil
+			},
+		})
+	}
+
+	if s.cfg.BootstrapManifests != "" {
+		bootstrapController, err := bootstrap.NewController(server.LoopbackClientConfig, s.cfg.BootstrapManifests, s.cfg.BootstrapInterval)
+		if err != nil {
+			return err
+		}
+		// the manifests may hold objects of the CRDs of kcp
+		var requires []string
+		if s.cfg.InstallWorkspaceController {
+			requires = append(requires, "workspace-crds")
+		}
+		if s.cfg.InstallAPIBindingController {
+			requires = append(requires, "apibinding-crds")
+		}
+		controllers.Add(startup.Step{
+			Name:     "bootstrap-manifests",
+			Requires: requires,
+			Run: func(_ context.Context) error {
+				go bootstrapController.Start(ctx, 2)
+				return nil
+			},
+		})
+	}
+
+	if flowControl != nil {
+		kubeClient, err := kubernetes.NewForConfig(server.LoopbackClientConfig)
+		if err != nil {
+			return err
+		}
+		// the priority levels of kcp are bootstrapped in the root logical cluster, next to
+		// the upstream ones
+		controllers.Add(startup.Step{
+			Name:    "priority-and-fairness",
+			Timeout: s.cfg.StartupStepTimeout,
+			Run:     priorityandfairness.Bootstrap(kubeClient.FlowcontrolV1beta1()),
+		})
+	}
+
+	if err := controllers.Validate(); err != nil {
+		return err
+	}
+	if err := server.AddReadyzChecks(controllers.ReadyzChecks()...); err != nil {
+		return err
+	}
+	if err := server.AddPostStartHook("start-controllers", func(_ genericapiserver.PostStartHookContext) error {
+		// failed steps are logged, and fail their readyz checks
+		go func() {
+			_ = controllers.Run(ctx)
+		}()
+		return nil
+	}); err != nil {
+		return err
 	}
 
 	prepared := server.PrepareRun()
@@ -412,6 +1096,18 @@ func (s *Server) AddPostStartHook(name string, hook genericapiserver.PostStartHo
 	})
 }
 
+// AddAdmissionPlugin allows you to add a compiled-in admission plugin, which runs after the
+// admission plugins of kcp, in the order plugins are added. Like the other plugins, it can
+// be disabled with --disable-admission-plugins.
+func (s *Server) AddAdmissionPlugin(name string, factory admission.Factory) {
+	s.admissionPlugins = append(s.admissionPlugins, admissionPluginEntry{
+		name: name,
+		register: func(plugins *admission.Plugins) {
+			plugins.Register(name, factory)
+		},
+	})
+}
+
 // AddPreShutdownHook allows you to add a PreShutdownHookFunc that gets passed to the underlying genericapiserver implementation.
 func (s *Server) AddPreShutdownHook(name string, hook genericapiserver.PreShutdownHookFunc) {
 	// you could potentially add duplicate or invalid post start hooks here, but we'll let
@@ -430,34 +1126,43 @@ func NewServer(cfg *Config) *Server {
 		name: "start-namespace-controller",
 		hook: s.startNamespaceController,
 	})
+	s.postStartHooks = append(s.postStartHooks, postStartHookEntry{
+		name: "start-crd-controller",
+		hook: s.startCRDController,
+	})
+	s.postStartHooks = append(s.postStartHooks, postStartHookEntry{
+		name: "start-garbage-collector",
+		hook: s.startGarbageCollector,
+	})
 	return s
 }
 
 func (s *Server) startNamespaceController(hookContext genericapiserver.PostStartHookContext) error {
-	kubeClient, err := kubernetes.NewForConfig(hookContext.LoopbackClientConfig)
+	// The namespaces of all the logical clusters are watched. The controller passes the
+	// logical cluster of each namespace in the context of its requests, which the
+	// multi-cluster round tripper turns into the cluster header.
+	clusterClient, err := kubernetes.NewClusterForConfig(hookContext.LoopbackClientConfig)
 	if err != nil {
 		return err
 	}
-	metadata, err := metadata.NewForConfig(hookContext.LoopbackClientConfig)
+	const clusterAll = "*"
+	versionedInformer := informers.NewSharedInformerFactory(clusterClient.Cluster(clusterAll), resyncPeriod)
+
+	config := rest.CopyConfig(hookContext.LoopbackClientConfig)
+	clientutils.EnableMultiCluster(config, nil, true)
+	kubeClient, err := kubernetes.NewForConfig(config)
 	if err != nil {
 		return err
 	}
-	versionedInformer := informers.NewSharedInformerFactory(kubeClient, resyncPeriod)
-
-	discoverResourcesFn := func(clusterName string) ([]*metav1.APIResourceList, error) {
-		logicalClusterConfig := rest.CopyConfig(hookContext.LoopbackClientConfig)
-		logicalClusterConfig.Host += "/clusters/" + clusterName
-		discoveryClient, err := discovery.NewDiscoveryClientForConfig(logicalClusterConfig)
-		if err != nil {
-			return nil, err
-		}
-		return discoveryClient.ServerPreferredNamespacedResources()
+	metadata, err := metadata.NewForConfig(config)
+	if err != nil {
+		return err
 	}
 
 	go namespace.NewNamespaceController(
 		kubeClient,
 		metadata,
-		discoverResourcesFn,
+		s.discoveryCache.ServerPreferredNamespacedResources,
 		versionedInformer.Core().V1().Namespaces(),
 		time.Duration(30)*time.Second,
 		v1.FinalizerKubernetes,
@@ -468,6 +1173,58 @@ func (s *Server) startNamespaceController(hookContext genericapiserver.PostStart
 	return nil
 }
 
+func (s *Server) startCRDController(hookContext genericapiserver.PostStartHookContext) error {
+	apiExtensionsClient, err := apiextensionsclient.NewClusterForConfig(hookContext.LoopbackClientConfig)
+	if err != nil {
+		return err
+	}
+	const clusterAll = "*"
+	crdSharedInformerFactory := crdexternalversions.NewSharedInformerFactoryWithOptions(apiExtensionsClient.Cluster(clusterAll), resyncPeriod)
+
+	crdController, err := crd.NewController(
+		apiExtensionsClient,
+		crdSharedInformerFactory.Apiextensions().V1().CustomResourceDefinitions(),
+	)
+	if err != nil {
+		return err
+	}
+
+	crdSharedInformerFactory.Start(hookContext.StopCh)
+
+	go crdController.Start(adaptContext(hookContext), 2)
+
+	return nil
+}
+
+func (s *Server) startGarbageCollector(hookContext genericapiserver.PostStartHookContext) error {
+	apiExtensionsClient, err := apiextensionsclient.NewClusterForConfig(hookContext.LoopbackClientConfig)
+	if err != nil {
+		return err
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(hookContext.LoopbackClientConfig)
+	if err != nil {
+		return err
+	}
+	const clusterAll = "*"
+	crdSharedInformerFactory := crdexternalversions.NewSharedInformerFactoryWithOptions(apiExtensionsClient.Cluster(clusterAll), resyncPeriod)
+
+	garbageCollector, err := garbagecollector.NewController(
+		hookContext.LoopbackClientConfig,
+		discoveryClient,
+		crdSharedInformerFactory.Apiextensions().V1().CustomResourceDefinitions(),
+		s.restMapper,
+	)
+	if err != nil {
+		return err
+	}
+
+	crdSharedInformerFactory.Start(hookContext.StopCh)
+
+	go garbageCollector.Start(adaptContext(hookContext), 2)
+
+	return nil
+}
+
 // adaptContext turns the PostStartHookContext into a context.Context for use in routines that may or may not
 // run inside of a post-start-hook. The k8s APIServer wrote the post-start-hook context code before contexts
 // were part of the Go stdlib.
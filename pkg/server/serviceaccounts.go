@@ -0,0 +1,87 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/spf13/pflag"
+
+	"k8s.io/client-go/util/keyutil"
+
+	"github.com/kcp-dev/kcp/pkg/serviceaccount"
+)
+
+const (
+	serviceAccountKeyFile       = "service-account.key"
+	serviceAccountPublicKeyFile = "service-account.pub"
+)
+
+// ServiceAccountConfig configures the signing and the validation of the service account
+// tokens minted in the workspaces.
+type ServiceAccountConfig struct {
+	// Issuers are the accepted issuers of the tokens. The first one is the issuer of the
+	// tokens minted, which external integrations validating them expect.
+	Issuers []string
+	// SigningKeyFile holds the private key the tokens are signed with. It defaults to a
+	// key generated in the root directory.
+	SigningKeyFile string
+	// KeyFiles hold additional keys the tokens may be signed with, e.g. the previous
+	// signing keys while rotating them.
+	KeyFiles []string
+}
+
+// DefaultServiceAccountConfig returns a ServiceAccountConfig signing tokens of the default
+// issuer with a generated key.
+func DefaultServiceAccountConfig() *ServiceAccountConfig {
+	return &ServiceAccountConfig{
+		Issuers: []string{serviceaccount.Issuer},
+	}
+}
+
+func (c *ServiceAccountConfig) bindOptions(fs *pflag.FlagSet) {
+	fs.StringArrayVar(&c.Issuers, "service-account-issuer", c.Issuers, "Identifier of the service account token issuer. When this flag is specified multiple times, the first is used to generate tokens and all are used to determine which issuers are accepted.")
+	fs.StringVar(&c.SigningKeyFile, "service-account-signing-key-file", c.SigningKeyFile, "File containing the PEM-encoded private key service account tokens are signed with. If empty, a key is generated in the root directory.")
+	fs.StringArrayVar(&c.KeyFiles, "service-account-key-file", c.KeyFiles, "File containing PEM-encoded public or private keys service account tokens may also be signed with. The flag can be specified multiple times with different files.")
+}
+
+// signingKeyFile returns the signing key file, the generated one of dir by default.
+func (c *ServiceAccountConfig) signingKeyFile(dir string) string {
+	if c.SigningKeyFile != "" {
+		return c.SigningKeyFile
+	}
+	return filepath.Join(dir, serviceAccountKeyFile)
+}
+
+// writePublicKey writes the public key of the signing key file to dir, for the external
+// integrations validating service account tokens.
+func writePublicKey(signingKeyFile, dir string) error {
+	keys, err := keyutil.PublicKeysFromFile(signingKeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load the service account signing key: %w", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(keys[0])
+	if err != nil {
+		return err
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	return ioutil.WriteFile(filepath.Join(dir, serviceAccountPublicKeyFile), data, 0644)
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package serviceaccount
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"k8s.io/apiserver/pkg/authentication/user"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// KubeconfigPath is the non-resource path of every logical cluster serving kubeconfigs
+// for the requesting user.
+const KubeconfigPath = "/kubeconfig"
+
+// Kubeconfig mints a token for the user in the given logical cluster, and returns a
+// kubeconfig using it against the logical cluster of the server. Unlike the loopback
+// token, the token is short-lived and does not authenticate against any other logical
+// cluster.
+func (i *TokenIssuer) Kubeconfig(server *rest.Config, clusterName string, u user.Info, expirationSeconds int64) (*clientcmdapi.Config, time.Time, error) {
+	token, expiry, err := i.IssueUserToken(clusterName, u, expirationSeconds)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	config := clientcmdapi.NewConfig()
+	config.Clusters[clusterName] = &clientcmdapi.Cluster{
+		Server:                   server.Host + "/clusters/" + clusterName,
+		CertificateAuthorityData: server.CAData,
+		TLSServerName:            server.TLSClientConfig.ServerName,
+	}
+	config.AuthInfos[u.GetName()] = &clientcmdapi.AuthInfo{Token: token}
+	config.Contexts[clusterName] = &clientcmdapi.Context{Cluster: clusterName, AuthInfo: u.GetName()}
+	config.CurrentContext = clusterName
+	return config, expiry, nil
+}
+
+// WithKubeconfig serves the kubeconfig path of every logical cluster with a kubeconfig
+// for the requesting user, valid for expirationSeconds if given in the query. Users
+// allowed to impersonate can mint a kubeconfig for someone else by impersonating them.
+// It must be wrapped by the authentication and authorization filters.
+func WithKubeconfig(handler http.Handler, issuer *TokenIssuer, server *rest.Config) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		info, ok := genericapirequest.RequestInfoFrom(req.Context())
+		if !ok || info.IsResourceRequest || info.Path != KubeconfigPath {
+			handler.ServeHTTP(w, req)
+			return
+		}
+		if info.Verb != "get" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		cluster := genericapirequest.ClusterFrom(req.Context())
+		if cluster == nil || cluster.Name == "" || cluster.Wildcard {
+			http.Error(w, "kubeconfigs can only be requested within a logical cluster", http.StatusBadRequest)
+			return
+		}
+		u, ok := genericapirequest.UserFrom(req.Context())
+		if !ok || u.GetName() == "" || u.GetName() == user.Anonymous {
+			http.Error(w, "kubeconfigs can only be requested by authenticated users", http.StatusForbidden)
+			return
+		}
+		var expirationSeconds int64
+		if value := req.URL.Query().Get("expirationSeconds"); value != "" {
+			var err error
+			if expirationSeconds, err = strconv.ParseInt(value, 10, 64); err != nil || expirationSeconds <= 0 {
+				http.Error(w, fmt.Sprintf("invalid expirationSeconds %q", value), http.StatusBadRequest)
+				return
+			}
+		}
+
+		config, expiry, err := issuer.Kubeconfig(server, cluster.Name, u, expirationSeconds)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to mint a token: %v", err), http.StatusInternalServerError)
+			return
+		}
+		data, err := clientcmd.Write(*config)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to write the kubeconfig: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Header().Set("Expires", expiry.UTC().Format(http.TimeFormat))
+		w.Header().Set("Cache-Control", "no-store")
+		_, _ = w.Write(data)
+	})
+}
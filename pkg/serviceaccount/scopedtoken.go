@@ -0,0 +1,125 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package serviceaccount
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/kubernetes/plugin/pkg/auth/authorizer/rbac"
+)
+
+// ScopedTokensPath is the non-resource path serving scoped tokens.
+const ScopedTokensPath = "/scopedtokens"
+
+// ScopedTokenRequest requests a token restricted to some logical clusters and rules.
+type ScopedTokenRequest struct {
+	// Clusters are the logical clusters the token authenticates against.
+	Clusters []string `json:"clusters"`
+	// Rules are the only requests the token may be used for.
+	Rules []rbacv1.PolicyRule `json:"rules"`
+	// ExpirationSeconds is the lifetime of the token, an hour by default and a day at
+	// most.
+	ExpirationSeconds int64 `json:"expirationSeconds,omitempty"`
+}
+
+// ScopedTokenResponse holds a scoped token.
+type ScopedTokenResponse struct {
+	Token               string      `json:"token"`
+	ExpirationTimestamp metav1.Time `json:"expirationTimestamp"`
+}
+
+// WithScopedTokens serves POST requests to the scoped tokens path with tokens of the
+// requesting user, restricted to the logical clusters and rules of the ScopedTokenRequest
+// in the body. Tokens which are already scoped cannot request other ones. It must be
+// wrapped by the authentication and authorization filters.
+func WithScopedTokens(handler http.Handler, issuer *TokenIssuer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		info, ok := genericapirequest.RequestInfoFrom(req.Context())
+		if !ok || info.IsResourceRequest || info.Path != ScopedTokensPath {
+			handler.ServeHTTP(w, req)
+			return
+		}
+		if info.Verb != "post" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		u, ok := genericapirequest.UserFrom(req.Context())
+		if !ok || u.GetName() == "" {
+			http.Error(w, "scoped tokens can only be requested by authenticated users", http.StatusForbidden)
+			return
+		}
+		if _, scoped := u.GetExtra()[ScopeExtraKey]; scoped {
+			http.Error(w, "scoped tokens cannot request other tokens", http.StatusForbidden)
+			return
+		}
+		request := &ScopedTokenRequest{}
+		if err := json.NewDecoder(req.Body).Decode(request); err != nil {
+			http.Error(w, fmt.Sprintf("invalid ScopedTokenRequest: %v", err), http.StatusBadRequest)
+			return
+		}
+		for _, clusterName := range request.Clusters {
+			if clusterName == "" || clusterName == "*" {
+				http.Error(w, fmt.Sprintf("invalid logical cluster %q", clusterName), http.StatusBadRequest)
+				return
+			}
+		}
+
+		token, expiry, err := issuer.IssueScopedToken(request.Clusters, u, request.Rules, request.ExpirationSeconds)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		_ = json.NewEncoder(w).Encode(&ScopedTokenResponse{Token: token, ExpirationTimestamp: metav1.NewTime(expiry)})
+	})
+}
+
+// ScopeAuthorizer denies the requests of scoped tokens which their rules don't allow. It has
+// no opinion on any other request, which still has to be authorized for the user of the
+// token.
+func ScopeAuthorizer() authorizer.Authorizer {
+	return authorizer.AuthorizerFunc(func(ctx context.Context, attr authorizer.Attributes) (authorizer.Decision, string, error) {
+		if attr.GetUser() == nil {
+			return authorizer.DecisionNoOpinion, "", nil
+		}
+		scope, scoped := attr.GetUser().GetExtra()[ScopeExtraKey]
+		if !scoped {
+			return authorizer.DecisionNoOpinion, "", nil
+		}
+		rules := make([]rbacv1.PolicyRule, 0, len(scope))
+		for _, data := range scope {
+			var rule rbacv1.PolicyRule
+			if err := json.Unmarshal([]byte(data), &rule); err != nil {
+				return authorizer.DecisionDeny, "invalid token scope", nil
+			}
+			rules = append(rules, rule)
+		}
+		if !rbac.RulesAllow(attr, rules...) {
+			return authorizer.DecisionDeny, "not allowed by the scope of the token", nil
+		}
+		return authorizer.DecisionNoOpinion, "", nil
+	})
+}
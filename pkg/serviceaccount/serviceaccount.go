@@ -0,0 +1,246 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package serviceaccount issues and validates service account tokens which are scoped to
+// the logical cluster the service account lives in: a token minted in one workspace does
+// not authenticate requests to any other workspace.
+package serviceaccount
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/request/bearertoken"
+	tokenunion "k8s.io/apiserver/pkg/authentication/token/union"
+	"k8s.io/apiserver/pkg/authentication/user"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/keyutil"
+	"k8s.io/kubernetes/pkg/apis/core"
+	"k8s.io/kubernetes/pkg/serviceaccount"
+)
+
+const (
+	// Issuer is the default issuer of the service account tokens minted by kcp.
+	Issuer = "https://kcp.dev"
+
+	// ClusterNameExtraKey is the user info extra key holding the logical cluster
+	// a service account token was authenticated for.
+	ClusterNameExtraKey = "authentication.kcp.dev/cluster-name"
+
+	// ServiceAccountClusterNameExtraKey is the user info extra key holding the logical
+	// cluster of the service account a token was authenticated as. Unlike
+	// ClusterNameExtraKey, it is never set for user tokens.
+	ServiceAccountClusterNameExtraKey = "authentication.kcp.dev/service-account-cluster-name"
+
+	// DefaultExpirationSeconds is the lifetime of tokens requested without expiration.
+	DefaultExpirationSeconds = int64(60 * 60)
+)
+
+// ClusterAudience returns the audience every token minted in the given logical cluster
+// carries, and which is required to authenticate against that logical cluster.
+func ClusterAudience(clusterName string) string {
+	return "kcp.dev/clusters/" + clusterName
+}
+
+// GetterFunc returns the client used to look up service accounts, and the objects their
+// tokens are bound to, in the given logical cluster.
+type GetterFunc func(clusterName string) (kubernetes.Interface, error)
+
+// Options configure the issuer, verification keys and default audiences of the service
+// account tokens.
+type Options struct {
+	// Issuers are the accepted issuers of service account tokens, the first one being
+	// the issuer of the tokens minted. Defaults to Issuer.
+	Issuers []string
+	// KeyFiles hold the public keys, or the private keys, service account tokens signed
+	// with another key than the signing key are verified with, e.g. the signing keys
+	// before a rotation.
+	KeyFiles []string
+	// APIAudiences are the audiences of the tokens requested without any, in addition to
+	// the cluster audience.
+	APIAudiences []string
+}
+
+// TokenIssuer mints and validates logical cluster scoped service account tokens.
+type TokenIssuer struct {
+	generator     serviceaccount.TokenGenerator
+	userGenerator serviceaccount.TokenGenerator
+	issuers       []string
+	apiAudiences  []string
+	publicKey     interface{}
+	publicKeys    []interface{}
+	getter        GetterFunc
+}
+
+// NewTokenIssuer returns a TokenIssuer signing tokens with the private key stored in
+// keyFile, which is generated if it does not exist yet.
+func NewTokenIssuer(keyFile string, opts Options, getter GetterFunc) (*TokenIssuer, error) {
+	data, _, err := keyutil.LoadOrGenerateKeyFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load service account signing key: %w", err)
+	}
+	privateKey, err := keyutil.ParsePrivateKeyPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse service account signing key %q: %w", keyFile, err)
+	}
+	return NewTokenIssuerForKey(privateKey, opts, getter)
+}
+
+// NewTokenIssuerForKey returns a TokenIssuer signing tokens with the given RSA or ECDSA
+// private key.
+func NewTokenIssuerForKey(privateKey interface{}, opts Options, getter GetterFunc) (*TokenIssuer, error) {
+	var publicKey interface{}
+	switch key := privateKey.(type) {
+	case *rsa.PrivateKey:
+		publicKey = &key.PublicKey
+	case *ecdsa.PrivateKey:
+		publicKey = &key.PublicKey
+	default:
+		return nil, fmt.Errorf("unsupported service account signing key type %T", privateKey)
+	}
+	publicKeys := []interface{}{publicKey}
+	for _, keyFile := range opts.KeyFiles {
+		keys, err := keyutil.PublicKeysFromFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load service account verification keys: %w", err)
+		}
+		publicKeys = append(publicKeys, keys...)
+	}
+	issuers := opts.Issuers
+	if len(issuers) == 0 {
+		issuers = []string{Issuer}
+	}
+
+	generator, err := serviceaccount.JWTTokenGenerator(issuers[0], privateKey)
+	if err != nil {
+		return nil, err
+	}
+	userGenerator, err := serviceaccount.JWTTokenGenerator(UserIssuer, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenIssuer{
+		generator:     generator,
+		userGenerator: userGenerator,
+		issuers:       issuers,
+		apiAudiences:  opts.APIAudiences,
+		publicKey:     publicKey,
+		publicKeys:    publicKeys,
+		getter:        getter,
+	}, nil
+}
+
+// IssueToken mints a token for the service account of the given logical cluster. The
+// token is valid for the cluster audience in addition to the requested audiences.
+func (i *TokenIssuer) IssueToken(clusterName string, sa *corev1.ServiceAccount, audiences []string, expirationSeconds int64) (string, time.Time, error) {
+	if expirationSeconds <= 0 {
+		expirationSeconds = DefaultExpirationSeconds
+	}
+	internalSA := core.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      sa.Name,
+			Namespace: sa.Namespace,
+			UID:       sa.UID,
+		},
+	}
+	audiences = append([]string{ClusterAudience(clusterName)}, audiences...)
+	public, private := serviceaccount.Claims(internalSA, nil, nil, expirationSeconds, 0, audiences)
+	token, err := i.generator.GenerateToken(public, private)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, public.Expiry.Time(), nil
+}
+
+// Authenticator returns a request authenticator accepting the service account and user
+// tokens minted by this issuer for the logical cluster the request is targeted at.
+func (i *TokenIssuer) Authenticator() authenticator.Request {
+	return bearertoken.New(tokenunion.New(
+		authenticator.TokenFunc(i.authenticateToken),
+		authenticator.TokenFunc(i.authenticateUserToken),
+	))
+}
+
+func (i *TokenIssuer) authenticateToken(ctx context.Context, token string) (*authenticator.Response, bool, error) {
+	cluster := genericapirequest.ClusterFrom(ctx)
+	if cluster == nil || cluster.Name == "" || cluster.Wildcard {
+		return nil, false, nil
+	}
+	// Only tokens carrying the audience of the target cluster are accepted. The issuers
+	// are the implicit audiences of tokens without any, which therefore never match.
+	delegate := serviceaccount.JWTTokenAuthenticator(
+		i.issuers,
+		i.publicKeys,
+		authenticator.Audiences(i.issuers),
+		serviceaccount.NewValidator(&getter{ctx: ctx, clusterName: cluster.Name, clientFor: i.getter}),
+	)
+	resp, ok, err := delegate.AuthenticateToken(authenticator.WithAudiences(ctx, authenticator.Audiences{ClusterAudience(cluster.Name)}), token)
+	if err != nil || !ok {
+		return resp, ok, err
+	}
+
+	extra := map[string][]string{}
+	for k, v := range resp.User.GetExtra() {
+		extra[k] = v
+	}
+	extra[ClusterNameExtraKey] = []string{cluster.Name}
+	extra[ServiceAccountClusterNameExtraKey] = []string{cluster.Name}
+	resp.User = &user.DefaultInfo{
+		Name:   resp.User.GetName(),
+		UID:    resp.User.GetUID(),
+		Groups: resp.User.GetGroups(),
+		Extra:  extra,
+	}
+	return resp, true, nil
+}
+
+// getter looks up the objects a token is bound to in a single logical cluster.
+type getter struct {
+	ctx         context.Context
+	clusterName string
+	clientFor   GetterFunc
+}
+
+func (g *getter) GetServiceAccount(namespace, name string) (*corev1.ServiceAccount, error) {
+	client, err := g.clientFor(g.clusterName)
+	if err != nil {
+		return nil, err
+	}
+	return client.CoreV1().ServiceAccounts(namespace).Get(g.ctx, name, metav1.GetOptions{})
+}
+
+func (g *getter) GetPod(namespace, name string) (*corev1.Pod, error) {
+	client, err := g.clientFor(g.clusterName)
+	if err != nil {
+		return nil, err
+	}
+	return client.CoreV1().Pods(namespace).Get(g.ctx, name, metav1.GetOptions{})
+}
+
+func (g *getter) GetSecret(namespace, name string) (*corev1.Secret, error) {
+	client, err := g.clientFor(g.clusterName)
+	if err != nil {
+		return nil, err
+	}
+	return client.CoreV1().Secrets(namespace).Get(g.ctx, name, metav1.GetOptions{})
+}
@@ -0,0 +1,236 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package serviceaccount
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	tokenunion "k8s.io/apiserver/pkg/authentication/token/union"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/util/keyutil"
+)
+
+func TestTokenScopedToCluster(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sa := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "robot", Namespace: "default", UID: "1234"}}
+	clients := map[string]kubernetes.Interface{
+		"foo": fake.NewSimpleClientset(sa),
+		"bar": fake.NewSimpleClientset(sa),
+	}
+	issuer, err := NewTokenIssuerForKey(key, Options{}, func(clusterName string) (kubernetes.Interface, error) {
+		return clients[clusterName], nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, _, err := issuer.IssueToken("foo", sa, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		cluster       string
+		authenticated bool
+	}{
+		{cluster: "foo", authenticated: true},
+		{cluster: "bar", authenticated: false},
+	} {
+		t.Run(tc.cluster, func(t *testing.T) {
+			ctx := genericapirequest.WithCluster(context.Background(), genericapirequest.Cluster{Name: tc.cluster})
+			resp, ok, _ := issuer.authenticateToken(ctx, token)
+			if ok != tc.authenticated {
+				t.Fatalf("expected authenticated=%v, got %v", tc.authenticated, ok)
+			}
+			if !ok {
+				return
+			}
+			if got, expected := resp.User.GetName(), "system:serviceaccount:default:robot"; got != expected {
+				t.Errorf("expected user %q, got %q", expected, got)
+			}
+			if got := resp.User.GetExtra()[ClusterNameExtraKey]; len(got) != 1 || got[0] != tc.cluster {
+				t.Errorf("expected cluster extra %q, got %v", tc.cluster, got)
+			}
+			if got := resp.User.GetExtra()[ServiceAccountClusterNameExtraKey]; len(got) != 1 || got[0] != tc.cluster {
+				t.Errorf("expected service account cluster extra %q, got %v", tc.cluster, got)
+			}
+		})
+	}
+}
+
+func TestTokenOfRotatedKey(t *testing.T) {
+	oldKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sa := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "robot", Namespace: "default", UID: "1234"}}
+	getter := func(clusterName string) (kubernetes.Interface, error) {
+		return fake.NewSimpleClientset(sa), nil
+	}
+
+	oldIssuer, err := NewTokenIssuerForKey(oldKey, Options{Issuers: []string{"https://old.example.com"}}, getter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _, err := oldIssuer.IssueToken("foo", sa, []string{"vault"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyData, err := keyutil.MarshalPrivateKeyToPEM(oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "old.key")
+	if err := keyutil.WriteKey(keyFile, keyData); err != nil {
+		t.Fatal(err)
+	}
+	ctx := genericapirequest.WithCluster(context.Background(), genericapirequest.Cluster{Name: "foo"})
+
+	// the tokens of the old key and issuer are rejected until both are configured
+	issuer, err := NewTokenIssuerForKey(newKey, Options{Issuers: []string{"https://new.example.com"}, KeyFiles: []string{keyFile}}, getter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := issuer.authenticateToken(ctx, token); ok {
+		t.Errorf("expected the token of another issuer to be rejected")
+	}
+	issuer, err = NewTokenIssuerForKey(newKey, Options{Issuers: []string{"https://new.example.com", "https://old.example.com"}, KeyFiles: []string{keyFile}}, getter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := issuer.authenticateToken(ctx, token); !ok {
+		t.Errorf("expected the token of the previous key and issuer to be accepted")
+	}
+}
+
+func TestUserTokenScopedToCluster(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer, err := NewTokenIssuerForKey(key, Options{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the service account cluster of the minting user must not be passed on
+	token, _, err := issuer.IssueUserToken("foo", &user.DefaultInfo{Name: "alice", Groups: []string{"admins"}, Extra: map[string][]string{ServiceAccountClusterNameExtraKey: {"foo"}}}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		cluster       string
+		authenticated bool
+	}{
+		{cluster: "foo", authenticated: true},
+		{cluster: "bar", authenticated: false},
+	} {
+		t.Run(tc.cluster, func(t *testing.T) {
+			ctx := genericapirequest.WithCluster(context.Background(), genericapirequest.Cluster{Name: tc.cluster})
+			resp, ok, _ := tokenunion.New(
+				authenticator.TokenFunc(issuer.authenticateToken),
+				authenticator.TokenFunc(issuer.authenticateUserToken),
+			).AuthenticateToken(ctx, token)
+			if ok != tc.authenticated {
+				t.Fatalf("expected authenticated=%v, got %v", tc.authenticated, ok)
+			}
+			if !ok {
+				return
+			}
+			if got, expected := resp.User.GetName(), "alice"; got != expected {
+				t.Errorf("expected user %q, got %q", expected, got)
+			}
+			if diff := cmp.Diff([]string{"admins", user.AllAuthenticated}, resp.User.GetGroups()); diff != "" {
+				t.Errorf("unexpected groups (-want +got):\n%s", diff)
+			}
+			if got, found := resp.User.GetExtra()[ServiceAccountClusterNameExtraKey]; found {
+				t.Errorf("expected user tokens not to authenticate as service accounts, got %v", got)
+			}
+		})
+	}
+}
+
+func TestScopedToken(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer, err := NewTokenIssuerForKey(key, Options{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readPods := []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get", "list"}}}
+	token, _, err := issuer.IssueScopedToken([]string{"foo", "baz"}, &user.DefaultInfo{Name: "ci"}, readPods, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authenticate := func(cluster string) (user.Info, bool) {
+		ctx := genericapirequest.WithCluster(context.Background(), genericapirequest.Cluster{Name: cluster})
+		resp, ok, _ := issuer.authenticateUserToken(ctx, token)
+		if !ok {
+			return nil, false
+		}
+		return resp.User, true
+	}
+	if _, ok := authenticate("bar"); ok {
+		t.Errorf("expected the token not to authenticate against a logical cluster outside of its scope")
+	}
+	u, ok := authenticate("baz")
+	if !ok {
+		t.Fatalf("expected the token to authenticate against a logical cluster of its scope")
+	}
+	if _, found := u.GetExtra()[ClusterNameExtraKey]; found {
+		t.Errorf("expected scoped tokens not to be bound to the logical cluster")
+	}
+
+	for _, tc := range []struct {
+		verb     string
+		expected authorizer.Decision
+	}{
+		{verb: "list", expected: authorizer.DecisionNoOpinion},
+		{verb: "delete", expected: authorizer.DecisionDeny},
+	} {
+		attr := authorizer.AttributesRecord{User: u, Verb: tc.verb, Resource: "pods", ResourceRequest: true}
+		if decision, _, _ := ScopeAuthorizer().Authorize(context.Background(), attr); decision != tc.expected {
+			t.Errorf("expected decision %v to %s pods, got %v", tc.expected, tc.verb, decision)
+		}
+	}
+}
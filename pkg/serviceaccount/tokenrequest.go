@@ -0,0 +1,102 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package serviceaccount
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/endpoints/handlers/negotiation"
+	"k8s.io/apiserver/pkg/endpoints/handlers/responsewriters"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// WithTokenRequest serves the serviceaccounts/token subresource of every logical cluster
+// with tokens minted by the issuer, and passes all other requests to handler. It must be
+// wrapped by the authentication and authorization filters.
+func WithTokenRequest(handler http.Handler, issuer *TokenIssuer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		info, ok := genericapirequest.RequestInfoFrom(req.Context())
+		if !ok || !info.IsResourceRequest || info.APIGroup != "" || info.Resource != "serviceaccounts" || info.Subresource != "token" || info.Verb != "create" {
+			handler.ServeHTTP(w, req)
+			return
+		}
+		issuer.serveTokenRequest(w, req, info)
+	})
+}
+
+func (i *TokenIssuer) serveTokenRequest(w http.ResponseWriter, req *http.Request, info *genericapirequest.RequestInfo) {
+	gv := schema.GroupVersion{Version: info.APIVersion}
+	writeError := func(err error) {
+		responsewriters.ErrorNegotiated(err, scheme.Codecs, gv, w, req)
+	}
+
+	cluster := genericapirequest.ClusterFrom(req.Context())
+	if cluster == nil || cluster.Name == "" || cluster.Wildcard {
+		writeError(apierrors.NewBadRequest("tokens can only be requested within a logical cluster"))
+		return
+	}
+
+	tokenRequest := &authenticationv1.TokenRequest{}
+	if err := json.NewDecoder(req.Body).Decode(tokenRequest); err != nil {
+		writeError(apierrors.NewBadRequest(fmt.Sprintf("invalid TokenRequest: %v", err)))
+		return
+	}
+	if tokenRequest.Spec.BoundObjectRef != nil {
+		writeError(apierrors.NewBadRequest("bound object references are not supported"))
+		return
+	}
+
+	client, err := i.getter(cluster.Name)
+	if err != nil {
+		writeError(err)
+		return
+	}
+	sa, err := client.CoreV1().ServiceAccounts(info.Namespace).Get(req.Context(), info.Name, metav1.GetOptions{})
+	if err != nil {
+		writeError(err)
+		return
+	}
+
+	var expirationSeconds int64
+	if tokenRequest.Spec.ExpirationSeconds != nil {
+		expirationSeconds = *tokenRequest.Spec.ExpirationSeconds
+	}
+	if len(tokenRequest.Spec.Audiences) == 0 {
+		tokenRequest.Spec.Audiences = i.apiAudiences
+	}
+	token, expiry, err := i.IssueToken(cluster.Name, sa, tokenRequest.Spec.Audiences, expirationSeconds)
+	if err != nil {
+		writeError(apierrors.NewInternalError(err))
+		return
+	}
+
+	tokenRequest.TypeMeta = metav1.TypeMeta{APIVersion: authenticationv1.SchemeGroupVersion.String(), Kind: "TokenRequest"}
+	tokenRequest.ObjectMeta = metav1.ObjectMeta{Name: sa.Name, Namespace: sa.Namespace, CreationTimestamp: metav1.Now()}
+	tokenRequest.Spec.Audiences = append([]string{ClusterAudience(cluster.Name)}, tokenRequest.Spec.Audiences...)
+	tokenRequest.Status = authenticationv1.TokenRequestStatus{
+		Token:               token,
+		ExpirationTimestamp: metav1.NewTime(expiry),
+	}
+	responsewriters.WriteObjectNegotiated(scheme.Codecs, negotiation.DefaultEndpointRestrictions, authenticationv1.SchemeGroupVersion, w, req, http.StatusCreated, tokenRequest)
+}
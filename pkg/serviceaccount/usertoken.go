@@ -0,0 +1,186 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package serviceaccount
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gopkg.in/square/go-jose.v2/jwt"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/user"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+)
+
+const (
+	// UserIssuer is the issuer of the user tokens minted by kcp. It differs from the
+	// issuer of service account tokens, so that each kind of token is only accepted as
+	// what it is.
+	UserIssuer = "https://kcp.dev/users"
+
+	// MaxUserExpirationSeconds bounds the lifetime of user tokens.
+	MaxUserExpirationSeconds = int64(24 * 60 * 60)
+
+	// ScopeExtraKey is the user info extra key holding the rules a scoped token is
+	// restricted to, one JSON serialized PolicyRule per value.
+	ScopeExtraKey = "authentication.kcp.dev/scope"
+)
+
+// userClaims are the private claims of user tokens.
+type userClaims struct {
+	Kcp kcpUserClaims `json:"kcp.dev"`
+}
+
+type kcpUserClaims struct {
+	UID    string              `json:"uid,omitempty"`
+	Groups []string            `json:"groups,omitempty"`
+	Extra  map[string][]string `json:"extra,omitempty"`
+	// Rules restrict scoped tokens to the requests they allow.
+	Rules []rbacv1.PolicyRule `json:"rules,omitempty"`
+}
+
+// IssueUserToken mints a token authenticating the user against the given logical cluster
+// only.
+func (i *TokenIssuer) IssueUserToken(clusterName string, u user.Info, expirationSeconds int64) (string, time.Time, error) {
+	return i.issueUserToken([]string{clusterName}, u, nil, expirationSeconds)
+}
+
+// IssueScopedToken mints a token authenticating the user against the given logical
+// clusters, for the requests allowed by the rules only, e.g. to give a CI system read-only
+// access to a workspace. The requests must be authorized for the user too: the rules only
+// narrow down what the token can be used for.
+func (i *TokenIssuer) IssueScopedToken(clusterNames []string, u user.Info, rules []rbacv1.PolicyRule, expirationSeconds int64) (string, time.Time, error) {
+	if len(clusterNames) == 0 {
+		return "", time.Time{}, errors.New("scoped tokens require at least one logical cluster")
+	}
+	if len(rules) == 0 {
+		return "", time.Time{}, errors.New("scoped tokens require at least one rule")
+	}
+	return i.issueUserToken(clusterNames, u, rules, expirationSeconds)
+}
+
+func (i *TokenIssuer) issueUserToken(clusterNames []string, u user.Info, rules []rbacv1.PolicyRule, expirationSeconds int64) (string, time.Time, error) {
+	if expirationSeconds <= 0 {
+		expirationSeconds = DefaultExpirationSeconds
+	}
+	if expirationSeconds > MaxUserExpirationSeconds {
+		expirationSeconds = MaxUserExpirationSeconds
+	}
+	audience := make(jwt.Audience, 0, len(clusterNames))
+	for _, clusterName := range clusterNames {
+		audience = append(audience, ClusterAudience(clusterName))
+	}
+	now := time.Now()
+	expiry := now.Add(time.Duration(expirationSeconds) * time.Second)
+	public := &jwt.Claims{
+		Issuer:    UserIssuer,
+		Subject:   u.GetName(),
+		Audience:  audience,
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		Expiry:    jwt.NewNumericDate(expiry),
+	}
+	private := &userClaims{Kcp: kcpUserClaims{
+		UID:    u.GetUID(),
+		Groups: u.GetGroups(),
+		Extra:  u.GetExtra(),
+		Rules:  rules,
+	}}
+	token, err := i.userGenerator.GenerateToken(public, private)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiry, nil
+}
+
+func (i *TokenIssuer) authenticateUserToken(ctx context.Context, token string) (*authenticator.Response, bool, error) {
+	cluster := genericapirequest.ClusterFrom(ctx)
+	if cluster == nil || cluster.Name == "" || cluster.Wildcard {
+		return nil, false, nil
+	}
+	parsed, err := jwt.ParseSigned(token)
+	if err != nil {
+		// not a JWT
+		return nil, false, nil
+	}
+	var unverified jwt.Claims
+	if err := parsed.UnsafeClaimsWithoutVerification(&unverified); err != nil || unverified.Issuer != UserIssuer {
+		// not a user token
+		return nil, false, nil
+	}
+
+	var public jwt.Claims
+	var private userClaims
+	if err := parsed.Claims(i.publicKey, &public, &private); err != nil {
+		return nil, false, fmt.Errorf("invalid user token: %w", err)
+	}
+	if err := public.Validate(jwt.Expected{
+		Issuer:   UserIssuer,
+		Audience: jwt.Audience{ClusterAudience(cluster.Name)},
+		Time:     time.Now(),
+	}); err != nil {
+		return nil, false, fmt.Errorf("invalid user token for logical cluster %s: %w", cluster.Name, err)
+	}
+
+	extra := map[string][]string{}
+	for k, v := range private.Kcp.Extra {
+		extra[k] = v
+	}
+	// the holder of a token minted by a service account is not that service account
+	delete(extra, ServiceAccountClusterNameExtraKey)
+	if len(private.Kcp.Rules) == 0 {
+		extra[ClusterNameExtraKey] = []string{cluster.Name}
+	} else {
+		// scoped tokens are not bound to a single logical cluster, and are restricted by
+		// the scope authorizer
+		scope := make([]string, 0, len(private.Kcp.Rules))
+		for _, rule := range private.Kcp.Rules {
+			data, err := json.Marshal(rule)
+			if err != nil {
+				return nil, false, err
+			}
+			scope = append(scope, string(data))
+		}
+		extra[ScopeExtraKey] = scope
+	}
+	groups := private.Kcp.Groups
+	if !contains(groups, user.AllAuthenticated) {
+		groups = append(groups, user.AllAuthenticated)
+	}
+	return &authenticator.Response{
+		User: &user.DefaultInfo{
+			Name:   public.Subject,
+			UID:    private.Kcp.UID,
+			Groups: groups,
+			Extra:  extra,
+		},
+	}, true, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
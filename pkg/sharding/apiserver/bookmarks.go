@@ -0,0 +1,153 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+const (
+	// ShardResourceVersionsAnnotation is set on the bookmarks of cross-cluster watches to the
+	// JSON map of the resource versions each shard has been watched up to, so that clients
+	// can tell how far they caught up with each shard. The resource version of the bookmark
+	// holds the same versions, but is opaque.
+	ShardResourceVersionsAnnotation = "kcp.dev/shard-resource-versions"
+	// InitialEventsEndAnnotation is set to "true" on the bookmark which follows the ADDED
+	// events of the objects existing when a cross-cluster watch started, once those of all
+	// shards have been sent.
+	InitialEventsEndAnnotation = "kcp.dev/initial-events-end"
+)
+
+// IsInitialEventsEnd returns whether the event is the bookmark sent after the initial
+// events of a cross-cluster watch.
+func IsInitialEventsEnd(event watch.Event) bool {
+	if event.Type != watch.Bookmark {
+		return false
+	}
+	obj, err := meta.Accessor(event.Object)
+	if err != nil {
+		return false
+	}
+	return obj.GetAnnotations()[InitialEventsEndAnnotation] == "true"
+}
+
+// ShardResourceVersionsFrom returns the resource versions per shard of a bookmark of a
+// cross-cluster watch, or nil if it has none.
+func ShardResourceVersionsFrom(obj runtime.Object) (map[string]int64, error) {
+	metaObj, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, err
+	}
+	value, found := metaObj.GetAnnotations()[ShardResourceVersionsAnnotation]
+	if !found {
+		return nil, nil
+	}
+	versions := map[string]int64{}
+	if err := json.Unmarshal([]byte(value), &versions); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", ShardResourceVersionsAnnotation, err)
+	}
+	return versions, nil
+}
+
+// annotateBookmark sets the resource versions per shard of the state on the bookmark.
+func annotateBookmark(obj runtime.Object, state *ShardedResourceVersions) error {
+	metaObj, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	versions := map[string]int64{}
+	for _, shard := range state.ResourceVersions {
+		versions[shard.Identifier] = shard.ResourceVersion
+	}
+	data, err := json.Marshal(versions)
+	if err != nil {
+		return err
+	}
+	annotations := metaObj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[ShardResourceVersionsAnnotation] = string(data)
+	metaObj.SetAnnotations(annotations)
+	return nil
+}
+
+// shardLister lists all the objects of a shard.
+type shardLister func(ctx context.Context, identifier string) (*unstructured.UnstructuredList, error)
+
+// initialEvents lists the shards of the state one after the other, and returns ADDED events
+// for their objects followed by the bookmark ending the initial events. The state is
+// advanced to the resource versions of the lists.
+//
+// The resource version of each ADDED event only holds the versions of the shards whose
+// objects have all been sent before it: the shard being sent is still at 0, so that a
+// watch resumed from it receives the objects of that shard again rather than missing
+// those not sent yet.
+func initialEvents(ctx context.Context, state *ShardedResourceVersions, list shardLister) ([]watch.Event, error) {
+	var events []watch.Event
+	var apiVersion, kind string
+	for i := range state.ResourceVersions {
+		shard := state.ResourceVersions[i].Identifier
+		objs, err := list(ctx, shard)
+		if err != nil {
+			return nil, err
+		}
+		encoded, err := state.Encode()
+		if err != nil {
+			return nil, err
+		}
+		for j := range objs.Items {
+			obj := &objs.Items[j]
+			obj.SetResourceVersion(encoded)
+			events = append(events, watch.Event{Type: watch.Added, Object: obj})
+		}
+		if err := state.UpdateWith(shard, objs); err != nil {
+			return nil, err
+		}
+		apiVersion, kind = objs.GetAPIVersion(), strings.TrimSuffix(objs.GetKind(), "List")
+	}
+
+	encoded, err := state.Encode()
+	if err != nil {
+		return nil, err
+	}
+	bookmark := &unstructured.Unstructured{}
+	bookmark.SetAPIVersion(apiVersion)
+	bookmark.SetKind(kind)
+	bookmark.SetResourceVersion(encoded)
+	bookmark.SetAnnotations(map[string]string{InitialEventsEndAnnotation: "true"})
+	if err := annotateBookmark(bookmark, state); err != nil {
+		return nil, err
+	}
+	return append(events, watch.Event{Type: watch.Bookmark, Object: bookmark}), nil
+}
+
+// sendsInitialEvents returns whether a cross-cluster watch starts with the initial events
+// of all shards, followed by a bookmark. Like without sharding, watches from no or 0
+// resource version start with ADDED events for the existing objects; when the client
+// accepts bookmarks, they are sent shard after shard and their end is signalled.
+func sendsInitialEvents(resourceVersion string, allowWatchBookmarks bool) bool {
+	return allowWatchBookmarks && (resourceVersion == "" || resourceVersion == "0")
+}
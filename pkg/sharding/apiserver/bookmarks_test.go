@@ -0,0 +1,140 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func TestInitialEvents(t *testing.T) {
+	lists := map[string]*unstructured.UnstructuredList{}
+	for shard, names := range map[string][]string{"first": {"a", "b"}, "second": {"c"}} {
+		list := &unstructured.UnstructuredList{Object: map[string]interface{}{"apiVersion": "v1", "kind": "ConfigMapList"}}
+		list.SetResourceVersion(map[string]string{"first": "10", "second": "20"}[shard])
+		for _, name := range names {
+			obj := unstructured.Unstructured{}
+			obj.SetAPIVersion("v1")
+			obj.SetKind("ConfigMap")
+			obj.SetName(name)
+			obj.SetResourceVersion("5")
+			list.Items = append(list.Items, obj)
+		}
+		lists[shard] = list
+	}
+	state, err := NewResourceVersionState("", []string{"first", "second"}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := initialEvents(context.Background(), state, func(_ context.Context, shard string) (*unstructured.UnstructuredList, error) {
+		return lists[shard], nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type result struct {
+		Type             watch.EventType
+		Name             string
+		ResourceVersions []ShardedResourceVersion
+	}
+	var got []result
+	for _, event := range events {
+		obj, err := meta.Accessor(event.Object)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded := &ShardedResourceVersions{}
+		if err := decoded.Decode(obj.GetResourceVersion()); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, result{Type: event.Type, Name: obj.GetName(), ResourceVersions: decoded.ResourceVersions})
+	}
+	// the shard being sent stays at 0 until all of its objects are sent
+	want := []result{
+		{Type: watch.Added, Name: "a", ResourceVersions: []ShardedResourceVersion{{Identifier: "first"}, {Identifier: "second"}}},
+		{Type: watch.Added, Name: "b", ResourceVersions: []ShardedResourceVersion{{Identifier: "first"}, {Identifier: "second"}}},
+		{Type: watch.Added, Name: "c", ResourceVersions: []ShardedResourceVersion{{Identifier: "first", ResourceVersion: 10}, {Identifier: "second"}}},
+		{Type: watch.Bookmark, ResourceVersions: []ShardedResourceVersion{{Identifier: "first", ResourceVersion: 10}, {Identifier: "second", ResourceVersion: 20}}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("unexpected initial events (-want +got):\n%s", diff)
+	}
+
+	end := events[len(events)-1]
+	if !IsInitialEventsEnd(end) {
+		t.Errorf("expected the last event to end the initial events, got %#v", end.Object)
+	}
+	if kind := end.Object.GetObjectKind().GroupVersionKind().Kind; kind != "ConfigMap" {
+		t.Errorf("expected the bookmark to be a ConfigMap, got %q", kind)
+	}
+	versions, err := ShardResourceVersionsFrom(end.Object)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(map[string]int64{"first": 10, "second": 20}, versions); diff != "" {
+		t.Errorf("unexpected shard resource versions (-want +got):\n%s", diff)
+	}
+}
+
+func TestAggregateWatcherBookmarks(t *testing.T) {
+	for _, allowBookmarks := range []bool{true, false} {
+		state, err := NewResourceVersionState("", []string{"first", "second"}, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		first, second := watch.NewFake(), watch.NewFake()
+		w := newAggregateWatcher(state, map[string]watch.Interface{"first": first, "second": second}, nil, allowBookmarks)
+
+		bookmark := &unstructured.Unstructured{}
+		bookmark.SetAPIVersion("v1")
+		bookmark.SetKind("ConfigMap")
+		bookmark.SetResourceVersion("30")
+		second.Action(watch.Bookmark, bookmark)
+		if allowBookmarks {
+			event := <-w.ResultChan()
+			if event.Type != watch.Bookmark {
+				t.Fatalf("expected a bookmark, got %s", event.Type)
+			}
+			versions, err := ShardResourceVersionsFrom(event.Object)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(map[string]int64{"first": 0, "second": 30}, versions); diff != "" {
+				t.Errorf("unexpected shard resource versions (-want +got):\n%s", diff)
+			}
+		}
+
+		added := &unstructured.Unstructured{}
+		added.SetAPIVersion("v1")
+		added.SetKind("ConfigMap")
+		added.SetName("a")
+		added.SetResourceVersion("12")
+		first.Add(added)
+		if event := <-w.ResultChan(); event.Type != watch.Added {
+			t.Errorf("expected only bookmarks which are allowed to be sent, got %s", event.Type)
+		}
+		w.Stop()
+	}
+}
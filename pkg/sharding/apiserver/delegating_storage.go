@@ -33,6 +33,8 @@ import (
 	"k8s.io/apiserver/pkg/registry/rest"
 	clientrest "k8s.io/client-go/rest"
 	"k8s.io/kubernetes/pkg/genericcontrolplane"
+
+	"github.com/kcp-dev/kcp/pkg/statuserrors"
 )
 
 // delegatingStorage delegates requests off to individual shards based on the cluster
@@ -65,7 +67,7 @@ func (s *delegatingStorage) Watch(ctx context.Context, options *internalversion.
 	}
 	watcher, err := req.Watch(ctx)
 	if err != nil {
-		return nil, err
+		return nil, shardError(shardIdentifierFrom(ctx), err)
 	}
 	return mutatingWatcherFor(watcher, updater), nil
 }
@@ -106,7 +108,7 @@ func (s *delegatingStorage) routedResponse(ctx context.Context, resourceVersion
 		return &statusError.ErrStatus, nil
 	}
 	if e != nil {
-		return r, e
+		return r, shardError(shardIdentifierFrom(ctx), e)
 	}
 	if err := updater(r); err != nil {
 		e = fmt.Errorf("failed to update response: %w", err)
@@ -127,11 +129,11 @@ func (s *delegatingStorage) routedRequest(ctx context.Context, resourceVersion *
 
 	cfg, exists := s.shards[identifier]
 	if !exists {
-		return nil, nil, fmt.Errorf("no such shard %v", identifier)
+		return nil, nil, statuserrors.NewShardUnavailable(identifier, fmt.Errorf("no such shard"))
 	}
 	client, err := s.clientFor(cfg)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create sharded client: %w", err)
+		return nil, nil, statuserrors.NewShardUnavailable(identifier, fmt.Errorf("failed to create sharded client: %w", err))
 	}
 	rv, err := collapseResourceVersion(resourceVersion, identifier)
 	if err != nil {
@@ -150,6 +152,28 @@ func (s *delegatingStorage) routedRequest(ctx context.Context, resourceVersion *
 	return request, mutateOutputResourceVersion(identifier), nil
 }
 
+// shardIdentifierFrom returns the identifier of the shard serving the logical cluster of
+// the request, if any.
+func shardIdentifierFrom(ctx context.Context) string {
+	clusterName, err := genericapirequest.ClusterNameFrom(ctx)
+	if err != nil {
+		return ""
+	}
+	identifier, _, _ := genericcontrolplane.ParseClusterName(clusterName)
+	return identifier
+}
+
+// shardError returns the error of a request to the shard: errors the shard responded with
+// are passed along, while those of requests which did not get a response are returned as
+// ShardUnavailable.
+func shardError(identifier string, err error) error {
+	var statusErr k8serrors.APIStatus
+	if errors.As(err, &statusErr) {
+		return err
+	}
+	return statuserrors.NewShardUnavailable(identifier, err)
+}
+
 func mutateInputResourceVersion(identifier string) func(runtime.Object) error {
 	return func(obj runtime.Object) error {
 		switch r := obj.(type) {
@@ -31,6 +31,8 @@ import (
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/apiserver/pkg/endpoints/request"
 	"k8s.io/apiserver/pkg/registry/rest"
+
+	"github.com/kcp-dev/kcp/pkg/statuserrors"
 )
 
 type shardedStorage struct {
@@ -51,11 +53,18 @@ func (s *shardedStorage) Watch(ctx context.Context, options *internalversion.Lis
 		return nil, fmt.Errorf("failed to parse sharded resource version state: %w", err)
 	}
 
+	var initial []watch.Event
+	if sendsInitialEvents(options.ResourceVersion, options.AllowWatchBookmarks) {
+		if initial, err = initialEvents(ctx, state, s.listShard); err != nil {
+			return nil, err
+		}
+	}
+
 	watchers := map[string]watch.Interface{}
 	for i := range state.ResourceVersions {
 		client, err := s.clientFor(s.shards[state.ResourceVersions[i].Identifier])
 		if err != nil {
-			return nil, fmt.Errorf("failed to create sharded client: %w", err)
+			return nil, statuserrors.NewShardUnavailable(state.ResourceVersions[i].Identifier, fmt.Errorf("failed to create sharded client: %w", err))
 		}
 		request, err := s.requestFor(client)
 		if err != nil {
@@ -66,12 +75,53 @@ func (s *shardedStorage) Watch(ctx context.Context, options *internalversion.Lis
 		request.SetHeader("X-Kubernetes-Cluster", "*")
 		watcher, err := request.Watch(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("error executing watch request: %w", err)
+			return nil, shardError(state.ResourceVersions[i].Identifier, err)
 		}
 		watchers[state.ResourceVersions[i].Identifier] = watcher
 	}
 
-	return NewAggregateWatcher(state, watchers), nil
+	return newAggregateWatcher(state, watchers, initial, options.AllowWatchBookmarks), nil
+}
+
+// listShard lists all the objects of the shard, page after page.
+func (s *shardedStorage) listShard(ctx context.Context, identifier string) (*unstructured.UnstructuredList, error) {
+	client, err := s.clientFor(s.shards[identifier])
+	if err != nil {
+		return nil, statuserrors.NewShardUnavailable(identifier, fmt.Errorf("failed to create sharded client: %w", err))
+	}
+	var output *unstructured.UnstructuredList
+	continueToken := ""
+	for {
+		request, err := s.requestFor(client)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create sharded request: %w", err)
+		}
+		request.OverwriteParam("watch", "false")
+		request.OverwriteParam("allowWatchBookmarks", "false")
+		request.OverwriteParam("resourceVersion", "")
+		request.OverwriteParam("limit", "500")
+		request.OverwriteParam("continue", continueToken)
+		request.SetHeader("X-Kubernetes-Cluster", "*")
+		result, err := request.Do(ctx).Get()
+		if err != nil {
+			return nil, shardError(identifier, err)
+		}
+		list, ok := result.(*unstructured.UnstructuredList)
+		if !ok {
+			return nil, fmt.Errorf("could not parse sharded response as a list, got %T", result)
+		}
+		if output == nil {
+			output = list
+		} else {
+			output.Items = append(output.Items, list.Items...)
+			output.SetResourceVersion(list.GetResourceVersion())
+		}
+		if continueToken = list.GetContinue(); continueToken == "" {
+			break
+		}
+	}
+	output.SetContinue("")
+	return output, nil
 }
 
 type stopper interface {
@@ -82,12 +132,17 @@ type aggregateWatcher struct {
 	delegates []stopper
 	wg        *sync.WaitGroup
 	events    chan watch.Event
+	// stopped is closed when the client stops watching, to stop sending events.
+	stopped chan struct{}
+	// bookmarks tells whether the client accepts bookmarks.
+	bookmarks bool
 
 	state *ShardedResourceVersions
 	lock  *sync.Mutex
 }
 
 func (a *aggregateWatcher) Stop() {
+	close(a.stopped)
 	for i := range a.delegates {
 		a.delegates[i].Stop()
 	}
@@ -95,6 +150,14 @@ func (a *aggregateWatcher) Stop() {
 	close(a.events)
 }
 
+// send sends the event unless the client stopped watching.
+func (a *aggregateWatcher) send(event watch.Event) {
+	select {
+	case a.events <- event:
+	case <-a.stopped:
+	}
+}
+
 func (a *aggregateWatcher) ResultChan() <-chan watch.Event {
 	return a.events
 }
@@ -102,47 +165,73 @@ func (a *aggregateWatcher) ResultChan() <-chan watch.Event {
 func (a *aggregateWatcher) process(identifier string, event watch.Event) {
 	obj, ok := event.Object.(metav1.Common)
 	if !ok {
-		a.events <- watch.Event{
+		a.send(watch.Event{
 			Type:   watch.Error,
 			Object: &errors.NewInternalError(fmt.Errorf("watch event contained a %T which could not cast to metav1.Common", event.Object)).ErrStatus,
-		}
+		})
+		return
+	}
+	if event.Type == watch.Bookmark && !a.bookmarks {
 		return
 	}
 	a.lock.Lock()
 	err := a.state.UpdateWith(identifier, obj)
+	if err == nil && event.Type == watch.Bookmark {
+		err = annotateBookmark(event.Object, a.state)
+	}
 	a.lock.Unlock()
 	if err != nil {
-		a.events <- watch.Event{
+		a.send(watch.Event{
 			Type:   watch.Error,
 			Object: &errors.NewInternalError(fmt.Errorf("failed to update resource version vector clock: %w", err)).ErrStatus,
-		}
+		})
 		return
 	}
 	encoded, err := a.state.Encode()
 	if err != nil {
-		a.events <- watch.Event{
+		a.send(watch.Event{
 			Type:   watch.Error,
 			Object: &errors.NewInternalError(fmt.Errorf("failed to encode resource version vector clock: %w", err)).ErrStatus,
-		}
+		})
 		return
 	}
 	obj.SetResourceVersion(encoded)
-	a.events <- event
+	a.send(event)
 }
 
 func NewAggregateWatcher(state *ShardedResourceVersions, delegates map[string]watch.Interface) watch.Interface {
+	return newAggregateWatcher(state, delegates, nil, true)
+}
+
+// newAggregateWatcher returns a watcher sending the initial events, then the events of the
+// delegates. Bookmarks are only sent when the client accepts them.
+func newAggregateWatcher(state *ShardedResourceVersions, delegates map[string]watch.Interface, initial []watch.Event, bookmarks bool) watch.Interface {
 	w := &aggregateWatcher{
 		delegates: []stopper{},
 		events:    make(chan watch.Event),
+		stopped:   make(chan struct{}),
 		wg:        &sync.WaitGroup{},
+		bookmarks: bookmarks,
 		state:     state,
 		lock:      &sync.Mutex{},
 	}
+	// the events of the delegates follow the initial ones
+	initialSent := make(chan struct{})
+	w.wg.Add(1)
+	go func() {
+		defer utilruntime.HandleCrash()
+		defer w.wg.Done()
+		defer close(initialSent)
+		for _, event := range initial {
+			w.send(event)
+		}
+	}()
 	for identifier := range delegates {
 		w.wg.Add(1)
 		go func(identifier string, events <-chan watch.Event) {
 			defer utilruntime.HandleCrash()
 			defer w.wg.Done()
+			<-initialSent
 			for event := range events {
 				w.process(identifier, event)
 			}
@@ -176,7 +265,7 @@ func (s *shardedStorage) List(ctx context.Context, options *internalversion.List
 		}
 		client, err := s.clientFor(s.shards[shard])
 		if err != nil {
-			return nil, fmt.Errorf("failed to create sharded client: %w", err)
+			return nil, statuserrors.NewShardUnavailable(shard, fmt.Errorf("failed to create sharded client: %w", err))
 		}
 		request, err := s.requestFor(client)
 		if err != nil {
@@ -187,7 +276,7 @@ func (s *shardedStorage) List(ctx context.Context, options *internalversion.List
 		request.SetHeader("X-Kubernetes-Cluster", "*")
 		result, err := request.Do(ctx).Get()
 		if err != nil {
-			return nil, fmt.Errorf("failed to get data from shard %q: %w", shard, err)
+			return nil, shardError(shard, err)
 		}
 		var list *unstructured.UnstructuredList
 		switch r := result.(type) {
@@ -30,11 +30,19 @@ type IdentifiedConfig struct {
 	Config     *rest.Config
 }
 
+// Compatibility returns why requests can't be proxied to the shard with the given
+// identifier, or an empty string if they can.
+type Compatibility func(identifier string) string
+
 type ClientLoader struct {
 	*sync.RWMutex
-	clients map[string]*rest.Config
+	clients       map[string]*rest.Config
+	compatibility Compatibility
 }
 
+// New returns a ClientLoader for the shards of the contexts of the given kubeconfig, each
+// identified by the name of its context, and for the shard whose config is sent on the
+// injector, if any.
 func New(delegates string, injector <-chan IdentifiedConfig) (*ClientLoader, error) {
 	l := &ClientLoader{
 		clients: map[string]*rest.Config{},
@@ -57,6 +65,9 @@ func New(delegates string, injector <-chan IdentifiedConfig) (*ClientLoader, err
 		l.Unlock()
 	}
 
+	if injector == nil {
+		return l, nil
+	}
 	l.Lock()
 	go func() {
 		defer l.Unlock()
@@ -77,3 +88,41 @@ func (c *ClientLoader) Clients() map[string]*rest.Config {
 	c.Unlock()
 	return out
 }
+
+// Set replaces the config used to reach the given shard, e.g. after its credentials
+// were rotated.
+func (c *ClientLoader) Set(identifier string, config *rest.Config) {
+	config = rest.CopyConfig(config)
+	config.ContentType = "application/json"
+	c.Lock()
+	c.clients[genericcontrolplane.SanitizeClusterId(identifier)] = config
+	c.Unlock()
+}
+
+// SetCompatibility makes the loader check the shards with the given function before
+// requests are proxied to them.
+func (c *ClientLoader) SetCompatibility(compatibility Compatibility) {
+	c.Lock()
+	c.compatibility = compatibility
+	c.Unlock()
+}
+
+// CompatibleClients returns the configs of the shards requests can be proxied to, and
+// why the other shards are incompatible.
+func (c *ClientLoader) CompatibleClients() (map[string]*rest.Config, map[string]string) {
+	clients := c.Clients()
+	c.RLock()
+	compatibility := c.compatibility
+	c.RUnlock()
+	incompatible := map[string]string{}
+	if compatibility == nil {
+		return clients, incompatible
+	}
+	for identifier := range clients {
+		if reason := compatibility(identifier); reason != "" {
+			incompatible[identifier] = reason
+			delete(clients, identifier)
+		}
+	}
+	return clients, incompatible
+}
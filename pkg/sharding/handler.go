@@ -17,12 +17,21 @@ limitations under the License.
 package sharding
 
 import (
+	"fmt"
 	"net/http"
 	"time"
 
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/handlers/responsewriters"
 	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/apiserver/pkg/warning"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/kubernetes/pkg/genericcontrolplane"
 
 	"github.com/kcp-dev/kcp/pkg/sharding/apiserver"
+	"github.com/kcp-dev/kcp/pkg/statuserrors"
 )
 
 func ServeHTTP(apiHandler http.Handler, loader *ClientLoader) func(w http.ResponseWriter, req *http.Request) {
@@ -42,7 +51,48 @@ func ServeHTTP(apiHandler http.Handler, loader *ClientLoader) func(w http.Respon
 			apiHandler.ServeHTTP(w, req)
 			return
 		}
-		handler := apiserver.NewShardedHandler(loader.Clients(), 0, 10*time.Minute)
+		u, ok := request.UserFrom(req.Context())
+		if !ok {
+			http.Error(w, "no user", http.StatusInternalServerError)
+			return
+		}
+		clients, incompatible := loader.CompatibleClients()
+		if cluster := request.ClusterFrom(req.Context()); cluster != nil && !cluster.Wildcard {
+			// requests routed to an incompatible shard are refused
+			if identifier, _, err := genericcontrolplane.ParseClusterName(cluster.Name); err == nil {
+				if reason, found := incompatible[identifier]; found {
+					err := statuserrors.NewShardIncompatible(identifier, reason)
+					responsewriters.ErrorNegotiated(err, scheme.Codecs, schema.GroupVersion{Group: info.APIGroup, Version: info.APIVersion}, w, req)
+					return
+				}
+			}
+		}
+		// while cross-cluster requests leave incompatible shards out
+		for identifier, reason := range incompatible {
+			warning.AddWarning(req.Context(), "", fmt.Sprintf("shard %q was left out because it is incompatible: %s", identifier, reason))
+		}
+		handler := apiserver.NewShardedHandler(impersonating(clients, u), 0, 10*time.Minute)
 		handler.ServeHTTP(w, req)
 	}
 }
+
+// impersonating makes the clients of the shards impersonate the user of the request, so
+// that the shards authorize the original user instead of the credentials of the proxy,
+// which are only allowed to impersonate.
+func impersonating(clients map[string]*rest.Config, u user.Info) map[string]*rest.Config {
+	for identifier, config := range clients {
+		clients[identifier] = Impersonating(config, u)
+	}
+	return clients
+}
+
+// Impersonating returns a copy of the config of a shard impersonating the given user.
+func Impersonating(config *rest.Config, u user.Info) *rest.Config {
+	config = rest.CopyConfig(config)
+	config.Impersonate = rest.ImpersonationConfig{
+		UserName: u.GetName(),
+		Groups:   u.GetGroups(),
+		Extra:    u.GetExtra(),
+	}
+	return config
+}
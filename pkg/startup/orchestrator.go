@@ -0,0 +1,308 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package startup starts the controllers of kcp in steps, like bootstrapping CRDs, syncing
+// informers and starting controllers, which run concurrently as soon as the steps they
+// require are done. The state of every step is reported by a readyz check.
+package startup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	apiextensionsv1client "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/typed/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apiserver/pkg/server/healthz"
+	"k8s.io/klog/v2"
+
+	"github.com/kcp-dev/kcp/config"
+)
+
+// Step is a step of the startup.
+type Step struct {
+	// Name names the step in the Requires of other steps and in its readyz check.
+	Name string
+	// Requires are the names of the steps which must be done before this one starts.
+	Requires []string
+	// Timeout fails the step when it takes longer. Zero means no timeout.
+	Timeout time.Duration
+	// Run runs the step until it is done. Its context is done when the step times out:
+	// what keeps running afterwards, like informers and controllers, must be bound to
+	// another context.
+	Run func(ctx context.Context) error
+}
+
+// state is the state of a step.
+type state struct {
+	started  time.Time
+	finished bool
+	err      error
+	// done is closed when the step finishes, successfully or not
+	done chan struct{}
+}
+
+// Orchestrator runs steps concurrently, each once the steps it requires are done.
+type Orchestrator struct {
+	lock   sync.RWMutex
+	steps  []Step
+	states map[string]*state
+}
+
+// NewOrchestrator returns an Orchestrator without any step.
+func NewOrchestrator() *Orchestrator {
+	return &Orchestrator{states: map[string]*state{}}
+}
+
+// Add adds steps. It must not be called once the orchestrator runs.
+func (o *Orchestrator) Add(steps ...Step) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	for _, step := range steps {
+		o.steps = append(o.steps, step)
+		o.states[step.Name] = &state{done: make(chan struct{})}
+	}
+}
+
+// Validate returns an error when steps have the same name, or require unknown steps or
+// themselves, directly or not.
+func (o *Orchestrator) Validate() error {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+
+	var errs []error
+	steps := map[string]Step{}
+	for _, step := range o.steps {
+		if _, found := steps[step.Name]; found {
+			errs = append(errs, fmt.Errorf("duplicate startup step %q", step.Name))
+		}
+		steps[step.Name] = step
+	}
+	for _, step := range o.steps {
+		for _, required := range step.Requires {
+			if _, found := steps[required]; !found {
+				errs = append(errs, fmt.Errorf("startup step %q requires unknown step %q", step.Name, required))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return utilerrors.NewAggregate(errs)
+	}
+
+	// depth-first search of cycles, from every step
+	const visiting, visited = 1, 2
+	marks := map[string]int{}
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch marks[name] {
+		case visiting:
+			return fmt.Errorf("startup steps require each other: %s", strings.Join(append(path, name), " -> "))
+		case visited:
+			return nil
+		}
+		marks[name] = visiting
+		for _, required := range steps[name].Requires {
+			if err := visit(required, append(path, name)); err != nil {
+				return err
+			}
+		}
+		marks[name] = visited
+		return nil
+	}
+	for _, step := range o.steps {
+		if err := visit(step.Name, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run runs all the steps and waits for them to finish. It returns the errors of the failed
+// steps; the steps requiring them fail too, without running.
+func (o *Orchestrator) Run(ctx context.Context) error {
+	o.lock.RLock()
+	steps := o.steps
+	o.lock.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, step := range steps {
+		wg.Add(1)
+		go func(step Step) {
+			defer wg.Done()
+			o.finish(step.Name, o.run(ctx, step))
+		}(step)
+	}
+	wg.Wait()
+
+	var errs []error
+	for _, step := range steps {
+		if _, err := o.state(step.Name); err != nil {
+			errs = append(errs, fmt.Errorf("startup step %q failed: %w", step.Name, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func (o *Orchestrator) run(ctx context.Context, step Step) error {
+	for _, required := range step.Requires {
+		o.lock.RLock()
+		done := o.states[required].done
+		o.lock.RUnlock()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if _, err := o.state(required); err != nil {
+			return fmt.Errorf("required step %q failed", required)
+		}
+	}
+
+	o.lock.Lock()
+	o.states[step.Name].started = time.Now()
+	o.lock.Unlock()
+	klog.Infof("Starting startup step %q", step.Name)
+
+	stepCtx, cancel := ctx, context.CancelFunc(func() {})
+	if step.Timeout > 0 {
+		stepCtx, cancel = context.WithTimeout(ctx, step.Timeout)
+	}
+	defer cancel()
+	// the step is failed on timeout even if it doesn't return
+	result := make(chan error, 1)
+	go func() {
+		result <- step.Run(stepCtx)
+	}()
+	select {
+	case err := <-result:
+		return err
+	case <-stepCtx.Done():
+		if errors.Is(stepCtx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("timed out after %s", step.Timeout)
+		}
+		return stepCtx.Err()
+	}
+}
+
+func (o *Orchestrator) finish(name string, err error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	s := o.states[name]
+	s.finished, s.err = true, err
+	close(s.done)
+	if err != nil {
+		klog.Errorf("Startup step %q failed: %v", name, err)
+	} else {
+		klog.Infof("Finished startup step %q in %s", name, time.Since(s.started).Round(time.Millisecond))
+	}
+}
+
+// state returns whether the step finished, and its error if it failed.
+func (o *Orchestrator) state(name string) (bool, error) {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+	s := o.states[name]
+	return s.finished, s.err
+}
+
+// ReadyzChecks returns a readyz check per step, named startup-<step>, which fails until the
+// step is done.
+func (o *Orchestrator) ReadyzChecks() []healthz.HealthChecker {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+	checks := make([]healthz.HealthChecker, 0, len(o.steps))
+	for _, step := range o.steps {
+		checks = append(checks, &stepCheck{o: o, step: step})
+	}
+	return checks
+}
+
+type stepCheck struct {
+	o    *Orchestrator
+	step Step
+}
+
+func (c *stepCheck) Name() string {
+	return "startup-" + c.step.Name
+}
+
+func (c *stepCheck) Check(_ *http.Request) error {
+	c.o.lock.RLock()
+	defer c.o.lock.RUnlock()
+	s := c.o.states[c.step.Name]
+	switch {
+	case s.finished && s.err != nil:
+		return fmt.Errorf("failed: %w", s.err)
+	case s.finished:
+		return nil
+	case !s.started.IsZero():
+		return fmt.Errorf("running for %s", time.Since(s.started).Round(time.Second))
+	}
+	var waiting []string
+	for _, required := range c.step.Requires {
+		if !c.o.states[required].finished {
+			waiting = append(waiting, required)
+		}
+	}
+	if len(waiting) > 0 {
+		return fmt.Errorf("waiting for %s", strings.Join(waiting, ", "))
+	}
+	return errors.New("not started")
+}
+
+// BootstrapCRDs returns the Run function of a step creating the given CRDs of kcp, and
+// waiting for them to be established.
+func BootstrapCRDs(client apiextensionsv1client.CustomResourceDefinitionInterface, gks ...metav1.GroupKind) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return config.BootstrapCustomResourceDefinitions(ctx, client, gks)
+	}
+}
+
+// InformerFactory is a shared informer factory, of any clientset.
+type InformerFactory interface {
+	Start(stopCh <-chan struct{})
+	WaitForCacheSync(stopCh <-chan struct{}) map[reflect.Type]bool
+}
+
+// SyncInformers returns the Run function of a step starting the informers of the given
+// factories until the stop channel is closed, and waiting for them to sync.
+func SyncInformers(stopCh <-chan struct{}, factories ...InformerFactory) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		for _, factory := range factories {
+			factory.Start(stopCh)
+		}
+		var unsynced []string
+		for _, factory := range factories {
+			for informerType, synced := range factory.WaitForCacheSync(ctx.Done()) {
+				if !synced {
+					unsynced = append(unsynced, informerType.String())
+				}
+			}
+		}
+		if len(unsynced) > 0 {
+			sort.Strings(unsynced)
+			return fmt.Errorf("informers not synced: %s", strings.Join(unsynced, ", "))
+		}
+		return nil
+	}
+}
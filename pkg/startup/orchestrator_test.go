@@ -0,0 +1,135 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package startup
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	noop := func(context.Context) error { return nil }
+	for _, tc := range []struct {
+		name     string
+		steps    []Step
+		expected string
+	}{
+		{name: "valid", steps: []Step{{Name: "a", Run: noop}, {Name: "b", Requires: []string{"a"}, Run: noop}}},
+		{name: "duplicate", steps: []Step{{Name: "a", Run: noop}, {Name: "a", Run: noop}}, expected: `duplicate startup step "a"`},
+		{name: "unknown", steps: []Step{{Name: "a", Requires: []string{"b"}, Run: noop}}, expected: `startup step "a" requires unknown step "b"`},
+		{name: "cycle", steps: []Step{
+			{Name: "a", Requires: []string{"c"}, Run: noop},
+			{Name: "b", Requires: []string{"a"}, Run: noop},
+			{Name: "c", Requires: []string{"b"}, Run: noop},
+		}, expected: "startup steps require each other: a -> c -> b -> a"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			o := NewOrchestrator()
+			o.Add(tc.steps...)
+			err := o.Validate()
+			if tc.expected == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.expected {
+				t.Fatalf("expected error %q, got %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	var lock sync.Mutex
+	var ran []string
+	step := func(name string, err error) func(context.Context) error {
+		return func(context.Context) error {
+			lock.Lock()
+			defer lock.Unlock()
+			ran = append(ran, name)
+			return err
+		}
+	}
+	release := make(chan struct{})
+	o := NewOrchestrator()
+	o.Add(
+		Step{Name: "controllers", Requires: []string{"informers"}, Run: step("controllers", nil)},
+		Step{Name: "informers", Requires: []string{"crds"}, Run: step("informers", nil)},
+		Step{Name: "crds", Run: func(ctx context.Context) error {
+			<-release
+			return step("crds", nil)(ctx)
+		}},
+		Step{Name: "broken", Run: step("broken", errors.New("boom"))},
+		Step{Name: "after-broken", Requires: []string{"broken"}, Run: step("after-broken", nil)},
+		Step{Name: "slow", Timeout: 10 * time.Millisecond, Run: func(context.Context) error {
+			select {}
+		}},
+	)
+	if err := o.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	checks := map[string]func() error{}
+	for _, check := range o.ReadyzChecks() {
+		check := check
+		checks[check.Name()] = func() error { return check.Check(nil) }
+	}
+	if err := checks["startup-informers"](); err == nil || err.Error() != "waiting for crds" {
+		t.Errorf("expected the informers to wait for the CRDs, got %v", err)
+	}
+
+	errs := make(chan error)
+	go func() {
+		errs <- o.Run(context.Background())
+	}()
+	time.Sleep(50 * time.Millisecond)
+	lock.Lock()
+	if len(ran) != 1 || ran[0] != "broken" {
+		t.Errorf("expected only the broken step to run before the CRDs are bootstrapped, got %v", ran)
+	}
+	lock.Unlock()
+	if err := checks["startup-crds"](); err == nil || !strings.HasPrefix(err.Error(), "running for") {
+		t.Errorf("expected the CRDs to be bootstrapping, got %v", err)
+	}
+	close(release)
+
+	err := <-errs
+	if err == nil || !strings.Contains(err.Error(), `startup step "broken" failed: boom`) ||
+		!strings.Contains(err.Error(), `startup step "after-broken" failed: required step "broken" failed`) ||
+		!strings.Contains(err.Error(), `startup step "slow" failed: timed out after 10ms`) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if expected := []string{"broken", "crds", "informers", "controllers"}; strings.Join(ran, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected steps %v to run, got %v", expected, ran)
+	}
+	for name, expected := range map[string]string{
+		"startup-controllers":  "",
+		"startup-after-broken": `failed: required step "broken" failed`,
+		"startup-slow":         "failed: timed out after 10ms",
+	} {
+		err := checks[name]()
+		if expected == "" && err != nil {
+			t.Errorf("expected check %s to pass, got %v", name, err)
+		} else if expected != "" && (err == nil || err.Error() != expected) {
+			t.Errorf("expected check %s to fail with %q, got %v", name, expected, err)
+		}
+	}
+}
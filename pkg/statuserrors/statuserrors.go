@@ -0,0 +1,160 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package statuserrors defines the API errors kcp returns when a request can't be served
+// because of the state of a shard, a workspace or a placement, so that clients and
+// controllers can tell them apart from other failures with their reason, and whether
+// retrying them helps with their RetryableCause.
+package statuserrors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ReasonShardUnavailable means the shard serving the request could not be reached.
+	// It is returned with 503 Service Unavailable and can be retried.
+	ReasonShardUnavailable metav1.StatusReason = "ShardUnavailable"
+	// ReasonShardIncompatible means the shard serving the request runs a kcp version or
+	// feature gates which are incompatible with the shard proxying the request. It is
+	// returned with 503 Service Unavailable, and retrying only helps once the shards are
+	// upgraded.
+	ReasonShardIncompatible metav1.StatusReason = "ShardIncompatible"
+	// ReasonWorkspaceNotReady means the workspace is not scheduled or initialized yet.
+	// It is returned with 503 Service Unavailable and can be retried.
+	ReasonWorkspaceNotReady metav1.StatusReason = "WorkspaceNotReady"
+	// ReasonWorkspaceHibernated means the workspace is hibernated, and is being woken up
+	// by the request. It is returned with 425 Too Early and can be retried.
+	ReasonWorkspaceHibernated metav1.StatusReason = "WorkspaceHibernated"
+	// ReasonPlacementFailed means no Cluster is eligible for the Placement of an object.
+	// It is returned with 409 Conflict, and retrying only helps once the Clusters or the
+	// Placement change.
+	ReasonPlacementFailed metav1.StatusReason = "PlacementFailed"
+)
+
+// RetryableCause is the type of the cause, set on all the errors of this package, whose
+// message is "true" when the request can be retried as is after RetryAfterSeconds, and
+// "false" when something has to change first.
+const RetryableCause metav1.CauseType = "Retryable"
+
+// retryAfterSeconds is how long clients are asked to wait before retrying, unless the
+// error tells otherwise.
+const retryAfterSeconds = 5
+
+// NewShardUnavailable returns the error of a request which failed to reach the shard.
+func NewShardUnavailable(shard string, err error) *apierrors.StatusError {
+	return newError(http.StatusServiceUnavailable, ReasonShardUnavailable, retryAfterSeconds,
+		metav1.StatusDetails{Group: "tenancy.kcp.dev", Kind: "workspaceshards", Name: shard},
+		fmt.Sprintf("shard %q is unavailable: %v", shard, err))
+}
+
+// NewShardIncompatible returns the error of a request which is not proxied to the shard
+// because it is incompatible, for the given reason.
+func NewShardIncompatible(shard, reason string) *apierrors.StatusError {
+	return newError(http.StatusServiceUnavailable, ReasonShardIncompatible, 0,
+		metav1.StatusDetails{Group: "tenancy.kcp.dev", Kind: "workspaceshards", Name: shard},
+		fmt.Sprintf("shard %q is incompatible: %s", shard, reason))
+}
+
+// NewWorkspaceNotReady returns the error of a request to a workspace which is not ready
+// to serve it yet, for the given reason.
+func NewWorkspaceNotReady(workspace, reason string) *apierrors.StatusError {
+	return newError(http.StatusServiceUnavailable, ReasonWorkspaceNotReady, retryAfterSeconds,
+		metav1.StatusDetails{Group: "tenancy.kcp.dev", Kind: "workspaces", Name: workspace},
+		fmt.Sprintf("workspace %q is not ready: %s", workspace, reason))
+}
+
+// NewWorkspaceHibernated returns the error of a request to a hibernated workspace, which
+// is expected to wake up within the given number of seconds.
+func NewWorkspaceHibernated(workspace string, wakeUpSeconds int32) *apierrors.StatusError {
+	return newError(http.StatusTooEarly, ReasonWorkspaceHibernated, wakeUpSeconds,
+		metav1.StatusDetails{Group: "tenancy.kcp.dev", Kind: "workspaces", Name: workspace},
+		fmt.Sprintf("workspace %q is hibernated and waking up, please retry", workspace))
+}
+
+// NewPlacementFailed returns the error of an object which can't be placed on any Cluster
+// by the given Placement.
+func NewPlacementFailed(placement string) *apierrors.StatusError {
+	return newError(http.StatusConflict, ReasonPlacementFailed, 0,
+		metav1.StatusDetails{Group: "cluster.kcp.dev", Kind: "placements", Name: placement},
+		fmt.Sprintf("no Cluster is eligible for Placement %q", placement))
+}
+
+func newError(code int32, reason metav1.StatusReason, retryAfter int32, details metav1.StatusDetails, message string) *apierrors.StatusError {
+	details.RetryAfterSeconds = retryAfter
+	details.Causes = []metav1.StatusCause{{
+		Type:    RetryableCause,
+		Message: strconv.FormatBool(retryAfter > 0),
+	}}
+	return &apierrors.StatusError{ErrStatus: metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    code,
+		Reason:  reason,
+		Details: &details,
+		Message: message,
+	}}
+}
+
+// IsShardUnavailable returns whether the error was returned because a shard could not be
+// reached.
+func IsShardUnavailable(err error) bool {
+	return apierrors.ReasonForError(err) == ReasonShardUnavailable
+}
+
+// IsShardIncompatible returns whether the error was returned because a shard is
+// incompatible.
+func IsShardIncompatible(err error) bool {
+	return apierrors.ReasonForError(err) == ReasonShardIncompatible
+}
+
+// IsWorkspaceNotReady returns whether the error was returned because a workspace is not
+// ready.
+func IsWorkspaceNotReady(err error) bool {
+	return apierrors.ReasonForError(err) == ReasonWorkspaceNotReady
+}
+
+// IsWorkspaceHibernated returns whether the error was returned because a workspace is
+// hibernated.
+func IsWorkspaceHibernated(err error) bool {
+	return apierrors.ReasonForError(err) == ReasonWorkspaceHibernated
+}
+
+// IsPlacementFailed returns whether the error was returned because no Cluster is
+// eligible for a Placement.
+func IsPlacementFailed(err error) bool {
+	return apierrors.ReasonForError(err) == ReasonPlacementFailed
+}
+
+// IsRetryable returns whether the error is one of this package which can be retried as
+// is, after the number of seconds it suggests.
+func IsRetryable(err error) bool {
+	var statusErr apierrors.APIStatus
+	if !errors.As(err, &statusErr) || statusErr.Status().Details == nil {
+		return false
+	}
+	for _, cause := range statusErr.Status().Details.Causes {
+		if cause.Type == RetryableCause {
+			return cause.Message == "true"
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statuserrors
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+func TestErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		err       error
+		is        func(error) bool
+		code      int32
+		retryable bool
+	}{
+		{
+			name:      "shard unavailable",
+			err:       NewShardUnavailable("shard-1", fmt.Errorf("connection refused")),
+			is:        IsShardUnavailable,
+			code:      http.StatusServiceUnavailable,
+			retryable: true,
+		},
+		{
+			name: "shard incompatible",
+			err:  NewShardIncompatible("shard-1", "too old"),
+			is:   IsShardIncompatible,
+			code: http.StatusServiceUnavailable,
+		},
+		{
+			name:      "workspace not ready",
+			err:       NewWorkspaceNotReady("team", "not scheduled yet"),
+			is:        IsWorkspaceNotReady,
+			code:      http.StatusServiceUnavailable,
+			retryable: true,
+		},
+		{
+			name:      "workspace hibernated",
+			err:       NewWorkspaceHibernated("team", 10),
+			is:        IsWorkspaceHibernated,
+			code:      http.StatusTooEarly,
+			retryable: true,
+		},
+		{
+			name: "placement failed",
+			err:  NewPlacementFailed("default"),
+			is:   IsPlacementFailed,
+			code: http.StatusConflict,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			wrapped := fmt.Errorf("wrapped: %w", tc.err)
+			if !tc.is(wrapped) {
+				t.Errorf("expected the reason of %v to match", wrapped)
+			}
+			if code := tc.err.(*apierrors.StatusError).Status().Code; code != tc.code {
+				t.Errorf("expected code %d, got %d", tc.code, code)
+			}
+			if retryable := IsRetryable(wrapped); retryable != tc.retryable {
+				t.Errorf("expected retryable %v, got %v", tc.retryable, retryable)
+			}
+			if _, suggested := apierrors.SuggestsClientDelay(tc.err); suggested != tc.retryable {
+				t.Errorf("expected a client delay to be suggested: %v, got %v", tc.retryable, suggested)
+			}
+		})
+	}
+
+	if IsRetryable(apierrors.NewServiceUnavailable("overloaded")) || IsShardUnavailable(fmt.Errorf("no shard")) {
+		t.Errorf("expected errors of other reasons not to match")
+	}
+}
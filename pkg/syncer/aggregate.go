@@ -0,0 +1,263 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/client-go/dynamic"
+)
+
+// ClusterStatusAnnotationPrefix prefixes the annotations of upstream objects holding their
+// status in each cluster they are synced to. The rest of the annotation key is the cluster.
+const ClusterStatusAnnotationPrefix = "status.kcp.dev/"
+
+// StatusAggregator combines the statuses of an object in the clusters it is synced to
+// into the status of the upstream object.
+type StatusAggregator interface {
+	// Aggregate returns the upstream status given the status in each cluster, keyed by
+	// cluster.
+	Aggregate(statuses map[string]interface{}) (interface{}, error)
+}
+
+// StatusAggregatorFunc is a function implementing StatusAggregator.
+type StatusAggregatorFunc func(statuses map[string]interface{}) (interface{}, error)
+
+func (f StatusAggregatorFunc) Aggregate(statuses map[string]interface{}) (interface{}, error) {
+	return f(statuses)
+}
+
+// DefaultStatusAggregator aggregates statuses of any resource: counters are summed, the
+// observed generation is the lowest one, a condition is only true if it is true in every
+// cluster, and other fields are taken from the first cluster by name.
+var DefaultStatusAggregator StatusAggregator = StatusAggregatorFunc(mergeStatuses)
+
+func clusterStatusAnnotation(clusterID string) string {
+	return ClusterStatusAnnotationPrefix + clusterID
+}
+
+// clusterStatuses returns the statuses of the object in each cluster, keyed by cluster.
+func clusterStatuses(obj *unstructured.Unstructured) (map[string]interface{}, error) {
+	statuses := map[string]interface{}{}
+	for key, value := range obj.GetAnnotations() {
+		if !strings.HasPrefix(key, ClusterStatusAnnotationPrefix) {
+			continue
+		}
+		var status interface{}
+		if err := utiljson.Unmarshal([]byte(value), &status); err != nil {
+			return nil, fmt.Errorf("invalid %s annotation: %w", key, err)
+		}
+		statuses[strings.TrimPrefix(key, ClusterStatusAnnotationPrefix)] = status
+	}
+	return statuses, nil
+}
+
+// withoutClusterStatuses removes the cluster status annotations from the given annotations.
+func withoutClusterStatuses(annotations map[string]string) map[string]string {
+	for key := range annotations {
+		if strings.HasPrefix(key, ClusterStatusAnnotationPrefix) {
+			delete(annotations, key)
+		}
+	}
+	return annotations
+}
+
+func (c *Controller) statusAggregatorFor(gvr schema.GroupVersionResource) StatusAggregator {
+	if aggregator, found := c.statusAggregators[gvr.GroupResource()]; found {
+		return aggregator
+	}
+	return DefaultStatusAggregator
+}
+
+// setClusterStatus records the status of the upstream object in the cluster of the
+// syncer, or forgets it for a nil status, then applies the status aggregated across all
+// the clusters of the object. It does nothing if the upstream object doesn't exist.
+func (c *Controller) setClusterStatus(ctx context.Context, upstream dynamic.ResourceInterface, gvr schema.GroupVersionResource, name string, status interface{}) error {
+	var value interface{}
+	if status != nil {
+		data, err := json.Marshal(status)
+		if err != nil {
+			return err
+		}
+		value = string(data)
+	}
+	// A merge patch fails rather than creating the object when it was deleted.
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				clusterStatusAnnotation(c.clusterID): value,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	obj, err := upstream.Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	statuses, err := clusterStatuses(obj)
+	if err != nil {
+		return err
+	}
+	if len(statuses) == 0 {
+		return nil
+	}
+	aggregated, err := c.statusAggregatorFor(gvr).Aggregate(statuses)
+	if err != nil {
+		return fmt.Errorf("failed to aggregate status of %s %s: %w", gvr.Resource, name, err)
+	}
+
+	// Only the status is applied, leaving the rest of the upstream object to its owners.
+	applied := &unstructured.Unstructured{Object: map[string]interface{}{
+		"status": aggregated,
+	}}
+	applied.SetAPIVersion(obj.GetAPIVersion())
+	applied.SetKind(obj.GetKind())
+	applied.SetNamespace(obj.GetNamespace())
+	applied.SetName(obj.GetName())
+	data, err := json.Marshal(applied.Object)
+	if err != nil {
+		return err
+	}
+	force := true
+	if _, err := upstream.Patch(ctx, name, types.ApplyPatchType, data, metav1.PatchOptions{FieldManager: statusFieldManager, Force: &force}, "status"); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// mergeStatuses implements DefaultStatusAggregator.
+func mergeStatuses(statuses map[string]interface{}) (interface{}, error) {
+	clusters := make([]string, 0, len(statuses))
+	for cluster := range statuses {
+		clusters = append(clusters, cluster)
+	}
+	sort.Strings(clusters)
+	if len(clusters) == 1 {
+		return statuses[clusters[0]], nil
+	}
+
+	var keys []string
+	seen := map[string]bool{}
+	values := map[string][]interface{}{}
+	for _, cluster := range clusters {
+		status, ok := statuses[cluster].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("status in cluster %s is a %T, not an object", cluster, statuses[cluster])
+		}
+		for key, value := range status {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+			values[key] = append(values[key], value)
+		}
+	}
+
+	merged := map[string]interface{}{}
+	for _, key := range keys {
+		merged[key] = mergeField(key, values[key], clusters)
+	}
+	return merged, nil
+}
+
+func mergeField(key string, values []interface{}, clusters []string) interface{} {
+	var ints []int64
+	for _, value := range values {
+		if i, ok := value.(int64); ok {
+			ints = append(ints, i)
+		}
+	}
+	if len(ints) == len(values) {
+		result := ints[0]
+		for _, i := range ints[1:] {
+			if key == "observedGeneration" {
+				if i < result {
+					result = i
+				}
+			} else {
+				result += i
+			}
+		}
+		return result
+	}
+
+	if key == "conditions" && len(values) == len(clusters) {
+		return mergeConditions(values, clusters)
+	}
+	return runtime.DeepCopyJSONValue(values[0])
+}
+
+// mergeConditions merges the conditions of each cluster by type. A condition is taken
+// from the first cluster where it is not true, with the cluster prefixed to its message.
+func mergeConditions(values []interface{}, clusters []string) interface{} {
+	var conditionTypes []string
+	byType := map[string]map[string]interface{}{}
+	for i, value := range values {
+		conditions, ok := value.([]interface{})
+		if !ok {
+			return runtime.DeepCopyJSONValue(values[0])
+		}
+		for _, c := range conditions {
+			condition, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			conditionType, _ := condition["type"].(string)
+			existing, found := byType[conditionType]
+			if !found {
+				conditionTypes = append(conditionTypes, conditionType)
+			}
+			if !found || (existing["status"] == "True" && condition["status"] != "True") {
+				condition = runtime.DeepCopyJSONValue(condition).(map[string]interface{})
+				if condition["status"] != "True" {
+					if message, _ := condition["message"].(string); message != "" {
+						condition["message"] = clusters[i] + ": " + message
+					} else {
+						condition["message"] = clusters[i]
+					}
+				}
+				byType[conditionType] = condition
+			}
+		}
+	}
+
+	merged := make([]interface{}, 0, len(conditionTypes))
+	for _, conditionType := range conditionTypes {
+		merged = append(merged, byType[conditionType])
+	}
+	return merged
+}
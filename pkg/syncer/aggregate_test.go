@@ -0,0 +1,69 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestMergeStatuses(t *testing.T) {
+	obj := &unstructured.Unstructured{}
+	obj.SetAnnotations(map[string]string{
+		"kcp.dev/placement":     "ignored",
+		"status.kcp.dev/east":   `{"replicas":2,"readyReplicas":2,"observedGeneration":3,"conditions":[{"type":"Available","status":"True"},{"type":"Progressing","status":"True","message":"done"}]}`,
+		"status.kcp.dev/europe": `{"replicas":1,"observedGeneration":2,"conditions":[{"type":"Available","status":"False","message":"no pods"},{"type":"Progressing","status":"True","message":"done"}]}`,
+	})
+	statuses, err := clusterStatuses(obj)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(statuses) != 2 {
+		t.Fatalf("expected the statuses of 2 clusters, got %v", statuses)
+	}
+
+	merged, err := DefaultStatusAggregator.Aggregate(statuses)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]interface{}{
+		"replicas":           int64(3),
+		"readyReplicas":      int64(2),
+		"observedGeneration": int64(2),
+		"conditions": []interface{}{
+			map[string]interface{}{"type": "Available", "status": "False", "message": "europe: no pods"},
+			map[string]interface{}{"type": "Progressing", "status": "True", "message": "done"},
+		},
+	}
+	if diff := cmp.Diff(expected, merged); diff != "" {
+		t.Errorf("unexpected aggregated status (-want +got):\n%s", diff)
+	}
+}
+
+func TestMergeStatusesSingleCluster(t *testing.T) {
+	status := map[string]interface{}{"phase": "Running", "replicas": int64(1)}
+	merged, err := DefaultStatusAggregator.Aggregate(map[string]interface{}{"east": status})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(status, merged); diff != "" {
+		t.Errorf("unexpected aggregated status (-want +got):\n%s", diff)
+	}
+}
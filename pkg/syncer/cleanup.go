@@ -0,0 +1,124 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+
+	clusterv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/cluster/v1alpha1"
+)
+
+// LogicalClusterLabel is set on the downstream objects and namespaces created by the
+// syncer, to the logical cluster they are synced from.
+const LogicalClusterLabel = "kcp.dev/logical-cluster"
+
+// syncedLabels returns the labels of the downstream objects and namespaces created by the
+// syncer of the cluster for the logical cluster.
+func syncedLabels(clusterID, logicalCluster string) labels.Set {
+	return labels.Set{
+		clusterv1alpha1.ClusterLabel: clusterID,
+		LogicalClusterLabel:          logicalCluster,
+	}
+}
+
+// DeleteSynced deletes the downstream objects of the given resources synced from the
+// logical cluster to the cluster, then the namespaces the syncer created for them once
+// they hold no synced object anymore. Objects upsynced from the cluster were not created
+// by the syncer and are left alone.
+//
+// It returns the number of objects which are still being deleted: the syncer must be
+// stopped beforehand, and DeleteSynced called again until none remains.
+func DeleteSynced(ctx context.Context, client dynamic.Interface, gvrs []schema.GroupVersionResource, clusterID, logicalCluster string) (int, error) {
+	selector := labels.SelectorFromSet(syncedLabels(clusterID, logicalCluster)).String()
+
+	remaining := 0
+	for _, gvr := range gvrs {
+		list, err := client.Resource(gvr).List(ctx, metav1.ListOptions{LabelSelector: selector})
+		if k8serrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to list synced %s: %w", gvr.Resource, err)
+		}
+		for _, obj := range list.Items {
+			if _, upsynced := obj.GetAnnotations()[UpsyncedFromAnnotation]; upsynced {
+				continue
+			}
+			remaining++
+			if obj.GetDeletionTimestamp() != nil {
+				continue
+			}
+			if err := client.Resource(gvr).Namespace(obj.GetNamespace()).Delete(ctx, obj.GetName(), metav1.DeleteOptions{}); err != nil && !k8serrors.IsNotFound(err) {
+				return 0, fmt.Errorf("failed to delete synced %s %s/%s: %w", gvr.Resource, obj.GetNamespace(), obj.GetName(), err)
+			}
+			klog.Infof("Deleted synced %s %s/%s", gvr.Resource, obj.GetNamespace(), obj.GetName())
+		}
+	}
+	if remaining > 0 {
+		return remaining, nil
+	}
+
+	// With the Identity namespace strategy, namespaces are shared with the other logical
+	// clusters: they are kept as long as they hold objects synced from anywhere, or
+	// upsynced objects.
+	anySynced, err := labels.NewRequirement(LogicalClusterLabel, selection.Exists, nil)
+	if err != nil {
+		return 0, err
+	}
+	namespaces := client.Resource(corev1.SchemeGroupVersion.WithResource("namespaces"))
+	list, err := namespaces.List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list synced namespaces: %w", err)
+	}
+namespaces:
+	for _, ns := range list.Items {
+		if ns.GetDeletionTimestamp() != nil {
+			continue
+		}
+		for _, gvr := range gvrs {
+			objs, err := client.Resource(gvr).Namespace(ns.GetName()).List(ctx, metav1.ListOptions{
+				LabelSelector: labels.NewSelector().Add(*anySynced).String(),
+				Limit:         1,
+			})
+			if k8serrors.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return 0, fmt.Errorf("failed to list synced %s in namespace %s: %w", gvr.Resource, ns.GetName(), err)
+			}
+			if len(objs.Items) > 0 {
+				klog.Infof("Keeping namespace %s which still holds synced objects", ns.GetName())
+				continue namespaces
+			}
+		}
+		if err := namespaces.Delete(ctx, ns.GetName(), metav1.DeleteOptions{}); err != nil && !k8serrors.IsNotFound(err) {
+			return 0, fmt.Errorf("failed to delete synced namespace %s: %w", ns.GetName(), err)
+		}
+		klog.Infof("Deleted synced namespace %s", ns.GetName())
+	}
+	return 0, nil
+}
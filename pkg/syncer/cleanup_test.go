@@ -0,0 +1,99 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	clusterv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/cluster/v1alpha1"
+)
+
+func TestDeleteSynced(t *testing.T) {
+	configmaps := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	namespaces := schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+
+	object := func(kind, namespace, name string, labels, annotations map[string]string) runtime.Object {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("v1")
+		obj.SetKind(kind)
+		obj.SetNamespace(namespace)
+		obj.SetName(name)
+		obj.SetLabels(labels)
+		obj.SetAnnotations(annotations)
+		return obj
+	}
+	synced := syncedLabels("us-east1", "admin")
+	fromOther := syncedLabels("us-east1", "other")
+
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		configmaps: "ConfigMapList",
+		namespaces: "NamespaceList",
+	},
+		object("Namespace", "", "mine", synced, nil),
+		object("Namespace", "", "with-upsynced", synced, nil),
+		object("Namespace", "", "shared", synced, nil),
+		object("Namespace", "", "foreign", nil, nil),
+		object("ConfigMap", "mine", "synced", synced, nil),
+		object("ConfigMap", "with-upsynced", "upsynced", synced, map[string]string{UpsyncedFromAnnotation: "us-east1"}),
+		object("ConfigMap", "shared", "synced", synced, nil),
+		object("ConfigMap", "shared", "from-other", fromOther, nil),
+		object("ConfigMap", "foreign", "unsynced", map[string]string{clusterv1alpha1.ClusterLabel: "us-east1"}, nil),
+	)
+
+	ctx := context.Background()
+	remaining, err := DeleteSynced(ctx, client, []schema.GroupVersionResource{configmaps}, "us-east1", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The fake client deletes objects right away.
+	if remaining != 2 {
+		t.Errorf("expected 2 objects being deleted, got %d", remaining)
+	}
+	if remaining, err = DeleteSynced(ctx, client, []schema.GroupVersionResource{configmaps}, "us-east1", "admin"); err != nil {
+		t.Fatal(err)
+	} else if remaining != 0 {
+		t.Errorf("expected no object left, got %d", remaining)
+	}
+
+	names := func(gvr schema.GroupVersionResource) []string {
+		list, err := client.Resource(gvr).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, obj := range list.Items {
+			names = append(names, obj.GetNamespace()+"/"+obj.GetName())
+		}
+		return sets.NewString(names...).List()
+	}
+	if diff := cmp.Diff([]string{"foreign/unsynced", "shared/from-other", "with-upsynced/upsynced"}, names(configmaps)); diff != "" {
+		t.Errorf("unexpected configmaps left (-want +got): %s", diff)
+	}
+	if diff := cmp.Diff([]string{"/foreign", "/shared", "/with-upsynced"}, names(namespaces)); diff != "" {
+		t.Errorf("unexpected namespaces left (-want +got): %s", diff)
+	}
+}
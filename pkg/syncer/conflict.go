@@ -0,0 +1,207 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// UpstreamVersionAnnotation is set on downstream objects to the generation of the upstream
+// object they were synced from, or to its resource version for resources without
+// generation.
+const UpstreamVersionAnnotation = "kcp.dev/upstream-version"
+
+// ConflictPolicy decides which side wins when a downstream object was changed out of band.
+type ConflictPolicy string
+
+const (
+	// ConflictPolicyUpstreamWins overwrites out-of-band changes of downstream objects.
+	ConflictPolicyUpstreamWins ConflictPolicy = "UpstreamWins"
+	// ConflictPolicyDownstreamWins keeps out-of-band changes of downstream objects until
+	// the upstream object changes.
+	ConflictPolicyDownstreamWins ConflictPolicy = "DownstreamWins"
+)
+
+// ParseConflictPolicy parses a conflict policy, returning the default one for an empty string.
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	switch policy := ConflictPolicy(s); policy {
+	case "":
+		return ConflictPolicyUpstreamWins, nil
+	case ConflictPolicyUpstreamWins, ConflictPolicyDownstreamWins:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown conflict policy %q, must be one of %s, %s", s, ConflictPolicyUpstreamWins, ConflictPolicyDownstreamWins)
+}
+
+// upstreamVersion returns the version of the upstream object recorded on the downstream
+// objects synced from it.
+func upstreamVersion(upstream *unstructured.Unstructured) string {
+	if generation := upstream.GetGeneration(); generation > 0 {
+		return strconv.FormatInt(generation, 10)
+	}
+	return upstream.GetResourceVersion()
+}
+
+// drifted returns whether the existing downstream object was changed out of band since
+// it was synced from the same upstream version as the desired object.
+func drifted(existing, desired *unstructured.Unstructured) bool {
+	version := desired.GetAnnotations()[UpstreamVersionAnnotation]
+	if existing.GetAnnotations()[UpstreamVersionAnnotation] != version {
+		return false
+	}
+	return !containsFields(existing.Object, desired.Object)
+}
+
+// containsFields returns whether all the fields set in desired have the same value in
+// actual, ignoring the status and the metadata other than labels and annotations. Fields
+// defaulted by the downstream API server are therefore not reported as drift.
+func containsFields(actual, desired map[string]interface{}) bool {
+	for key, desiredValue := range desired {
+		switch key {
+		case "status":
+			continue
+		case "metadata":
+			actualMetadata, _ := actual["metadata"].(map[string]interface{})
+			desiredMetadata, _ := desiredValue.(map[string]interface{})
+			for _, field := range []string{"labels", "annotations"} {
+				if !contained(actualMetadata[field], desiredMetadata[field]) {
+					return false
+				}
+			}
+		default:
+			if !contained(actual[key], desiredValue) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func contained(actual, desired interface{}) bool {
+	switch desired := desired.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		actual, ok := actual.(map[string]interface{})
+		if !ok {
+			return len(desired) == 0 && actual == nil
+		}
+		for key, value := range desired {
+			if !contained(actual[key], value) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		actual, ok := actual.([]interface{})
+		if !ok {
+			return len(desired) == 0 && actual == nil
+		}
+		if len(actual) != len(desired) {
+			return false
+		}
+		for i := range desired {
+			if !contained(actual[i], desired[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(actual, desired)
+	}
+}
+
+// recordDrift reports an out-of-band change of the downstream object synced from upstream.
+func (c *Controller) recordDrift(upstream *unstructured.Unstructured, gvr schema.GroupVersionResource, corrected bool) {
+	if corrected {
+		klog.Infof("Object %s %s/%s was changed downstream, overwriting it", gvr.Resource, upstream.GetNamespace(), upstream.GetName())
+		if c.recorder != nil {
+			c.recorder.Event(upstream, corev1.EventTypeWarning, "DriftCorrected", "The object was changed in the physical cluster and was synced again")
+		}
+		return
+	}
+	klog.Infof("Object %s %s/%s was changed downstream, keeping the changes", gvr.Resource, upstream.GetNamespace(), upstream.GetName())
+	if c.recorder != nil {
+		c.recorder.Event(upstream, corev1.EventTypeWarning, "DriftDetected", "The object was changed in the physical cluster, the changes are kept until the object changes")
+	}
+}
+
+// recordNameConflict records that the upstream object of a cluster-scoped resource is not
+// synced, because an object of the same name exists downstream which was not synced from
+// the same logical cluster.
+func (c *Controller) recordNameConflict(upstream *unstructured.Unstructured, gvr schema.GroupVersionResource, existing *unstructured.Unstructured) {
+	owner := "the cluster"
+	if identity, ok := existing.GetAnnotations()[SyncIdentityAnnotation]; ok {
+		owner = "logical cluster " + strings.SplitN(identity, "/", 2)[0]
+	} else if logicalCluster, ok := existing.GetLabels()[LogicalClusterLabel]; ok {
+		owner = "logical cluster " + logicalCluster
+	}
+	klog.Infof("Not syncing %s %s: an object of the same name is owned by %s", gvr.Resource, upstream.GetName(), owner)
+	if c.recorder != nil {
+		c.recorder.Eventf(upstream, corev1.EventTypeWarning, "NameConflict", "The object is not synced to cluster %s: an object of the same name is owned by %s", c.clusterID, owner)
+	}
+}
+
+// enqueueUpstreamOf queues the upstream object a changed downstream object was synced
+// from, so that the change is checked for drift.
+func (c *Controller) enqueueUpstreamOf(downstreamGVR schema.GroupVersionResource, obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	downstream, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+	if _, synced := downstream.GetAnnotations()[UpstreamVersionAnnotation]; !synced {
+		return
+	}
+	if downstream.GetNamespace() == "" && !syncedFrom(downstream, c.logicalCluster) {
+		// A cluster-scoped object synced from another logical cluster.
+		return
+	}
+	for _, gvr := range c.gvrs {
+		if gvr.GroupResource() != downstreamGVR.GroupResource() {
+			continue
+		}
+		namespace, ok := c.transformer.UpstreamNamespace(gvr, downstream.GetNamespace())
+		if !ok {
+			return
+		}
+		key := &metav1.PartialObjectMetadata{
+			ObjectMeta: metav1.ObjectMeta{
+				ClusterName: c.logicalCluster,
+				Namespace:   namespace,
+				Name:        downstream.GetName(),
+			},
+		}
+		if _, exists, err := c.fromDSIF.ForResource(gvr).Informer().GetIndexer().Get(key); err != nil || !exists {
+			return
+		}
+		c.AddToQueue(gvr, key)
+		return
+	}
+}
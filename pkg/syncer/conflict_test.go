@@ -0,0 +1,100 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestDrifted(t *testing.T) {
+	desired := &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":              "web",
+			"creationTimestamp": nil,
+			"labels":            map[string]interface{}{"app": "web"},
+			"annotations":       map[string]interface{}{UpstreamVersionAnnotation: "2"},
+		},
+		"spec": map[string]interface{}{
+			"replicas": int64(2),
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{"creationTimestamp": nil},
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{"name": "web", "image": "nginx"},
+					},
+				},
+			},
+		},
+	}}
+	existing := func(version string, mutate func(obj *unstructured.Unstructured)) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"name":            "web",
+				"resourceVersion": "42",
+				"labels":          map[string]interface{}{"app": "web", "extra": "label"},
+				"annotations":     map[string]interface{}{UpstreamVersionAnnotation: version},
+			},
+			"spec": map[string]interface{}{
+				"replicas":                int64(2),
+				"progressDeadlineSeconds": int64(600),
+				"template": map[string]interface{}{
+					"metadata": map[string]interface{}{},
+					"spec": map[string]interface{}{
+						"containers": []interface{}{
+							map[string]interface{}{"name": "web", "image": "nginx", "imagePullPolicy": "Always"},
+						},
+					},
+				},
+			},
+			"status": map[string]interface{}{"replicas": int64(1)},
+		}}
+		if mutate != nil {
+			mutate(obj)
+		}
+		return obj
+	}
+
+	for _, tt := range []struct {
+		name     string
+		existing *unstructured.Unstructured
+		drifted  bool
+	}{
+		{name: "in sync apart from defaults", existing: existing("2", nil)},
+		{name: "changed downstream", existing: existing("2", func(obj *unstructured.Unstructured) {
+			_ = unstructured.SetNestedField(obj.Object, int64(5), "spec", "replicas")
+		}), drifted: true},
+		{name: "label removed downstream", existing: existing("2", func(obj *unstructured.Unstructured) {
+			obj.SetLabels(nil)
+		}), drifted: true},
+		{name: "container added downstream", existing: existing("2", func(obj *unstructured.Unstructured) {
+			containers, _, _ := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+			containers = append(containers, map[string]interface{}{"name": "sidecar"})
+			_ = unstructured.SetNestedSlice(obj.Object, containers, "spec", "template", "spec", "containers")
+		}), drifted: true},
+		{name: "changed upstream", existing: existing("1", func(obj *unstructured.Unstructured) {
+			_ = unstructured.SetNestedField(obj.Object, int64(1), "spec", "replicas")
+		})},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := drifted(tt.existing, desired); got != tt.drifted {
+				t.Errorf("expected drifted %v, got %v", tt.drifted, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,336 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+)
+
+// DependencyLabel is set on the downstream objects the syncer synced because synced
+// objects reference them, rather than because they are labeled for the cluster.
+const DependencyLabel = "kcp.dev/dependency"
+
+var (
+	configMapsGVR = corev1.SchemeGroupVersion.WithResource("configmaps")
+	secretsGVR    = corev1.SchemeGroupVersion.WithResource("secrets")
+)
+
+// DependencyResources are the resources of the objects synced along with the objects
+// referencing them.
+var DependencyResources = []schema.GroupVersionResource{configMapsGVR, secretsGVR}
+
+// dependency identifies an object a synced object depends on, in the same namespace.
+type dependency struct {
+	gvr  schema.GroupVersionResource
+	name string
+}
+
+// resolvedDependency is a dependency found upstream.
+type resolvedDependency struct {
+	dependency
+	upstream *unstructured.Unstructured
+}
+
+// podSpecPaths are the paths of the pod specs in the objects of the workload resources.
+var podSpecPaths = [][]string{
+	{"spec", "template", "spec"},
+	{"spec", "jobTemplate", "spec", "template", "spec"},
+}
+
+// dependencies returns the ConfigMaps and Secrets the object references in its pod spec,
+// as volumes, environment variables or image pull secrets.
+func dependencies(gvr schema.GroupVersionResource, obj *unstructured.Unstructured) ([]dependency, error) {
+	paths := podSpecPaths
+	if gvr.Group == "" && gvr.Resource == "pods" {
+		paths = [][]string{{"spec"}}
+	}
+	for _, path := range paths {
+		raw, found, err := unstructured.NestedMap(obj.Object, path...)
+		if err != nil || !found {
+			continue
+		}
+		var podSpec corev1.PodSpec
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, &podSpec); err != nil {
+			return nil, fmt.Errorf("invalid pod spec in %s %s/%s: %w", gvr.Resource, obj.GetNamespace(), obj.GetName(), err)
+		}
+		return podSpecDependencies(&podSpec), nil
+	}
+	return nil, nil
+}
+
+func podSpecDependencies(podSpec *corev1.PodSpec) []dependency {
+	configMaps, secrets := sets.NewString(), sets.NewString()
+	for _, volume := range podSpec.Volumes {
+		if volume.ConfigMap != nil {
+			configMaps.Insert(volume.ConfigMap.Name)
+		}
+		if volume.Secret != nil {
+			secrets.Insert(volume.Secret.SecretName)
+		}
+		if volume.Projected != nil {
+			for _, source := range volume.Projected.Sources {
+				if source.ConfigMap != nil {
+					configMaps.Insert(source.ConfigMap.Name)
+				}
+				if source.Secret != nil {
+					secrets.Insert(source.Secret.Name)
+				}
+			}
+		}
+	}
+	containers := append(append([]corev1.Container{}, podSpec.InitContainers...), podSpec.Containers...)
+	for _, container := range containers {
+		for _, env := range container.Env {
+			if env.ValueFrom == nil {
+				continue
+			}
+			if ref := env.ValueFrom.ConfigMapKeyRef; ref != nil {
+				configMaps.Insert(ref.Name)
+			}
+			if ref := env.ValueFrom.SecretKeyRef; ref != nil {
+				secrets.Insert(ref.Name)
+			}
+		}
+		for _, envFrom := range container.EnvFrom {
+			if envFrom.ConfigMapRef != nil {
+				configMaps.Insert(envFrom.ConfigMapRef.Name)
+			}
+			if envFrom.SecretRef != nil {
+				secrets.Insert(envFrom.SecretRef.Name)
+			}
+		}
+	}
+	for _, ref := range podSpec.ImagePullSecrets {
+		secrets.Insert(ref.Name)
+	}
+	configMaps.Delete("")
+	secrets.Delete("")
+
+	var deps []dependency
+	for _, name := range configMaps.List() {
+		deps = append(deps, dependency{gvr: configMapsGVR, name: name})
+	}
+	for _, name := range secrets.List() {
+		deps = append(deps, dependency{gvr: secretsGVR, name: name})
+	}
+	return deps
+}
+
+// resolveDependencies returns the upstream objects the object depends on, transitively.
+// Missing dependencies are skipped: the object is synced anyway, and they are synced
+// once they are created and the object is synced again.
+func (c *Controller) resolveDependencies(ctx context.Context, gvr schema.GroupVersionResource, obj *unstructured.Unstructured) ([]resolvedDependency, error) {
+	namespace := obj.GetNamespace()
+	pending, err := dependencies(gvr, obj)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[dependency]bool{}
+	var resolved []resolvedDependency
+	for len(pending) > 0 {
+		dep := pending[0]
+		pending = pending[1:]
+		if seen[dep] {
+			continue
+		}
+		seen[dep] = true
+
+		upstream, err := c.fromClient.Resource(dep.gvr).Namespace(namespace).Get(ctx, dep.name, metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			klog.V(2).Infof("Dependency %s %s/%s of %s %s/%s not found", dep.gvr.Resource, namespace, dep.name, gvr.Resource, namespace, obj.GetName())
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if dep.gvr == secretsGVR && upstream.Object["type"] == string(corev1.SecretTypeServiceAccountToken) {
+			// Service account tokens are issued by each cluster.
+			continue
+		}
+		if allowed, err := c.filter.AllowsDependency(dep.gvr); err != nil {
+			return nil, err
+		} else if !allowed {
+			continue
+		}
+		resolved = append(resolved, resolvedDependency{dependency: dep, upstream: upstream})
+
+		next, err := dependencies(dep.gvr, upstream)
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, next...)
+	}
+	return resolved, nil
+}
+
+// syncDependencies syncs the objects the upstream object depends on to the downstream
+// namespace, before the object itself, so that its pods can start.
+func (c *Controller) syncDependencies(ctx context.Context, gvr schema.GroupVersionResource, upstream *unstructured.Unstructured, downstreamNamespace string) error {
+	deps, err := c.resolveDependencies(ctx, gvr, upstream)
+	if err != nil {
+		return fmt.Errorf("failed to resolve the dependencies of %s %s/%s: %w", gvr.Resource, upstream.GetNamespace(), upstream.GetName(), err)
+	}
+	for _, dep := range deps {
+		if err := c.syncDependency(ctx, dep.gvr, dep.upstream, downstreamNamespace); err != nil {
+			return fmt.Errorf("failed to sync %s %s/%s: %w", dep.gvr.Resource, upstream.GetNamespace(), dep.name, err)
+		}
+	}
+	return nil
+}
+
+func (c *Controller) syncDependency(ctx context.Context, gvr schema.GroupVersionResource, upstream *unstructured.Unstructured, downstreamNamespace string) error {
+	client := c.getClient(gvr, downstreamNamespace)
+	existing, err := client.Get(ctx, upstream.GetName(), metav1.GetOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+	if err == nil {
+		if existing.GetLabels()[DependencyLabel] == "" {
+			// Objects labeled for the cluster or created downstream are not taken over.
+			return nil
+		}
+		if existing.GetAnnotations()[UpstreamVersionAnnotation] == upstreamVersion(upstream) {
+			return nil
+		}
+	}
+
+	unstrob := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	for key, value := range upstream.Object {
+		if key != "metadata" {
+			unstrob.Object[key] = value
+		}
+	}
+	unstrob.SetAPIVersion(upstream.GetAPIVersion())
+	unstrob.SetKind(upstream.GetKind())
+	unstrob.SetNamespace(downstreamNamespace)
+	unstrob.SetName(upstream.GetName())
+	objLabels := labels.Merge(upstream.GetLabels(), syncedLabels(c.clusterID, c.logicalCluster))
+	objLabels[DependencyLabel] = "true"
+	unstrob.SetLabels(objLabels)
+	annotations := withoutClusterStatuses(upstream.GetAnnotations())
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[UpstreamVersionAnnotation] = upstreamVersion(upstream)
+	unstrob.SetAnnotations(annotations)
+
+	data, err := json.Marshal(unstrob.Object)
+	if err != nil {
+		return err
+	}
+	force := true
+	if _, err := client.Patch(ctx, unstrob.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{FieldManager: specFieldManager, Force: &force}); err != nil {
+		return err
+	}
+	klog.Infof("Applied dependency %s %s/%s", gvr.Resource, downstreamNamespace, unstrob.GetName())
+	return nil
+}
+
+// collectDependencies deletes the dependencies synced to the downstream namespace which
+// none of the upstream objects synced to it depend on anymore, directly or transitively.
+func (c *Controller) collectDependencies(ctx context.Context, upstreamNamespace, downstreamNamespace string) error {
+	if downstreamNamespace == "" {
+		// Cluster-scoped objects have no dependencies, and the dependencies of all the
+		// namespaces must not be collected at once.
+		return nil
+	}
+	var pending []dependency
+	for _, gvr := range c.gvrs {
+		objs, err := c.fromDSIF.ForResource(gvr).Lister().ByNamespace(upstreamNamespace).List(labels.Everything())
+		if err != nil {
+			return err
+		}
+		for _, obj := range objs {
+			unstrob, ok := obj.(*unstructured.Unstructured)
+			if !ok {
+				continue
+			}
+			if c.filter != nil {
+				if allowed, err := c.filter.Allows(gvr, unstrob); err != nil || !allowed {
+					continue
+				}
+			}
+			deps, err := dependencies(gvr, unstrob)
+			if err != nil {
+				return err
+			}
+			pending = append(pending, deps...)
+		}
+	}
+
+	selector := labels.Merge(syncedLabels(c.clusterID, c.logicalCluster), labels.Set{DependencyLabel: "true"})
+	synced := map[dependency]*unstructured.Unstructured{}
+	for _, gvr := range DependencyResources {
+		list, err := c.getClient(gvr, downstreamNamespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return err
+		}
+		for i := range list.Items {
+			synced[dependency{gvr: gvr, name: list.Items[i].GetName()}] = &list.Items[i]
+		}
+	}
+
+	// The dependencies of the synced dependencies are needed too.
+	needed := map[dependency]bool{}
+	for len(pending) > 0 {
+		dep := pending[0]
+		pending = pending[1:]
+		if needed[dep] {
+			continue
+		}
+		needed[dep] = true
+		if obj := synced[dep]; obj != nil {
+			next, err := dependencies(dep.gvr, obj)
+			if err != nil {
+				return err
+			}
+			pending = append(pending, next...)
+		}
+	}
+
+	for dep, obj := range synced {
+		if obj.GetDeletionTimestamp() != nil {
+			continue
+		}
+		if needed[dep] {
+			// Dependencies the sync policy excludes now are not needed either.
+			if allowed, err := c.filter.AllowsDependency(dep.gvr); err != nil {
+				return err
+			} else if allowed {
+				continue
+			}
+		}
+		if err := c.getClient(dep.gvr, downstreamNamespace).Delete(ctx, dep.name, metav1.DeleteOptions{}); err != nil && !k8serrors.IsNotFound(err) {
+			return err
+		}
+		klog.Infof("Deleted dependency %s %s/%s which is not needed anymore", dep.gvr.Resource, downstreamNamespace, dep.name)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestDependencies(t *testing.T) {
+	podSpec := map[string]interface{}{
+		"volumes": []interface{}{
+			map[string]interface{}{"name": "config", "configMap": map[string]interface{}{"name": "web-config"}},
+			map[string]interface{}{"name": "tls", "secret": map[string]interface{}{"secretName": "web-tls"}},
+			map[string]interface{}{"name": "all", "projected": map[string]interface{}{"sources": []interface{}{
+				map[string]interface{}{"configMap": map[string]interface{}{"name": "shared-config"}},
+				map[string]interface{}{"secret": map[string]interface{}{"name": "web-tls"}},
+			}}},
+		},
+		"initContainers": []interface{}{
+			map[string]interface{}{"name": "init", "envFrom": []interface{}{
+				map[string]interface{}{"secretRef": map[string]interface{}{"name": "init-env"}},
+			}},
+		},
+		"containers": []interface{}{
+			map[string]interface{}{"name": "web", "env": []interface{}{
+				map[string]interface{}{"name": "PLAIN", "value": "value"},
+				map[string]interface{}{"name": "LEVEL", "valueFrom": map[string]interface{}{
+					"configMapKeyRef": map[string]interface{}{"name": "web-config", "key": "level"},
+				}},
+			}},
+		},
+		"imagePullSecrets": []interface{}{map[string]interface{}{"name": "registry"}},
+	}
+	podSpecDeps := []dependency{
+		{gvr: configMapsGVR, name: "shared-config"},
+		{gvr: configMapsGVR, name: "web-config"},
+		{gvr: secretsGVR, name: "init-env"},
+		{gvr: secretsGVR, name: "registry"},
+		{gvr: secretsGVR, name: "web-tls"},
+	}
+
+	tests := []struct {
+		name string
+		gvr  schema.GroupVersionResource
+		obj  map[string]interface{}
+		want []dependency
+	}{
+		{
+			name: "deployment",
+			gvr:  schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"},
+			obj:  map[string]interface{}{"spec": map[string]interface{}{"template": map[string]interface{}{"spec": podSpec}}},
+			want: podSpecDeps,
+		},
+		{
+			name: "cronjob",
+			gvr:  schema.GroupVersionResource{Group: "batch", Version: "v1", Resource: "cronjobs"},
+			obj: map[string]interface{}{"spec": map[string]interface{}{"jobTemplate": map[string]interface{}{"spec": map[string]interface{}{
+				"template": map[string]interface{}{"spec": podSpec},
+			}}}},
+			want: podSpecDeps,
+		},
+		{
+			name: "pod",
+			gvr:  schema.GroupVersionResource{Version: "v1", Resource: "pods"},
+			obj:  map[string]interface{}{"spec": podSpec},
+			want: podSpecDeps,
+		},
+		{
+			name: "without pod spec",
+			gvr:  configMapsGVR,
+			obj:  map[string]interface{}{"data": map[string]interface{}{"key": "value"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := dependencies(tt.gvr, &unstructured.Unstructured{Object: tt.obj})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got, cmp.AllowUnexported(dependency{})); diff != "" {
+				t.Errorf("unexpected dependencies (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilcache "k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// MirroredFromAnnotation is set on the upstream Events mirrored from downstream Events, to
+// the cluster they were mirrored from.
+const MirroredFromAnnotation = "kcp.dev/mirrored-from"
+
+const (
+	// relatedCacheSize and relatedCacheTTL bound the cache of the involved objects known
+	// to be related to synced objects or not, so that the owners of an object are not
+	// read again for every one of its Events.
+	relatedCacheSize = 4096
+	relatedCacheTTL  = 10 * time.Minute
+)
+
+var eventsGVR = corev1.SchemeGroupVersion.WithResource("events")
+
+// eventMirror mirrors the downstream Events about synced objects, or about objects they
+// own like the Pods of a Deployment, into the upstream namespaces of the synced objects,
+// so that scheduling failures or image pull errors are visible from kcp.
+type eventMirror struct {
+	clusterID      string
+	logicalCluster string
+	transformer    *Transformer
+
+	// Downstream
+	mapper     meta.RESTMapper
+	downstream dynamic.Interface
+
+	// Upstream
+	upstream kubernetes.Interface
+
+	related *utilcache.LRUExpireCache
+}
+
+func newEventMirror(clusterID, logicalCluster string, transformer *Transformer, mapper meta.RESTMapper, downstream dynamic.Interface, upstream kubernetes.Interface) *eventMirror {
+	return &eventMirror{
+		clusterID:      clusterID,
+		logicalCluster: logicalCluster,
+		transformer:    transformer,
+		mapper:         mapper,
+		downstream:     downstream,
+		upstream:       upstream,
+		related:        utilcache.NewLRUExpireCache(relatedCacheSize),
+	}
+}
+
+// handlers returns the event handlers of the downstream Events informer. Events are
+// mirrored on a best effort basis: failures are logged, and the next change of the Event
+// is mirrored again.
+func (m *eventMirror) handlers() cache.ResourceEventHandlerFuncs {
+	mirror := func(obj interface{}) {
+		event, ok := obj.(*corev1.Event)
+		if !ok {
+			return
+		}
+		if err := m.mirror(context.TODO(), event); err != nil {
+			klog.Errorf("Mirroring Event %s/%s of cluster %s: %v", event.Namespace, event.Name, m.clusterID, err)
+		}
+	}
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc:    mirror,
+		UpdateFunc: func(_, newObj interface{}) { mirror(newObj) },
+	}
+}
+
+// mirror creates or updates the upstream Event mirroring the downstream Event, if it is
+// about a synced object or one of its dependents. Other Events are ignored.
+func (m *eventMirror) mirror(ctx context.Context, event *corev1.Event) error {
+	namespace, ok := m.transformer.UpstreamNamespace(eventsGVR, event.Namespace)
+	if !ok {
+		return nil
+	}
+	related, err := m.isRelated(ctx, event.InvolvedObject)
+	if err != nil || !related {
+		return err
+	}
+
+	mirrored := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        mirroredEventName(m.clusterID, event.Name),
+			Namespace:   namespace,
+			Annotations: map[string]string{MirroredFromAnnotation: m.clusterID},
+		},
+		InvolvedObject:      upstreamReference(event.InvolvedObject, event.Namespace, namespace),
+		Reason:              event.Reason,
+		Message:             event.Message,
+		Source:              event.Source,
+		FirstTimestamp:      event.FirstTimestamp,
+		LastTimestamp:       event.LastTimestamp,
+		Count:               event.Count,
+		Type:                event.Type,
+		EventTime:           event.EventTime,
+		Series:              event.Series,
+		Action:              event.Action,
+		ReportingController: event.ReportingController,
+		ReportingInstance:   event.ReportingInstance,
+	}
+	if event.Related != nil {
+		reference := upstreamReference(*event.Related, event.Namespace, namespace)
+		mirrored.Related = &reference
+	}
+
+	client := m.upstream.CoreV1().Events(namespace)
+	_, err = client.Create(ctx, mirrored, metav1.CreateOptions{})
+	if !k8serrors.IsAlreadyExists(err) {
+		return err
+	}
+	existing, err := client.Get(ctx, mirrored.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	mirrored.ResourceVersion = existing.ResourceVersion
+	_, err = client.Update(ctx, mirrored, metav1.UpdateOptions{})
+	return err
+}
+
+// isRelated returns whether the involved object of a downstream Event is a synced object,
+// or is owned by one.
+func (m *eventMirror) isRelated(ctx context.Context, reference corev1.ObjectReference) (bool, error) {
+	if reference.UID != "" {
+		if related, ok := m.related.Get(reference.UID); ok {
+			return related.(bool), nil
+		}
+	}
+
+	related, err := IsSyncedOrOwned(ctx, m.downstream, m.mapper, reference, m.clusterID, m.logicalCluster)
+	if err != nil {
+		return false, err
+	}
+	if reference.UID != "" {
+		m.related.Add(reference.UID, related, relatedCacheTTL)
+	}
+	return related, nil
+}
+
+// upstreamReference returns the reference to the upstream object of a downstream object,
+// moved from the downstream to the upstream namespace. Its UID and resource version only
+// have a meaning downstream.
+func upstreamReference(reference corev1.ObjectReference, downstreamNamespace, upstreamNamespace string) corev1.ObjectReference {
+	if reference.Namespace == downstreamNamespace {
+		reference.Namespace = upstreamNamespace
+	}
+	reference.UID = ""
+	reference.ResourceVersion = ""
+	return reference
+}
+
+// mirroredEventName returns the name of the upstream Event mirroring a downstream Event,
+// prefixed with the cluster, so that Events mirrored from several clusters don't collide.
+func mirroredEventName(clusterID, name string) string {
+	mirrored := fmt.Sprintf("%s.%s", clusterID, name)
+	if len(mirrored) > validation.DNS1123SubdomainMaxLength {
+		mirrored = strings.TrimRight(mirrored[:validation.DNS1123SubdomainMaxLength], ".-")
+	}
+	return mirrored
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+
+	clusterlisters "github.com/kcp-dev/kcp/pkg/client/listers/cluster/v1alpha1"
+)
+
+func TestMirrorEvents(t *testing.T) {
+	deployments := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	replicasets := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "ReplicaSet"}
+	pods := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	mapper := meta.NewDefaultRESTMapper(nil)
+	for _, gvk := range []schema.GroupVersionKind{deployments, replicasets, pods} {
+		mapper.Add(gvk, meta.RESTScopeNamespace)
+	}
+
+	namespace := workspaceNamespacePrefix("admin") + "default"
+	object := func(gvk schema.GroupVersionKind, name string, labels map[string]string, owner *unstructured.Unstructured) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(gvk)
+		obj.SetNamespace(namespace)
+		obj.SetName(name)
+		obj.SetUID(types.UID(name + "-uid"))
+		obj.SetLabels(labels)
+		if owner != nil {
+			controller := true
+			obj.SetOwnerReferences([]metav1.OwnerReference{{
+				APIVersion: owner.GetAPIVersion(),
+				Kind:       owner.GetKind(),
+				Name:       owner.GetName(),
+				UID:        owner.GetUID(),
+				Controller: &controller,
+			}})
+		}
+		return obj
+	}
+	deployment := object(deployments, "web", syncedLabels("us-east1", "admin"), nil)
+	replicaset := object(replicasets, "web-1", nil, deployment)
+	pod := object(pods, "web-1-a", nil, replicaset)
+	foreignDeployment := object(deployments, "other", syncedLabels("us-east1", "tenant"), nil)
+	foreignPod := object(pods, "other-a", nil, foreignDeployment)
+	unsyncedPod := object(pods, "standalone", nil, nil)
+
+	event := func(name string, involved *unstructured.Unstructured, count int32) *corev1.Event {
+		return &corev1.Event{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			InvolvedObject: corev1.ObjectReference{
+				APIVersion:      involved.GetAPIVersion(),
+				Kind:            involved.GetKind(),
+				Namespace:       namespace,
+				Name:            involved.GetName(),
+				UID:             involved.GetUID(),
+				ResourceVersion: "42",
+			},
+			Reason:  "FailedScheduling",
+			Message: "0/3 nodes are available",
+			Type:    corev1.EventTypeWarning,
+			Count:   count,
+		}
+	}
+
+	downstream := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), deployment, replicaset, pod, foreignDeployment, foreignPod, unsyncedPod)
+	upstream := fake.NewSimpleClientset()
+	transformer := NewTransformer("us-east1", "admin", NamespaceStrategyWorkspacePrefix, clusterlisters.NewSyncTransformLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})))
+	mirror := newEventMirror("us-east1", "admin", transformer, mapper, downstream, upstream)
+
+	ctx := context.Background()
+	for _, e := range []*corev1.Event{
+		event("web.1", deployment, 1),
+		event("web-1-a.1", pod, 1),
+		event("web-1-a.1", pod, 2),
+		event("other-a.1", foreignPod, 1),
+		event("standalone.1", unsyncedPod, 1),
+	} {
+		if err := mirror.mirror(ctx, e); err != nil {
+			t.Fatalf("unexpected error mirroring Event %s: %v", e.Name, err)
+		}
+	}
+
+	list, err := upstream.CoreV1().Events(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]int32{}
+	for _, e := range list.Items {
+		if e.Namespace != "default" || e.InvolvedObject.Namespace != "default" {
+			t.Errorf("expected Event %s to be mirrored to the upstream namespace, got %s", e.Name, e.Namespace)
+		}
+		if e.InvolvedObject.UID != "" || e.InvolvedObject.ResourceVersion != "" {
+			t.Errorf("expected the downstream UID and resource version of Event %s to be dropped", e.Name)
+		}
+		if e.Annotations[MirroredFromAnnotation] != "us-east1" {
+			t.Errorf("expected Event %s to be annotated with its cluster, got %v", e.Name, e.Annotations)
+		}
+		got[e.Name] = e.Count
+	}
+	want := map[string]int32{"us-east1.web.1": 1, "us-east1.web-1-a.1": 2}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected mirrored Events (-want +got):\n%s", diff)
+	}
+}
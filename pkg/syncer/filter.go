@@ -0,0 +1,167 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/clusters"
+
+	clusterv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/cluster/v1alpha1"
+	clusterlisters "github.com/kcp-dev/kcp/pkg/client/listers/cluster/v1alpha1"
+)
+
+// Filter applies the SyncPolicy of a Cluster to the upstream objects labeled for it. A nil
+// Filter allows every object.
+type Filter struct {
+	clusterID       string
+	logicalCluster  string
+	clusterLister   clusterlisters.ClusterLister
+	namespaceLister corelisters.NamespaceLister
+}
+
+// NewFilter returns a Filter applying the SyncPolicy of the given Cluster of the logical
+// cluster, looking up the labels of upstream namespaces with namespaceLister.
+func NewFilter(clusterID, logicalCluster string, clusterLister clusterlisters.ClusterLister, namespaceLister corelisters.NamespaceLister) *Filter {
+	return &Filter{
+		clusterID:       clusterID,
+		logicalCluster:  logicalCluster,
+		clusterLister:   clusterLister,
+		namespaceLister: namespaceLister,
+	}
+}
+
+// Allows returns whether the upstream object of the given resource may be synced
+// according to the SyncPolicy of the Cluster. Objects are allowed when the Cluster
+// doesn't exist upstream, e.g. for syncers run by hand.
+func (f *Filter) Allows(gvr schema.GroupVersionResource, obj metav1.Object) (bool, error) {
+	policy, err := f.policy()
+	if err != nil || policy == nil {
+		return err == nil, err
+	}
+
+	gr := gvr.GroupResource()
+	if len(policy.Resources) > 0 && !contains(policy.Resources, gr.String()) && !contains(policy.Resources, gr.Resource) {
+		return false, nil
+	}
+	if contains(policy.ExcludedResources, gr.String()) || contains(policy.ExcludedResources, gr.Resource) {
+		return false, nil
+	}
+
+	if ok, err := matchesSelector(policy.ObjectSelector, obj.GetLabels()); err != nil || !ok {
+		return false, err
+	}
+
+	if policy.NamespaceSelector != nil && obj.GetNamespace() != "" {
+		namespace, err := f.namespaceLister.Get(clusters.ToClusterAwareKey(f.logicalCluster, obj.GetNamespace()))
+		if k8serrors.IsNotFound(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if ok, err := matchesSelector(policy.NamespaceSelector, namespace.Labels); err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// AllowsDependency returns whether the upstream objects of the given resource may be
+// synced as dependencies of synced objects. Dependencies follow the objects depending on
+// them: only the resources the SyncPolicy excludes explicitly are not synced.
+func (f *Filter) AllowsDependency(gvr schema.GroupVersionResource) (bool, error) {
+	policy, err := f.policy()
+	if err != nil || policy == nil {
+		return err == nil, err
+	}
+	gr := gvr.GroupResource()
+	return !contains(policy.ExcludedResources, gr.String()) && !contains(policy.ExcludedResources, gr.Resource), nil
+}
+
+// Direction returns the direction the objects of the given resource are synced in
+// according to the SyncPolicy of the Cluster, both ways by default.
+func (f *Filter) Direction(gvr schema.GroupVersionResource) (clusterv1alpha1.SyncDirectionPolicy, error) {
+	policy, err := f.policy()
+	if err != nil {
+		return "", err
+	}
+	if policy != nil {
+		gr := gvr.GroupResource()
+		for _, direction := range policy.Directions {
+			if direction.Resource == gr.String() || direction.Resource == gr.Resource {
+				return direction.Direction, nil
+			}
+		}
+	}
+	return clusterv1alpha1.SyncDirectionPolicyBoth, nil
+}
+
+// Deletion returns what happens to the downstream objects of the resource when their
+// upstream object is deleted.
+func (f *Filter) Deletion(gvr schema.GroupVersionResource) (clusterv1alpha1.ObjectDeletionPolicy, error) {
+	policy, err := f.policy()
+	if err != nil {
+		return "", err
+	}
+	if policy != nil {
+		gr := gvr.GroupResource()
+		for _, deletion := range policy.Deletions {
+			if deletion.Resource == gr.String() || deletion.Resource == gr.Resource {
+				return deletion.Policy, nil
+			}
+		}
+	}
+	return clusterv1alpha1.ObjectDeletionPolicyBackground, nil
+}
+
+// policy returns the SyncPolicy of the Cluster, if any.
+func (f *Filter) policy() (*clusterv1alpha1.SyncPolicy, error) {
+	if f == nil {
+		return nil, nil
+	}
+	cluster, err := f.clusterLister.Get(clusters.ToClusterAwareKey(f.logicalCluster, f.clusterID))
+	if k8serrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cluster.Spec.SyncPolicy, nil
+}
+
+// policyOf returns the SyncPolicy of the given Cluster object, for change detection.
+func policyOf(obj interface{}) *clusterv1alpha1.SyncPolicy {
+	if cluster, ok := obj.(*clusterv1alpha1.Cluster); ok {
+		return cluster.Spec.SyncPolicy
+	}
+	return nil
+}
+
+func matchesSelector(selector *metav1.LabelSelector, set map[string]string) (bool, error) {
+	if selector == nil {
+		return true, nil
+	}
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return false, err
+	}
+	return s.Matches(labels.Set(set)), nil
+}
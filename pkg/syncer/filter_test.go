@@ -0,0 +1,135 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	clusterv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/cluster/v1alpha1"
+	clusterlisters "github.com/kcp-dev/kcp/pkg/client/listers/cluster/v1alpha1"
+)
+
+func TestFilterAllows(t *testing.T) {
+	clusterIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	if err := clusterIndexer.Add(&clusterv1alpha1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "us-east1", ClusterName: "admin"},
+		Spec: clusterv1alpha1.ClusterSpec{
+			SyncPolicy: &clusterv1alpha1.SyncPolicy{
+				ExcludedResources: []string{"secrets"},
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}},
+				ObjectSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "internal", Operator: metav1.LabelSelectorOpDoesNotExist},
+				}},
+			},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	namespaceIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for name, env := range map[string]string{"prod": "prod", "dev": "dev"} {
+		if err := namespaceIndexer.Add(&corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: name, ClusterName: "admin", Labels: map[string]string{"env": env}},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	filter := NewFilter("us-east1", "admin", clusterlisters.NewClusterLister(clusterIndexer), corelisters.NewNamespaceLister(namespaceIndexer))
+	unfiltered := NewFilter("us-west1", "admin", clusterlisters.NewClusterLister(clusterIndexer), corelisters.NewNamespaceLister(namespaceIndexer))
+
+	deployments := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	secrets := schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
+	object := func(namespace string, labels map[string]string) metav1.Object {
+		return &metav1.ObjectMeta{Namespace: namespace, Name: "web", ClusterName: "admin", Labels: labels}
+	}
+
+	tests := []struct {
+		name   string
+		filter *Filter
+		gvr    schema.GroupVersionResource
+		obj    metav1.Object
+		want   bool
+	}{
+		{name: "allowed", filter: filter, gvr: deployments, obj: object("prod", nil), want: true},
+		{name: "excluded resource", filter: filter, gvr: secrets, obj: object("prod", nil)},
+		{name: "namespace not selected", filter: filter, gvr: deployments, obj: object("dev", nil)},
+		{name: "unknown namespace", filter: filter, gvr: deployments, obj: object("staging", nil)},
+		{name: "object not selected", filter: filter, gvr: deployments, obj: object("prod", map[string]string{"internal": "true"})},
+		{name: "cluster without policy", filter: unfiltered, gvr: secrets, obj: object("dev", nil), want: true},
+		{name: "nil filter", gvr: secrets, obj: object("dev", nil), want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.filter.Allows(tt.gvr, tt.obj)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFilterDirection(t *testing.T) {
+	clusterIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	if err := clusterIndexer.Add(&clusterv1alpha1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "us-east1", ClusterName: "admin"},
+		Spec: clusterv1alpha1.ClusterSpec{
+			SyncPolicy: &clusterv1alpha1.SyncPolicy{
+				Directions: []clusterv1alpha1.ResourceSyncDirection{
+					{Resource: "deployments.apps", Direction: clusterv1alpha1.SyncDirectionPolicySpecDown},
+					{Resource: "services", Direction: clusterv1alpha1.SyncDirectionPolicyStatusUp},
+				},
+			},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	namespaces := corelisters.NewNamespaceLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{}))
+	filter := NewFilter("us-east1", "admin", clusterlisters.NewClusterLister(clusterIndexer), namespaces)
+	unknown := NewFilter("us-west1", "admin", clusterlisters.NewClusterLister(clusterIndexer), namespaces)
+
+	tests := []struct {
+		name   string
+		filter *Filter
+		gvr    schema.GroupVersionResource
+		want   clusterv1alpha1.SyncDirectionPolicy
+	}{
+		{name: "by group resource", filter: filter, gvr: schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}, want: clusterv1alpha1.SyncDirectionPolicySpecDown},
+		{name: "by resource", filter: filter, gvr: schema.GroupVersionResource{Version: "v1", Resource: "services"}, want: clusterv1alpha1.SyncDirectionPolicyStatusUp},
+		{name: "unlisted resource", filter: filter, gvr: schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}, want: clusterv1alpha1.SyncDirectionPolicyBoth},
+		{name: "cluster without policy", filter: unknown, gvr: schema.GroupVersionResource{Version: "v1", Resource: "services"}, want: clusterv1alpha1.SyncDirectionPolicyBoth},
+		{name: "nil filter", gvr: schema.GroupVersionResource{Version: "v1", Resource: "services"}, want: clusterv1alpha1.SyncDirectionPolicyBoth},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.filter.Direction(tt.gvr)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
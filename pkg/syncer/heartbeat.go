@@ -0,0 +1,50 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+)
+
+// heartbeat records that the syncer is alive in the SyncerLastSeen status of its Cluster,
+// every period until the context is done. The status is patched rather than updated, so
+// that heartbeats don't conflict with the cluster controller.
+func heartbeat(ctx context.Context, client kcpclient.Interface, cluster string, period time.Duration) {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		patch, err := json.Marshal(map[string]interface{}{
+			"status": map[string]interface{}{
+				"syncerLastSeen": metav1.Now(),
+			},
+		})
+		if err != nil {
+			klog.Errorf("Failed to build the heartbeat of cluster %s: %v", cluster, err)
+			return
+		}
+		if _, err := client.ClusterV1alpha1().Clusters().Patch(ctx, cluster, types.MergePatchType, patch, metav1.PatchOptions{}, "status"); err != nil {
+			klog.Errorf("Failed to send the heartbeat of cluster %s: %v", cluster, err)
+		}
+	}, period)
+}
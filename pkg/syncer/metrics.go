@@ -0,0 +1,101 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+const metricsSubsystem = "syncer"
+
+// throttledThreshold is how long a request must wait for the rate limiter to count as
+// throttled.
+const throttledThreshold = 10 * time.Millisecond
+
+var (
+	throttleWait = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Subsystem:      metricsSubsystem,
+			Name:           "throttle_wait_seconds",
+			Help:           "How long requests to a physical cluster waited for its rate limit.",
+			Buckets:        metrics.ExponentialBuckets(0.001, 4, 10),
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"logical_cluster", "cluster"},
+	)
+	throttledRequests = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      metricsSubsystem,
+			Name:           "throttled_requests_total",
+			Help:           "Number of requests to a physical cluster delayed by its rate limit.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"logical_cluster", "cluster"},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(throttleWait, throttledRequests)
+}
+
+// throttle is a rate limiter recording how much the requests to a cluster are throttled.
+type throttle struct {
+	flowcontrol.RateLimiter
+	wait      metrics.ObserverMetric
+	throttled metrics.CounterMetric
+	labels    map[string]string
+}
+
+func newThrottle(limiter flowcontrol.RateLimiter, clusterID, logicalCluster string) *throttle {
+	return &throttle{
+		RateLimiter: limiter,
+		wait:        throttleWait.WithLabelValues(logicalCluster, clusterID),
+		throttled:   throttledRequests.WithLabelValues(logicalCluster, clusterID),
+		labels:      map[string]string{"logical_cluster": logicalCluster, "cluster": clusterID},
+	}
+}
+
+func (t *throttle) Accept() {
+	start := time.Now()
+	t.RateLimiter.Accept()
+	t.observe(time.Since(start))
+}
+
+func (t *throttle) Wait(ctx context.Context) error {
+	start := time.Now()
+	err := t.RateLimiter.Wait(ctx)
+	t.observe(time.Since(start))
+	return err
+}
+
+func (t *throttle) observe(waited time.Duration) {
+	t.wait.Observe(waited.Seconds())
+	if waited >= throttledThreshold {
+		t.throttled.Inc()
+	}
+}
+
+// forget drops the metrics of the cluster once its syncer is stopped.
+func (t *throttle) forget() {
+	throttleWait.Delete(t.labels)
+	throttledRequests.Delete(t.labels)
+}
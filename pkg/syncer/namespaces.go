@@ -0,0 +1,81 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// NamespaceStrategy decides which downstream namespaces the objects of upstream
+// namespaces are synced to, when no SyncTransform maps their namespace explicitly.
+type NamespaceStrategy string
+
+const (
+	// NamespaceStrategyIdentity syncs objects to the namespace of the same name. Logical
+	// clusters syncing to the same physical cluster share its namespaces.
+	NamespaceStrategyIdentity NamespaceStrategy = "Identity"
+	// NamespaceStrategyWorkspacePrefix syncs objects to a namespace prefixed with a hash of
+	// their logical cluster, so that logical clusters syncing to the same physical cluster
+	// don't collide.
+	NamespaceStrategyWorkspacePrefix NamespaceStrategy = "WorkspacePrefix"
+)
+
+// ParseNamespaceStrategy parses a namespace strategy, returning the default one for an
+// empty string.
+func ParseNamespaceStrategy(s string) (NamespaceStrategy, error) {
+	switch strategy := NamespaceStrategy(s); strategy {
+	case "":
+		return NamespaceStrategyIdentity, nil
+	case NamespaceStrategyIdentity, NamespaceStrategyWorkspacePrefix:
+		return strategy, nil
+	}
+	return "", fmt.Errorf("unknown namespace strategy %q, must be one of %s, %s", s, NamespaceStrategyIdentity, NamespaceStrategyWorkspacePrefix)
+}
+
+// workspaceNamespacePrefix returns the prefix of the downstream namespaces of the logical
+// cluster with the WorkspacePrefix strategy.
+func workspaceNamespacePrefix(logicalCluster string) string {
+	hash := sha256.Sum256([]byte(logicalCluster))
+	return "kcp-" + hex.EncodeToString(hash[:])[:8] + "-"
+}
+
+func downstreamNamespace(strategy NamespaceStrategy, logicalCluster, namespace string) (string, error) {
+	if strategy != NamespaceStrategyWorkspacePrefix || namespace == "" {
+		return namespace, nil
+	}
+	downstream := workspaceNamespacePrefix(logicalCluster) + namespace
+	if len(downstream) > validation.DNS1123LabelMaxLength {
+		return "", fmt.Errorf("namespace %q is too long to be prefixed with its workspace, it must be at most %d characters", namespace, validation.DNS1123LabelMaxLength-len(workspaceNamespacePrefix(logicalCluster)))
+	}
+	return downstream, nil
+}
+
+func upstreamNamespace(strategy NamespaceStrategy, logicalCluster, namespace string) (string, bool) {
+	if strategy != NamespaceStrategyWorkspacePrefix || namespace == "" {
+		return namespace, true
+	}
+	prefix := workspaceNamespacePrefix(logicalCluster)
+	if !strings.HasPrefix(namespace, prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(namespace, prefix), true
+}
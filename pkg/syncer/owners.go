@@ -0,0 +1,66 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// maxOwnerDepth is how many owners up a downstream object is looked for a synced object,
+// e.g. from a Pod to its ReplicaSet and then its Deployment.
+const maxOwnerDepth = 3
+
+// IsSyncedOrOwned returns whether the referenced downstream object was synced from the
+// logical cluster to the cluster, or is controlled by such an object, directly or through
+// a few owners, like the Pods of a synced Deployment. Objects which don't exist are not.
+func IsSyncedOrOwned(ctx context.Context, client dynamic.Interface, mapper meta.RESTMapper, reference corev1.ObjectReference, clusterID, logicalCluster string) (bool, error) {
+	synced := labels.SelectorFromSet(syncedLabels(clusterID, logicalCluster))
+	gvk := schema.FromAPIVersionAndKind(reference.APIVersion, reference.Kind)
+	name := reference.Name
+	for depth := 0; depth <= maxOwnerDepth; depth++ {
+		mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if meta.IsNoMatchError(err) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		obj, err := client.Resource(mapping.Resource).Namespace(reference.Namespace).Get(ctx, name, metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		if synced.Matches(labels.Set(obj.GetLabels())) && ownedBy(obj, logicalCluster) {
+			return true, nil
+		}
+		owner := metav1.GetControllerOf(obj)
+		if owner == nil {
+			return false, nil
+		}
+		gvk = schema.FromAPIVersionAndKind(owner.APIVersion, owner.Kind)
+		name = owner.Name
+	}
+	return false, nil
+}
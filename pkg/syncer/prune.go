@@ -0,0 +1,178 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+
+	clusterv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/cluster/v1alpha1"
+)
+
+// SyncIdentityAnnotation is set on the downstream objects synced from upstream, to the
+// identity of their upstream object: its logical cluster and UID.
+const SyncIdentityAnnotation = "kcp.dev/sync-identity"
+
+// syncIdentity returns the identity of the upstream object of the logical cluster.
+func syncIdentity(logicalCluster string, upstream metav1.Object) string {
+	return logicalCluster + "/" + string(upstream.GetUID())
+}
+
+// ownedBy returns whether the downstream object was synced from the logical cluster.
+// Objects synced before identities were recorded are owned by the logical cluster they
+// are labeled for, if any.
+func ownedBy(downstream metav1.Object, logicalCluster string) bool {
+	if identity, ok := downstream.GetAnnotations()[SyncIdentityAnnotation]; ok {
+		return strings.HasPrefix(identity, logicalCluster+"/")
+	}
+	if owner, ok := downstream.GetLabels()[LogicalClusterLabel]; ok {
+		return owner == logicalCluster
+	}
+	return true
+}
+
+// syncedFrom returns whether the downstream object was synced from the logical cluster.
+// Unlike ownedBy, objects created downstream are not: the names of cluster-scoped objects
+// are shared by all the logical clusters syncing to the cluster, and the objects of the
+// cluster itself, like its ClusterRoles, must not be taken over.
+func syncedFrom(downstream metav1.Object, logicalCluster string) bool {
+	if identity, ok := downstream.GetAnnotations()[SyncIdentityAnnotation]; ok {
+		return strings.HasPrefix(identity, logicalCluster+"/")
+	}
+	return downstream.GetLabels()[LogicalClusterLabel] == logicalCluster
+}
+
+// prune deletes the downstream objects synced from upstream objects which don't target
+// the cluster anymore, e.g. whose cluster label changed while the syncer was not running.
+// Dependencies are collected separately.
+func (c *Controller) prune(ctx context.Context) {
+	selector := labels.SelectorFromSet(syncedLabels(c.clusterID, c.logicalCluster))
+	notDependency, err := labels.NewRequirement(DependencyLabel, selection.DoesNotExist, nil)
+	if err != nil {
+		klog.Errorf("Building the selector of the objects to prune: %v", err)
+		return
+	}
+	selector = selector.Add(*notDependency)
+
+	for _, gvr := range c.gvrs {
+		if direction, err := c.filter.Direction(gvr); err != nil {
+			klog.Errorf("Getting the sync direction of %v to prune: %v", gvr, err)
+			continue
+		} else if !direction.SyncsDown() {
+			continue
+		}
+		list, err := c.toClient.Resource(gvr).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			klog.Errorf("Listing %v to prune: %v", gvr, err)
+			continue
+		}
+		for i := range list.Items {
+			downstream := &list.Items[i]
+			if downstream.GetDeletionTimestamp() != nil || !ownedBy(downstream, c.logicalCluster) {
+				continue
+			}
+			namespace, ok := c.transformer.UpstreamNamespace(gvr, downstream.GetNamespace())
+			if !ok {
+				continue
+			}
+			_, exists, err := c.fromDSIF.ForResource(gvr).Informer().GetIndexer().Get(&metav1.PartialObjectMetadata{
+				ObjectMeta: metav1.ObjectMeta{
+					ClusterName: c.logicalCluster,
+					Namespace:   namespace,
+					Name:        downstream.GetName(),
+				},
+			})
+			if err != nil || exists {
+				continue
+			}
+			if err := c.removeDownstream(ctx, gvr, c.getClient(gvr, downstream.GetNamespace()), downstream); err != nil {
+				klog.Errorf("Pruning %s %s/%s: %v", gvr.Resource, downstream.GetNamespace(), downstream.GetName(), err)
+				continue
+			}
+			klog.Infof("Pruned %s %s/%s which doesn't target cluster %s anymore", gvr.Resource, downstream.GetNamespace(), downstream.GetName(), c.clusterID)
+		}
+	}
+}
+
+// removeDownstream removes the downstream object of an upstream object which was deleted,
+// or doesn't target the cluster anymore, following the deletion policy of its resource.
+func (c *Controller) removeDownstream(ctx context.Context, gvr schema.GroupVersionResource, client dynamic.ResourceInterface, downstream *unstructured.Unstructured) error {
+	policy, err := c.filter.Deletion(gvr)
+	if err != nil {
+		return err
+	}
+	var propagation metav1.DeletionPropagation
+	switch policy {
+	case clusterv1alpha1.ObjectDeletionPolicyRetain:
+		return retain(ctx, client, downstream)
+	case clusterv1alpha1.ObjectDeletionPolicyForeground:
+		propagation = metav1.DeletePropagationForeground
+	case clusterv1alpha1.ObjectDeletionPolicyOrphan:
+		propagation = metav1.DeletePropagationOrphan
+	default:
+		propagation = metav1.DeletePropagationBackground
+	}
+	return deleteUnchanged(ctx, client, downstream, propagation)
+}
+
+// retain leaves the downstream object in the cluster, labeled as retained, and releases it:
+// without the labels and annotation of synced objects, it is not pruned nor deleted with
+// the Cluster anymore.
+func retain(ctx context.Context, client dynamic.ResourceInterface, downstream *unstructured.Unstructured) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"uid": downstream.GetUID(),
+			"labels": map[string]interface{}{
+				clusterv1alpha1.RetainedLabel: "true",
+				clusterv1alpha1.ClusterLabel:  nil,
+				LogicalClusterLabel:           nil,
+			},
+			"annotations": map[string]interface{}{
+				SyncIdentityAnnotation: nil,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = client.Patch(ctx, downstream.GetName(), types.MergePatchType, patch, metav1.PatchOptions{})
+	if k8serrors.IsNotFound(err) || k8serrors.IsConflict(err) {
+		return nil
+	}
+	return err
+}
+
+// deleteUnchanged deletes the downstream object, unless it was replaced in the meantime.
+func deleteUnchanged(ctx context.Context, client dynamic.ResourceInterface, downstream *unstructured.Unstructured, propagation metav1.DeletionPropagation) error {
+	uid := downstream.GetUID()
+	err := client.Delete(ctx, downstream.GetName(), metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: &uid}, PropagationPolicy: &propagation})
+	if k8serrors.IsNotFound(err) || k8serrors.IsConflict(err) {
+		return nil
+	}
+	return err
+}
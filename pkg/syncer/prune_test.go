@@ -0,0 +1,155 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/tools/cache"
+
+	clusterv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/cluster/v1alpha1"
+	clusterlisters "github.com/kcp-dev/kcp/pkg/client/listers/cluster/v1alpha1"
+)
+
+func TestPrune(t *testing.T) {
+	configmaps := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	listKinds := map[schema.GroupVersionResource]string{configmaps: "ConfigMapList"}
+
+	object := func(clusterName, name, uid string, labels, annotations map[string]string) runtime.Object {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("v1")
+		obj.SetKind("ConfigMap")
+		obj.SetClusterName(clusterName)
+		obj.SetNamespace("default")
+		obj.SetName(name)
+		obj.SetUID(types.UID(uid))
+		obj.SetLabels(labels)
+		obj.SetAnnotations(annotations)
+		return obj
+	}
+	synced := syncedLabels("us-east1", "admin")
+	identity := func(uid string) map[string]string {
+		return map[string]string{SyncIdentityAnnotation: "admin/" + uid}
+	}
+
+	upstream := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds,
+		object("admin", "targeted", "up-1", map[string]string{clusterv1alpha1.ClusterLabel: "us-east1"}, nil),
+	)
+	downstream := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds,
+		object("", "targeted", "down-1", synced, identity("up-1")),
+		object("", "retargeted", "down-2", synced, identity("up-2")),
+		object("", "legacy", "down-3", synced, nil),
+		object("", "dependency", "down-4", labels.Merge(synced, labels.Set{DependencyLabel: "true"}), nil),
+		object("", "foreign", "down-5", nil, nil),
+	)
+
+	informers := dynamicinformer.NewDynamicSharedInformerFactory(upstream, 0)
+	informers.ForResource(configmaps)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	informers.Start(stopCh)
+	informers.WaitForCacheSync(stopCh)
+
+	c := &Controller{
+		fromDSIF:       informers,
+		toClient:       downstream,
+		gvrs:           []schema.GroupVersionResource{configmaps},
+		clusterID:      "us-east1",
+		logicalCluster: "admin",
+	}
+	c.prune(context.Background())
+
+	list, err := downstream.Resource(configmaps).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	remaining := sets.NewString()
+	for _, obj := range list.Items {
+		remaining.Insert(obj.GetName())
+	}
+	if diff := cmp.Diff([]string{"dependency", "foreign", "targeted"}, remaining.List()); diff != "" {
+		t.Errorf("unexpected remaining objects (-want +got):\n%s", diff)
+	}
+}
+
+func TestPruneRetain(t *testing.T) {
+	configmaps := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	listKinds := map[schema.GroupVersionResource]string{configmaps: "ConfigMapList"}
+
+	retargeted := &unstructured.Unstructured{}
+	retargeted.SetAPIVersion("v1")
+	retargeted.SetKind("ConfigMap")
+	retargeted.SetNamespace("default")
+	retargeted.SetName("retargeted")
+	retargeted.SetUID("down-1")
+	retargeted.SetLabels(syncedLabels("us-east1", "admin"))
+	retargeted.SetAnnotations(map[string]string{SyncIdentityAnnotation: "admin/up-1"})
+	downstream := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, retargeted)
+
+	upstream := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds)
+	informers := dynamicinformer.NewDynamicSharedInformerFactory(upstream, 0)
+	informers.ForResource(configmaps)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	informers.Start(stopCh)
+	informers.WaitForCacheSync(stopCh)
+
+	clusterIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	if err := clusterIndexer.Add(&clusterv1alpha1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "us-east1", ClusterName: "admin"},
+		Spec: clusterv1alpha1.ClusterSpec{
+			SyncPolicy: &clusterv1alpha1.SyncPolicy{
+				Deletions: []clusterv1alpha1.ResourceDeletionPolicy{{Resource: "configmaps", Policy: clusterv1alpha1.ObjectDeletionPolicyRetain}},
+			},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Controller{
+		fromDSIF:       informers,
+		toClient:       downstream,
+		gvrs:           []schema.GroupVersionResource{configmaps},
+		clusterID:      "us-east1",
+		logicalCluster: "admin",
+		filter:         NewFilter("us-east1", "admin", clusterlisters.NewClusterLister(clusterIndexer), nil),
+	}
+	c.prune(context.Background())
+
+	retained, err := downstream.Resource(configmaps).Namespace("default").Get(context.Background(), "retargeted", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected the object to be retained: %v", err)
+	}
+	if diff := cmp.Diff(map[string]string{clusterv1alpha1.RetainedLabel: "true"}, retained.GetLabels()); diff != "" {
+		t.Errorf("unexpected labels of the retained object (-want +got):\n%s", diff)
+	}
+	if _, found := retained.GetAnnotations()[SyncIdentityAnnotation]; found {
+		t.Errorf("expected the retained object to be released")
+	}
+}
@@ -18,18 +18,22 @@ package syncer
 
 import (
 	"context"
+	"encoding/json"
 
 	"k8s.io/apimachinery/pkg/api/equality"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
+
+	clusterv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/cluster/v1alpha1"
 )
 
 func deepEqualApartFromStatus(oldObj, newObj interface{}) bool {
@@ -38,7 +42,8 @@ func deepEqualApartFromStatus(oldObj, newObj interface{}) bool {
 	if !isOldObjUnstructured || !isNewObjUnstructured {
 		return false
 	}
-	if !equality.Semantic.DeepEqual(oldUnstrob.GetAnnotations(), newUnstrob.GetAnnotations()) {
+	// The statuses recorded per cluster are part of the status.
+	if !equality.Semantic.DeepEqual(withoutClusterStatuses(oldUnstrob.GetAnnotations()), withoutClusterStatuses(newUnstrob.GetAnnotations())) {
 		return false
 	}
 	if !equality.Semantic.DeepEqual(oldUnstrob.GetLabels(), newUnstrob.GetLabels()) {
@@ -58,9 +63,15 @@ func deepEqualApartFromStatus(oldObj, newObj interface{}) bool {
 	return true
 }
 
-const specSyncerAgent = "kcp#spec-syncer/v0.0.0"
+const (
+	specSyncerAgent = "kcp#spec-syncer/v0.0.0"
+
+	// specFieldManager is the field manager owning the fields of downstream objects which
+	// are synced from upstream.
+	specFieldManager = "kcp-spec-syncer"
+)
 
-func NewSpecSyncer(from, to *rest.Config, syncedResourceTypes []string, clusterID, logicalClusterID string) (*Controller, error) {
+func NewSpecSyncer(from, to *rest.Config, syncedResourceTypes []string, clusterID, logicalClusterID string, transformer *Transformer) (*Controller, error) {
 	from = rest.CopyConfig(from)
 	from.UserAgent = specSyncerAgent
 	to = rest.CopyConfig(to)
@@ -87,7 +98,7 @@ func NewSpecSyncer(from, to *rest.Config, syncedResourceTypes []string, clusterI
 			},
 			DeleteFunc: func(obj interface{}) { c.AddToQueue(gvr, obj) },
 		}
-	}, syncedResourceTypes, clusterID)
+	}, syncedResourceTypes, clusterID, transformer)
 }
 
 // TODO:
@@ -103,6 +114,9 @@ func (c *Controller) ensureNamespaceExists(namespace string) error {
 	newNamespace.SetAPIVersion("v1")
 	newNamespace.SetKind("Namespace")
 	newNamespace.SetName(namespace)
+	// Namespaces created by the syncer are deleted along with the objects synced to them
+	// when the cluster is deregistered.
+	newNamespace.SetLabels(syncedLabels(c.clusterID, c.logicalCluster))
 	if _, err := namespaces.Create(context.TODO(), newNamespace, metav1.CreateOptions{}); err != nil {
 		if !k8serrors.IsAlreadyExists(err) {
 			klog.Infof("Error while creating namespace %s: %v", namespace, err)
@@ -113,25 +127,89 @@ func (c *Controller) ensureNamespaceExists(namespace string) error {
 }
 
 func deleteFromDownstream(c *Controller, ctx context.Context, gvr schema.GroupVersionResource, namespace, name string) error {
+	if direction, err := c.filter.Direction(gvr); err != nil {
+		return err
+	} else if !direction.SyncsDown() {
+		return nil
+	}
+
 	// TODO: get UID of just-deleted object and pass it as a precondition on this delete.
 	// This would avoid races where an object is deleted and another object with the same name is created immediately after.
 
-	return c.getClient(gvr, namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	downstreamNamespace, err := c.transformer.DownstreamNamespace(gvr, namespace)
+	if err != nil {
+		return err
+	}
+	client := c.getClient(gvr, downstreamNamespace)
+	existing, err := client.Get(ctx, name, metav1.GetOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+	// Objects of the same name synced from other logical clusters are left alone, as are
+	// the cluster-scoped objects which were not synced from this one.
+	deleted := false
+	if err == nil && ownedBy(existing, c.logicalCluster) && (downstreamNamespace != "" || syncedFrom(existing, c.logicalCluster)) {
+		if err := c.removeDownstream(ctx, gvr, client, existing); err != nil {
+			return err
+		}
+		deleted = true
+	}
+	// The dependencies of the object may not be needed anymore.
+	if err := c.collectDependencies(ctx, namespace, downstreamNamespace); err != nil {
+		return err
+	}
+	if !deleted {
+		return nil
+	}
+
+	// The object may still exist upstream, synced to other clusters.
+	return c.setClusterStatus(ctx, c.fromClient.Resource(gvr).Namespace(namespace), gvr, name, nil)
 }
 
-func upsertIntoDownstream(c *Controller, ctx context.Context, gvr schema.GroupVersionResource, namespace string, unstrob *unstructured.Unstructured) error {
-	if err := c.ensureNamespaceExists(namespace); err != nil {
-		klog.Error(err)
+func upsertIntoDownstream(c *Controller, ctx context.Context, gvr schema.GroupVersionResource, namespace string, upstream *unstructured.Unstructured) error {
+	allowed, err := c.filter.Allows(gvr, upstream)
+	if err != nil {
 		return err
 	}
+	if !allowed {
+		// The object may have been synced before the sync policy excluded it.
+		return deleteFromDownstream(c, ctx, gvr, namespace, upstream.GetName())
+	}
+	if direction, err := c.filter.Direction(gvr); err != nil {
+		return err
+	} else if !direction.SyncsDown() {
+		klog.V(4).Infof("Not syncing %s %s/%s down: its resource is synced %s", gvr.Resource, namespace, upstream.GetName(), direction)
+		return nil
+	}
 
-	client := c.getClient(gvr, namespace)
+	unstrob := upstream.DeepCopy()
+	if err := c.transformer.Transform(clusterv1alpha1.SyncDirectionDown, gvr, unstrob); err != nil {
+		klog.Errorf("Transforming resource %s/%s: %v", namespace, unstrob.GetName(), err)
+		return err
+	}
+	namespace = unstrob.GetNamespace()
 
-	unstrob = unstrob.DeepCopy()
+	if namespace != "" {
+		if err := c.ensureNamespaceExists(namespace); err != nil {
+			klog.Error(err)
+			return err
+		}
+		if err := c.syncDependencies(ctx, gvr, upstream, namespace); err != nil {
+			klog.Error(err)
+			return err
+		}
+	}
+
+	client := c.getClient(gvr, namespace)
 
-	// Attempt to create the object; if the object already exists, update it.
+	// Only the fields set upstream are applied, so that fields defaulted or added
+	// downstream are kept.
 	unstrob.SetUID("")
 	unstrob.SetResourceVersion("")
+	unstrob.SetGeneration(0)
+	unstrob.SetCreationTimestamp(metav1.Time{})
+	unstrob.SetManagedFields(nil)
+	unstructured.RemoveNestedField(unstrob.Object, "status")
 
 	ownedByLabel := unstrob.GetLabels()["kcp.dev/owned-by"]
 	var ownerReferences []metav1.OwnerReference
@@ -143,26 +221,65 @@ func upsertIntoDownstream(c *Controller, ctx context.Context, gvr schema.GroupVe
 	}
 	unstrob.SetOwnerReferences(ownerReferences)
 
-	if _, err := client.Create(ctx, unstrob, metav1.CreateOptions{}); err != nil {
-		if !k8serrors.IsAlreadyExists(err) {
-			klog.Errorf("Creating resource %s/%s: %v", namespace, unstrob.GetName(), err)
-			return err
-		}
+	objLabels := unstrob.GetLabels()
+	if objLabels == nil {
+		objLabels = map[string]string{}
+	}
+	for k, v := range syncedLabels(c.clusterID, c.logicalCluster) {
+		objLabels[k] = v
+	}
+	unstrob.SetLabels(objLabels)
 
-		existing, err := client.Get(ctx, unstrob.GetName(), metav1.GetOptions{})
-		if err != nil {
-			klog.Errorf("Getting resource %s/%s: %v", namespace, unstrob.GetName(), err)
-			return err
+	annotations := withoutClusterStatuses(unstrob.GetAnnotations())
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[UpstreamVersionAnnotation] = upstreamVersion(upstream)
+	annotations[SyncIdentityAnnotation] = syncIdentity(c.logicalCluster, upstream)
+	unstrob.SetAnnotations(annotations)
+
+	existing, err := client.Get(ctx, unstrob.GetName(), metav1.GetOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		klog.Errorf("Getting resource %s/%s: %v", namespace, unstrob.GetName(), err)
+		return err
+	}
+	if err == nil && namespace == "" && !syncedFrom(existing, c.logicalCluster) {
+		// Cluster-scoped objects are shared by the logical clusters syncing to the cluster:
+		// the first one to sync an object of a given name owns it.
+		c.recordNameConflict(upstream, gvr, existing)
+		return nil
+	}
+	if err == nil {
+		if drifted(existing, unstrob) {
+			if c.conflictPolicy == ConflictPolicyDownstreamWins {
+				c.recordDrift(upstream, gvr, false)
+				return nil
+			}
+			c.recordDrift(upstream, gvr, true)
+		} else if containsFields(existing.Object, unstrob.Object) {
+			klog.V(4).Infof("Object %s/%s is up to date", gvr.Resource, unstrob.GetName())
+			return nil
 		}
-		klog.Infof("Object %s/%s already exists: update it", gvr.Resource, unstrob.GetName())
+	}
 
-		unstrob.SetResourceVersion(existing.GetResourceVersion())
-		if _, err := client.Update(ctx, unstrob, metav1.UpdateOptions{}); err != nil {
-			klog.Errorf("Updating resource %s/%s: %v", namespace, unstrob.GetName(), err)
-			return err
+	data, err := json.Marshal(unstrob.Object)
+	if err != nil {
+		return err
+	}
+	force := true
+	if _, err := client.Patch(ctx, unstrob.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{FieldManager: specFieldManager, Force: &force}); err != nil {
+		klog.Errorf("Applying resource %s/%s: %v", namespace, unstrob.GetName(), err)
+		return err
+	}
+	klog.Infof("Applied object %s/%s", gvr.Resource, unstrob.GetName())
+
+	if existing != nil {
+		// Dependencies the object doesn't reference anymore may not be needed anymore.
+		before, _ := dependencies(gvr, existing)
+		after, _ := dependencies(gvr, unstrob)
+		if !equality.Semantic.DeepEqual(before, after) {
+			return c.collectDependencies(ctx, upstream.GetNamespace(), namespace)
 		}
-		return nil
 	}
-	klog.Infof("Created object %s/%s", gvr.Resource, unstrob.GetName())
 	return nil
 }
@@ -0,0 +1,87 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"testing"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestClusterScopedOwnership(t *testing.T) {
+	clusterroles := schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterroles"}
+	listKinds := map[schema.GroupVersionResource]string{clusterroles: "ClusterRoleList"}
+
+	clusterRole := func(name string, labels, annotations map[string]string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("rbac.authorization.k8s.io/v1")
+		obj.SetKind("ClusterRole")
+		obj.SetName(name)
+		obj.SetUID(types.UID("down-" + name))
+		obj.SetLabels(labels)
+		obj.SetAnnotations(annotations)
+		return obj
+	}
+	preexisting := clusterRole("admin", nil, nil)
+	foreign := clusterRole("shared", syncedLabels("us-east1", "tenant"), map[string]string{SyncIdentityAnnotation: "tenant/up-1"})
+
+	for _, tt := range []struct {
+		name     string
+		existing *unstructured.Unstructured
+	}{
+		{name: "created in the cluster", existing: preexisting},
+		{name: "synced from another logical cluster", existing: foreign},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			downstream := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, tt.existing.DeepCopy())
+			c := &Controller{
+				toClient:       downstream,
+				clusterID:      "us-east1",
+				logicalCluster: "admin",
+			}
+			ctx := context.Background()
+
+			upstream := clusterRole(tt.existing.GetName(), map[string]string{"kcp.dev/cluster": "us-east1"}, nil)
+			upstream.SetUID("up-2")
+			upstream.Object["rules"] = []interface{}{map[string]interface{}{"verbs": []interface{}{"*"}}}
+			if err := upsertIntoDownstream(c, ctx, clusterroles, "", upstream); err != nil {
+				t.Fatalf("unexpected error syncing: %v", err)
+			}
+			got, err := downstream.Resource(clusterroles).Get(ctx, tt.existing.GetName(), metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, found := got.Object["rules"]; found {
+				t.Errorf("expected the existing object not to be overwritten")
+			}
+
+			if err := deleteFromDownstream(c, ctx, clusterroles, "", tt.existing.GetName()); err != nil {
+				t.Fatalf("unexpected error deleting: %v", err)
+			}
+			if _, err := downstream.Resource(clusterroles).Get(ctx, tt.existing.GetName(), metav1.GetOptions{}); k8serrors.IsNotFound(err) {
+				t.Errorf("expected the existing object not to be deleted")
+			}
+		})
+	}
+}
@@ -20,7 +20,6 @@ import (
 	"context"
 
 	"k8s.io/apimachinery/pkg/api/equality"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/discovery"
@@ -28,6 +27,8 @@ import (
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
+
+	clusterv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/cluster/v1alpha1"
 )
 
 func deepEqualStatus(oldObj, newObj interface{}) bool {
@@ -43,9 +44,15 @@ func deepEqualStatus(oldObj, newObj interface{}) bool {
 	return equality.Semantic.DeepEqual(oldStatus, newStatus)
 }
 
-const statusSyncerAgent = "kcp#status-syncer/v0.0.0"
+const (
+	statusSyncerAgent = "kcp#status-syncer/v0.0.0"
+
+	// statusFieldManager is the field manager owning the status of upstream objects which
+	// is synced from downstream.
+	statusFieldManager = "kcp-status-syncer"
+)
 
-func NewStatusSyncer(from, to *rest.Config, syncedResourceTypes []string, clusterID, logicalClusterID string) (*Controller, error) {
+func NewStatusSyncer(from, to *rest.Config, syncedResourceTypes []string, clusterID, logicalClusterID string, transformer *Transformer) (*Controller, error) {
 	from = rest.CopyConfig(from)
 	from.UserAgent = statusSyncerAgent
 	to = rest.CopyConfig(to)
@@ -69,27 +76,39 @@ func NewStatusSyncer(from, to *rest.Config, syncedResourceTypes []string, cluste
 				}
 			},
 		}
-	}, syncedResourceTypes, clusterID)
+	}, syncedResourceTypes, clusterID, transformer)
 }
 
 func updateStatusInUpstream(c *Controller, ctx context.Context, gvr schema.GroupVersionResource, namespace string, unstrob *unstructured.Unstructured) error {
-	client := c.getClient(gvr, namespace)
-
+	if direction, err := c.filter.Direction(gvr); err != nil {
+		return err
+	} else if !direction.SyncsUp() {
+		return nil
+	}
+	if _, ok := c.transformer.UpstreamNamespace(gvr, namespace); !ok {
+		// synced from another logical cluster
+		return nil
+	}
+	if namespace == "" && !syncedFrom(unstrob, c.logicalCluster) {
+		// cluster-scoped object synced from another logical cluster
+		return nil
+	}
 	unstrob = unstrob.DeepCopy()
-
-	// Attempt to create the object; if the object already exists, update it.
-	unstrob.SetUID("")
-	unstrob.SetResourceVersion("")
-
-	existing, err := client.Get(ctx, unstrob.GetName(), metav1.GetOptions{})
-	if err != nil {
-		klog.Errorf("Getting resource %s/%s: %v", namespace, unstrob.GetName(), err)
+	if err := c.transformer.Transform(clusterv1alpha1.SyncDirectionUp, gvr, unstrob); err != nil {
+		klog.Errorf("Transforming resource %s/%s: %v", namespace, unstrob.GetName(), err)
 		return err
 	}
+	namespace = unstrob.GetNamespace()
+
+	status, found := unstrob.Object["status"]
+	if !found {
+		return nil
+	}
 
-	unstrob.SetResourceVersion(existing.GetResourceVersion())
-	if _, err := client.UpdateStatus(ctx, unstrob, metav1.UpdateOptions{}); err != nil {
-		klog.Errorf("Updating status of resource %s/%s: %v", namespace, unstrob.GetName(), err)
+	// The status in this cluster is recorded, and the upstream status is aggregated
+	// across all the clusters the object is synced to.
+	if err := c.setClusterStatus(ctx, c.getClient(gvr, namespace), gvr, unstrob.GetName(), status); err != nil {
+		klog.Errorf("Applying status of resource %s/%s: %v", namespace, unstrob.GetName(), err)
 		return err
 	}
 
@@ -23,33 +23,104 @@ import (
 	"strings"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/dynamic/dynamicinformer"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/flowcontrol"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
+
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	"github.com/kcp-dev/kcp/pkg/client/clusterkeys"
+	kcpinformers "github.com/kcp-dev/kcp/pkg/client/informers/externalversions"
+	"github.com/kcp-dev/kcp/pkg/events"
 )
 
 const resyncPeriod = 10 * time.Hour
+
+var namespacesGR = corev1.SchemeGroupVersion.WithResource("namespaces").GroupResource()
 const SyncerNamespaceKey = "SYNCER_NAMESPACE"
 
 type Syncer struct {
 	specSyncer   *Controller
 	statusSyncer *Controller
 	Resources    sets.String
+
+	throttle *throttle
+	cancel   context.CancelFunc
 }
 
 func (s *Syncer) Stop() {
 	s.specSyncer.Stop()
 	s.statusSyncer.Stop()
+	s.cancel()
+	if s.throttle != nil {
+		s.throttle.forget()
+	}
+}
+
+// Options configure the syncer.
+type Options struct {
+	// ConflictPolicy decides which side wins when a downstream object was changed out of
+	// band.
+	ConflictPolicy ConflictPolicy
+	// ResyncPeriod is how often all the synced objects are synced again, catching drift
+	// missed by the watches. Zero disables periodic resyncs.
+	ResyncPeriod time.Duration
+	// StatusAggregators aggregate the statuses of objects synced to several clusters, by
+	// resource. DefaultStatusAggregator is used for the other resources.
+	StatusAggregators map[schema.GroupResource]StatusAggregator
+	// UpsyncSelector selects the pre-existing downstream objects to import upstream, see
+	// ParseUpsyncSelector. Empty disables upsyncing.
+	UpsyncSelector string
+	// NamespaceStrategy decides the downstream namespaces of objects which are not mapped
+	// by a SyncTransform.
+	NamespaceStrategy NamespaceStrategy
+	// QPS and Burst limit the requests made to the downstream cluster, by the spec and
+	// status syncers together. Zero QPS leaves the client defaults.
+	QPS   float32
+	Burst int
+	// BatchInterval is how long the changes of an upstream object are batched before it is
+	// synced: an object changed several times within the interval is only written once
+	// downstream. Zero syncs every change right away.
+	BatchInterval time.Duration
+	// HeartbeatPeriod is how often the syncer records that it is alive in the status of its
+	// Cluster. Zero disables heartbeats.
+	HeartbeatPeriod time.Duration
+	// MirrorEvents mirrors the downstream Events about synced objects and their dependents,
+	// like the Pods of a Deployment, into the upstream namespaces of the synced objects.
+	MirrorEvents bool
+}
+
+// DefaultOptions returns the default options of the syncer.
+func DefaultOptions() Options {
+	return Options{
+		ConflictPolicy:    ConflictPolicyUpstreamWins,
+		ResyncPeriod:      10 * time.Minute,
+		NamespaceStrategy: NamespaceStrategyIdentity,
+		QPS:               10,
+		Burst:             20,
+		BatchInterval:     500 * time.Millisecond,
+		HeartbeatPeriod:   30 * time.Second,
+	}
 }
 
 func (s *Syncer) WaitUntilDone() {
@@ -57,23 +128,164 @@ func (s *Syncer) WaitUntilDone() {
 	<-s.statusSyncer.Done()
 }
 
-func StartSyncer(upstream, downstream *rest.Config, resources sets.String, cluster, logicalCluster string, numSyncerThreads int) (*Syncer, error) {
-	specSyncer, err := NewSpecSyncer(upstream, downstream, resources.List(), cluster, logicalCluster)
+func StartSyncer(upstream, downstream *rest.Config, resources sets.String, cluster, logicalCluster string, numSyncerThreads int, options Options) (*Syncer, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	syncer, err := startSyncer(ctx, upstream, downstream, resources, cluster, logicalCluster, numSyncerThreads, options)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	syncer.cancel = cancel
+	return syncer, nil
+}
+
+func startSyncer(ctx context.Context, upstream, downstream *rest.Config, resources sets.String, cluster, logicalCluster string, numSyncerThreads int, options Options) (*Syncer, error) {
+	upsyncSelector, err := ParseUpsyncSelector(options.UpsyncSelector)
 	if err != nil {
 		return nil, err
 	}
-	statusSyncer, err := NewStatusSyncer(downstream, upstream, resources.List(), cluster, logicalCluster)
+
+	var rateLimiter *throttle
+	if options.QPS > 0 {
+		// The spec and status syncers share the rate limit of the downstream cluster.
+		rateLimiter = newThrottle(flowcontrol.NewTokenBucketRateLimiter(options.QPS, options.Burst), cluster, logicalCluster)
+		downstream = rest.CopyConfig(downstream)
+		downstream.QPS = options.QPS
+		downstream.Burst = options.Burst
+		downstream.RateLimiter = rateLimiter
+	}
+
+	upstreamKubeClient, err := kubernetes.NewClusterForConfig(upstream)
+	if err != nil {
+		return nil, err
+	}
+	kcpClients, err := kcpclient.NewClusterForConfig(upstream)
+	if err != nil {
+		return nil, err
+	}
+	downstreamKubeClient, err := kubernetes.NewForConfig(downstream)
+	if err != nil {
+		return nil, err
+	}
+	kcpInformers := kcpinformers.NewSharedInformerFactoryWithOptions(kcpClients.Cluster(logicalCluster), resyncPeriod)
+	kubeInformers := kubeinformers.NewSharedInformerFactoryWithOptions(upstreamKubeClient.Cluster(logicalCluster), resyncPeriod)
+	transformsInformer := kcpInformers.Cluster().V1alpha1().SyncTransforms()
+	clustersInformer := kcpInformers.Cluster().V1alpha1().Clusters()
+	namespacesInformer := kubeInformers.Core().V1().Namespaces()
+
+	transformer := NewTransformer(cluster, logicalCluster, options.NamespaceStrategy, transformsInformer.Lister())
+	filter := NewFilter(cluster, logicalCluster, clustersInformer.Lister(), namespacesInformer.Lister())
+	recorder := events.NewRecorder(ctx, upstreamKubeClient, scheme.Scheme, "syncer-"+cluster)
+
+	specSyncer, err := NewSpecSyncer(upstream, downstream, resources.List(), cluster, logicalCluster, transformer)
+	if err != nil {
+		return nil, err
+	}
+	statusSyncer, err := NewStatusSyncer(downstream, upstream, resources.List(), cluster, logicalCluster, transformer)
 	if err != nil {
 		specSyncer.Stop()
 		return nil, err
 	}
+	for _, c := range []*Controller{specSyncer, statusSyncer} {
+		c.logicalCluster = logicalCluster
+		c.conflictPolicy = options.ConflictPolicy
+		c.resyncPeriod = options.ResyncPeriod
+		c.statusAggregators = options.StatusAggregators
+		c.recorder = recorder
+	}
+	specSyncer.filter = filter
+	statusSyncer.filter = filter
+	specSyncer.batchInterval = options.BatchInterval
+	specSyncer.recordFailures = true
+
+	// Objects are synced again with the new transforms when they change.
+	resync := func(interface{}) {
+		specSyncer.resyncAll()
+		statusSyncer.resyncAll()
+	}
+	transformsInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    resync,
+		UpdateFunc: func(_, obj interface{}) { resync(obj) },
+		DeleteFunc: resync,
+	})
+	// Objects are filtered again when the sync policy or the namespace labels change.
+	clustersInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			if !equality.Semantic.DeepEqual(policyOf(oldObj), policyOf(newObj)) {
+				specSyncer.resyncAll()
+				statusSyncer.resyncAll()
+			}
+		},
+	})
+	namespacesInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			if !equality.Semantic.DeepEqual(oldObj.(*corev1.Namespace).Labels, newObj.(*corev1.Namespace).Labels) {
+				specSyncer.resyncAll()
+			}
+		},
+	})
+	kcpInformers.Start(ctx.Done())
+	kubeInformers.Start(ctx.Done())
+	if !waitForSync(func() bool {
+		return transformsInformer.Informer().HasSynced() && clustersInformer.Informer().HasSynced() && namespacesInformer.Informer().HasSynced()
+	}, syncTransformsSyncTimeout) {
+		klog.Warningf("Sync transforms and policy of logical cluster %s not synced after %v, syncing without them until they are", logicalCluster, syncTransformsSyncTimeout)
+	}
+
+	// Downstream objects changed out of band are checked for drift by the spec syncer.
+	for _, gvr := range statusSyncer.gvrs {
+		gvr := gvr
+		statusSyncer.fromDSIF.ForResource(gvr).Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				if !deepEqualApartFromStatus(oldObj, newObj) {
+					specSyncer.enqueueUpstreamOf(gvr, newObj)
+				}
+			},
+			DeleteFunc: func(obj interface{}) { specSyncer.enqueueUpstreamOf(gvr, obj) },
+		})
+	}
+
 	specSyncer.Start(numSyncerThreads)
 	statusSyncer.Start(numSyncerThreads)
 
+	// Downstream objects whose upstream object doesn't target the cluster anymore are
+	// pruned at startup, and then at every resync.
+	go func() {
+		if options.ResyncPeriod <= 0 {
+			specSyncer.prune(ctx)
+			return
+		}
+		wait.UntilWithContext(ctx, specSyncer.prune, options.ResyncPeriod)
+	}()
+
+	// Pre-existing downstream objects are imported at startup, and then at every resync.
+	if upsyncSelector != nil {
+		go func() {
+			if options.ResyncPeriod <= 0 {
+				specSyncer.upsyncAll(ctx, upsyncSelector)
+				return
+			}
+			wait.UntilWithContext(ctx, func(ctx context.Context) { specSyncer.upsyncAll(ctx, upsyncSelector) }, options.ResyncPeriod)
+		}()
+	}
+
+	if options.MirrorEvents {
+		mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(downstreamKubeClient.Discovery()))
+		mirror := newEventMirror(cluster, logicalCluster, transformer, mapper, specSyncer.toClient, upstreamKubeClient.Cluster(logicalCluster))
+		downstreamInformers := kubeinformers.NewSharedInformerFactory(downstreamKubeClient, resyncPeriod)
+		downstreamInformers.Core().V1().Events().Informer().AddEventHandler(mirror.handlers())
+		downstreamInformers.Start(ctx.Done())
+	}
+
+	if options.HeartbeatPeriod > 0 && cluster != "" {
+		go heartbeat(ctx, kcpClients.Cluster(logicalCluster), cluster, options.HeartbeatPeriod)
+	}
+
 	return &Syncer{
 		specSyncer:   specSyncer,
 		statusSyncer: statusSyncer,
 		Resources:    resources,
+		throttle:     rateLimiter,
 	}, nil
 }
 
@@ -85,7 +297,8 @@ type Controller struct {
 	queue workqueue.RateLimitingInterface
 
 	// Upstream
-	fromDSIF dynamicinformer.DynamicSharedInformerFactory
+	fromDSIF   dynamicinformer.DynamicSharedInformerFactory
+	fromClient dynamic.Interface
 
 	// Downstream
 	toClient dynamic.Interface
@@ -96,10 +309,26 @@ type Controller struct {
 	deleteFn DeleteFunc
 
 	namespace string
+
+	gvrs        []schema.GroupVersionResource
+	transformer *Transformer
+
+	clusterID         string
+	logicalCluster    string
+	filter            *Filter
+	conflictPolicy    ConflictPolicy
+	resyncPeriod      time.Duration
+	statusAggregators map[schema.GroupResource]StatusAggregator
+	recorder          record.EventRecorder
+	batchInterval     time.Duration
+
+	// recordFailures is set when the synced objects are read from kcp, so that Events
+	// about their sync failures can be recorded on them.
+	recordFailures bool
 }
 
 // New returns a new syncer Controller syncing spec from "from" to "to".
-func New(fromDiscovery discovery.DiscoveryInterface, fromClient, toClient dynamic.Interface, upsertFn UpsertFunc, deleteFn DeleteFunc, handlers HandlersProvider, syncedResourceTypes []string, clusterID string) (*Controller, error) {
+func New(fromDiscovery discovery.DiscoveryInterface, fromClient, toClient dynamic.Interface, upsertFn UpsertFunc, deleteFn DeleteFunc, handlers HandlersProvider, syncedResourceTypes []string, clusterID string, transformer *Transformer) (*Controller, error) {
 	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
 	stopCh := make(chan struct{})
 
@@ -107,13 +336,17 @@ func New(fromDiscovery discovery.DiscoveryInterface, fromClient, toClient dynami
 		// TODO: should we have separate upstream and downstream sync workqueues?
 		queue: queue,
 
-		toClient: toClient,
+		fromClient: fromClient,
+		toClient:   toClient,
 
 		stopCh: stopCh,
 
 		upsertFn:  upsertFn,
 		deleteFn:  deleteFn,
 		namespace: os.Getenv(SyncerNamespaceKey),
+
+		clusterID:   clusterID,
+		transformer: transformer,
 	}
 
 	fromDSIF := dynamicinformer.NewFilteredDynamicSharedInformerFactory(fromClient, resyncPeriod, metav1.NamespaceAll, func(o *metav1.ListOptions) {
@@ -135,6 +368,7 @@ func New(fromDiscovery discovery.DiscoveryInterface, fromClient, toClient dynami
 		}
 
 		fromDSIF.ForResource(*gvr).Informer().AddEventHandler(handlers(&c, *gvr))
+		c.gvrs = append(c.gvrs, *gvr)
 		klog.Infof("Set up informer for %v", gvr)
 	}
 	fromDSIF.WaitForCacheSync(stopCh)
@@ -200,8 +434,8 @@ func getAllGVRs(discoveryClient discovery.DiscoveryInterface, resourcesToSync ..
 				// foo/status, pods/exec, namespace/finalize, etc.
 				continue
 			}
-			if !ai.Namespaced {
-				// Ignore cluster-scoped things.
+			if groupResource == namespacesGR {
+				// Namespaces are created by the syncer for the objects synced to them.
 				continue
 			}
 			if !contains(ai.Verbs, "watch") {
@@ -225,11 +459,36 @@ func getAllGVRs(discoveryClient discovery.DiscoveryInterface, resourcesToSync ..
 
 type holder struct {
 	gvr schema.GroupVersionResource
-	obj interface{}
+	key string
 }
 
+// AddToQueue queues the object to sync. Objects are queued by key, so that the changes of
+// an object batched for the batch interval are synced at once.
 func (c *Controller) AddToQueue(gvr schema.GroupVersionResource, obj interface{}) {
-	c.queue.AddRateLimited(holder{gvr: gvr, obj: obj})
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	if c.batchInterval > 0 {
+		c.queue.AddAfter(holder{gvr: gvr, key: key}, c.batchInterval)
+		return
+	}
+	c.queue.AddRateLimited(holder{gvr: gvr, key: key})
+}
+
+// resyncAll queues all the objects to sync again.
+func (c *Controller) resyncAll() {
+	for _, gvr := range c.gvrs {
+		objs, err := c.fromDSIF.ForResource(gvr).Lister().List(labels.Everything())
+		if err != nil {
+			klog.Errorf("Listing %v to resync: %v", gvr, err)
+			continue
+		}
+		for _, obj := range objs {
+			c.AddToQueue(gvr, obj)
+		}
+	}
 }
 
 // Start starts N worker processes processing work items.
@@ -237,6 +496,23 @@ func (c *Controller) Start(numThreads int) {
 	for i := 0; i < numThreads; i++ {
 		go c.startWorker()
 	}
+	if c.resyncPeriod > 0 {
+		go c.startResyncs()
+	}
+}
+
+// startResyncs syncs all the objects again every resync period until stopCh is closed.
+func (c *Controller) startResyncs() {
+	ticker := time.NewTicker(c.resyncPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stopCh:
+			return
+		case <-ticker.C:
+			c.resyncAll()
+		}
+	}
 }
 
 // startWorker processes work items until stopCh is closed.
@@ -273,7 +549,7 @@ func (c *Controller) processNextWorkItem() bool {
 	// other workers.
 	defer c.queue.Done(i)
 
-	err := c.process(h.gvr, h.obj)
+	err := c.process(h.gvr, h.key)
 	c.handleErr(err, i)
 	return true
 }
@@ -297,34 +573,36 @@ func (c *Controller) handleErr(err error, i interface{}) {
 	c.queue.Forget(i)
 	utilruntime.HandleError(err)
 	klog.Errorf("Dropping key %q after failed retries: %v", i, err)
+	c.recordFailure(i.(holder), err)
 }
 
-func (c *Controller) process(gvr schema.GroupVersionResource, obj interface{}) error {
-	klog.V(2).Infof("Process object of type: %T : %v", obj, obj)
-	meta, isMeta := obj.(metav1.Object)
-	if !isMeta {
-		if tombstone, isTombstone := obj.(cache.DeletedFinalStateUnknown); isTombstone {
-			meta, isMeta = tombstone.Obj.(metav1.Object)
-			if !isMeta {
-				err := fmt.Errorf("Tombstone contained object is expected to be a metav1.Object, but is %T: %#v", obj, obj)
-				klog.Error(err)
-				return err
-			}
-		} else {
-			err := fmt.Errorf("Object to synchronize is expected to be a metav1.Object, but is %T", obj)
-			klog.Error(err)
-			return err
-		}
+// recordFailure records an Event about the failed sync of an object on the object in kcp.
+func (c *Controller) recordFailure(h holder, err error) {
+	if !c.recordFailures || c.recorder == nil {
+		return
+	}
+	obj, exists, getErr := c.fromDSIF.ForResource(h.gvr).Informer().GetIndexer().GetByKey(h.key)
+	if getErr != nil || !exists {
+		return
+	}
+	c.recorder.Eventf(obj.(runtime.Object), corev1.EventTypeWarning, "SyncFailed", "Failed to sync to cluster %s: %v", c.clusterID, err)
+}
+
+func (c *Controller) process(gvr schema.GroupVersionResource, key string) error {
+	klog.V(2).Infof("Process %s object %s", gvr.Resource, key)
+	_, namespace, name, err := clusterkeys.SplitClusterKey(key)
+	if err != nil {
+		klog.Error(err)
+		return err
 	}
-	namespace, name := meta.GetNamespace(), meta.GetName()
 	if c.inSyncerNamespace(namespace) {
-		klog.V(2).Infof("Skipping object of type: %T : %v in syncer namespace", obj, obj)
+		klog.V(2).Infof("Skipping %s object %s in syncer namespace", gvr.Resource, key)
 		return nil
 	}
 
 	ctx := context.TODO()
 
-	obj, exists, err := c.fromDSIF.ForResource(gvr).Informer().GetIndexer().Get(obj)
+	obj, exists, err := c.fromDSIF.ForResource(gvr).Informer().GetIndexer().GetByKey(key)
 	if err != nil {
 		klog.Error(err)
 		return err
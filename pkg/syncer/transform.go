@@ -0,0 +1,208 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	clusterv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/cluster/v1alpha1"
+	clusterlisters "github.com/kcp-dev/kcp/pkg/client/listers/cluster/v1alpha1"
+)
+
+// Transformer applies the SyncTransforms of a logical cluster to the objects synced to
+// and from a physical cluster, and maps their namespaces according to the namespace
+// strategy. A nil Transformer leaves objects unchanged.
+type Transformer struct {
+	clusterID         string
+	logicalCluster    string
+	namespaceStrategy NamespaceStrategy
+	lister            clusterlisters.SyncTransformLister
+}
+
+// NewTransformer returns a Transformer applying the SyncTransforms listed by lister which
+// match the given cluster, and mapping the namespaces of the given logical cluster with
+// the given strategy.
+func NewTransformer(clusterID, logicalCluster string, namespaceStrategy NamespaceStrategy, lister clusterlisters.SyncTransformLister) *Transformer {
+	return &Transformer{
+		clusterID:         clusterID,
+		logicalCluster:    logicalCluster,
+		namespaceStrategy: namespaceStrategy,
+		lister:            lister,
+	}
+}
+
+// transforms returns the transforms matching the direction and resource, ordered by name.
+func (t *Transformer) transforms(direction clusterv1alpha1.SyncDirection, gvr schema.GroupVersionResource) []*clusterv1alpha1.SyncTransform {
+	if t == nil {
+		return nil
+	}
+	all, err := t.lister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("Listing sync transforms: %v", err)
+		return nil
+	}
+	var matching []*clusterv1alpha1.SyncTransform
+	for _, transform := range all {
+		transformDirection := transform.Spec.Direction
+		if transformDirection == "" {
+			transformDirection = clusterv1alpha1.SyncDirectionDown
+		}
+		if transformDirection != direction {
+			continue
+		}
+		if len(transform.Spec.Clusters) > 0 && !contains(transform.Spec.Clusters, t.clusterID) {
+			continue
+		}
+		if len(transform.Spec.Resources) > 0 && !contains(transform.Spec.Resources, gvr.GroupResource().String()) && !contains(transform.Spec.Resources, gvr.Resource) {
+			continue
+		}
+		matching = append(matching, transform)
+	}
+	sort.Slice(matching, func(i, j int) bool { return matching[i].Name < matching[j].Name })
+	return matching
+}
+
+// Transform applies the matching transforms to obj in place, and moves it to the
+// namespace it is synced to in the given direction.
+func (t *Transformer) Transform(direction clusterv1alpha1.SyncDirection, gvr schema.GroupVersionResource, obj *unstructured.Unstructured) error {
+	for _, transform := range t.transforms(direction, gvr) {
+		if err := applyTransform(transform, obj); err != nil {
+			return fmt.Errorf("applying sync transform %q: %w", transform.Name, err)
+		}
+	}
+	if obj.GetNamespace() == "" {
+		return nil
+	}
+	if direction == clusterv1alpha1.SyncDirectionUp {
+		namespace, ok := t.UpstreamNamespace(gvr, obj.GetNamespace())
+		if !ok {
+			return fmt.Errorf("namespace %q is not synced from logical cluster %s", obj.GetNamespace(), t.logicalCluster)
+		}
+		obj.SetNamespace(namespace)
+		return nil
+	}
+	namespace, err := t.DownstreamNamespace(gvr, obj.GetNamespace())
+	if err != nil {
+		return err
+	}
+	obj.SetNamespace(namespace)
+	return nil
+}
+
+// DownstreamNamespace returns the namespace the objects of the given resource and
+// namespace are synced to. The namespace mappings of SyncTransforms take precedence over
+// the namespace strategy.
+func (t *Transformer) DownstreamNamespace(gvr schema.GroupVersionResource, namespace string) (string, error) {
+	mapped := false
+	for _, transform := range t.transforms(clusterv1alpha1.SyncDirectionDown, gvr) {
+		if to, ok := transform.Spec.Namespaces[namespace]; ok {
+			namespace = to
+			mapped = true
+		}
+	}
+	if mapped || t == nil {
+		return namespace, nil
+	}
+	return downstreamNamespace(t.namespaceStrategy, t.logicalCluster, namespace)
+}
+
+// UpstreamNamespace returns the namespace the objects of the given resource and
+// downstream namespace were synced from. It returns false if objects of that namespace
+// are not synced from the logical cluster of the Transformer.
+func (t *Transformer) UpstreamNamespace(gvr schema.GroupVersionResource, namespace string) (string, bool) {
+	mapped := false
+	transforms := t.transforms(clusterv1alpha1.SyncDirectionDown, gvr)
+	for i := len(transforms) - 1; i >= 0; i-- {
+		for from, to := range transforms[i].Spec.Namespaces {
+			if to == namespace {
+				namespace = from
+				mapped = true
+				break
+			}
+		}
+	}
+	if mapped || t == nil {
+		return namespace, true
+	}
+	return upstreamNamespace(t.namespaceStrategy, t.logicalCluster, namespace)
+}
+
+func applyTransform(transform *clusterv1alpha1.SyncTransform, obj *unstructured.Unstructured) error {
+	for _, path := range transform.Spec.RemoveFields {
+		unstructured.RemoveNestedField(obj.Object, fieldPath(path)...)
+	}
+
+	for _, move := range transform.Spec.MoveFields {
+		value, found, err := unstructured.NestedFieldNoCopy(obj.Object, fieldPath(move.From)...)
+		if err != nil {
+			return err
+		}
+		if !found {
+			continue
+		}
+		unstructured.RemoveNestedField(obj.Object, fieldPath(move.From)...)
+		if err := unstructured.SetNestedField(obj.Object, value, fieldPath(move.To)...); err != nil {
+			return err
+		}
+	}
+
+	if len(transform.Spec.Labels) > 0 {
+		objLabels := obj.GetLabels()
+		if objLabels == nil {
+			objLabels = map[string]string{}
+		}
+		for k, v := range transform.Spec.Labels {
+			objLabels[k] = v
+		}
+		obj.SetLabels(objLabels)
+	}
+
+	if len(transform.Spec.Annotations) > 0 {
+		annotations := obj.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		for k, v := range transform.Spec.Annotations {
+			annotations[k] = v
+		}
+		obj.SetAnnotations(annotations)
+	}
+	return nil
+}
+
+func fieldPath(path string) []string {
+	return strings.Split(path, ".")
+}
+
+const syncTransformsSyncTimeout = 30 * time.Second
+
+// waitForSync waits for the informer to be synced, up to the given timeout.
+func waitForSync(hasSynced cache.InformerSynced, timeout time.Duration) bool {
+	stopCh := make(chan struct{})
+	timer := time.AfterFunc(timeout, func() { close(stopCh) })
+	defer timer.Stop()
+	return cache.WaitForCacheSync(stopCh, hasSynced)
+}
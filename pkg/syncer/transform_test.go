@@ -0,0 +1,182 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+
+	clusterv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/cluster/v1alpha1"
+	clusterlisters "github.com/kcp-dev/kcp/pkg/client/listers/cluster/v1alpha1"
+)
+
+func TestTransform(t *testing.T) {
+	deployments := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, transform := range []*clusterv1alpha1.SyncTransform{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "a-strip"},
+			Spec: clusterv1alpha1.SyncTransformSpec{
+				Resources:    []string{"deployments.apps"},
+				RemoveFields: []string{"spec.template.spec.nodeSelector"},
+				MoveFields:   []clusterv1alpha1.FieldMove{{From: "spec.paused", To: "metadata.annotations.paused"}},
+				Namespaces:   map[string]string{"default": "tenant-default"},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "b-label"},
+			Spec: clusterv1alpha1.SyncTransformSpec{
+				Clusters: []string{"us-east1"},
+				Labels:   map[string]string{"region": "us-east1"},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "c-other-cluster"},
+			Spec: clusterv1alpha1.SyncTransformSpec{
+				Clusters: []string{"us-west1"},
+				Labels:   map[string]string{"region": "us-west1"},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "d-up"},
+			Spec: clusterv1alpha1.SyncTransformSpec{
+				Direction:    clusterv1alpha1.SyncDirectionUp,
+				RemoveFields: []string{"status.conditions"},
+			},
+		},
+	} {
+		if err := indexer.Add(transform); err != nil {
+			t.Fatal(err)
+		}
+	}
+	transformer := NewTransformer("us-east1", "admin", NamespaceStrategyIdentity, clusterlisters.NewSyncTransformLister(indexer))
+
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name":      "web",
+			"namespace": "default",
+		},
+		"spec": map[string]interface{}{
+			"paused": "true",
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"nodeSelector": map[string]interface{}{"disk": "ssd"},
+				},
+			},
+		},
+		"status": map[string]interface{}{
+			"replicas":   int64(1),
+			"conditions": []interface{}{},
+		},
+	}}
+
+	down := obj.DeepCopy()
+	if err := transformer.Transform(clusterv1alpha1.SyncDirectionDown, deployments, down); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := down.GetNamespace(), "tenant-default"; got != want {
+		t.Errorf("expected namespace %q, got %q", want, got)
+	}
+	if diff := cmp.Diff(map[string]string{"region": "us-east1"}, down.GetLabels()); diff != "" {
+		t.Errorf("unexpected labels (-want +got): %s", diff)
+	}
+	if diff := cmp.Diff(map[string]string{"paused": "true"}, down.GetAnnotations()); diff != "" {
+		t.Errorf("unexpected annotations (-want +got): %s", diff)
+	}
+	if _, found, _ := unstructured.NestedFieldNoCopy(down.Object, "spec", "template", "spec", "nodeSelector"); found {
+		t.Error("expected nodeSelector to be removed")
+	}
+	if _, found, _ := unstructured.NestedFieldNoCopy(down.Object, "status", "conditions"); !found {
+		t.Error("expected Up transforms not to apply Down")
+	}
+	if got, err := transformer.DownstreamNamespace(deployments, "default"); err != nil || got != "tenant-default" {
+		t.Errorf("expected downstream namespace %q, got %q (%v)", "tenant-default", got, err)
+	}
+
+	up := down.DeepCopy()
+	if err := transformer.Transform(clusterv1alpha1.SyncDirectionUp, deployments, up); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := up.GetNamespace(), "default"; got != want {
+		t.Errorf("expected namespace %q, got %q", want, got)
+	}
+	if _, found, _ := unstructured.NestedFieldNoCopy(up.Object, "status", "conditions"); found {
+		t.Error("expected conditions to be removed")
+	}
+
+	var nilTransformer *Transformer
+	unchanged := obj.DeepCopy()
+	if err := nilTransformer.Transform(clusterv1alpha1.SyncDirectionDown, deployments, unchanged); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(obj, unchanged); diff != "" {
+		t.Errorf("expected nil transformer not to change the object (-want +got): %s", diff)
+	}
+}
+
+func TestWorkspacePrefixNamespaceStrategy(t *testing.T) {
+	deployments := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	if err := indexer.Add(&clusterv1alpha1.SyncTransform{
+		ObjectMeta: metav1.ObjectMeta{Name: "explicit"},
+		Spec:       clusterv1alpha1.SyncTransformSpec{Namespaces: map[string]string{"shared": "shared"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	lister := clusterlisters.NewSyncTransformLister(indexer)
+	tenant1 := NewTransformer("us-east1", "tenant1", NamespaceStrategyWorkspacePrefix, lister)
+	tenant2 := NewTransformer("us-east1", "tenant2", NamespaceStrategyWorkspacePrefix, lister)
+
+	down1, err := tenant1.DownstreamNamespace(deployments, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	down2, err := tenant2.DownstreamNamespace(deployments, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if down1 == down2 || down1 == "default" {
+		t.Errorf("expected distinct prefixed namespaces, got %q and %q", down1, down2)
+	}
+	if up, ok := tenant1.UpstreamNamespace(deployments, down1); !ok || up != "default" {
+		t.Errorf("expected %q to map back to %q, got %q (%v)", down1, "default", up, ok)
+	}
+	if _, ok := tenant2.UpstreamNamespace(deployments, down1); ok {
+		t.Errorf("expected %q not to be synced from tenant2", down1)
+	}
+
+	if down, err := tenant1.DownstreamNamespace(deployments, "shared"); err != nil || down != "shared" {
+		t.Errorf("expected explicitly mapped namespace %q, got %q (%v)", "shared", down, err)
+	}
+	if up, ok := tenant2.UpstreamNamespace(deployments, "shared"); !ok || up != "shared" {
+		t.Errorf("expected explicitly mapped namespace %q, got %q (%v)", "shared", up, ok)
+	}
+
+	if _, err := tenant1.DownstreamNamespace(deployments, strings.Repeat("a", 60)); err == nil {
+		t.Error("expected an error for a namespace too long to be prefixed")
+	}
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+
+	clusterv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/cluster/v1alpha1"
+)
+
+// UpsyncedFromAnnotation is set on upstream objects imported from pre-existing downstream
+// objects, to the cluster they were imported from.
+const UpsyncedFromAnnotation = "kcp.dev/upsynced-from"
+
+// ParseUpsyncSelector parses the label selector of the downstream objects to upsync. An
+// empty selector disables upsyncing.
+func ParseUpsyncSelector(s string) (labels.Selector, error) {
+	if s == "" {
+		return nil, nil
+	}
+	selector, err := labels.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upsync selector %q: %w", s, err)
+	}
+	if selector.Empty() {
+		return nil, fmt.Errorf("invalid upsync selector %q: selecting every object is not allowed", s)
+	}
+	return selector, nil
+}
+
+// upsyncAll imports the downstream objects matching the selector which were not synced
+// from upstream into the upstream logical cluster, labeled for this cluster. The spec
+// and status syncers then adopt them like objects created upstream. Objects which
+// already exist upstream are left alone.
+func (c *Controller) upsyncAll(ctx context.Context, selector labels.Selector) {
+	for _, gvr := range c.gvrs {
+		if direction, err := c.filter.Direction(gvr); err != nil {
+			klog.Errorf("Getting the sync direction of %v to upsync: %v", gvr, err)
+			continue
+		} else if !direction.SyncsUp() {
+			continue
+		}
+		list, err := c.toClient.Resource(gvr).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			klog.Errorf("Listing %v to upsync: %v", gvr, err)
+			continue
+		}
+		for i := range list.Items {
+			obj := &list.Items[i]
+			if c.inSyncerNamespace(obj.GetNamespace()) {
+				continue
+			}
+			if _, synced := obj.GetAnnotations()[UpstreamVersionAnnotation]; synced {
+				continue
+			}
+			if _, ok := c.transformer.UpstreamNamespace(gvr, obj.GetNamespace()); !ok {
+				continue
+			}
+			if err := c.upsync(ctx, gvr, obj); err != nil {
+				klog.Errorf("Upsyncing %s %s/%s: %v", gvr.Resource, obj.GetNamespace(), obj.GetName(), err)
+			}
+		}
+	}
+}
+
+func (c *Controller) upsync(ctx context.Context, gvr schema.GroupVersionResource, downstream *unstructured.Unstructured) error {
+	unstrob := downstream.DeepCopy()
+	if err := c.transformer.Transform(clusterv1alpha1.SyncDirectionUp, gvr, unstrob); err != nil {
+		return err
+	}
+	namespace := unstrob.GetNamespace()
+	if allowed, err := c.filter.Allows(gvr, unstrob); err != nil || !allowed {
+		// Objects excluded by the sync policy would be removed from the cluster once upsynced.
+		return err
+	}
+
+	unstrob.SetUID("")
+	unstrob.SetResourceVersion("")
+	unstrob.SetGeneration(0)
+	unstrob.SetCreationTimestamp(metav1.Time{})
+	unstrob.SetManagedFields(nil)
+	unstrob.SetOwnerReferences(nil)
+	unstrob.SetClusterName("")
+	unstructured.RemoveNestedField(unstrob.Object, "status")
+
+	objLabels := unstrob.GetLabels()
+	if objLabels == nil {
+		objLabels = map[string]string{}
+	}
+	objLabels[clusterv1alpha1.ClusterLabel] = c.clusterID
+	unstrob.SetLabels(objLabels)
+	annotations := unstrob.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[UpsyncedFromAnnotation] = c.clusterID
+	unstrob.SetAnnotations(annotations)
+
+	if namespace != "" {
+		if _, err := c.fromClient.Resource(corev1.SchemeGroupVersion.WithResource("namespaces")).Create(ctx, &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Namespace",
+			"metadata":   map[string]interface{}{"name": namespace},
+		}}, metav1.CreateOptions{}); err != nil && !k8serrors.IsAlreadyExists(err) {
+			return err
+		}
+	}
+
+	created, err := c.fromClient.Resource(gvr).Namespace(namespace).Create(ctx, unstrob, metav1.CreateOptions{})
+	if err != nil {
+		if k8serrors.IsAlreadyExists(err) {
+			return nil
+		}
+		return err
+	}
+	klog.Infof("Upsynced %s %s/%s from cluster %s", gvr.Resource, namespace, created.GetName(), c.clusterID)
+	if c.recorder != nil {
+		c.recorder.Eventf(created, corev1.EventTypeNormal, "Upsynced", "Imported from cluster %s", c.clusterID)
+	}
+	return nil
+}
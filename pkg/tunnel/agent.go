@@ -0,0 +1,197 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tunnel
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http2"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+)
+
+// NewProxy returns a handler forwarding the requests sent through a tunnel to the API
+// server reached with the given configuration.
+func NewProxy(config *rest.Config) (http.Handler, error) {
+	target, err := url.Parse(config.Host)
+	if err != nil {
+		return nil, err
+	}
+	if target.Scheme == "" {
+		target.Scheme = "https"
+	}
+	transport, err := rest.TransportFor(config)
+	if err != nil {
+		return nil, err
+	}
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	director := proxy.Director
+	proxy.Director = func(req *http.Request) {
+		director(req)
+		req.Host = target.Host
+	}
+	proxy.Transport = transport
+	// Watches are streamed.
+	proxy.FlushInterval = -1
+	return proxy, nil
+}
+
+// Run keeps the tunnel of the cluster to the logical cluster of kcp connected until the
+// context is done, serving the requests sent through it with handler.
+func Run(ctx context.Context, kcpConfig *rest.Config, logicalCluster, clusterID string, handler http.Handler) {
+	backoff := wait.Backoff{Duration: time.Second, Factor: 2, Jitter: 0.1, Steps: 8, Cap: time.Minute}
+	for ctx.Err() == nil {
+		start := time.Now()
+		if err := Connect(ctx, kcpConfig, logicalCluster, clusterID, handler); err != nil {
+			klog.Errorf("Tunnel to kcp failed: %v", err)
+		}
+		if time.Since(start) > backoff.Cap {
+			// The tunnel was up for a while: reconnect quickly.
+			backoff = wait.Backoff{Duration: time.Second, Factor: 2, Jitter: 0.1, Steps: 8, Cap: time.Minute}
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff.Step()):
+		}
+	}
+}
+
+// Connect opens the tunnel of the cluster to the logical cluster of kcp, and serves the
+// requests sent through it with handler until the context is done or the tunnel is lost.
+func Connect(ctx context.Context, kcpConfig *rest.Config, logicalCluster, clusterID string, handler http.Handler) error {
+	host := kcpConfig.Host
+	if !strings.Contains(host, "://") {
+		host = "https://" + host
+	}
+	u, err := url.Parse(host)
+	if err != nil {
+		return err
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/clusters/" + logicalCluster + pathPrefix + clusterID
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", Protocol)
+
+	tlsConfig, err := rest.TLSConfigFor(kcpConfig)
+	if err != nil {
+		return err
+	}
+	upgrader := &upgrader{tlsConfig: tlsConfig}
+	// The wrappers authenticate the request.
+	roundTripper, err := rest.HTTPWrappersForConfig(kcpConfig, upgrader)
+	if err != nil {
+		return err
+	}
+	resp, err := roundTripper.RoundTrip(req)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if upgrader.conn != nil {
+			upgrader.conn.Close()
+		}
+		return fmt.Errorf("failed to open the tunnel: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	conn := upgrader.conn
+	klog.Infof("Tunnel to kcp connected, serving cluster %s of logical cluster %s", clusterID, logicalCluster)
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+	(&http2.Server{}).ServeConn(conn, &http2.ServeConnOpts{Handler: handler})
+	return ctx.Err()
+}
+
+// upgrader sends requests over HTTP/1.1 on a connection of its own, kept for the
+// tunnel.
+type upgrader struct {
+	tlsConfig *tls.Config
+	conn      net.Conn
+}
+
+func (u *upgrader) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		if req.URL.Scheme == "https" {
+			host = net.JoinHostPort(host, "443")
+		} else {
+			host = net.JoinHostPort(host, "80")
+		}
+	}
+
+	var conn net.Conn
+	var err error
+	if req.URL.Scheme == "https" {
+		config := &tls.Config{}
+		if u.tlsConfig != nil {
+			config = u.tlsConfig.Clone()
+		}
+		// Upgrades are not supported by HTTP/2.
+		config.NextProtos = []string{"http/1.1"}
+		if config.ServerName == "" {
+			config.ServerName = req.URL.Hostname()
+		}
+		conn, err = (&tls.Dialer{Config: config}).DialContext(req.Context(), "tcp", host)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(req.Context(), "tcp", host)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	// The response may be followed by data sent through the tunnel.
+	u.conn = &bufferedConn{Conn: conn, reader: reader}
+	return resp, nil
+}
+
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
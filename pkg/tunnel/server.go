@@ -0,0 +1,232 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tunnel lets kcp reach physical clusters which don't accept inbound connections.
+//
+// The syncer running in the physical cluster dials out to kcp and upgrades the connection
+// to the tunnel protocol. The roles are then reversed: kcp sends HTTP/2 requests through
+// the connection, which the syncer forwards to the API server of its cluster.
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http2"
+
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clusters"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Protocol is the protocol the tunnel connections are upgraded to.
+	Protocol = "kcp-tunnel"
+
+	// pathPrefix is the path the tunnels of the clusters of a logical cluster are opened at,
+	// followed by the name of the cluster.
+	pathPrefix = "/tunnels/"
+
+	pingInterval = 30 * time.Second
+	pingTimeout  = 10 * time.Second
+)
+
+// ErrNotConnected is returned when there is no tunnel to a cluster.
+var ErrNotConnected = errors.New("no tunnel is connected")
+
+// Server holds the tunnels opened by the syncers of the physical clusters.
+type Server struct {
+	lock     sync.RWMutex
+	conns    map[string]*http2.ClientConn
+	handlers []func(logicalCluster, clusterID string)
+}
+
+// NewServer returns a Server without any tunnel.
+func NewServer() *Server {
+	return &Server{conns: map[string]*http2.ClientConn{}}
+}
+
+// OnChange registers a handler called with the logical cluster and the cluster whenever a
+// tunnel connects or disconnects.
+func (s *Server) OnChange(handler func(logicalCluster, clusterID string)) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.handlers = append(s.handlers, handler)
+}
+
+func (s *Server) notify(key string) {
+	s.lock.RLock()
+	handlers := s.handlers
+	s.lock.RUnlock()
+	logicalCluster, clusterID := clusters.SplitClusterAwareKey(key)
+	for _, handler := range handlers {
+		handler(logicalCluster, clusterID)
+	}
+}
+
+// WithTunnels serves the tunnel connections of the server, and hands the other requests to
+// handler. It must be wrapped by authentication and authorization, and by the filter
+// setting the logical cluster of the request.
+func WithTunnels(handler http.Handler, server *Server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !strings.HasPrefix(req.URL.Path, pathPrefix) {
+			handler.ServeHTTP(w, req)
+			return
+		}
+		server.ServeHTTP(w, req)
+	})
+}
+
+// ServeHTTP accepts a tunnel connection opened at /tunnels/<cluster> in a logical cluster.
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	clusterID := strings.TrimPrefix(req.URL.Path, pathPrefix)
+	if clusterID == "" || strings.Contains(clusterID, "/") {
+		http.Error(w, "Unknown cluster", http.StatusNotFound)
+		return
+	}
+	cluster := genericapirequest.ClusterFrom(req.Context())
+	if cluster == nil || cluster.Name == "" {
+		http.Error(w, "Unknown logical cluster", http.StatusNotFound)
+		return
+	}
+	if !strings.EqualFold(req.Header.Get("Upgrade"), Protocol) {
+		http.Error(w, fmt.Sprintf("Expected an upgrade to %s", Protocol), http.StatusBadRequest)
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "Connection cannot be upgraded", http.StatusInternalServerError)
+		return
+	}
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		klog.Errorf("Failed to hijack the tunnel connection of cluster %s: %v", clusterID, err)
+		return
+	}
+	if _, err := conn.Write([]byte("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: " + Protocol + "\r\n\r\n")); err != nil {
+		klog.Errorf("Failed to upgrade the tunnel connection of cluster %s: %v", clusterID, err)
+		conn.Close()
+		return
+	}
+	// The request is done, the tunnel outlives it.
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		klog.Errorf("Failed to reset the deadline of the tunnel connection of cluster %s: %v", clusterID, err)
+	}
+	s.add(clusters.ToClusterAwareKey(cluster.Name, clusterID), conn)
+}
+
+func (s *Server) add(key string, conn net.Conn) {
+	cc, err := (&http2.Transport{}).NewClientConn(conn)
+	if err != nil {
+		klog.Errorf("Failed to open the tunnel of cluster %s: %v", key, err)
+		conn.Close()
+		return
+	}
+
+	s.lock.Lock()
+	previous := s.conns[key]
+	s.conns[key] = cc
+	s.lock.Unlock()
+	if previous != nil {
+		previous.Close()
+	}
+	klog.Infof("Tunnel of cluster %s connected from %s", key, conn.RemoteAddr())
+	s.notify(key)
+
+	go s.keepAlive(key, cc)
+}
+
+// keepAlive pings the tunnel until it fails, then removes it.
+func (s *Server) keepAlive(key string, cc *http2.ClientConn) {
+	defer func() {
+		cc.Close()
+		s.lock.Lock()
+		current := s.conns[key] == cc
+		if current {
+			delete(s.conns, key)
+		}
+		s.lock.Unlock()
+		if current {
+			klog.Infof("Tunnel of cluster %s disconnected", key)
+			s.notify(key)
+		}
+	}()
+
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if !cc.CanTakeNewRequest() {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+		err := cc.Ping(ctx)
+		cancel()
+		if err != nil {
+			klog.Errorf("Tunnel of cluster %s failed: %v", key, err)
+			return
+		}
+	}
+}
+
+// Connected returns whether the tunnel of the cluster of the logical cluster is connected.
+func (s *Server) Connected(logicalCluster, clusterID string) bool {
+	_, err := s.conn(clusters.ToClusterAwareKey(logicalCluster, clusterID))
+	return err == nil
+}
+
+func (s *Server) conn(key string) (*http2.ClientConn, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	cc, ok := s.conns[key]
+	if !ok || !cc.CanTakeNewRequest() {
+		return nil, ErrNotConnected
+	}
+	return cc, nil
+}
+
+// Config returns a client configuration reaching the API server of the cluster of the
+// logical cluster through its tunnel, as the syncer of the cluster. Requests made while
+// the tunnel is disconnected fail with ErrNotConnected.
+func (s *Server) Config(logicalCluster, clusterID string) (*rest.Config, error) {
+	key := clusters.ToClusterAwareKey(logicalCluster, clusterID)
+	if _, err := s.conn(key); err != nil {
+		return nil, err
+	}
+	return &rest.Config{
+		// The host is only used to build the URLs of the requests.
+		Host: "http://" + clusterID,
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			cc, err := s.conn(key)
+			if err != nil {
+				return nil, err
+			}
+			return cc.RoundTrip(req)
+		}),
+	}, nil
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
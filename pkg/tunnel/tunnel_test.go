@@ -0,0 +1,105 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tunnel
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/rest"
+)
+
+func TestTunnel(t *testing.T) {
+	server := NewServer()
+	kcp := httptest.NewServer(withLogicalCluster(WithTunnels(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "not a tunnel", http.StatusTeapot)
+	}), server)))
+	defer kcp.Close()
+
+	if _, err := server.Config("admin", "east"); err != ErrNotConnected {
+		t.Fatalf("expected %v before the tunnel is connected, got %v", ErrNotConnected, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cluster := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte("served " + req.URL.Path))
+	})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Run(ctx, &rest.Config{Host: kcp.URL}, "admin", "east", cluster)
+	}()
+
+	if err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		return server.Connected("admin", "east"), nil
+	}); err != nil {
+		t.Fatal("expected the tunnel to connect")
+	}
+	if server.Connected("other", "east") {
+		t.Error("expected the tunnel to be scoped to its logical cluster")
+	}
+
+	config, err := server.Config("admin", "east")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport, err := rest.TransportFor(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: transport}
+	resp, err := client.Get(config.Host + "/api/v1/namespaces")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(body), "served /api/v1/namespaces"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	cancel()
+	<-done
+	if err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		_, err := client.Get(config.Host + "/api")
+		return err != nil && strings.Contains(err.Error(), ErrNotConnected.Error()), nil
+	}); err != nil {
+		t.Error("expected requests to fail once the tunnel is closed")
+	}
+}
+
+// withLogicalCluster mimics the logical cluster filter of kcp.
+func withLogicalCluster(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		path := strings.TrimPrefix(req.URL.Path, "/clusters/")
+		i := strings.Index(path, "/")
+		ctx := genericapirequest.WithCluster(req.Context(), genericapirequest.Cluster{Name: path[:i]})
+		req.URL.Path = path[i:]
+		handler.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
@@ -0,0 +1,246 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package usage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	"github.com/kcp-dev/kcp/pkg/apis/tenancy/helpers/logicalcluster"
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	tenancyinformer "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/tenancy/v1alpha1"
+	tenancylister "github.com/kcp-dev/kcp/pkg/client/listers/tenancy/v1alpha1"
+)
+
+const controllerName = "usage"
+
+// listPageSize is the number of objects listed at a time when counting them, not to load
+// all objects of a resource into memory at once.
+const listPageSize = 500
+
+// DiscoverResourcesFunc returns the preferred resources served in the given logical cluster.
+type DiscoverResourcesFunc func(clusterName string) ([]*metav1.APIResourceList, error)
+
+// NewController returns a controller which periodically counts the objects stored in the
+// logical cluster of every Workspace and records them in the tracker.
+func NewController(
+	dynamicClient dynamic.ClusterInterface,
+	discoverResources DiscoverResourcesFunc,
+	workspaceInformer tenancyinformer.WorkspaceInformer,
+	tracker *Tracker,
+	interval time.Duration,
+) *Controller {
+	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+
+	c := &Controller{
+		queue:             queue,
+		dynamicClient:     dynamicClient,
+		discoverResources: discoverResources,
+		workspaceLister:   workspaceInformer.Lister(),
+		tracker:           tracker,
+		interval:          interval,
+		syncChecks: []cache.InformerSynced{
+			workspaceInformer.Informer().HasSynced,
+		},
+	}
+
+	workspaceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) { c.enqueue(obj) },
+		UpdateFunc: func(oldObj, obj interface{}) {
+			// the objects are counted again once the logical cluster of the workspace is known
+			oldWorkspace, ok := oldObj.(*tenancyv1alpha1.Workspace)
+			workspace, ok2 := obj.(*tenancyv1alpha1.Workspace)
+			if ok && ok2 && logicalcluster.Of(oldWorkspace) != logicalcluster.Of(workspace) {
+				c.enqueue(obj)
+			}
+		},
+		DeleteFunc: func(obj interface{}) { c.forget(obj) },
+	})
+
+	return c
+}
+
+// Controller counts the objects of Workspaces.
+type Controller struct {
+	queue workqueue.RateLimitingInterface
+
+	dynamicClient     dynamic.ClusterInterface
+	discoverResources DiscoverResourcesFunc
+	workspaceLister   tenancylister.WorkspaceLister
+
+	tracker  *Tracker
+	interval time.Duration
+
+	syncChecks []cache.InformerSynced
+}
+
+func (c *Controller) enqueue(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	klog.V(4).Infof("queueing workspace %q", key)
+	c.queue.Add(key)
+}
+
+// forget drops the usage of the logical cluster of a deleted workspace, unless the
+// workspace was renamed.
+func (c *Controller) forget(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	workspace, ok := obj.(*tenancyv1alpha1.Workspace)
+	if !ok || logicalcluster.Renamed(workspace) {
+		return
+	}
+	c.tracker.Forget(logicalcluster.Of(workspace))
+}
+
+func (c *Controller) Start(ctx context.Context, numThreads int) {
+	defer runtime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	klog.Info("Starting usage controller")
+	defer klog.Info("Shutting down usage controller")
+
+	if !cache.WaitForNamedCacheSync(controllerName, ctx.Done(), c.syncChecks...) {
+		klog.Warning("Failed to wait for caches to sync")
+		return
+	}
+
+	for i := 0; i < numThreads; i++ {
+		go wait.Until(func() { c.startWorker(ctx) }, time.Second, ctx.Done())
+	}
+
+	<-ctx.Done()
+}
+
+func (c *Controller) startWorker(ctx context.Context) {
+	for c.processNextWorkItem(ctx) {
+	}
+}
+
+func (c *Controller) processNextWorkItem(ctx context.Context) bool {
+	// Wait until there is a new item in the working queue
+	k, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	key := k.(string)
+
+	// No matter what, tell the queue we're done with this key, to unblock
+	// other workers.
+	defer c.queue.Done(key)
+
+	if err := c.process(ctx, key); err != nil {
+		runtime.HandleError(fmt.Errorf("%q controller failed to sync %q, err: %w", controllerName, key, err))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	c.queue.Forget(key)
+	return true
+}
+
+func (c *Controller) process(ctx context.Context, key string) error {
+	workspace, err := c.workspaceLister.Get(key)
+	if errors.IsNotFound(err) {
+		return nil // the usage is dropped when the workspace is deleted
+	} else if err != nil {
+		return err
+	}
+
+	resources, err := c.countResources(ctx, logicalcluster.Of(workspace))
+	if err != nil {
+		return err
+	}
+	c.tracker.SetResources(workspace, resources)
+
+	c.queue.AddAfter(key, c.interval)
+	return nil
+}
+
+// countResources lists every resource of the given logical cluster, a page at a time, and
+// returns the number and serialized size of its objects.
+func (c *Controller) countResources(ctx context.Context, clusterName string) ([]tenancyv1alpha1.ResourceUsage, error) {
+	resourceLists, err := c.discoverResources(clusterName)
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, err
+	}
+
+	client := c.dynamicClient.Cluster(clusterName)
+	var resources []tenancyv1alpha1.ResourceUsage
+	for _, resourceList := range resourceLists {
+		gv, err := schema.ParseGroupVersion(resourceList.GroupVersion)
+		if err != nil {
+			return nil, err
+		}
+		for _, resource := range resourceList.APIResources {
+			if strings.Contains(resource.Name, "/") || !sets.NewString(resource.Verbs...).Has("list") {
+				continue
+			}
+			usage := tenancyv1alpha1.ResourceUsage{
+				Group:    gv.Group,
+				Version:  gv.Version,
+				Resource: resource.Name,
+			}
+			opts := metav1.ListOptions{Limit: listPageSize}
+			for {
+				list, err := client.Resource(gv.WithResource(resource.Name)).List(ctx, opts)
+				if err != nil {
+					return nil, fmt.Errorf("failed to list %s in logical cluster %q: %w", resource.Name, clusterName, err)
+				}
+				usage.Count += int64(len(list.Items))
+				for i := range list.Items {
+					data, err := json.Marshal(list.Items[i].Object)
+					if err != nil {
+						return nil, err
+					}
+					usage.StorageBytes += int64(len(data))
+				}
+				if list.GetContinue() == "" {
+					break
+				}
+				opts.Continue = list.GetContinue()
+			}
+			resources = append(resources, usage)
+		}
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		if resources[i].Group != resources[j].Group {
+			return resources[i].Group < resources[j].Group
+		}
+		return resources[i].Resource < resources[j].Resource
+	})
+	return resources, nil
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package usage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+)
+
+// pagedResource serves the lists of its objects a page at a time, recording the options of
+// every list.
+type pagedResource struct {
+	dynamic.NamespaceableResourceInterface
+	objects []unstructured.Unstructured
+	lists   []metav1.ListOptions
+}
+
+func (r *pagedResource) List(_ context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	r.lists = append(r.lists, opts)
+	start := 0
+	if opts.Continue != "" {
+		var err error
+		if start, err = strconv.Atoi(opts.Continue); err != nil {
+			return nil, err
+		}
+	}
+	end := len(r.objects)
+	if opts.Limit > 0 && start+int(opts.Limit) < end {
+		end = start + int(opts.Limit)
+	}
+	list := &unstructured.UnstructuredList{Items: r.objects[start:end]}
+	if end < len(r.objects) {
+		list.SetContinue(strconv.Itoa(end))
+	}
+	return list, nil
+}
+
+type pagedClient struct {
+	dynamic.Interface
+	resources map[schema.GroupVersionResource]*pagedResource
+}
+
+func (c pagedClient) Resource(resource schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	return c.resources[resource]
+}
+
+type dynamicClients struct {
+	pagedClient
+	clusters []string
+}
+
+func (c *dynamicClients) Cluster(name string) dynamic.Interface {
+	c.clusters = append(c.clusters, name)
+	return c.pagedClient
+}
+
+func TestCountResources(t *testing.T) {
+	configMaps := &pagedResource{}
+	var storageBytes int64
+	for i := 0; i < 2*listPageSize+1; i++ {
+		obj := unstructured.Unstructured{}
+		obj.SetAPIVersion("v1")
+		obj.SetKind("ConfigMap")
+		obj.SetNamespace("default")
+		obj.SetName(fmt.Sprintf("cm-%d", i))
+		data, err := json.Marshal(obj.Object)
+		if err != nil {
+			t.Fatal(err)
+		}
+		storageBytes += int64(len(data))
+		configMaps.objects = append(configMaps.objects, obj)
+	}
+	secrets := &pagedResource{}
+	client := &dynamicClients{pagedClient: pagedClient{resources: map[schema.GroupVersionResource]*pagedResource{
+		{Version: "v1", Resource: "configmaps"}: configMaps,
+		{Version: "v1", Resource: "secrets"}:    secrets,
+	}}}
+	c := &Controller{
+		dynamicClient: client,
+		discoverResources: func(string) ([]*metav1.APIResourceList, error) {
+			return []*metav1.APIResourceList{{
+				GroupVersion: "v1",
+				APIResources: []metav1.APIResource{
+					{Name: "configmaps", Verbs: []string{"get", "list"}},
+					{Name: "secrets", Verbs: []string{"get", "list"}},
+					{Name: "pods/log", Verbs: []string{"get"}},
+					{Name: "bindings", Verbs: []string{"create"}},
+				},
+			}}, nil
+		},
+	}
+
+	resources, err := c.countResources(context.Background(), "k7c2q9x4")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []tenancyv1alpha1.ResourceUsage{
+		{Version: "v1", Resource: "configmaps", Count: 2*listPageSize + 1, StorageBytes: storageBytes},
+		{Version: "v1", Resource: "secrets"},
+	}
+	if diff := cmp.Diff(expected, resources); diff != "" {
+		t.Errorf("unexpected resources (-want +got):\n%s", diff)
+	}
+	expectedLists := []metav1.ListOptions{
+		{Limit: listPageSize},
+		{Limit: listPageSize, Continue: strconv.Itoa(listPageSize)},
+		{Limit: listPageSize, Continue: strconv.Itoa(2 * listPageSize)},
+	}
+	if diff := cmp.Diff(expectedLists, configMaps.lists); diff != "" {
+		t.Errorf("unexpected lists of configmaps (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"k7c2q9x4"}, client.clusters); diff != "" {
+		t.Errorf("unexpected logical clusters (-want +got):\n%s", diff)
+	}
+}
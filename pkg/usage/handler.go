@@ -0,0 +1,53 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package usage
+
+import (
+	"net/http"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apiserver/pkg/endpoints/handlers/negotiation"
+	"k8s.io/apiserver/pkg/endpoints/handlers/responsewriters"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/client/clientset/versioned/scheme"
+)
+
+// WithUsage serves the workspaces/usage subresource from the tracker, and passes all other
+// requests to handler. It must be wrapped by the authentication and authorization filters.
+func WithUsage(handler http.Handler, tracker *Tracker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		info, ok := genericapirequest.RequestInfoFrom(req.Context())
+		if !ok || !info.IsResourceRequest || info.APIGroup != tenancyv1alpha1.SchemeGroupVersion.Group || info.Resource != "workspaces" || info.Subresource != "usage" || info.Verb != "get" {
+			handler.ServeHTTP(w, req)
+			return
+		}
+
+		var usage *tenancyv1alpha1.WorkspaceUsage
+		found := false
+		if cluster := genericapirequest.ClusterFrom(req.Context()); cluster != nil && !cluster.Wildcard {
+			usage, found = tracker.Usage(cluster.Name, info.Name)
+		}
+		if !found {
+			err := apierrors.NewNotFound(tenancyv1alpha1.Resource("workspaces/usage"), info.Name)
+			responsewriters.ErrorNegotiated(err, scheme.Codecs, tenancyv1alpha1.SchemeGroupVersion, w, req)
+			return
+		}
+		responsewriters.WriteObjectNegotiated(scheme.Codecs, negotiation.DefaultEndpointRestrictions, tenancyv1alpha1.SchemeGroupVersion, w, req, http.StatusOK, usage)
+	})
+}
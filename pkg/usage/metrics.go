@@ -0,0 +1,79 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package usage
+
+import (
+	"k8s.io/component-base/metrics"
+)
+
+const metricsSubsystem = "workspace"
+
+var (
+	requestsDesc = metrics.NewDesc(
+		metrics.BuildFQName("", metricsSubsystem, "requests_total"),
+		"Number of API requests served for a workspace, excluding those of privileged system users.",
+		[]string{"logical_cluster", "workspace"}, nil, metrics.ALPHA, "",
+	)
+	objectsDesc = metrics.NewDesc(
+		metrics.BuildFQName("", metricsSubsystem, "objects"),
+		"Number of objects of a resource stored in a workspace when they were last counted.",
+		[]string{"logical_cluster", "workspace", "group", "resource"}, nil, metrics.ALPHA, "",
+	)
+	storageBytesDesc = metrics.NewDesc(
+		metrics.BuildFQName("", metricsSubsystem, "storage_bytes"),
+		"Serialized size of the objects of a resource stored in a workspace when they were last counted.",
+		[]string{"logical_cluster", "workspace", "group", "resource"}, nil, metrics.ALPHA, "",
+	)
+)
+
+// collector exports the usage of the workspaces of a Tracker, labelled with the logical
+// cluster of the Workspace and its name so that the usage can be charged back.
+type collector struct {
+	metrics.BaseStableCollector
+
+	tracker *Tracker
+}
+
+// NewCollector returns a collector of the usage of the workspaces of the tracker, to be
+// registered with legacyregistry.CustomMustRegister. Only the workspaces whose objects were
+// counted are exported.
+func NewCollector(tracker *Tracker) metrics.StableCollector {
+	return &collector{tracker: tracker}
+}
+
+func (c *collector) DescribeWithStability(ch chan<- *metrics.Desc) {
+	ch <- requestsDesc
+	ch <- objectsDesc
+	ch <- storageBytesDesc
+}
+
+func (c *collector) CollectWithStability(ch chan<- metrics.Metric) {
+	c.tracker.lock.RLock()
+	defer c.tracker.lock.RUnlock()
+
+	for name, u := range c.tracker.clusters {
+		if u.workspaceCluster == "" {
+			continue
+		}
+		workspace := u.workspaceName(name)
+		ch <- metrics.NewLazyConstMetric(requestsDesc, metrics.CounterValue, float64(u.requests), u.workspaceCluster, workspace)
+		for _, r := range u.resources {
+			ch <- metrics.NewLazyConstMetric(objectsDesc, metrics.GaugeValue, float64(r.Count), u.workspaceCluster, workspace, r.Group, r.Resource)
+			ch <- metrics.NewLazyConstMetric(storageBytesDesc, metrics.GaugeValue, float64(r.StorageBytes), u.workspaceCluster, workspace, r.Group, r.Resource)
+		}
+	}
+}
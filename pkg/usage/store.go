@@ -0,0 +1,152 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package usage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+)
+
+// storedUsage is the usage of a logical cluster kept across restarts.
+type storedUsage struct {
+	WorkspaceCluster string                          `json:"workspaceCluster,omitempty"`
+	Workspace        string                          `json:"workspace,omitempty"`
+	Requests         int64                           `json:"requests"`
+	LastActivity     time.Time                       `json:"lastActivity"`
+	Resources        []tenancyv1alpha1.ResourceUsage `json:"resources,omitempty"`
+	ObservedTime     time.Time                       `json:"observedTime"`
+}
+
+// lastSeen returns when the logical cluster was last active or counted.
+func (u *clusterUsage) lastSeen() time.Time {
+	if u.lastActivity.After(u.observedTime) {
+		return u.lastActivity
+	}
+	return u.observedTime
+}
+
+// Save writes the usage of all logical clusters to the given file, replacing it atomically.
+func (t *Tracker) Save(path string) error {
+	t.lock.RLock()
+	stored := make(map[string]storedUsage, len(t.clusters))
+	for name, u := range t.clusters {
+		stored[name] = storedUsage{
+			WorkspaceCluster: u.workspaceCluster,
+			Workspace:        u.workspace,
+			Requests:         u.requests,
+			LastActivity:     u.lastActivity,
+			Resources:        u.resources,
+			ObservedTime:     u.observedTime,
+		}
+	}
+	t.lock.RUnlock()
+
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Load adds the usage saved in the given file to the tracker, except the usage of the
+// logical clusters neither active nor counted within the retention period. A missing file
+// is not an error.
+func (t *Tracker) Load(path string, retention time.Duration) error {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	var stored map[string]storedUsage
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return fmt.Errorf("failed to decode the usage in %s: %w", path, err)
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for name, s := range stored {
+		u := t.cluster(name)
+		u.requests += s.Requests
+		if s.LastActivity.After(u.lastActivity) {
+			u.lastActivity = s.LastActivity
+		}
+		if u.observedTime.IsZero() {
+			u.workspaceCluster = s.WorkspaceCluster
+			u.workspace = s.Workspace
+			u.resources = s.Resources
+			u.observedTime = s.ObservedTime
+		}
+	}
+	t.prune(retention)
+	return nil
+}
+
+// prune drops the usage of the logical clusters neither active nor counted within the
+// retention period. It must be called with the lock held.
+func (t *Tracker) prune(retention time.Duration) {
+	cutoff := t.now().Add(-retention)
+	for name, u := range t.clusters {
+		if u.lastSeen().Before(cutoff) {
+			delete(t.clusters, name)
+		}
+	}
+}
+
+// Persist loads the usage saved in the given file, then saves the usage to it at every
+// interval and when the context is done, pruning the usage older than the retention period
+// beforehand, so that the request counters survive restarts.
+func (t *Tracker) Persist(ctx context.Context, path string, interval, retention time.Duration) error {
+	if err := t.Load(path, retention); err != nil {
+		return err
+	}
+	go func() {
+		save := func() {
+			t.lock.Lock()
+			t.prune(retention)
+			t.lock.Unlock()
+			if err := t.Save(path); err != nil {
+				klog.Errorf("Failed to save the usage of workspaces to %s: %v", path, err)
+			}
+		}
+		wait.Until(save, interval, ctx.Done())
+		save()
+	}()
+	return nil
+}
@@ -0,0 +1,189 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package usage keeps track of the objects stored in every logical cluster and of the API
+// requests served for it, and serves them through the usage subresource of Workspaces.
+package usage
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/authentication/user"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+
+	"github.com/kcp-dev/kcp/pkg/apis/tenancy/helpers/logicalcluster"
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+)
+
+// recentWindow is the period over which recent requests are counted, in buckets of a minute.
+const recentWindow = 5
+
+type clusterUsage struct {
+	requests     int64
+	buckets      [recentWindow]int64
+	bucketStart  [recentWindow]time.Time
+	lastActivity time.Time
+
+	// workspaceCluster is the logical cluster of the Workspace this logical cluster belongs to
+	workspaceCluster string
+	// workspace is the name of the Workspace, which is also the name of the logical cluster
+	// when empty
+	workspace    string
+	resources    []tenancyv1alpha1.ResourceUsage
+	observedTime time.Time
+}
+
+// Tracker holds the usage of the logical clusters served by this shard.
+type Tracker struct {
+	lock     sync.RWMutex
+	clusters map[string]*clusterUsage
+
+	// now is overridden in tests
+	now func() time.Time
+}
+
+// NewTracker returns an empty Tracker.
+func NewTracker() *Tracker {
+	return &Tracker{
+		clusters: map[string]*clusterUsage{},
+		now:      time.Now,
+	}
+}
+
+// workspaceName returns the name of the Workspace of the logical cluster with the given name.
+func (u *clusterUsage) workspaceName(clusterName string) string {
+	if u.workspace != "" {
+		return u.workspace
+	}
+	return clusterName
+}
+
+func (t *Tracker) cluster(name string) *clusterUsage {
+	u, ok := t.clusters[name]
+	if !ok {
+		u = &clusterUsage{}
+		t.clusters[name] = u
+	}
+	return u
+}
+
+// RecordRequest counts a request served for the given logical cluster.
+func (t *Tracker) RecordRequest(clusterName string) {
+	now := t.now()
+	minute := now.Truncate(time.Minute)
+	i := int(minute.Unix()/60) % recentWindow
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	u := t.cluster(clusterName)
+	u.requests++
+	if !u.bucketStart[i].Equal(minute) {
+		u.bucketStart[i] = minute
+		u.buckets[i] = 0
+	}
+	u.buckets[i]++
+	u.lastActivity = now
+}
+
+// SetResources replaces the object counts of the logical cluster of the given workspace.
+func (t *Tracker) SetResources(workspace *tenancyv1alpha1.Workspace, resources []tenancyv1alpha1.ResourceUsage) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	u := t.cluster(logicalcluster.Of(workspace))
+	u.workspaceCluster = workspace.ClusterName
+	u.workspace = workspace.Name
+	u.resources = resources
+	u.observedTime = t.now()
+}
+
+// Forget drops the usage of the given logical cluster.
+func (t *Tracker) Forget(clusterName string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	delete(t.clusters, clusterName)
+}
+
+// LastActivity returns when the last request for the given logical cluster was served,
+// or the zero time if none was.
+func (t *Tracker) LastActivity(clusterName string) time.Time {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	if u, ok := t.clusters[clusterName]; ok {
+		return u.lastActivity
+	}
+	return time.Time{}
+}
+
+// Usage returns the usage of the workspace with the given name in the given logical
+// cluster, and false if its objects have not been counted yet.
+func (t *Tracker) Usage(workspaceCluster, name string) (*tenancyv1alpha1.WorkspaceUsage, bool) {
+	now := t.now()
+
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	var u *clusterUsage
+	for clusterName, candidate := range t.clusters {
+		if candidate.workspaceCluster == workspaceCluster && candidate.workspaceName(clusterName) == name {
+			u = candidate
+			break
+		}
+	}
+	if u == nil || u.observedTime.IsZero() {
+		return nil, false
+	}
+
+	usage := &tenancyv1alpha1.WorkspaceUsage{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: tenancyv1alpha1.SchemeGroupVersion.String(),
+			Kind:       "WorkspaceUsage",
+		},
+		ObjectMeta:   metav1.ObjectMeta{Name: name},
+		Resources:    append([]tenancyv1alpha1.ResourceUsage(nil), u.resources...),
+		ObservedTime: &metav1.Time{Time: u.observedTime},
+		Requests:     u.requests,
+	}
+	for _, r := range u.resources {
+		usage.StorageBytes += r.StorageBytes
+	}
+	cutoff := now.Truncate(time.Minute).Add(-(recentWindow - 1) * time.Minute)
+	for i := range u.buckets {
+		if !u.bucketStart[i].Before(cutoff) {
+			usage.RecentRequests += u.buckets[i]
+		}
+	}
+	if !u.lastActivity.IsZero() {
+		usage.LastActivityTime = &metav1.Time{Time: u.lastActivity}
+	}
+	return usage, true
+}
+
+// WithRequestTracking counts the requests passed to handler against the logical cluster
+// they target. Requests of privileged system users, like the ones of kcp controllers, are
+// not counted. It must be wrapped by the authentication filter.
+func (t *Tracker) WithRequestTracking(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		cluster := genericapirequest.ClusterFrom(req.Context())
+		u, ok := genericapirequest.UserFrom(req.Context())
+		if cluster != nil && cluster.Name != "" && !cluster.Wildcard && !(ok && sets.NewString(u.GetGroups()...).Has(user.SystemPrivilegedGroup)) {
+			t.RecordRequest(cluster.Name)
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package usage
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/component-base/metrics/testutil"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+)
+
+func TestTracker(t *testing.T) {
+	now := time.Date(2021, 12, 1, 10, 0, 0, 0, time.UTC)
+	tracker := NewTracker()
+	tracker.now = func() time.Time { return now }
+
+	workspace := &tenancyv1alpha1.Workspace{ObjectMeta: metav1.ObjectMeta{Name: "foo", ClusterName: "org"}}
+	tracker.RecordRequest("foo")
+	if _, found := tracker.Usage("org", "foo"); found {
+		t.Fatal("expected no usage before the objects were counted")
+	}
+	tracker.SetResources(workspace, []tenancyv1alpha1.ResourceUsage{
+		{Resource: "configmaps", Version: "v1", Count: 2, StorageBytes: 100},
+		{Resource: "secrets", Version: "v1", Count: 1, StorageBytes: 50},
+	})
+
+	now = now.Add(3 * time.Minute)
+	tracker.RecordRequest("foo")
+	tracker.RecordRequest("foo")
+
+	if _, found := tracker.Usage("other", "foo"); found {
+		t.Error("expected no usage for a workspace of another logical cluster")
+	}
+	usage, found := tracker.Usage("org", "foo")
+	if !found {
+		t.Fatal("expected usage")
+	}
+	if usage.StorageBytes != 150 {
+		t.Errorf("expected 150 storage bytes, got %d", usage.StorageBytes)
+	}
+	if usage.Requests != 3 || usage.RecentRequests != 3 {
+		t.Errorf("expected 3 requests and 3 recent requests, got %d and %d", usage.Requests, usage.RecentRequests)
+	}
+	if !usage.LastActivityTime.Time.Equal(now) {
+		t.Errorf("expected last activity at %v, got %v", now, usage.LastActivityTime)
+	}
+
+	now = now.Add(3 * time.Minute)
+	if usage, _ := tracker.Usage("org", "foo"); usage.RecentRequests != 2 {
+		t.Errorf("expected 2 recent requests, got %d", usage.RecentRequests)
+	}
+}
+
+func TestPersistence(t *testing.T) {
+	now := time.Date(2021, 12, 1, 10, 0, 0, 0, time.UTC)
+	tracker := NewTracker()
+	tracker.now = func() time.Time { return now }
+	tracker.SetResources(&tenancyv1alpha1.Workspace{ObjectMeta: metav1.ObjectMeta{Name: "foo", ClusterName: "org"}}, []tenancyv1alpha1.ResourceUsage{
+		{Resource: "configmaps", Version: "v1", Count: 2, StorageBytes: 100},
+	})
+	tracker.RecordRequest("foo")
+	tracker.RecordRequest("foo")
+	now = now.Add(48 * time.Hour)
+	tracker.RecordRequest("bar")
+
+	path := filepath.Join(t.TempDir(), "usage.json")
+	if err := tracker.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := NewTracker()
+	restarted.now = func() time.Time { return now }
+	restarted.RecordRequest("bar")
+	if err := restarted.Load(path, 24*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, found := restarted.clusters["foo"]; found {
+		t.Error("expected the usage older than the retention period to be pruned")
+	}
+	if got := restarted.clusters["bar"].requests; got != 2 {
+		t.Errorf("expected the saved requests to be added to the new ones, got %d requests", got)
+	}
+
+	if err := restarted.Load(filepath.Join(t.TempDir(), "missing.json"), 24*time.Hour); err != nil {
+		t.Errorf("expected a missing file to be ignored, got %v", err)
+	}
+}
+
+func TestCollector(t *testing.T) {
+	tracker := NewTracker()
+	tracker.SetResources(&tenancyv1alpha1.Workspace{ObjectMeta: metav1.ObjectMeta{Name: "foo", ClusterName: "org"}}, []tenancyv1alpha1.ResourceUsage{
+		{Group: "apps", Resource: "deployments", Version: "v1", Count: 1, StorageBytes: 300},
+		{Resource: "configmaps", Version: "v1", Count: 2, StorageBytes: 100},
+	})
+	tracker.RecordRequest("foo")
+	// not a workspace
+	tracker.RecordRequest("admin")
+
+	expected := `
+# HELP workspace_objects [ALPHA] Number of objects of a resource stored in a workspace when they were last counted.
+# TYPE workspace_objects gauge
+workspace_objects{group="",logical_cluster="org",resource="configmaps",workspace="foo"} 2
+workspace_objects{group="apps",logical_cluster="org",resource="deployments",workspace="foo"} 1
+# HELP workspace_requests_total [ALPHA] Number of API requests served for a workspace, excluding those of privileged system users.
+# TYPE workspace_requests_total counter
+workspace_requests_total{logical_cluster="org",workspace="foo"} 1
+# HELP workspace_storage_bytes [ALPHA] Serialized size of the objects of a resource stored in a workspace when they were last counted.
+# TYPE workspace_storage_bytes gauge
+workspace_storage_bytes{group="",logical_cluster="org",resource="configmaps",workspace="foo"} 100
+workspace_storage_bytes{group="apps",logical_cluster="org",resource="deployments",workspace="foo"} 300
+`
+	if err := testutil.CustomCollectAndCompare(NewCollector(tracker), strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
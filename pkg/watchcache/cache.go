@@ -0,0 +1,137 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package watchcache serves the LISTs of all logical clusters ("/clusters/*") and the
+// LISTs of single logical clusters from memory, instead of scanning the storage for each
+// of them. The objects are watched across all logical clusters and indexed by
+// ClusterName, so that the objects of a logical cluster are found without going through
+// those of the others.
+package watchcache
+
+import (
+	"context"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	"github.com/kcp-dev/kcp/pkg/client/dynamicinformer"
+)
+
+// UserAgent is the user agent of the requests filling the cache. They are never served
+// from the cache itself.
+const UserAgent = "kcp-watch-cache"
+
+// Cache watches the resources listed across all logical clusters, and holds their
+// objects in memory.
+type Cache struct {
+	client dynamic.Interface
+	stopCh <-chan struct{}
+
+	lock      sync.RWMutex
+	resources map[schema.GroupVersionResource]*resource
+}
+
+// NewCache returns a cache filled through the server reached with config, whose
+// informers run until stopCh is closed.
+func NewCache(config *rest.Config, stopCh <-chan struct{}) (*Cache, error) {
+	config = rest.CopyConfig(config)
+	config.UserAgent = UserAgent
+	client, err := dynamic.NewClusterForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Cache{
+		client:    client.Cluster("*"),
+		stopCh:    stopCh,
+		resources: map[schema.GroupVersionResource]*resource{},
+	}, nil
+}
+
+// resource holds the objects of a resource across all logical clusters.
+type resource struct {
+	indexer         cache.Indexer
+	hasSynced       cache.InformerSynced
+	resourceVersion func() string
+
+	lock sync.RWMutex
+	// listKind is the kind of the lists of the resource, as last returned by the server.
+	listKind string
+}
+
+func (r *resource) getListKind() string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.listKind
+}
+
+func (r *resource) setListKind(kind string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.listKind = kind
+}
+
+// get returns the cached resource, or nil if it isn't watched.
+func (c *Cache) get(gvr schema.GroupVersionResource) *resource {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.resources[gvr]
+}
+
+// watch starts watching the resource across all logical clusters, unless it's already
+// watched.
+func (c *Cache) watch(gvr schema.GroupVersionResource) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if _, found := c.resources[gvr]; found {
+		return
+	}
+
+	r := &resource{}
+	client := c.client.Resource(gvr)
+	informer := cache.NewSharedIndexInformer(&cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			list, err := client.List(context.TODO(), options)
+			if err != nil {
+				return nil, err
+			}
+			r.setListKind(list.GetKind())
+			return list, nil
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return client.Watch(context.TODO(), options)
+		},
+	}, &unstructured.Unstructured{}, 0, cache.Indexers{
+		cache.NamespaceIndex:                      cache.MetaNamespaceIndexFunc,
+		dynamicinformer.ClusterIndexName:          dynamicinformer.ClusterIndexFunc,
+		dynamicinformer.ClusterNamespaceIndexName: dynamicinformer.ClusterNamespaceIndexFunc,
+	})
+	r.indexer = informer.GetIndexer()
+	r.hasSynced = informer.HasSynced
+	r.resourceVersion = informer.LastSyncResourceVersion
+	c.resources[gvr] = r
+
+	klog.Infof("Caching %s across all logical clusters", gvr)
+	go informer.Run(c.stopCh)
+}
@@ -0,0 +1,303 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watchcache
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clusters"
+
+	"github.com/kcp-dev/kcp/pkg/client/dynamicinformer"
+)
+
+// continueTokenVersion tells the continue tokens of the cache from those of the storage.
+const continueTokenVersion = "watchcache.kcp.dev/v1"
+
+// continueToken resumes a paginated LIST served from the cache. It holds the key of the
+// last object returned rather than a position in the cache, so that it stays valid
+// when the cache is rebuilt: objects are returned in key order, and the next page starts
+// with the first object after that key, whatever the cache holds by then.
+type continueToken struct {
+	APIVersion      string `json:"v"`
+	ResourceVersion string `json:"rv"`
+	StartKey        string `json:"start"`
+}
+
+func encodeContinue(resourceVersion, key string) (string, error) {
+	data, err := json.Marshal(continueToken{APIVersion: continueTokenVersion, ResourceVersion: resourceVersion, StartKey: key})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeContinue returns the key to continue after, or false if the token wasn't issued
+// by the cache.
+func decodeContinue(token string) (string, bool) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", false
+	}
+	var c continueToken
+	if err := json.Unmarshal(data, &c); err != nil || c.APIVersion != continueTokenVersion {
+		return "", false
+	}
+	return c.StartKey, true
+}
+
+// listOptions are the options of a LIST which can be served from the cache.
+type listOptions struct {
+	labelSelector labels.Selector
+	fieldSelector fields.Selector
+	limit         int64
+	// startKey is the key of the last object of the previous page, if any.
+	startKey string
+}
+
+// ClusterNameField is the field selecting the logical cluster of the objects.
+const ClusterNameField = "metadata.clusterName"
+
+// cachedFields are the fields which can be selected on in a LIST served from the cache.
+var cachedFields = map[string]bool{
+	"metadata.name":      true,
+	"metadata.namespace": true,
+	ClusterNameField:     true,
+}
+
+// cacheableOptions returns the options of the LIST, or false if it must be served by the
+// storage: when the client asks for a consistent read, for another representation than
+// JSON, or for fields the cache can't select on.
+func cacheableOptions(req *http.Request) (*listOptions, bool) {
+	if !acceptsJSON(req.Header.Get("Accept")) {
+		return nil, false
+	}
+	query := req.URL.Query()
+	opts := &listOptions{labelSelector: labels.Everything(), fieldSelector: fields.Everything()}
+
+	if token := query.Get("continue"); token != "" {
+		startKey, ok := decodeContinue(token)
+		if !ok || query.Get("resourceVersion") != "" {
+			return nil, false
+		}
+		opts.startKey = startKey
+	} else if query.Get("resourceVersion") != "0" {
+		return nil, false
+	}
+	if match := query.Get("resourceVersionMatch"); match != "" && match != "NotOlderThan" {
+		return nil, false
+	}
+
+	if s := query.Get("labelSelector"); s != "" {
+		selector, err := labels.Parse(s)
+		if err != nil {
+			return nil, false
+		}
+		opts.labelSelector = selector
+	}
+	if s := query.Get("fieldSelector"); s != "" {
+		selector, err := fields.ParseSelector(s)
+		if err != nil {
+			return nil, false
+		}
+		for _, r := range selector.Requirements() {
+			if !cachedFields[r.Field] {
+				return nil, false
+			}
+		}
+		opts.fieldSelector = selector
+	}
+	if s := query.Get("limit"); s != "" {
+		limit, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || limit < 0 {
+			return nil, false
+		}
+		opts.limit = limit
+	}
+	return opts, true
+}
+
+// acceptsJSON returns whether the preferred media type of the Accept header is plain JSON.
+func acceptsJSON(accept string) bool {
+	if accept == "" {
+		return true
+	}
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if err != nil {
+			continue
+		}
+		delete(params, "q")
+		return len(params) == 0 && (mediaType == "application/json" || mediaType == "application/*" || mediaType == "*/*")
+	}
+	return false
+}
+
+// WithCache serves the LISTs of the resources watched by the cache from memory, and passes
+// all other requests to handler. Resources are watched once they have been listed across
+// all logical clusters by a client other than the cache. It must be wrapped by the
+// authentication and authorization filters.
+//
+// Like the watch cache of kube-apiserver, the cache only serves the LISTs which accept a
+// stale read, that is with resourceVersion=0, and their following pages.
+func WithCache(handler http.Handler, c *Cache) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		info, ok := genericapirequest.RequestInfoFrom(req.Context())
+		cluster := genericapirequest.ClusterFrom(req.Context())
+		if !ok || cluster == nil || !info.IsResourceRequest || info.Verb != "list" || info.Subresource != "" || req.UserAgent() == UserAgent {
+			handler.ServeHTTP(w, req)
+			return
+		}
+
+		gvr := schema.GroupVersionResource{Group: info.APIGroup, Version: info.APIVersion, Resource: info.Resource}
+		r := c.get(gvr)
+		if r == nil {
+			if !cluster.Wildcard {
+				handler.ServeHTTP(w, req)
+				return
+			}
+			// only resources which are actually served are watched
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			handler.ServeHTTP(recorder, req)
+			if recorder.status == http.StatusOK {
+				c.watch(gvr)
+			}
+			return
+		}
+
+		opts, ok := cacheableOptions(req)
+		if !ok || !r.hasSynced() {
+			handler.ServeHTTP(w, req)
+			return
+		}
+		if clusterName, found := opts.fieldSelector.RequiresExactMatch(ClusterNameField); found && cluster.Wildcard {
+			// only the objects of the selected logical cluster are looked at
+			cluster = &genericapirequest.Cluster{Name: clusterName}
+		}
+		data, err := r.list(cluster, info.Namespace, gvr.GroupVersion(), opts)
+		if err != nil {
+			runtime.HandleError(err)
+			handler.ServeHTTP(w, req)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(data)
+	})
+}
+
+// statusRecorder records the status code of a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// list returns the JSON list of the objects of the given logical cluster and namespace
+// matching the options.
+func (r *resource) list(cluster *genericapirequest.Cluster, namespace string, gv schema.GroupVersion, opts *listOptions) ([]byte, error) {
+	var objs []interface{}
+	var err error
+	switch {
+	case cluster.Wildcard && namespace == "":
+		objs = r.indexer.List()
+	case cluster.Wildcard:
+		objs, err = r.indexer.ByIndex(cache.NamespaceIndex, namespace)
+	case namespace == "":
+		objs, err = r.indexer.ByIndex(dynamicinformer.ClusterIndexName, cluster.Name)
+	default:
+		objs, err = r.indexer.ByIndex(dynamicinformer.ClusterNamespaceIndexName, clusters.ToClusterAwareKey(cluster.Name, namespace))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	type keyed struct {
+		key string
+		obj *unstructured.Unstructured
+	}
+	var matching []keyed
+	for _, obj := range objs {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		metaObj, err := meta.Accessor(u)
+		if err != nil {
+			return nil, err
+		}
+		if !opts.labelSelector.Matches(labels.Set(metaObj.GetLabels())) {
+			continue
+		}
+		if !opts.fieldSelector.Matches(fields.Set{"metadata.name": metaObj.GetName(), "metadata.namespace": metaObj.GetNamespace(), ClusterNameField: metaObj.GetClusterName()}) {
+			continue
+		}
+		key := objectKey(metaObj)
+		if opts.startKey != "" && key <= opts.startKey {
+			continue
+		}
+		matching = append(matching, keyed{key: key, obj: u})
+	}
+	sort.Slice(matching, func(i, j int) bool {
+		return matching[i].key < matching[j].key
+	})
+
+	list := &unstructured.UnstructuredList{Object: map[string]interface{}{
+		"apiVersion": gv.String(),
+		"kind":       r.getListKind(),
+	}}
+	resourceVersion := r.resourceVersion()
+	list.SetResourceVersion(resourceVersion)
+	if opts.limit > 0 && int64(len(matching)) > opts.limit {
+		token, err := encodeContinue(resourceVersion, matching[opts.limit-1].key)
+		if err != nil {
+			return nil, err
+		}
+		remaining := int64(len(matching)) - opts.limit
+		list.SetContinue(token)
+		list.SetRemainingItemCount(&remaining)
+		matching = matching[:opts.limit]
+	}
+	list.Items = make([]unstructured.Unstructured, 0, len(matching))
+	for _, m := range matching {
+		list.Items = append(list.Items, *m.obj)
+	}
+	return list.MarshalJSON()
+}
+
+// objectKey orders the objects of all logical clusters.
+func objectKey(obj metav1.Object) string {
+	return obj.GetClusterName() + "/" + obj.GetNamespace() + "/" + obj.GetName()
+}
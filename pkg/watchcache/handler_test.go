@@ -0,0 +1,162 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watchcache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/kcp-dev/kcp/pkg/client/dynamicinformer"
+)
+
+func TestWithCache(t *testing.T) {
+	configmaps := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		cache.NamespaceIndex:                      cache.MetaNamespaceIndexFunc,
+		dynamicinformer.ClusterIndexName:          dynamicinformer.ClusterIndexFunc,
+		dynamicinformer.ClusterNamespaceIndexName: dynamicinformer.ClusterNamespaceIndexFunc,
+	})
+	for _, o := range []struct{ cluster, namespace, name, app string }{
+		{"admin", "default", "a", "web"},
+		{"admin", "default", "b", "db"},
+		{"admin", "other", "c", "web"},
+		{"user", "default", "a", "web"},
+	} {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("v1")
+		obj.SetKind("ConfigMap")
+		obj.SetClusterName(o.cluster)
+		obj.SetNamespace(o.namespace)
+		obj.SetName(o.name)
+		obj.SetLabels(map[string]string{"app": o.app})
+		if err := indexer.Add(obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := &Cache{resources: map[schema.GroupVersionResource]*resource{
+		configmaps: {
+			indexer:         indexer,
+			hasSynced:       func() bool { return true },
+			resourceVersion: func() string { return "42" },
+			listKind:        "ConfigMapList",
+		},
+	}}
+	storage := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	handler := WithCache(storage, c)
+
+	list := func(cluster genericapirequest.Cluster, namespace string, query url.Values) (*unstructured.UnstructuredList, int) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/configmaps?"+query.Encode(), nil)
+		ctx := genericapirequest.WithCluster(req.Context(), cluster)
+		ctx = genericapirequest.WithRequestInfo(ctx, &genericapirequest.RequestInfo{
+			IsResourceRequest: true,
+			Verb:              "list",
+			APIVersion:        "v1",
+			Resource:          "configmaps",
+			Namespace:         namespace,
+		})
+		rw := httptest.NewRecorder()
+		handler.ServeHTTP(rw, req.WithContext(ctx))
+		if rw.Code != http.StatusOK {
+			return nil, rw.Code
+		}
+		list := &unstructured.UnstructuredList{}
+		if err := list.UnmarshalJSON(rw.Body.Bytes()); err != nil {
+			t.Fatal(err)
+		}
+		return list, rw.Code
+	}
+	names := func(list *unstructured.UnstructuredList) []string {
+		var names []string
+		for _, item := range list.Items {
+			names = append(names, item.GetClusterName()+"/"+item.GetNamespace()+"/"+item.GetName())
+		}
+		return names
+	}
+
+	wildcard := genericapirequest.Cluster{Name: "admin", Wildcard: true}
+	admin := genericapirequest.Cluster{Name: "admin"}
+
+	if _, code := list(wildcard, "", url.Values{}); code != http.StatusTeapot {
+		t.Errorf("expected consistent reads to be served by the storage, got %d", code)
+	}
+	if _, code := list(wildcard, "", url.Values{"resourceVersion": {"0"}, "fieldSelector": {"data.key=value"}}); code != http.StatusTeapot {
+		t.Errorf("expected unsupported field selectors to be served by the storage, got %d", code)
+	}
+
+	for _, tc := range []struct {
+		name      string
+		cluster   genericapirequest.Cluster
+		namespace string
+		query     url.Values
+		want      []string
+	}{
+		{"all clusters", wildcard, "", url.Values{}, []string{"admin/default/a", "admin/default/b", "admin/other/c", "user/default/a"}},
+		{"namespace of all clusters", wildcard, "default", url.Values{}, []string{"admin/default/a", "admin/default/b", "user/default/a"}},
+		{"cluster", admin, "", url.Values{}, []string{"admin/default/a", "admin/default/b", "admin/other/c"}},
+		{"namespace of cluster", admin, "other", url.Values{}, []string{"admin/other/c"}},
+		{"label selector", wildcard, "", url.Values{"labelSelector": {"app=web"}}, []string{"admin/default/a", "admin/other/c", "user/default/a"}},
+		{"field selector", admin, "", url.Values{"fieldSelector": {"metadata.name=b"}}, []string{"admin/default/b"}},
+		{"cluster name", wildcard, "default", url.Values{"fieldSelector": {"metadata.clusterName=user"}}, []string{"user/default/a"}},
+		{"excluded cluster name", wildcard, "", url.Values{"fieldSelector": {"metadata.clusterName!=admin"}}, []string{"user/default/a"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.query.Set("resourceVersion", "0")
+			got, code := list(tc.cluster, tc.namespace, tc.query)
+			if code != http.StatusOK {
+				t.Fatalf("expected the list to be served from the cache, got %d", code)
+			}
+			if got.GetKind() != "ConfigMapList" || got.GetResourceVersion() != "42" {
+				t.Errorf("unexpected list kind %q or resource version %q", got.GetKind(), got.GetResourceVersion())
+			}
+			if diff := cmp.Diff(tc.want, names(got)); diff != "" {
+				t.Errorf("unexpected objects (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	var pages [][]string
+	query := url.Values{"resourceVersion": {"0"}, "limit": {"3"}}
+	for {
+		page, code := list(wildcard, "", query)
+		if code != http.StatusOK {
+			t.Fatalf("expected the page to be served from the cache, got %d", code)
+		}
+		pages = append(pages, names(page))
+		if page.GetContinue() == "" {
+			break
+		}
+		// the continue token stays valid when the cache changes between pages
+		if err := indexer.Delete(&page.Items[len(page.Items)-1]); err != nil {
+			t.Fatal(err)
+		}
+		query = url.Values{"continue": {page.GetContinue()}, "limit": {"3"}}
+	}
+	if diff := cmp.Diff([][]string{{"admin/default/a", "admin/default/b", "admin/other/c"}, {"user/default/a"}}, pages); diff != "" {
+		t.Errorf("unexpected pages (-want +got):\n%s", diff)
+	}
+}
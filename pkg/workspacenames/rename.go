@@ -0,0 +1,159 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workspacenames
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apiserver/pkg/endpoints/handlers/responsewriters"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+
+	tenancyapi "github.com/kcp-dev/kcp/pkg/apis/tenancy"
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+)
+
+// RenameSubresource is the subresource of Workspaces renaming them.
+const RenameSubresource = "rename"
+
+var workspacesResource = schema.GroupResource{Group: tenancyapi.GroupName, Resource: "workspaces"}
+
+// WithWorkspaceRename serves a POST of workspaces/<name>/rename?name=<new name>, which
+// creates a workspace with the new name holding the logical cluster of the renamed one, and
+// returns it with 201 Created. The workspace controller then deletes the renamed workspace,
+// whose name keeps reaching the logical cluster for the grace period. The references to the
+// renamed workspace by its siblings are updated to the new name.
+//
+// The workspace is renamed through the API of the kcp server reached with the given
+// privileged config, whose host must not have a /clusters/... suffix. It must be wrapped by
+// the authentication and authorization filters, which authorize the subresource like any
+// other.
+func WithWorkspaceRename(handler http.Handler, config *rest.Config) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		info, ok := genericapirequest.RequestInfoFrom(req.Context())
+		if !ok || !info.IsResourceRequest || info.APIGroup != tenancyapi.GroupName || info.Resource != "workspaces" || info.Subresource != RenameSubresource || info.Name == "" {
+			handler.ServeHTTP(w, req)
+			return
+		}
+		gv := schema.GroupVersion{Group: info.APIGroup, Version: info.APIVersion}
+		fail := func(err error) {
+			responsewriters.ErrorNegotiated(err, scheme.Codecs, gv, w, req)
+		}
+		if info.Verb != "create" {
+			fail(apierrors.NewMethodNotSupported(schema.GroupResource{Group: tenancyapi.GroupName, Resource: "workspaces/" + RenameSubresource}, info.Verb))
+			return
+		}
+		cluster := genericapirequest.ClusterFrom(req.Context())
+		if cluster == nil || cluster.Name == "" || cluster.Wildcard {
+			fail(apierrors.NewBadRequest("renaming a workspace requires a logical cluster"))
+			return
+		}
+		newName := req.URL.Query().Get("name")
+		if errs := validation.IsDNS1123Label(newName); len(errs) > 0 {
+			fail(apierrors.NewBadRequest(fmt.Sprintf("invalid new name %q: %s", newName, strings.Join(errs, ", "))))
+			return
+		}
+
+		client, err := kcpclient.NewClusterForConfig(config)
+		if err != nil {
+			fail(apierrors.NewInternalError(err))
+			return
+		}
+		renamed, err := rename(req.Context(), client.Cluster(cluster.Name), info.Name, newName)
+		if err != nil {
+			fail(err)
+			return
+		}
+		responsewriters.WriteRawJSON(http.StatusCreated, renamed, w)
+	})
+}
+
+// rename creates the workspace with the new name, and points the references of the
+// siblings of the renamed workspace to it.
+func rename(ctx context.Context, client kcpclient.Interface, oldName, newName string) (*tenancyv1alpha1.Workspace, error) {
+	old, err := client.TenancyV1alpha1().Workspaces().Get(ctx, oldName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if old.Status.Cluster == "" {
+		return nil, apierrors.NewConflict(workspacesResource, oldName, fmt.Errorf("the workspace is not initialized yet"))
+	}
+	if to, renamed := old.Annotations[tenancyv1alpha1.WorkspaceRenamedToAnnotation]; renamed {
+		return nil, apierrors.NewConflict(workspacesResource, oldName, fmt.Errorf("the workspace is renamed to %q already", to))
+	}
+	if old.DeletionTimestamp != nil {
+		return nil, apierrors.NewConflict(workspacesResource, oldName, fmt.Errorf("the workspace is being deleted"))
+	}
+
+	workspace := &tenancyv1alpha1.Workspace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        newName,
+			Labels:      old.Labels,
+			Annotations: map[string]string{},
+		},
+		Spec: old.Spec,
+	}
+	for key, value := range old.Annotations {
+		workspace.Annotations[key] = value
+	}
+	workspace.Annotations[tenancyv1alpha1.WorkspaceRenamedFromAnnotation] = oldName
+	created, err := client.TenancyV1alpha1().Workspaces().Create(ctx, workspace, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	// the references are best effort: the old name keeps working until the grace period ends
+	siblings, err := client.TenancyV1alpha1().Workspaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return created, nil
+	}
+	for i := range siblings.Items {
+		sibling := &siblings.Items[i]
+		if sibling.Spec.InheritFrom != oldName {
+			continue
+		}
+		sibling.Spec.InheritFrom = newName
+		_, _ = client.TenancyV1alpha1().Workspaces().Update(ctx, sibling, metav1.UpdateOptions{})
+	}
+	bindings, err := client.TenancyV1alpha1().WorkspaceRoleBindings().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return created, nil
+	}
+	for i := range bindings.Items {
+		binding := &bindings.Items[i]
+		changed := false
+		for j, name := range binding.Spec.Workspaces {
+			if name == oldName {
+				binding.Spec.Workspaces[j] = newName
+				changed = true
+			}
+		}
+		if changed {
+			_, _ = client.TenancyV1alpha1().WorkspaceRoleBindings().Update(ctx, binding, metav1.UpdateOptions{})
+		}
+	}
+	return created, nil
+}
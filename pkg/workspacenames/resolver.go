@@ -0,0 +1,221 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package workspacenames decouples the names of workspaces, which are the paths they are
+// reached under, from their logical clusters, whose names are generated: it resolves the
+// /clusters/<parent>:<workspace> paths to the logical clusters, serves the rename of
+// workspaces, and redirects the old paths of renamed workspaces for a grace period.
+package workspacenames
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/tools/cache"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	tenancyinformer "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/indexers"
+)
+
+// ErrNotInitialized is returned when resolving the name of a workspace whose logical
+// cluster is not assigned yet.
+var ErrNotInitialized = errors.New("the workspace is not initialized yet")
+
+// ErrNotFound is returned when resolving a path through a workspace which doesn't exist in
+// its parent.
+var ErrNotFound = errors.New("the workspace doesn't exist")
+
+// PathSeparator separates the names of a path to a workspace, from the outermost to the
+// innermost.
+const PathSeparator = ":"
+
+// Resolver resolves the paths requests address logical clusters with. A path starts with
+// the name of a logical cluster or of a workspace of the root logical cluster, followed by
+// the names of the workspaces nested into it, like org:team. The names of the existing
+// logical clusters are never shadowed by the names of workspaces. Names which are neither
+// are names of logical clusters, and so are all paths until the informers are installed
+// and synced.
+type Resolver struct {
+	lock             sync.RWMutex
+	workspaceIndexer cache.Indexer
+	hasSynced        func() bool
+	root             string
+
+	// now is overridden in tests
+	now func() time.Time
+}
+
+// NewResolver returns a Resolver leaving all names as they are until its informer is
+// installed.
+func NewResolver() *Resolver {
+	return &Resolver{now: time.Now}
+}
+
+// Install makes the resolver resolve paths with the given informer once it has synced,
+// starting from the workspaces of the given root logical cluster.
+func (r *Resolver) Install(workspaceInformer tenancyinformer.WorkspaceInformer, root string) error {
+	if err := indexers.AddIfNotPresent(workspaceInformer.Informer().GetIndexer(), cache.Indexers{
+		indexers.WorkspaceCluster: indexers.IndexWorkspaceByCluster,
+		indexers.WorkspaceParent:  indexers.IndexWorkspaceByParent,
+	}); err != nil {
+		return fmt.Errorf("failed to add indexer for Workspace: %w", err)
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.workspaceIndexer = workspaceInformer.Informer().GetIndexer()
+	r.hasSynced = workspaceInformer.Informer().HasSynced
+	r.root = root
+	return nil
+}
+
+// Resolve returns the logical cluster addressed with the given path. When the path goes
+// through the previous name of a renamed workspace, it also returns the path with its
+// current name.
+func (r *Resolver) Resolve(path string) (clusterName string, renamedTo string, err error) {
+	r.lock.RLock()
+	workspaceIndexer, hasSynced, root := r.workspaceIndexer, r.hasSynced, r.root
+	r.lock.RUnlock()
+	if hasSynced == nil || !hasSynced() {
+		return path, "", nil
+	}
+
+	names := strings.Split(path, PathSeparator)
+	first := 0
+	clusterName = root
+	isCluster, err := isLogicalCluster(workspaceIndexer, root, names[0])
+	if err != nil {
+		return "", "", err
+	}
+	if isCluster {
+		clusterName, first = names[0], 1
+	}
+	var renamed bool
+	for i := first; i < len(names); i++ {
+		workspace, previous, err := r.child(workspaceIndexer, clusterName, names[i])
+		if err != nil {
+			return "", "", err
+		}
+		if workspace == nil {
+			if len(names) == 1 {
+				// the name of a logical cluster without workspaces
+				return path, "", nil
+			}
+			return "", "", ErrNotFound
+		}
+		if workspace.Status.Cluster == "" {
+			return "", "", ErrNotInitialized
+		}
+		if previous {
+			names[i], renamed = workspace.Name, true
+		}
+		clusterName = workspace.Status.Cluster
+	}
+	if renamed {
+		renamedTo = strings.Join(names, PathSeparator)
+	}
+	return clusterName, renamedTo, nil
+}
+
+// IsLogicalCluster returns whether the given name is the name of an existing logical
+// cluster: the root one, one holding workspaces, or one of a workspace.
+func (r *Resolver) IsLogicalCluster(name string) (bool, error) {
+	r.lock.RLock()
+	workspaceIndexer, hasSynced, root := r.workspaceIndexer, r.hasSynced, r.root
+	r.lock.RUnlock()
+	if hasSynced == nil || !hasSynced() {
+		return false, nil
+	}
+	return isLogicalCluster(workspaceIndexer, root, name)
+}
+
+func isLogicalCluster(workspaceIndexer cache.Indexer, root, name string) (bool, error) {
+	if name == root {
+		return true, nil
+	}
+	parents, err := workspaceIndexer.ByIndex(indexers.WorkspaceParent, name)
+	if err != nil {
+		return false, err
+	}
+	if len(parents) > 0 {
+		return true, nil
+	}
+	workspaces, err := workspaceIndexer.ByIndex(indexers.WorkspaceCluster, name)
+	if err != nil {
+		return false, err
+	}
+	for _, obj := range workspaces {
+		// the logical cluster of a workspace not initialized yet doesn't exist
+		if obj.(*tenancyv1alpha1.Workspace).Status.Cluster == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// child returns the workspace of the given name in the given logical cluster, or the one
+// renamed from it during the grace period, in which case previous is true.
+func (r *Resolver) child(workspaceIndexer cache.Indexer, clusterName, name string) (workspace *tenancyv1alpha1.Workspace, previous bool, err error) {
+	children, err := workspaceIndexer.ByIndex(indexers.WorkspaceParent, clusterName)
+	if err != nil {
+		return nil, false, err
+	}
+	now := r.now()
+	for _, obj := range children {
+		child := obj.(*tenancyv1alpha1.Workspace)
+		if child.Name == name {
+			return child, false, nil
+		}
+		for _, previousName := range child.Status.PreviousNames {
+			if previousName.Name == name && now.Before(previousName.Until.Time) {
+				workspace, previous = child, true
+			}
+		}
+	}
+	return workspace, previous, nil
+}
+
+type renamedToKey struct{}
+
+// WithRenamedTo returns a context recording that the request addressed a renamed
+// workspace with its previous name, through its path.
+func WithRenamedTo(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, renamedToKey{}, name)
+}
+
+// WithRedirects redirects the requests to the previous path of a renamed workspace to its
+// current path. It must be wrapped by the authentication and authorization filters, so
+// that only the users allowed into the workspace learn its current name.
+func WithRedirects(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		name, ok := req.Context().Value(renamedToKey{}).(string)
+		cluster := genericapirequest.ClusterFrom(req.Context())
+		if !ok || name == "" || cluster == nil {
+			handler.ServeHTTP(w, req)
+			return
+		}
+		location := url.URL{Path: "/clusters/" + name + req.URL.Path, RawQuery: req.URL.RawQuery}
+		http.Redirect(w, req, location.String(), http.StatusTemporaryRedirect)
+	})
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workspacenames
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/tools/cache"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/indexers"
+)
+
+func TestResolve(t *testing.T) {
+	now := time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC)
+	workspaceIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		indexers.WorkspaceCluster: indexers.IndexWorkspaceByCluster,
+		indexers.WorkspaceParent:  indexers.IndexWorkspaceByParent,
+	})
+	for _, obj := range []interface{}{
+		&tenancyv1alpha1.Workspace{ObjectMeta: metav1.ObjectMeta{Name: "acme", ClusterName: "root"}, Status: tenancyv1alpha1.WorkspaceStatus{Cluster: "a1b2c3d4"}},
+		&tenancyv1alpha1.Workspace{ObjectMeta: metav1.ObjectMeta{Name: "globex", ClusterName: "root"}, Status: tenancyv1alpha1.WorkspaceStatus{Cluster: "g5h6j7k8"}},
+		&tenancyv1alpha1.Workspace{
+			ObjectMeta: metav1.ObjectMeta{Name: "platform", ClusterName: "a1b2c3d4"},
+			Status: tenancyv1alpha1.WorkspaceStatus{
+				Cluster: "k7c2q9x4",
+				PreviousNames: []tenancyv1alpha1.WorkspacePreviousName{
+					{Name: "team", Until: metav1.NewTime(now.Add(time.Hour))},
+					{Name: "infra", Until: metav1.NewTime(now.Add(-time.Hour))},
+				},
+			},
+		},
+		// the same name under another parent
+		&tenancyv1alpha1.Workspace{ObjectMeta: metav1.ObjectMeta{Name: "platform", ClusterName: "g5h6j7k8"}, Status: tenancyv1alpha1.WorkspaceStatus{Cluster: "m3n4p5q6"}},
+		&tenancyv1alpha1.Workspace{ObjectMeta: metav1.ObjectMeta{Name: "legacy", ClusterName: "root"}, Status: tenancyv1alpha1.WorkspaceStatus{Cluster: "legacy"}},
+		&tenancyv1alpha1.Workspace{ObjectMeta: metav1.ObjectMeta{Name: "new", ClusterName: "root"}},
+		// named after the logical clusters of other workspaces
+		&tenancyv1alpha1.Workspace{ObjectMeta: metav1.ObjectMeta{Name: "k7c2q9x4", ClusterName: "root"}, Status: tenancyv1alpha1.WorkspaceStatus{Cluster: "x9y8z7w6"}},
+		&tenancyv1alpha1.Workspace{ObjectMeta: metav1.ObjectMeta{Name: "a1b2c3d4", ClusterName: "g5h6j7k8"}, Status: tenancyv1alpha1.WorkspaceStatus{Cluster: "r1s2t3u4"}},
+		// in a logical cluster outside of the root
+		&tenancyv1alpha1.Workspace{ObjectMeta: metav1.ObjectMeta{Name: "steve", ClusterName: "e2e"}, Status: tenancyv1alpha1.WorkspaceStatus{Cluster: "s4t5e6v7"}},
+	} {
+		if err := workspaceIndexer.Add(obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := &Resolver{workspaceIndexer: workspaceIndexer, hasSynced: func() bool { return true }, root: "root", now: func() time.Time { return now }}
+
+	for _, tc := range []struct {
+		path              string
+		expectedCluster   string
+		expectedRenamedTo string
+		expectedErr       error
+	}{
+		{path: "acme", expectedCluster: "a1b2c3d4"},
+		{path: "acme:platform", expectedCluster: "k7c2q9x4"},
+		{path: "globex:platform", expectedCluster: "m3n4p5q6"},
+		{path: "platform", expectedCluster: "platform"},
+		{path: "acme:team", expectedCluster: "k7c2q9x4", expectedRenamedTo: "acme:platform"},
+		{path: "acme:infra", expectedErr: ErrNotFound},
+		{path: "acme:missing", expectedErr: ErrNotFound},
+		{path: "legacy", expectedCluster: "legacy"},
+		{path: "new", expectedErr: ErrNotInitialized},
+		{path: "root:acme", expectedCluster: "a1b2c3d4"},
+		{path: "a1b2c3d4:platform", expectedCluster: "k7c2q9x4"},
+		{path: "k7c2q9x4", expectedCluster: "k7c2q9x4"},
+		{path: "g5h6j7k8:a1b2c3d4", expectedCluster: "r1s2t3u4"},
+		{path: "e2e", expectedCluster: "e2e"},
+		{path: "e2e:steve", expectedCluster: "s4t5e6v7"},
+		{path: "steve", expectedCluster: "steve"},
+		{path: "admin", expectedCluster: "admin"},
+	} {
+		t.Run(tc.path, func(t *testing.T) {
+			cluster, renamedTo, err := r.Resolve(tc.path)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+			if cluster != tc.expectedCluster || renamedTo != tc.expectedRenamedTo {
+				t.Errorf("expected %q renamed to %q, got %q renamed to %q", tc.expectedCluster, tc.expectedRenamedTo, cluster, renamedTo)
+			}
+		})
+	}
+
+	for name, expected := range map[string]bool{"root": true, "a1b2c3d4": true, "k7c2q9x4": true, "e2e": true, "legacy": true, "new": false, "acme": false} {
+		if exists, err := r.IsLogicalCluster(name); err != nil || exists != expected {
+			t.Errorf("expected %q to be a logical cluster: %v, got %v, %v", name, expected, exists, err)
+		}
+	}
+
+	if cluster, _, _ := NewResolver().Resolve("acme"); cluster != "acme" {
+		t.Errorf("expected names to be left as they are until the resolver is installed, got %q", cluster)
+	}
+}
+
+func TestWithRedirects(t *testing.T) {
+	handler := WithRedirects(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/namespaces?limit=10", nil)
+	ctx := genericapirequest.WithCluster(req.Context(), genericapirequest.Cluster{Name: "k7c2q9x4"})
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req.WithContext(WithRenamedTo(ctx, "platform")))
+	if w.Code != http.StatusTemporaryRedirect {
+		t.Fatalf("expected a redirect, got %d", w.Code)
+	}
+	if location, want := w.Header().Get("Location"), "/clusters/platform/api/v1/namespaces?limit=10"; location != want {
+		t.Errorf("expected a redirect to %s, got %s", want, location)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req.WithContext(ctx))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected requests to current names to be served, got %d", w.Code)
+	}
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ChaosServer is a RunningServer whose processes tests can disrupt, to check that kcp and
+// its controllers recover. The kcp servers a test asks for implement it, the workspaces of
+// an external kcp do not:
+//
+//	chaos, ok := servers[0].(framework.ChaosServer)
+//
+// The test is not failed by the server being unhealthy while disrupted. Recovering
+// operations return once the server is ready again.
+type ChaosServer interface {
+	RunningServer
+
+	// Kill kills the kcp process, without letting it shut down.
+	Kill() error
+	// Restart kills the kcp process if it runs, and starts it again with the same state.
+	Restart() error
+	// Pause freezes the kcp process, along with its embedded etcd if any.
+	Pause() error
+	// Resume resumes the paused kcp process.
+	Resume() error
+	// PauseEtcd freezes the etcd of the server, which requires KcpConfig.SeparateEtcd.
+	PauseEtcd() error
+	// ResumeEtcd resumes the paused etcd of the server.
+	ResumeEtcd() error
+}
+
+var _ ChaosServer = &kcpServer{}
+
+func (c *kcpServer) Kill() error {
+	c.lock.Lock()
+	cmd, exited := c.cmd, c.exited
+	c.lock.Unlock()
+	if cmd == nil {
+		return errors.New("kcp server is not running")
+	}
+	select {
+	case <-exited:
+		return nil
+	default:
+	}
+	c.setDisrupted(true)
+	c.t.Logf("chaos: killing kcp server %q", c.name)
+	if err := cmd.Process.Kill(); err != nil {
+		return fmt.Errorf("failed to kill kcp server %q: %w", c.name, err)
+	}
+	<-exited
+	return nil
+}
+
+func (c *kcpServer) Restart() error {
+	if err := c.Kill(); err != nil {
+		return err
+	}
+	c.setDisrupted(true)
+	c.t.Logf("chaos: restarting kcp server %q", c.name)
+	if err := c.start(); err != nil {
+		return err
+	}
+	return c.recover()
+}
+
+func (c *kcpServer) Pause() error {
+	c.lock.Lock()
+	cmd := c.cmd
+	c.lock.Unlock()
+	if cmd == nil {
+		return errors.New("kcp server is not running")
+	}
+	c.setDisrupted(true)
+	c.t.Logf("chaos: pausing kcp server %q", c.name)
+	return stopProcess(cmd.Process)
+}
+
+func (c *kcpServer) Resume() error {
+	c.lock.Lock()
+	cmd := c.cmd
+	c.lock.Unlock()
+	if cmd == nil {
+		return errors.New("kcp server is not running")
+	}
+	c.t.Logf("chaos: resuming kcp server %q", c.name)
+	if err := continueProcess(cmd.Process); err != nil {
+		return err
+	}
+	return c.recover()
+}
+
+func (c *kcpServer) PauseEtcd() error {
+	if c.etcd == nil {
+		return fmt.Errorf("kcp server %q runs an embedded etcd, which cannot be paused without pausing the server: set SeparateEtcd", c.name)
+	}
+	c.setDisrupted(true)
+	c.t.Logf("chaos: pausing the etcd of kcp server %q", c.name)
+	return c.etcd.signal(stopProcess)
+}
+
+func (c *kcpServer) ResumeEtcd() error {
+	if c.etcd == nil {
+		return fmt.Errorf("kcp server %q runs an embedded etcd", c.name)
+	}
+	c.t.Logf("chaos: resuming the etcd of kcp server %q", c.name)
+	if err := c.etcd.signal(continueProcess); err != nil {
+		return err
+	}
+	return c.recover()
+}
+
+// recover waits for the disrupted server to be ready again.
+func (c *kcpServer) recover() error {
+	if err := c.Ready(); err != nil {
+		return err
+	}
+	c.setDisrupted(false)
+	return nil
+}
+
+func (c *kcpServer) setDisrupted(disrupted bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.disrupted = disrupted
+}
+
+func (c *kcpServer) isDisrupted() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.disrupted
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// etcdServer is an etcd process serving a kcp server, for tests to disrupt it separately
+// from the server.
+type etcdServer struct {
+	name        string
+	dataDir     string
+	artifactDir string
+	clientPort  string
+	peerPort    string
+
+	lock sync.Mutex
+	cmd  *exec.Cmd
+
+	t TestingTInterface
+}
+
+func newEtcdServer(t TestingTInterface, name, dataDir, artifactDir string) (*etcdServer, error) {
+	ports, err := GetFreePorts(t, 2)
+	if err != nil {
+		return nil, err
+	}
+	return &etcdServer{
+		name:        name,
+		dataDir:     filepath.Join(dataDir, "etcd"),
+		artifactDir: artifactDir,
+		clientPort:  ports[0],
+		peerPort:    ports[1],
+		t:           t,
+	}, nil
+}
+
+// endpoint is the client URL of the etcd server.
+func (e *etcdServer) endpoint() string {
+	return "http://localhost:" + e.clientPort
+}
+
+// run runs etcd while the context is active, and waits for it to be healthy.
+func (e *etcdServer) run(ctx context.Context) error {
+	peerURL := "http://localhost:" + e.peerPort
+	cmd := exec.CommandContext(ctx, "etcd",
+		"--name="+e.name,
+		"--data-dir="+e.dataDir,
+		"--listen-client-urls="+e.endpoint(),
+		"--advertise-client-urls="+e.endpoint(),
+		"--listen-peer-urls="+peerURL,
+		"--initial-advertise-peer-urls="+peerURL,
+		"--initial-cluster="+e.name+"="+peerURL,
+	)
+	e.t.Logf("running: %v", strings.Join(cmd.Args, " "))
+	logFile, err := os.Create(filepath.Join(e.artifactDir, "etcd.log"))
+	if err != nil {
+		return fmt.Errorf("could not create log file: %w", err)
+	}
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	if err := cmd.Start(); err != nil {
+		logFile.Close()
+		return err
+	}
+	e.lock.Lock()
+	e.cmd = cmd
+	e.lock.Unlock()
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		defer logFile.Close()
+		if err := cmd.Wait(); err != nil && ctx.Err() == nil {
+			e.t.Errorf("`etcd` failed: %v, logs in %s", err, logFile.Name())
+		}
+	}()
+	e.t.Cleanup(func() {
+		<-exited
+	})
+
+	var lastErr error
+	healthCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	wait.UntilWithContext(healthCtx, func(ctx context.Context) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.endpoint()+"/health", nil)
+		if err != nil {
+			lastErr = err
+			return
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			lastErr = err
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			lastErr = fmt.Errorf("unhealthy: %s", resp.Status)
+			return
+		}
+		lastErr = nil
+		cancel()
+	}, 100*time.Millisecond)
+	if lastErr != nil {
+		return fmt.Errorf("etcd of kcp server %q never became healthy: %w", e.name, lastErr)
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// signal sends the etcd process a signal, e.g. to pause it.
+func (e *etcdServer) signal(send func(*os.Process) error) error {
+	e.lock.Lock()
+	cmd := e.cmd
+	e.lock.Unlock()
+	if cmd == nil {
+		return errors.New("etcd is not running")
+	}
+	return send(cmd.Process)
+}
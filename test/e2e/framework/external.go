@@ -0,0 +1,224 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	apiextensionsv1client "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/typed/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	configcrds "github.com/kcp-dev/kcp/config"
+	apiresourceapi "github.com/kcp-dev/kcp/pkg/apis/apiresource"
+	clusterapi "github.com/kcp-dev/kcp/pkg/apis/cluster"
+	tenancyapi "github.com/kcp-dev/kcp/pkg/apis/tenancy"
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	"github.com/kcp-dev/kcp/pkg/cmd/workspace"
+	"github.com/kcp-dev/kcp/pkg/scenario"
+	"github.com/kcp-dev/kcp/pkg/workspacenames"
+)
+
+const (
+	// ExternalKubeconfigEnvVar is the environment variable pointing at the kubeconfig of an
+	// already-running kcp. When it is set, tests run against that kcp instead of spawning
+	// their own servers.
+	ExternalKubeconfigEnvVar = "KCP_E2E_KUBECONFIG"
+	// ExternalContextEnvVar is the environment variable naming the context of the external
+	// kubeconfig to use, its current context by default.
+	ExternalContextEnvVar = "KCP_E2E_CONTEXT"
+	// ExternalServerEnvVar is the environment variable overriding the address of the server
+	// of the external kubeconfig.
+	ExternalServerEnvVar = "KCP_E2E_SERVER"
+
+	// TestNameAnnotation is the annotation recording the test owning a workspace created
+	// against an external kcp.
+	TestNameAnnotation = "e2e.kcp.dev/test"
+)
+
+// externalCustomResourceDefinitions are the CRDs kcp installs in the admin logical cluster,
+// installed in the workspace of each test since CRDs are scoped to logical clusters.
+var externalCustomResourceDefinitions = []metav1.GroupKind{
+	{Group: tenancyapi.GroupName, Kind: "workspaces"},
+	{Group: tenancyapi.GroupName, Kind: "workspaceshards"},
+	{Group: tenancyapi.GroupName, Kind: "workspacetypes"},
+	{Group: tenancyapi.GroupName, Kind: "workspacerolebindings"},
+	{Group: apiresourceapi.GroupName, Kind: "apiresourceimports"},
+	{Group: apiresourceapi.GroupName, Kind: "negotiatedapiresources"},
+	{Group: clusterapi.GroupName, Kind: "clusters"},
+	{Group: clusterapi.GroupName, Kind: "synctransforms"},
+	{Group: clusterapi.GroupName, Kind: "placements"},
+}
+
+// externalKcp is an already-running kcp, shared by the tests, each of which runs in its own
+// workspaces.
+type externalKcp struct {
+	// kubeconfig only has the context to use, as its current context.
+	kubeconfig clientcmdapi.Config
+}
+
+// externalKcpFromEnv returns the external kcp configured by the environment, or nil if tests
+// should spawn their own servers.
+func externalKcpFromEnv() (*externalKcp, error) {
+	path := os.Getenv(ExternalKubeconfigEnvVar)
+	if path == "" {
+		return nil, nil
+	}
+	kubeconfig, err := clientcmd.LoadFromFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the kubeconfig of the external kcp from %s: %w", path, err)
+	}
+	if context := os.Getenv(ExternalContextEnvVar); context != "" {
+		kubeconfig.CurrentContext = context
+	}
+	if err := clientcmdapi.MinifyConfig(kubeconfig); err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig of the external kcp %s: %w", path, err)
+	}
+	if server := os.Getenv(ExternalServerEnvVar); server != "" {
+		kubeconfig.Clusters[kubeconfig.Contexts[kubeconfig.CurrentContext].Cluster].Server = server
+	}
+	return &externalKcp{kubeconfig: *kubeconfig}, nil
+}
+
+// newServer creates a workspace for the given server of the test, and returns a server whose
+// configs are scoped to it. The workspace is deleted when the test finishes.
+func (e *externalKcp) newServer(ctx context.Context, t *T, cfg KcpConfig) (*externalServer, error) {
+	t.Helper()
+	if len(cfg.Args) > 0 {
+		t.Logf("ignoring the arguments of kcp server %q, the external kcp must have been started with: %s", cfg.Name, strings.Join(cfg.Args, " "))
+	}
+	parentConfig, err := clientcmd.NewNonInteractiveClientConfig(e.kubeconfig, e.kubeconfig.CurrentContext, nil, nil).ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	client, err := kcpclient.NewForConfig(parentConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to construct client for the external kcp: %w", err)
+	}
+	ws, err := client.TenancyV1alpha1().Workspaces().Create(ctx, &tenancyv1alpha1.Workspace{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "e2e-" + cfg.Name + "-",
+			Annotations:  map[string]string{TestNameAnnotation: t.Name()},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create workspace for kcp server %q: %w", cfg.Name, err)
+	}
+	t.Logf("Running kcp server %q in workspace %s of the external kcp.", cfg.Name, ws.Name)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		// the test is over, so errors go to the delegate
+		err := client.TenancyV1alpha1().Workspaces().Delete(ctx, ws.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			t.T.Errorf("failed to delete workspace %s: %v", ws.Name, err)
+		}
+	})
+
+	kubeconfig := *e.kubeconfig.DeepCopy()
+	cluster := kubeconfig.Clusters[kubeconfig.Contexts[kubeconfig.CurrentContext].Cluster]
+	base, parent, err := workspace.SplitServer(cluster.Server)
+	if err != nil {
+		return nil, err
+	}
+	path := ws.Name
+	if parent != workspace.RootWorkspace {
+		path = parent + workspacenames.PathSeparator + ws.Name
+	}
+	cluster.Server = base + "/clusters/" + path
+	server := &externalServer{
+		workspace: ws.Name,
+		cfg:       clientcmd.NewNonInteractiveClientConfig(kubeconfig, kubeconfig.CurrentContext, nil, nil),
+	}
+
+	config, err := server.Config()
+	if err != nil {
+		return nil, err
+	}
+	crdClient, err := apiextensionsv1client.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to construct client for workspace %s: %w", ws.Name, err)
+	}
+	if err := configcrds.BootstrapCustomResourceDefinitions(ctx, crdClient.CustomResourceDefinitions(), externalCustomResourceDefinitions); err != nil {
+		return nil, fmt.Errorf("failed to install CRDs in workspace %s: %w", ws.Name, err)
+	}
+	return server, nil
+}
+
+// ready checks that the external kcp is healthy and ready. Unlike the servers spawned for a
+// test, the external kcp is not monitored while the test runs.
+func (e *externalKcp) ready(ctx context.Context) error {
+	config, err := clientcmd.NewNonInteractiveClientConfig(e.kubeconfig, e.kubeconfig.CurrentContext, nil, nil).ClientConfig()
+	if err != nil {
+		return err
+	}
+	if config.Host, _, err = workspace.SplitServer(config.Host); err != nil {
+		return err
+	}
+	if config.NegotiatedSerializer == nil {
+		config.NegotiatedSerializer = scheme.Codecs.WithoutConversion()
+	}
+	client, err := rest.UnversionedRESTClientFor(config)
+	if err != nil {
+		return fmt.Errorf("failed to create unversioned client: %w", err)
+	}
+	for _, endpoint := range []string{"/livez", "/readyz"} {
+		if _, err := rest.NewRequest(client).RequestURI(endpoint).Do(ctx).Raw(); err != nil {
+			return fmt.Errorf("external kcp at %s is not healthy: error contacting %s: %w", config.Host, endpoint, err)
+		}
+	}
+	return nil
+}
+
+// externalServer is a workspace of an external kcp standing in for a kcp server of a test.
+type externalServer struct {
+	workspace string
+	cfg       clientcmd.ClientConfig
+	// recorder records the requests of the test to the server, if enabled.
+	recorder *scenario.Recorder
+}
+
+// Config exposes a copy of the client config for the workspace of this server.
+func (s *externalServer) Config() (*rest.Config, error) {
+	config, err := s.cfg.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	return recordConfig(config, s.recorder), nil
+}
+
+// RawConfig exposes a copy of the client config for the workspace of this server.
+func (s *externalServer) RawConfig() (clientcmdapi.Config, error) {
+	return s.cfg.RawConfig()
+}
+
+// ScopedToWorkspace returns whether the config of a server is already scoped to a logical
+// cluster, as are the configs of the servers of tests running against an external kcp.
+// Requests of clients built from such configs must not set a cluster.
+func ScopedToWorkspace(config *rest.Config) bool {
+	_, ws, err := workspace.SplitServer(config.Host)
+	return err == nil && ws != workspace.RootWorkspace
+}
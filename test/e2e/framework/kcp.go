@@ -35,6 +35,8 @@ import (
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/kcp-dev/kcp/pkg/scenario"
 )
 
 // kcpServer exposes a kcp invocation to a test and
@@ -43,6 +45,7 @@ import (
 //  - all ports and data directories are unique to support
 //    concurrent execution within a test case and across tests
 type kcpServer struct {
+	name        string
 	args        []string
 	ctx         context.Context
 	dataDir     string
@@ -51,6 +54,19 @@ type kcpServer struct {
 	lock *sync.Mutex
 	cfg  clientcmd.ClientConfig
 
+	// cmd is the latest kcp process, and exited is closed when it exits.
+	cmd    *exec.Cmd
+	exited chan struct{}
+	// disrupted is set while a test kills or pauses the server on purpose, which must not
+	// fail the test.
+	disrupted bool
+	// monitoring is set once the endpoints of the server are monitored.
+	monitoring bool
+	// etcd is the etcd of the server, unless it is embedded.
+	etcd *etcdServer
+	// recorder records the requests of the test to the server, if enabled.
+	recorder *scenario.Recorder
+
 	t TestingTInterface
 }
 
@@ -65,18 +81,11 @@ func newKcpServer(t *T, cfg KcpConfig, artifactDir, dataDir string) (*kcpServer,
 		ctx = c
 		t.Cleanup(cancel) // this does not really matter but govet is upset
 	}
-	kcpListenPort, err := GetFreePort(t)
-	if err != nil {
-		return nil, err
-	}
-	etcdClientPort, err := GetFreePort(t)
-	if err != nil {
-		return nil, err
-	}
-	etcdPeerPort, err := GetFreePort(t)
+	ports, err := GetFreePorts(t, 3)
 	if err != nil {
 		return nil, err
 	}
+	kcpListenPort, etcdClientPort, etcdPeerPort := ports[0], ports[1], ports[2]
 	artifactDir = filepath.Join(artifactDir, "kcp", cfg.Name)
 	if err := os.MkdirAll(artifactDir, 0755); err != nil {
 		return nil, fmt.Errorf("could not create artifact dir: %w", err)
@@ -85,7 +94,16 @@ func newKcpServer(t *T, cfg KcpConfig, artifactDir, dataDir string) (*kcpServer,
 	if err := os.MkdirAll(dataDir, 0755); err != nil {
 		return nil, fmt.Errorf("could not create data dir: %w", err)
 	}
+	var etcd *etcdServer
+	if cfg.SeparateEtcd {
+		if etcd, err = newEtcdServer(t, cfg.Name, dataDir, artifactDir); err != nil {
+			return nil, err
+		}
+		cfg.Args = append([]string{"--etcd-servers=" + etcd.endpoint()}, cfg.Args...)
+	}
 	return &kcpServer{
+		name: cfg.Name,
+		etcd: etcd,
 		args: append([]string{
 			"--root_directory=" + dataDir,
 			"--listen=:" + kcpListenPort,
@@ -115,17 +133,33 @@ func (c *kcpServer) Run(parentCtx context.Context) error {
 		c.t.Cleanup(deadlinedCancel) // this does not really matter but govet is upset
 	}
 	c.ctx = ctx
-	cleanupCtx, cleanupCancel := context.WithCancel(context.Background())
 	c.t.Cleanup(func() {
 		c.t.Log("cleanup: ending kcp server")
 		cancel()
-		<-cleanupCtx.Done()
+		// the server may have been restarted, so wait for the latest process
+		c.lock.Lock()
+		exited := c.exited
+		c.lock.Unlock()
+		if exited != nil {
+			<-exited
+		}
 	})
-	cmd := exec.CommandContext(ctx, "kcp", append([]string{"start"}, c.args...)...)
+	if c.etcd != nil {
+		if err := c.etcd.run(ctx); err != nil {
+			return err
+		}
+	}
+	return c.start()
+}
+
+// start starts the kcp process, and reports it failing unless the test is over or the
+// server is disrupted on purpose.
+func (c *kcpServer) start() error {
+	cmd := exec.CommandContext(c.ctx, "kcp", append([]string{"start"}, c.args...)...)
 	c.t.Logf("running: %v", strings.Join(cmd.Args, " "))
-	logFile, err := os.Create(filepath.Join(c.artifactDir, "kcp.log"))
+	// restarts append to the logs of the previous processes
+	logFile, err := os.OpenFile(filepath.Join(c.artifactDir, "kcp.log"), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
 	if err != nil {
-		cleanupCancel()
 		return fmt.Errorf("could not create log file: %w", err)
 	}
 	log := bytes.Buffer{}
@@ -134,14 +168,20 @@ func (c *kcpServer) Run(parentCtx context.Context) error {
 	cmd.Stdout = mw
 	cmd.Stderr = mw
 	if err := cmd.Start(); err != nil {
-		cleanupCancel()
+		logFile.Close()
 		return err
 	}
+	exited := make(chan struct{})
+	c.lock.Lock()
+	c.cmd = cmd
+	c.exited = exited
+	c.lock.Unlock()
 	go func() {
-		defer func() { cleanupCancel() }()
+		defer close(exited)
+		defer logFile.Close()
 		err := cmd.Wait()
 		data := c.filterKcpLogs(&log)
-		if err != nil && ctx.Err() == nil {
+		if err != nil && c.ctx.Err() == nil && !c.isDisrupted() {
 			// we care about errors in the process that did not result from the
 			// context expiring and us ending the process
 			c.t.Errorf("`kcp` failed: %w logs:\n%v", err, data)
@@ -189,7 +229,11 @@ func (c *kcpServer) Config() (*rest.Config, error) {
 	if c.cfg == nil {
 		return nil, fmt.Errorf("programmer error: kcpServer.Config() called before load succeeded. Stack: %s", string(debug.Stack()))
 	}
-	return c.cfg.ClientConfig()
+	config, err := c.cfg.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	return recordConfig(config, c.recorder), nil
 }
 
 // RawConfig exposes a copy of the client config for this server.
@@ -236,6 +280,13 @@ func (c *kcpServer) Ready() error {
 	}
 	wg.Wait()
 
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.monitoring {
+		// a restarted server is already monitored
+		return nil
+	}
+	c.monitoring = true
 	for _, endpoint := range []string{"/livez", "/readyz"} {
 		go func(endpoint string) {
 			c.monitorEndpoint(client, endpoint)
@@ -304,7 +355,7 @@ func (c *kcpServer) monitorEndpoint(client *rest.RESTClient, endpoint string) {
 	}
 	wait.UntilWithContext(ctx, func(ctx context.Context) {
 		_, err := rest.NewRequest(client).RequestURI(endpoint).Do(ctx).Raw()
-		if err != nil {
+		if err != nil && !c.isDisrupted() {
 			c.t.Errorf("error contacting %s: %v", endpoint, err)
 		}
 	}, 1*time.Second)
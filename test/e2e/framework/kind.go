@@ -0,0 +1,154 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	// KindClusterEnvVar is the environment variable naming an existing kind cluster to attach
+	// to instead of provisioning one per test. The cluster is shared by the tests and kept
+	// after they finish.
+	KindClusterEnvVar = "KCP_E2E_KIND_CLUSTER"
+	// KindNodeImageEnvVar is the environment variable overriding the node image of the kind
+	// clusters provisioned for tests.
+	KindNodeImageEnvVar = "KCP_E2E_KIND_NODE_IMAGE"
+)
+
+// KindAvailable returns whether kind clusters can be provisioned or attached to, i.e. the
+// kind binary is installed.
+func KindAvailable() bool {
+	_, err := exec.LookPath("kind")
+	return err == nil
+}
+
+// kindCluster is a physical cluster run by kind.
+type kindCluster struct {
+	name string
+	cfg  clientcmd.ClientConfig
+}
+
+// NewKindCluster provisions a kind cluster for the test, to register with kcp as a physical
+// cluster, or attaches to the one named by the KindClusterEnvVar environment variable. The
+// provisioned cluster is deleted when the test finishes. The kubeconfig of the cluster
+// points at its port on the host, so that kcp can reach it.
+func NewKindCluster(ctx context.Context, t TestingTInterface, name string) (RunningServer, error) {
+	if existing := os.Getenv(KindClusterEnvVar); existing != "" {
+		t.Logf("Attaching to kind cluster %s.", existing)
+		return loadKindCluster(ctx, existing)
+	}
+
+	// kind names the containers of the cluster after it, which must be unique on the host
+	clusterName := fmt.Sprintf("kcp-e2e-%s-%s", name, rand.String(5))
+	// keep the kubeconfig of the user untouched
+	kubeconfigPath := filepath.Join(t.TempDir(), "kind.kubeconfig")
+	args := []string{"create", "cluster", "--name", clusterName, "--kubeconfig", kubeconfigPath, "--wait", "2m"}
+	if image := os.Getenv(KindNodeImageEnvVar); image != "" {
+		args = append(args, "--image", image)
+	}
+	start := time.Now()
+	t.Logf("Provisioning kind cluster %s...", clusterName)
+	if _, err := runKind(ctx, args...); err != nil {
+		// kind may have created the nodes before failing
+		deleteKindCluster(t, clusterName, kubeconfigPath)
+		return nil, err
+	}
+	t.Cleanup(func() {
+		deleteKindCluster(t, clusterName, kubeconfigPath)
+	})
+	t.Logf("Provisioned kind cluster %s after %s", clusterName, time.Since(start))
+	return loadKindCluster(ctx, clusterName)
+}
+
+func loadKindCluster(ctx context.Context, name string) (*kindCluster, error) {
+	kubeconfig, err := runKind(ctx, "get", "kubeconfig", "--name", name)
+	if err != nil {
+		return nil, err
+	}
+	config, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the kubeconfig of kind cluster %s: %w", name, err)
+	}
+	return &kindCluster{
+		name: name,
+		cfg:  clientcmd.NewNonInteractiveClientConfig(*config, config.CurrentContext, nil, nil),
+	}, nil
+}
+
+func deleteKindCluster(t TestingTInterface, name, kubeconfigPath string) {
+	// the test context is likely over when cleaning up
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if _, err := runKind(ctx, "delete", "cluster", "--name", name, "--kubeconfig", kubeconfigPath); err != nil {
+		t.Logf("failed to delete kind cluster %s: %v", name, err)
+	}
+}
+
+func runKind(ctx context.Context, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "kind", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("`kind %s` failed: %w: %s", strings.Join(args, " "), err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
+
+// Config exposes a copy of the client config for this cluster.
+func (c *kindCluster) Config() (*rest.Config, error) {
+	return c.cfg.ClientConfig()
+}
+
+// RawConfig exposes a copy of the client config for this cluster.
+func (c *kindCluster) RawConfig() (clientcmdapi.Config, error) {
+	return c.cfg.RawConfig()
+}
+
+// Kubeconfig serializes the kubeconfig of the server, e.g. for the spec of the Cluster
+// registering it with kcp.
+func Kubeconfig(server RunningServer) (string, error) {
+	config, err := server.RawConfig()
+	if err != nil {
+		return "", err
+	}
+	data, err := clientcmd.Write(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize kubeconfig: %w", err)
+	}
+	return string(data), nil
+}
+
+// IsPhysicalCluster returns whether the server is a physical cluster rather than kcp, whose
+// clients must not set logical clusters.
+func IsPhysicalCluster(server RunningServer) bool {
+	_, ok := server.(*kindCluster)
+	return ok
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// GetFreePort asks the kernel for a free open port that is ready to use.
+func GetFreePort(t TestingTInterface) (string, error) {
+	ports, err := GetFreePorts(t, 1)
+	if err != nil {
+		return "", err
+	}
+	return ports[0], nil
+}
+
+// GetFreePorts asks the kernel for n distinct free open ports that are ready to use. The
+// ports are reserved for the other test processes of the machine until the test finishes.
+func GetFreePorts(t TestingTInterface, n int) ([]string, error) {
+	// Tests run in -parallel will run in separate processes, so we must use the file-system
+	// for sharing state and locking across them to coordinate who gets which port. Without
+	// some mechanism for sharing state, the following race is possible:
+	// - process A calls net.ListenTCP() to resolve a new port
+	// - process A calls l.Close() to close the listener to allow accessory to use it
+	// - process B calls net.ListenTCP() and resolves the same port
+	// - process A attempts to use the port, fails as it is in use
+	// Therefore, holding the listeners open is our kernel-based lock for this process, and
+	// while we hold them open we must record our intent to disk. Holding all of them until
+	// every port is reserved also ensures the kernel does not hand out the same port twice.
+	lockDir := filepath.Join(os.TempDir(), "kcp-e2e-ports")
+	if err := os.MkdirAll(lockDir, 0755); err != nil {
+		return nil, fmt.Errorf("could not create port lockfile dir: %w", err)
+	}
+	var ports []string
+	for len(ports) < n {
+		addr, err := net.ResolveTCPAddr("tcp", "localhost:0")
+		if err != nil {
+			return nil, fmt.Errorf("could not resolve free port: %w", err)
+		}
+
+		l, err := net.ListenTCP("tcp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("could not listen on free port: %w", err)
+		}
+		defer func(c io.Closer) {
+			if err := c.Close(); err != nil {
+				t.Errorf("could not close listener: %v", err)
+			}
+		}(l)
+		port := strconv.Itoa(l.Addr().(*net.TCPAddr).Port)
+		lockFile := filepath.Join(lockDir, port)
+		locked, err := lockPort(lockFile)
+		if err != nil {
+			return nil, err
+		}
+		if !locked {
+			t.Logf("found a previously-seen port, retrying: %s", port)
+			continue
+		}
+		// the lifecycle of an accessory (and thereby its ports) is the test lifecycle
+		t.Cleanup(func() {
+			if err := os.Remove(lockFile); err != nil {
+				t.Errorf("failed to remove port lockfile: %v", err)
+			}
+		})
+		ports = append(ports, port)
+	}
+	return ports, nil
+}
+
+// lockPort records the intent of this process to use a port in the given lockfile, and
+// returns false if another process already did. Creating the lockfile exclusively makes
+// this atomic across processes.
+func lockPort(lockFile string) (bool, error) {
+	f, err := os.OpenFile(lockFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("could not record port lockfile: %w", err)
+	}
+	// the process holding the port helps debugging leaked lockfiles
+	if _, err := fmt.Fprintf(f, "%d\n", os.Getpid()); err != nil {
+		f.Close()
+		return false, fmt.Errorf("could not record port lockfile: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return false, fmt.Errorf("could not close port lockfile: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLockPort(t *testing.T) {
+	lockFile := filepath.Join(t.TempDir(), "6443")
+	locked, err := lockPort(lockFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !locked {
+		t.Fatal("expected the first lock of the port to succeed")
+	}
+	if locked, err = lockPort(lockFile); err != nil {
+		t.Fatal(err)
+	} else if locked {
+		t.Error("expected the port to be locked already")
+	}
+}
+
+func TestGetFreePorts(t *testing.T) {
+	ports, err := GetFreePorts(t, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]bool{}
+	for _, port := range ports {
+		if seen[port] {
+			t.Errorf("port %s was handed out twice", port)
+		}
+		seen[port] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("expected 3 ports, got %v", ports)
+	}
+}
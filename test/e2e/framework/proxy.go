@@ -0,0 +1,194 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// FaultProxy is a TCP proxy injecting faults in the connections to a target, e.g. the
+// latency of the network between shards. Tests point clients at its address in place of
+// the address of the target, e.g. in the base URL of a WorkspaceShard.
+type FaultProxy struct {
+	listener net.Listener
+	target   string
+
+	lock    sync.Mutex
+	latency time.Duration
+	// resumed is closed when the proxy is not paused.
+	resumed chan struct{}
+	conns   map[net.Conn]struct{}
+	closed  bool
+
+	t TestingTInterface
+}
+
+// NewFaultProxy starts a proxy to the target host:port, stopped when the test finishes.
+func NewFaultProxy(t TestingTInterface, target string) (*FaultProxy, error) {
+	port, err := GetFreePort(t)
+	if err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", "localhost:"+port)
+	if err != nil {
+		return nil, fmt.Errorf("could not listen for proxy to %s: %w", target, err)
+	}
+	resumed := make(chan struct{})
+	close(resumed)
+	p := &FaultProxy{
+		listener: listener,
+		target:   target,
+		resumed:  resumed,
+		conns:    map[net.Conn]struct{}{},
+		t:        t,
+	}
+	t.Cleanup(p.close)
+	go p.serve()
+	return p, nil
+}
+
+// Addr is the host:port of the proxy.
+func (p *FaultProxy) Addr() string {
+	return p.listener.Addr().String()
+}
+
+// SetLatency delays all the data going through the proxy, in either direction, by the
+// given duration, until set to zero.
+func (p *FaultProxy) SetLatency(latency time.Duration) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.latency = latency
+}
+
+// Pause holds all the data going through the proxy until it is resumed, as a partition
+// of the network would. Connections are kept open.
+func (p *FaultProxy) Pause() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	select {
+	case <-p.resumed:
+		p.resumed = make(chan struct{})
+	default:
+		// already paused
+	}
+}
+
+// Resume lets the data held by Pause through.
+func (p *FaultProxy) Resume() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	select {
+	case <-p.resumed:
+		// not paused
+	default:
+		close(p.resumed)
+	}
+}
+
+// DropConnections closes the current connections through the proxy, e.g. for clients to
+// reconnect.
+func (p *FaultProxy) DropConnections() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for conn := range p.conns {
+		conn.Close()
+	}
+	p.conns = map[net.Conn]struct{}{}
+}
+
+func (p *FaultProxy) serve() {
+	for {
+		conn, err := p.listener.Accept()
+		if err != nil {
+			p.lock.Lock()
+			closed := p.closed
+			p.lock.Unlock()
+			if !closed {
+				p.t.Errorf("proxy to %s failed to accept connections: %v", p.target, err)
+			}
+			return
+		}
+		go p.proxy(conn)
+	}
+}
+
+func (p *FaultProxy) proxy(conn net.Conn) {
+	upstream, err := net.Dial("tcp", p.target)
+	if err != nil {
+		// the target may be down on purpose, which clients see as a refused connection
+		p.t.Logf("proxy could not reach %s: %v", p.target, err)
+		conn.Close()
+		return
+	}
+	if !p.track(conn, upstream) {
+		return
+	}
+	go p.copy(upstream, conn)
+	p.copy(conn, upstream)
+}
+
+// track records the connections to close them along with the proxy, and returns false if
+// the proxy is closed already.
+func (p *FaultProxy) track(conns ...net.Conn) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.closed {
+		for _, conn := range conns {
+			conn.Close()
+		}
+		return false
+	}
+	for _, conn := range conns {
+		p.conns[conn] = struct{}{}
+	}
+	return true
+}
+
+// copy forwards the data read from src to dst, holding each chunk as long as the faults ask.
+func (p *FaultProxy) copy(dst, src net.Conn) {
+	defer dst.Close()
+	defer src.Close()
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			p.lock.Lock()
+			latency, resumed := p.latency, p.resumed
+			p.lock.Unlock()
+			<-resumed
+			time.Sleep(latency)
+			if _, err := dst.Write(buf[:n]); err != nil {
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (p *FaultProxy) close() {
+	p.lock.Lock()
+	p.closed = true
+	p.lock.Unlock()
+	p.Resume()
+	p.listener.Close()
+	p.DropConnections()
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestFaultProxy(t *testing.T) {
+	// an echo server
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					if _, err := conn.Write(append(scanner.Bytes(), '\n')); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+
+	proxy, err := NewFaultProxy(t, listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := net.Dial("tcp", proxy.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	roundTrip := func() time.Duration {
+		start := time.Now()
+		if _, err := conn.Write([]byte("ping\n")); err != nil {
+			t.Fatal(err)
+		}
+		if line, err := reader.ReadString('\n'); err != nil || line != "ping\n" {
+			t.Fatalf("unexpected echo %q: %v", line, err)
+		}
+		return time.Since(start)
+	}
+
+	roundTrip()
+	proxy.SetLatency(50 * time.Millisecond)
+	// the latency applies in both directions
+	if elapsed := roundTrip(); elapsed < 100*time.Millisecond {
+		t.Errorf("expected a round trip of at least 100ms, took %s", elapsed)
+	}
+	proxy.SetLatency(0)
+
+	proxy.Pause()
+	time.AfterFunc(100*time.Millisecond, proxy.Resume)
+	if elapsed := roundTrip(); elapsed < 100*time.Millisecond {
+		t.Errorf("expected the round trip to wait for the proxy to resume, took %s", elapsed)
+	}
+}
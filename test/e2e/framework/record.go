@@ -0,0 +1,57 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"k8s.io/client-go/rest"
+
+	"github.com/kcp-dev/kcp/pkg/scenario"
+)
+
+// RecordScenariosEnvVar is the environment variable enabling the recording of the API
+// mutations tests perform on each server into scenario scripts, which `kcp replay` replays.
+const RecordScenariosEnvVar = "KCP_E2E_RECORD_SCENARIOS"
+
+// recordScenario returns a recorder for the requests of the test to the named server, if
+// recording is enabled. The script is written to the artifact dir when the test finishes.
+func recordScenario(t TestingTInterface, name, artifactDir string) *scenario.Recorder {
+	if record, _ := strconv.ParseBool(os.Getenv(RecordScenariosEnvVar)); !record {
+		return nil
+	}
+	recorder := scenario.NewRecorder()
+	t.Cleanup(func() {
+		path := filepath.Join(artifactDir, name+".scenario.yaml")
+		if err := recorder.Script().WriteFile(path); err != nil {
+			t.Logf("failed to write the scenario of kcp server %q: %v", name, err)
+			return
+		}
+		t.Logf("Recorded the scenario of kcp server %q in %s.", name, path)
+	})
+	return recorder
+}
+
+// recordConfig returns the config recording its requests with the recorder, if any.
+func recordConfig(config *rest.Config, recorder *scenario.Recorder) *rest.Config {
+	if recorder == nil {
+		return config
+	}
+	return recorder.RecordConfig(config)
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	"github.com/kcp-dev/kcp/pkg/cmd/workspace"
+)
+
+// RootShardName is the name of the root shard of a ShardedFixture.
+const RootShardName = "root"
+
+// ShardedFixture is a root kcp shard and member shards wired together: every shard
+// enables sharding, the root shard delegates requests to the members, and all the shards
+// are registered as WorkspaceShards in the admin logical cluster of the root shard, which
+// runs the workspace controller.
+type ShardedFixture struct {
+	// Members is the number of member shards, named shard-1 to shard-<Members>.
+	Members int
+	// Args are passed to every shard.
+	Args []string
+	// RootArgs are passed to the root shard only, after Args.
+	RootArgs []string
+}
+
+// RunSharded runs the test against the shards of the fixture, as Run does against
+// independent servers. The test gets the root shard first, then the member shards.
+// Since the shards are wired on startup, the test is skipped when running against an
+// external kcp.
+func RunSharded(top *testing.T, name string, f TestFunc, fixture ShardedFixture) {
+	run(top, name, f, fixture.configs(), fixture.start, false)
+}
+
+func (s ShardedFixture) configs() []KcpConfig {
+	rootArgs := append([]string{"--enable-sharding", "--install_workspace_controller"}, s.Args...)
+	cfgs := []KcpConfig{{Name: RootShardName, Args: append(rootArgs, s.RootArgs...)}}
+	for i := 1; i <= s.Members; i++ {
+		cfgs = append(cfgs, KcpConfig{
+			Name: fmt.Sprintf("shard-%d", i),
+			Args: append([]string{"--enable-sharding"}, s.Args...),
+		})
+	}
+	return cfgs
+}
+
+// start starts the member shards, then the root shard delegating to them, and registers
+// all the shards in the root shard.
+func (s ShardedFixture) start(ctx context.Context, t TestingTInterface, servers []*kcpServer) error {
+	root, members := servers[0], servers[1:]
+	if err := startInParallel(ctx, t, members); err != nil {
+		return err
+	}
+	// members write the kubeconfig with which peers reach them once they are started
+	var kubeconfigs []string
+	for _, member := range members {
+		kubeconfigs = append(kubeconfigs, filepath.Join(member.dataDir, "data", "shard.kubeconfig"))
+	}
+	shardKubeconfig, err := mergeShardKubeconfigs(kubeconfigs)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(root.dataDir, "shards.kubeconfig")
+	if err := clientcmd.WriteToFile(*shardKubeconfig, path); err != nil {
+		return fmt.Errorf("failed to write the kubeconfig of the member shards: %w", err)
+	}
+	root.args = append(root.args, "--shard-kubeconfig-file="+path)
+	if err := startInParallel(ctx, t, []*kcpServer{root}); err != nil {
+		return err
+	}
+	return registerShards(ctx, root, servers)
+}
+
+// mergeShardKubeconfigs merges the kubeconfigs written by shards into one holding the
+// context of each shard, named after the address of the shard as the sharding delegation
+// expects.
+func mergeShardKubeconfigs(paths []string) (*clientcmdapi.Config, error) {
+	merged := clientcmdapi.NewConfig()
+	for _, path := range paths {
+		kubeconfig, err := clientcmd.LoadFromFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load shard kubeconfig: %w", err)
+		}
+		for name, context := range kubeconfig.Contexts {
+			cluster, authInfo := kubeconfig.Clusters[context.Cluster], kubeconfig.AuthInfos[context.AuthInfo]
+			if cluster == nil || authInfo == nil {
+				return nil, fmt.Errorf("context %q of shard kubeconfig %s is incomplete", name, path)
+			}
+			if _, exists := merged.Contexts[name]; exists {
+				return nil, fmt.Errorf("several shards are named %q", name)
+			}
+			// the auth infos of all the shards have the same name, so they are renamed too
+			merged.Clusters[name] = cluster
+			merged.AuthInfos[name] = authInfo
+			merged.Contexts[name] = &clientcmdapi.Context{Cluster: name, AuthInfo: name}
+			merged.CurrentContext = name
+		}
+	}
+	return merged, nil
+}
+
+// registerShards creates a WorkspaceShard for each server in the admin logical cluster of
+// the root shard, with the base URL of the server.
+func registerShards(ctx context.Context, root *kcpServer, servers []*kcpServer) error {
+	// the shards are part of the fixture, not of the scenario of the test
+	rawConfig, err := root.RawConfig()
+	if err != nil {
+		return err
+	}
+	config, err := clientcmd.NewNonInteractiveClientConfig(rawConfig, "admin", nil, nil).ClientConfig()
+	if err != nil {
+		return err
+	}
+	client, err := kcpclient.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to construct client for the root shard: %w", err)
+	}
+	for _, server := range servers {
+		rawConfig, err := server.RawConfig()
+		if err != nil {
+			return err
+		}
+		baseURL, _, err := workspace.SplitServer(rawConfig.Clusters["admin"].Server)
+		if err != nil {
+			return err
+		}
+		if _, err := client.TenancyV1alpha1().WorkspaceShards().Create(ctx, &tenancyv1alpha1.WorkspaceShard{
+			ObjectMeta: metav1.ObjectMeta{Name: server.name},
+			Spec:       tenancyv1alpha1.WorkspaceShardSpec{BaseURL: baseURL},
+		}, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("failed to register shard %q: %w", server.name, err)
+		}
+	}
+	return nil
+}
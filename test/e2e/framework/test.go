@@ -23,6 +23,7 @@ import (
 	"testing"
 	"time"
 
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/client-go/rest"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
@@ -44,6 +45,9 @@ type TestFunc func(t TestingTInterface, servers ...RunningServer)
 type KcpConfig struct {
 	Name string
 	Args []string
+	// SeparateEtcd runs the etcd of the server in its own process instead of embedding it,
+	// for tests to pause it. It requires the etcd binary.
+	SeparateEtcd bool
 }
 
 // Run mimics the testing.T.Run function while providing a nice set of concurrency
@@ -67,7 +71,20 @@ type KcpConfig struct {
 // other than the main testing goroutine. Therefore, when more than one routine needs
 // to be able to influence the execution flow (e.g. preempt other routines) we must
 // have the central routine watch for incoming errors from delegate routines.
+//
+// When the ExternalKubeconfigEnvVar environment variable is set, no processes are
+// started: each test runs against the kcp of that kubeconfig instead, every kcp server
+// it asks for being a fresh workspace of that kcp, deleted when the test finishes.
 func Run(top *testing.T, name string, f TestFunc, cfgs ...KcpConfig) {
+	run(top, name, f, cfgs, startInParallel, true)
+}
+
+// startFunc starts the kcp servers of a test, returning once they are all ready.
+type startFunc func(ctx context.Context, t TestingTInterface, servers []*kcpServer) error
+
+// run runs the test against the servers of the configs, started by startServers. Unless
+// supportsExternal is set, the test is skipped when running against an external kcp.
+func run(top *testing.T, name string, f TestFunc, cfgs []KcpConfig, startServers startFunc, supportsExternal bool) {
 	if _, previouslyCalled := seen.LoadOrStore(fmt.Sprintf("%p", top), nil); !previouslyCalled {
 		top.Parallel()
 	}
@@ -81,6 +98,24 @@ func Run(top *testing.T, name string, f TestFunc, cfgs ...KcpConfig) {
 			cancel()
 			return
 		}
+		cfgs, err = uniqueNames(cfgs)
+		if err != nil {
+			cancel()
+			mid.Fatal(err)
+		}
+		external, err := externalKcpFromEnv()
+		if err != nil {
+			cancel()
+			mid.Fatal(err)
+		}
+		if external != nil && !supportsExternal {
+			cancel()
+			mid.Skipf("%s is set, but the test needs to start its own kcp servers", ExternalKubeconfigEnvVar)
+		}
+		if external != nil {
+			runExternal(ctx, cancel, bottom, external, f, cfgs, artifactDir)
+			return
+		}
 		var servers []*kcpServer
 		var runningServers []RunningServer
 		for _, cfg := range cfgs {
@@ -88,6 +123,7 @@ func Run(top *testing.T, name string, f TestFunc, cfgs ...KcpConfig) {
 			if err != nil {
 				mid.Fatal(err)
 			}
+			server.recorder = recordScenario(bottom, cfg.Name, artifactDir)
 			servers = append(servers, server)
 			runningServers = append(runningServers, server)
 		}
@@ -99,25 +135,11 @@ func Run(top *testing.T, name string, f TestFunc, cfgs ...KcpConfig) {
 
 			start := time.Now()
 			t.Log("Starting kcp servers...")
-			// launch kcp servers and ensure they are ready before starting the test
-			wg := sync.WaitGroup{}
-			wg.Add(len(servers))
-			for _, srv := range servers {
-				// binding the server to ctx ensures its lifetime is only
-				// as long as the test we are running in this specific case
-				if err := srv.Run(ctx); err != nil {
-					t.Error(err)
-					wg.Done()
-				} else {
-					go func(s *kcpServer) {
-						defer wg.Done()
-						if err := s.Ready(); err != nil {
-							t.Errorf("kcp server never became ready: %v", err)
-						}
-					}(srv)
-				}
+			// binding the servers to ctx ensures their lifetime is only
+			// as long as the test we are running in this specific case
+			if err := startServers(ctx, t, servers); err != nil {
+				t.Error(err)
 			}
-			wg.Wait()
 
 			// if we've failed during startup, don't bother running the test
 			if t.Failed() {
@@ -132,3 +154,77 @@ func Run(top *testing.T, name string, f TestFunc, cfgs ...KcpConfig) {
 		bottom.Wait()
 	})
 }
+
+// startInParallel launches the kcp servers in parallel and ensures they are all ready.
+func startInParallel(ctx context.Context, t TestingTInterface, servers []*kcpServer) error {
+	start := time.Now()
+	var lock sync.Mutex
+	var errs []error
+	wg := sync.WaitGroup{}
+	wg.Add(len(servers))
+	for _, srv := range servers {
+		go func(s *kcpServer) {
+			defer wg.Done()
+			err := s.Run(ctx)
+			if err == nil {
+				if err = s.Ready(); err != nil {
+					err = fmt.Errorf("kcp server %q never became ready: %w", s.name, err)
+				}
+			}
+			if err != nil {
+				lock.Lock()
+				errs = append(errs, err)
+				lock.Unlock()
+				return
+			}
+			t.Logf("kcp server %q ready after %s", s.name, time.Since(start))
+		}(srv)
+	}
+	wg.Wait()
+	return utilerrors.NewAggregate(errs)
+}
+
+// runExternal runs the test against the external kcp, with a workspace per server the
+// test asks for.
+func runExternal(ctx context.Context, cancel context.CancelFunc, bottom *T, external *externalKcp, f TestFunc, cfgs []KcpConfig, artifactDir string) {
+	if err := external.ready(ctx); err != nil {
+		cancel()
+		bottom.T.Fatal(err)
+	}
+	var runningServers []RunningServer
+	for _, cfg := range cfgs {
+		server, err := external.newServer(ctx, bottom, cfg)
+		if err != nil {
+			cancel()
+			bottom.T.Fatal(err)
+		}
+		server.recorder = recordScenario(bottom, cfg.Name, artifactDir)
+		runningServers = append(runningServers, server)
+	}
+
+	go func(t TestingTInterface) {
+		defer func() { cancel() }() // stop waiting for errors
+		f(t, runningServers...)
+	}(bottom)
+
+	bottom.Wait()
+}
+
+// uniqueNames returns the configs with a name for each, defaulting to its index, and
+// fails if several configs share a name, since the state and artifacts of the servers
+// are stored under their names.
+func uniqueNames(cfgs []KcpConfig) ([]KcpConfig, error) {
+	named := make([]KcpConfig, 0, len(cfgs))
+	seen := map[string]bool{}
+	for i, cfg := range cfgs {
+		if cfg.Name == "" {
+			cfg.Name = fmt.Sprintf("kcp-%d", i)
+		}
+		if seen[cfg.Name] {
+			return nil, fmt.Errorf("several kcp servers are named %q", cfg.Name)
+		}
+		seen[cfg.Name] = true
+		named = append(named, cfg)
+	}
+	return named, nil
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// Condition checks whether something tests wait for happened, returning why not otherwise.
+// Errors end the wait.
+type Condition func(ctx context.Context) (done bool, reason string, err error)
+
+// Eventually polls the condition at the interval until it is done, it fails or the timeout
+// expires, in which case the error carries the last reason the condition was not done.
+func Eventually(ctx context.Context, condition Condition, interval, timeout time.Duration) error {
+	var reason string
+	err := wait.PollImmediate(interval, timeout, func() (bool, error) {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		done, why, err := condition(ctx)
+		reason = why
+		return done, err
+	})
+	if err == wait.ErrWaitTimeout {
+		return fmt.Errorf("timed out after %s waiting for condition: %s", timeout, reason)
+	}
+	return err
+}
+
+// Getter gets an object from any client, e.g. a closure around the Get of a typed client.
+type Getter func(ctx context.Context) (runtime.Object, error)
+
+// EventuallyMatches polls the object returned by the getter until it matches, returning the
+// last object seen. Errors getting the object count as the object not matching, so that
+// tests can wait for objects to be created.
+func EventuallyMatches(ctx context.Context, get Getter, matcher Matcher, interval, timeout time.Duration) (runtime.Object, error) {
+	var object runtime.Object
+	err := Eventually(ctx, func(ctx context.Context) (bool, string, error) {
+		var err error
+		if object, err = get(ctx); err != nil {
+			return false, err.Error(), nil
+		}
+		if err := matcher(object); err != nil {
+			return false, err.Error(), nil
+		}
+		return true, "", nil
+	}, interval, timeout)
+	return object, err
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1client "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/typed/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/kcp-dev/kcp/config"
+	clusterv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/cluster/v1alpha1"
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+)
+
+// InstallCustomResourceDefinitions installs the CRDs of the given group and kinds, read from
+// the <group>_<kind>.yaml files of the file system, and waits for them to be established.
+func InstallCustomResourceDefinitions(ctx context.Context, client apiextensionsv1client.CustomResourceDefinitionInterface, fs embed.FS, group string, kinds ...string) error {
+	var errs []error
+	for _, kind := range kinds {
+		if err := config.BootstrapCustomResourceDefinitionFromFS(ctx, client, metav1.GroupKind{Group: group, Kind: kind}, fs); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := kerrors.NewAggregate(errs); err != nil {
+		return fmt.Errorf("could not bootstrap CRDs: %w", err)
+	}
+	return nil
+}
+
+// InstallNamespace creates the namespace, unless it already exists.
+func InstallNamespace(ctx context.Context, client kubernetes.Interface, name string) error {
+	_, err := client.CoreV1().Namespaces().Create(ctx, &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+	}, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		return nil
+	}
+	return err
+}
+
+// InstallCluster registers the physical cluster of the kubeconfig as a Cluster of the given
+// name, and waits for it to be ready.
+func InstallCluster(ctx context.Context, client kcpclient.Interface, name, kubeconfig string, timeout time.Duration) (*clusterv1alpha1.Cluster, error) {
+	_, err := client.ClusterV1alpha1().Clusters().Create(ctx, &clusterv1alpha1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       clusterv1alpha1.ClusterSpec{KubeConfig: kubeconfig},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cluster %s: %w", name, err)
+	}
+	object, err := EventuallyMatches(ctx, func(ctx context.Context) (runtime.Object, error) {
+		return client.ClusterV1alpha1().Clusters().Get(ctx, name, metav1.GetOptions{})
+	}, ClusterReady(), 100*time.Millisecond, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wait for cluster %s to be ready: %w", name, err)
+	}
+	return object.(*clusterv1alpha1.Cluster), nil
+}
+
+// ClusterReady matches Clusters whose Ready condition is true.
+func ClusterReady() Matcher {
+	return OfType(&clusterv1alpha1.Cluster{}, func(object runtime.Object) error {
+		cluster := object.(*clusterv1alpha1.Cluster)
+		if !cluster.Status.Conditions.IsTrue(clusterv1alpha1.ClusterConditionReady) {
+			return fmt.Errorf("cluster %s is not ready, status.conditions: %#v", cluster.Name, cluster.Status.Conditions)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package testing holds helpers for tests of controllers running against kcp: expectations
+// about the events of watches, polling for conditions, and fixtures installing the objects
+// tests commonly need.
+package testing
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// Matcher checks an object, returning why it does not match.
+type Matcher func(object runtime.Object) error
+
+// ExactMatcher matches objects semantically equal to the expected one.
+func ExactMatcher(expected runtime.Object) Matcher {
+	return func(object runtime.Object) error {
+		if !apiequality.Semantic.DeepEqual(expected, object) {
+			return fmt.Errorf("incorrect object: %v", cmp.Diff(expected, object))
+		}
+		return nil
+	}
+}
+
+// AllOf matches objects matched by all the given matchers.
+func AllOf(matchers ...Matcher) Matcher {
+	return func(object runtime.Object) error {
+		for _, matcher := range matchers {
+			if err := matcher(object); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// AnyObject matches all objects.
+func AnyObject() Matcher {
+	return func(runtime.Object) error {
+		return nil
+	}
+}
+
+// NextEvent returns the next event of the watch, failing if none comes within the duration.
+func NextEvent(w watch.Interface, duration time.Duration) (watch.Event, error) {
+	stopTimer := time.NewTimer(duration)
+	defer stopTimer.Stop()
+	select {
+	case event, ok := <-w.ResultChan():
+		if !ok {
+			return watch.Event{}, errors.New("watch closed unexpectedly")
+		}
+		return event, nil
+	case <-stopTimer.C:
+		return watch.Event{}, errors.New("timed out waiting for event")
+	}
+}
+
+// IgnoreNextEvent waits for the next event of the watch, whatever it is.
+func IgnoreNextEvent(w watch.Interface, duration time.Duration) error {
+	_, err := NextEvent(w, duration)
+	return err
+}
+
+// ExpectNextEvent expects the next event of the watch to be of the given type, with an
+// object matching the matcher. The object is returned whenever the event came, even if it
+// does not match.
+func ExpectNextEvent(w watch.Interface, expectType watch.EventType, matcher Matcher, duration time.Duration) (runtime.Object, error) {
+	event, err := NextEvent(w, duration)
+	if err != nil {
+		return nil, err
+	}
+	if expectType != event.Type {
+		return nil, fmt.Errorf("got incorrect watch event type: %v != %v", expectType, event.Type)
+	}
+	if err := matcher(event.Object); err != nil {
+		return event.Object, err
+	}
+	return event.Object, nil
+}
+
+// ExpectNextEventInto is ExpectNextEvent for watches of a single type, storing the object of
+// the event into into, a pointer to an object of that type, so that callers get typed objects.
+func ExpectNextEventInto(w watch.Interface, expectType watch.EventType, matcher Matcher, duration time.Duration, into runtime.Object) error {
+	object, err := ExpectNextEvent(w, expectType, OfType(into, matcher), duration)
+	if object != nil && reflect.TypeOf(object) == reflect.TypeOf(into) {
+		reflect.ValueOf(into).Elem().Set(reflect.ValueOf(object).Elem())
+	}
+	return err
+}
+
+// OfType matches objects of the type of the prototype which the matcher matches.
+func OfType(prototype runtime.Object, matcher Matcher) Matcher {
+	return func(object runtime.Object) error {
+		if reflect.TypeOf(object) != reflect.TypeOf(prototype) {
+			return fmt.Errorf("got %T, not a %T", object, prototype)
+		}
+		return matcher(object)
+	}
+}
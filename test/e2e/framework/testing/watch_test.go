@@ -0,0 +1,80 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func TestExpectNextEventInto(t *testing.T) {
+	w := watch.NewFakeWithChanSize(2, false)
+	expected := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}}
+	w.Add(expected)
+	w.Add(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "default"}})
+
+	namespace := &corev1.Namespace{}
+	if err := ExpectNextEventInto(w, watch.Added, ExactMatcher(expected), time.Second, namespace); err != nil {
+		t.Fatal(err)
+	}
+	if namespace.Name != "default" {
+		t.Errorf("expected the namespace of the event, got %#v", namespace)
+	}
+	if err := ExpectNextEventInto(w, watch.Added, AnyObject(), time.Second, namespace); err == nil {
+		t.Error("expected a config map not to match a namespace")
+	}
+	if _, err := ExpectNextEvent(w, watch.Added, AnyObject(), 10*time.Millisecond); err == nil {
+		t.Error("expected to time out without events")
+	}
+}
+
+func TestEventuallyMatches(t *testing.T) {
+	attempts := 0
+	get := func(ctx context.Context) (runtime.Object, error) {
+		attempts++
+		if attempts < 3 {
+			return nil, errors.New("not found")
+		}
+		return &corev1.Namespace{Status: corev1.NamespaceStatus{Phase: corev1.NamespaceActive}}, nil
+	}
+	active := OfType(&corev1.Namespace{}, func(object runtime.Object) error {
+		if phase := object.(*corev1.Namespace).Status.Phase; phase != corev1.NamespaceActive {
+			return errors.New("not active")
+		}
+		return nil
+	})
+	if _, err := EventuallyMatches(context.Background(), get, active, time.Millisecond, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+
+	_, err := EventuallyMatches(context.Background(), func(ctx context.Context) (runtime.Object, error) {
+		return nil, errors.New("not found")
+	}, active, time.Millisecond, 10*time.Millisecond)
+	if err == nil || err.Error() != "timed out after 10ms waiting for condition: not found" {
+		t.Errorf("expected a timeout with the last reason, got %v", err)
+	}
+}
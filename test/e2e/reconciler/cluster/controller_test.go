@@ -30,20 +30,17 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	apiextensionsclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	apiextensionsv1client "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/typed/apiextensions/v1"
-	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	kerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/watch"
 	kubernetesclientset "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/clientcmd"
 
-	"github.com/kcp-dev/kcp/config"
-	clusterv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/cluster/v1alpha1"
 	kcpclientset "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
 	"github.com/kcp-dev/kcp/test/e2e/framework"
+	e2etesting "github.com/kcp-dev/kcp/test/e2e/framework/testing"
 	"github.com/kcp-dev/kcp/test/e2e/reconciler/cluster/apis/wildwest"
 	wildwestv1alpha1 "github.com/kcp-dev/kcp/test/e2e/reconciler/cluster/apis/wildwest/v1alpha1"
 	wildwestclientset "github.com/kcp-dev/kcp/test/e2e/reconciler/cluster/client/clientset/versioned"
@@ -56,178 +53,121 @@ var rawCustomResourceDefinitions embed.FS
 const testNamespace = "cluster-controller-test"
 const clusterName = "us-east1"
 
-func TestClusterController(t *testing.T) {
-	var testCases = []struct {
-		name string
-		work func(ctx context.Context, t framework.TestingTInterface, sourceClient, sinkClient wildwestclient.CowboyInterface, sourceWatcher, sinkWatcher watch.Interface)
-	}{
-		{
-			name: "create an object, expect spec to sync to sink",
-			work: func(ctx context.Context, t framework.TestingTInterface, sourceClient, sinkClient wildwestclient.CowboyInterface, sourceWatcher, sinkWatcher watch.Interface) {
-				cowboy, err := sourceClient.Create(ctx, &wildwestv1alpha1.Cowboy{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "timothy",
-						Labels: map[string]string{
-							"kcp.dev/cluster": clusterName,
-						},
+type workFunc func(ctx context.Context, t framework.TestingTInterface, sourceClient, sinkClient wildwestclient.CowboyInterface, sourceWatcher, sinkWatcher watch.Interface)
+
+var testCases = []struct {
+	name string
+	work workFunc
+}{
+	{
+		name: "create an object, expect spec to sync to sink",
+		work: func(ctx context.Context, t framework.TestingTInterface, sourceClient, sinkClient wildwestclient.CowboyInterface, sourceWatcher, sinkWatcher watch.Interface) {
+			cowboy, err := sourceClient.Create(ctx, &wildwestv1alpha1.Cowboy{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "timothy",
+					Labels: map[string]string{
+						"kcp.dev/cluster": clusterName,
 					},
-					Spec: wildwestv1alpha1.CowboySpec{Intent: "yeehaw"},
-				}, metav1.CreateOptions{})
-				if err != nil {
-					t.Errorf("failed to create cowboy: %v", err)
-					return
+				},
+				Spec: wildwestv1alpha1.CowboySpec{Intent: "yeehaw"},
+			}, metav1.CreateOptions{})
+			if err != nil {
+				t.Errorf("failed to create cowboy: %v", err)
+				return
+			}
+			if _, err := e2etesting.ExpectNextEvent(sourceWatcher, watch.Added, e2etesting.ExactMatcher(cowboy), 30*time.Second); err != nil {
+				t.Errorf("did not see cowboy created: %v", err)
+				return
+			}
+			if _, err := e2etesting.ExpectNextEvent(sinkWatcher, watch.Added, cowboyMatcher(func(object *wildwestv1alpha1.Cowboy) error {
+				if expected, actual := cowboy.ObjectMeta.Namespace, object.ObjectMeta.Namespace; expected != actual {
+					return fmt.Errorf("saw incorrect namespace, expected %s, saw %s", expected, actual)
 				}
-				if _, err := expectNextEvent(sourceWatcher, watch.Added, exactMatcher(cowboy), 30*time.Second); err != nil {
-					t.Errorf("did not see cowboy created: %v", err)
-					return
+				if expected, actual := cowboy.ObjectMeta.Name, object.ObjectMeta.Name; expected != actual {
+					return fmt.Errorf("saw incorrect name, expected %s, saw %s", expected, actual)
 				}
-				if _, err := expectNextEvent(sinkWatcher, watch.Added, func(object *wildwestv1alpha1.Cowboy) error {
-					if expected, actual := cowboy.ObjectMeta.Namespace, object.ObjectMeta.Namespace; expected != actual {
-						return fmt.Errorf("saw incorrect namespace, expected %s, saw %s", expected, actual)
-					}
-					if expected, actual := cowboy.ObjectMeta.Name, object.ObjectMeta.Name; expected != actual {
-						return fmt.Errorf("saw incorrect name, expected %s, saw %s", expected, actual)
-					}
-					if diff := cmp.Diff(cowboy.Spec, object.Spec); diff != "" {
-						return fmt.Errorf("saw incorrect spec on sink cluster: %s", diff)
-					}
-					return nil
-				}, 30*time.Second); err != nil {
-					t.Errorf("did not see cowboy spec updated on sink cluster: %v", err)
-					data, err := sinkClient.List(ctx, metav1.ListOptions{})
-					t.Errorf("%#v", data)
-					t.Errorf("%#v", err)
-					return
+				if diff := cmp.Diff(cowboy.Spec, object.Spec); diff != "" {
+					return fmt.Errorf("saw incorrect spec on sink cluster: %s", diff)
 				}
-			},
+				return nil
+			}), 30*time.Second); err != nil {
+				t.Errorf("did not see cowboy spec updated on sink cluster: %v", err)
+				data, err := sinkClient.List(ctx, metav1.ListOptions{})
+				t.Errorf("%#v", data)
+				t.Errorf("%#v", err)
+				return
+			}
 		},
-		{
-			name: "update a synced object, expect status to sync to source",
-			work: func(ctx context.Context, t framework.TestingTInterface, sourceClient, sinkClient wildwestclient.CowboyInterface, sourceWatcher, sinkWatcher watch.Interface) {
-				cowboy, err := sourceClient.Create(ctx, &wildwestv1alpha1.Cowboy{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "timothy",
-						Labels: map[string]string{
-							"kcp.dev/cluster": clusterName,
-						},
+	},
+	{
+		name: "update a synced object, expect status to sync to source",
+		work: func(ctx context.Context, t framework.TestingTInterface, sourceClient, sinkClient wildwestclient.CowboyInterface, sourceWatcher, sinkWatcher watch.Interface) {
+			cowboy, err := sourceClient.Create(ctx, &wildwestv1alpha1.Cowboy{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "timothy",
+					Labels: map[string]string{
+						"kcp.dev/cluster": clusterName,
 					},
-					Spec: wildwestv1alpha1.CowboySpec{Intent: "yeehaw"},
-				}, metav1.CreateOptions{})
-				if err != nil {
-					t.Errorf("failed to create cowboy: %v", err)
-					return
-				}
-				// the sync happens and we don't care to validate it in this test case
-				if err := ignoreNextEvent(sourceWatcher, 30*time.Second); err != nil {
-					t.Errorf("error ignoring source event when watching cowboys: %v", err)
-					return
-				}
-				if err := ignoreNextEvent(sinkWatcher, 30*time.Second); err != nil {
-					t.Errorf("error ignoring sink event when watching cowboys: %v", err)
-					return
-				}
-				updated, err := sinkClient.Patch(ctx, cowboy.Name, types.MergePatchType, []byte(`{"status":{"result":"giddyup"}}`), metav1.PatchOptions{}, "status")
-				if err != nil {
-					t.Errorf("failed to patch cowboy: %v", err)
-					return
-				}
-				if _, err := expectNextEvent(sinkWatcher, watch.Modified, exactMatcher(updated), 30*time.Second); err != nil {
-					t.Errorf("did not see cowboy patched: %v", err)
-					return
-				}
-				if _, err := expectNextEvent(sourceWatcher, watch.Modified, func(object *wildwestv1alpha1.Cowboy) error {
-					if expected, actual := cowboy.ObjectMeta.Namespace, object.ObjectMeta.Namespace; expected != actual {
-						return fmt.Errorf("saw incorrect namespace, expected %s, saw %s", expected, actual)
-					}
-					if expected, actual := cowboy.ObjectMeta.Name, object.ObjectMeta.Name; expected != actual {
-						return fmt.Errorf("saw incorrect name, expected %s, saw %s", expected, actual)
-					}
-					if diff := cmp.Diff(updated.Status, object.Status); diff != "" {
-						return fmt.Errorf("saw incorrect status on source cluster: %s", diff)
-					}
-					return nil
-				}, 30*time.Second); err != nil {
-					t.Errorf("did not see cowboy status updated on source cluster: %v", err)
-					return
-				}
-			},
-		},
-	}
-	for i := range testCases {
-		testCase := testCases[i]
-		framework.Run(t, testCase.name, func(t framework.TestingTInterface, servers ...framework.RunningServer) {
-			start := time.Now()
-			ctx := context.Background()
-			if deadline, ok := t.Deadline(); ok {
-				withDeadline, cancel := context.WithDeadline(ctx, deadline)
-				t.Cleanup(cancel)
-				ctx = withDeadline
+				},
+				Spec: wildwestv1alpha1.CowboySpec{Intent: "yeehaw"},
+			}, metav1.CreateOptions{})
+			if err != nil {
+				t.Errorf("failed to create cowboy: %v", err)
+				return
 			}
-			if len(servers) != 2 {
-				t.Errorf("incorrect number of servers: %d", len(servers))
+			// the sync happens and we don't care to validate it in this test case
+			if err := e2etesting.IgnoreNextEvent(sourceWatcher, 30*time.Second); err != nil {
+				t.Errorf("error ignoring source event when watching cowboys: %v", err)
 				return
 			}
-			t.Log("Installing test CRDs...")
-			if err := installCrd(ctx, servers...); err != nil {
-				t.Error(err)
+			if err := e2etesting.IgnoreNextEvent(sinkWatcher, 30*time.Second); err != nil {
+				t.Errorf("error ignoring sink event when watching cowboys: %v", err)
 				return
 			}
-			t.Logf("Installed test CRDs after %s", time.Since(start))
-			start = time.Now()
-			source, sink := servers[0], servers[1]
-			t.Log("Installing sink cluster...")
-			if err := installCluster(ctx, source, sink); err != nil {
-				t.Error(err)
+			updated, err := sinkClient.Patch(ctx, cowboy.Name, types.MergePatchType, []byte(`{"status":{"result":"giddyup"}}`), metav1.PatchOptions{}, "status")
+			if err != nil {
+				t.Errorf("failed to patch cowboy: %v", err)
 				return
 			}
-			t.Logf("Installed sink cluster after %s", time.Since(start))
-			start = time.Now()
-			t.Log("Setting up clients for test...")
-			if err := installNamespace(ctx, source); err != nil {
-				t.Error(err)
+			if _, err := e2etesting.ExpectNextEvent(sinkWatcher, watch.Modified, e2etesting.ExactMatcher(updated), 30*time.Second); err != nil {
+				t.Errorf("did not see cowboy patched: %v", err)
 				return
 			}
-			var clients []wildwestclient.CowboyInterface
-			var watchers []watch.Interface
-			for _, server := range servers {
-				cfg, err := server.Config()
-				if err != nil {
-					t.Error(err)
-					return
+			if _, err := e2etesting.ExpectNextEvent(sourceWatcher, watch.Modified, cowboyMatcher(func(object *wildwestv1alpha1.Cowboy) error {
+				if expected, actual := cowboy.ObjectMeta.Namespace, object.ObjectMeta.Namespace; expected != actual {
+					return fmt.Errorf("saw incorrect namespace, expected %s, saw %s", expected, actual)
 				}
-				clusterName, err := detectClusterName(cfg, ctx, "cowboys.wildwest.dev")
-				if err != nil {
-					t.Errorf("failed to detect cluster name: %v", err)
-					return
-				}
-				wildwestClients, err := wildwestclientset.NewClusterForConfig(cfg)
-				if err != nil {
-					t.Errorf("failed to construct client for server: %v", err)
-					return
+				if expected, actual := cowboy.ObjectMeta.Name, object.ObjectMeta.Name; expected != actual {
+					return fmt.Errorf("saw incorrect name, expected %s, saw %s", expected, actual)
 				}
-				wildwestClient := wildwestClients.Cluster(clusterName)
-				watcher, err := wildwestClient.WildwestV1alpha1().Cowboys(corev1.NamespaceAll).Watch(ctx, metav1.ListOptions{})
-				if err != nil {
-					t.Errorf("failed to start watching cowboys: %v", err)
-					return
+				if diff := cmp.Diff(updated.Status, object.Status); diff != "" {
+					return fmt.Errorf("saw incorrect status on source cluster: %s", diff)
 				}
-				clients = append(clients, wildwestClient.WildwestV1alpha1().Cowboys(testNamespace))
-				watchers = append(watchers, watcher)
+				return nil
+			}), 30*time.Second); err != nil {
+				t.Errorf("did not see cowboy status updated on source cluster: %v", err)
+				return
 			}
-			t.Logf("Set up clients for test after %s", time.Since(start))
-			t.Log("Starting test...")
-			testCase.work(ctx, t, clients[0], clients[1], watchers[0], watchers[1])
 		},
-			// this is the host kcp cluster from which we sync spec
-			framework.KcpConfig{
-				Name: "source",
-				Args: []string{
-					"--push_mode",
-					"--install_cluster_controller",
-					"--resources_to_sync=cowboys.wildwest.dev",
-					"--auto_publish_apis",
-				},
-			},
+	},
+}
+
+// sourceConfig is the host kcp cluster from which we sync spec
+var sourceConfig = framework.KcpConfig{
+	Name: "source",
+	Args: []string{
+		"--push_mode",
+		"--install_cluster_controller",
+		"--resources_to_sync=cowboys.wildwest.dev",
+		"--auto_publish_apis",
+	},
+}
+
+func TestClusterController(t *testing.T) {
+	for i := range testCases {
+		testCase := testCases[i]
+		framework.Run(t, testCase.name, clusterControllerTest(testCase.work, nil),
+			sourceConfig,
 			// this is a kcp acting as a target cluster to sync status from
 			framework.KcpConfig{
 				Name: "sink",
@@ -237,6 +177,99 @@ func TestClusterController(t *testing.T) {
 	}
 }
 
+// TestClusterControllerWithKind runs the test cases against a physical cluster provisioned
+// with kind as the sink.
+func TestClusterControllerWithKind(t *testing.T) {
+	if !framework.KindAvailable() {
+		t.Skip("kind is not installed")
+	}
+	for i := range testCases {
+		testCase := testCases[i]
+		framework.Run(t, testCase.name, clusterControllerTest(testCase.work, func(ctx context.Context, t framework.TestingTInterface) (framework.RunningServer, error) {
+			return framework.NewKindCluster(ctx, t, "sink")
+		}), sourceConfig)
+	}
+}
+
+// clusterControllerTest sets up the source kcp, and the sink cluster, which is the second
+// server unless newSink provisions it, before running the test case.
+func clusterControllerTest(work workFunc, newSink func(ctx context.Context, t framework.TestingTInterface) (framework.RunningServer, error)) framework.TestFunc {
+	return func(t framework.TestingTInterface, servers ...framework.RunningServer) {
+		start := time.Now()
+		ctx := context.Background()
+		if deadline, ok := t.Deadline(); ok {
+			withDeadline, cancel := context.WithDeadline(ctx, deadline)
+			t.Cleanup(cancel)
+			ctx = withDeadline
+		}
+		if newSink != nil {
+			t.Log("Provisioning sink cluster...")
+			sink, err := newSink(ctx, t)
+			if err != nil {
+				t.Errorf("failed to provision sink cluster: %v", err)
+				return
+			}
+			servers = append(servers, sink)
+		}
+		if len(servers) != 2 {
+			t.Errorf("incorrect number of servers: %d", len(servers))
+			return
+		}
+		t.Log("Installing test CRDs...")
+		if err := installCrd(ctx, servers...); err != nil {
+			t.Error(err)
+			return
+		}
+		t.Logf("Installed test CRDs after %s", time.Since(start))
+		start = time.Now()
+		source, sink := servers[0], servers[1]
+		t.Log("Installing sink cluster...")
+		if err := installCluster(ctx, source, sink); err != nil {
+			t.Error(err)
+			return
+		}
+		t.Logf("Installed sink cluster after %s", time.Since(start))
+		start = time.Now()
+		t.Log("Setting up clients for test...")
+		if err := installNamespace(ctx, source); err != nil {
+			t.Error(err)
+			return
+		}
+		var clients []wildwestclient.CowboyInterface
+		var watchers []watch.Interface
+		for _, server := range servers {
+			cfg, err := server.Config()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			var clusterName string
+			if !framework.IsPhysicalCluster(server) {
+				if clusterName, err = detectClusterName(cfg, ctx, "cowboys.wildwest.dev"); err != nil {
+					t.Errorf("failed to detect cluster name: %v", err)
+					return
+				}
+			}
+			wildwestClients, err := wildwestclientset.NewClusterForConfig(cfg)
+			if err != nil {
+				t.Errorf("failed to construct client for server: %v", err)
+				return
+			}
+			wildwestClient := wildwestClients.Cluster(clusterName)
+			watcher, err := wildwestClient.WildwestV1alpha1().Cowboys(corev1.NamespaceAll).Watch(ctx, metav1.ListOptions{})
+			if err != nil {
+				t.Errorf("failed to start watching cowboys: %v", err)
+				return
+			}
+			clients = append(clients, wildwestClient.WildwestV1alpha1().Cowboys(testNamespace))
+			watchers = append(watchers, watcher)
+		}
+		t.Logf("Set up clients for test after %s", time.Since(start))
+		t.Log("Starting test...")
+		work(ctx, t, clients[0], clients[1], watchers[0], watchers[1])
+	}
+}
+
 func installNamespace(ctx context.Context, server framework.RunningServer) error {
 	cfg, err := server.Config()
 	if err != nil {
@@ -250,11 +283,7 @@ func installNamespace(ctx context.Context, server framework.RunningServer) error
 	if err != nil {
 		return fmt.Errorf("failed to construct client for server: %w", err)
 	}
-	client := clients.Cluster(clusterName)
-	_, err = client.CoreV1().Namespaces().Create(ctx, &corev1.Namespace{
-		ObjectMeta: metav1.ObjectMeta{Name: testNamespace},
-	}, metav1.CreateOptions{})
-	return err
+	return e2etesting.InstallNamespace(ctx, clients.Cluster(clusterName), testNamespace)
 }
 
 func installCrd(ctx context.Context, servers ...framework.RunningServer) error {
@@ -274,10 +303,7 @@ func installCrd(ctx context.Context, servers ...framework.RunningServer) error {
 				bootstrapErrChan <- fmt.Errorf("failed to construct client for server: %w", err)
 				return
 			}
-			bootstrapErrChan <- config.BootstrapCustomResourceDefinitionFromFS(ctx, crdClient.CustomResourceDefinitions(), metav1.GroupKind{
-				Group: wildwest.GroupName,
-				Kind:  "cowboys",
-			}, rawCustomResourceDefinitions)
+			bootstrapErrChan <- e2etesting.InstallCustomResourceDefinitions(ctx, crdClient.CustomResourceDefinitions(), rawCustomResourceDefinitions, wildwest.GroupName, "cowboys")
 		}(server)
 	}
 	wg.Wait()
@@ -286,10 +312,7 @@ func installCrd(ctx context.Context, servers ...framework.RunningServer) error {
 	for err := range bootstrapErrChan {
 		bootstrapErrors = append(bootstrapErrors, err)
 	}
-	if err := kerrors.NewAggregate(bootstrapErrors); err != nil {
-		return fmt.Errorf("could not bootstrap CRDs: %w", err)
-	}
-	return nil
+	return kerrors.NewAggregate(bootstrapErrors)
 }
 
 func installCluster(ctx context.Context, source, sink framework.RunningServer) error {
@@ -297,7 +320,7 @@ func installCluster(ctx context.Context, source, sink framework.RunningServer) e
 	if err != nil {
 		return fmt.Errorf("failed to get source config: %w", err)
 	}
-	rawSinkCfg, err := sink.RawConfig()
+	sinkKubeconfig, err := framework.Kubeconfig(sink)
 	if err != nil {
 		return fmt.Errorf("failed to get sink config: %w", err)
 	}
@@ -309,62 +332,16 @@ func installCluster(ctx context.Context, source, sink framework.RunningServer) e
 	if err != nil {
 		return fmt.Errorf("failed to construct client for server: %w", err)
 	}
-	rawSinkCfgBytes, err := clientcmd.Write(rawSinkCfg)
-	if err != nil {
-		return fmt.Errorf("failed to serialize sink config: %w", err)
-	}
-	sourceKcpClient := sourceKcpClients.Cluster(sourceClusterName)
-	cluster, err := sourceKcpClient.ClusterV1alpha1().Clusters().Create(ctx, &clusterv1alpha1.Cluster{
-		ObjectMeta: metav1.ObjectMeta{Name: clusterName},
-		Spec:       clusterv1alpha1.ClusterSpec{KubeConfig: string(rawSinkCfgBytes)},
-	}, metav1.CreateOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to create cluster on source kcp: %w", err)
-	}
-	waitCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
-	defer func() {
-		cancel()
-	}()
-	watcher, err := sourceKcpClient.ClusterV1alpha1().Clusters().Watch(ctx, metav1.ListOptions{
-		FieldSelector: fields.OneTermEqualSelector("metadata.name", cluster.Name).String(),
-	})
-	if err != nil {
-		return fmt.Errorf("failed to watch cluster in source kcp: %w", err)
-	}
-	for {
-		select {
-		case <-waitCtx.Done():
-			return fmt.Errorf("failed to wait for cluster in source kcp to be ready: %w", waitCtx.Err())
-		case event := <-watcher.ResultChan():
-			switch event.Type {
-			case watch.Added, watch.Bookmark:
-				continue
-			case watch.Modified:
-				updated, ok := event.Object.(*clusterv1alpha1.Cluster)
-				if !ok {
-					continue
-				}
-				var ready bool
-				for _, condition := range updated.Status.Conditions {
-					if condition.Type == clusterv1alpha1.ClusterConditionReady && condition.Status == corev1.ConditionTrue {
-						ready = true
-						break
-					}
-				}
-				if ready {
-					return nil
-				}
-			case watch.Deleted:
-				return fmt.Errorf("cluster %s was deleted before being ready", cluster.Name)
-			case watch.Error:
-				return fmt.Errorf("encountered error while watching cluster %s: %#v", cluster.Name, event.Object)
-			}
-		}
-	}
+	_, err = e2etesting.InstallCluster(ctx, sourceKcpClients.Cluster(sourceClusterName), clusterName, sinkKubeconfig, 30*time.Second)
+	return err
 }
 
 // TODO: we need to undo the prefixing and get normal sharding behavior in soon ... ?
 func detectClusterName(cfg *rest.Config, ctx context.Context, crdName string) (string, error) {
+	if framework.ScopedToWorkspace(cfg) {
+		// the config already points at the workspace of the test
+		return "", nil
+	}
 	crdClient, err := apiextensionsclientset.NewClusterForConfig(cfg)
 	if err != nil {
 		return "", fmt.Errorf("failed to construct client for server: %w", err)
@@ -384,50 +361,9 @@ func detectClusterName(cfg *rest.Config, ctx context.Context, crdName string) (s
 	return "", errors.New("detected no admin cluster")
 }
 
-func exactMatcher(expected *wildwestv1alpha1.Cowboy) matcher {
-	return func(object *wildwestv1alpha1.Cowboy) error {
-		if !apiequality.Semantic.DeepEqual(expected, object) {
-			return fmt.Errorf("incorrect object: %v", cmp.Diff(expected, object))
-		}
-		return nil
-	}
-}
-
-func ignoreNextEvent(w watch.Interface, duration time.Duration) error {
-	_, err := nextEvent(w, duration)
-	return err
-}
-
-type matcher func(object *wildwestv1alpha1.Cowboy) error
-
-func expectNextEvent(w watch.Interface, expectType watch.EventType, matcher matcher, duration time.Duration) (*wildwestv1alpha1.Cowboy, error) {
-	event, err := nextEvent(w, duration)
-	if err != nil {
-		return nil, err
-	}
-	if expectType != event.Type {
-		return nil, fmt.Errorf("got incorrect watch event type: %v != %v\n", expectType, event.Type)
-	}
-	cowboy, ok := event.Object.(*wildwestv1alpha1.Cowboy)
-	if !ok {
-		return nil, fmt.Errorf("got %T, not a Workspace", event.Object)
-	}
-	if err := matcher(cowboy); err != nil {
-		return cowboy, err
-	}
-	return cowboy, nil
-}
-
-func nextEvent(w watch.Interface, duration time.Duration) (watch.Event, error) {
-	stopTimer := time.NewTimer(duration)
-	defer stopTimer.Stop()
-	select {
-	case event, ok := <-w.ResultChan():
-		if !ok {
-			return watch.Event{}, errors.New("watch closed unexpectedly")
-		}
-		return event, nil
-	case <-stopTimer.C:
-		return watch.Event{}, errors.New("timed out waiting for event")
-	}
+// cowboyMatcher adapts a matcher of cowboys to the objects of watches.
+func cowboyMatcher(matcher func(object *wildwestv1alpha1.Cowboy) error) e2etesting.Matcher {
+	return e2etesting.OfType(&wildwestv1alpha1.Cowboy{}, func(object runtime.Object) error {
+		return matcher(object.(*wildwestv1alpha1.Cowboy))
+	})
 }
@@ -18,16 +18,15 @@ package workspace
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"testing"
 	"time"
 
-	"github.com/google/go-cmp/cmp"
-
 	apiextensionsclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
-	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/rest"
 
@@ -35,6 +34,7 @@ import (
 	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
 	clientset "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
 	"github.com/kcp-dev/kcp/test/e2e/framework"
+	e2etesting "github.com/kcp-dev/kcp/test/e2e/framework/testing"
 )
 
 func TestWorkspaceController(t *testing.T) {
@@ -50,11 +50,11 @@ func TestWorkspaceController(t *testing.T) {
 					t.Errorf("failed to create workspace: %v", err)
 					return
 				}
-				if _, err := expectNextEvent(watcher, watch.Added, exactMatcher(workspace), 30*time.Second); err != nil {
+				if _, err := e2etesting.ExpectNextEvent(watcher, watch.Added, e2etesting.ExactMatcher(workspace), 30*time.Second); err != nil {
 					t.Errorf("did not see workspace created: %v", err)
 					return
 				}
-				if _, err := expectNextEvent(watcher, watch.Modified, unschedulableMatcher(), 30*time.Second); err != nil {
+				if _, err := e2etesting.ExpectNextEvent(watcher, watch.Modified, unschedulableMatcher(), 30*time.Second); err != nil {
 					t.Errorf("did not see workspace updated: %v", err)
 					return
 				}
@@ -68,11 +68,11 @@ func TestWorkspaceController(t *testing.T) {
 					t.Errorf("failed to create workspace: %v", err)
 					return
 				}
-				if _, err := expectNextEvent(watcher, watch.Added, exactMatcher(workspace), 30*time.Second); err != nil {
+				if _, err := e2etesting.ExpectNextEvent(watcher, watch.Added, e2etesting.ExactMatcher(workspace), 30*time.Second); err != nil {
 					t.Errorf("did not see workspace created: %v", err)
 					return
 				}
-				if _, err := expectNextEvent(watcher, watch.Modified, unschedulableMatcher(), 30*time.Second); err != nil {
+				if _, err := e2etesting.ExpectNextEvent(watcher, watch.Modified, unschedulableMatcher(), 30*time.Second); err != nil {
 					t.Errorf("did not see workspace updated: %v", err)
 					return
 				}
@@ -81,7 +81,7 @@ func TestWorkspaceController(t *testing.T) {
 					t.Errorf("failed to create workspace shard: %v", err)
 					return
 				}
-				if _, err := expectNextEvent(watcher, watch.Modified, scheduledMatcher(bostonShard.Name), 30*time.Second); err != nil {
+				if _, err := e2etesting.ExpectNextEvent(watcher, watch.Modified, scheduledMatcher(bostonShard.Name), 30*time.Second); err != nil {
 					t.Errorf("did not see workspace updated: %v", err)
 					return
 				}
@@ -100,11 +100,11 @@ func TestWorkspaceController(t *testing.T) {
 					t.Errorf("failed to create workspace: %v", err)
 					return
 				}
-				if _, err := expectNextEvent(watcher, watch.Added, exactMatcher(workspace), 30*time.Second); err != nil {
+				if _, err := e2etesting.ExpectNextEvent(watcher, watch.Added, e2etesting.ExactMatcher(workspace), 30*time.Second); err != nil {
 					t.Errorf("did not see workspace created: %v", err)
 					return
 				}
-				if _, err := expectNextEvent(watcher, watch.Modified, scheduledMatcher(bostonShard.Name), 30*time.Second); err != nil {
+				if _, err := e2etesting.ExpectNextEvent(watcher, watch.Modified, scheduledMatcher(bostonShard.Name), 30*time.Second); err != nil {
 					t.Errorf("did not see workspace updated: %v", err)
 					return
 				}
@@ -128,12 +128,11 @@ func TestWorkspaceController(t *testing.T) {
 					t.Errorf("failed to create workspace: %v", err)
 					return
 				}
-				if _, err := expectNextEvent(watcher, watch.Added, exactMatcher(workspace), 30*time.Second); err != nil {
+				if _, err := e2etesting.ExpectNextEvent(watcher, watch.Added, e2etesting.ExactMatcher(workspace), 30*time.Second); err != nil {
 					t.Errorf("did not see workspace created: %v", err)
 					return
 				}
-				workspace, err = expectNextEvent(watcher, watch.Modified, scheduledAnywhereMatcher(), 30*time.Second)
-				if err != nil {
+				if err := e2etesting.ExpectNextEventInto(watcher, watch.Modified, scheduledAnywhereMatcher(), 30*time.Second, workspace); err != nil {
 					t.Errorf("did not see workspace updated: %v", err)
 					return
 				}
@@ -149,12 +148,109 @@ func TestWorkspaceController(t *testing.T) {
 					t.Errorf("failed to delete workspace shard: %v", err)
 					return
 				}
-				if _, err := expectNextEvent(watcher, watch.Modified, scheduledMatcher(otherShard), 30*time.Second); err != nil {
+				if _, err := e2etesting.ExpectNextEvent(watcher, watch.Modified, scheduledMatcher(otherShard), 30*time.Second); err != nil {
 					t.Errorf("did not see workspace updated: %v", err)
 					return
 				}
 			},
 		},
+		{
+			name: "update the base URL of a shard, expect the workspace base URL to follow",
+			work: func(ctx context.Context, t framework.TestingTInterface, client clientset.Interface, watcher watch.Interface) {
+				bostonShard, err := client.TenancyV1alpha1().WorkspaceShards().Create(ctx, &tenancyv1alpha1.WorkspaceShard{
+					ObjectMeta: metav1.ObjectMeta{Name: "boston"},
+					Spec:       tenancyv1alpha1.WorkspaceShardSpec{BaseURL: "https://boston.kcp.dev"},
+				}, metav1.CreateOptions{})
+				if err != nil {
+					t.Errorf("failed to create workspace shard: %v", err)
+					return
+				}
+				workspace, err := client.TenancyV1alpha1().Workspaces().Create(ctx, &tenancyv1alpha1.Workspace{ObjectMeta: metav1.ObjectMeta{Name: "steve"}}, metav1.CreateOptions{})
+				if err != nil {
+					t.Errorf("failed to create workspace: %v", err)
+					return
+				}
+				if _, err := e2etesting.ExpectNextEvent(watcher, watch.Added, e2etesting.ExactMatcher(workspace), 30*time.Second); err != nil {
+					t.Errorf("did not see workspace created: %v", err)
+					return
+				}
+				if _, err := e2etesting.ExpectNextEvent(watcher, watch.Modified, baseURLMatcher(bostonShard.Name, "https://boston.kcp.dev"), 30*time.Second); err != nil {
+					t.Errorf("did not see workspace updated: %v", err)
+					return
+				}
+				bostonShard.Spec.BaseURL = "https://boston-2.kcp.dev/prefix"
+				if _, err := client.TenancyV1alpha1().WorkspaceShards().Update(ctx, bostonShard, metav1.UpdateOptions{}); err != nil {
+					t.Errorf("failed to update workspace shard: %v", err)
+					return
+				}
+				if _, err := e2etesting.ExpectNextEvent(watcher, watch.Modified, baseURLMatcher(bostonShard.Name, "https://boston-2.kcp.dev/prefix"), 30*time.Second); err != nil {
+					t.Errorf("did not see workspace updated: %v", err)
+					return
+				}
+			},
+		},
+		{
+			name: "list workspaces as a table, expect their printer columns",
+			work: func(ctx context.Context, t framework.TestingTInterface, client clientset.Interface, watcher watch.Interface) {
+				bostonShard, err := client.TenancyV1alpha1().WorkspaceShards().Create(ctx, &tenancyv1alpha1.WorkspaceShard{
+					ObjectMeta: metav1.ObjectMeta{Name: "boston"},
+					Spec:       tenancyv1alpha1.WorkspaceShardSpec{BaseURL: "https://boston.kcp.dev"},
+				}, metav1.CreateOptions{})
+				if err != nil {
+					t.Errorf("failed to create workspace shard: %v", err)
+					return
+				}
+				workspace, err := client.TenancyV1alpha1().Workspaces().Create(ctx, &tenancyv1alpha1.Workspace{ObjectMeta: metav1.ObjectMeta{Name: "steve"}}, metav1.CreateOptions{})
+				if err != nil {
+					t.Errorf("failed to create workspace: %v", err)
+					return
+				}
+				if _, err := e2etesting.ExpectNextEvent(watcher, watch.Added, e2etesting.ExactMatcher(workspace), 30*time.Second); err != nil {
+					t.Errorf("did not see workspace created: %v", err)
+					return
+				}
+				if _, err := e2etesting.ExpectNextEvent(watcher, watch.Modified, baseURLMatcher(bostonShard.Name, "https://boston.kcp.dev"), 30*time.Second); err != nil {
+					t.Errorf("did not see workspace updated: %v", err)
+					return
+				}
+				raw, err := client.TenancyV1alpha1().RESTClient().Get().
+					Cluster(workspace.ClusterName).
+					Resource("workspaces").
+					SetHeader("Accept", "application/json;as=Table;v=v1;g=meta.k8s.io").
+					DoRaw(ctx)
+				if err != nil {
+					t.Errorf("failed to list workspaces as a table: %v", err)
+					return
+				}
+				var table metav1.Table
+				if err := json.Unmarshal(raw, &table); err != nil {
+					t.Errorf("failed to decode table: %v", err)
+					return
+				}
+				if len(table.Rows) != 1 {
+					t.Errorf("expected a single row, got %d", len(table.Rows))
+					return
+				}
+				cells := map[string]interface{}{}
+				for i, column := range table.ColumnDefinitions {
+					if i < len(table.Rows[0].Cells) {
+						cells[column.Name] = table.Rows[0].Cells[i]
+					}
+				}
+				for column, expected := range map[string]interface{}{
+					"Name":  "steve",
+					"Shard": "boston",
+					"URL":   "https://boston.kcp.dev/clusters/steve",
+				} {
+					if cells[column] != expected {
+						t.Errorf("expected column %s to be %v, got %v", column, expected, cells[column])
+					}
+				}
+				if _, found := cells["Phase"]; !found {
+					t.Errorf("expected a Phase column, got %v", table.ColumnDefinitions)
+				}
+			},
+		},
 		{
 			name: "delete all shards, expect workspace to be unschedulable",
 			work: func(ctx context.Context, t framework.TestingTInterface, client clientset.Interface, watcher watch.Interface) {
@@ -168,11 +264,11 @@ func TestWorkspaceController(t *testing.T) {
 					t.Errorf("failed to create workspace: %v", err)
 					return
 				}
-				if _, err := expectNextEvent(watcher, watch.Added, exactMatcher(workspace), 30*time.Second); err != nil {
+				if _, err := e2etesting.ExpectNextEvent(watcher, watch.Added, e2etesting.ExactMatcher(workspace), 30*time.Second); err != nil {
 					t.Errorf("did not see workspace created: %v", err)
 					return
 				}
-				if _, err := expectNextEvent(watcher, watch.Modified, scheduledMatcher(bostonShard.Name), 30*time.Second); err != nil {
+				if _, err := e2etesting.ExpectNextEvent(watcher, watch.Modified, scheduledMatcher(bostonShard.Name), 30*time.Second); err != nil {
 					t.Errorf("did not see workspace updated: %v", err)
 					return
 				}
@@ -181,7 +277,7 @@ func TestWorkspaceController(t *testing.T) {
 					t.Errorf("failed to delete workspace shard: %v", err)
 					return
 				}
-				if _, err := expectNextEvent(watcher, watch.Modified, unschedulableMatcher(), 30*time.Second); err != nil {
+				if _, err := e2etesting.ExpectNextEvent(watcher, watch.Modified, unschedulableMatcher(), 30*time.Second); err != nil {
 					t.Errorf("did not see workspace updated: %v", err)
 					return
 				}
@@ -233,6 +329,10 @@ func TestWorkspaceController(t *testing.T) {
 
 // TODO: we need to undo the prefixing and get normal sharding behavior in soon ... ?
 func detectClusterName(cfg *rest.Config, ctx context.Context) (string, error) {
+	if framework.ScopedToWorkspace(cfg) {
+		// the config already points at the workspace of the test
+		return "", nil
+	}
 	crdClient, err := apiextensionsclientset.NewClusterForConfig(cfg)
 	if err != nil {
 		return "", fmt.Errorf("failed to construct client for server: %w", err)
@@ -252,26 +352,17 @@ func detectClusterName(cfg *rest.Config, ctx context.Context) (string, error) {
 	return "", errors.New("detected no admin cluster")
 }
 
-func exactMatcher(expected *tenancyv1alpha1.Workspace) matcher {
-	return func(object *tenancyv1alpha1.Workspace) error {
-		if !apiequality.Semantic.DeepEqual(expected, object) {
-			return fmt.Errorf("incorrect object: %v", cmp.Diff(expected, object))
-		}
-		return nil
-	}
-}
-
-func unschedulableMatcher() matcher {
-	return func(object *tenancyv1alpha1.Workspace) error {
+func unschedulableMatcher() e2etesting.Matcher {
+	return workspaceMatcher(func(object *tenancyv1alpha1.Workspace) error {
 		if !conditions.IsWorkspaceUnschedulable(object) {
 			return fmt.Errorf("expected an unschedulable workspace, got status.conditions: %#v", object.Status.Conditions)
 		}
 		return nil
-	}
+	})
 }
 
-func scheduledMatcher(target string) matcher {
-	return func(object *tenancyv1alpha1.Workspace) error {
+func scheduledMatcher(target string) e2etesting.Matcher {
+	return workspaceMatcher(func(object *tenancyv1alpha1.Workspace) error {
 		if conditions.IsWorkspaceUnschedulable(object) {
 			return fmt.Errorf("expected a scheduled workspace, got status.conditions: %#v", object.Status.Conditions)
 		}
@@ -279,48 +370,33 @@ func scheduledMatcher(target string) matcher {
 			return fmt.Errorf("expected workspace.status.location.current to be %q, got %q", target, object.Status.Location.Current)
 		}
 		return nil
-	}
+	})
 }
 
-func scheduledAnywhereMatcher() matcher {
-	return func(object *tenancyv1alpha1.Workspace) error {
-		if conditions.IsWorkspaceUnschedulable(object) {
-			return fmt.Errorf("expected a scheduled workspace, got status.conditions: %#v", object.Status.Conditions)
+func baseURLMatcher(target, shardBaseURL string) e2etesting.Matcher {
+	return workspaceMatcher(func(object *tenancyv1alpha1.Workspace) error {
+		if err := scheduledMatcher(target)(object); err != nil {
+			return err
+		}
+		if baseURL := shardBaseURL + "/clusters/" + object.Status.Cluster; object.Status.BaseURL != baseURL {
+			return fmt.Errorf("expected workspace.status.baseURL to be %q, got %q", baseURL, object.Status.BaseURL)
 		}
 		return nil
-	}
+	})
 }
 
-type matcher func(object *tenancyv1alpha1.Workspace) error
-
-func expectNextEvent(w watch.Interface, expectType watch.EventType, matcher matcher, duration time.Duration) (*tenancyv1alpha1.Workspace, error) {
-	event, err := nextEvent(w, duration)
-	if err != nil {
-		return nil, err
-	}
-	if expectType != event.Type {
-		return nil, fmt.Errorf("got incorrect watch event type: %v != %v\n", expectType, event.Type)
-	}
-	workspace, ok := event.Object.(*tenancyv1alpha1.Workspace)
-	if !ok {
-		return nil, fmt.Errorf("got %T, not a Workspace", event.Object)
-	}
-	if err := matcher(workspace); err != nil {
-		return workspace, err
-	}
-	return workspace, nil
+func scheduledAnywhereMatcher() e2etesting.Matcher {
+	return workspaceMatcher(func(object *tenancyv1alpha1.Workspace) error {
+		if conditions.IsWorkspaceUnschedulable(object) {
+			return fmt.Errorf("expected a scheduled workspace, got status.conditions: %#v", object.Status.Conditions)
+		}
+		return nil
+	})
 }
 
-func nextEvent(w watch.Interface, duration time.Duration) (watch.Event, error) {
-	stopTimer := time.NewTimer(duration)
-	defer stopTimer.Stop()
-	select {
-	case event, ok := <-w.ResultChan():
-		if !ok {
-			return watch.Event{}, errors.New("watch closed unexpectedly")
-		}
-		return event, nil
-	case <-stopTimer.C:
-		return watch.Event{}, errors.New("timed out waiting for event")
-	}
+// workspaceMatcher adapts a matcher of workspaces to the objects of watches.
+func workspaceMatcher(matcher func(object *tenancyv1alpha1.Workspace) error) e2etesting.Matcher {
+	return e2etesting.OfType(&tenancyv1alpha1.Workspace{}, func(object runtime.Object) error {
+		return matcher(object.(*tenancyv1alpha1.Workspace))
+	})
 }
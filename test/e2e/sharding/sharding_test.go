@@ -0,0 +1,165 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sharding
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	"github.com/kcp-dev/kcp/pkg/cmd/workspace"
+	"github.com/kcp-dev/kcp/test/e2e/framework"
+	e2etesting "github.com/kcp-dev/kcp/test/e2e/framework/testing"
+)
+
+func TestSharding(t *testing.T) {
+	var testCases = []struct {
+		name string
+		work func(ctx context.Context, t framework.TestingTInterface, root framework.RunningServer, members []framework.RunningServer)
+	}{
+		{
+			name: "create a namespace on a member shard, expect it to be listed across clusters by the root shard",
+			work: func(ctx context.Context, t framework.TestingTInterface, root framework.RunningServer, members []framework.RunningServer) {
+				memberConfig, err := members[0].Config()
+				if err != nil {
+					t.Errorf("failed to get member shard config: %v", err)
+					return
+				}
+				memberClient, err := kubernetes.NewForConfig(memberConfig)
+				if err != nil {
+					t.Errorf("failed to construct client for member shard: %v", err)
+					return
+				}
+				if err := e2etesting.InstallNamespace(ctx, memberClient, "cross-shard"); err != nil {
+					t.Errorf("failed to create namespace: %v", err)
+					return
+				}
+				rawConfig, err := root.RawConfig()
+				if err != nil {
+					t.Errorf("failed to get root shard config: %v", err)
+					return
+				}
+				crossClusterConfig, err := clientcmd.NewNonInteractiveClientConfig(rawConfig, "cross-cluster", nil, nil).ClientConfig()
+				if err != nil {
+					t.Errorf("failed to get cross-cluster config: %v", err)
+					return
+				}
+				rootClient, err := kubernetes.NewForConfig(crossClusterConfig)
+				if err != nil {
+					t.Errorf("failed to construct client for root shard: %v", err)
+					return
+				}
+				if err := e2etesting.Eventually(ctx, func(ctx context.Context) (bool, string, error) {
+					namespaces, err := rootClient.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+					if err != nil {
+						return false, err.Error(), nil
+					}
+					for _, namespace := range namespaces.Items {
+						if namespace.Name == "cross-shard" {
+							return true, "", nil
+						}
+					}
+					return false, fmt.Sprintf("namespace not among the %d listed", len(namespaces.Items)), nil
+				}, 100*time.Millisecond, 30*time.Second); err != nil {
+					t.Errorf("did not see the namespace of the member shard: %v", err)
+					return
+				}
+			},
+		},
+		{
+			name: "create a workspace, expect it to be scheduled to a shard with the base URL of the shard",
+			work: func(ctx context.Context, t framework.TestingTInterface, root framework.RunningServer, members []framework.RunningServer) {
+				baseURLs := map[string]bool{}
+				for _, server := range append([]framework.RunningServer{root}, members...) {
+					config, err := server.Config()
+					if err != nil {
+						t.Errorf("failed to get shard config: %v", err)
+						return
+					}
+					base, _, err := workspace.SplitServer(config.Host)
+					if err != nil {
+						t.Errorf("failed to get base URL of shard: %v", err)
+						return
+					}
+					baseURLs[base] = true
+				}
+				config, err := root.Config()
+				if err != nil {
+					t.Errorf("failed to get root shard config: %v", err)
+					return
+				}
+				client, err := kcpclient.NewForConfig(config)
+				if err != nil {
+					t.Errorf("failed to construct client for root shard: %v", err)
+					return
+				}
+				shards, err := client.TenancyV1alpha1().WorkspaceShards().List(ctx, metav1.ListOptions{})
+				if err != nil {
+					t.Errorf("failed to list shards: %v", err)
+					return
+				}
+				if len(shards.Items) != len(members)+1 {
+					t.Errorf("expected %d registered shards, got %d", len(members)+1, len(shards.Items))
+					return
+				}
+				ws, err := client.TenancyV1alpha1().Workspaces().Create(ctx, &tenancyv1alpha1.Workspace{ObjectMeta: metav1.ObjectMeta{Name: "steve"}}, metav1.CreateOptions{})
+				if err != nil {
+					t.Errorf("failed to create workspace: %v", err)
+					return
+				}
+				if _, err := e2etesting.EventuallyMatches(ctx, func(ctx context.Context) (runtime.Object, error) {
+					return client.TenancyV1alpha1().Workspaces().Get(ctx, ws.Name, metav1.GetOptions{})
+				}, func(object runtime.Object) error {
+					scheduled := object.(*tenancyv1alpha1.Workspace)
+					if scheduled.Status.Location.Current == "" {
+						return fmt.Errorf("workspace is not scheduled")
+					}
+					for base := range baseURLs {
+						if strings.HasPrefix(scheduled.Status.BaseURL, base+"/clusters/") {
+							return nil
+						}
+					}
+					return fmt.Errorf("workspace scheduled to shard %q has base URL %q, not the one of a shard", scheduled.Status.Location.Current, scheduled.Status.BaseURL)
+				}, 100*time.Millisecond, 30*time.Second); err != nil {
+					t.Errorf("did not see workspace scheduled: %v", err)
+					return
+				}
+			},
+		},
+	}
+	for i := range testCases {
+		testCase := testCases[i]
+		framework.RunSharded(t, testCase.name, func(t framework.TestingTInterface, servers ...framework.RunningServer) {
+			ctx := context.Background()
+			if deadline, ok := t.Deadline(); ok {
+				withDeadline, cancel := context.WithDeadline(ctx, deadline)
+				t.Cleanup(cancel)
+				ctx = withDeadline
+			}
+			testCase.work(ctx, t, servers[0], servers[1:])
+		}, framework.ShardedFixture{Members: 1})
+	}
+}
@@ -0,0 +1,323 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package performance drives load against a kcp server: it creates workspaces with
+// objects in their logical clusters, churns the objects in steady state while listing
+// and watching them, and reports the latency percentiles of the operations along with
+// the growth of the memory, etcd and per-subsystem gauges of the server.
+package performance
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/flowcontrol"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	"github.com/kcp-dev/kcp/pkg/cmd/workspace"
+)
+
+// The operations of a run.
+const (
+	OperationCreateWorkspace = "create-workspace"
+	OperationCreateObject    = "create-object"
+	OperationUpdateObject    = "update-object"
+	OperationListCluster     = "list-cluster"
+	OperationListWildcard    = "list-wildcard"
+	// OperationWatchWildcard is the delay between writing an object and receiving its
+	// event on a watch across all logical clusters.
+	OperationWatchWildcard = "watch-wildcard"
+)
+
+const (
+	// RunLabel is set on the objects of a run to its identifier.
+	RunLabel = "performance.kcp.dev/run"
+	// WrittenAtAnnotation is set on the objects of a run to the time they were last
+	// written, in RFC 3339 format with nanoseconds.
+	WrittenAtAnnotation = "performance.kcp.dev/written-at"
+
+	namespace = "performance"
+)
+
+// Options configures the load of a run.
+type Options struct {
+	// Workspaces is the number of workspaces to create.
+	Workspaces int `json:"workspaces"`
+	// ObjectsPerWorkspace is the number of ConfigMaps to create in the logical cluster of
+	// each workspace.
+	ObjectsPerWorkspace int `json:"objectsPerWorkspace"`
+	// Concurrency is the number of requests in flight at a time.
+	Concurrency int `json:"concurrency"`
+	// Churn is how long objects are updated and listed in steady state, after they are
+	// all created.
+	Churn metav1.Duration `json:"churn"`
+	// ChurnQPS is the rate of the operations of the steady state.
+	ChurnQPS float32 `json:"churnQPS"`
+}
+
+// Run drives the load against the server of the config, which must be able to create
+// workspaces, and reports on it. The workspaces and objects are left in place.
+func Run(ctx context.Context, config *rest.Config, o Options) (*Report, error) {
+	config = rest.CopyConfig(config)
+	// the load is paced by the run, not by client-side throttling
+	config.QPS = -1
+	base, _, err := workspace.SplitServer(config.Host)
+	if err != nil {
+		return nil, err
+	}
+	kcpClient, err := kcpclient.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to construct kcp client: %w", err)
+	}
+	clusterConfig := rest.CopyConfig(config)
+	clusterConfig.Host = base
+	kubeClient, err := kubernetes.NewClusterForConfig(clusterConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to construct kube client: %w", err)
+	}
+	metricsClient := kubeClient.Cluster("").Discovery().RESTClient()
+
+	r := &run{
+		options:    o,
+		id:         rand.String(5),
+		kcpClient:  kcpClient,
+		kubeClient: kubeClient,
+		latencies:  newLatencies(),
+	}
+	before, err := sampleGauges(ctx, metricsClient)
+	if err != nil {
+		return nil, err
+	}
+	watchCtx, stopWatching := context.WithCancel(ctx)
+	watching := make(chan struct{})
+	go func() {
+		defer close(watching)
+		r.watchWildcard(watchCtx)
+	}()
+	err = r.populate(ctx)
+	if err == nil {
+		r.churn(ctx)
+	}
+	stopWatching()
+	<-watching
+	if err != nil {
+		return nil, err
+	}
+	after, err := sampleGauges(ctx, metricsClient)
+	if err != nil {
+		return nil, err
+	}
+	return &Report{
+		Options:    o,
+		Operations: r.latencies.stats(),
+		Gauges:     gaugeStats(before, after),
+	}, nil
+}
+
+// run is the state of a run.
+type run struct {
+	options    Options
+	id         string
+	kcpClient  kcpclient.Interface
+	kubeClient *kubernetes.Cluster
+	latencies  *latencies
+	// workspaces are the names of the created workspaces, which are also the names of
+	// their logical clusters.
+	workspaces []string
+}
+
+// populate creates the workspaces, then the objects in their logical clusters.
+func (r *run) populate(ctx context.Context) error {
+	r.workspaces = make([]string, r.options.Workspaces)
+	if err := r.parallelize(ctx, r.options.Workspaces, func(i int) error {
+		name := fmt.Sprintf("perf-%s-%d", r.id, i)
+		r.workspaces[i] = name
+		return r.latencies.time(OperationCreateWorkspace, func() error {
+			_, err := r.kcpClient.TenancyV1alpha1().Workspaces().Create(ctx, &tenancyv1alpha1.Workspace{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{RunLabel: r.id}},
+			}, metav1.CreateOptions{})
+			return err
+		})
+	}); err != nil {
+		return fmt.Errorf("failed to create workspaces: %w", err)
+	}
+	if err := r.parallelize(ctx, r.options.Workspaces, func(i int) error {
+		_, err := r.kubeClient.Cluster(r.workspaces[i]).CoreV1().Namespaces().Create(ctx, &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: namespace},
+		}, metav1.CreateOptions{})
+		if apierrors.IsAlreadyExists(err) {
+			return nil
+		}
+		return err
+	}); err != nil {
+		return fmt.Errorf("failed to create namespaces: %w", err)
+	}
+	if err := r.parallelize(ctx, r.options.Workspaces*r.options.ObjectsPerWorkspace, func(i int) error {
+		cluster, object := r.workspaces[i/r.options.ObjectsPerWorkspace], i%r.options.ObjectsPerWorkspace
+		return r.latencies.time(OperationCreateObject, func() error {
+			_, err := r.kubeClient.Cluster(cluster).CoreV1().ConfigMaps(namespace).Create(ctx, &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        objectName(object),
+					Labels:      map[string]string{RunLabel: r.id},
+					Annotations: map[string]string{WrittenAtAnnotation: time.Now().Format(time.RFC3339Nano)},
+				},
+				Data: map[string]string{"generation": "0"},
+			}, metav1.CreateOptions{})
+			return err
+		})
+	}); err != nil {
+		return fmt.Errorf("failed to create objects: %w", err)
+	}
+	return nil
+}
+
+// churn updates random objects at the configured rate until the churn duration elapsed,
+// listing the objects of a logical cluster every tenth operation, and the objects of all
+// logical clusters every hundredth. Failures are recorded, not returned.
+func (r *run) churn(ctx context.Context) {
+	if r.options.Churn.Duration <= 0 || r.options.Workspaces == 0 || r.options.ObjectsPerWorkspace == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.options.Churn.Duration)
+	defer cancel()
+	limiter := flowcontrol.NewTokenBucketRateLimiter(r.options.ChurnQPS, r.options.Concurrency)
+	var lock sync.Mutex
+	operation := 0
+	var wg sync.WaitGroup
+	for worker := 0; worker < r.options.Concurrency; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for limiter.Wait(ctx) == nil {
+				lock.Lock()
+				operation++
+				i := operation
+				lock.Unlock()
+				cluster := r.workspaces[rand.Intn(len(r.workspaces))]
+				switch {
+				case i%100 == 0:
+					_ = r.latencies.time(OperationListWildcard, func() error {
+						_, err := r.kubeClient.Cluster("*").CoreV1().ConfigMaps("").List(ctx, metav1.ListOptions{LabelSelector: RunLabel + "=" + r.id})
+						return err
+					})
+				case i%10 == 0:
+					_ = r.latencies.time(OperationListCluster, func() error {
+						_, err := r.kubeClient.Cluster(cluster).CoreV1().ConfigMaps(namespace).List(ctx, metav1.ListOptions{})
+						return err
+					})
+				default:
+					_ = r.latencies.time(OperationUpdateObject, func() error {
+						patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}},"data":{"generation":%q}}`,
+							WrittenAtAnnotation, time.Now().Format(time.RFC3339Nano), strconv.Itoa(i))
+						_, err := r.kubeClient.Cluster(cluster).CoreV1().ConfigMaps(namespace).Patch(ctx,
+							objectName(rand.Intn(r.options.ObjectsPerWorkspace)), types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+						return err
+					})
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// watchWildcard watches the objects of the run across all logical clusters until the
+// context is done, recording the delay between the writes and their events.
+func (r *run) watchWildcard(ctx context.Context) {
+	for ctx.Err() == nil {
+		watcher, err := r.kubeClient.Cluster("*").CoreV1().ConfigMaps("").Watch(ctx, metav1.ListOptions{LabelSelector: RunLabel + "=" + r.id})
+		if err != nil {
+			r.latencies.observe(OperationWatchWildcard, 0, err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		for event := range watcher.ResultChan() {
+			if event.Type != watch.Added && event.Type != watch.Modified {
+				continue
+			}
+			object, ok := event.Object.(*corev1.ConfigMap)
+			if !ok {
+				continue
+			}
+			writtenAt, err := time.Parse(time.RFC3339Nano, object.Annotations[WrittenAtAnnotation])
+			if err != nil {
+				continue
+			}
+			r.latencies.observe(OperationWatchWildcard, time.Since(writtenAt), nil)
+		}
+		watcher.Stop()
+	}
+}
+
+// parallelize calls f for each of n items with the configured concurrency, returning the
+// errors of the calls.
+func (r *run) parallelize(ctx context.Context, n int, f func(i int) error) error {
+	items := make(chan int)
+	go func() {
+		defer close(items)
+		for i := 0; i < n; i++ {
+			select {
+			case items <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	var lock sync.Mutex
+	var errs []error
+	var wg sync.WaitGroup
+	concurrency := r.options.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	for worker := 0; worker < concurrency; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range items {
+				if err := f(i); err != nil {
+					lock.Lock()
+					errs = append(errs, err)
+					lock.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func objectName(i int) string {
+	return fmt.Sprintf("object-%d", i)
+}
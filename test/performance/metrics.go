@@ -0,0 +1,134 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package performance
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"k8s.io/client-go/rest"
+)
+
+// gauge is a metric of the server tracked over a run, broken down by a label if set.
+type gauge struct {
+	subsystem string
+	metric    string
+	label     string
+}
+
+// gauges are the metrics whose growth a run reports, by subsystem of the server.
+var gauges = []gauge{
+	{subsystem: "process", metric: "process_resident_memory_bytes"},
+	{subsystem: "go", metric: "go_memstats_heap_inuse_bytes"},
+	{subsystem: "go", metric: "go_goroutines"},
+	{subsystem: "etcd", metric: "etcd_db_total_size_in_bytes"},
+	{subsystem: "storage", metric: "apiserver_storage_objects", label: "resource"},
+	{subsystem: "watch", metric: "apiserver_registered_watchers", label: "kind"},
+	{subsystem: "workqueue", metric: "workqueue_depth", label: "name"},
+}
+
+// sampleGauges scrapes the metrics of the server, and returns the value of each gauge
+// series by name.
+func sampleGauges(ctx context.Context, client rest.Interface) (map[string]float64, error) {
+	data, err := client.Get().AbsPath("/metrics").DoRaw(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scrape metrics: %w", err)
+	}
+	return parseGauges(data)
+}
+
+func parseGauges(data []byte) (map[string]float64, error) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse metrics: %w", err)
+	}
+	values := map[string]float64{}
+	for _, g := range gauges {
+		family, ok := families[g.metric]
+		if !ok {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			// series not broken down are summed, e.g. the etcd size of all endpoints
+			values[g.name(metric)] += value(metric)
+		}
+	}
+	return values, nil
+}
+
+// name is the name of the series of the gauge the metric belongs to.
+func (g gauge) name(metric *dto.Metric) string {
+	if g.label == "" {
+		return g.metric
+	}
+	for _, label := range metric.GetLabel() {
+		if label.GetName() == g.label {
+			return fmt.Sprintf("%s{%s=%s}", g.metric, g.label, label.GetValue())
+		}
+	}
+	return g.metric
+}
+
+func value(metric *dto.Metric) float64 {
+	switch {
+	case metric.Gauge != nil:
+		return metric.Gauge.GetValue()
+	case metric.Counter != nil:
+		return metric.Counter.GetValue()
+	case metric.Untyped != nil:
+		return metric.Untyped.GetValue()
+	}
+	return 0
+}
+
+// gaugeStats pairs the gauges sampled before and after a run, sorted by subsystem and name.
+func gaugeStats(before, after map[string]float64) []GaugeStats {
+	var stats []GaugeStats
+	for _, g := range gauges {
+		names := map[string]bool{}
+		for name := range before {
+			if g.owns(name) {
+				names[name] = true
+			}
+		}
+		for name := range after {
+			if g.owns(name) {
+				names[name] = true
+			}
+		}
+		var sorted []string
+		for name := range names {
+			sorted = append(sorted, name)
+		}
+		sort.Strings(sorted)
+		for _, name := range sorted {
+			stats = append(stats, GaugeStats{Subsystem: g.subsystem, Name: name, Before: before[name], After: after[name]})
+		}
+	}
+	return stats
+}
+
+// owns returns whether the series of the given name belongs to the gauge.
+func (g gauge) owns(name string) bool {
+	return name == g.metric || (g.label != "" && strings.HasPrefix(name, g.metric+"{"))
+}
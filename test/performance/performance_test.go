@@ -0,0 +1,104 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package performance
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kcp-dev/kcp/test/e2e/framework"
+)
+
+var (
+	workspaces  = flag.Int("workspaces", 0, "Number of workspaces to create. The scale test is skipped unless set.")
+	objects     = flag.Int("objects", 10, "Number of objects to create in each workspace.")
+	concurrency = flag.Int("concurrency", 16, "Number of requests in flight at a time.")
+	churn       = flag.Duration("churn", time.Minute, "How long to churn the objects once they are created.")
+	churnQPS    = flag.Float64("churn-qps", 50, "Rate of the operations while churning.")
+	kcpArgs     = flag.String("kcp-args", "--install_workspace_controller --cache_wildcard_lists", "Arguments of the kcp server under load.")
+	thresholds  = flag.String("thresholds", "", "YAML file with the thresholds failing the test when exceeded.")
+	reportPath  = flag.String("report", "", "File to write the report to, as JSON.")
+)
+
+func TestWorkspaceScale(t *testing.T) {
+	if *workspaces == 0 {
+		t.Skip("set -workspaces to run the scale test")
+	}
+	var limits *Thresholds
+	if *thresholds != "" {
+		var err error
+		if limits, err = LoadThresholds(*thresholds); err != nil {
+			t.Fatal(err)
+		}
+	}
+	framework.Run(t, "scale", func(t framework.TestingTInterface, servers ...framework.RunningServer) {
+		ctx := context.Background()
+		if deadline, ok := t.Deadline(); ok {
+			withDeadline, cancel := context.WithDeadline(ctx, deadline)
+			t.Cleanup(cancel)
+			ctx = withDeadline
+		}
+		config, err := servers[0].Config()
+		if err != nil {
+			t.Errorf("failed to get server config: %v", err)
+			return
+		}
+		report, err := Run(ctx, config, Options{
+			Workspaces:          *workspaces,
+			ObjectsPerWorkspace: *objects,
+			Concurrency:         *concurrency,
+			Churn:               metav1.Duration{Duration: *churn},
+			ChurnQPS:            float32(*churnQPS),
+		})
+		if err != nil {
+			t.Errorf("failed to run the load: %v", err)
+			return
+		}
+		var out strings.Builder
+		if err := report.Print(&out); err != nil {
+			t.Errorf("failed to print the report: %v", err)
+			return
+		}
+		t.Logf("Report:\n%s", out.String())
+		if *reportPath != "" {
+			data, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				t.Errorf("failed to encode the report: %v", err)
+				return
+			}
+			if err := ioutil.WriteFile(*reportPath, data, 0644); err != nil {
+				t.Errorf("failed to write the report: %v", err)
+				return
+			}
+		}
+		if limits != nil {
+			if err := report.Check(limits); err != nil {
+				t.Errorf("thresholds exceeded: %v", err)
+			}
+		}
+	}, framework.KcpConfig{
+		Name: "main",
+		Args: strings.Fields(*kcpArgs),
+	})
+}
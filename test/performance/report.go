@@ -0,0 +1,196 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package performance
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// Report holds the latencies of the operations of a run, and the growth of the gauges
+// of the server over the run.
+type Report struct {
+	Options    Options          `json:"options"`
+	Operations []OperationStats `json:"operations"`
+	Gauges     []GaugeStats     `json:"gauges"`
+}
+
+// OperationStats are the latency percentiles of an operation.
+type OperationStats struct {
+	Operation string          `json:"operation"`
+	Count     int             `json:"count"`
+	Errors    int             `json:"errors"`
+	P50       metav1.Duration `json:"p50"`
+	P90       metav1.Duration `json:"p90"`
+	P99       metav1.Duration `json:"p99"`
+	Max       metav1.Duration `json:"max"`
+}
+
+// GaugeStats is the value of a gauge of the server before and after a run.
+type GaugeStats struct {
+	Subsystem string  `json:"subsystem"`
+	Name      string  `json:"name"`
+	Before    float64 `json:"before"`
+	After     float64 `json:"after"`
+}
+
+// Growth is how much the gauge grew over the run.
+func (g GaugeStats) Growth() float64 {
+	return g.After - g.Before
+}
+
+// Print writes the report as tables.
+func (r *Report) Print(out io.Writer) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "OPERATION\tCOUNT\tERRORS\tP50\tP90\tP99\tMAX\n")
+	for _, op := range r.Operations {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\t%s\t%s\n", op.Operation, op.Count, op.Errors, op.P50.Duration, op.P90.Duration, op.P99.Duration, op.Max.Duration)
+	}
+	fmt.Fprintf(w, "\nSUBSYSTEM\tGAUGE\tBEFORE\tAFTER\tGROWTH\n")
+	for _, g := range r.Gauges {
+		fmt.Fprintf(w, "%s\t%s\t%g\t%g\t%g\n", g.Subsystem, g.Name, g.Before, g.After, g.Growth())
+	}
+	return w.Flush()
+}
+
+// Thresholds bound the results of a run, to gate regressions.
+type Thresholds struct {
+	// P99 is the maximum 99th percentile latency, by operation.
+	P99 map[string]metav1.Duration `json:"p99,omitempty"`
+	// ErrorRate is the maximum ratio of failed operations, for all operations.
+	ErrorRate float64 `json:"errorRate,omitempty"`
+	// Growth is the maximum growth of gauges over a run, by gauge name.
+	Growth map[string]float64 `json:"growth,omitempty"`
+}
+
+// LoadThresholds reads thresholds from a YAML file.
+func LoadThresholds(path string) (*Thresholds, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var thresholds Thresholds
+	if err := yaml.UnmarshalStrict(data, &thresholds); err != nil {
+		return nil, fmt.Errorf("failed to parse thresholds %s: %w", path, err)
+	}
+	return &thresholds, nil
+}
+
+// Check returns an error for each result of the report exceeding the thresholds.
+func (r *Report) Check(thresholds *Thresholds) error {
+	var errs []error
+	for _, op := range r.Operations {
+		if max, ok := thresholds.P99[op.Operation]; ok && op.P99.Duration > max.Duration {
+			errs = append(errs, fmt.Errorf("p99 latency of %s is %s, above %s", op.Operation, op.P99.Duration, max.Duration))
+		}
+		if op.Count > 0 && thresholds.ErrorRate > 0 {
+			if rate := float64(op.Errors) / float64(op.Count); rate > thresholds.ErrorRate {
+				errs = append(errs, fmt.Errorf("error rate of %s is %.3f, above %.3f", op.Operation, rate, thresholds.ErrorRate))
+			}
+		}
+	}
+	for _, g := range r.Gauges {
+		if max, ok := thresholds.Growth[g.Name]; ok && g.Growth() > max {
+			errs = append(errs, fmt.Errorf("%s grew by %g, above %g", g.Name, g.Growth(), max))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// latencies records the latencies of the operations of a run.
+type latencies struct {
+	lock    sync.Mutex
+	samples map[string][]time.Duration
+	errors  map[string]int
+}
+
+func newLatencies() *latencies {
+	return &latencies{
+		samples: map[string][]time.Duration{},
+		errors:  map[string]int{},
+	}
+}
+
+// observe records an operation which took the given time, and failed if err is set.
+func (l *latencies) observe(operation string, latency time.Duration, err error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if err != nil {
+		l.errors[operation]++
+		return
+	}
+	l.samples[operation] = append(l.samples[operation], latency)
+}
+
+// time runs and records an operation.
+func (l *latencies) time(operation string, f func() error) error {
+	start := time.Now()
+	err := f()
+	l.observe(operation, time.Since(start), err)
+	return err
+}
+
+// stats returns the percentiles of the recorded operations, sorted by operation.
+func (l *latencies) stats() []OperationStats {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	operations := map[string]bool{}
+	for operation := range l.samples {
+		operations[operation] = true
+	}
+	for operation := range l.errors {
+		operations[operation] = true
+	}
+	var stats []OperationStats
+	for operation := range operations {
+		samples := append([]time.Duration(nil), l.samples[operation]...)
+		sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+		stats = append(stats, OperationStats{
+			Operation: operation,
+			Count:     len(samples) + l.errors[operation],
+			Errors:    l.errors[operation],
+			P50:       metav1.Duration{Duration: percentile(samples, 0.5)},
+			P90:       metav1.Duration{Duration: percentile(samples, 0.9)},
+			P99:       metav1.Duration{Duration: percentile(samples, 0.99)},
+			Max:       metav1.Duration{Duration: percentile(samples, 1)},
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Operation < stats[j].Operation })
+	return stats
+}
+
+// percentile returns the nearest-rank percentile of sorted samples.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
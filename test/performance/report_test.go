@@ -0,0 +1,100 @@
+/*
+Copyright 2021 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package performance
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestLatencies(t *testing.T) {
+	l := newLatencies()
+	for i := 1; i <= 100; i++ {
+		l.observe(OperationUpdateObject, time.Duration(i)*time.Millisecond, nil)
+	}
+	l.observe(OperationUpdateObject, 0, errors.New("conflict"))
+	l.observe(OperationListCluster, time.Second, nil)
+
+	expected := []OperationStats{
+		{
+			Operation: OperationListCluster,
+			Count:     1,
+			P50:       metav1.Duration{Duration: time.Second},
+			P90:       metav1.Duration{Duration: time.Second},
+			P99:       metav1.Duration{Duration: time.Second},
+			Max:       metav1.Duration{Duration: time.Second},
+		},
+		{
+			Operation: OperationUpdateObject,
+			Count:     101,
+			Errors:    1,
+			P50:       metav1.Duration{Duration: 50 * time.Millisecond},
+			P90:       metav1.Duration{Duration: 90 * time.Millisecond},
+			P99:       metav1.Duration{Duration: 99 * time.Millisecond},
+			Max:       metav1.Duration{Duration: 100 * time.Millisecond},
+		},
+	}
+	if diff := cmp.Diff(expected, l.stats()); diff != "" {
+		t.Errorf("unexpected stats (-want +got):\n%s", diff)
+	}
+}
+
+func TestGauges(t *testing.T) {
+	before, err := parseGauges([]byte(`# TYPE process_resident_memory_bytes gauge
+process_resident_memory_bytes 1000
+# TYPE apiserver_storage_objects gauge
+apiserver_storage_objects{resource="configmaps"} 10
+apiserver_storage_objects{resource="namespaces"} 2
+# TYPE etcd_db_total_size_in_bytes gauge
+etcd_db_total_size_in_bytes{endpoint="a"} 100
+etcd_db_total_size_in_bytes{endpoint="b"} 100
+# TYPE unrelated gauge
+unrelated 1
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	after := map[string]float64{
+		"process_resident_memory_bytes":                 1500,
+		"etcd_db_total_size_in_bytes":                   300,
+		"apiserver_storage_objects{resource=configmaps}": 110,
+		"apiserver_storage_objects{resource=secrets}":    1,
+	}
+	report := &Report{Gauges: gaugeStats(before, after)}
+	expected := []GaugeStats{
+		{Subsystem: "process", Name: "process_resident_memory_bytes", Before: 1000, After: 1500},
+		{Subsystem: "etcd", Name: "etcd_db_total_size_in_bytes", Before: 200, After: 300},
+		{Subsystem: "storage", Name: "apiserver_storage_objects{resource=configmaps}", Before: 10, After: 110},
+		{Subsystem: "storage", Name: "apiserver_storage_objects{resource=namespaces}", Before: 2, After: 0},
+		{Subsystem: "storage", Name: "apiserver_storage_objects{resource=secrets}", After: 1},
+	}
+	if diff := cmp.Diff(expected, report.Gauges); diff != "" {
+		t.Errorf("unexpected gauges (-want +got):\n%s", diff)
+	}
+
+	err = report.Check(&Thresholds{Growth: map[string]float64{
+		"process_resident_memory_bytes":                 400,
+		"apiserver_storage_objects{resource=configmaps}": 100,
+	}})
+	if err == nil || err.Error() != "process_resident_memory_bytes grew by 500, above 400" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}